@@ -0,0 +1,190 @@
+// Command chessplay-epd runs EPD test suites such as WAC or STS: it searches
+// every position and checks the move played against the suite's best moves
+// (bm) and moves to avoid (am), or scores it by an STS-style c0 comment of
+// points per move. Comparing the totals across versions tracks tactical and
+// positional strength.
+//
+// Usage:
+//
+//	chessplay-epd -movetime 1s wac.epd
+//	chessplay-epd -nodes 200000 -evalfile nn-big.nnue STS1-STS15.epd
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+var (
+	moveTime      = flag.Duration("movetime", time.Second, "search time per position (0 = no limit)")
+	depth         = flag.Int("depth", 0, "search depth per position (0 = no limit)")
+	nodes         = flag.Uint64("nodes", 0, "nodes per position (0 = no limit)")
+	hashMB        = flag.Int("hash", 64, "transposition table size in MB")
+	evalFile      = flag.String("evalfile", "", "big NNUE network (default: classical evaluation)")
+	evalFileSmall = flag.String("evalfilesmall", "", "small NNUE network (default: the embedded one)")
+	limit         = flag.Int("limit", 0, "maximum number of positions per file (0 = all)")
+	quiet         = flag.Bool("q", false, "only print failed positions and the totals")
+)
+
+// totals are the results of a suite.
+type totals struct {
+	positions, solved int
+	points, maxPoints int // STS-style points, if the suite has them
+	nodes             uint64
+	time              time.Duration
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 || (*moveTime == 0 && *depth == 0 && *nodes == 0) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	eng := engine.NewEngine(*hashMB)
+	if *evalFile != "" || *evalFileSmall != "" {
+		if err := eng.LoadNNUE(*evalFile, *evalFileSmall); err != nil {
+			log.Fatalf("Failed to load NNUE: %v", err)
+		}
+		eng.SetUseNNUE(true)
+	}
+	limits := engine.SearchLimits{MoveTime: *moveTime, Depth: *depth, Nodes: *nodes}
+
+	var all totals
+	for _, path := range flag.Args() {
+		t, err := runSuite(eng, path, limits)
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		report(path, t)
+		all.add(t)
+	}
+	if flag.NArg() > 1 {
+		report("Total", all)
+	}
+}
+
+// runSuite searches every position of an EPD file, printing its result.
+func runSuite(eng *engine.Engine, path string, limits engine.SearchLimits) (totals, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return totals{}, err
+	}
+	defer f.Close()
+
+	var t totals
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() && (*limit == 0 || t.positions < *limit) {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		epd, err := board.ParseEPD(line)
+		if err != nil {
+			return t, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		bm, err := epd.Moves("bm")
+		if err != nil {
+			return t, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		am, err := epd.Moves("am")
+		if err != nil {
+			return t, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		id := epd.ID()
+		if id == "" {
+			id = fmt.Sprintf("line %d", lineNo)
+		}
+		if _, _, ok := epd.MovePoints(board.NoMove); !ok && len(bm) == 0 && len(am) == 0 {
+			log.Printf("%s: %s has no bm, am or c0 points, skipped", path, id)
+			continue
+		}
+
+		// Every position is searched from a clean state, as by a fresh engine
+		pos, _ := epd.Position()
+		eng.Clear()
+		eng.SetPositionHistory([]uint64{pos.Hash})
+		move := eng.SearchWithLimits(pos, limits)
+		info := eng.LastSearchInfo()
+		t.positions++
+		t.nodes += info.Nodes
+		t.time += info.Time
+
+		points, best, hasPoints := epd.MovePoints(move)
+		pass := points == best
+		if len(bm) > 0 || len(am) > 0 {
+			pass = (len(bm) == 0 || slices.Contains(bm, move)) && !slices.Contains(am, move)
+		}
+		if pass {
+			t.solved++
+		}
+		if hasPoints {
+			t.points += points
+			t.maxPoints += best
+		}
+
+		if pass && *quiet {
+			continue
+		}
+		result := "fail"
+		if pass {
+			result = "ok"
+		}
+		expected := ""
+		if len(bm) > 0 {
+			expected += " bm " + sanList(pos, bm)
+		}
+		if len(am) > 0 {
+			expected += " am " + sanList(pos, am)
+		}
+		if hasPoints {
+			expected += fmt.Sprintf(" (%d/%d points)", points, best)
+		}
+		fmt.Printf("%4d %-16s %-4s %-8s%s  score %s depth %d\n", t.positions, id, result,
+			move.ToSAN(pos), expected, engine.ScoreToString(info.Score), info.Depth)
+	}
+	return t, scanner.Err()
+}
+
+// sanList writes moves of pos in SAN, separated by spaces.
+func sanList(pos *board.Position, moves []board.Move) string {
+	sans := make([]string, len(moves))
+	for i, m := range moves {
+		sans[i] = m.ToSAN(pos)
+	}
+	return strings.Join(sans, " ")
+}
+
+// add adds the results of another suite.
+func (t *totals) add(o totals) {
+	t.positions += o.positions
+	t.solved += o.solved
+	t.points += o.points
+	t.maxPoints += o.maxPoints
+	t.nodes += o.nodes
+	t.time += o.time
+}
+
+// report prints the totals of a suite.
+func report(name string, t totals) {
+	if t.positions == 0 {
+		fmt.Printf("%s: no positions\n", name)
+		return
+	}
+	fmt.Printf("%s: solved %d of %d (%.1f%%)", name, t.solved, t.positions, 100*float64(t.solved)/float64(t.positions))
+	if t.maxPoints > 0 {
+		fmt.Printf(", %d of %d points (%.1f%%)", t.points, t.maxPoints, 100*float64(t.points)/float64(t.maxPoints))
+	}
+	fmt.Printf(", %d nodes in %v\n", t.nodes, t.time.Round(time.Millisecond))
+}
@@ -0,0 +1,78 @@
+// Command chessplay-import imports PGN files into the local games database,
+// which the GUI searches for games reaching the current position.
+//
+// Usage:
+//
+//	chessplay-import games.pgn more-games.pgn.gz
+//	chessplay-import -fen "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2"
+//
+// SCID and ChessBase databases must be exported to PGN first.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/gamedb"
+	"github.com/hailam/chessplay/internal/storage"
+)
+
+var (
+	dbDir   = flag.String("db", "", "database directory (default: the GUI's games database)")
+	dataDir = flag.String("data-dir", "", "data directory of the GUI (default: the platform's)")
+	fen     = flag.String("fen", "", "list the games reaching this position")
+	limit   = flag.Int("limit", 20, "maximum number of games listed by -fen")
+)
+
+func main() {
+	flag.Parse()
+	storage.SetDataDir(*dataDir)
+	if flag.NArg() == 0 && *fen == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := *dbDir
+	if dir == "" {
+		var err error
+		if dir, err = storage.GetGameDBDir(); err != nil {
+			log.Fatalf("Failed to find the database directory: %v", err)
+		}
+	}
+	db, err := gamedb.Open(dir)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", dir, err)
+	}
+	defer db.Close()
+
+	for _, path := range flag.Args() {
+		start := time.Now()
+		stats, err := db.ImportFile(path, func(s gamedb.ImportStats) {
+			log.Printf("%s: %d games, %d skipped", path, s.Games, s.Skipped)
+		})
+		if err != nil {
+			log.Fatalf("Failed to import %s: %v", path, err)
+		}
+		log.Printf("Imported %d games (%d positions) from %s in %v, skipped %d",
+			stats.Games, stats.Positions, path, time.Since(start).Round(time.Millisecond), stats.Skipped)
+	}
+
+	if *fen != "" {
+		pos, err := board.ParseFEN(*fen)
+		if err != nil {
+			log.Fatalf("Invalid FEN: %v", err)
+		}
+		matches, total, err := db.Search(pos, *limit)
+		if err != nil {
+			log.Fatalf("Search failed: %v", err)
+		}
+		for _, m := range matches {
+			fmt.Printf("%8d  %s - %s  %s  %s  %s (ply %d)\n", m.ID, m.White, m.Black, m.Result, m.Event, m.Date, m.Ply)
+		}
+		fmt.Printf("%d games\n", total)
+	}
+}
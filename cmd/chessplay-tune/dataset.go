@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// sample is a labeled training position
+type sample struct {
+	pos    board.Position
+	result float64 // Game result from White's perspective: 1, 0.5 or 0
+}
+
+// Positions from the opening are mostly book knowledge, not evaluation
+const pgnSkipPlies = 8
+
+// loadSamples reads labeled positions from an EPD or PGN file (chosen by extension).
+func loadSamples(path string, limit int) ([]sample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+	if strings.EqualFold(filepath.Ext(path), ".pgn") {
+		return loadPGN(scanner, limit)
+	}
+	return loadEPD(scanner, limit)
+}
+
+// loadEPD reads one position per line: a FEN (4 or 6 fields) followed by the
+// result, either as an EPD opcode (c9 "1-0";) or in brackets ([0.5]).
+func loadEPD(scanner *bufio.Scanner, limit int) ([]sample, error) {
+	var samples []sample
+	lineNo := 0
+	for scanner.Scan() && (limit == 0 || len(samples) < limit) {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("line %d: expected FEN and result", lineNo)
+		}
+		pos, err := board.ParseFEN(strings.Join(fields[:4], " "))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		result, ok := epdResult(strings.Join(fields[4:], " "))
+		if !ok {
+			return nil, fmt.Errorf("line %d: no result found", lineNo)
+		}
+		samples = append(samples, sample{pos: *pos, result: result})
+	}
+	return samples, scanner.Err()
+}
+
+// epdResult extracts the result from the part of an EPD line after the FEN.
+func epdResult(s string) (float64, bool) {
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		if j := strings.IndexByte(s[i:], ']'); j > 0 {
+			return parseResult(s[i+1 : i+j])
+		}
+	}
+	if i := strings.Index(s, "c9 \""); i >= 0 {
+		rest := s[i+4:]
+		if j := strings.IndexByte(rest, '"'); j >= 0 {
+			return parseResult(rest[:j])
+		}
+	}
+	return 0, false
+}
+
+// parseResult converts a game result token to a score for White.
+func parseResult(s string) (float64, bool) {
+	switch strings.TrimSpace(s) {
+	case "1-0":
+		return 1, true
+	case "0-1":
+		return 0, true
+	case "1/2-1/2", "1/2", "=":
+		return 0.5, true
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || v < 0 || v > 1 {
+		return 0, false
+	}
+	return v, true
+}
+
+// loadPGN labels every quiet position of decided and drawn games with the
+// game result. Positions in check or right after a capture are skipped, as
+// the static evaluation cannot judge them.
+func loadPGN(scanner *bufio.Scanner, limit int) ([]sample, error) {
+	var samples []sample
+	var movetext strings.Builder
+	result := ""
+	inMoves := false
+
+	flush := func() {
+		if res, ok := parseResult(result); ok && movetext.Len() > 0 {
+			samples = append(samples, pgnSamples(movetext.String(), res)...)
+		}
+		movetext.Reset()
+		result = ""
+		inMoves = false
+	}
+
+	for scanner.Scan() && (limit == 0 || len(samples) < limit) {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			if inMoves {
+				flush()
+			}
+			if strings.HasPrefix(line, "[Result ") {
+				result = strings.Trim(strings.TrimPrefix(line, "[Result "), "\"]")
+			}
+			continue
+		}
+		if line != "" {
+			inMoves = true
+			movetext.WriteString(line)
+			movetext.WriteString(" ")
+		}
+	}
+	flush()
+
+	if limit > 0 && len(samples) > limit {
+		samples = samples[:limit]
+	}
+	return samples, scanner.Err()
+}
+
+// pgnSamples replays the moves of one game. Illegal or unparsable moves end
+// the game early; the positions before them are kept.
+func pgnSamples(movetext string, result float64) []sample {
+	var samples []sample
+	pos := board.NewPosition()
+	ply := 0
+
+	for _, tok := range pgnTokens(movetext) {
+		m, err := board.ParseSAN(tok, pos)
+		if err != nil {
+			break
+		}
+		noisy := m.IsCapture(pos) || m.IsPromotion()
+		pos.MakeMove(m)
+		pos.UpdateCheckers()
+		ply++
+
+		if ply > pgnSkipPlies && !noisy && !pos.InCheck() {
+			samples = append(samples, sample{pos: *pos, result: result})
+		}
+	}
+	return samples
+}
+
+// pgnTokens returns the SAN moves of a movetext, dropping comments,
+// variations, move numbers, NAGs, annotations and the result.
+func pgnTokens(movetext string) []string {
+	var tokens []string
+	var sb strings.Builder
+	commentDepth, variationDepth := 0, 0
+
+	emit := func() {
+		tok := strings.TrimRight(sb.String(), "+#!?")
+		sb.Reset()
+		if i := strings.LastIndexByte(tok, '.'); i >= 0 {
+			tok = tok[i+1:] // "12.e4" and "12..."
+		}
+		if tok == "" || tok[0] == '$' || tok == "*" {
+			return
+		}
+		if _, ok := parseResult(tok); ok {
+			return
+		}
+		tokens = append(tokens, tok)
+	}
+
+	for _, r := range movetext {
+		switch {
+		case r == '{':
+			commentDepth++
+		case r == '}':
+			commentDepth--
+		case commentDepth > 0:
+		case r == '(':
+			variationDepth++
+		case r == ')':
+			variationDepth--
+		case variationDepth > 0:
+		case r == ' ' || r == '\t':
+			emit()
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	emit()
+	return tokens
+}
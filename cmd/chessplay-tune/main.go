@@ -0,0 +1,95 @@
+// Command chessplay-tune tunes the classical evaluation weights with Texel's
+// method on a file of positions labeled with game results, and writes the
+// tuned weights as a Go source file for internal/engine.
+//
+// Usage:
+//
+//	chessplay-tune -data quiet-labeled.epd -out internal/engine/eval_tuned.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+var (
+	dataPath   = flag.String("data", "", "EPD or PGN file of labeled positions (required)")
+	outPath    = flag.String("out", "eval_tuned.go", "Go source file for the tuned weights")
+	method     = flag.String("method", "local", "optimizer: local (Texel local search) or gradient (Adam)")
+	iterations = flag.Int("iterations", 0, "maximum iterations (0 = until no improvement for local, 100 for gradient)")
+	step       = flag.Int("step", 1, "local search step in centipawns")
+	rate       = flag.Float64("rate", 1, "gradient descent step size in centipawns")
+	k          = flag.Float64("k", 0, "sigmoid scaling constant (0 = fit to the data)")
+	threads    = flag.Int("threads", runtime.NumCPU(), "evaluation threads")
+	limit      = flag.Int("limit", 0, "maximum number of positions (0 = all)")
+)
+
+func main() {
+	flag.Parse()
+	if *dataPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	start := time.Now()
+	samples, err := loadSamples(*dataPath, *limit)
+	if err != nil {
+		log.Fatalf("Failed to load %s: %v", *dataPath, err)
+	}
+	if len(samples) == 0 {
+		log.Fatalf("No labeled positions in %s", *dataPath)
+	}
+	log.Printf("Loaded %d positions in %v", len(samples), time.Since(start).Round(time.Millisecond))
+
+	t := newTuner(samples, max(*threads, 1))
+	if *k > 0 {
+		t.k = *k
+	} else {
+		t.fitK()
+	}
+	initial := t.error()
+	log.Printf("K = %.3f, initial error %.6f, %d weights", t.k, initial, len(t.params))
+
+	// The weights are written after every iteration, so a long run can be interrupted
+	onIteration := func(iter int, err float64) {
+		log.Printf("Iteration %d: error %.6f (%v)", iter, err, time.Since(start).Round(time.Second))
+		if werr := writeWeights(t, initial, err); werr != nil {
+			log.Printf("Warning: Failed to write %s: %v", *outPath, werr)
+		}
+	}
+
+	var final float64
+	switch *method {
+	case "local":
+		final = t.localSearch(*step, *iterations, onIteration)
+	case "gradient":
+		final = t.gradientDescent(*rate, *iterations, onIteration)
+	default:
+		log.Fatalf("Unknown method %q", *method)
+	}
+
+	if err := writeWeights(t, initial, final); err != nil {
+		log.Fatalf("Failed to write %s: %v", *outPath, err)
+	}
+	log.Printf("Final error %.6f (was %.6f), weights written to %s", final, initial, *outPath)
+}
+
+// writeWeights writes the current weights to the output file.
+func writeWeights(t *tuner, initial, final float64) error {
+	comment := fmt.Sprintf("Tuned on %s (%d positions, K=%.3f, method %s).\nError %.6f -> %.6f.",
+		filepath.Base(*dataPath), len(t.samples), t.k, *method, initial, final)
+
+	var buf bytes.Buffer
+	if err := engine.WriteEvalSource(&buf, comment); err != nil {
+		return err
+	}
+	return os.WriteFile(*outPath, buf.Bytes(), 0644)
+}
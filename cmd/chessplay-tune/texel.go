@@ -0,0 +1,168 @@
+package main
+
+import (
+	"math"
+	"sync"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+// tuner minimizes the mean squared error between game results and the
+// win probability predicted by the classical evaluation (Texel's method).
+type tuner struct {
+	samples []sample
+	threads int
+	k       float64 // Sigmoid scaling: probability = 1 / (1 + 10^(-k*eval/400))
+	params  []*int  // Every tunable evaluation weight
+}
+
+func newTuner(samples []sample, threads int) *tuner {
+	t := &tuner{samples: samples, threads: threads, k: 1}
+	for _, w := range engine.EvalWeights() {
+		t.params = append(t.params, w.Values...)
+	}
+	return t
+}
+
+// sigmoid converts a centipawn evaluation to an expected score.
+func sigmoid(eval, k float64) float64 {
+	return 1 / (1 + math.Pow(10, -k*eval/400))
+}
+
+// errorFor returns the mean squared error of the current weights with scaling k.
+// Positions are evaluated in parallel; weights must not change meanwhile.
+func (t *tuner) errorFor(k float64) float64 {
+	chunk := (len(t.samples) + t.threads - 1) / t.threads
+	sums := make([]float64, t.threads)
+
+	var wg sync.WaitGroup
+	for i := 0; i < t.threads; i++ {
+		start := i * chunk
+		end := min(start+chunk, len(t.samples))
+		if start >= end {
+			break
+		}
+		wg.Add(1)
+		go func(i int, samples []sample) {
+			defer wg.Done()
+			var sum float64
+			for j := range samples {
+				s := &samples[j]
+				eval := engine.Evaluate(&s.pos)
+				if s.pos.SideToMove == board.Black {
+					eval = -eval
+				}
+				d := s.result - sigmoid(float64(eval), k)
+				sum += d * d
+			}
+			sums[i] = sum
+		}(i, t.samples[start:end])
+	}
+	wg.Wait()
+
+	var total float64
+	for _, s := range sums {
+		total += s
+	}
+	return total / float64(len(t.samples))
+}
+
+// error returns the mean squared error with the tuner's scaling.
+func (t *tuner) error() float64 {
+	return t.errorFor(t.k)
+}
+
+// fitK finds the scaling constant that best fits the untuned evaluation,
+// refining a scan of [0, 3] by a factor of ten per round.
+func (t *tuner) fitK() {
+	best, bestErr := 1.0, t.errorFor(1.0)
+	lo, hi, step := 0.0, 3.0, 0.1
+	for round := 0; round < 3; round++ {
+		for k := lo; k <= hi+1e-9; k += step {
+			if k <= 0 {
+				continue
+			}
+			if err := t.errorFor(k); err < bestErr {
+				best, bestErr = k, err
+			}
+		}
+		lo, hi, step = best-step, best+step, step/10
+	}
+	t.k = best
+}
+
+// localSearch is Texel's original method: each weight is moved by one step
+// while that lowers the error. Returns the final error.
+func (t *tuner) localSearch(step, iterations int, onIteration func(int, float64)) float64 {
+	best := t.error()
+	for iter := 1; iterations == 0 || iter <= iterations; iter++ {
+		improved := false
+		for _, p := range t.params {
+			orig := *p
+
+			*p = orig + step
+			if err := t.error(); err < best {
+				best, improved = err, true
+				continue
+			}
+			*p = orig - step
+			if err := t.error(); err < best {
+				best, improved = err, true
+				continue
+			}
+			*p = orig
+		}
+
+		onIteration(iter, best)
+		if !improved {
+			break
+		}
+	}
+	return best
+}
+
+// gradientDescent follows central-difference gradients with Adam step sizes,
+// keeping real-valued weights and rounding them for evaluation. rate is the
+// approximate change per iteration in centipawns. Returns the final error.
+func (t *tuner) gradientDescent(rate float64, iterations int, onIteration func(int, float64)) float64 {
+	const beta1, beta2, eps = 0.9, 0.999, 1e-12
+
+	n := len(t.params)
+	values := make([]float64, n)
+	m := make([]float64, n)
+	v := make([]float64, n)
+	grad := make([]float64, n)
+	for i, p := range t.params {
+		values[i] = float64(*p)
+	}
+
+	if iterations == 0 {
+		iterations = 100
+	}
+	best := t.error()
+	for iter := 1; iter <= iterations; iter++ {
+		for i, p := range t.params {
+			orig := *p
+			*p = orig + 1
+			up := t.error()
+			*p = orig - 1
+			down := t.error()
+			*p = orig
+			grad[i] = (up - down) / 2
+		}
+
+		for i, p := range t.params {
+			m[i] = beta1*m[i] + (1-beta1)*grad[i]
+			v[i] = beta2*v[i] + (1-beta2)*grad[i]*grad[i]
+			mHat := m[i] / (1 - math.Pow(beta1, float64(iter)))
+			vHat := v[i] / (1 - math.Pow(beta2, float64(iter)))
+			values[i] -= rate * mHat / (math.Sqrt(vHat) + eps)
+			*p = int(math.Round(values[i]))
+		}
+
+		best = t.error()
+		onIteration(iter, best)
+	}
+	return best
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+// serveHealth serves the health and metrics endpoints of a deployed engine
+// on addr, for container orchestrators and Prometheus:
+//   - /healthz answers "ok" while the process is running
+//   - /metrics reports the engine's activity and memory use
+//
+// The UCI protocol owns stdout, so errors are only logged.
+func serveHealth(addr string, eng *engine.Engine) {
+	started := time.Now()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, eng.Stats(), started)
+	})
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Warning: health endpoints stopped: %v", err)
+		}
+	}()
+	log.Printf("Serving /healthz and /metrics on %s", addr)
+}
+
+// writeMetrics writes the metrics in the Prometheus text format. A UCI
+// process runs one search at a time, so the active sessions are the running
+// search and there is no queue of searches.
+func writeMetrics(w http.ResponseWriter, st engine.Stats, started time.Time) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	metric := func(name, kind, help string, value any) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	searching := 0
+	if st.Searching {
+		searching = 1
+	}
+
+	metric("chessplay_engine_workers", "gauge", "Search workers of the engine pool.", st.Workers)
+	metric("chessplay_engine_active_searches", "gauge", "Searches running now.", searching)
+	metric("chessplay_engine_searches_total", "counter", "Searches completed.", st.Searches)
+	metric("chessplay_engine_nodes_total", "counter", "Nodes searched by completed searches.", st.Nodes)
+	metric("chessplay_engine_nps", "gauge", "Nodes per second of the running or last search.", st.NPS)
+	metric("chessplay_engine_hashfull_permille", "gauge", "Transposition table use by the current search.", st.HashFull)
+	metric("chessplay_memory_heap_bytes", "gauge", "Heap memory in use.", mem.HeapAlloc)
+	metric("chessplay_memory_sys_bytes", "gauge", "Memory obtained from the OS.", mem.Sys)
+	metric("chessplay_goroutines", "gauge", "Goroutines running.", runtime.NumGoroutine())
+	metric("chessplay_uptime_seconds", "gauge", "Seconds since the engine started.", int(time.Since(started).Seconds()))
+}
@@ -4,23 +4,23 @@ import (
 	"flag"
 	"log"
 	"os"
-	"path/filepath"
 	"runtime/pprof"
 
 	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/storage"
 	"github.com/hailam/chessplay/internal/uci"
+	"github.com/hailam/chessplay/sfnnue"
 )
 
-// Default NNUE file names (Stockfish compatible)
-const (
-	defaultBigNet   = "nn-c288c895ea92.nnue" // ~108MB
-	defaultSmallNet = "nn-37f18f62d772.nnue" // ~3.5MB
+var (
+	cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
+	httpAddr   = flag.String("http", "", "serve /healthz and /metrics on this address, e.g. :8080")
+	dataDir    = flag.String("data-dir", "", "data directory for NNUE networks and books (default: the platform's)")
 )
 
-var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
-
 func main() {
 	flag.Parse()
+	storage.SetDataDir(*dataDir)
 
 	// Start CPU profiling if requested (via flag or environment variable)
 	profilePath := *cpuprofile
@@ -45,62 +45,39 @@ func main() {
 	eng := engine.NewEngine(64)
 
 	// Auto-load NNUE from default locations
-	if err := autoLoadNNUE(eng); err != nil {
+	dirs := storage.NNUESearchDirs()
+	if err := autoLoadNNUE(eng, dirs); err != nil {
 		log.Printf("Warning: NNUE not loaded: %v (using classical evaluation)", err)
 	}
 
-	// Create and run UCI protocol handler
-	protocol := uci.New(eng)
-	protocol.Run()
-}
-
-// autoLoadNNUE attempts to load NNUE weights from standard locations
-func autoLoadNNUE(eng *engine.Engine) error {
-	// Try multiple locations in order of preference
-	searchPaths := []string{
-		getAppSupportDir(),                    // ~/Library/Application Support/chessplay/nnue/
-		filepath.Join(getHomeDir(), ".chessplay", "nnue"), // ~/.chessplay/nnue/
-		"./nnue",                              // ./nnue/ (current directory)
-		".",                                   // current directory
+	if *httpAddr != "" {
+		serveHealth(*httpAddr, eng)
 	}
 
-	for _, dir := range searchPaths {
-		bigPath := filepath.Join(dir, defaultBigNet)
-		smallPath := filepath.Join(dir, defaultSmallNet)
+	// Create and run UCI protocol handler
+	protocol := uci.New(eng)
+	protocol.SetNNUEDirs(dirs)
 
-		// Check if both files exist
-		if fileExists(bigPath) && fileExists(smallPath) {
-			if err := eng.LoadNNUE(bigPath, smallPath); err != nil {
-				log.Printf("Failed to load NNUE from %s: %v", dir, err)
-				continue
-			}
-			eng.SetUseNNUE(true)
-			log.Printf("NNUE loaded from %s", dir)
-			return nil
-		}
+	// "chessplay-uci bench [depth]" runs the benchmark and exits
+	if flag.Arg(0) == "bench" {
+		protocol.Bench(flag.Args()[1:])
+		return
 	}
-
-	return os.ErrNotExist
-}
-
-// getAppSupportDir returns the application support directory for chessplay
-func getAppSupportDir() string {
-	home := getHomeDir()
-	// macOS: ~/Library/Application Support/chessplay/nnue/
-	return filepath.Join(home, "Library", "Application Support", "chessplay", "nnue")
+	protocol.Run()
 }
 
-// getHomeDir returns the user's home directory
-func getHomeDir() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "."
+// autoLoadNNUE loads the newest big and small networks found in dirs.
+// Without a big network the small one is used alone, and without a small
+// network the embedded one (if built with -tags embednet).
+func autoLoadNNUE(eng *engine.Engine, dirs []string) error {
+	bigPath, smallPath := engine.NewestNNUE(engine.ScanNNUE(dirs...))
+	if smallPath == "" && !sfnnue.HasEmbeddedNetwork() {
+		return os.ErrNotExist
 	}
-	return home
-}
-
-// fileExists checks if a file exists
-func fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
+	if err := eng.LoadNNUE(bigPath, smallPath); err != nil {
+		return err
+	}
+	eng.SetUseNNUE(true)
+	log.Printf("NNUE loaded: %s, %s", bigPath, smallPath)
+	return nil
 }
@@ -0,0 +1,165 @@
+package board
+
+import "testing"
+
+// TestCastlingRightsParsing verifies the rooks found for the castling
+// rights of a FEN, and that rights the position does not back are dropped.
+func TestCastlingRightsParsing(t *testing.T) {
+	tests := []struct {
+		name, fen string
+		rooks     [4]Square // K, Q, k, q
+		castling  string    // Castling field written back
+	}{
+		{"standard", StartFEN,
+			[4]Square{H1, A1, H8, A8}, "KQkq"},
+		{"no rooks", "4k3/8/8/8/8/8/8/4K3 w KQkq - 0 1",
+			[4]Square{NoSquare, NoSquare, NoSquare, NoSquare}, "-"},
+		{"king off the back rank", "r3k2r/8/8/8/8/8/4K3/R6R w KQkq - 0 1",
+			[4]Square{NoSquare, NoSquare, H8, A8}, "kq"},
+		{"rook gone", "r3k3/8/8/8/8/8/8/R3K2R b KQkq - 0 1",
+			[4]Square{H1, A1, NoSquare, A8}, "KQq"},
+		{"outermost rook", "4k3/8/8/8/8/8/8/R1R1K1R1 w KQ - 0 1",
+			[4]Square{G1, A1, NoSquare, NoSquare}, "KQ"},
+		{"inner rook by file", "4k3/8/8/8/8/8/8/R1R1K3 w C - 0 1",
+			[4]Square{NoSquare, C1, NoSquare, NoSquare}, "C"},
+		{"chess960 by file", "1r4kr/8/8/8/8/8/8/RK5R w HAhb - 0 1",
+			[4]Square{H1, A1, H8, B8}, "KQkq"},
+		{"file of no rook", "r3k2r/8/8/8/8/8/8/R3K2R w Bg - 0 1",
+			[4]Square{NoSquare, NoSquare, NoSquare, NoSquare}, "-"},
+	}
+	for _, tt := range tests {
+		pos, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for i, want := range tt.rooks {
+			c, kingSide := Color(i/2), i%2 == 0
+			if got := pos.CastlingRook(c, kingSide); got != want {
+				t.Errorf("%s: right %d rook on %v, want %v", tt.name, i, got, want)
+			}
+		}
+		if err := pos.Validate(); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		back, err := ParseFEN(pos.ToFEN())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := back.CastlingRights; got != pos.CastlingRights || back.CastlingRooks != pos.CastlingRooks {
+			t.Errorf("%s: %s does not read back the same rights", tt.name, pos.ToFEN())
+		}
+		if got := pos.castlingString(); got != tt.castling {
+			t.Errorf("%s: castling field %q, want %q", tt.name, got, tt.castling)
+		}
+	}
+
+	if _, err := ParseFEN("4k3/8/8/8/8/8/8/4K3 w KX - 0 1"); err == nil {
+		t.Error("Castling character X accepted")
+	}
+}
+
+// TestCastlingMoves verifies castling with rooks off their standard
+// squares, where the king and rook may land on each other's square.
+func TestCastlingMoves(t *testing.T) {
+	tests := []struct {
+		name, fen string
+		uci       string // Castling move as UCI notation may give it
+		after     string // FEN after it, "" if it is not legal
+	}{
+		{"standard", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			"e1g1", "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1"},
+		{"king onto its rook", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1",
+			"e8a8", "2kr3r/8/8/8/8/8/8/R3K2R w KQ - 1 2"},
+		{"king lands on the rook", "4k3/8/8/8/8/8/8/R1R1K3 w C - 0 1",
+			"e1c1", "4k3/8/8/8/8/8/8/R1KR4 b - - 1 1"},
+		{"rook lands on the king", "4k3/8/8/8/8/8/8/2RK4 w C - 0 1",
+			"d1c1", "4k3/8/8/8/8/8/8/2KR4 b - - 1 1"},
+		{"chess960 queen side", "r5kr/8/8/8/8/8/8/RK5R w HAha - 0 1",
+			"b1a1", "r5kr/8/8/8/8/8/8/2KR3R b kq - 1 1"},
+		{"chess960 king side", "r5kr/8/8/8/8/8/8/RK5R w HAha - 0 1",
+			"b1h1", "r5kr/8/8/8/8/8/8/R4RK1 b kq - 1 1"},
+		{"king moves right to the queen side", "rk2r3/8/8/8/8/8/8/RK2R3 w KQ - 0 1",
+			"b1a1", "rk2r3/8/8/8/8/8/8/2KRR3 b - - 1 1"},
+		// With the castling rook off c1, the rook on a1 attacks the king on c1
+		{"rook uncovers an attack", "4k3/8/8/8/8/8/8/r1RK4 w C - 0 1",
+			"d1c1", ""},
+		{"through check", "4k3/8/8/8/8/5r2/8/R3K2R w KQ - 0 1",
+			"e1g1", ""},
+	}
+	for _, tt := range tests {
+		pos, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		m, err := ParseMove(tt.uci, pos)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !m.IsCastling() {
+			t.Fatalf("%s: %s is not castling", tt.name, tt.uci)
+		}
+		legal := pos.GenerateLegalMoves().Contains(m)
+		if legal != (tt.after != "") {
+			t.Fatalf("%s: %v legal = %v", tt.name, m, legal)
+		}
+		if !legal {
+			continue
+		}
+		if !pos.PseudoLegal(m) {
+			t.Errorf("%s: %v not pseudo-legal", tt.name, m)
+		}
+		san := m.ToSAN(pos)
+		if back, err := ParseSAN(san, pos); err != nil || back != m {
+			t.Errorf("%s: %s reads back as %v, %v", tt.name, san, back, err)
+		}
+
+		before := pos.ToFEN()
+		undo := pos.MakeMove(m)
+		pos.UpdateCheckers()
+		if got := pos.ToFEN(); got != tt.after {
+			t.Errorf("%s: after %v got %s, want %s", tt.name, m, got, tt.after)
+		}
+		if err := pos.Validate(); err != nil {
+			t.Errorf("%s: after %v: %v", tt.name, m, err)
+		}
+		pos.UnmakeMove(m, undo)
+		if got := pos.ToFEN(); got != before {
+			t.Errorf("%s: unmake got %s, want %s", tt.name, got, before)
+		}
+	}
+}
+
+// TestCastlingWalk plays every line a few moves deep from positions with
+// unusual castling rooks, checking the position after each move.
+func TestCastlingWalk(t *testing.T) {
+	for _, fen := range []string{
+		"4k3/8/8/8/8/8/8/R1R1K3 w C - 0 1",
+		"r5kr/8/8/8/8/8/8/RK5R w HAha - 0 1",
+		"rk2r3/pppppppp/8/8/8/8/PPPPPPPP/RK2R3 w KQkq - 0 1",
+	} {
+		pos, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var walk func(depth int)
+		walk = func(depth int) {
+			if depth == 0 {
+				return
+			}
+			for _, m := range pos.GenerateLegalMoves().Slice() {
+				before := pos.ToFEN()
+				undo := pos.MakeMove(m)
+				pos.UpdateCheckers()
+				if err := pos.Validate(); err != nil {
+					t.Fatalf("%s %v: %v", before, m, err)
+				}
+				walk(depth - 1)
+				pos.UnmakeMove(m, undo)
+				if pos.ToFEN() != before {
+					t.Fatalf("%s %v: unmake gives %s", before, m, pos.ToFEN())
+				}
+			}
+		}
+		walk(3)
+	}
+}
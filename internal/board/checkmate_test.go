@@ -66,3 +66,21 @@ func TestNotCheckmate(t *testing.T) {
 		t.Error("Expected NOT checkmate but got true")
 	}
 }
+
+func TestPinnerOf(t *testing.T) {
+	// White bishop on d2 pinned against Ke1 by the black queen on a5; knight on g1 is free
+	pos, err := ParseFEN("4k3/8/8/q7/8/8/3B4/4K1N1 w - - 0 1")
+	if err != nil {
+		t.Fatal("Error parsing FEN:", err)
+	}
+
+	if got := pos.PinnerOf(D2); got != A5 {
+		t.Errorf("PinnerOf(d2) = %v, want a5", got)
+	}
+	if got := pos.PinnerOf(G1); got != NoSquare {
+		t.Errorf("PinnerOf(g1) = %v, want none", got)
+	}
+	if got := pos.PinnerOf(E1); got != NoSquare {
+		t.Errorf("PinnerOf(e1) = %v, want none for the king", got)
+	}
+}
@@ -0,0 +1,63 @@
+package board
+
+import "strings"
+
+// DiagramOptions controls how Diagram draws the board.
+type DiagramOptions struct {
+	Unicode     bool // Chess symbols instead of FEN letters
+	Coordinates bool // File letters and rank numbers around the board
+	Flipped     bool // Black at the bottom
+}
+
+// Diagram draws the board as text in Stockfish's "d" style:
+//
+//	+---+---+---+---+---+---+---+---+
+//	| r | n | b | q | k | b | n | r | 8
+//	+---+---+---+---+---+---+---+---+
+func (p *Position) Diagram(opts DiagramOptions) string {
+	const separator = " +---+---+---+---+---+---+---+---+\n"
+
+	var sb strings.Builder
+	sb.WriteString("\n")
+	sb.WriteString(separator)
+	for i := 0; i < 8; i++ {
+		rank := 7 - i
+		if opts.Flipped {
+			rank = i
+		}
+
+		for j := 0; j < 8; j++ {
+			file := j
+			if opts.Flipped {
+				file = 7 - j
+			}
+
+			symbol := " "
+			if piece := p.PieceAt(NewSquare(file, rank)); piece != NoPiece {
+				if opts.Unicode {
+					symbol = piece.Unicode()
+				} else {
+					symbol = piece.String()
+				}
+			}
+			sb.WriteString(" | ")
+			sb.WriteString(symbol)
+		}
+		sb.WriteString(" |")
+		if opts.Coordinates {
+			sb.WriteString(" ")
+			sb.WriteByte(byte('1' + rank))
+		}
+		sb.WriteString("\n")
+		sb.WriteString(separator)
+	}
+
+	if opts.Coordinates {
+		files := "   a   b   c   d   e   f   g   h\n"
+		if opts.Flipped {
+			files = "   h   g   f   e   d   c   b   a\n"
+		}
+		sb.WriteString(files)
+	}
+	return sb.String()
+}
@@ -0,0 +1,27 @@
+package board
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiagram(t *testing.T) {
+	pos := NewPosition()
+
+	d := pos.Diagram(DiagramOptions{Coordinates: true})
+	if !strings.Contains(d, " | r | n | b | q | k | b | n | r | 8\n") {
+		t.Errorf("Missing black back rank:\n%s", d)
+	}
+	if !strings.HasSuffix(d, "   a   b   c   d   e   f   g   h\n") {
+		t.Errorf("Missing file coordinates:\n%s", d)
+	}
+
+	d = pos.Diagram(DiagramOptions{Unicode: true, Flipped: true})
+	lines := strings.Split(strings.Trim(d, "\n"), "\n")
+	if len(lines) != 17 {
+		t.Fatalf("Expected 17 lines without coordinates, got %d", len(lines))
+	}
+	if lines[1] != " | ♖ | ♘ | ♗ | ♔ | ♕ | ♗ | ♘ | ♖ |" {
+		t.Errorf("Flipped diagram should start with White's back rank, got %q", lines[1])
+	}
+}
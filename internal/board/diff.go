@@ -0,0 +1,103 @@
+package board
+
+// PieceMove is a piece that left one square and appeared on another.
+type PieceMove struct {
+	Piece    Piece
+	From, To Square
+	Promoted Piece // Piece that arrived if this is a promotion, NoPiece otherwise
+}
+
+// PlacedPiece is a piece on a square.
+type PlacedPiece struct {
+	Piece  Piece
+	Square Square
+}
+
+// Diff describes the piece placement changes between two positions.
+// Applying Removed, then Moved, then Added to the first position's board
+// gives the second position's board.
+type Diff struct {
+	Moved   []PieceMove
+	Added   []PlacedPiece // Pieces that appeared without a matching removal
+	Removed []PlacedPiece // Pieces that disappeared, e.g. captures
+}
+
+// IsEmpty returns true if both positions have the same piece placement.
+func (d Diff) IsEmpty() bool {
+	return len(d.Moved) == 0 && len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// Diff returns the piece placement changes from p to other.
+// A piece that vanished from one square and appeared on another is reported
+// as moved, pairing the nearest squares first (so castling gives two moves and
+// an ordinary move one). A pawn that vanished and a piece of the same color
+// that appeared on the last rank form a promotion. Side to move, castling
+// rights and the other state fields are not compared.
+func (p *Position) Diff(other *Position) Diff {
+	var d Diff
+	var removed, added [2][6]Bitboard
+
+	for c := White; c <= Black; c++ {
+		for pt := Pawn; pt <= King; pt++ {
+			removed[c][pt] = p.Pieces[c][pt] &^ other.Pieces[c][pt]
+			added[c][pt] = other.Pieces[c][pt] &^ p.Pieces[c][pt]
+
+			// Same piece type: nearest pairs are moves
+			for removed[c][pt] != 0 && added[c][pt] != 0 {
+				from, to := nearestPair(removed[c][pt], added[c][pt])
+				removed[c][pt] = removed[c][pt].Clear(from)
+				added[c][pt] = added[c][pt].Clear(to)
+				d.Moved = append(d.Moved, PieceMove{Piece: NewPiece(pt, c), From: from, To: to, Promoted: NoPiece})
+			}
+		}
+
+		// Promotions: a pawn that vanished and a new piece on the last rank
+		lastRank := Rank8
+		if c == Black {
+			lastRank = Rank1
+		}
+		for pt := Knight; pt <= Queen; pt++ {
+			for removed[c][Pawn] != 0 && added[c][pt]&lastRank != 0 {
+				from, to := nearestPair(removed[c][Pawn], added[c][pt]&lastRank)
+				removed[c][Pawn] = removed[c][Pawn].Clear(from)
+				added[c][pt] = added[c][pt].Clear(to)
+				d.Moved = append(d.Moved, PieceMove{Piece: NewPiece(Pawn, c), From: from, To: to, Promoted: NewPiece(pt, c)})
+			}
+		}
+	}
+
+	// Everything left over was captured or placed
+	for c := White; c <= Black; c++ {
+		for pt := Pawn; pt <= King; pt++ {
+			for bb := removed[c][pt]; bb != 0; {
+				d.Removed = append(d.Removed, PlacedPiece{Piece: NewPiece(pt, c), Square: bb.PopLSB()})
+			}
+			for bb := added[c][pt]; bb != 0; {
+				d.Added = append(d.Added, PlacedPiece{Piece: NewPiece(pt, c), Square: bb.PopLSB()})
+			}
+		}
+	}
+
+	return d
+}
+
+// nearestPair returns the closest (from, to) squares of two non-empty sets,
+// by king distance. Ties go to the lowest squares.
+func nearestPair(from, to Bitboard) (Square, Square) {
+	bestFrom, bestTo, bestDist := NoSquare, NoSquare, 8
+	for f := from; f != 0; {
+		fsq := f.PopLSB()
+		for t := to; t != 0; {
+			tsq := t.PopLSB()
+			if dist := squareDistance(fsq, tsq); dist < bestDist {
+				bestFrom, bestTo, bestDist = fsq, tsq, dist
+			}
+		}
+	}
+	return bestFrom, bestTo
+}
+
+// squareDistance returns the king distance between two squares.
+func squareDistance(a, b Square) int {
+	return max(abs(a.File()-b.File()), abs(a.Rank()-b.Rank()))
+}
@@ -0,0 +1,80 @@
+package board
+
+import (
+	"testing"
+)
+
+// diffAfter plays a UCI move from a FEN and returns the diff between the two positions.
+func diffAfter(t *testing.T, fen, uci string) Diff {
+	t.Helper()
+	pos, err := ParseFEN(fen)
+	if err != nil {
+		t.Fatal("Error parsing FEN:", err)
+	}
+	m, err := ParseMove(uci, pos)
+	if err != nil {
+		t.Fatalf("Error parsing move %s: %v", uci, err)
+	}
+	after := pos.Copy()
+	after.MakeMove(m)
+	return pos.Diff(after)
+}
+
+func TestDiff(t *testing.T) {
+	// Quiet move: one piece moved
+	d := diffAfter(t, StartFEN, "g1f3")
+	if len(d.Moved) != 1 || len(d.Added) != 0 || len(d.Removed) != 0 {
+		t.Fatalf("Quiet move: unexpected diff %+v", d)
+	}
+	if mv := d.Moved[0]; mv.Piece != WhiteKnight || mv.From != G1 || mv.To != F3 || mv.Promoted != NoPiece {
+		t.Errorf("Quiet move: got %+v", mv)
+	}
+
+	// Capture: the captured piece is removed
+	d = diffAfter(t, "4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "e4d5")
+	if len(d.Moved) != 1 || len(d.Removed) != 1 || d.Removed[0] != (PlacedPiece{BlackPawn, D5}) {
+		t.Errorf("Capture: unexpected diff %+v", d)
+	}
+
+	// En passant: the captured pawn is not on the destination square
+	d = diffAfter(t, "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6")
+	if len(d.Moved) != 1 || len(d.Removed) != 1 || d.Removed[0] != (PlacedPiece{BlackPawn, D5}) {
+		t.Errorf("En passant: unexpected diff %+v", d)
+	}
+
+	// Castling: king and rook both move
+	d = diffAfter(t, "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1")
+	if len(d.Moved) != 2 || len(d.Added) != 0 || len(d.Removed) != 0 {
+		t.Fatalf("Castling: unexpected diff %+v", d)
+	}
+	for _, mv := range d.Moved {
+		if !(mv.Piece == WhiteKing && mv.From == E1 && mv.To == G1) &&
+			!(mv.Piece == WhiteRook && mv.From == H1 && mv.To == F1) {
+			t.Errorf("Castling: unexpected move %+v", mv)
+		}
+	}
+
+	// Capture promotion: pawn moves and becomes a queen, the rook is removed
+	d = diffAfter(t, "1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7b8q")
+	if len(d.Moved) != 1 || len(d.Removed) != 1 || len(d.Added) != 0 {
+		t.Fatalf("Promotion: unexpected diff %+v", d)
+	}
+	if mv := d.Moved[0]; mv.Piece != WhitePawn || mv.From != A7 || mv.To != B8 || mv.Promoted != WhiteQueen {
+		t.Errorf("Promotion: got %+v", mv)
+	}
+
+	// Identical positions
+	pos := NewPosition()
+	if d := pos.Diff(pos.Copy()); !d.IsEmpty() {
+		t.Errorf("Expected empty diff, got %+v", d)
+	}
+
+	// Unrelated positions: pieces without a counterpart are added
+	empty, err := ParseFEN("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal("Error parsing FEN:", err)
+	}
+	if d := empty.Diff(pos); len(d.Added) != 30 || len(d.Moved) != 0 || len(d.Removed) != 0 {
+		t.Errorf("Expected 30 added pieces, got %+v", d)
+	}
+}
@@ -0,0 +1,182 @@
+package board
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EPD is a position in Extended Position Description: the first four FEN
+// fields followed by operations such as
+//
+//	bm Qg6; id "WAC.001"; c0 "Qg6=10, Rf3=3";
+//
+// as used by test suites (WAC, STS). The move counters may be given as the
+// hmvc and fmvn operations, or, as many suites do, as plain FEN fields.
+type EPD struct {
+	FEN string              // Full FEN of the position
+	Ops map[string][]string // Operands by opcode, quotes removed
+}
+
+// ParseEPD parses one EPD line.
+func ParseEPD(line string) (EPD, error) {
+	var fen [4]string
+	rest := line
+	for i := range fen {
+		if fen[i], rest = cutField(rest); fen[i] == "" {
+			return EPD{}, fmt.Errorf("%w: need at least 4 fields, got %d", ErrInvalidFEN, i)
+		}
+	}
+
+	// Counters as FEN fields, before the operations
+	hmvc, fmvn := "0", "1"
+	if h, after := cutField(rest); isNumber(h) {
+		if f, after := cutField(after); isNumber(f) {
+			hmvc, fmvn, rest = h, f, after
+		}
+	}
+
+	ops, err := parseEPDOps(rest)
+	if err != nil {
+		return EPD{}, err
+	}
+	if v := ops["hmvc"]; len(v) == 1 {
+		hmvc = v[0]
+	}
+	if v := ops["fmvn"]; len(v) == 1 {
+		fmvn = v[0]
+	}
+
+	e := EPD{FEN: strings.Join(append(fen[:], hmvc, fmvn), " "), Ops: ops}
+	if _, err := ParseFEN(e.FEN); err != nil {
+		return EPD{}, err
+	}
+	return e, nil
+}
+
+// parseEPDOps parses the operations of an EPD line: an opcode and its
+// operands, ended by a semicolon (which the last operation may leave out).
+// Quoted operands may hold spaces and semicolons.
+func parseEPDOps(s string) (map[string][]string, error) {
+	ops := make(map[string][]string)
+	var tokens []string
+	var tok strings.Builder
+	quoted, inToken := false, false
+	end := func() {
+		if inToken {
+			tokens = append(tokens, tok.String())
+			tok.Reset()
+			inToken = false
+		}
+	}
+	endOp := func() {
+		end()
+		if len(tokens) > 0 {
+			ops[tokens[0]] = tokens[1:]
+		}
+		tokens = nil
+	}
+	for _, r := range s {
+		switch {
+		case r == '"':
+			end()
+			inToken = !quoted // An empty string is still an operand
+			quoted = !quoted
+		case quoted:
+			tok.WriteRune(r)
+		case r == ';':
+			endOp()
+		case r == ' ' || r == '\t':
+			end()
+		default:
+			tok.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated string in EPD operations %q", s)
+	}
+	endOp()
+	return ops, nil
+}
+
+// cutField returns the first whitespace-separated field of s and the rest.
+func cutField(s string) (field, rest string) {
+	s = strings.TrimLeft(s, " \t")
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// isNumber returns true if s is a non-negative decimal integer.
+func isNumber(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0
+}
+
+// Position returns the position of the EPD.
+func (e EPD) Position() (*Position, error) {
+	return ParseFEN(e.FEN)
+}
+
+// ID returns the id operation, or "" if there is none.
+func (e EPD) ID() string {
+	if v := e.Ops["id"]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Moves returns the moves of an operation such as bm (best moves) or am
+// (avoid moves), given in SAN or, as some suites do, in UCI notation.
+func (e EPD) Moves(opcode string) ([]Move, error) {
+	pos, err := e.Position()
+	if err != nil {
+		return nil, err
+	}
+	var moves []Move
+	for _, s := range e.Ops[opcode] {
+		m, err := ParseSAN(s, pos)
+		if err != nil {
+			var uciErr error
+			if m, uciErr = ParseMove(s, pos); uciErr != nil || !pos.GenerateLegalMoves().Contains(m) {
+				return nil, fmt.Errorf("%s move %q: %w", opcode, s, err)
+			}
+		}
+		moves = append(moves, m)
+	}
+	return moves, nil
+}
+
+// MovePoints returns the points a move scores in an STS-style c0 comment,
+// such as "Qg6=10, Rf3=3", and the most points any move scores. Moves the
+// comment does not name score 0. ok is false if there is no such comment.
+func (e EPD) MovePoints(m Move) (points, best int, ok bool) {
+	v := e.Ops["c0"]
+	if len(v) != 1 || !strings.Contains(v[0], "=") {
+		return 0, 0, false
+	}
+	pos, err := e.Position()
+	if err != nil {
+		return 0, 0, false
+	}
+	for _, entry := range strings.Split(v[0], ",") {
+		// The last '=' separates the points, as promotions use one too
+		entry = strings.TrimSpace(entry)
+		i := strings.LastIndexByte(entry, '=')
+		if i < 0 {
+			return 0, 0, false
+		}
+		san := entry[:i]
+		n, err := strconv.Atoi(strings.TrimSpace(entry[i+1:]))
+		if err != nil {
+			return 0, 0, false
+		}
+		best = max(best, n)
+		if mm, err := ParseSAN(strings.TrimSpace(san), pos); err == nil && mm == m {
+			points = n
+		}
+	}
+	return points, best, true
+}
@@ -0,0 +1,82 @@
+package board
+
+import "testing"
+
+// TestParseEPD verifies EPD lines of the common test suite layouts.
+func TestParseEPD(t *testing.T) {
+	tests := []struct {
+		line, fen, id string
+		bm, am        []string // Moves in UCI notation
+	}{
+		{`2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001";`,
+			"2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1", "WAC.001",
+			[]string{"g3g6"}, nil},
+		{`r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3 am Qe2 Ke2; bm Bb5 Bc4; id "counters"`,
+			"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", "counters",
+			[]string{"f1b5", "f1c4"}, []string{"d1e2", "e1e2"}},
+		{`4k3/8/8/8/8/8/8/4K2R w K - hmvc 7; fmvn 40; bm e1g1; id "uci; with semicolon";`,
+			"4k3/8/8/8/8/8/8/4K2R w K - 7 40", "uci; with semicolon",
+			[]string{"e1g1"}, nil},
+	}
+	for _, tt := range tests {
+		e, err := ParseEPD(tt.line)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.line, err)
+		}
+		if e.FEN != tt.fen || e.ID() != tt.id {
+			t.Errorf("Got FEN %q id %q, want %q %q", e.FEN, e.ID(), tt.fen, tt.id)
+		}
+		for opcode, want := range map[string][]string{"bm": tt.bm, "am": tt.am} {
+			moves, err := e.Moves(opcode)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.id, err)
+			}
+			if len(moves) != len(want) {
+				t.Fatalf("%s: %s %v, want %v", tt.id, opcode, moves, want)
+			}
+			for i, m := range moves {
+				if m.String() != want[i] {
+					t.Errorf("%s: %s %v, want %v", tt.id, opcode, moves, want)
+				}
+			}
+		}
+	}
+
+	for _, line := range []string{
+		"8/8/8/8 w - -",
+		`4k3/8/8/8/8/8/8/4K3 w - - id "unterminated;`,
+	} {
+		if _, err := ParseEPD(line); err == nil {
+			t.Errorf("ParseEPD(%q) accepted", line)
+		}
+	}
+	e, _ := ParseEPD(`4k3/8/8/8/8/8/8/4K3 w - - bm Qd1;`)
+	if _, err := e.Moves("bm"); err == nil {
+		t.Error("Illegal bm move accepted")
+	}
+}
+
+// TestEPDMovePoints verifies the scoring of STS-style c0 comments.
+func TestEPDMovePoints(t *testing.T) {
+	e, err := ParseEPD(`6k1/4P3/8/8/8/8/8/4K2R w K - bm e8=Q; c0 "e8=Q=10, O-O=3, Rh8+=1"; id "STS";`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos, _ := e.Position()
+	for _, tt := range []struct {
+		san    string
+		points int
+	}{{"e8=Q", 10}, {"O-O", 3}, {"Rh8+", 1}, {"Kd2", 0}} {
+		m, err := ParseSAN(tt.san, pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		points, best, ok := e.MovePoints(m)
+		if !ok || points != tt.points || best != 10 {
+			t.Errorf("%s: %d/%d points (%v), want %d/10", tt.san, points, best, ok, tt.points)
+		}
+	}
+	if _, _, ok := (EPD{}).MovePoints(NoMove); ok {
+		t.Error("Points found without a c0 comment")
+	}
+}
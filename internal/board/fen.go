@@ -1,6 +1,7 @@
 package board
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -9,19 +10,19 @@ import (
 // StartFEN is the FEN string for the starting position.
 const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
 
+// ErrInvalidFEN is wrapped by all ParseFEN errors, which say what is wrong
+// with the FEN.
+var ErrInvalidFEN = errors.New("invalid FEN")
+
 // ParseFEN parses a FEN string and returns a Position.
 func ParseFEN(fen string) (*Position, error) {
 	parts := strings.Fields(fen)
 	if len(parts) < 4 {
-		return nil, fmt.Errorf("invalid FEN: need at least 4 fields, got %d", len(parts))
+		return nil, fmt.Errorf("%w: need at least 4 fields, got %d", ErrInvalidFEN, len(parts))
 	}
 
-	pos := &Position{
-		EnPassant:      NoSquare,
-		FullMoveNumber: 1,
-	}
-	pos.KingSquare[White] = NoSquare
-	pos.KingSquare[Black] = NoSquare
+	pos := &Position{}
+	pos.Clear()
 
 	// Parse piece placement (field 0)
 	if err := parsePiecePlacement(pos, parts[0]); err != nil {
@@ -35,7 +36,7 @@ func ParseFEN(fen string) (*Position, error) {
 	case "b":
 		pos.SideToMove = Black
 	default:
-		return nil, fmt.Errorf("invalid side to move: %s", parts[1])
+		return nil, fmt.Errorf("%w: side to move %q", ErrInvalidFEN, parts[1])
 	}
 
 	// Parse castling rights (field 2)
@@ -47,7 +48,7 @@ func ParseFEN(fen string) (*Position, error) {
 	if parts[3] != "-" {
 		sq, err := ParseSquare(parts[3])
 		if err != nil {
-			return nil, fmt.Errorf("invalid en passant square: %s", parts[3])
+			return nil, fmt.Errorf("%w: en passant square %q", ErrInvalidFEN, parts[3])
 		}
 		pos.EnPassant = sq
 	}
@@ -56,7 +57,7 @@ func ParseFEN(fen string) (*Position, error) {
 	if len(parts) > 4 {
 		hmc, err := strconv.Atoi(parts[4])
 		if err != nil {
-			return nil, fmt.Errorf("invalid half-move clock: %s", parts[4])
+			return nil, fmt.Errorf("%w: half-move clock %q", ErrInvalidFEN, parts[4])
 		}
 		pos.HalfMoveClock = hmc
 	}
@@ -65,11 +66,18 @@ func ParseFEN(fen string) (*Position, error) {
 	if len(parts) > 5 {
 		fmn, err := strconv.Atoi(parts[5])
 		if err != nil {
-			return nil, fmt.Errorf("invalid full-move number: %s", parts[5])
+			return nil, fmt.Errorf("%w: full-move number %q", ErrInvalidFEN, parts[5])
 		}
 		pos.FullMoveNumber = fmn
 	}
 
+	// Move generation needs exactly one king a side
+	for _, c := range []Color{White, Black} {
+		if n := pos.Pieces[c][King].PopCount(); n != 1 {
+			return nil, fmt.Errorf("%w: %v has %d kings", ErrInvalidFEN, c, n)
+		}
+	}
+
 	// Update derived state
 	pos.updateOccupied()
 	pos.findKings()
@@ -84,7 +92,7 @@ func ParseFEN(fen string) (*Position, error) {
 func parsePiecePlacement(pos *Position, placement string) error {
 	ranks := strings.Split(placement, "/")
 	if len(ranks) != 8 {
-		return fmt.Errorf("invalid piece placement: need 8 ranks, got %d", len(ranks))
+		return fmt.Errorf("%w: need 8 ranks, got %d", ErrInvalidFEN, len(ranks))
 	}
 
 	for i, rankStr := range ranks {
@@ -93,7 +101,7 @@ func parsePiecePlacement(pos *Position, placement string) error {
 
 		for _, c := range rankStr {
 			if file > 7 {
-				return fmt.Errorf("too many squares in rank %d", rank+1)
+				return fmt.Errorf("%w: too many squares in rank %d", ErrInvalidFEN, rank+1)
 			}
 
 			if c >= '1' && c <= '8' {
@@ -103,7 +111,7 @@ func parsePiecePlacement(pos *Position, placement string) error {
 				// Place a piece
 				piece := PieceFromChar(byte(c))
 				if piece == NoPiece {
-					return fmt.Errorf("invalid piece character: %c", c)
+					return fmt.Errorf("%w: piece character %q", ErrInvalidFEN, c)
 				}
 				sq := NewSquare(file, rank)
 				pos.setPiece(piece, sq)
@@ -112,38 +120,111 @@ func parsePiecePlacement(pos *Position, placement string) error {
 		}
 
 		if file != 8 {
-			return fmt.Errorf("invalid number of squares in rank %d: got %d", rank+1, file)
+			return fmt.Errorf("%w: %d squares in rank %d", ErrInvalidFEN, file, rank+1)
 		}
 	}
 
 	return nil
 }
 
-// parseCastlingRights parses the castling rights section of a FEN string.
+// parseCastlingRights parses the castling rights section of a FEN string
+// and finds the rook each right castles with. KQkq name the outermost rook
+// on either side of the king (X-FEN); the letters A-H and a-h name the file
+// of the rook instead (Shredder-FEN), as Chess960 positions may need. A
+// right without the king on its back rank and a rook to castle with is
+// dropped: FENs in the wild often keep rights the position lost.
 func parseCastlingRights(pos *Position, castling string) error {
+	pos.CastlingRights = NoCastling
 	if castling == "-" {
-		pos.CastlingRights = NoCastling
 		return nil
 	}
 
-	for _, c := range castling {
-		switch c {
-		case 'K':
-			pos.CastlingRights |= WhiteKingSideCastle
-		case 'Q':
-			pos.CastlingRights |= WhiteQueenSideCastle
-		case 'k':
-			pos.CastlingRights |= BlackKingSideCastle
-		case 'q':
-			pos.CastlingRights |= BlackQueenSideCastle
+	for _, ch := range castling {
+		c := White
+		if ch >= 'a' && ch <= 'z' {
+			c = Black
+			ch -= 'a' - 'A'
+		}
+		ksq := pos.KingSquare[c]
+		if ksq != NoSquare && ksq.RelativeRank(c) != 0 {
+			ksq = NoSquare
+		}
+
+		rsq := NoSquare
+		switch {
+		case ch == 'K' || ch == 'Q':
+			if ksq != NoSquare {
+				rsq = pos.outermostRook(c, ksq, ch == 'K')
+			}
+		case ch >= 'A' && ch <= 'H':
+			if ksq != NoSquare {
+				sq := NewSquare(int(ch-'A'), ksq.Rank())
+				if pos.Pieces[c][Rook]&SquareBB(sq) != 0 {
+					rsq = sq
+				}
+			}
 		default:
-			return fmt.Errorf("invalid castling character: %c", c)
+			return fmt.Errorf("%w: castling character %q", ErrInvalidFEN, ch)
+		}
+		if rsq == NoSquare {
+			continue
 		}
+
+		kingSide := rsq > ksq
+		pos.CastlingRights |= castlingRight(c, kingSide)
+		pos.CastlingRooks[castlingIndex(c, kingSide)] = rsq
 	}
 
 	return nil
 }
 
+// outermostRook returns the rook of c furthest from the king on ksq on the
+// given side along the back rank, or NoSquare if there is none.
+func (p *Position) outermostRook(c Color, ksq Square, kingSide bool) Square {
+	rooks := p.Pieces[c][Rook] & RankMask[ksq.Rank()]
+	if kingSide {
+		rooks &^= SquareBB(ksq)<<1 - 1 // Files up to the king's
+		if rooks != 0 {
+			return rooks.MSB()
+		}
+	} else {
+		rooks &= SquareBB(ksq) - 1
+		if rooks != 0 {
+			return rooks.LSB()
+		}
+	}
+	return NoSquare
+}
+
+// castlingString returns the castling rights field of the FEN: KQkq for the
+// outermost rooks, as in standard chess, and the rook's file otherwise.
+func (p *Position) castlingString() string {
+	if p.CastlingRights == NoCastling {
+		return "-"
+	}
+	var sb strings.Builder
+	for c := White; c <= Black; c++ {
+		for _, kingSide := range []bool{true, false} {
+			rsq := p.CastlingRook(c, kingSide)
+			if rsq == NoSquare {
+				continue
+			}
+			ch := byte('A' + rsq.File())
+			if rsq == p.outermostRook(c, p.KingSquare[c], kingSide) {
+				ch = 'Q'
+				if kingSide {
+					ch = 'K'
+				}
+			}
+			if c == Black {
+				ch += 'a' - 'A'
+			}
+			sb.WriteByte(ch)
+		}
+	}
+	return sb.String()
+}
+
 // ToFEN returns the FEN representation of the position.
 func (p *Position) ToFEN() string {
 	var sb strings.Builder
@@ -182,7 +263,7 @@ func (p *Position) ToFEN() string {
 
 	// Castling rights
 	sb.WriteByte(' ')
-	sb.WriteString(p.CastlingRights.String())
+	sb.WriteString(p.castlingString())
 
 	// En passant
 	sb.WriteByte(' ')
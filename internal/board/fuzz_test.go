@@ -0,0 +1,140 @@
+package board
+
+import (
+	"errors"
+	"testing"
+)
+
+// Fuzz targets for the parsers that read user and file input. Besides the
+// seeds here, testdata/fuzz holds malformed inputs met in real files.
+// Run one with e.g. go test -fuzz=FuzzParseFEN ./internal/board
+
+// fuzzFENs are valid and malformed FEN seeds.
+var fuzzFENs = []string{
+	StartFEN,
+	sanTestFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/8/8/8/8/8/8/8 w - - 0 1",
+	"8/8/8/8/8/8/8/K6k w - -",
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR",
+	"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e9 0 1",
+	"kkkkkkkk/8/8/8/8/8/8/KKKKKKKK w KQkq - 0 1",
+	"4k3/8/8/8/8/8/8/4K2R w KQkq - -1 0",
+	"P3k3/8/8/8/8/8/8/p3K3 w - - 0 1",
+	"4k3/8/8/3pP3/8/8/8/4K3 w - a1 0 1",
+	"4k3/8/8/8/8/8/8/4K3 b - - 0 1 extra",
+}
+
+// FuzzParseFEN checks that any FEN either fails to parse with
+// ErrInvalidFEN or gives a position that moves can be generated, made and
+// written back from.
+func FuzzParseFEN(f *testing.F) {
+	for _, fen := range fuzzFENs {
+		f.Add(fen)
+	}
+	f.Fuzz(func(t *testing.T, fen string) {
+		pos, err := ParseFEN(fen)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidFEN) {
+				t.Fatalf("ParseFEN(%q) error is not ErrInvalidFEN: %v", fen, err)
+			}
+			return
+		}
+		out := pos.ToFEN()
+		if _, err := ParseFEN(out); err != nil {
+			t.Fatalf("ToFEN of %q gives unparsable %q: %v", fen, out, err)
+		}
+		moves := pos.GenerateLegalMoves()
+		for i := 0; i < moves.Len(); i++ {
+			m := moves.Get(i)
+			_ = m.ToSAN(pos)
+			p := pos.Copy()
+			p.MakeMove(m)
+		}
+	})
+}
+
+// FuzzParseMove checks that SAN move text is either rejected as invalid or
+// illegal or gives a legal move of the position, and that UCI move text
+// gives a move that can be made.
+func FuzzParseMove(f *testing.F) {
+	for _, s := range []string{"e2e4", "e7e8q", "e1g1", "a1a9", "e2e4x", "Nf3", "exd5", "e8=", "e8=Q+", "O-O-O", "0-0", "Qxx", "", "=", "x"} {
+		f.Add(StartFEN, s)
+		f.Add(sanTestFEN, s)
+	}
+	f.Fuzz(func(t *testing.T, fen, s string) {
+		pos, err := ParseFEN(fen)
+		if err != nil {
+			return
+		}
+		legal := pos.GenerateLegalMoves()
+		m, err := ParseSAN(s, pos)
+		switch {
+		case err == nil && !legal.Contains(m):
+			t.Fatalf("ParseSAN(%q) in %q gives illegal move %v", s, fen, m)
+		case err != nil && !errors.Is(err, ErrInvalidMove) && !errors.Is(err, ErrIllegalMove):
+			t.Fatalf("ParseSAN(%q) in %q error is of no move kind: %v", s, fen, err)
+		}
+		if m, err := ParseMove(s, pos); err == nil && legal.Contains(m) {
+			p := pos.Copy()
+			p.MakeMove(m)
+		}
+	})
+}
+
+// FuzzMakeUnmake plays a sequence of moves chosen by the fuzzer's bytes from
+// a valid position, checking Validate after every move, then takes them all
+// back and checks that the starting position is restored. A zero byte plays
+// a null move when not in check.
+func FuzzMakeUnmake(f *testing.F) {
+	for _, fen := range fuzzFENs {
+		f.Add(fen, []byte{0, 7, 13, 1, 250, 42, 3, 0, 99, 18})
+	}
+	f.Add("8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []byte{5, 17, 2, 9, 31, 4, 4, 11, 6, 1, 8, 3})
+	f.Add("r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	f.Fuzz(func(t *testing.T, fen string, path []byte) {
+		pos, err := ParseFEN(fen)
+		if err != nil || pos.Validate() != nil {
+			return
+		}
+		start := pos.ToFEN()
+		startHash := pos.Hash
+
+		type played struct {
+			move Move
+			undo UndoInfo
+			null NullMoveUndo
+		}
+		var line []played
+		for _, b := range path {
+			if b == 0 && !pos.InCheck() {
+				line = append(line, played{move: NoMove, null: pos.MakeNullMove()})
+			} else {
+				moves := pos.GenerateLegalMoves()
+				if moves.Len() == 0 {
+					break
+				}
+				m := moves.Get(int(b) % moves.Len())
+				line = append(line, played{move: m, undo: pos.MakeMove(m)})
+			}
+			if err := pos.Validate(); err != nil {
+				t.Fatalf("%s: invalid after %v: %v", start, line[len(line)-1].move, err)
+			}
+		}
+
+		for i := len(line) - 1; i >= 0; i-- {
+			if line[i].move == NoMove {
+				pos.UnmakeNullMove(line[i].null)
+			} else {
+				pos.UnmakeMove(line[i].move, line[i].undo)
+			}
+		}
+		if err := pos.Validate(); err != nil {
+			t.Fatalf("%s: invalid after taking back %d moves: %v", start, len(line), err)
+		}
+		if got := pos.ToFEN(); got != start || pos.Hash != startHash {
+			t.Fatalf("Taking back %d moves gives %s (hash %x), want %s (hash %x)", len(line), got, pos.Hash, start, startHash)
+		}
+	})
+}
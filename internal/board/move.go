@@ -1,6 +1,9 @@
 package board
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // Move encodes a chess move in 16 bits:
 // bits 0-5:   from square (0-63)
@@ -72,6 +75,13 @@ func (m Move) IsCastling() bool {
 	return m.Flag() == FlagCastling
 }
 
+// IsKingSideCastling returns true if this is castling to the king side
+// (O-O). The king lands on the g-file then, and on the c-file when castling
+// to the queen side; in Chess960 either may move it left or right.
+func (m Move) IsKingSideCastling() bool {
+	return m.IsCastling() && m.To().File() == 6
+}
+
 // IsEnPassant returns true if this is an en passant capture.
 func (m Move) IsEnPassant() bool {
 	return m.Flag() == FlagEnPassant
@@ -106,20 +116,27 @@ func (m Move) String() string {
 	return s
 }
 
-// ParseMove parses a UCI format move string.
+// Move parsing errors, wrapped by the errors of ParseMove and ParseSAN.
+var (
+	ErrInvalidMove = errors.New("invalid move") // Not a move in the notation
+	ErrIllegalMove = errors.New("illegal move") // A move, but not one of the position
+)
+
+// ParseMove parses a UCI format move string. The move is not checked to be
+// legal beyond its piece being on the board.
 func ParseMove(s string, pos *Position) (Move, error) {
 	if len(s) < 4 {
-		return NoMove, fmt.Errorf("invalid move string: %s", s)
+		return NoMove, fmt.Errorf("%w %q", ErrInvalidMove, s)
 	}
 
 	from, err := ParseSquare(s[0:2])
 	if err != nil {
-		return NoMove, err
+		return NoMove, fmt.Errorf("%w %q: %w", ErrInvalidMove, s, err)
 	}
 
 	to, err := ParseSquare(s[2:4])
 	if err != nil {
-		return NoMove, err
+		return NoMove, fmt.Errorf("%w %q: %w", ErrInvalidMove, s, err)
 	}
 
 	// Check for promotion
@@ -135,7 +152,7 @@ func ParseMove(s string, pos *Position) (Move, error) {
 		case 'q':
 			promo = Queen
 		default:
-			return NoMove, fmt.Errorf("invalid promotion piece: %c", s[4])
+			return NoMove, fmt.Errorf("%w %q: promotion piece %q", ErrInvalidMove, s, s[4])
 		}
 		return NewPromotion(from, to, promo), nil
 	}
@@ -143,14 +160,23 @@ func ParseMove(s string, pos *Position) (Move, error) {
 	// Detect special moves
 	piece := pos.PieceAt(from)
 	if piece == NoPiece {
-		return NoMove, fmt.Errorf("no piece at %s", from)
+		return NoMove, fmt.Errorf("%w %q: no piece on %v", ErrIllegalMove, s, from)
 	}
 
 	pt := piece.Type()
 
-	// Castling
-	if pt == King && abs(int(to)-int(from)) == 2 {
-		return NewCastling(from, to), nil
+	// Castling: the king moves two squares, or onto its own castling rook
+	// as Chess960 notation has it
+	if pt == King {
+		if abs(int(to)-int(from)) == 2 && from.Rank() == to.Rank() && (to.File() == 2 || to.File() == 6) {
+			return NewCastling(from, to), nil
+		}
+		for _, kingSide := range []bool{true, false} {
+			if rsq := pos.CastlingRook(piece.Color(), kingSide); rsq == to {
+				kingTo, _ := castlingTargets(from, kingSide)
+				return NewCastling(from, kingTo), nil
+			}
+		}
 	}
 
 	// En passant
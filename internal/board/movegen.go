@@ -217,55 +217,55 @@ func (p *Position) generateKingMoves(ml *MoveList, us Color) {
 	}
 }
 
-// generateCastlingMoves generates castling moves.
+// generateCastlingMoves generates castling moves. The king lands on the g-
+// or c-file and the rook next to it on the f- or d-file, from wherever they
+// stand (see CastlingRooks): the squares they cross and land on must be
+// empty but for the two of them, and the king may not start on, cross or
+// land on an attacked square.
 func (p *Position) generateCastlingMoves(ml *MoveList, us Color) {
 	them := us.Other()
+	ksq := p.KingSquare[us]
 
-	if us == White {
-		// Kingside (O-O)
-		if p.CastlingRights&WhiteKingSideCastle != 0 {
-			// Check squares are empty (f1, g1)
-			if p.AllOccupied&((1<<F1)|(1<<G1)) == 0 {
-				// Check king doesn't pass through check (e1, f1, g1)
-				if !p.IsSquareAttacked(E1, them) && !p.IsSquareAttacked(F1, them) && !p.IsSquareAttacked(G1, them) {
-					ml.Add(NewCastling(E1, G1))
-				}
-			}
+	for _, kingSide := range [2]bool{true, false} {
+		rsq := p.CastlingRook(us, kingSide)
+		if rsq == NoSquare {
+			continue
 		}
-
-		// Queenside (O-O-O)
-		if p.CastlingRights&WhiteQueenSideCastle != 0 {
-			// Check squares are empty (b1, c1, d1)
-			if p.AllOccupied&((1<<B1)|(1<<C1)|(1<<D1)) == 0 {
-				// Check king doesn't pass through check (c1, d1, e1)
-				if !p.IsSquareAttacked(E1, them) && !p.IsSquareAttacked(D1, them) && !p.IsSquareAttacked(C1, them) {
-					ml.Add(NewCastling(E1, C1))
-				}
-			}
+		kingTo, rookTo := castlingTargets(ksq, kingSide)
+		if kingTo == ksq {
+			continue // Castling moves are king moves; one in place cannot be told apart
 		}
-	} else {
-		// Kingside (O-O)
-		if p.CastlingRights&BlackKingSideCastle != 0 {
-			// Check squares are empty (f8, g8)
-			if p.AllOccupied&((1<<F8)|(1<<G8)) == 0 {
-				// Check king doesn't pass through check
-				if !p.IsSquareAttacked(E8, them) && !p.IsSquareAttacked(F8, them) && !p.IsSquareAttacked(G8, them) {
-					ml.Add(NewCastling(E8, G8))
-				}
-			}
+
+		// The king and the rook make way for each other
+		occ := p.AllOccupied &^ (SquareBB(ksq) | SquareBB(rsq))
+		kingPath := Between(ksq, kingTo) | SquareBB(kingTo)
+		if occ&(kingPath|Between(rsq, rookTo)|SquareBB(rookTo)) != 0 {
+			continue
 		}
 
-		// Queenside (O-O-O)
-		if p.CastlingRights&BlackQueenSideCastle != 0 {
-			// Check squares are empty (b8, c8, d8)
-			if p.AllOccupied&((1<<B8)|(1<<C8)|(1<<D8)) == 0 {
-				// Check king doesn't pass through check
-				if !p.IsSquareAttacked(E8, them) && !p.IsSquareAttacked(D8, them) && !p.IsSquareAttacked(C8, them) {
-					ml.Add(NewCastling(E8, C8))
-				}
+		if p.AttackersByColor(ksq, them, p.AllOccupied) != 0 {
+			return // In check
+		}
+		safe := true
+		for path := kingPath; path != 0; {
+			if p.AttackersByColor(path.PopLSB(), them, occ) != 0 {
+				safe = false
+				break
 			}
 		}
+		if safe {
+			ml.Add(NewCastling(ksq, kingTo))
+		}
+	}
+}
+
+// castlingTargets returns the squares the king on ksq and its rook land on
+// when castling to the given side.
+func castlingTargets(ksq Square, kingSide bool) (kingTo, rookTo Square) {
+	if kingSide {
+		return NewSquare(6, ksq.Rank()), NewSquare(5, ksq.Rank())
 	}
+	return NewSquare(2, ksq.Rank()), NewSquare(3, ksq.Rank())
 }
 
 // generateCaptures generates capture moves only.
@@ -741,8 +741,15 @@ func (p *Position) MakeMove(m Move) UndoInfo {
 	// Clear en passant
 	p.EnPassant = NoSquare
 
-	// Handle captures
-	if m.IsEnPassant() {
+	// Handle castling
+	if m.IsCastling() {
+		kingSide := m.IsKingSideCastling()
+		rookFrom := p.CastlingRooks[castlingIndex(us, kingSide)]
+		_, rookTo := castlingTargets(from, kingSide)
+		p.castle(us, from, to, rookFrom, rookTo)
+		p.Hash ^= zobristPiece[us][King][from] ^ zobristPiece[us][King][to]
+		p.Hash ^= zobristPiece[us][Rook][rookFrom] ^ zobristPiece[us][Rook][rookTo]
+	} else if m.IsEnPassant() {
 		// En passant capture
 		var capturedSq Square
 		if us == White {
@@ -763,10 +770,12 @@ func (p *Position) MakeMove(m Move) UndoInfo {
 		}
 	}
 
-	// Move the piece
-	p.movePiece(from, to)
-	p.Hash ^= zobristPiece[us][pt][from]
-	p.Hash ^= zobristPiece[us][pt][to]
+	// Move the piece (the king has already moved when castling)
+	if !m.IsCastling() {
+		p.movePiece(from, to)
+		p.Hash ^= zobristPiece[us][pt][from]
+		p.Hash ^= zobristPiece[us][pt][to]
+	}
 
 	// Update pawn key for pawn moves
 	if pt == Pawn {
@@ -786,23 +795,6 @@ func (p *Position) MakeMove(m Move) UndoInfo {
 		p.PawnKey ^= zobristPiece[us][Pawn][to]
 	}
 
-	// Handle castling
-	if m.IsCastling() {
-		var rookFrom, rookTo Square
-		if to > from {
-			// Kingside
-			rookFrom = NewSquare(7, from.Rank())
-			rookTo = NewSquare(5, from.Rank())
-		} else {
-			// Queenside
-			rookFrom = NewSquare(0, from.Rank())
-			rookTo = NewSquare(3, from.Rank())
-		}
-		p.movePiece(rookFrom, rookTo)
-		p.Hash ^= zobristPiece[us][Rook][rookFrom]
-		p.Hash ^= zobristPiece[us][Rook][rookTo]
-	}
-
 	// Update castling rights
 	if pt == King {
 		if us == White {
@@ -812,18 +804,13 @@ func (p *Position) MakeMove(m Move) UndoInfo {
 		}
 	}
 
-	// Rook moves or captures affect castling
-	if from == A1 || to == A1 {
-		p.CastlingRights &^= WhiteQueenSideCastle
-	}
-	if from == H1 || to == H1 {
-		p.CastlingRights &^= WhiteKingSideCastle
-	}
-	if from == A8 || to == A8 {
-		p.CastlingRights &^= BlackQueenSideCastle
-	}
-	if from == H8 || to == H8 {
-		p.CastlingRights &^= BlackKingSideCastle
+	// Moving or capturing a castling rook loses its right
+	if p.CastlingRights != NoCastling {
+		for i, rsq := range p.CastlingRooks {
+			if rsq == from || rsq == to {
+				p.CastlingRights &^= CastlingRights(1) << i
+			}
+		}
 	}
 
 	// Update hash for new castling rights
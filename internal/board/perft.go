@@ -0,0 +1,236 @@
+package board
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Perft counts the leaf nodes of the legal move tree to the given depth.
+// This is the standard way to verify move generation correctness.
+func Perft(p *Position, depth int) uint64 {
+	if depth == 0 {
+		return 1
+	}
+
+	moves := p.GenerateLegalMoves()
+	if depth == 1 {
+		return uint64(moves.Len())
+	}
+
+	var nodes uint64
+	for i := 0; i < moves.Len(); i++ {
+		m := moves.Get(i)
+		undo := p.MakeMove(m)
+		nodes += Perft(p, depth-1)
+		p.UnmakeMove(m, undo)
+	}
+	return nodes
+}
+
+// PerftDivideEntry is the node count below one root move.
+type PerftDivideEntry struct {
+	Move  Move
+	Nodes uint64
+}
+
+// PerftDivide returns the perft node count below each root move, in move
+// generation order. Comparing it with another engine's divide output narrows
+// a move generation bug down to a single move.
+func PerftDivide(p *Position, depth int) []PerftDivideEntry {
+	if depth < 1 {
+		return nil
+	}
+
+	moves := p.GenerateLegalMoves()
+	entries := make([]PerftDivideEntry, 0, moves.Len())
+	for i := 0; i < moves.Len(); i++ {
+		m := moves.Get(i)
+		undo := p.MakeMove(m)
+		entries = append(entries, PerftDivideEntry{Move: m, Nodes: Perft(p, depth-1)})
+		p.UnmakeMove(m, undo)
+	}
+	return entries
+}
+
+// PerftParallel counts the same nodes as Perft with the root moves split
+// over threads goroutines, each with its own copy of the position and node
+// counter. A non-nil table caches subtree counts (see PerftTable); it may be
+// shared by the goroutines.
+func PerftParallel(p *Position, depth, threads int, t *PerftTable) uint64 {
+	if depth <= 1 {
+		return Perft(p, depth)
+	}
+
+	var nodes uint64
+	for _, e := range PerftDivideParallel(p, depth, threads, t) {
+		nodes += e.Nodes
+	}
+	return nodes
+}
+
+// PerftDivideParallel is PerftDivide with the root moves split over threads
+// goroutines, and an optional hash table as in PerftParallel.
+func PerftDivideParallel(p *Position, depth, threads int, t *PerftTable) []PerftDivideEntry {
+	if depth < 1 {
+		return nil
+	}
+
+	moves := p.GenerateLegalMoves()
+	entries := make([]PerftDivideEntry, moves.Len())
+	for i := range entries {
+		entries[i].Move = moves.Get(i)
+	}
+
+	// Each goroutine takes the next root move until none are left, so a
+	// goroutine with small subtrees does not sit idle
+	var next atomic.Int32
+	var wg sync.WaitGroup
+	for range max(1, min(threads, len(entries))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pos := p.Copy()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= len(entries) {
+					return
+				}
+				m := entries[i].Move
+				undo := pos.MakeMove(m)
+				entries[i].Nodes = PerftHashed(pos, depth-1, t)
+				pos.UnmakeMove(m, undo)
+			}
+		}()
+	}
+	wg.Wait()
+	return entries
+}
+
+// PerftHashed counts the same nodes as Perft, looking up the counts of
+// positions reached again through transpositions in t. A nil table disables
+// hashing.
+func PerftHashed(p *Position, depth int, t *PerftTable) uint64 {
+	if t == nil || depth < 2 {
+		return Perft(p, depth)
+	}
+	if nodes, ok := t.probe(p.Hash, depth); ok {
+		return nodes
+	}
+
+	moves := p.GenerateLegalMoves()
+	var nodes uint64
+	for i := 0; i < moves.Len(); i++ {
+		m := moves.Get(i)
+		undo := p.MakeMove(m)
+		nodes += PerftHashed(p, depth-1, t)
+		p.UnmakeMove(m, undo)
+	}
+	t.store(p.Hash, depth, nodes)
+	return nodes
+}
+
+// PerftTable caches perft subtree counts by position key and depth. It is
+// separate from the search transposition table, so perft runs do not evict
+// search results, and it is safe for concurrent use: each entry stores its
+// key XORed with its data, so a torn read by another goroutine fails the key
+// check instead of returning a wrong count.
+type PerftTable struct {
+	entries []perftEntry
+	mask    uint64
+}
+
+// perftEntry is one slot of a PerftTable. data packs the node count above
+// the depth in the low 8 bits.
+type perftEntry struct {
+	check atomic.Uint64 // key ^ data
+	data  atomic.Uint64
+}
+
+// perftEntrySize is the size of a perftEntry in bytes.
+const perftEntrySize = 16
+
+// NewPerftTable creates a perft table of about sizeMB megabytes, rounded
+// down to a power of two entries.
+func NewPerftTable(sizeMB int) *PerftTable {
+	n := uint64(1)
+	for n*2*perftEntrySize <= uint64(max(sizeMB, 1))<<20 {
+		n *= 2
+	}
+	return &PerftTable{entries: make([]perftEntry, n), mask: n - 1}
+}
+
+// probe returns the stored node count of a position at a depth.
+func (t *PerftTable) probe(key uint64, depth int) (uint64, bool) {
+	e := &t.entries[key&t.mask]
+	data := e.data.Load()
+	if e.check.Load()^data != key || int(data&0xFF) != depth {
+		return 0, false
+	}
+	return data >> 8, true
+}
+
+// store saves the node count of a position at a depth, replacing the slot.
+func (t *PerftTable) store(key uint64, depth int, nodes uint64) {
+	e := &t.entries[key&t.mask]
+	data := nodes<<8 | uint64(depth)
+	e.data.Store(data)
+	e.check.Store(key ^ data)
+}
+
+// PerftCase is a position with known perft results.
+type PerftCase struct {
+	Name   string
+	FEN    string
+	Counts []uint64 // Counts[d-1] is the node count at depth d
+}
+
+// PerftSuite is the standard set of perft positions from the Chess
+// Programming Wiki, covering castling, en passant, promotions and pins.
+var PerftSuite = []PerftCase{
+	{"Start position", StartFEN,
+		[]uint64{20, 400, 8902, 197281, 4865609, 119060324}},
+	{"Kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		[]uint64{48, 2039, 97862, 4085603, 193690690}},
+	{"Position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		[]uint64{14, 191, 2812, 43238, 674624, 11030083}},
+	{"Position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		[]uint64{6, 264, 9467, 422333, 15833292}},
+	{"Position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		[]uint64{44, 1486, 62379, 2103487, 89941194}},
+	{"Position 6", "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		[]uint64{46, 2079, 89890, 3894594, 164075551}},
+}
+
+// ParsePerftEPD parses a line of a perft suite in the common EPD format:
+//
+//	<fen> ;D1 20 ;D2 400 ;D3 8902
+//
+// Depths must be consecutive from 1.
+func ParsePerftEPD(line string) (PerftCase, error) {
+	parts := strings.Split(line, ";")
+	fen := strings.TrimSpace(parts[0])
+	if _, err := ParseFEN(fen); err != nil {
+		return PerftCase{}, err
+	}
+
+	c := PerftCase{Name: fen, FEN: fen}
+	for _, part := range parts[1:] {
+		fields := strings.Fields(part)
+		if len(fields) != 2 || len(fields[0]) < 2 || fields[0][0] != 'D' {
+			return PerftCase{}, fmt.Errorf("invalid perft entry %q", strings.TrimSpace(part))
+		}
+		depth, err := strconv.Atoi(fields[0][1:])
+		if err != nil || depth != len(c.Counts)+1 {
+			return PerftCase{}, fmt.Errorf("invalid perft depth %q", fields[0])
+		}
+		nodes, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return PerftCase{}, fmt.Errorf("invalid perft count %q", fields[1])
+		}
+		c.Counts = append(c.Counts, nodes)
+	}
+	return c, nil
+}
@@ -2,26 +2,9 @@ package board
 
 import "testing"
 
-// Perft counts the number of leaf nodes at the given depth.
-// This is the standard way to verify move generation correctness.
+// perft returns Perft as int64 for the expected counts below.
 func perft(p *Position, depth int) int64 {
-	if depth == 0 {
-		return 1
-	}
-
-	moves := p.GenerateLegalMoves()
-	if depth == 1 {
-		return int64(moves.Len())
-	}
-
-	var nodes int64
-	for i := 0; i < moves.Len(); i++ {
-		m := moves.Get(i)
-		undo := p.MakeMove(m)
-		nodes += perft(p, depth-1)
-		p.UnmakeMove(m, undo)
-	}
-	return nodes
+	return int64(Perft(p, depth))
 }
 
 // TestPerftStartingPosition tests move generation from the starting position.
@@ -146,3 +129,90 @@ func TestPerftEnPassantPin(t *testing.T) {
 		})
 	}
 }
+
+// TestPerftSuite runs the standard suite to a shallow depth.
+func TestPerftSuite(t *testing.T) {
+	const maxDepth = 3
+	for _, c := range PerftSuite {
+		pos, err := ParseFEN(c.FEN)
+		if err != nil {
+			t.Fatalf("%s: failed to parse FEN: %v", c.Name, err)
+		}
+		for d := 1; d <= maxDepth && d <= len(c.Counts); d++ {
+			if got := Perft(pos, d); got != c.Counts[d-1] {
+				t.Errorf("%s: perft(%d) = %d, want %d", c.Name, d, got, c.Counts[d-1])
+			}
+		}
+	}
+}
+
+// TestPerftDivide verifies that divide counts add up to the perft total.
+func TestPerftDivide(t *testing.T) {
+	pos, err := ParseFEN(PerftSuite[1].FEN)
+	if err != nil {
+		t.Fatalf("Failed to parse FEN: %v", err)
+	}
+
+	entries := PerftDivide(pos, 3)
+	if len(entries) != 48 {
+		t.Fatalf("Expected 48 root moves, got %d", len(entries))
+	}
+	var total uint64
+	for _, e := range entries {
+		total += e.Nodes
+	}
+	if total != 97862 {
+		t.Errorf("Divide total = %d, want 97862", total)
+	}
+}
+
+func TestParsePerftEPD(t *testing.T) {
+	c, err := ParsePerftEPD("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 ;D1 20 ;D2 400 ;D3 8902")
+	if err != nil {
+		t.Fatalf("ParsePerftEPD failed: %v", err)
+	}
+	if len(c.Counts) != 3 || c.Counts[2] != 8902 {
+		t.Errorf("Unexpected counts %v", c.Counts)
+	}
+
+	if _, err := ParsePerftEPD(StartFEN + " ;D2 400"); err == nil {
+		t.Errorf("Expected an error for a missing depth 1")
+	}
+	if _, err := ParsePerftEPD(StartFEN + " ;D1 x"); err == nil {
+		t.Errorf("Expected an error for an invalid count")
+	}
+}
+
+// TestPerftParallel checks the parallel and hashed counts against the suite.
+func TestPerftParallel(t *testing.T) {
+	const maxDepth = 4
+	table := NewPerftTable(16)
+	for _, c := range PerftSuite {
+		pos, err := ParseFEN(c.FEN)
+		if err != nil {
+			t.Fatalf("%s: failed to parse FEN: %v", c.Name, err)
+		}
+		for d := 1; d <= maxDepth && d <= len(c.Counts); d++ {
+			if got := PerftParallel(pos, d, 4, nil); got != c.Counts[d-1] {
+				t.Errorf("%s: parallel perft(%d) = %d, want %d", c.Name, d, got, c.Counts[d-1])
+			}
+			if got := PerftParallel(pos, d, 4, table); got != c.Counts[d-1] {
+				t.Errorf("%s: hashed perft(%d) = %d, want %d", c.Name, d, got, c.Counts[d-1])
+			}
+		}
+	}
+}
+
+// TestPerftHashed checks that a table shared by depths and runs gives the
+// same counts as plain perft.
+func TestPerftHashed(t *testing.T) {
+	pos := NewPosition()
+	table := NewPerftTable(1)
+	for range 2 {
+		for d := 1; d <= 4; d++ {
+			if got, want := PerftHashed(pos, d, table), Perft(pos, d); got != want {
+				t.Errorf("hashed perft(%d) = %d, want %d", d, got, want)
+			}
+		}
+	}
+}
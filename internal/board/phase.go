@@ -0,0 +1,78 @@
+package board
+
+// GamePhase is the stage of the game a position belongs to.
+type GamePhase int
+
+const (
+	PhaseOpening GamePhase = iota
+	PhaseMiddlegame
+	PhaseEndgame
+)
+
+// String returns the lowercase name of the phase.
+func (ph GamePhase) String() string {
+	switch ph {
+	case PhaseOpening:
+		return "opening"
+	case PhaseMiddlegame:
+		return "middlegame"
+	case PhaseEndgame:
+		return "endgame"
+	}
+	return "unknown"
+}
+
+// Phase classification thresholds. Non-pawn material is counted in the
+// evaluation's phase units: 1 per minor piece, 2 per rook, 4 per queen,
+// 24 in the starting position.
+const (
+	endgameMaterial    = 10 // At most this much left: about a rook and two minors each
+	openingMaterial    = 20 // The opening ends once more has been traded
+	openingMaxMove     = 15 // Nor does it last beyond this move
+	openingUndeveloped = 3  // Minor pieces still on their home squares, both sides
+)
+
+// homeMinors are the starting squares of the knights and bishops.
+var homeMinors = [2]Bitboard{
+	SquareBB(B1) | SquareBB(C1) | SquareBB(F1) | SquareBB(G1),
+	SquareBB(B8) | SquareBB(C8) | SquareBB(F8) | SquareBB(G8),
+}
+
+// PhaseMaterial returns the non-pawn material of both sides in phase units
+// (24 in the starting position, 0 with only kings and pawns).
+func (p *Position) PhaseMaterial() int {
+	material := 0
+	for c := White; c <= Black; c++ {
+		material += (p.Pieces[c][Knight] | p.Pieces[c][Bishop]).PopCount()
+		material += 2 * p.Pieces[c][Rook].PopCount()
+		material += 4 * p.Pieces[c][Queen].PopCount()
+	}
+	return material
+}
+
+// Phase classifies the position by material, castling and development:
+//   - endgame: little non-pawn material is left
+//   - opening: few trades, an early move number, and either minor pieces
+//     still at home or both kings still able to castle
+//   - middlegame: everything else
+func (p *Position) Phase() GamePhase {
+	material := p.PhaseMaterial()
+	if material <= endgameMaterial {
+		return PhaseEndgame
+	}
+	if material < openingMaterial || p.FullMoveNumber > openingMaxMove {
+		return PhaseMiddlegame
+	}
+
+	undeveloped := 0
+	for c := White; c <= Black; c++ {
+		minors := p.Pieces[c][Knight] | p.Pieces[c][Bishop]
+		undeveloped += (minors & homeMinors[c]).PopCount()
+	}
+	whiteCanCastle := p.CastlingRights&(WhiteKingSideCastle|WhiteQueenSideCastle) != 0
+	blackCanCastle := p.CastlingRights&(BlackKingSideCastle|BlackQueenSideCastle) != 0
+	if undeveloped >= openingUndeveloped || (whiteCanCastle && blackCanCastle) {
+		return PhaseOpening
+	}
+	return PhaseMiddlegame
+}
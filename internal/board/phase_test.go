@@ -0,0 +1,35 @@
+package board
+
+import "testing"
+
+// TestPhase verifies the game phase classification of typical positions.
+func TestPhase(t *testing.T) {
+	tests := []struct {
+		fen   string
+		phase GamePhase
+	}{
+		{StartFEN, PhaseOpening},
+		// Italian, both sides still able to castle
+		{"r1bqk2r/pppp1ppp/2n2n2/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4", PhaseOpening},
+		// Castled and developed
+		{"r2q1rk1/pp2bppp/2n1pn2/3p4/3P4/2NBPN2/PP3PPP/R2Q1RK1 w - - 2 10", PhaseMiddlegame},
+		// Full material, but late in the game
+		{"r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/2NP1N2/PPP2PPP/R1BQ1RK1 w - - 0 25", PhaseMiddlegame},
+		// Rook and bishop each
+		{"4rbk1/5ppp/8/8/8/8/5PPP/4RBK1 w - - 0 40", PhaseEndgame},
+		{"8/5pk1/6p1/8/8/6P1/5PK1/8 w - - 0 50", PhaseEndgame},
+	}
+	for _, tt := range tests {
+		pos, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("Failed to parse FEN %s: %v", tt.fen, err)
+		}
+		if got := pos.Phase(); got != tt.phase {
+			t.Errorf("%s: got %s, want %s", tt.fen, got, tt.phase)
+		}
+	}
+
+	if got := NewPosition().PhaseMaterial(); got != 24 {
+		t.Errorf("Starting position phase material %d, want 24", got)
+	}
+}
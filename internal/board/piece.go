@@ -125,6 +125,15 @@ func (p Piece) String() string {
 	return string(chars[p])
 }
 
+// Unicode returns the chess symbol for the piece (♔ for a white king).
+func (p Piece) Unicode() string {
+	if p >= NoPiece {
+		return " "
+	}
+	symbols := []string{"♙", "♘", "♗", "♖", "♕", "♔", "♟", "♞", "♝", "♜", "♛", "♚"}
+	return symbols[p]
+}
+
 // PieceFromChar converts a FEN character to a Piece.
 func PieceFromChar(c byte) Piece {
 	switch c {
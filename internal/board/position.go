@@ -41,16 +41,21 @@ func (cr CastlingRights) String() string {
 
 // CanCastle returns true if the given side can castle in the given direction.
 func (cr CastlingRights) CanCastle(c Color, kingSide bool) bool {
-	if c == White {
-		if kingSide {
-			return cr&WhiteKingSideCastle != 0
-		}
-		return cr&WhiteQueenSideCastle != 0
-	}
+	return cr&castlingRight(c, kingSide) != 0
+}
+
+// castlingIndex returns the index of a castling right in CastlingRooks:
+// 0-3 for K, Q, k and q.
+func castlingIndex(c Color, kingSide bool) int {
 	if kingSide {
-		return cr&BlackKingSideCastle != 0
+		return 2 * int(c)
 	}
-	return cr&BlackQueenSideCastle != 0
+	return 2*int(c) + 1
+}
+
+// castlingRight returns the castling right of c on the given side.
+func castlingRight(c Color, kingSide bool) CastlingRights {
+	return CastlingRights(1) << castlingIndex(c, kingSide)
 }
 
 // Position represents a complete chess position.
@@ -78,6 +83,10 @@ type Position struct {
 	// King positions (cached for check detection)
 	KingSquare [2]Square
 
+	// Squares of the rooks castled with, indexed K, Q, k, q (see
+	// castlingIndex). Chess960 rooks start anywhere on the back rank.
+	CastlingRooks [4]Square
+
 	// Checkers bitboard (pieces giving check)
 	Checkers Bitboard
 }
@@ -88,12 +97,37 @@ func NewPosition() *Position {
 	return pos
 }
 
+// CastlingRook returns the square of the rook c castles with on the given
+// side, or NoSquare if c has no such castling right.
+func (p *Position) CastlingRook(c Color, kingSide bool) Square {
+	if !p.CastlingRights.CanCastle(c, kingSide) {
+		return NoSquare
+	}
+	return p.CastlingRooks[castlingIndex(c, kingSide)]
+}
+
 // Copy creates a deep copy of the position.
 func (p *Position) Copy() *Position {
 	newPos := *p
 	return &newPos
 }
 
+// WithoutPiece returns a copy of the position with the piece on sq taken
+// off, to measure what the piece is worth to the evaluation. Castling and en
+// passant rights are kept. Kings cannot be taken off; the copy then equals
+// the position.
+func (p *Position) WithoutPiece(sq Square) *Position {
+	q := p.Copy()
+	if piece := q.PieceAt(sq); piece == NoPiece || piece.Type() == King {
+		return q
+	}
+	q.removePiece(sq)
+	q.Hash = q.ComputeHash()
+	q.PawnKey = q.ComputePawnKey()
+	q.UpdateCheckers()
+	return q
+}
+
 // PieceAt returns the piece at the given square, or NoPiece if empty.
 func (p *Position) PieceAt(sq Square) Piece {
 	bb := SquareBB(sq)
@@ -170,6 +204,18 @@ func (p *Position) removePiece(sq Square) Piece {
 	return piece
 }
 
+// castle moves the king and the rook of c when castling (does not update
+// hash). Both are lifted before either is put down, as each may land where
+// the other stood.
+func (p *Position) castle(c Color, kingFrom, kingTo, rookFrom, rookTo Square) {
+	from := SquareBB(kingFrom) | SquareBB(rookFrom)
+	p.Pieces[c][King] = p.Pieces[c][King]&^SquareBB(kingFrom) | SquareBB(kingTo)
+	p.Pieces[c][Rook] = p.Pieces[c][Rook]&^SquareBB(rookFrom) | SquareBB(rookTo)
+	p.Occupied[c] = p.Occupied[c]&^from | SquareBB(kingTo) | SquareBB(rookTo)
+	p.AllOccupied = p.Occupied[White] | p.Occupied[Black]
+	p.KingSquare[c] = kingTo
+}
+
 // movePiece moves a piece from one square to another (does not update hash).
 func (p *Position) movePiece(from, to Square) {
 	piece := p.PieceAt(from)
@@ -246,12 +292,19 @@ func (p *Position) Clear() {
 	*p = Position{
 		EnPassant:      NoSquare,
 		FullMoveNumber: 1,
+		CastlingRooks:  [4]Square{NoSquare, NoSquare, NoSquare, NoSquare},
 	}
 	p.KingSquare[White] = NoSquare
 	p.KingSquare[Black] = NoSquare
 }
 
-// Validate checks if the position is valid.
+// Validate checks that the position is legal and its state consistent: one
+// king a side, no pawns on the back ranks, castling rights backed by a king
+// and rook at home, the side that just moved not in check, bitboards, king
+// squares and piece placement agreeing (see Verify), and the incremental
+// hash and pawn key matching the position. The error names the first
+// problem found. The search runs it at every node when DebugMoveValidation
+// is set, and the fuzz tests after every move.
 func (p *Position) Validate() error {
 	// Check that each side has exactly one king
 	if p.Pieces[White][King].PopCount() != 1 {
@@ -266,9 +319,51 @@ func (p *Position) Validate() error {
 		return fmt.Errorf("pawns cannot be on rank 1 or 8")
 	}
 
-	// Check that opponent's king is not in check (would be illegal position)
-	// This will be implemented after attack generation
+	if err := p.Verify(); err != nil {
+		return err
+	}
+
+	// Castling rights need the king on its back rank and the castling rook
+	// beside it on the side castled to
+	for c := White; c <= Black; c++ {
+		for _, kingSide := range []bool{true, false} {
+			right := castlingRight(c, kingSide)
+			if p.CastlingRights&right == 0 {
+				continue
+			}
+			ksq, rsq := p.KingSquare[c], p.CastlingRooks[castlingIndex(c, kingSide)]
+			if rsq == NoSquare || p.Pieces[c][Rook]&SquareBB(rsq) == 0 {
+				return fmt.Errorf("castling right %v without a rook to castle with", right)
+			}
+			if ksq.RelativeRank(c) != 0 || rsq.Rank() != ksq.Rank() || (rsq > ksq) != kingSide {
+				return fmt.Errorf("castling right %v with king on %s and rook on %s", right, ksq, rsq)
+			}
+		}
+	}
+
+	// No square may hold two pieces
+	for c := White; c <= Black; c++ {
+		var seen Bitboard
+		for pt := Pawn; pt <= King; pt++ {
+			if overlap := seen & p.Pieces[c][pt]; overlap != 0 {
+				return fmt.Errorf("%v %v on an occupied square %s", c, pt, overlap.LSB())
+			}
+			seen |= p.Pieces[c][pt]
+		}
+	}
+
+	// The side that just moved cannot be in check
+	them := p.SideToMove.Other()
+	if p.IsSquareAttacked(p.KingSquare[them], p.SideToMove) {
+		return fmt.Errorf("%v is in check with %v to move", them, p.SideToMove)
+	}
 
+	if hash := p.ComputeHash(); hash != p.Hash {
+		return fmt.Errorf("hash mismatch: computed=%016x stored=%016x", hash, p.Hash)
+	}
+	if key := p.ComputePawnKey(); key != p.PawnKey {
+		return fmt.Errorf("pawn key mismatch: computed=%016x stored=%016x", key, p.PawnKey)
+	}
 	return nil
 }
 
@@ -368,6 +463,28 @@ func (p *Position) ComputePinned() Bitboard {
 	return pinned
 }
 
+// PinnerOf returns the square of the enemy slider that pins the side to move's
+// piece on sq to its king, or NoSquare if that piece is not pinned.
+func (p *Position) PinnerOf(sq Square) Square {
+	us := p.SideToMove
+	them := us.Other()
+	ksq := p.KingSquare[us]
+	target := SquareBB(sq)
+	if p.Occupied[us]&target == 0 || sq == ksq {
+		return NoSquare
+	}
+
+	snipers := (RookAttacks(ksq, 0) & (p.Pieces[them][Rook] | p.Pieces[them][Queen])) |
+		(BishopAttacks(ksq, 0) & (p.Pieces[them][Bishop] | p.Pieces[them][Queen]))
+	for snipers != 0 {
+		sniper := snipers.PopLSB()
+		if Between(sniper, ksq)&p.AllOccupied == target {
+			return sniper
+		}
+	}
+	return NoSquare
+}
+
 // NullMoveUndo stores state for unmake of null move.
 // Returned by MakeNullMove and passed to UnmakeNullMove.
 type NullMoveUndo struct {
@@ -444,18 +561,19 @@ func (p *Position) PseudoLegal(m Move) bool {
 		return false
 	}
 
+	// For special moves (promotion, en passant, castling), do full validation.
+	// Castling comes first: the king may land where its rook stands.
+	if m.Flag() != FlagNormal {
+		legal := p.GenerateLegalMoves()
+		return legal.Contains(m)
+	}
+
 	// Destination cannot have friendly piece
 	destPiece := p.PieceAt(to)
 	if destPiece != NoPiece && destPiece.Color() == us {
 		return false
 	}
 
-	// For special moves (promotion, en passant, castling), do full validation
-	if m.Flag() != FlagNormal {
-		legal := p.GenerateLegalMoves()
-		return legal.Contains(m)
-	}
-
 	pt := piece.Type()
 
 	// Validate pawn moves
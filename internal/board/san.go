@@ -1,6 +1,7 @@
 package board
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -22,8 +23,8 @@ func (m Move) ToSAN(pos *Position) string {
 
 	// Castling
 	if m.IsCastling() {
-		if to > from {
-			return "O-O" // Kingside
+		if m.IsKingSideCastling() {
+			return "O-O"
 		}
 		return "O-O-O" // Queenside
 	}
@@ -61,8 +62,8 @@ func (m Move) ToSAN(pos *Position) string {
 	}
 
 	// Check/checkmate marker
-	// Make the move temporarily to check
-	newPos := pos.Copy()
+	// Make the move on a copy kept on the stack to check
+	newPos := *pos
 	newPos.MakeMove(m)
 	if newPos.IsCheckmate() {
 		sb.WriteByte('#')
@@ -73,6 +74,31 @@ func (m Move) ToSAN(pos *Position) string {
 	return sb.String()
 }
 
+// SANCache keeps the SAN of the moves of one position, so a position drawn
+// on every frame formats each move once. Asking about another position
+// starts it over. The zero value is ready to use.
+type SANCache struct {
+	hash uint64
+	sans map[Move]string
+}
+
+// ToSAN returns the SAN of m in pos, formatting it only the first time.
+func (c *SANCache) ToSAN(pos *Position, m Move) string {
+	if c.sans == nil {
+		c.sans = make(map[Move]string)
+	} else if c.hash != pos.Hash {
+		clear(c.sans)
+	}
+	c.hash = pos.Hash
+
+	san, ok := c.sans[m]
+	if !ok {
+		san = m.ToSAN(pos)
+		c.sans[m] = san
+	}
+	return san
+}
+
 // getDisambiguation returns the disambiguation string needed for a move.
 func getDisambiguation(pos *Position, m Move, pt PieceType) string {
 	from := m.From()
@@ -84,6 +110,9 @@ func getDisambiguation(pos *Position, m Move, pt PieceType) string {
 
 	// Get all pieces of this type
 	pieces := pos.Pieces[us][pt]
+	if pieces.PopCount() < 2 {
+		return "" // No other piece of the type to confuse it with
+	}
 
 	// Generate legal moves for each piece
 	allMoves := pos.GenerateLegalMoves()
@@ -134,31 +163,32 @@ func getDisambiguation(pos *Position, m Move, pt PieceType) string {
 	return from.String()
 }
 
-// ParseSAN parses a SAN string and returns the corresponding move.
+// ParseSAN parses a SAN string and returns the corresponding legal move.
+// A move that is not legal in the position is an ErrIllegalMove.
 func ParseSAN(s string, pos *Position) (Move, error) {
 	s = strings.TrimSpace(s)
-
-	// Handle castling
-	if s == "O-O" || s == "0-0" {
-		if pos.SideToMove == White {
-			return NewCastling(E1, G1), nil
-		}
-		return NewCastling(E8, G8), nil
-	}
-	if s == "O-O-O" || s == "0-0-0" {
-		if pos.SideToMove == White {
-			return NewCastling(E1, C1), nil
-		}
-		return NewCastling(E8, C8), nil
-	}
+	san := s
 
 	// Remove check/checkmate markers
 	s = strings.TrimSuffix(s, "+")
 	s = strings.TrimSuffix(s, "#")
 
+	// Handle castling, when legal
+	if s == "O-O" || s == "0-0" || s == "O-O-O" || s == "0-0-0" {
+		from := pos.KingSquare[pos.SideToMove]
+		to, _ := castlingTargets(from, len(s) == 3)
+		if m := NewCastling(from, to); pos.GenerateLegalMoves().Contains(m) {
+			return m, nil
+		}
+		return NoMove, fmt.Errorf("%w %q", ErrIllegalMove, san)
+	}
+
 	// Parse promotion
 	var promoPiece PieceType = NoPieceType
 	if idx := strings.Index(s, "="); idx >= 0 {
+		if idx+1 >= len(s) {
+			return NoMove, fmt.Errorf("%w %q: missing promotion piece", ErrInvalidMove, san)
+		}
 		promoChar := s[idx+1]
 		switch promoChar {
 		case 'N':
@@ -197,12 +227,12 @@ func ParseSAN(s string, pos *Position) (Move, error) {
 
 	// Parse destination (last 2 characters)
 	if len(s) < 2 {
-		return NoMove, nil
+		return NoMove, fmt.Errorf("%w %q", ErrInvalidMove, san)
 	}
 	destStr := s[len(s)-2:]
 	dest, err := ParseSquare(destStr)
 	if err != nil {
-		return NoMove, err
+		return NoMove, fmt.Errorf("%w %q: %w", ErrInvalidMove, san, err)
 	}
 	s = s[:len(s)-2]
 
@@ -253,7 +283,7 @@ func ParseSAN(s string, pos *Position) (Move, error) {
 		return m, nil
 	}
 
-	return NoMove, nil
+	return NoMove, fmt.Errorf("%w %q", ErrIllegalMove, san)
 }
 
 // MovesToSAN converts a slice of moves to SAN notation.
@@ -0,0 +1,139 @@
+package board
+
+import (
+	"errors"
+	"testing"
+)
+
+// sanTestFEN has disambiguation, captures, checks and a promotion.
+const sanTestFEN = "r3k2r/1P3ppp/2n5/8/8/2N3N1/5PPP/R3K2R w KQkq - 0 20"
+
+// TestToSAN verifies the notation of the different kinds of moves.
+func TestToSAN(t *testing.T) {
+	pos, err := ParseFEN(sanTestFEN)
+	if err != nil {
+		t.Fatalf("Failed to parse FEN: %v", err)
+	}
+
+	tests := []struct {
+		from, to Square
+		promo    PieceType
+		want     string
+	}{
+		{C3, D5, NoPieceType, "Nd5"},
+		{G3, E4, NoPieceType, "Nge4"},
+		{B7, A8, Queen, "bxa8=Q+"},
+		{E1, G1, NoPieceType, "O-O"},
+		{A1, A8, NoPieceType, "Rxa8+"},
+	}
+	for _, tt := range tests {
+		m := findTestMove(pos, tt.from, tt.to, tt.promo)
+		if m == NoMove {
+			t.Fatalf("No legal move %v%v", tt.from, tt.to)
+		}
+		if got := m.ToSAN(pos); got != tt.want {
+			t.Errorf("%v: got %s, want %s", m, got, tt.want)
+		}
+	}
+}
+
+// TestParseErrors verifies the kinds of error of the FEN and move parsers.
+func TestParseErrors(t *testing.T) {
+	pos, err := ParseFEN(sanTestFEN)
+	if err != nil {
+		t.Fatalf("Failed to parse FEN: %v", err)
+	}
+
+	for _, fen := range []string{"", "8/8/8/8 w - -", "4k3/8/8/8/8/8/8/4K3 x - -", "4k3/8/8/8/8/8/8/4K2R w Z -", "8/8/8/8/8/8/8/4K3 w - -"} {
+		if _, err := ParseFEN(fen); !errors.Is(err, ErrInvalidFEN) {
+			t.Errorf("ParseFEN(%q): got %v, want ErrInvalidFEN", fen, err)
+		}
+	}
+
+	tests := []struct {
+		san  string
+		want error
+	}{
+		{"Nd5", nil},
+		{"Nd6", ErrIllegalMove},
+		{"Ke3", ErrIllegalMove},
+		{"O-O-O", nil},
+		{"Nz9", ErrInvalidMove},
+		{"b8=", ErrInvalidMove},
+		{"N", ErrInvalidMove},
+	}
+	for _, tt := range tests {
+		if _, err := ParseSAN(tt.san, pos); !errors.Is(err, tt.want) {
+			t.Errorf("ParseSAN(%q): got %v, want %v", tt.san, err, tt.want)
+		}
+	}
+
+	if _, err := ParseMove("e2e9", pos); !errors.Is(err, ErrInvalidMove) {
+		t.Errorf("ParseMove(e2e9): got %v, want ErrInvalidMove", err)
+	}
+	if _, err := ParseMove("d4d5", pos); !errors.Is(err, ErrIllegalMove) {
+		t.Errorf("ParseMove(d4d5): got %v, want ErrIllegalMove", err)
+	}
+}
+
+// TestSANCache verifies the cache formats like ToSAN and starts over when
+// the position changes.
+func TestSANCache(t *testing.T) {
+	pos := NewPosition()
+	var cache SANCache
+
+	e4 := findTestMove(pos, E2, E4, NoPieceType)
+	if got := cache.ToSAN(pos, e4); got != "e4" {
+		t.Fatalf("got %s, want e4", got)
+	}
+	if got := cache.ToSAN(pos, e4); got != "e4" {
+		t.Fatalf("Cached: got %s, want e4", got)
+	}
+
+	pos.MakeMove(e4)
+	e5 := findTestMove(pos, E7, E5, NoPieceType)
+	if got := cache.ToSAN(pos, e5); got != "e5" {
+		t.Errorf("After e4: got %s, want e5", got)
+	}
+	if len(cache.sans) != 1 {
+		t.Errorf("Cache holds %d moves after the position changed, want 1", len(cache.sans))
+	}
+}
+
+// findTestMove returns the legal move from one square to another, or NoMove.
+func findTestMove(pos *Position, from, to Square, promo PieceType) Move {
+	moves := pos.GenerateLegalMoves()
+	for i := 0; i < moves.Len(); i++ {
+		m := moves.Get(i)
+		if m.From() == from && m.To() == to && (!m.IsPromotion() || m.Promotion() == promo) {
+			return m
+		}
+	}
+	return NoMove
+}
+
+// BenchmarkToSAN formats every legal move of a position, as a move list or
+// move entry drawn on every frame did.
+func BenchmarkToSAN(b *testing.B) {
+	pos, _ := ParseFEN(sanTestFEN)
+	moves := pos.GenerateLegalMoves()
+	b.ReportAllocs()
+	for b.Loop() {
+		for i := 0; i < moves.Len(); i++ {
+			moves.Get(i).ToSAN(pos)
+		}
+	}
+}
+
+// BenchmarkSANCache formats the same moves through a cache.
+func BenchmarkSANCache(b *testing.B) {
+	pos, _ := ParseFEN(sanTestFEN)
+	moves := pos.GenerateLegalMoves()
+	var cache SANCache
+	b.ReportAllocs()
+	for b.Loop() {
+		for i := 0; i < moves.Len(); i++ {
+			cache.ToSAN(pos, moves.Get(i))
+		}
+	}
+}
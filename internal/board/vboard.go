@@ -8,6 +8,8 @@ type VBoard struct {
 	Occupied    [2]Bitboard
 	AllOccupied Bitboard
 	KingSquare  [2]Square
+
+	CastlingRooks [4]Square // As in Position
 }
 
 // NewVBoard creates a VBoard from a Position.
@@ -17,6 +19,8 @@ func NewVBoard(p *Position) VBoard {
 		Occupied:    p.Occupied,
 		AllOccupied: p.AllOccupied,
 		KingSquare:  p.KingSquare,
+
+		CastlingRooks: p.CastlingRooks,
 	}
 }
 
@@ -26,6 +30,21 @@ func (v *VBoard) ApplyMove(m Move, us Color) {
 	from, to := m.From(), m.To()
 	fromBB, toBB := SquareBB(from), SquareBB(to)
 
+	// Castling lifts the king and rook before putting either down, as each
+	// may land where the other stood
+	if m.IsCastling() {
+		kingSide := m.IsKingSideCastling()
+		rookFrom := v.CastlingRooks[castlingIndex(us, kingSide)]
+		_, rookTo := castlingTargets(from, kingSide)
+		rookFromBB, rookToBB := SquareBB(rookFrom), SquareBB(rookTo)
+		v.Pieces[us][King] = v.Pieces[us][King]&^fromBB | toBB
+		v.Pieces[us][Rook] = v.Pieces[us][Rook]&^rookFromBB | rookToBB
+		v.Occupied[us] = v.Occupied[us]&^(fromBB|rookFromBB) | toBB | rookToBB
+		v.AllOccupied = v.Occupied[White] | v.Occupied[Black]
+		v.KingSquare[us] = to
+		return
+	}
+
 	// Find moving piece type
 	var pt PieceType
 	for t := Pawn; t <= King; t++ {
@@ -73,22 +92,6 @@ func (v *VBoard) ApplyMove(m Move, us Color) {
 		v.Pieces[us][Pawn] &^= toBB
 		v.Pieces[us][m.Promotion()] |= toBB
 	}
-
-	// Handle castling rook movement
-	if m.IsCastling() {
-		var rookFrom, rookTo Square
-		if to > from { // Kingside
-			rookFrom = from + 3
-			rookTo = from + 1
-		} else { // Queenside
-			rookFrom = from - 4
-			rookTo = from - 1
-		}
-		rookBB := SquareBB(rookFrom) | SquareBB(rookTo)
-		v.Pieces[us][Rook] ^= rookBB
-		v.Occupied[us] ^= rookBB
-		v.AllOccupied = v.Occupied[White] | v.Occupied[Black]
-	}
 }
 
 // IsKingAttacked checks if the king on kingSq is attacked by byColor.
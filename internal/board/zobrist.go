@@ -75,3 +75,23 @@ func ZobristCastling(cr CastlingRights) uint64 {
 func ZobristSideToMove() uint64 {
 	return zobristSideToMove
 }
+
+// KeyAfter returns the hash of the position after move m, for prefetching.
+// Like Stockfish's key_after it is exact for ordinary moves and captures but
+// ignores castling rights, en passant squares and captures, the rook of a
+// castling move and promotions, so the result is only a hint.
+func (p *Position) KeyAfter(m Move) uint64 {
+	from, to := m.From(), m.To()
+	piece := p.PieceAt(from)
+	if piece == NoPiece {
+		return p.Hash
+	}
+	key := p.Hash ^ zobristSideToMove
+	if p.EnPassant != NoSquare {
+		key ^= zobristEnPassant[p.EnPassant.File()]
+	}
+	if captured := p.PieceAt(to); captured != NoPiece {
+		key ^= zobristPiece[captured.Color()][captured.Type()][to]
+	}
+	return key ^ zobristPiece[piece.Color()][piece.Type()][from] ^ zobristPiece[piece.Color()][piece.Type()][to]
+}
@@ -0,0 +1,39 @@
+package board
+
+import "testing"
+
+// TestKeyAfter checks that KeyAfter predicts the hash after every move it
+// covers exactly: moves and captures that change no castling rights and
+// set no en passant square.
+func TestKeyAfter(t *testing.T) {
+	for _, fen := range []string{
+		StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+	} {
+		pos, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moves := pos.GenerateLegalMoves()
+		checked := 0
+		for i := 0; i < moves.Len(); i++ {
+			m := moves.Get(i)
+			if m.IsCastling() || m.IsEnPassant() || m.IsPromotion() {
+				continue
+			}
+			p := pos.Copy()
+			p.MakeMove(m)
+			if p.CastlingRights != pos.CastlingRights || p.EnPassant != NoSquare {
+				continue
+			}
+			if got := pos.KeyAfter(m); got != p.Hash {
+				t.Errorf("%s: KeyAfter(%v) = %x, hash after the move %x", fen, m, got, p.Hash)
+			}
+			checked++
+		}
+		if checked == 0 {
+			t.Errorf("%s: no move checked", fen)
+		}
+	}
+}
@@ -18,7 +18,9 @@ type BookEntry struct {
 
 // Book represents an opening book.
 type Book struct {
-	entries map[uint64][]BookEntry
+	entries   map[uint64][]BookEntry
+	disk      *diskBook // Set for books searched on disk (entries is then empty)
+	minWeight uint16    // Entries with a lower weight are ignored
 }
 
 // New creates an empty book.
@@ -29,6 +31,7 @@ func New() *Book {
 }
 
 // LoadPolyglot loads a Polyglot format opening book from a file.
+// Large books are not read into memory but searched on disk (see OpenPolyglot).
 func LoadPolyglot(filename string) (*Book, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -36,9 +39,63 @@ func LoadPolyglot(filename string) (*Book, error) {
 	}
 	defer file.Close()
 
+	if info, err := file.Stat(); err == nil && info.Size() > diskThreshold {
+		return OpenPolyglot(filename)
+	}
 	return LoadPolyglotReader(file)
 }
 
+// OpenPolyglot opens a Polyglot book for lookups on disk: the file is
+// memory-mapped where supported and binary searched, so multi-gigabyte books
+// load instantly and need almost no memory. The file must be sorted by key,
+// as Polyglot books are. Call Close when done with the book.
+func OpenPolyglot(filename string) (*Book, error) {
+	d, err := openDiskBook(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &Book{entries: make(map[uint64][]BookEntry), disk: d}, nil
+}
+
+// Close releases the file of a book opened on disk. It is a no-op for books
+// loaded into memory.
+func (b *Book) Close() error {
+	if b == nil || b.disk == nil {
+		return nil
+	}
+	err := b.disk.close()
+	b.disk = nil
+	return err
+}
+
+// SetMinWeight makes Probe and ProbeAll ignore entries with a lower weight,
+// so rarely played moves are not chosen.
+func (b *Book) SetMinWeight(w uint16) {
+	b.minWeight = w
+}
+
+// lookup returns the entries stored for a position key, without those
+// below the minimum weight.
+func (b *Book) lookup(key uint64) []BookEntry {
+	var entries []BookEntry
+	if b.disk != nil {
+		entries = b.disk.lookup(key)
+	} else {
+		entries = b.entries[key]
+	}
+	if b.minWeight == 0 {
+		return entries
+	}
+
+	var result []BookEntry
+	for _, e := range entries {
+		if e.Weight >= b.minWeight {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
 // LoadPolyglotReader loads a Polyglot format book from a reader.
 func LoadPolyglotReader(r io.Reader) (*Book, error) {
 	book := New()
@@ -102,6 +159,9 @@ func decodePolyglotMove(data uint16) board.Move {
 		to = board.C8 // Black queenside
 	}
 
+	if promo > 4 {
+		return board.NoMove // Not a piece; the entry is broken
+	}
 	if promo > 0 {
 		// Promotion pieces: 1=knight, 2=bishop, 3=rook, 4=queen
 		promoTypes := []board.PieceType{0, board.Knight, board.Bishop, board.Rook, board.Queen}
@@ -117,9 +177,8 @@ func (b *Book) Probe(pos *board.Position) (board.Move, bool) {
 		return board.NoMove, false
 	}
 
-	key := pos.PolyglotHash()
-	entries, ok := b.entries[key]
-	if !ok || len(entries) == 0 {
+	entries := b.lookup(pos.PolyglotHash())
+	if len(entries) == 0 {
 		return board.NoMove, false
 	}
 
@@ -158,9 +217,8 @@ func (b *Book) ProbeAll(pos *board.Position) []BookEntry {
 		return nil
 	}
 
-	key := pos.PolyglotHash()
-	entries, ok := b.entries[key]
-	if !ok {
+	entries := b.lookup(pos.PolyglotHash())
+	if len(entries) == 0 {
 		return nil
 	}
 
@@ -198,10 +256,15 @@ func verifyAndConvert(pos *board.Position, move board.Move) board.Move {
 	return board.NoMove
 }
 
-// Size returns the number of unique positions in the book.
+// Size returns the number of unique positions in the book. For books
+// searched on disk it returns the number of entries instead, as counting
+// positions would mean reading the whole file.
 func (b *Book) Size() int {
 	if b == nil {
 		return 0
 	}
+	if b.disk != nil {
+		return int(b.disk.entries)
+	}
 	return len(b.entries)
 }
@@ -3,6 +3,8 @@ package book
 import (
 	"bytes"
 	"encoding/binary"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hailam/chessplay/internal/board"
@@ -116,3 +118,156 @@ func TestDecodePolyglotMove(t *testing.T) {
 		t.Errorf("Expected to=d5, got %s", move.To().String())
 	}
 }
+
+func TestOpenPolyglot(t *testing.T) {
+	pos := board.NewPosition()
+	key := pos.PolyglotHash()
+	e2e4 := uint16(4 | (3 << 3) | (4 << 6) | (1 << 9))
+	d2d4 := uint16(3 | (3 << 3) | (3 << 6) | (1 << 9))
+
+	// A sorted book of filler keys around the start position's two entries
+	var buf bytes.Buffer
+	write := func(k uint64, move, weight uint16) {
+		binary.Write(&buf, binary.BigEndian, k)
+		binary.Write(&buf, binary.BigEndian, move)
+		binary.Write(&buf, binary.BigEndian, weight)
+		binary.Write(&buf, binary.BigEndian, uint32(0))
+	}
+	below, above := key/1000, (^uint64(0)-key)/1000
+	for i := uint64(0); i < 500; i++ {
+		write(i*below, e2e4, 1)
+	}
+	write(key, e2e4, 100)
+	write(key, d2d4, 50)
+	for i := uint64(1); i <= 500; i++ {
+		write(key+i*above, e2e4, 1)
+	}
+
+	path := filepath.Join(t.TempDir(), "book.bin")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	book, err := OpenPolyglot(path)
+	if err != nil {
+		t.Fatalf("Failed to open book: %v", err)
+	}
+	defer book.Close()
+
+	check := func(name string) {
+		entries := book.ProbeAll(pos)
+		if len(entries) != 2 || entries[0].Weight != 100 || entries[1].Weight != 50 {
+			t.Fatalf("%s: expected e2e4 (100) and d2d4 (50), got %v", name, entries)
+		}
+		if move, found := book.Probe(pos); !found || move == board.NoMove {
+			t.Errorf("%s: expected a book move", name)
+		}
+
+		pos.MakeMove(board.NewMove(board.E2, board.E4))
+		if entries := book.ProbeAll(pos); len(entries) != 0 {
+			t.Errorf("%s: expected a miss after e2e4, got %v", name, entries)
+		}
+		pos = board.NewPosition()
+	}
+	check("mapped")
+
+	// Targets without mmap read the entries with ReadAt
+	if book.disk.data != nil {
+		munmapFile(book.disk.data)
+		book.disk.data = nil
+	}
+	check("ReadAt")
+
+	if book.Size() != 1002 {
+		t.Errorf("Expected 1002 entries, got %d", book.Size())
+	}
+
+	// Truncated files are rejected
+	if err := os.WriteFile(path, buf.Bytes()[:100], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := OpenPolyglot(path); err == nil {
+		b.Close()
+		t.Error("Expected an error for a truncated book")
+	}
+}
+
+func TestBookMinWeight(t *testing.T) {
+	pos := board.NewPosition()
+	key := pos.PolyglotHash()
+
+	// e2e4 with weight 100, d2d4 with weight 5
+	e2e4Encoded := uint16(4 | (3 << 3) | (4 << 6) | (1 << 9))
+	d2d4Encoded := uint16(3 | (3 << 3) | (3 << 6) | (1 << 9))
+
+	var buf bytes.Buffer
+	for _, e := range []struct{ move, weight uint16 }{{e2e4Encoded, 100}, {d2d4Encoded, 5}} {
+		binary.Write(&buf, binary.BigEndian, key)
+		binary.Write(&buf, binary.BigEndian, e.move)
+		binary.Write(&buf, binary.BigEndian, e.weight)
+		binary.Write(&buf, binary.BigEndian, uint32(0))
+	}
+	book, err := LoadPolyglotReader(&buf)
+	if err != nil {
+		t.Fatalf("Failed to load book: %v", err)
+	}
+
+	if n := len(book.ProbeAll(pos)); n != 2 {
+		t.Fatalf("Expected 2 book moves, got %d", n)
+	}
+
+	book.SetMinWeight(10)
+	entries := book.ProbeAll(pos)
+	if len(entries) != 1 || entries[0].Move.From() != board.E2 {
+		t.Errorf("Expected only e2e4 with min weight 10, got %v", entries)
+	}
+
+	book.SetMinWeight(200)
+	if _, found := book.Probe(pos); found {
+		t.Error("Expected book miss when every entry is below the min weight")
+	}
+}
+
+// FuzzPolyglot reads arbitrary bytes as a book, in memory and on disk. The
+// key of every whole entry is set to the start position's, so each move is
+// decoded and probed; a broken book may give no move, never a wrong one.
+func FuzzPolyglot(f *testing.F) {
+	e2e4 := uint16(4 | (3 << 3) | (4 << 6) | (1 << 9))
+	entry := make([]byte, polyglotEntrySize)
+	binary.BigEndian.PutUint16(entry[8:10], e2e4)
+	binary.BigEndian.PutUint16(entry[10:12], 10)
+	f.Add(entry)
+	f.Add(entry[:10])
+	f.Add([]byte{})
+	f.Add(bytes.Repeat([]byte{0xff}, 2*polyglotEntrySize))
+
+	key := board.NewPosition().PolyglotHash()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		data = bytes.Clone(data)
+		for off := 0; off+polyglotEntrySize <= len(data); off += polyglotEntrySize {
+			binary.BigEndian.PutUint64(data[off:], key)
+		}
+
+		check := func(b *Book) {
+			pos := board.NewPosition()
+			legal := pos.GenerateLegalMoves()
+			if move, found := b.Probe(pos); found && move != board.NoMove && !legal.Contains(move) {
+				t.Fatalf("Probe gave illegal move %v", move)
+			}
+			b.ProbeAll(pos)
+		}
+
+		if b, err := LoadPolyglotReader(bytes.NewReader(data)); err == nil {
+			check(b)
+		}
+
+		path := filepath.Join(t.TempDir(), "book.bin")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if b, err := OpenPolyglot(path); err == nil {
+			check(b)
+			b.Close()
+		}
+	})
+}
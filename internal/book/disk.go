@@ -0,0 +1,111 @@
+package book
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// Books larger than this are searched on disk instead of loaded into memory.
+const diskThreshold = 64 << 20
+
+// polyglotEntrySize is the size of one Polyglot entry in bytes.
+const polyglotEntrySize = 16
+
+// errMmapUnsupported is returned by mmapFile where memory mapping is not
+// available (non-Unix targets, or files too large for the address space).
+var errMmapUnsupported = errors.New("memory mapping not supported")
+
+// diskBook reads entries straight from a Polyglot file, which is sorted by
+// key, with a binary search. The file is memory-mapped where possible and
+// read with ReadAt otherwise, so books of any size need almost no memory.
+type diskBook struct {
+	file    *os.File
+	data    []byte // Mapped file, nil when reading through file
+	entries int64
+}
+
+// openDiskBook opens a Polyglot file for on-disk lookups.
+func openDiskBook(filename string) (*diskBook, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size()%polyglotEntrySize != 0 {
+		file.Close()
+		return nil, fmt.Errorf("%s: size %d is not a multiple of %d bytes", filename, info.Size(), polyglotEntrySize)
+	}
+
+	d := &diskBook{file: file, entries: info.Size() / polyglotEntrySize}
+	if d.entries > 0 {
+		// Fall back to ReadAt when the file cannot be mapped (e.g. 32-bit, wasm)
+		if data, err := mmapFile(file, info.Size()); err == nil {
+			d.data = data
+		}
+	}
+	return d, nil
+}
+
+// entry reads the i-th raw entry.
+func (d *diskBook) entry(i int64, buf *[polyglotEntrySize]byte) error {
+	off := i * polyglotEntrySize
+	if d.data != nil {
+		copy(buf[:], d.data[off:off+polyglotEntrySize])
+		return nil
+	}
+	_, err := d.file.ReadAt(buf[:], off)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// lookup returns the book entries for a key. Read errors end the lookup
+// early; a broken book gives fewer moves, never a wrong one.
+func (d *diskBook) lookup(key uint64) []BookEntry {
+	var buf [polyglotEntrySize]byte
+
+	// Binary search for the first entry with this key
+	lo, hi := int64(0), d.entries
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if d.entry(mid, &buf) != nil {
+			return nil
+		}
+		if binary.BigEndian.Uint64(buf[0:8]) < key {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	var result []BookEntry
+	for i := lo; i < d.entries; i++ {
+		if d.entry(i, &buf) != nil || binary.BigEndian.Uint64(buf[0:8]) != key {
+			break
+		}
+		move := decodePolyglotMove(binary.BigEndian.Uint16(buf[8:10]))
+		if move != board.NoMove {
+			result = append(result, BookEntry{Move: move, Weight: binary.BigEndian.Uint16(buf[10:12])})
+		}
+	}
+	return result
+}
+
+// close unmaps and closes the file.
+func (d *diskBook) close() error {
+	if d.data != nil {
+		munmapFile(d.data)
+		d.data = nil
+	}
+	return d.file.Close()
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package book
+
+import "os"
+
+// mmapFile is not supported here; books are read with ReadAt instead.
+func mmapFile(f *os.File, size int64) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+// munmapFile is never called without a mapping.
+func munmapFile(data []byte) {}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package book
+
+import (
+	"math"
+	"os"
+	"syscall"
+)
+
+// mmapFile maps a file read-only into memory.
+func mmapFile(f *os.File, size int64) ([]byte, error) {
+	// 32-bit address spaces cannot map multi-gigabyte books
+	if size > math.MaxInt32 && math.MaxInt == math.MaxInt32 {
+		return nil, errMmapUnsupported
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmapFile releases a mapping made by mmapFile.
+func munmapFile(data []byte) {
+	syscall.Munmap(data)
+}
@@ -0,0 +1,100 @@
+// Package eco names chess openings. It embeds a small table of common
+// openings with their ECO (Encyclopaedia of Chess Openings) codes, keyed by
+// the position each line reaches, so transposed move orders are recognised.
+package eco
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// openingsTSV holds one opening per line: ECO code, name and the moves
+// leading to it, separated by tabs. The first line is a header.
+//
+//go:embed openings.tsv
+var openingsTSV string
+
+// Opening is a named opening.
+type Opening struct {
+	ECO  string // Code such as "C60"
+	Name string // Name such as "Ruy Lopez"
+}
+
+// String returns the code and name, as in "C60 Ruy Lopez".
+func (o Opening) String() string {
+	return o.ECO + " " + o.Name
+}
+
+var (
+	tableOnce sync.Once
+	table     map[uint64]Opening // Keyed by Polyglot hash
+	tableErr  error
+)
+
+// load parses the embedded table on first use. The Polyglot hash is used as
+// the key since it only includes the en passant square when a capture is
+// possible, so move orders ending in different double pawn pushes meet.
+func load() (map[uint64]Opening, error) {
+	tableOnce.Do(func() {
+		table, tableErr = parse(openingsTSV)
+	})
+	return table, tableErr
+}
+
+// parse reads an opening table. A later line for the same position replaces
+// an earlier one.
+func parse(data string) (map[uint64]Opening, error) {
+	openings := make(map[uint64]Opening)
+	for i, line := range strings.Split(data, "\n") {
+		if i == 0 || strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("eco: line %d: want 3 fields, got %d", i+1, len(fields))
+		}
+		pos := board.NewPosition()
+		for _, tok := range strings.Fields(fields[2]) {
+			if strings.HasSuffix(tok, ".") {
+				continue // Move number
+			}
+			m, err := board.ParseSAN(tok, pos)
+			if err != nil {
+				return nil, fmt.Errorf("eco: line %d: %w", i+1, err)
+			}
+			pos.MakeMove(m)
+			pos.UpdateCheckers()
+		}
+		openings[pos.PolyglotHash()] = Opening{ECO: fields[0], Name: fields[1]}
+	}
+	return openings, nil
+}
+
+// Lookup returns the opening whose line reaches exactly pos.
+func Lookup(pos *board.Position) (Opening, bool) {
+	openings, err := load()
+	if err != nil {
+		return Opening{}, false
+	}
+	o, ok := openings[pos.PolyglotHash()]
+	return o, ok
+}
+
+// Classify returns the opening of a game played from start: the opening of
+// the latest position along moves that is in the table. Games that leave
+// the table keep the name of the last opening they passed through.
+func Classify(start *board.Position, moves []board.Move) (Opening, bool) {
+	pos := start.Copy()
+	found, ok := Lookup(pos)
+	for _, m := range moves {
+		pos.MakeMove(m)
+		if o, hit := Lookup(pos); hit {
+			found, ok = o, true
+		}
+	}
+	return found, ok
+}
@@ -0,0 +1,77 @@
+package eco
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// playSAN plays SAN moves from the start position.
+func playSAN(t *testing.T, sans string) (*board.Position, []board.Move) {
+	t.Helper()
+	pos := board.NewPosition()
+	var moves []board.Move
+	for _, s := range strings.Fields(sans) {
+		m, err := board.ParseSAN(s, pos)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		pos.MakeMove(m)
+		pos.UpdateCheckers()
+		moves = append(moves, m)
+	}
+	return pos, moves
+}
+
+func TestTable(t *testing.T) {
+	openings, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Count(strings.TrimSpace(openingsTSV), "\n")
+	if len(openings) != lines {
+		t.Errorf("%d openings for %d lines: some lines reach the same position", len(openings), lines)
+	}
+	for _, o := range openings {
+		if len(o.ECO) != 3 || o.ECO[0] < 'A' || o.ECO[0] > 'E' || o.Name == "" {
+			t.Errorf("bad opening %+v", o)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if _, ok := Lookup(board.NewPosition()); ok {
+		t.Error("start position named")
+	}
+
+	// The Nimzo-Indian reached from the English move order
+	pos, _ := playSAN(t, "c4 e6 d4 Nf6 Nc3 Bb4")
+	o, ok := Lookup(pos)
+	if !ok || o.ECO != "E20" || o.Name != "Nimzo-Indian Defense" {
+		t.Errorf("transposition: got %v, %v", o, ok)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		moves string
+		want  string
+	}{
+		{"e4 e5 Nf3 Nc6 Bb5", "C60 Ruy Lopez"},
+		{"e4 e5 Nf3 Nc6 Bb5 a6 h3", "C70 Ruy Lopez: Morphy Defense"}, // Left the table
+		{"d4 Nf6 c4 g6 Nc3 d5 cxd5 Nxd5", "D85 Grunfeld Defense: Exchange Variation"},
+		{"e4 c5 Nf3 d6 d4 cxd4 Nxd4 Nf6 Nc3 a6 Be3", "B90 Sicilian Defense: Najdorf Variation"},
+	}
+	for _, tt := range tests {
+		_, moves := playSAN(t, tt.moves)
+		o, ok := Classify(board.NewPosition(), moves)
+		if !ok || o.String() != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.moves, o, ok, tt.want)
+		}
+	}
+
+	if _, ok := Classify(board.NewPosition(), nil); ok {
+		t.Error("empty game named")
+	}
+}
@@ -0,0 +1,249 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// Threats lists the pieces of one side that the opponent threatens.
+// Kings are never included.
+type Threats struct {
+	Hanging board.Bitboard // Attacked and not defended
+	ByPawn  board.Bitboard // Pieces attacked by a pawn
+	ByMinor board.Bitboard // Rooks and queens attacked by a knight or bishop
+}
+
+// All returns every threatened piece.
+func (t Threats) All() board.Bitboard {
+	return t.Hanging | t.ByPawn | t.ByMinor
+}
+
+// FindThreats returns the threats against the pieces of the given color,
+// using the same attack maps as the evaluation's threat term.
+func FindThreats(pos *board.Position, color board.Color) Threats {
+	enemy := color.Other()
+	occupied := pos.AllOccupied
+
+	enemyPawnAttacks := computePawnAttacksBB(pos, enemy)
+	enemyMinorAttacks := computeKnightAttacksBB(pos, enemy) | computeBishopAttacksBB(pos, enemy, occupied)
+	enemyAttacks := enemyPawnAttacks | enemyMinorAttacks |
+		computeRookAttacksBB(pos, enemy, occupied) | computeQueenAttacksBB(pos, enemy, occupied) |
+		board.KingAttacks(pos.KingSquare[enemy])
+	ourAttacks := allAttacksBB(pos, color)
+
+	pieces := pos.Occupied[color] &^ board.SquareBB(pos.KingSquare[color])
+	majors := pos.Pieces[color][board.Rook] | pos.Pieces[color][board.Queen]
+	return Threats{
+		Hanging: pieces & enemyAttacks &^ ourAttacks,
+		ByPawn:  pieces &^ pos.Pieces[color][board.Pawn] & enemyPawnAttacks,
+		ByMinor: majors & enemyMinorAttacks,
+	}
+}
+
+// allAttacksBB returns every square attacked by the given color.
+func allAttacksBB(pos *board.Position, color board.Color) board.Bitboard {
+	occupied := pos.AllOccupied
+	return computePawnAttacksBB(pos, color) | computeKnightAttacksBB(pos, color) |
+		computeBishopAttacksBB(pos, color, occupied) | computeRookAttacksBB(pos, color, occupied) |
+		computeQueenAttacksBB(pos, color, occupied) | board.KingAttacks(pos.KingSquare[color])
+}
+
+// Coach commentary limits
+const (
+	coachTermThreshold = 25 // Smallest eval term change worth a comment, in centipawns
+	coachMaxTerms      = 2  // At most this many eval term comments per move
+	coachMaxSquares    = 2  // At most this many pieces or squares per comment
+)
+
+// coachTermPhrases describes an eval term getting better or worse for the
+// side that moved. Material and PST are left out: captures are described
+// directly and piece-square changes are too noisy to explain. Threats are
+// described through FindThreats.
+var coachTermPhrases = map[string][2]string{
+	"Passed pawns":   {"Strengthens the passed pawns", "Weakens the passed pawns"},
+	"Mobility":       {"Gives the pieces more mobility", "Leaves the pieces less mobile"},
+	"King safety":    {"Improves king safety", "Exposes the king"},
+	"King tropism":   {"Brings pieces closer to the enemy king", "Pulls pieces away from the enemy king"},
+	"Bishop pair":    {"Wins the bishop pair", "Gives up the bishop pair"},
+	"Rooks on files": {"Activates a rook on an open file", "Takes a rook off its open file"},
+	"Coordination":   {"Improves piece coordination", "Leaves the pieces less coordinated"},
+	"Pawn structure": {"Improves the pawn structure", "Weakens the pawn structure"},
+	"Outposts":       {"Occupies an outpost", "Gives up an outpost"},
+	"Space":          {"Gains space", "Gives up space"},
+	"Trapped pieces": {"Frees a trapped piece", "Gets a piece trapped"},
+}
+
+// CoachNotes explains a move in plain language for the GUI's coach: material
+// won, check, new threats, pieces left en prise or rescued, weakened squares
+// and the evaluation terms that changed the most. pos is the position before
+// the move and is not modified.
+func CoachNotes(pos *board.Position, move board.Move) []string {
+	us := pos.SideToMove
+	them := us.Other()
+	piece := pos.PieceAt(move.From()).Type()
+	captured := board.NoPieceType
+	if move.IsEnPassant() {
+		captured = board.Pawn
+	} else if move.IsCapture(pos) {
+		captured = pos.PieceAt(move.To()).Type()
+	}
+
+	after := pos.Copy()
+	after.MakeMove(move)
+	after.UpdateCheckers()
+
+	var notes []string
+
+	// Material
+	if captured != board.NoPieceType {
+		notes = append(notes, "Captures a "+pieceName(captured)+".")
+	}
+	if move.IsPromotion() {
+		notes = append(notes, "Promotes to a "+pieceName(move.Promotion())+".")
+	}
+	if captured != board.NoPieceType || move.IsPromotion() {
+		notes = append(notes, materialBalance(after))
+	}
+
+	// Check and mate
+	if after.InCheck() {
+		if after.GenerateLegalMoves().Len() == 0 {
+			notes = append(notes, "Checkmate!")
+			return notes
+		}
+		notes = append(notes, "Gives check.")
+	}
+
+	// Threats created against the opponent
+	newThreats := FindThreats(after, them).All() &^ FindThreats(pos, them).All()
+	if newThreats != 0 {
+		notes = append(notes, "Threatens "+describePieces(after, newThreats)+".")
+	}
+
+	// Our pieces: rescued or newly left en prise
+	threatsBefore := FindThreats(pos, us)
+	threatsAfter := FindThreats(after, us)
+	if threatsBefore.All().IsSet(move.From()) && !threatsAfter.All().IsSet(move.To()) {
+		notes = append(notes, "Moves the "+pieceName(piece)+" out of danger.")
+	}
+	if hanging := threatsAfter.Hanging &^ threatsBefore.Hanging; hanging != 0 {
+		notes = append(notes, "Leaves "+describePieces(after, hanging)+" undefended.")
+	}
+
+	// Squares our pawns can no longer defend
+	if piece == board.Pawn {
+		if weak := pawnHoles(after, us) &^ pawnHoles(pos, us); weak != 0 {
+			notes = append(notes, "Weakens "+describeSquares(weak)+".")
+		}
+	}
+
+	// The game moving on to the next phase
+	if phase := after.Phase(); phase > pos.Phase() {
+		notes = append(notes, "The game enters the "+phase.String()+".")
+	}
+
+	return append(notes, termNotes(pos, after, us)...)
+}
+
+// termNotes describes the largest changes of the positional eval terms,
+// from the perspective of the side that moved.
+func termNotes(before, after *board.Position, us board.Color) []string {
+	tb, ta := TraceEvaluate(before), TraceEvaluate(after)
+
+	type change struct {
+		name  string
+		delta int
+	}
+	var changes []change
+	for i, term := range ta.Terms {
+		if _, ok := coachTermPhrases[term.Name]; !ok {
+			continue
+		}
+		// Both traces list the same terms in the same order
+		delta := taper(term.MG-tb.Terms[i].MG, term.EG-tb.Terms[i].EG, ta.Phase)
+		if us == board.Black {
+			delta = -delta
+		}
+		if delta >= coachTermThreshold || delta <= -coachTermThreshold {
+			changes = append(changes, change{term.Name, delta})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return abs(changes[i].delta) > abs(changes[j].delta)
+	})
+
+	var notes []string
+	for i := 0; i < len(changes) && i < coachMaxTerms; i++ {
+		phrases := coachTermPhrases[changes[i].name]
+		phrase := phrases[0]
+		if changes[i].delta < 0 {
+			phrase = phrases[1]
+		}
+		notes = append(notes, fmt.Sprintf("%s (%+.1f).", phrase, float64(changes[i].delta)/100))
+	}
+	return notes
+}
+
+// taper blends middlegame and endgame values by game phase (24 = middlegame).
+func taper(mg, eg, phase int) int {
+	return (mg*phase + eg*(24-phase)) / 24
+}
+
+// materialBalance describes who is ahead in material, in pawns.
+func materialBalance(pos *board.Position) string {
+	balance := pos.Material()
+	switch {
+	case balance >= 50:
+		return fmt.Sprintf("White is ahead in material (%+.1f).", float64(balance)/100)
+	case balance <= -50:
+		return fmt.Sprintf("Black is ahead in material (%+.1f).", float64(-balance)/100)
+	}
+	return "Material is level."
+}
+
+// pawnHoles returns the squares on a side's third to fifth ranks, off the
+// rook files, that none of its pawns can attack any more.
+func pawnHoles(pos *board.Position, color board.Color) board.Bitboard {
+	pawns := pos.Pieces[color][board.Pawn]
+	zone := (board.Rank3 | board.Rank4 | board.Rank5) &^ (board.FileA | board.FileH)
+	var span board.Bitboard
+	if color == board.White {
+		fill := pawns.NorthFill()
+		span = fill.NorthEast() | fill.NorthWest()
+	} else {
+		zone = (board.Rank4 | board.Rank5 | board.Rank6) &^ (board.FileA | board.FileH)
+		fill := pawns.SouthFill()
+		span = fill.SouthEast() | fill.SouthWest()
+	}
+	return zone &^ span
+}
+
+// describePieces names the pieces on a set of squares, e.g. "the knight on f6".
+func describePieces(pos *board.Position, bb board.Bitboard) string {
+	var names []string
+	for bb != 0 && len(names) < coachMaxSquares {
+		sq := bb.PopLSB()
+		names = append(names, "the "+pieceName(pos.PieceAt(sq).Type())+" on "+sq.String())
+	}
+	return strings.Join(names, " and ")
+}
+
+// describeSquares names a set of squares, e.g. "the d5 and e5 squares".
+func describeSquares(bb board.Bitboard) string {
+	var names []string
+	for bb != 0 && len(names) < coachMaxSquares {
+		names = append(names, bb.PopLSB().String())
+	}
+	if len(names) == 1 {
+		return "the " + names[0] + " square"
+	}
+	return "the " + strings.Join(names, " and ") + " squares"
+}
+
+// pieceName returns the lowercase name of a piece type.
+func pieceName(pt board.PieceType) string {
+	return strings.ToLower(pt.String())
+}
@@ -8,14 +8,46 @@ import (
 const CorrectionHistorySize = 262144 // 2^18
 const CorrectionHistoryMask = CorrectionHistorySize - 1
 
+// Structure-keyed tables are smaller: far fewer distinct pawn/piece configurations occur
+const structureCorrSize = 16384 // 2^14
+const structureCorrMask = structureCorrSize - 1
+
+// Correction history limits
+const (
+	corrBonusLimit = 256  // Maximum single update
+	corrValueLimit = 1024 // Maximum stored correction per table (centipawns)
+)
+
+// Correction table weights (Stockfish search.cpp correction_value)
+// Each table learns the same eval error, so they are blended rather than summed.
+const (
+	corrWeightPosition     = 3
+	corrWeightPawn         = 3
+	corrWeightMinor        = 2
+	corrWeightNonPawn      = 2 // Per color
+	corrWeightContinuation = 2
+	corrWeightTotal        = corrWeightPosition + corrWeightPawn + corrWeightMinor +
+		2*corrWeightNonPawn + corrWeightContinuation
+)
+
 // CorrectionHistory adjusts static evaluation based on search results.
 // When the search discovers the static eval was wrong, we record the error
 // and apply corrections to similar positions in the future.
-// Based on Stockfish's correction history.
+// Based on Stockfish's correction history, with separate tables keyed by
+// the full position, pawn structure, minor pieces, each side's non-pawn
+// material and the previous move.
 type CorrectionHistory struct {
 	// Position-based correction indexed by hash
 	// Uses 16-bit entries to save memory (512KB total)
 	positionCorr [CorrectionHistorySize]int16
+
+	// Structure-based corrections, indexed by side to move
+	pawnCorr    [2][structureCorrSize]int16
+	minorCorr   [2][structureCorrSize]int16
+	nonPawnCorr [2][2][structureCorrSize]int16 // [material color][side to move]
+
+	// Continuation correction: indexed by the previous move's piece and destination
+	contCorr [2][12][64]int16
 }
 
 // NewCorrectionHistory creates a new correction history table.
@@ -30,17 +62,80 @@ func (ch *CorrectionHistory) hashIndex(hash uint64) int {
 	return int((hash ^ (hash >> 18)) & CorrectionHistoryMask)
 }
 
+// structureIndex folds a structure key into a structure table index.
+func structureIndex(key uint64) int {
+	key ^= key >> 32
+	key *= 0x9E3779B97F4A7C15
+	return int((key >> 40) & structureCorrMask)
+}
+
+// minorKey hashes the knight, bishop and king placement of both sides.
+func minorKey(pos *board.Position) uint64 {
+	var key uint64
+	for c := board.White; c <= board.Black; c++ {
+		key = key*0xFF51AFD7ED558CCD ^ uint64(pos.Pieces[c][board.Knight])
+		key = key*0xFF51AFD7ED558CCD ^ uint64(pos.Pieces[c][board.Bishop])
+		key = key*0xFF51AFD7ED558CCD ^ uint64(pos.Pieces[c][board.King])
+	}
+	return key
+}
+
+// nonPawnKey hashes the non-pawn piece placement of one side.
+func nonPawnKey(pos *board.Position, c board.Color) uint64 {
+	var key uint64
+	for pt := board.Knight; pt <= board.King; pt++ {
+		key = key*0xC4CEB9FE1A85EC53 ^ uint64(pos.Pieces[c][pt])
+	}
+	return key
+}
+
+// corrIndices holds the table indices for one position, shared by Get and Update.
+type corrIndices struct {
+	stm       board.Color
+	position  int
+	pawn      int
+	minor     int
+	nonPawn   [2]int
+	prevPiece board.Piece
+	prevTo    board.Square
+}
+
+// indices computes all table indices for a position.
+// prevPiece is board.NoPiece when there is no previous move (root or after a null move).
+func (ch *CorrectionHistory) indices(pos *board.Position, prevPiece board.Piece, prevTo board.Square) corrIndices {
+	return corrIndices{
+		stm:       pos.SideToMove,
+		position:  ch.hashIndex(pos.Hash),
+		pawn:      structureIndex(pos.PawnKey),
+		minor:     structureIndex(minorKey(pos)),
+		nonPawn:   [2]int{structureIndex(nonPawnKey(pos, board.White)), structureIndex(nonPawnKey(pos, board.Black))},
+		prevPiece: prevPiece,
+		prevTo:    prevTo,
+	}
+}
+
 // Get returns the correction value for a position.
 // The correction should be added to the static evaluation.
-func (ch *CorrectionHistory) Get(pos *board.Position) int {
-	idx := ch.hashIndex(pos.Hash)
-	return int(ch.positionCorr[idx])
+func (ch *CorrectionHistory) Get(pos *board.Position, prevPiece board.Piece, prevTo board.Square) int {
+	idx := ch.indices(pos, prevPiece, prevTo)
+	stm := idx.stm
+
+	sum := corrWeightPosition*int(ch.positionCorr[idx.position]) +
+		corrWeightPawn*int(ch.pawnCorr[stm][idx.pawn]) +
+		corrWeightMinor*int(ch.minorCorr[stm][idx.minor]) +
+		corrWeightNonPawn*int(ch.nonPawnCorr[board.White][stm][idx.nonPawn[board.White]]) +
+		corrWeightNonPawn*int(ch.nonPawnCorr[board.Black][stm][idx.nonPawn[board.Black]])
+	if idx.prevPiece < board.NoPiece {
+		sum += corrWeightContinuation * int(ch.contCorr[stm][idx.prevPiece][idx.prevTo])
+	}
+
+	return sum / corrWeightTotal
 }
 
 // Update records a correction based on the difference between
 // the static evaluation and the search result.
 // Uses gravity update: new = old + (target - old) / 16
-func (ch *CorrectionHistory) Update(pos *board.Position, searchScore, staticEval, depth int) {
+func (ch *CorrectionHistory) Update(pos *board.Position, prevPiece board.Piece, prevTo board.Square, searchScore, staticEval, depth int) {
 	// Only update if we have meaningful data
 	if depth < 1 {
 		return
@@ -53,38 +148,66 @@ func (ch *CorrectionHistory) Update(pos *board.Position, searchScore, staticEval
 	bonus := diff * depth / 8
 
 	// Clamp the bonus to prevent extreme updates
-	if bonus > 256 {
-		bonus = 256
-	} else if bonus < -256 {
-		bonus = -256
+	if bonus > corrBonusLimit {
+		bonus = corrBonusLimit
+	} else if bonus < -corrBonusLimit {
+		bonus = -corrBonusLimit
 	}
 
-	idx := ch.hashIndex(pos.Hash)
-	old := int(ch.positionCorr[idx])
+	idx := ch.indices(pos, prevPiece, prevTo)
+	stm := idx.stm
+
+	updateCorr(&ch.positionCorr[idx.position], bonus)
+	updateCorr(&ch.pawnCorr[stm][idx.pawn], bonus)
+	updateCorr(&ch.minorCorr[stm][idx.minor], bonus)
+	updateCorr(&ch.nonPawnCorr[board.White][stm][idx.nonPawn[board.White]], bonus)
+	updateCorr(&ch.nonPawnCorr[board.Black][stm][idx.nonPawn[board.Black]], bonus)
+	if idx.prevPiece < board.NoPiece {
+		updateCorr(&ch.contCorr[stm][idx.prevPiece][idx.prevTo], bonus)
+	}
+}
+
+// updateCorr applies a gravity update to one entry and clamps it.
+func updateCorr(entry *int16, bonus int) {
+	old := int(*entry)
 
 	// Gravity update: gradually move toward the target
 	newVal := old + (bonus-old)/16
 
-	// Clamp to int16 range but with reasonable limits
-	if newVal > 16000 {
-		newVal = 16000
-	} else if newVal < -16000 {
-		newVal = -16000
+	if newVal > corrValueLimit {
+		newVal = corrValueLimit
+	} else if newVal < -corrValueLimit {
+		newVal = -corrValueLimit
 	}
 
-	ch.positionCorr[idx] = int16(newVal)
+	*entry = int16(newVal)
 }
 
 // Clear resets all correction values.
 func (ch *CorrectionHistory) Clear() {
-	for i := range ch.positionCorr {
-		ch.positionCorr[i] = 0
-	}
+	*ch = CorrectionHistory{}
 }
 
-// Age scales down all correction values (called between games/positions).
+// Age scales down all correction values by a quarter. Within a game the
+// tables carry over from search to search, every update already averaging
+// out older corrections. Between games (Engine.Clear, on ucinewgame) they
+// are aged rather than cleared: corrections for pawn structures and
+// material balances mostly still hold, but should give way to new ones.
 func (ch *CorrectionHistory) Age() {
 	for i := range ch.positionCorr {
-		ch.positionCorr[i] /= 2
+		ch.positionCorr[i] = ch.positionCorr[i] * 3 / 4
+	}
+	for stm := 0; stm < 2; stm++ {
+		for i := 0; i < structureCorrSize; i++ {
+			ch.pawnCorr[stm][i] = ch.pawnCorr[stm][i] * 3 / 4
+			ch.minorCorr[stm][i] = ch.minorCorr[stm][i] * 3 / 4
+			ch.nonPawnCorr[board.White][stm][i] = ch.nonPawnCorr[board.White][stm][i] * 3 / 4
+			ch.nonPawnCorr[board.Black][stm][i] = ch.nonPawnCorr[board.Black][stm][i] * 3 / 4
+		}
+		for pc := range ch.contCorr[stm] {
+			for sq := range ch.contCorr[stm][pc] {
+				ch.contCorr[stm][pc][sq] = ch.contCorr[stm][pc][sq] * 3 / 4
+			}
+		}
 	}
 }
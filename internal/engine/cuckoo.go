@@ -0,0 +1,127 @@
+package engine
+
+import "github.com/hailam/chessplay/internal/board"
+
+// Cuckoo tables for upcoming repetition detection (Marcel van Kervinck's
+// method, as in Stockfish). Every reversible move of a knight, bishop, rook,
+// queen or king changes the hash by a key that depends only on the piece and
+// its two squares. The tables hold these keys with their moves, so a single
+// lookup of the hash difference between the current position and an earlier
+// one tells whether one move connects them.
+
+// cuckooSize is the number of slots of each table.
+const cuckooSize = 8192
+
+// cuckooMove is a reversible move between two squares, in either direction.
+type cuckooMove struct {
+	from, to board.Square
+}
+
+var (
+	cuckooKeys  [cuckooSize]uint64
+	cuckooMoves [cuckooSize]cuckooMove
+	cuckooCount int // Moves stored (3668)
+)
+
+// cuckooH1 and cuckooH2 are the two slots a key may occupy.
+func cuckooH1(key uint64) int { return int(key & (cuckooSize - 1)) }
+func cuckooH2(key uint64) int { return int((key >> 16) & (cuckooSize - 1)) }
+
+func init() {
+	for c := board.White; c <= board.Black; c++ {
+		for pt := board.Knight; pt <= board.King; pt++ {
+			for s1 := board.A1; s1 <= board.H8; s1++ {
+				for s2 := s1 + 1; s2 <= board.H8; s2++ {
+					if emptyBoardAttacks(pt, s1)&board.SquareBB(s2) == 0 {
+						continue
+					}
+					key := board.ZobristPiece(c, pt, s1) ^ board.ZobristPiece(c, pt, s2) ^ board.ZobristSideToMove()
+					move := cuckooMove{s1, s2}
+
+					// Insert, moving any key in the way to its other slot
+					i := cuckooH1(key)
+					for {
+						cuckooKeys[i], key = key, cuckooKeys[i]
+						cuckooMoves[i], move = move, cuckooMoves[i]
+						if key == 0 {
+							break
+						}
+						if i == cuckooH1(key) {
+							i = cuckooH2(key)
+						} else {
+							i = cuckooH1(key)
+						}
+					}
+					cuckooCount++
+				}
+			}
+		}
+	}
+}
+
+// emptyBoardAttacks returns the squares a piece of type pt attacks from sq
+// on an empty board.
+func emptyBoardAttacks(pt board.PieceType, sq board.Square) board.Bitboard {
+	switch pt {
+	case board.Knight:
+		return board.KnightAttacks(sq)
+	case board.Bishop:
+		return board.BishopAttacks(sq, 0)
+	case board.Rook:
+		return board.RookAttacks(sq, 0)
+	case board.Queen:
+		return board.QueenAttacks(sq, 0)
+	case board.King:
+		return board.KingAttacks(sq)
+	}
+	return 0
+}
+
+// cuckooLookup returns the move that changes the hash by key, if any.
+func cuckooLookup(key uint64) (cuckooMove, bool) {
+	if i := cuckooH1(key); cuckooKeys[i] == key {
+		return cuckooMoves[i], true
+	}
+	if i := cuckooH2(key); cuckooKeys[i] == key {
+		return cuckooMoves[i], true
+	}
+	return cuckooMove{}, false
+}
+
+// upcomingRepetition returns true if the side to move has a move to a
+// position already on the path, so it can force a draw by repetition.
+// Positions before the root count only if they already occurred twice, as
+// for isDraw.
+func (w *Worker) upcomingRepetition() bool {
+	cur := w.posHistoryLen - 1
+	first := w.repetitionStart()
+	if cur-3 < first {
+		return false
+	}
+	occupied := w.pos.AllOccupied
+	for i := cur - 3; i >= first; i -= 2 {
+		move, ok := cuckooLookup(w.pos.Hash ^ w.posHistoryBuffer[i])
+		if !ok || board.Between(move.from, move.to)&occupied != 0 {
+			continue
+		}
+		if i > w.rootHistoryIdx {
+			return true
+		}
+
+		// Before the root, the move must be ours, not a move of the opponent
+		// that led here, and the position must already be a repetition
+		sq := move.from
+		if w.pos.PieceAt(sq) == board.NoPiece {
+			sq = move.to
+		}
+		if w.pos.PieceAt(sq).Color() != w.pos.SideToMove {
+			continue
+		}
+		for j := i - 4; j >= first; j -= 2 {
+			if w.posHistoryBuffer[j] == w.posHistoryBuffer[i] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,8 +1,11 @@
 package engine
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -26,8 +29,24 @@ type SearchInfo struct {
 	Time     time.Duration
 	PV       []board.Move
 	HashFull int // Permille of hash table used
+	SelDepth int // Deepest ply reached, including quiescence
+
+	TB tablebase.WDLCacheStats // Tablebase probes of this search
+}
+
+// CurrMoveInfo reports the root move the main worker is currently searching.
+type CurrMoveInfo struct {
+	Depth      int
+	Move       board.Move
+	MoveNumber int // 1-based position in the root move order
 }
 
+// Progress reporting intervals
+const (
+	currMoveDelay = time.Second // Root move reports start after this much search time
+	statsInterval = time.Second // Period of OnStats updates
+)
+
 // SearchLimits specifies constraints on the search.
 type SearchLimits struct {
 	Depth    int           // Maximum depth (0 = no limit)
@@ -35,6 +54,10 @@ type SearchLimits struct {
 	MoveTime time.Duration // Time for this move (0 = no limit)
 	Infinite bool          // Search until stopped
 	MultiPV  int           // Number of principal variations to find (0 or 1 = single best move)
+	Mate     int           // Stop once a forced mate in this many moves is proven (0 = no limit)
+
+	// SearchMoves restricts the root to these moves (UCI "go searchmoves"; nil = all moves)
+	SearchMoves []board.Move
 }
 
 // SearchResult contains the result of a single PV search.
@@ -67,8 +90,14 @@ type Engine struct {
 	workers       []*Worker
 	pawnTable     *PawnTable
 	tt            *TranspositionTable
+	evalCache     *EvalCache     // Static evaluations shared by the workers
 	sharedHistory *SharedHistory // Shared history for Lazy SMP
 	stopFlag      atomic.Bool
+	nodeCounter   atomic.Uint64 // Nodes published by all workers (for node limits)
+	timeMan       *TimeManager  // Soft/hard time bounds, driven by the main worker
+	features      atomic.Uint32 // SearchFeatures applied to the workers at each search
+	contempt      atomic.Int32  // Draw penalty for the side to move at the root, in centipawns
+	nodesTime     atomic.Int64  // Nodes per millisecond of clock time (0 = real time, see SetNodesTime)
 
 	// Legacy single-threaded searcher (for Multi-PV compatibility)
 	searcher *Searcher
@@ -76,19 +105,58 @@ type Engine struct {
 	difficulty Difficulty
 	book       *book.Book
 	tablebase  tablebase.Prober
+	tbCache    *tablebase.WDLCache // Wraps tablebase; shared by the workers
+
+	// Opening book limits and state
+	bookMaxPly    int         // The book is not probed from this game ply on (0 = no limit)
+	bookMinWeight uint16      // Book entries with a lower weight are ignored
+	bookOpening   bool        // The book is only probed in the opening phase
+	bookExited    atomic.Bool // The book had no move in this game (reset by Clear)
 
 	// Position history for repetition detection
 	rootPosHashes []uint64
 
+	// Root moves to avoid (see AvoidMoves)
+	forbiddenMoves    []board.Move
+	discouragedMoves  []board.Move
+	discouragePenalty int
+
+	// Node budget left on the engine's clock under nodestime, carried from
+	// move to move (-1 = not started in this game; reset by Clear)
+	availableNodes int64
+
+	// Hard time bound of the running search; armed at PonderHit while pondering
+	hardStopMu sync.Mutex
+	hardStop   *time.Timer
+
+	// Summary of the last search (zero for book and tablebase moves)
+	lastSearch SearchInfo
+
+	// Search statistics, collected when collectStats is set
+	collectStats atomic.Bool
+	lastStats    SearchStats
+
+	// Activity counters for monitoring, read concurrently by Stats
+	searching   atomic.Bool
+	searchStart atomic.Int64 // Start of the running search, in Unix nanoseconds
+	searches    atomic.Uint64
+	nodesTotal  atomic.Uint64
+	lastNPS     atomic.Uint64
+
 	// NNUE evaluation
-	useNNUE bool
-	nnueNet *sfnnue.Networks // Shared networks (immutable after load)
+	useNNUE   bool
+	nnueNet   *sfnnue.Networks // Shared networks (immutable after load)
+	nnueBig   string           // Paths of the loaded networks
+	nnueSmall string
 
 	// Debug mode
 	debug bool
 
 	// Callbacks
-	OnInfo func(SearchInfo)
+	OnInfo     func(SearchInfo)
+	OnCurrMove func(CurrMoveInfo) // Root move progress from the main worker
+	OnStats    func(SearchInfo)   // Periodic nodes/nps/hashfull update (no score or PV)
+	OnBookExit func()             // The book had no move for the first time in this game
 }
 
 // SetDebug enables or disables debug logging in the engine.
@@ -101,27 +169,39 @@ func (e *Engine) SetDebug(enabled bool) {
 
 // NewEngine creates a new chess engine with the given transposition table size in MB.
 func NewEngine(ttSizeMB int) *Engine {
+	return newEngine(ttSizeMB, NumWorkers)
+}
+
+// newEngine creates an engine with the given number of search workers.
+func newEngine(ttSizeMB, numWorkers int) *Engine {
 	tt := NewTranspositionTable(ttSizeMB)
 	sharedHistory := NewSharedHistory()
 
 	e := &Engine{
 		tt:            tt,
+		evalCache:     NewEvalCache(evalCacheEntries),
 		pawnTable:     NewPawnTable(1), // Shared pawn table for legacy searcher
 		sharedHistory: sharedHistory,
 		difficulty:    Medium,
-		workers:       make([]*Worker, NumWorkers),
+		workers:       make([]*Worker, numWorkers),
+		timeMan:       NewTimeManager(),
+
+		availableNodes: -1,
 	}
 
-	log.Printf("[Engine] Creating %d workers (GOMAXPROCS=%d)", NumWorkers, runtime.GOMAXPROCS(0))
+	log.Printf("[Engine] Creating %d workers (GOMAXPROCS=%d)", numWorkers, runtime.GOMAXPROCS(0))
 
 	// Create workers, each with its own pawn table for thread safety
-	for i := 0; i < NumWorkers; i++ {
+	for i := 0; i < numWorkers; i++ {
 		workerPawnTable := NewPawnTable(1) // 1MB per worker
 		e.workers[i] = NewWorker(i, tt, workerPawnTable, sharedHistory, &e.stopFlag)
+		e.workers[i].evalCache = e.evalCache
 	}
 
 	// Create legacy searcher for Multi-PV
 	e.searcher = NewSearcher(tt)
+	e.searcher.worker.evalCache = e.evalCache
+	e.features.Store(uint32(DefaultSearchFeatures))
 
 	return e
 }
@@ -131,19 +211,34 @@ func (e *Engine) SetDifficulty(d Difficulty) {
 	e.difficulty = d
 }
 
+// Difficulty returns the engine difficulty.
+func (e *Engine) Difficulty() Difficulty {
+	return e.difficulty
+}
+
 // LoadBook loads an opening book from a Polyglot file.
 func (e *Engine) LoadBook(filename string) error {
 	b, err := book.LoadPolyglot(filename)
 	if err != nil {
 		return err
 	}
-	e.book = b
+	e.book.Close()
+	e.SetBook(b)
 	return nil
 }
 
-// SetBook sets the opening book.
+// SetBook sets the opening book (nil for none).
 func (e *Engine) SetBook(b *book.Book) {
 	e.book = b
+	if b != nil {
+		b.SetMinWeight(e.bookMinWeight)
+	}
+}
+
+// CloseBook unloads the opening book.
+func (e *Engine) CloseBook() {
+	e.book.Close()
+	e.book = nil
 }
 
 // HasBook returns true if an opening book is loaded.
@@ -151,12 +246,112 @@ func (e *Engine) HasBook() bool {
 	return e.book != nil
 }
 
+// SetBookMaxPly stops book probing from the given game ply on (0 = no limit).
+func (e *Engine) SetBookMaxPly(ply int) {
+	e.bookMaxPly = ply
+}
+
+// SetBookOpeningOnly stops book probing once the game leaves the opening
+// phase (see board.Position.Phase), whatever the book still knows.
+func (e *Engine) SetBookOpeningOnly(enabled bool) {
+	e.bookOpening = enabled
+}
+
+// SetBookMinWeight makes the book ignore entries with a lower weight.
+func (e *Engine) SetBookMinWeight(w int) {
+	e.bookMinWeight = uint16(max(0, min(w, 65535)))
+	if e.book != nil {
+		e.book.SetMinWeight(e.bookMinWeight)
+	}
+}
+
+// SetContempt sets how many centipawns a draw costs the engine, from the
+// side to move at the root: positive values avoid draws, negative ones seek
+// them. A nonzero contempt also grows or shrinks with the search's optimism.
+// Like search features, it applies from the next search on.
+func (e *Engine) SetContempt(cp int) {
+	e.contempt.Store(int32(max(-maxContempt, min(cp, maxContempt))))
+}
+
+// Contempt returns the contempt set with SetContempt.
+func (e *Engine) Contempt() int {
+	return int(e.contempt.Load())
+}
+
+// SetNodesTime makes clock-based searches count time in searched nodes,
+// npmsec nodes to the millisecond (0 turns it off), as Stockfish's nodestime
+// option does for testing frameworks. The engine's clock at its first move
+// of a game becomes a node budget, which then only shrinks by the nodes it
+// searches and grows by the increment; the clock the GUI sends later is
+// ignored. Games are reproducible whatever the hardware, given one worker.
+// Fixed move time searches still run on the clock.
+func (e *Engine) SetNodesTime(npmsec int) {
+	e.nodesTime.Store(int64(max(0, npmsec)))
+}
+
+// NodesTime returns the nodes per millisecond set with SetNodesTime.
+func (e *Engine) NodesTime() int {
+	return int(e.nodesTime.Load())
+}
+
+// nodesTimeLimits turns the clock of the side to move into its node budget,
+// in milliseconds of npmsec nodes. It returns false when the search is not
+// clock-based, so the budget does not apply.
+func (e *Engine) nodesTimeLimits(limits UCILimits, us board.Color, npmsec int64) (UCILimits, bool) {
+	if limits.Time[us] <= 0 || limits.MoveTime > 0 || limits.Infinite {
+		return limits, false
+	}
+	if e.availableNodes < 0 {
+		e.availableNodes = npmsec * limits.Time[us].Milliseconds()
+	}
+	// A spent budget still leaves the minimum time, never an untimed search
+	limits.Time[us] = time.Duration(e.availableNodes/npmsec) * time.Millisecond
+	if limits.Time[us] < time.Millisecond {
+		limits.Time[us] = time.Millisecond
+	}
+	return limits, true
+}
+
+// InBook returns true if a book is loaded and it has not run out of moves
+// in this game yet.
+func (e *Engine) InBook() bool {
+	return e.book != nil && !e.bookExited.Load()
+}
+
+// probeBook returns a book move for the position. The first miss in a game
+// is reported through OnBookExit.
+func (e *Engine) probeBook(pos *board.Position) (board.Move, bool) {
+	ply := 2 * (pos.FullMoveNumber - 1)
+	if pos.SideToMove == board.Black {
+		ply++
+	}
+	inPhase := !e.bookOpening || pos.Phase() == board.PhaseOpening
+	if (e.bookMaxPly == 0 || ply < e.bookMaxPly) && inPhase {
+		if move, ok := e.book.Probe(pos); ok && move != board.NoMove {
+			return move, true
+		}
+	}
+
+	if !e.bookExited.Swap(true) {
+		if e.OnBookExit != nil {
+			e.OnBookExit()
+		}
+	}
+	return board.NoMove, false
+}
+
 // SetTablebase sets the tablebase prober for the engine and all workers.
+// Search probes go through a WDL cache shared by the workers.
 func (e *Engine) SetTablebase(tb tablebase.Prober) {
-	e.tablebase = tb
+	e.tablebase = nil
+	e.tbCache = nil
+	if tb != nil {
+		e.tbCache = tablebase.NewWDLCache(tb, tablebase.DefaultWDLCacheEntries)
+		e.tablebase = e.tbCache
+	}
 	// Pass tablebase to all workers with default probe depth
 	for _, w := range e.workers {
-		w.SetTablebase(tb, 1)
+		w.SetTablebase(e.tablebase, 1)
 	}
 }
 
@@ -172,11 +367,15 @@ func (e *Engine) SetSyzygyProbeDepth(depth int) {
 
 // EnableLichessTablebase enables Lichess online tablebase lookups.
 func (e *Engine) EnableLichessTablebase() {
-	e.tablebase = tablebase.NewLichessProber()
-	// Pass to all workers
-	for _, w := range e.workers {
-		w.SetTablebase(e.tablebase, 1)
+	e.SetTablebase(tablebase.NewLichessProber())
+}
+
+// tbStats returns the tablebase probe counts of the running search.
+func (e *Engine) tbStats() tablebase.WDLCacheStats {
+	if e.tbCache == nil {
+		return tablebase.WDLCacheStats{}
 	}
+	return e.tbCache.Stats()
 }
 
 // HasTablebase returns true if a tablebase is available.
@@ -199,6 +398,24 @@ func (e *Engine) SetPositionHistory(hashes []uint64) {
 	e.searcher.SetRootHistory(hashes)
 }
 
+// AvoidMoves sets root moves that the following searches avoid, until it is
+// called again (nil lists clear them). Forbidden moves are not searched, and
+// played only if nothing else is legal. Discouraged moves score penalty
+// centipawns lower, so they are played only when better by more than that.
+// The opening book and tablebases are skipped when they suggest either.
+// This lets a GUI steer away from a repetition after declining a draw, or a
+// bot follow anti-draw rules.
+func (e *Engine) AvoidMoves(forbidden, discouraged []board.Move, penalty int) {
+	e.forbiddenMoves = slices.Clone(forbidden)
+	e.discouragedMoves = slices.Clone(discouraged)
+	e.discouragePenalty = penalty
+}
+
+// avoided returns true if a move is forbidden or discouraged.
+func (e *Engine) avoided(move board.Move) bool {
+	return slices.Contains(e.forbiddenMoves, move) || slices.Contains(e.discouragedMoves, move)
+}
+
 // Search finds the best move for the given position.
 func (e *Engine) Search(pos *board.Position) board.Move {
 	limits := DifficultySettings[e.difficulty]
@@ -208,31 +425,87 @@ func (e *Engine) Search(pos *board.Position) board.Move {
 // SearchWithLimits finds the best move with specific search limits.
 // Uses Lazy SMP with multiple workers searching in parallel.
 func (e *Engine) SearchWithLimits(pos *board.Position, limits SearchLimits) board.Move {
-	// Try opening book first
-	if e.book != nil {
-		if move, ok := e.book.Probe(pos); ok {
+	return e.SearchWithUCILimits(pos, UCILimits{
+		MoveTime:    limits.MoveTime,
+		Depth:       limits.Depth,
+		Nodes:       limits.Nodes,
+		Mate:        limits.Mate,
+		Infinite:    limits.Infinite,
+		SearchMoves: limits.SearchMoves,
+	}, 0)
+}
+
+// SearchWithUCILimits finds the best move using UCI time controls.
+// Supports wtime/btime/winc/binc for proper tournament time management.
+// Time management is done by the main worker (see TimeManager); this function
+// only enforces the hard bound and collects results.
+func (e *Engine) SearchWithUCILimits(pos *board.Position, limits UCILimits, ply int) board.Move {
+	e.lastSearch = SearchInfo{}
+	e.lastStats = SearchStats{}
+
+	// Try opening book first (not when the root moves are restricted)
+	if e.book != nil && len(limits.SearchMoves) == 0 {
+		if move, ok := e.probeBook(pos); ok && !e.avoided(move) {
 			return move
 		}
 	}
 
 	// Try tablebase for endgames
-	if e.tablebase != nil && e.tablebase.Available() {
+	if e.tablebase != nil && e.tablebase.Available() && len(limits.SearchMoves) == 0 {
 		pieceCount := tablebase.CountPieces(pos)
 		if pieceCount <= e.tablebase.MaxPieces() {
 			result := e.tablebase.ProbeRoot(pos)
-			if result.Found && result.Move != board.NoMove {
+			if result.Found && result.Move != board.NoMove && !e.avoided(result.Move) {
 				return result.Move
 			}
 		}
 	}
 
+	// Initialize time manager; under nodestime the clock runs on nodes
+	us := pos.SideToMove
+	npmsec := e.nodesTime.Load()
+	nodesTime := false
+	if npmsec > 0 {
+		limits, nodesTime = e.nodesTimeLimits(limits, us, npmsec)
+	}
+	e.timeMan.Init(limits, us, ply)
+	if nodesTime {
+		e.timeMan.CountNodes(npmsec, &e.nodeCounter)
+	}
+	e.timeMan.AdjustForPhase(pos.Phase())
+
+	// The hard bound of a nodestime search is a node limit
+	nodeLimit := limits.Nodes
+	if nodesTime && !limits.Ponder {
+		if hard := uint64(npmsec * e.timeMan.MaximumTime().Milliseconds()); nodeLimit == 0 || hard < nodeLimit {
+			nodeLimit = hard
+		}
+	}
+
 	// Reset for new search
 	e.stopFlag.Store(false)
 	e.tt.NewSearch()
 
-	// Reset all workers
+	// Reset all workers and arm the shared node limit
+	e.nodeCounter.Store(0)
+	if e.tbCache != nil {
+		e.tbCache.ResetStats()
+	}
+	e.searchStart.Store(time.Now().UnixNano())
+	e.searching.Store(true)
+	defer e.searching.Store(false)
 	for _, w := range e.workers {
 		w.Reset()
+		w.SetNodeLimit(&e.nodeCounter, nodeLimit)
+		w.SetSearchMoves(limits.SearchMoves)
+		w.SetExcludedMoves(e.forbiddenMoves)
+		w.SetDiscouragedMoves(e.discouragedMoves, e.discouragePenalty)
+		w.features = e.SearchFeatures()
+		w.contempt = e.Contempt()
+		w.stats = nil
+		if e.collectStats.Load() {
+			w.stats = &SearchStats{}
+		}
 	}
 
 	startTime := time.Now()
@@ -240,6 +513,7 @@ func (e *Engine) SearchWithLimits(pos *board.Position, limits SearchLimits) boar
 	var bestScore int
 	var bestPV []board.Move
 	var bestDepth int
+	var bestSelDepth int
 
 	// Determine maximum depth
 	maxDepth := MaxPly
@@ -247,29 +521,33 @@ func (e *Engine) SearchWithLimits(pos *board.Position, limits SearchLimits) boar
 		maxDepth = limits.Depth
 	}
 
-	// Determine deadline
-	var deadline time.Time
-	if limits.MoveTime > 0 {
-		deadline = startTime.Add(limits.MoveTime)
-
-		// Start a timer goroutine to enforce the deadline
-		// This ensures stopFlag is set even if workers are in deep search
-		go func() {
-			time.Sleep(limits.MoveTime)
+	// Hard bound: abort all workers even if they are deep inside an iteration.
+	// A ponder search only arms it at PonderHit; under nodestime it has none.
+	if e.timeMan.Timed() && !nodesTime {
+		e.hardStopMu.Lock()
+		e.hardStop = time.AfterFunc(e.timeMan.MaximumTime(), func() {
 			e.stopFlag.Store(true)
-		}()
+		})
+		if limits.Ponder {
+			e.hardStop.Stop()
+		}
+		e.hardStopMu.Unlock()
+		defer e.disarmHardStop()
 	}
 
 	// Create result channel
-	resultCh := make(chan WorkerResult, NumWorkers*maxDepth)
+	resultCh := make(chan WorkerResult, len(e.workers)*maxDepth)
+
+	// Root move progress must be wired up before the main worker starts
+	e.armCurrMoveReports(startTime)
 
 	// Start workers
 	// IMPORTANT: Copy position BEFORE spawning goroutines to avoid concurrent reads
 	var wg sync.WaitGroup
-	for i := 0; i < NumWorkers; i++ {
+	for i := range e.workers {
 		workerPos := pos.Copy() // Each worker gets its own dedicated copy
 		wg.Add(1)
-		go e.workerSearch(i, workerPos, maxDepth, resultCh, &wg)
+		go e.workerSearch(i, workerPos, maxDepth, limits.Mate, resultCh, &wg)
 	}
 
 	// Collect results in a separate goroutine
@@ -280,231 +558,103 @@ func (e *Engine) SearchWithLimits(pos *board.Position, limits SearchLimits) boar
 		close(done)
 	}()
 
-	// Track nodes across all workers
-	var totalNodes uint64
+	// Periodic nodes/nps/hashfull updates until all workers finish
+	go e.reportStats(startTime, done)
 
-	// Process results
-resultLoop:
-	for {
-		select {
-		case result, ok := <-resultCh:
-			if !ok {
-				break resultLoop
-			}
+	// Best result so far, reported as it improves
+	setBest := func(result WorkerResult) {
+		bestMove = result.Move
+		bestScore = result.Score
+		bestPV = result.PV
+		bestDepth = result.Depth
+		bestSelDepth = result.SelDepth
 
-			// Update total nodes
-			totalNodes += result.Nodes
-
-			// Update best result if this is deeper or same depth with better score
-			if result.Move != board.NoMove {
-				if result.Depth > bestDepth ||
-					(result.Depth == bestDepth && result.Score > bestScore) {
-					bestMove = result.Move
-					bestScore = result.Score
-					bestPV = result.PV
-					bestDepth = result.Depth
-
-					// Report info
-					if e.OnInfo != nil {
-						elapsed := time.Since(startTime)
-						e.OnInfo(SearchInfo{
-							Depth:    bestDepth,
-							Score:    bestScore,
-							Nodes:    e.getTotalNodes(),
-							Time:     elapsed,
-							PV:       bestPV,
-							HashFull: e.tt.HashFull(),
-						})
-					}
+		if e.OnInfo != nil {
+			elapsed := time.Since(startTime)
+			e.OnInfo(SearchInfo{
+				Depth:    bestDepth,
+				Score:    bestScore,
+				Nodes:    e.getTotalNodes(),
+				Time:     elapsed,
+				PV:       bestPV,
+				HashFull: e.tt.HashFull(),
+				SelDepth: bestSelDepth,
+				TB:       e.tbStats(),
+			})
+		}
+	}
+
+	// Last completed iteration of each worker, for the final vote
+	latest := make([]WorkerResult, len(e.workers))
 
-					// Early termination: found mate
-					if bestScore > MateScore-100 || bestScore < -MateScore+100 {
+	// Process results
+	// resultCh is closed once all workers finish, so every result is drained
+resultLoop:
+	for result := range resultCh {
+		// Update best result if this is deeper or same depth with better score
+		if result.Move != board.NoMove {
+			latest[result.WorkerID] = result
+
+			// A proven mate within the mate limit is accepted from any depth
+			if result.Depth > bestDepth ||
+				(result.Depth == bestDepth && result.Score > bestScore) ||
+				(limits.Mate > 0 && mateWithin(result.Score, limits.Mate)) {
+				setBest(result)
+
+				// Early termination: found mate (in mate mode, only a mate within the limit)
+				if limits.Mate > 0 {
+					if mateWithin(bestScore, limits.Mate) {
 						e.stopFlag.Store(true)
 						break resultLoop
 					}
+				} else if bestScore > MateScore-100 || bestScore < -MateScore+100 {
+					e.stopFlag.Store(true)
+					break resultLoop
 				}
 			}
-
-			// Check time limit
-			if !deadline.IsZero() && time.Now().After(deadline) {
-				e.stopFlag.Store(true)
-				break resultLoop
-			}
-
-		case <-done:
-			break resultLoop
 		}
 	}
 
 	// Ensure all workers are stopped
 	e.stopFlag.Store(true)
-
-	// Wait for workers to finish
 	<-done
 
-	// Fallback: if no move was found, return first legal move
-	if bestMove == board.NoMove {
-		moves := pos.GenerateLegalMoves()
-		if moves.Len() > 0 {
-			bestMove = moves.Get(0)
+	// The workers vote on the move to play; the deepest result may come
+	// from a helper that has not resolved a fail high. A mate search keeps
+	// the mate it found within the limit.
+	if limits.Mate == 0 && len(e.workers) > 1 {
+		if voted := voteBestResult(latest); voted.Move != board.NoMove && voted.Move != bestMove {
+			setBest(voted)
 		}
 	}
 
-	return bestMove
-}
-
-// SearchWithUCILimits finds the best move using UCI time controls.
-// Supports wtime/btime/winc/binc for proper tournament time management.
-func (e *Engine) SearchWithUCILimits(pos *board.Position, limits UCILimits, ply int) board.Move {
-	// Try opening book first
-	if e.book != nil {
-		if move, ok := e.book.Probe(pos); ok {
-			return move
-		}
+	e.lastSearch = SearchInfo{
+		Depth:    bestDepth,
+		Score:    bestScore,
+		Nodes:    e.getTotalNodes(),
+		Time:     time.Since(startTime),
+		PV:       bestPV,
+		HashFull: e.tt.HashFull(),
+		SelDepth: bestSelDepth,
+		TB:       e.tbStats(),
 	}
-
-	// Try tablebase for endgames
-	if e.tablebase != nil && e.tablebase.Available() {
-		pieceCount := tablebase.CountPieces(pos)
-		if pieceCount <= e.tablebase.MaxPieces() {
-			result := e.tablebase.ProbeRoot(pos)
-			if result.Found && result.Move != board.NoMove {
-				return result.Move
-			}
+	e.recordSearch(e.lastSearch)
+	if nodesTime {
+		e.availableNodes += npmsec*limits.Inc[us].Milliseconds() - int64(e.lastSearch.Nodes)
+		if e.availableNodes < 0 {
+			e.availableNodes = 0
 		}
 	}
-
-	// Initialize time manager
-	tm := NewTimeManager()
-	tm.Init(limits, pos.SideToMove, ply)
-
-	// Reset for new search
-	e.stopFlag.Store(false)
-	e.tt.NewSearch()
-
-	// Reset all workers
 	for _, w := range e.workers {
-		w.Reset()
-	}
-
-	startTime := time.Now()
-	var bestMove board.Move
-	var bestScore int
-	var bestPV []board.Move
-	var bestDepth int
-	var lastBestMove board.Move
-	var stabilityCount int
-	var instabilityCount int
-
-	// Determine maximum depth
-	maxDepth := MaxPly
-	if limits.Depth > 0 {
-		maxDepth = limits.Depth
-	}
-
-	// Create result channel
-	resultCh := make(chan WorkerResult, NumWorkers*maxDepth)
-
-	// Start workers
-	// IMPORTANT: Copy position BEFORE spawning goroutines to avoid concurrent reads
-	var wg sync.WaitGroup
-	for i := 0; i < NumWorkers; i++ {
-		workerPos := pos.Copy() // Each worker gets its own dedicated copy
-		wg.Add(1)
-		go e.workerSearch(i, workerPos, maxDepth, resultCh, &wg)
-	}
-
-	// Collect results in a separate goroutine
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(resultCh)
-		close(done)
-	}()
-
-	// Process results
-resultLoop:
-	for {
-		select {
-		case result, ok := <-resultCh:
-			if !ok {
-				break resultLoop
-			}
-
-			// Update best result if this is deeper or same depth with better score
-			if result.Move != board.NoMove {
-				if result.Depth > bestDepth ||
-					(result.Depth == bestDepth && result.Score > bestScore) {
-
-					// Track move stability
-					if result.Depth > bestDepth {
-						if result.Move == lastBestMove {
-							stabilityCount++
-							instabilityCount = 0
-						} else {
-							instabilityCount++
-							stabilityCount = 0
-						}
-						lastBestMove = result.Move
-					}
-
-					bestMove = result.Move
-					bestScore = result.Score
-					bestPV = result.PV
-					bestDepth = result.Depth
-
-					// Report info
-					if e.OnInfo != nil {
-						elapsed := time.Since(startTime)
-						e.OnInfo(SearchInfo{
-							Depth:    bestDepth,
-							Score:    bestScore,
-							Nodes:    e.getTotalNodes(),
-							Time:     elapsed,
-							PV:       bestPV,
-							HashFull: e.tt.HashFull(),
-						})
-					}
-
-					// Early termination: found mate
-					if bestScore > MateScore-100 || bestScore < -MateScore+100 {
-						e.stopFlag.Store(true)
-						break resultLoop
-					}
-
-					// Time management: check if we should stop based on stability
-					if tm.PastOptimum() {
-						if stabilityCount >= 4 {
-							// Move is very stable, stop early
-							e.stopFlag.Store(true)
-							break resultLoop
-						}
-					}
-				}
-			}
-
-			// Check time limit
-			if tm.ShouldStop() {
-				e.stopFlag.Store(true)
-				break resultLoop
-			}
-
-			// Node limit check
-			if limits.Nodes > 0 && e.getTotalNodes() >= limits.Nodes {
-				e.stopFlag.Store(true)
-				break resultLoop
-			}
-
-		case <-done:
-			break resultLoop
+		if w.stats != nil {
+			e.lastStats.add(w.stats)
 		}
 	}
 
-	// Ensure all workers are stopped
-	e.stopFlag.Store(true)
-	<-done
-
-	// Fallback: if no move was found, return first legal move
+	// Fallback: if no move was found, return the first allowed (or first legal) move
+	if bestMove == board.NoMove && len(limits.SearchMoves) > 0 {
+		bestMove = limits.SearchMoves[0]
+	}
 	if bestMove == board.NoMove {
 		moves := pos.GenerateLegalMoves()
 		if moves.Len() > 0 {
@@ -517,7 +667,8 @@ resultLoop:
 
 // workerSearch runs iterative deepening search in a worker goroutine.
 // Uses depth staggering: workers start at different depths to reduce redundant shallow work.
-func (e *Engine) workerSearch(workerID int, pos *board.Position, maxDepth int, resultCh chan<- WorkerResult, wg *sync.WaitGroup) {
+// mateLimit > 0 stops all workers as soon as a forced mate in at most mateLimit moves is proven.
+func (e *Engine) workerSearch(workerID int, pos *board.Position, maxDepth, mateLimit int, resultCh chan<- WorkerResult, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	// Recover from panics to prevent silent failures
@@ -558,9 +709,6 @@ func (e *Engine) workerSearch(workerID int, pos *board.Position, maxDepth int, r
 		startDepth = 2
 	}
 
-	// Track recent scores for volatility calculation
-	recentScores := make([]int, 0, 10)
-
 	for depth := startDepth; depth <= maxDepth; depth++ {
 		if e.stopFlag.Load() {
 			return
@@ -568,113 +716,30 @@ func (e *Engine) workerSearch(workerID int, pos *board.Position, maxDepth int, r
 
 		// Update optimism before each depth (Stockfish search.cpp)
 		worker.UpdateOptimism()
+		worker.resetSelDepth()
 
 		var move board.Move
 		var score int
 
-		// Use dynamic aspiration windows after depth 4
-		// Window size adapts based on score volatility
-		if depth >= 5 && prevScore != 0 {
-			// Calculate volatility from recent scores
-			volatility := 0
-			if len(recentScores) >= 2 {
-				minScore, maxScore := recentScores[0], recentScores[0]
-				for _, s := range recentScores {
-					if s < minScore {
-						minScore = s
-					}
-					if s > maxScore {
-						maxScore = s
-					}
-				}
-				volatility = maxScore - minScore
-			}
-
-			// Dynamic window size based on volatility
-			var window int
-			if volatility > 400 {
-				// High volatility (tactical position): use wider window
-				window = 150 + volatility/4
-			} else if volatility < 50 {
-				// Stable position: use tight window
-				window = 25
-			} else {
-				// Normal: moderate window
-				window = 50 + volatility/8
+		// DEBUG: Verify position before search
+		if board.DebugMoveValidation {
+			if err := worker.Pos().Validate(); err != nil {
+				log.Printf("ENGINE: Invalid position BEFORE SearchDepth! depth=%d: %v", depth, err)
 			}
+		}
 
-			// Add worker-specific variation for search diversity
-			window += (workerID % 8) * 3
-
-			alpha := prevScore - window
-			beta := prevScore + window
-			retryCount := 0
-
-			// Set rootDelta for LMR scaling (Stockfish search.cpp:354)
-			// Initial aspiration window width used to scale reductions
-			worker.rootDelta = window * 2 // beta - alpha = 2 * window
-
-			for {
-				// DEBUG: Verify position before search
-				if board.DebugMoveValidation {
-					if worker.Pos().Pieces[board.White][board.King] == 0 {
-						log.Printf("ENGINE: White King MISSING BEFORE SearchDepth! depth=%d", depth)
-					}
-				}
-
-				move, score = worker.SearchDepth(depth, alpha, beta)
-
-				// DEBUG: Verify position wasn't corrupted by search
-				if board.DebugMoveValidation {
-					if worker.Pos().Pieces[board.White][board.King] == 0 {
-						log.Printf("ENGINE: White King MISSING after SearchDepth! depth=%d", depth)
-					}
-				}
-
-				if e.stopFlag.Load() {
-					return
-				}
-
-				if score <= alpha {
-					// Failed low: gradually expand alpha
-					retryCount++
-					if retryCount >= 2 {
-						alpha = -Infinity
-					} else {
-						alpha = prevScore - window*2
-					}
-				} else if score >= beta {
-					// Failed high: gradually expand beta
-					retryCount++
-					if retryCount >= 2 {
-						beta = Infinity
-					} else {
-						beta = prevScore + window*2
-					}
-				} else {
-					break
-				}
-
-				if alpha == -Infinity && beta == Infinity {
-					break
-				}
-			}
+		// The main worker searches an aspiration window around its previous
+		// score; helpers search the full window, which also diversifies them
+		if workerID == 0 && depth >= aspirationMinDepth {
+			move, score = worker.aspirationSearch(depth, prevScore)
 		} else {
-			// DEBUG: Verify position before search
-			// For non-aspiration searches, use a large rootDelta
-			worker.rootDelta = Infinity // Full window search
-
-			if board.DebugMoveValidation {
-				if worker.Pos().Pieces[board.White][board.King] == 0 {
-					log.Printf("ENGINE: White King MISSING BEFORE SearchDepth (non-asp)! depth=%d", depth)
-				}
-			}
 			move, score = worker.SearchDepth(depth, -Infinity, Infinity)
-			// DEBUG: Verify position after search
-			if board.DebugMoveValidation {
-				if worker.Pos().Pieces[board.White][board.King] == 0 {
-					log.Printf("ENGINE: White King MISSING after SearchDepth (non-asp)! depth=%d", depth)
-				}
+		}
+
+		// DEBUG: Verify position wasn't corrupted by search
+		if board.DebugMoveValidation {
+			if err := worker.Pos().Validate(); err != nil {
+				log.Printf("ENGINE: Position corrupted by SearchDepth! depth=%d: %v", depth, err)
 			}
 		}
 
@@ -687,12 +752,6 @@ func (e *Engine) workerSearch(workerID int, pos *board.Position, maxDepth int, r
 		// Update avgScore for optimism calculation (Stockfish search.cpp)
 		worker.UpdateAvgScore(score)
 
-		// Track score for volatility calculation
-		recentScores = append(recentScores, score)
-		if len(recentScores) > 10 {
-			recentScores = recentScores[1:] // Keep last 10 scores
-		}
-
 		// Send result
 		pv := worker.GetPV()
 		resultCh <- WorkerResult{
@@ -702,7 +761,117 @@ func (e *Engine) workerSearch(workerID int, pos *board.Position, maxDepth int, r
 			Move:     move,
 			PV:       pv,
 			Nodes:    worker.Nodes(),
+			SelDepth: worker.SelDepth(),
 		}
+
+		// Mate search: a proven mate within the limit ends the search for everyone
+		if mateLimit > 0 && mateWithin(score, mateLimit) {
+			e.stopFlag.Store(true)
+			return
+		}
+
+		// The main worker owns time management: after each completed iteration it
+		// decides whether another one is worth starting, and stops all workers if not
+		if workerID == 0 && e.timeMan.StopAfterIteration(depth, move, score, e.nodeCounter.Load()) {
+			e.stopFlag.Store(true)
+			return
+		}
+	}
+}
+
+// armCurrMoveReports installs the root move callback on the main worker.
+// Reports are suppressed for the first currMoveDelay to avoid flooding the GUI at low depths.
+func (e *Engine) armCurrMoveReports(startTime time.Time) {
+	onCurrMove := e.OnCurrMove
+	if onCurrMove == nil {
+		e.workers[0].SetCurrMoveCallback(nil)
+		return
+	}
+	e.workers[0].SetCurrMoveCallback(func(depth int, move board.Move, moveNumber int) {
+		if time.Since(startTime) >= currMoveDelay {
+			onCurrMove(CurrMoveInfo{Depth: depth, Move: move, MoveNumber: moveNumber})
+		}
+	})
+}
+
+// reportStats calls OnStats every statsInterval until done is closed,
+// so GUIs see nps/hashfull even while a depth takes a long time to complete.
+func (e *Engine) reportStats(startTime time.Time, done <-chan struct{}) {
+	onStats := e.OnStats
+	if onStats == nil {
+		return
+	}
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	main := e.workers[0]
+	for {
+		select {
+		case <-ticker.C:
+			onStats(SearchInfo{
+				Depth:    main.Depth(),
+				SelDepth: main.SelDepth(),
+				Nodes:    e.getTotalNodes(),
+				Time:     time.Since(startTime),
+				HashFull: e.tt.HashFull(),
+				TB:       e.tbStats(),
+			})
+		case <-done:
+			return
+		}
+	}
+}
+
+// mateWithin returns true if score proves a forced mate in at most n moves
+// for the side to move.
+func mateWithin(score, n int) bool {
+	if score <= MateScore-MaxPly {
+		return false
+	}
+	return (MateScore-score+1)/2 <= n
+}
+
+// LastSearchInfo returns the depth, nodes and time of the most recent search.
+// Depth is 0 if the move came from the opening book or tablebase.
+func (e *Engine) LastSearchInfo() SearchInfo {
+	return e.lastSearch
+}
+
+// Stats is a snapshot of the engine's activity for monitoring. It is safe to
+// take while the engine searches.
+type Stats struct {
+	Workers   int    // Search workers (goroutines per search)
+	Searching bool   // A search is running
+	Searches  uint64 // Searches completed
+	Nodes     uint64 // Nodes searched by the completed searches
+	NPS       uint64 // Speed of the running search, or else of the last one
+	HashFull  int    // Permille of the transposition table used by this search
+}
+
+// Stats returns the engine's activity counters. Book and tablebase moves
+// are not counted as searches.
+func (e *Engine) Stats() Stats {
+	st := Stats{
+		Workers:   len(e.workers),
+		Searching: e.searching.Load(),
+		Searches:  e.searches.Load(),
+		Nodes:     e.nodesTotal.Load(),
+		NPS:       e.lastNPS.Load(),
+		HashFull:  e.tt.HashFull(),
+	}
+	// Nodes are published every nodeFlushInterval, so this lags slightly
+	if elapsed := time.Since(time.Unix(0, e.searchStart.Load())); st.Searching && elapsed > 0 {
+		st.NPS = uint64(float64(e.nodeCounter.Load()) / elapsed.Seconds())
+	}
+	return st
+}
+
+// recordSearch adds a completed search to the activity counters.
+func (e *Engine) recordSearch(info SearchInfo) {
+	e.searches.Add(1)
+	e.nodesTotal.Add(info.Nodes)
+	if info.Time > 0 {
+		e.lastNPS.Store(uint64(float64(info.Nodes) / info.Time.Seconds()))
 	}
 }
 
@@ -760,7 +929,11 @@ func (e *Engine) SearchMultiPV(pos *board.Position, limits SearchLimits) []Searc
 // searchWithExclusions searches for best move excluding certain moves at the root.
 func (e *Engine) searchWithExclusions(pos *board.Position, limits SearchLimits, excluded []board.Move) (board.Move, int, []board.Move, int) {
 	e.searcher.Reset()
-	e.searcher.SetExcludedMoves(excluded)
+	e.searcher.SetExcludedMoves(slices.Concat(excluded, e.forbiddenMoves))
+	e.searcher.SetSearchMoves(limits.SearchMoves)
+	e.searcher.worker.SetDiscouragedMoves(e.discouragedMoves, e.discouragePenalty)
+	e.searcher.worker.features = e.SearchFeatures()
+	e.searcher.worker.contempt = e.Contempt()
 	e.tt.NewSearch()
 
 	startTime := time.Now()
@@ -810,6 +983,8 @@ func (e *Engine) searchWithExclusions(pos *board.Position, limits SearchLimits,
 
 	pv := e.searcher.GetPV()
 	e.searcher.SetExcludedMoves(nil) // Clear exclusions
+	e.searcher.SetSearchMoves(nil)
+	e.searcher.worker.SetDiscouragedMoves(nil, 0)
 
 	return bestMove, bestScore, pv, bestDepth
 }
@@ -820,36 +995,75 @@ func (e *Engine) Stop() {
 	e.searcher.Stop()
 }
 
+// PonderHit tells a ponder search that the opponent played the expected
+// move: the search goes on under its time limits. As in Stockfish, the time
+// spent pondering counts toward the move.
+func (e *Engine) PonderHit() {
+	e.hardStopMu.Lock()
+	defer e.hardStopMu.Unlock()
+
+	if !e.timeMan.pondering.CompareAndSwap(true, false) || e.hardStop == nil {
+		return
+	}
+	remaining := e.timeMan.MaximumTime() - e.timeMan.Elapsed()
+	if remaining < 0 {
+		remaining = 0
+	}
+	e.hardStop.Reset(remaining)
+}
+
+// disarmHardStop stops the hard time bound of the finished search.
+func (e *Engine) disarmHardStop() {
+	e.hardStopMu.Lock()
+	defer e.hardStopMu.Unlock()
+
+	e.timeMan.pondering.Store(false)
+	e.hardStop.Stop()
+	e.hardStop = nil
+}
+
 // Clear clears the transposition table and other caches.
 func (e *Engine) Clear() {
 	e.tt.Clear()
-	// Clear all worker orderers
+	e.evalCache.Clear()
+	e.bookExited.Store(false)
+	e.availableNodes = -1
+	// Clear all worker orderers; correction histories are aged instead
 	for _, w := range e.workers {
 		w.orderer.Clear()
+		w.corrHistory.Age()
 	}
 	e.searcher.ClearOrderer()
 }
 
-// Perft performs a perft test (for debugging move generation).
+// ClearEvalCache empties the evaluation cache. Call it when the evaluation
+// changes, as after setting a tunable parameter.
+func (e *Engine) ClearEvalCache() {
+	e.evalCache.Clear()
+}
+
+// Perft performs a perft test (for debugging move generation), with the root
+// moves split over one goroutine per search worker.
 func (e *Engine) Perft(pos *board.Position, depth int) uint64 {
-	if depth == 0 {
-		return 1
-	}
+	return board.PerftParallel(pos, depth, len(e.workers), nil)
+}
 
-	moves := pos.GenerateLegalMoves()
-	if depth == 1 {
-		return uint64(moves.Len())
-	}
+// PerftDivide returns the perft node count below each root move, computed
+// in parallel as in Perft.
+func (e *Engine) PerftDivide(pos *board.Position, depth int) []board.PerftDivideEntry {
+	return board.PerftDivideParallel(pos, depth, len(e.workers), nil)
+}
 
-	var nodes uint64
-	for i := 0; i < moves.Len(); i++ {
-		move := moves.Get(i)
-		undo := pos.MakeMove(move)
-		nodes += e.Perft(pos, depth-1)
-		pos.UnmakeMove(move, undo)
-	}
+// PerftHashed performs a parallel perft test that caches subtree counts in a
+// dedicated perft table of sizeMB megabytes, leaving the search TT intact.
+func (e *Engine) PerftHashed(pos *board.Position, depth, sizeMB int) uint64 {
+	return e.PerftWithTable(pos, depth, board.NewPerftTable(sizeMB))
+}
 
-	return nodes
+// PerftWithTable performs a parallel perft test that caches subtree counts
+// in t, which may be kept across runs (as by a perft suite) to reuse them.
+func (e *Engine) PerftWithTable(pos *board.Position, depth int, t *board.PerftTable) uint64 {
+	return board.PerftParallel(pos, depth, len(e.workers), t)
 }
 
 // Evaluate returns the static evaluation of a position.
@@ -857,18 +1071,78 @@ func (e *Engine) Evaluate(pos *board.Position) int {
 	return Evaluate(pos)
 }
 
-// LoadNNUE loads NNUE network files.
+// EvaluateBatch returns the static evaluations of many positions, such as
+// every position of a game for analysis, from the side to move as Evaluate
+// but with the search's evaluation (NNUE when in use). The positions are
+// split over the search workers, each evaluating its share with its own
+// accumulators. Must not be called during a search.
+func (e *Engine) EvaluateBatch(positions []*board.Position) []int {
+	evals := make([]int, len(positions))
+	n := min(len(e.workers), len(positions))
+	features := e.SearchFeatures()
+
+	var wg sync.WaitGroup
+	for i, w := range e.workers[:n] {
+		lo, hi := i*len(positions)/n, (i+1)*len(positions)/n
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.features = features
+			w.stats = nil
+			for j := lo; j < hi; j++ {
+				evals[j] = w.staticEval(positions[j])
+			}
+		}()
+	}
+	wg.Wait()
+	return evals
+}
+
+// ErrNetworkLoad is wrapped, together with the cause, by the errors of
+// loading NNUE networks. The cause tells a missing file from a network of
+// the wrong version (sfnnue.ErrUnsupportedNetwork) or a damaged one.
+var ErrNetworkLoad = errors.New("cannot load NNUE network")
+
+// LoadNNUE loads NNUE network files. An empty bigPath evaluates with the
+// small network only; an empty smallPath uses the embedded small network.
 func (e *Engine) LoadNNUE(bigPath, smallPath string) error {
 	log.Printf("[Engine] Loading NNUE networks...")
 	log.Printf("[Engine]   Big network: %s", bigPath)
 	log.Printf("[Engine]   Small network: %s", smallPath)
 
-	nets, err := sfnnue.LoadNetworks(bigPath, smallPath)
+	var nets *sfnnue.Networks
+	var err error
+	if bigPath == "" {
+		nets, err = sfnnue.LoadSmallNetwork(smallPath)
+	} else {
+		nets, err = sfnnue.LoadNetworks(bigPath, smallPath)
+	}
+	if err != nil {
+		log.Printf("[Engine] Failed to load NNUE: %v", err)
+		return fmt.Errorf("%w: %w", ErrNetworkLoad, err)
+	}
+	e.setNNUE(nets, bigPath, smallPath)
+	return nil
+}
+
+// LoadSmallNNUEData loads the small network from the contents of the
+// network file name, for platforms without files such as the browser.
+func (e *Engine) LoadSmallNNUEData(data []byte, name string) error {
+	log.Printf("[Engine] Loading NNUE network %s (%d bytes)...", name, len(data))
+	nets, err := sfnnue.LoadSmallNetworkData(data, name)
 	if err != nil {
 		log.Printf("[Engine] Failed to load NNUE: %v", err)
-		return err
+		return fmt.Errorf("%w: %w", ErrNetworkLoad, err)
 	}
+	e.setNNUE(nets, "", name)
+	return nil
+}
+
+// setNNUE makes the workers evaluate with the loaded networks.
+func (e *Engine) setNNUE(nets *sfnnue.Networks, bigPath, smallPath string) {
 	e.nnueNet = nets
+	e.nnueBig, e.nnueSmall = bigPath, smallPath
+	e.evalCache.Clear()
 
 	// Initialize NNUE evaluators for all workers
 	for _, w := range e.workers {
@@ -879,11 +1153,13 @@ func (e *Engine) LoadNNUE(bigPath, smallPath string) error {
 	e.searcher.worker.initNNUE(nets)
 
 	log.Printf("[Engine] NNUE networks loaded successfully")
-	return nil
 }
 
 // SetUseNNUE enables or disables NNUE evaluation.
 func (e *Engine) SetUseNNUE(use bool) {
+	if use != e.useNNUE {
+		e.evalCache.Clear()
+	}
 	e.useNNUE = use
 	for _, w := range e.workers {
 		w.useNNUE = use
@@ -902,6 +1178,23 @@ func (e *Engine) UseNNUE() bool {
 	return e.useNNUE
 }
 
+// NNUEPaths returns the paths of the loaded networks (empty if none are loaded).
+func (e *Engine) NNUEPaths() (big, small string) {
+	return e.nnueBig, e.nnueSmall
+}
+
+// NNUEInfo describes the loaded networks. ok is false if none are loaded;
+// big is empty when evaluating with the small network only.
+func (e *Engine) NNUEInfo() (big, small sfnnue.NetworkFileInfo, ok bool) {
+	if e.nnueNet == nil {
+		return big, small, false
+	}
+	if !e.nnueNet.SmallOnly() {
+		big = e.nnueNet.Big.NetworkInfo()
+	}
+	return big, e.nnueNet.Small.NetworkInfo(), true
+}
+
 // HasNNUE returns whether NNUE networks are loaded.
 func (e *Engine) HasNNUE() bool {
 	return e.nnueNet != nil
@@ -1,10 +1,30 @@
 package engine
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/book"
+	"github.com/hailam/chessplay/internal/tablebase"
+	"github.com/hailam/chessplay/sfnnue"
 )
 
 func TestMultiPV(t *testing.T) {
@@ -99,7 +119,7 @@ func TestConcurrentSearchMultiplePositions(t *testing.T) {
 	positions := []string{
 		board.StartFEN,
 		"r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3", // Italian Game
-		"8/8/8/4k3/8/4K3/4P3/8 w - - 0 1",                                      // KP endgame
+		"8/8/8/4k3/8/4K3/4P3/8 w - - 0 1",                                   // KP endgame
 	}
 
 	for i, fen := range positions {
@@ -125,6 +145,650 @@ func TestConcurrentSearchMultiplePositions(t *testing.T) {
 	}
 }
 
+// TestNodeLimit verifies that "go nodes" is honored within a small tolerance.
+func TestNodeLimit(t *testing.T) {
+	pos := board.NewPosition()
+	eng := NewEngine(16)
+
+	const limit = 50000
+	move := eng.SearchWithLimits(pos, SearchLimits{Nodes: limit})
+	if move == board.NoMove {
+		t.Fatal("Search returned NoMove for starting position")
+	}
+
+	// Each worker may overshoot by one flush interval plus one stop-check interval
+	tolerance := uint64(NumWorkers * (nodeFlushInterval + 4096))
+	nodes := eng.getTotalNodes()
+	if nodes > limit+tolerance {
+		t.Errorf("Searched %d nodes, limit %d (tolerance %d)", nodes, limit, tolerance)
+	}
+	t.Logf("Searched %d nodes with limit %d", nodes, limit)
+}
+
+// TestStats verifies that completed searches are counted.
+func TestStats(t *testing.T) {
+	pos := board.NewPosition()
+	eng := NewEngine(16)
+
+	if st := eng.Stats(); st.Searches != 0 || st.Searching || st.Workers != NumWorkers {
+		t.Fatalf("Unexpected stats before searching: %+v", st)
+	}
+	eng.SearchWithLimits(pos, SearchLimits{Depth: 4})
+	eng.SearchWithLimits(pos, SearchLimits{Depth: 4})
+
+	st := eng.Stats()
+	if st.Searching || st.Searches != 2 {
+		t.Errorf("Expected 2 completed searches, got %+v", st)
+	}
+	if st.Nodes == 0 || st.NPS == 0 {
+		t.Errorf("Expected nodes and speed to be recorded, got %+v", st)
+	}
+}
+
+// TestMateSearch verifies that "go mate N" finds and stops on a forced mate.
+func TestMateSearch(t *testing.T) {
+	// Mate in 2: 1. Nf6+ gxf6 2. Bxf7#
+	pos, err := board.ParseFEN("r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1")
+	if err != nil {
+		t.Fatalf("Failed to parse FEN: %v", err)
+	}
+	eng := NewEngine(16)
+
+	var lastScore int
+	eng.OnInfo = func(info SearchInfo) {
+		lastScore = info.Score
+	}
+
+	move := eng.SearchWithLimits(pos, SearchLimits{Mate: 2, MoveTime: 10 * time.Second})
+	if move.String() != "d5f6" {
+		t.Errorf("Expected d5f6, got %s", move.String())
+	}
+	if !mateWithin(lastScore, 2) {
+		t.Errorf("Expected mate in 2 score, got %d", lastScore)
+	}
+}
+
+// TestNoRootTTCutoff verifies that a stored entry for the root position does
+// not end the search: a bound would narrow the root window until every move
+// fails low, leaving no best move, and an exact score would return its move
+// even when searchmoves excludes it.
+func TestNoRootTTCutoff(t *testing.T) {
+	// Rxd5 wins the queen
+	pos, err := board.ParseFEN("4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1")
+	if err != nil {
+		t.Fatalf("Failed to parse FEN: %v", err)
+	}
+	capture, _ := board.ParseMove("d1d5", pos)
+	kingMove, _ := board.ParseMove("e1e2", pos)
+	eng := NewEngine(16)
+
+	eng.tt.Store(pos.Hash, maxTTDepth, 5000, TTLowerBound, board.NoMove, true)
+	if move := eng.SearchWithLimits(pos, SearchLimits{Depth: 4}); move != capture {
+		t.Errorf("With a root lower bound: got %s, want %s", move.String(), capture.String())
+	}
+
+	eng.tt.Store(pos.Hash, maxTTDepth, 900, TTExact, capture, true)
+	limits := SearchLimits{Depth: 4, SearchMoves: []board.Move{kingMove}}
+	if move := eng.SearchWithLimits(pos, limits); move != kingMove {
+		t.Errorf("With a root exact score: got %s, want the only allowed move %s", move.String(), kingMove.String())
+	}
+}
+
+// TestSearchMoves verifies that "go searchmoves" restricts the root moves.
+func TestSearchMoves(t *testing.T) {
+	// Same mate-in-2 position: Nf6+ is best but not allowed
+	pos, err := board.ParseFEN("r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1")
+	if err != nil {
+		t.Fatalf("Failed to parse FEN: %v", err)
+	}
+	eng := NewEngine(16)
+
+	allowed := make([]board.Move, 0, 2)
+	for _, s := range []string{"a2a3", "h2h3"} {
+		m, err := board.ParseMove(s, pos)
+		if err != nil {
+			t.Fatalf("Failed to parse move %s: %v", s, err)
+		}
+		allowed = append(allowed, m)
+	}
+
+	move := eng.SearchWithLimits(pos, SearchLimits{Depth: 5, SearchMoves: allowed})
+	if move != allowed[0] && move != allowed[1] {
+		t.Errorf("Expected one of %v, got %s", allowed, move.String())
+	}
+	t.Logf("Best restricted move: %s", move.String())
+}
+
+// TestSelDepthReported verifies that selective depth (including quiescence) is reported.
+func TestSelDepthReported(t *testing.T) {
+	pos := board.NewPosition()
+	eng := NewEngine(16)
+
+	// Transposition table cutoffs may end a line before the nominal depth,
+	// so an iteration need not go past it, but quiescence takes some deeper
+	var deepest SearchInfo
+	eng.OnInfo = func(info SearchInfo) {
+		if info.SelDepth-info.Depth > deepest.SelDepth-deepest.Depth {
+			deepest = info
+		}
+	}
+	eng.SearchWithLimits(pos, SearchLimits{Depth: 6})
+
+	if deepest.SelDepth <= deepest.Depth {
+		t.Errorf("no iteration reported a seldepth past its depth")
+	}
+	t.Logf("depth %d seldepth %d", deepest.Depth, deepest.SelDepth)
+}
+
+// TestExtensionBudget verifies that double and triple extensions are cut
+// to the path's budget, while single extensions and reductions are not.
+func TestExtensionBudget(t *testing.T) {
+	w := NewWorker(0, NewTranspositionTable(1), NewPawnTable(1), NewSharedHistory(), &atomic.Bool{})
+	w.depth = 8
+
+	tests := []struct {
+		extensions, multiExtensions int // Used on the path
+		extension, want             int
+	}{
+		{0, 0, 3, 3},
+		{6, 0, 3, 2},
+		{8, 0, 3, 1},
+		{12, 0, 1, 1},
+		{12, 0, -3, -3},
+		{0, maxMultiExtensions, 2, 1},
+	}
+	for _, tt := range tests {
+		w.searchStack[1].extensions, w.searchStack[1].multiExtensions = tt.extensions, tt.multiExtensions
+		if got := w.limitExtension(1, tt.extension); got != tt.want {
+			t.Errorf("extension %d after %d (%d multi): got %d, want %d",
+				tt.extension, tt.extensions, tt.multiExtensions, got, tt.want)
+		}
+	}
+
+	// Nothing is extended from twice the root depth on
+	for _, tt := range []struct {
+		ply  int
+		want bool
+	}{{0, true}, {15, true}, {16, false}, {40, false}} {
+		if got := w.canExtend(tt.ply); got != tt.want {
+			t.Errorf("canExtend(%d) at depth %d = %v, want %v", tt.ply, w.depth, got, tt.want)
+		}
+	}
+
+	// With queens checking from both sides, check extensions and quiescence
+	// take the search past its depth but the limits keep it within MaxPly
+	pos, _ := board.ParseFEN("7k/6pp/8/8/8/8/6PP/q2Q3K w - - 0 1")
+	eng := NewEngine(16)
+	eng.SearchWithLimits(pos, SearchLimits{Depth: 8})
+	if info := eng.LastSearchInfo(); info.SelDepth <= info.Depth || info.SelDepth >= MaxPly {
+		t.Errorf("depth %d seldepth %d", info.Depth, info.SelDepth)
+	}
+}
+
+// TestSelDepthBookkeeping verifies that the selective depth keeps the deepest
+// ply reached and starts over with each iteration.
+func TestSelDepthBookkeeping(t *testing.T) {
+	w := NewWorker(0, NewTranspositionTable(1), NewPawnTable(1), NewSharedHistory(), &atomic.Bool{})
+
+	for _, ply := range []int{0, 3, 7, 2} {
+		w.updateSelDepth(ply)
+	}
+	if got := w.SelDepth(); got != 8 {
+		t.Errorf("after reaching ply 7: seldepth %d, want 8", got)
+	}
+
+	w.resetSelDepth()
+	if got := w.SelDepth(); got != 0 {
+		t.Errorf("after reset: seldepth %d, want 0", got)
+	}
+	w.updateSelDepth(4)
+	if got := w.SelDepth(); got != 5 {
+		t.Errorf("after reaching ply 4: seldepth %d, want 5", got)
+	}
+}
+
+// TestTTQSDepths verifies that quiescence entries are stored and probed at their own depth tiers.
+func TestTTQSDepths(t *testing.T) {
+	tt := NewTranspositionTable(1)
+
+	const hashChecks, hashNoChecks = 0x1234, 0x5678
+	tt.Store(hashChecks, DepthQSChecks, 42, TTExact, board.NoMove, false)
+	tt.Store(hashNoChecks, DepthQSNoChecks, -17, TTUpperBound, board.NoMove, false)
+
+	entry, found := tt.Probe(hashChecks)
+	if !found || int(entry.Depth) != DepthQSChecks || entry.Score != 42 {
+		t.Errorf("QS checks entry: found=%v depth=%d score=%d", found, entry.Depth, entry.Score)
+	}
+	entry, found = tt.Probe(hashNoChecks)
+	if !found || int(entry.Depth) != DepthQSNoChecks || entry.Score != -17 {
+		t.Errorf("QS no-checks entry: found=%v depth=%d score=%d", found, entry.Depth, entry.Score)
+	}
+
+	// Empty slots must not be reported as hits
+	if _, found := tt.Probe(0x9abc); found {
+		t.Error("Probe of empty slot reported a hit")
+	}
+
+	// Depths beyond the representable range are clamped
+	tt.Store(hashChecks, MaxPly, 0, TTExact, board.NoMove, false)
+	if entry, _ := tt.Probe(hashChecks); int(entry.Depth) != maxTTDepth {
+		t.Errorf("Expected clamped depth %d, got %d", maxTTDepth, entry.Depth)
+	}
+}
+
+// TestCaptureReduction verifies that late captures are reduced more at
+// higher depths and move counts, less with good capture history, and not at
+// all when they do not lose material.
+func TestCaptureReduction(t *testing.T) {
+	// Qxd5 loses the queen to cxd5, Rxh7 wins a pawn
+	pos, err := board.ParseFEN("4k3/7p/2p5/3p4/8/8/3Q4/4K2R w - - 0 1")
+	if err != nil {
+		t.Fatalf("Failed to parse FEN: %v", err)
+	}
+	losing, _ := board.ParseMove("d2d5", pos)
+	winning, _ := board.ParseMove("h1h7", pos)
+	w := NewWorker(0, NewTranspositionTable(1), NewPawnTable(1), NewSharedHistory(), &atomic.Bool{})
+	w.InitSearch(pos)
+
+	// A base table of ln(depth) * ln(moves) plies, so the growth shows
+	defer SetLMRParams(lmrBase, lmrDivisor)
+	SetLMRParams(1, 1)
+
+	if r := w.captureReduction(winning, 12, 10); r != 0 {
+		t.Errorf("Winning capture reduced by %d, want 0", r)
+	}
+	base := w.captureReduction(losing, 12, 10)
+	if base < 2 {
+		t.Fatalf("Losing capture reduced by %d, want at least 2", base)
+	}
+	if r := w.captureReduction(losing, 12, 40); r <= base {
+		t.Errorf("Reduction after 40 moves %d, want more than %d after 10", r, base)
+	}
+	if r := w.captureReduction(losing, 40, 10); r <= base {
+		t.Errorf("Reduction at depth 40 %d, want more than %d at depth 12", r, base)
+	}
+
+	queen := pos.PieceAt(losing.From())
+	for range 20 {
+		w.orderer.UpdateCaptureHistory(queen, losing.To(), board.Pawn, 20, true)
+	}
+	if r := w.captureReduction(losing, 12, 10); r != base-1 {
+		t.Errorf("Reduction with good capture history %d, want %d", r, base-1)
+	}
+	for range 40 {
+		w.orderer.UpdateCaptureHistory(queen, losing.To(), board.Pawn, 20, false)
+	}
+	if r := w.captureReduction(losing, 12, 10); r != base+1 {
+		t.Errorf("Reduction with bad capture history %d, want %d", r, base+1)
+	}
+}
+
+// TestRootDeltaReduction verifies that nodes searched with the full root
+// window are reduced less than null-window ones, and that the root window is
+// taken anew on every aspiration re-search.
+func TestRootDeltaReduction(t *testing.T) {
+	w := NewWorker(0, NewTranspositionTable(16), NewPawnTable(1), NewSharedHistory(), &atomic.Bool{})
+	w.InitSearch(board.NewPosition())
+
+	w.rootDelta = 2 * aspirationDelta
+	if r := w.rootDeltaReduction(-aspirationDelta, aspirationDelta); r != 1 {
+		t.Errorf("Full root window: reduced %d less, want 1", r)
+	}
+	if r := w.rootDeltaReduction(0, 1); r != 0 {
+		t.Errorf("Null window: reduced %d less, want 0", r)
+	}
+	// The same window in a wide root search is reduced more
+	w.rootDelta = 2 * Infinity
+	if r := w.rootDeltaReduction(-aspirationDelta, aspirationDelta); r != 0 {
+		t.Errorf("Narrow window in a full-width root search: reduced %d less, want 0", r)
+	}
+
+	// A score far from the expected one fails low and widens the window
+	w.rootDelta = 0
+	w.aspirationSearch(4, 2000)
+	if w.rootDelta <= 2*aspirationDelta {
+		t.Errorf("rootDelta %d after a re-search, want wider than the first window %d", w.rootDelta, 2*aspirationDelta)
+	}
+}
+
+// TestFailSoft verifies the values returned by reverse futility pruning, the
+// quiescence stand pat and null move pruning, and that none is a mate score.
+func TestFailSoft(t *testing.T) {
+	newWorker := func(fen string, features ...SearchFeature) *Worker {
+		pos, err := board.ParseFEN(fen)
+		if err != nil {
+			t.Fatalf("Failed to parse FEN: %v", err)
+		}
+		w := NewWorker(0, NewTranspositionTable(16), NewPawnTable(1), NewSharedHistory(), &atomic.Bool{})
+		w.features = 0
+		for _, f := range features {
+			w.features = w.features.With(f, true)
+		}
+		w.InitSearch(pos)
+		return w
+	}
+	isMate := func(score int) bool {
+		return score >= MateScore-MaxPly || score <= -MateScore+MaxPly
+	}
+
+	// Reverse futility pruning returns halfway between the evaluation and beta
+	const queens = "4k3/8/8/8/8/8/8/QQQ1K3 w - - 0 1"
+	for _, beta := range []int{100, -MateScore + MaxPly + 1} {
+		w := newWorker(queens, FeatureRFP)
+		score := w.negamax(3, 1, beta-1, beta, board.NoMove, board.NoMove, true, false)
+		if want := (w.evalStack[1] + beta) / 2; score != want {
+			t.Errorf("RFP with beta %d: got %d, want %d", beta, score, want)
+		}
+		if isMate(score) {
+			t.Errorf("RFP with beta %d returned mate score %d", beta, score)
+		}
+	}
+
+	// Stand pat returns the evaluation, not beta
+	w := newWorker(board.StartFEN)
+	standPat := w.evaluate()
+	beta := standPat - 5
+	if score := w.quiescence(1, beta-1, beta); score != standPat {
+		t.Errorf("Stand pat: got %d, want the evaluation %d", score, standPat)
+	}
+
+	// Rh8 mates whether or not White passes, but a mate after a null move is
+	// not proven: null move pruning cuts off with beta. The window is far
+	// from the material balance, so no lazy evaluation cutoff hides the mate.
+	w = newWorker("k7/8/1K6/8/8/8/8/7R w - - 0 1", FeatureNMP)
+	if score := w.negamax(18, 1, 999, 1000, board.NoMove, board.NoMove, true, false); score != 1000 {
+		t.Errorf("Null move mate: got %d, want beta 1000", score)
+	}
+}
+
+// TestTTReplacement verifies which slot of a cluster a store takes.
+func TestTTReplacement(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	// Hashes that differ only above the mask share a cluster
+	inCluster := func(i int) uint64 { return 0x42 + uint64(i)*(tt.mask+1) }
+	probe := func(hash uint64) (depth, score int, found bool) {
+		e, found := tt.Probe(hash)
+		return int(e.Depth), int(e.Score), found
+	}
+
+	// Same position: a bound 4 or more plies shallower keeps the entry, a
+	// closer bound or an exact score replaces it
+	h := inCluster(0)
+	tt.Store(h, 12, 50, TTLowerBound, board.NoMove, false)
+	tt.Store(h, 8, -30, TTUpperBound, board.NoMove, false)
+	tt.Store(h, DepthQSChecks, 7, TTLowerBound, board.NoMove, false)
+	if depth, score, _ := probe(h); depth != 12 || score != 50 {
+		t.Errorf("shallow bounds: depth %d score %d, want the depth 12 entry", depth, score)
+	}
+	tt.Store(h, 9, 20, TTUpperBound, board.NoMove, false)
+	if depth, score, _ := probe(h); depth != 9 || score != 20 {
+		t.Errorf("bound within 4 plies: depth %d score %d, want it stored", depth, score)
+	}
+	tt.Store(h, 1, 10, TTExact, board.NoMove, false)
+	if depth, score, _ := probe(h); depth != 1 || score != 10 {
+		t.Errorf("exact score: depth %d score %d, want it stored", depth, score)
+	}
+	// An entry of an earlier search is replaced by any depth
+	tt.Store(h, 12, 50, TTLowerBound, board.NoMove, false)
+	tt.NewSearch()
+	tt.Store(h, 2, 5, TTUpperBound, board.NoMove, false)
+	if depth, score, _ := probe(h); depth != 2 || score != 5 {
+		t.Errorf("earlier search: depth %d score %d, want the new bound", depth, score)
+	}
+
+	// Empty slots are taken before any entry, even a stale one
+	tt.Clear()
+	tt.Store(inCluster(0), 1, 0, TTExact, board.NoMove, false)
+	for range 10 {
+		tt.NewSearch()
+	}
+	for i := 1; i < ttClusterSize; i++ {
+		tt.Store(inCluster(i), 1, 0, TTExact, board.NoMove, false)
+	}
+	for i := range ttClusterSize {
+		if _, _, found := probe(inCluster(i)); !found {
+			t.Errorf("entry %d of a cluster filled in order was replaced", i)
+		}
+	}
+
+	// In a full cluster the shallowest entry goes, and an entry three
+	// searches old goes before a shallower current one
+	tt.Clear()
+	depths := []int{20, 19, 18, 30}
+	for i, d := range depths {
+		tt.Store(inCluster(i), d, 0, TTExact, board.NoMove, false)
+	}
+	tt.Store(inCluster(4), 25, 0, TTExact, board.NoMove, false)
+	if _, _, found := probe(inCluster(2)); found {
+		t.Error("shallowest entry kept in a full cluster")
+	}
+	for range 3 {
+		tt.NewSearch()
+	}
+	tt.Store(inCluster(5), 5, 0, TTExact, board.NoMove, false)
+	tt.Store(inCluster(6), 6, 0, TTExact, board.NoMove, false)
+	if _, _, found := probe(inCluster(5)); !found {
+		t.Error("current entry replaced before entries of earlier searches")
+	}
+}
+
+// TestQuiescenceChecks verifies that quiescence finds a quiet mating check
+// at its first ply.
+func TestQuiescenceChecks(t *testing.T) {
+	pos, err := board.ParseFEN("6k1/5ppp/8/8/8/8/5PPP/4R1K1 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newEngine(1, 1).workers[0]
+	w.InitSearch(pos)
+	if score := w.quiescence(0, -Infinity, Infinity); score != MateScore-1 {
+		t.Errorf("Quiescence score %d, want mate in one (%d)", score, MateScore-1)
+	}
+}
+
+// TestDebugValidation runs searches with position validation at every node
+// and checks that no corruption is reported.
+func TestDebugValidation(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	board.DebugMoveValidation = true
+	defer func() {
+		board.DebugMoveValidation = false
+		log.SetOutput(os.Stderr)
+	}()
+
+	eng := newEngine(1, 1)
+	for _, fen := range BenchPositions[:6] {
+		pos, err := board.ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eng.SearchWithLimits(pos, SearchLimits{Depth: 4})
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(line, "CORRUPT") || strings.Contains(line, "MISMATCH") || strings.Contains(line, "ENGINE:") {
+			t.Error(line)
+		}
+	}
+}
+
+// TestRootNotPruned verifies that late move, history and SEE pruning leave
+// root moves alone: the only mate here is a quiet move ordered late.
+func TestRootNotPruned(t *testing.T) {
+	pos, err := board.ParseFEN("k7/8/1K6/8/8/8/8/7R w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mate := board.NewMove(board.H1, board.H8)
+	for depth := 1; depth <= 4; depth++ {
+		eng := NewEngine(16)
+		if move := eng.SearchWithLimits(pos, SearchLimits{Depth: depth}); move != mate {
+			t.Errorf("Depth %d: played %v, want %v", depth, move, mate)
+		}
+	}
+}
+
+// TestTTRule50Scores verifies mate scores from the TT respect the 50-move rule.
+func TestTTRule50Scores(t *testing.T) {
+	mateIn5 := MateScore - 9 // Stored relative to the node: mate in 9 plies
+	tests := []struct {
+		score, ply, rule50, want int
+	}{
+		{mateIn5, 3, 0, mateIn5 - 3},               // Plenty of time
+		{mateIn5, 3, 91, mateIn5 - 3},              // Exactly reaches ply 100
+		{mateIn5, 3, 92, MateScore - MaxPly - 1},   // Too late: only a win
+		{-mateIn5, 3, 92, -MateScore + MaxPly + 1}, // Same for being mated
+		{250, 3, 99, 250},                          // Ordinary scores are unchanged
+	}
+	for _, tt := range tests {
+		if got := AdjustScoreFromTT(tt.score, tt.ply, tt.rule50); got != tt.want {
+			t.Errorf("AdjustScoreFromTT(%d, %d, %d) = %d, want %d", tt.score, tt.ply, tt.rule50, got, tt.want)
+		}
+	}
+}
+
+// TestRepetitionInSearch verifies that one repetition inside the search is a
+// draw, that a move back to a position on the path is found, and that
+// positions before the root need a threefold repetition.
+func TestRepetitionInSearch(t *testing.T) {
+	if cuckooCount != 3668 {
+		t.Errorf("Cuckoo tables hold %d moves, want 3668", cuckooCount)
+	}
+
+	pos, err := board.ParseFEN(board.StartFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newEngine(1, 1).workers[0]
+	w.SetRootHistory([]uint64{pos.Hash})
+	w.InitSearch(pos)
+	if w.posHistoryLen != 1 || w.rootHistoryIdx != 0 {
+		t.Fatalf("Root counted twice: history length %d, root index %d", w.posHistoryLen, w.rootHistoryIdx)
+	}
+
+	// Play moves as the search does, checking each position reached
+	play := func(from, to board.Square) {
+		w.pos.MakeMove(board.NewMove(from, to))
+		w.posHistoryBuffer[w.posHistoryLen] = w.pos.Hash
+		w.posHistoryLen++
+	}
+	play(board.G1, board.F3)
+	play(board.G8, board.F6)
+	if w.upcomingRepetition() {
+		t.Error("Repetition found with no move back")
+	}
+	play(board.F3, board.G1)
+	play(board.F6, board.G8)
+	if w.isDraw() {
+		t.Error("Return to the root position scored as a draw")
+	}
+	play(board.G1, board.F3)
+	if !w.isDraw() {
+		t.Error("Repetition inside the search not scored as a draw")
+	}
+	if !w.upcomingRepetition() {
+		t.Error("Ng8-f6 back to a position of the search not found")
+	}
+
+	// The same moves before the root are only a first repetition
+	hashes := make([]uint64, w.posHistoryLen)
+	copy(hashes, w.posHistoryBuffer[:w.posHistoryLen])
+	w.SetRootHistory(hashes)
+	w.InitSearch(w.pos)
+	if w.isDraw() || w.upcomingRepetition() {
+		t.Error("Single repetition before the root scored as a draw")
+	}
+}
+
+// TestAnalysisSession verifies that an exported analysis tree restores the
+// transposition table entries it came from.
+func TestAnalysisSession(t *testing.T) {
+	pos, err := board.ParseFEN(board.StartFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eng := newEngine(16, 1)
+	eng.SearchWithLimits(pos, SearchLimits{Depth: 6})
+
+	nodes := eng.ExportAnalysis(pos, 500)
+	if len(nodes) == 0 || len(nodes) > 500 || len(nodes[0].Moves) != 0 || nodes[0].Best == board.NoMove {
+		t.Fatalf("Unexpected export: %d nodes", len(nodes))
+	}
+	for i := 1; i < len(nodes); i++ {
+		if len(nodes[i].Moves) < len(nodes[i-1].Moves) {
+			t.Fatalf("Node %d is nearer the root than node %d", i, i-1)
+		}
+	}
+
+	eng.Clear()
+	if n := len(eng.ExportAnalysis(pos, 500)); n != 0 {
+		t.Fatalf("Cleared table exported %d nodes", n)
+	}
+	if stored := eng.ImportAnalysis(pos, nodes); stored != len(nodes) {
+		t.Errorf("Stored %d of %d nodes", stored, len(nodes))
+	}
+	again := eng.ExportAnalysis(pos, 500)
+	if len(again) != len(nodes) || again[0].Best != nodes[0].Best || again[0].Depth != nodes[0].Depth || again[0].Score != nodes[0].Score {
+		t.Errorf("Restored tree differs: %d nodes, root %+v, want %d nodes, root %+v", len(again), again[0], len(nodes), nodes[0])
+	}
+
+	// Nodes that do not fit the position are skipped
+	bad := []AnalysisNode{
+		{Moves: []board.Move{board.NewMove(board.E2, board.E5)}, Depth: 5},
+		{Best: board.NewMove(board.E7, board.E5), Depth: 5},
+	}
+	if stored := eng.ImportAnalysis(pos, bad); stored != 0 {
+		t.Errorf("Stored %d illegal nodes", stored)
+	}
+}
+
+// TestLineAnalysis verifies that a line searched elsewhere is stored along
+// its moves with alternating scores and mate distances counted from each
+// position.
+func TestLineAnalysis(t *testing.T) {
+	pos := board.NewPosition()
+	pv := []board.Move{board.NewMove(board.E2, board.E4), board.NewMove(board.E7, board.E5), board.NewMove(board.G1, board.F3)}
+
+	nodes := LineAnalysis(pv, 30, 40)
+	if len(nodes) != 3 || nodes[1].Score != -30 || nodes[2].Score != 30 || nodes[2].Depth != 38 || nodes[2].Best != pv[2] {
+		t.Fatalf("LineAnalysis = %+v", nodes)
+	}
+	mate := LineAnalysis(pv, MateScore-5, 40)
+	if mate[1].Score != -(MateScore-4) || mate[2].Score != MateScore-3 {
+		t.Errorf("Mate scores along the line: %d, %d; want %d, %d", mate[1].Score, mate[2].Score, -(MateScore - 4), MateScore-3)
+	}
+	if n := len(LineAnalysis(pv, 30, 2)); n != 2 {
+		t.Errorf("Line to depth 2 has %d nodes, want 2", n)
+	}
+
+	eng := newEngine(16, 1)
+	if stored := eng.ImportAnalysis(pos, nodes); stored != 3 {
+		t.Fatalf("Stored %d of 3 nodes", stored)
+	}
+	root := eng.ExportAnalysis(pos, 1)
+	if len(root) != 1 || root[0].Best != pv[0] || root[0].Score != 30 || root[0].Flag != TTExact {
+		t.Errorf("Root after import: %+v", root)
+	}
+}
+
+// TestLMRTableRegeneration verifies that changing LMR coefficients regenerates the table.
+func TestLMRTableRegeneration(t *testing.T) {
+	defer SetLMRParams(lmrBase, lmrDivisor)
+
+	before := lmrReductions[63][63]
+	SetLMRParams(lmrBase*10, lmrDivisor)
+	after := lmrReductions[63][63]
+	if after <= before {
+		t.Errorf("Scaling up the base coefficient did not increase reductions: %d -> %d", before, after)
+	}
+
+	// Invalid divisors are ignored
+	SetLMRParams(lmrBase, 0)
+	if lmrReductions[63][63] != after {
+		t.Errorf("Zero divisor changed the table")
+	}
+}
+
 func TestPawnHashTable(t *testing.T) {
 	pt := NewPawnTable(1) // 1MB
 
@@ -213,3 +877,1189 @@ func BenchmarkScoreMove(b *testing.B) {
 		}
 	}
 }
+
+// TestHistoryGravity verifies that history bonuses and maluses saturate at
+// historyMax and that a malus undoes a bonus.
+func TestHistoryGravity(t *testing.T) {
+	mo := NewMoveOrderer()
+	move := board.NewMove(board.G1, board.F3)
+
+	mo.UpdateHistory(move, 10, true)
+	mo.UpdateHistory(move, 10, false)
+	if got := mo.GetHistoryScore(move); got < 0 || got > 1 {
+		t.Errorf("Bonus then malus left history %d, want about 0", got)
+	}
+
+	for i := 0; i < 100000; i++ {
+		mo.UpdateHistory(move, 60, true)
+	}
+	if got := mo.GetHistoryScore(move); got <= historyMax*9/10 || got > historyMax {
+		t.Errorf("History %d after repeated bonuses, want just under %d", got, historyMax)
+	}
+	for i := 0; i < 100000; i++ {
+		mo.UpdateHistory(move, 60, false)
+	}
+	if got := mo.GetHistoryScore(move); got >= -historyMax*9/10 || got < -historyMax {
+		t.Errorf("History %d after repeated maluses, want just above %d", got, -historyMax)
+	}
+}
+
+// TestCountermoveHistoryUpdate verifies that a cutoff gives the countermove
+// history of the cutoff move a bonus, and the moves tried before it a malus.
+// The updates run after the move is unmade, so they must not look up the
+// moving piece on its destination square.
+func TestCountermoveHistoryUpdate(t *testing.T) {
+	// After 1. e4, as negamax sees it at the reply's node
+	pos, err := board.ParseFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newEngine(1, 1).workers[0]
+	w.InitSearch(pos)
+	prevMove := board.NewMove(board.E2, board.E4)
+	good := board.NewMove(board.G8, board.F6)
+	bad := board.NewMove(board.A7, board.A6)
+
+	w.updateQuietHistories(good, board.BlackKnight, prevMove, 1, 6, true)
+	w.updateQuietHistories(bad, board.BlackPawn, prevMove, 1, 6, false)
+
+	if got := w.orderer.GetCountermoveHistoryScore(prevMove, board.WhitePawn, board.BlackKnight, board.F6); got <= 0 {
+		t.Errorf("Countermove history of the cutoff move %d, want a bonus", got)
+	}
+	if got := w.orderer.GetCountermoveHistoryScore(prevMove, board.WhitePawn, board.BlackPawn, board.A6); got >= 0 {
+		t.Errorf("Countermove history of the move tried first %d, want a malus", got)
+	}
+}
+
+// TestRootEffortOrdering verifies that root moves which took the most nodes
+// are ordered first after the TT move.
+func TestRootEffortOrdering(t *testing.T) {
+	pos := board.NewPosition()
+	w := newEngine(1, 1).workers[0]
+	w.InitSearch(pos)
+	w.SearchDepth(4, -Infinity, Infinity)
+	var total uint64
+	for from := range w.rootEffort {
+		for to := range w.rootEffort[from] {
+			total += w.rootEffort[from][to]
+		}
+	}
+	if total == 0 || total >= w.nodes {
+		t.Fatalf("Root effort %d, want between 0 and %d nodes", total, w.nodes)
+	}
+
+	w.rootEffort = [64][64]uint64{}
+	w.rootEffort[board.A2][board.A3] = 900
+	w.rootEffort[board.H2][board.H3] = 100
+	ttMove := board.NewMove(board.G1, board.F3)
+	moves := w.pos.GenerateLegalMoves()
+	scores := w.orderer.ScoreMoves(w.pos, moves, 0, ttMove)
+	w.orderRootMoves(moves, scores, ttMove)
+	want := []board.Move{ttMove, board.NewMove(board.A2, board.A3), board.NewMove(board.H2, board.H3)}
+	for i, m := range want {
+		PickMove(moves, scores, i)
+		if moves.Get(i) != m {
+			t.Errorf("Root move %d is %v, want %v", i+1, moves.Get(i), m)
+		}
+	}
+}
+
+// TestEvasionOrdering verifies that out of check the capture of the checker
+// comes first, then king moves, safest first, then blocks.
+func TestEvasionOrdering(t *testing.T) {
+	pos, _ := board.ParseFEN("4k3/8/8/4r3/2N5/R7/8/4K3 w - - 0 1")
+	pos.UpdateCheckers()
+	mo := NewMoveOrderer()
+	moves := pos.GenerateLegalMoves()
+	scores := mo.ScoreEvasions(pos, moves, 0, board.NoMove)
+
+	kind := func(m board.Move) int {
+		switch {
+		case m.IsCapture(pos):
+			return 0
+		case m.From() == board.E1:
+			return 1
+		}
+		return 2
+	}
+	for i := 0; i < moves.Len(); i++ {
+		PickMove(moves, scores, i)
+		if i > 0 && kind(moves.Get(i)) < kind(moves.Get(i-1)) {
+			t.Errorf("Evasion %d is %v after %v", i+1, moves.Get(i), moves.Get(i-1))
+		}
+	}
+	if first := moves.Get(0); first != board.NewMove(board.C4, board.E5) {
+		t.Errorf("First evasion is %v, want c4e5", first)
+	}
+}
+
+// TestCorrectionHistoryTables verifies the blended correction tables learn, clamp and age.
+func TestCorrectionHistoryTables(t *testing.T) {
+	pos := board.NewPosition()
+	ch := NewCorrectionHistory()
+
+	if got := ch.Get(pos, board.NoPiece, board.NoSquare); got != 0 {
+		t.Fatalf("Fresh correction history returned %d, want 0", got)
+	}
+
+	// Repeated large errors push every table towards the bonus limit
+	for i := 0; i < 200; i++ {
+		ch.Update(pos, board.WhiteKnight, board.F3, 5000, 0, 20)
+	}
+	withCont := ch.Get(pos, board.WhiteKnight, board.F3)
+	withoutCont := ch.Get(pos, board.NoPiece, board.NoSquare)
+	if withCont <= 0 || withCont > corrValueLimit {
+		t.Errorf("Correction %d out of range (0, %d]", withCont, corrValueLimit)
+	}
+	if withoutCont >= withCont {
+		t.Errorf("Continuation table not applied: with=%d without=%d", withCont, withoutCont)
+	}
+
+	ch.Age()
+	if aged := ch.Get(pos, board.WhiteKnight, board.F3); aged >= withCont {
+		t.Errorf("Age did not reduce correction: before=%d after=%d", withCont, aged)
+	}
+
+	ch.Clear()
+	if got := ch.Get(pos, board.WhiteKnight, board.F3); got != 0 {
+		t.Errorf("Clear left correction %d", got)
+	}
+}
+
+// TestCorrectionHistoryAging verifies correction history is kept between
+// searches and aged, not cleared, between games.
+func TestCorrectionHistoryAging(t *testing.T) {
+	pos := board.NewPosition()
+	eng := newEngine(1, 1)
+	w := eng.workers[0]
+	for i := 0; i < 200; i++ {
+		w.corrHistory.Update(pos, board.NoPiece, board.NoSquare, 5000, 0, 20)
+	}
+	learned := w.corrHistory.Get(pos, board.NoPiece, board.NoSquare)
+
+	w.Reset()
+	if got := w.corrHistory.Get(pos, board.NoPiece, board.NoSquare); got != learned {
+		t.Errorf("Correction %d after a new search, want %d kept", got, learned)
+	}
+	eng.Clear()
+	if got := w.corrHistory.Get(pos, board.NoPiece, board.NoSquare); got <= 0 || got >= learned {
+		t.Errorf("Correction %d after a new game, want aged from %d", got, learned)
+	}
+}
+
+// TestTimeManager verifies soft/hard bounds and the main worker's iteration check.
+func TestTimeManager(t *testing.T) {
+	tm := NewTimeManager()
+
+	// Clock-based search: soft bound below hard bound, no stop right after depth 1
+	tm.Init(UCILimits{Time: [2]time.Duration{10 * time.Second, 10 * time.Second}}, board.White, 20)
+	if !tm.Timed() {
+		t.Fatal("Expected a timed search")
+	}
+	if tm.OptimumTime() >= tm.MaximumTime() {
+		t.Errorf("Optimum %v should be below maximum %v", tm.OptimumTime(), tm.MaximumTime())
+	}
+	if tm.StopAfterIteration(1, board.NewMove(board.E2, board.E4), 20, 100) {
+		t.Error("Search stopped right after the first iteration")
+	}
+
+	// Fixed move time: only the hard bound applies
+	tm.Init(UCILimits{MoveTime: time.Nanosecond}, board.White, 0)
+	if tm.StopAfterIteration(1, board.NewMove(board.E2, board.E4), 20, 100) {
+		t.Error("Fixed move time should not stop on the soft bound")
+	}
+
+	// Node-based cutoff
+	tm.Init(UCILimits{Nodes: 1000}, board.White, 0)
+	if tm.Timed() {
+		t.Error("Node-limited search should not be timed")
+	}
+	if !tm.StopAfterIteration(1, board.NewMove(board.E2, board.E4), 20, 1000) {
+		t.Error("Expected stop once the node limit is reached")
+	}
+
+	// Clock-based bounds: a share of the clock plus most of the increment,
+	// the hard bound capped by the optimum and the remaining time
+	clock := func(left, inc time.Duration, movesToGo, ply int) (optimum, maximum time.Duration) {
+		tm.Init(UCILimits{
+			Time:      [2]time.Duration{left, left},
+			Inc:       [2]time.Duration{inc, inc},
+			MovesToGo: movesToGo,
+		}, board.White, ply)
+		return tm.OptimumTime(), tm.MaximumTime()
+	}
+	if soft, hard := clock(2*time.Second, 0, 0, 0); hard >= time.Second || soft > 2*time.Second/50 {
+		t.Errorf("2s on the clock at the first move: optimum %v, maximum %v", soft, hard)
+	}
+	if soft, hard := clock(60*time.Second, 0, 0, 40); soft != 60*time.Second/40 || hard != 5*soft {
+		t.Errorf("60s at ply 40: optimum %v, maximum %v; want 1.5s and 7.5s", soft, hard)
+	}
+	if soft, _ := clock(60*time.Second, time.Second, 0, 40); soft != 60*time.Second/40+900*time.Millisecond {
+		t.Errorf("60s+1s at ply 40: optimum %v, want 2.4s", soft)
+	}
+	if _, hard := clock(10*time.Second, 0, 1, 40); hard > 10*time.Second*95/100 {
+		t.Errorf("Last move before the time control: maximum %v, want at most 95%% of the clock", hard)
+	}
+}
+
+// TestPonder verifies a ponder search ignores the clock until PonderHit, and
+// that the minimum thinking time holds back a quick move.
+func TestPonder(t *testing.T) {
+	eng := NewEngine(16)
+	limits := UCILimits{
+		Time:   [2]time.Duration{500 * time.Millisecond, 500 * time.Millisecond},
+		Ponder: true,
+	}
+
+	done := make(chan board.Move)
+	go func() {
+		done <- eng.SearchWithUCILimits(board.NewPosition(), limits, 0)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Ponder search stopped on the clock")
+	case <-time.After(time.Second):
+	}
+
+	eng.PonderHit()
+	select {
+	case move := <-done:
+		if move == board.NoMove {
+			t.Error("Ponder search returned NoMove")
+		}
+	case <-time.After(2 * time.Second):
+		eng.Stop()
+		<-done
+		t.Error("Search did not stop after PonderHit")
+	}
+
+	var tm TimeManager
+	tm.Init(UCILimits{Time: [2]time.Duration{10 * time.Second, 10 * time.Second}, MinTime: 3 * time.Second}, board.White, 20)
+	if tm.OptimumTime() < 3*time.Second || tm.MaximumTime() < 3*time.Second {
+		t.Errorf("Minimum thinking time not applied: optimum %v, maximum %v", tm.OptimumTime(), tm.MaximumTime())
+	}
+}
+
+// TestNodesTime verifies that under nodestime a clock-based search spends
+// a node budget, reproducibly, and that the budget carries over moves.
+func TestNodesTime(t *testing.T) {
+	const npmsec = 100
+	limits := UCILimits{
+		Time: [2]time.Duration{time.Second, time.Second},
+		Inc:  [2]time.Duration{10 * time.Millisecond, 10 * time.Millisecond},
+	}
+	search := func() (*Engine, board.Move) {
+		eng := newEngine(16, 1)
+		eng.SetNodesTime(npmsec)
+		return eng, eng.SearchWithUCILimits(board.NewPosition(), limits, 0)
+	}
+
+	eng, move := search()
+	nodes := eng.LastSearchInfo().Nodes
+	if move == board.NoMove || nodes == 0 {
+		t.Fatalf("Search returned %v after %d nodes", move, nodes)
+	}
+	if budget := uint64(npmsec * limits.Time[board.White].Milliseconds()); nodes > budget {
+		t.Errorf("Searched %d nodes with a budget of %d", nodes, budget)
+	}
+	if want := npmsec*(1000+10) - int64(nodes); eng.availableNodes != want {
+		t.Errorf("Budget left %d, want %d", eng.availableNodes, want)
+	}
+
+	again, move2 := search()
+	if move2 != move || again.LastSearchInfo().Nodes != nodes {
+		t.Errorf("Repeated search played %v after %d nodes, first %v after %d",
+			move2, again.LastSearchInfo().Nodes, move, nodes)
+	}
+
+	// The GUI clock is ignored after the first move, until a new game
+	left := eng.availableNodes
+	eng.SearchWithUCILimits(board.NewPosition(), limits, 0)
+	if eng.availableNodes >= left+npmsec*10 {
+		t.Errorf("Budget %d did not shrink from %d", eng.availableNodes, left)
+	}
+	eng.Clear()
+	if eng.availableNodes != -1 {
+		t.Errorf("Budget %d after Clear", eng.availableNodes)
+	}
+}
+
+// TestTraceEvaluate verifies the eval trace adds up to the static evaluation.
+func TestTraceEvaluate(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4",
+		"8/5pk1/6p1/3P4/1r6/6P1/5PK1/3R4 b - - 0 40",
+		"8/4kp2/2b3p1/8/3B4/5PP1/6K1/8 w - - 0 50", // Opposite-colored bishops
+	}
+	for _, fen := range fens {
+		pos, err := board.ParseFEN(fen)
+		if err != nil {
+			t.Fatalf("Failed to parse FEN %s: %v", fen, err)
+		}
+		trace := TraceEvaluate(pos)
+		if trace.Score != Evaluate(pos) {
+			t.Errorf("%s: trace score %d != Evaluate %d", fen, trace.Score, Evaluate(pos))
+		}
+		if !strings.Contains(trace.String(), "Mobility") {
+			t.Errorf("%s: trace output is missing terms:\n%s", fen, trace)
+		}
+	}
+}
+
+// TestTracePieces verifies the piece contributions of the starting position
+// mirror between the sides, and that a knight is worth more centralized.
+func TestTracePieces(t *testing.T) {
+	pieces := TracePieces(board.NewPosition())
+	if len(pieces) != 32 {
+		t.Fatalf("Got %d pieces, want 32", len(pieces))
+	}
+	scores := make(map[board.Square]int)
+	for _, p := range pieces {
+		scores[p.Square] = p.Score
+	}
+	for _, p := range pieces {
+		if p.Piece.Color() == board.White && scores[p.Square.Mirror()] != -p.Score {
+			t.Errorf("%v on %v: %d, mirrored %d", p.Piece, p.Square, p.Score, scores[p.Square.Mirror()])
+		}
+	}
+
+	positional := func(fen string, sq board.Square) int {
+		pos, err := board.ParseFEN(fen)
+		if err != nil {
+			t.Fatalf("Failed to parse FEN %s: %v", fen, err)
+		}
+		for _, p := range TracePieces(pos) {
+			if p.Square == sq {
+				return p.Positional()
+			}
+		}
+		t.Fatalf("%s: no piece on %v", fen, sq)
+		return 0
+	}
+	rim := positional("4k3/8/8/8/N7/8/8/4K3 w - - 0 1", board.A4)
+	center := positional("4k3/8/8/8/3N4/8/8/4K3 w - - 0 1", board.D4)
+	if center <= rim {
+		t.Errorf("Knight on d4 %d, on a4 %d: want the center better", center, rim)
+	}
+}
+
+// TestPracticalChances verifies playouts of won, drawn and lost positions,
+// and that a cancelled context stops them.
+func TestPracticalChances(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want float64
+	}{
+		{"4k3/8/8/8/8/8/8/3QK3 w - - 0 1", 1},  // KQ vs K
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", 0.5}, // KvK
+		{"3qk3/8/8/8/8/8/8/4K3 w - - 0 1", 0},  // K vs KQ
+	}
+	for _, tt := range tests {
+		pos, err := board.ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("Failed to parse FEN %s: %v", tt.fen, err)
+		}
+		c := PracticalChances(context.Background(), pos, PlayoutOptions{Playouts: 4})
+		if c.Playouts() != 4 {
+			t.Errorf("%s: %d playouts, want 4", tt.fen, c.Playouts())
+		}
+		if c.Score() != tt.want {
+			t.Errorf("%s: score %.2f (%+v), want %.2f", tt.fen, c.Score(), c, tt.want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if c := PracticalChances(ctx, board.NewPosition(), PlayoutOptions{}); c.Playouts() != 0 {
+		t.Errorf("Cancelled: %d playouts, want 0", c.Playouts())
+	}
+}
+
+// TestTunableParams verifies setting, range checks and the JSON round trip of parameter sets.
+func TestTunableParams(t *testing.T) {
+	defer ResetTunableParams()
+
+	if err := SetTunableParam("razorbase", 600); err != nil {
+		t.Fatalf("SetTunableParam failed: %v", err)
+	}
+	if razorBase != 600 {
+		t.Errorf("Expected razorBase 600, got %d", razorBase)
+	}
+	if err := SetTunableParam("RazorBase", 100000); err == nil {
+		t.Errorf("Out-of-range value was accepted")
+	}
+	if err := SetTunableParam("NoSuchParam", 1); err == nil {
+		t.Errorf("Unknown parameter was accepted")
+	}
+
+	// LMR parameters regenerate the table
+	if err := SetTunableParam("LMRBase", 4000); err != nil {
+		t.Fatalf("SetTunableParam failed: %v", err)
+	}
+	if FindTunableParam("LMRBase").Value() != 4000 {
+		t.Errorf("Expected LMRBase 4000, got %d", FindTunableParam("LMRBase").Value())
+	}
+
+	path := filepath.Join(t.TempDir(), "params.json")
+	if err := SaveTunableParams(path); err != nil {
+		t.Fatalf("SaveTunableParams failed: %v", err)
+	}
+
+	ResetTunableParams()
+	if razorBase != FindTunableParam("RazorBase").Default {
+		t.Errorf("Reset did not restore razorBase")
+	}
+
+	if err := LoadTunableParams(path); err != nil {
+		t.Fatalf("LoadTunableParams failed: %v", err)
+	}
+	if razorBase != 600 || FindTunableParam("LMRBase").Value() != 4000 {
+		t.Errorf("Loaded parameters differ: razorBase %d, LMRBase %d",
+			razorBase, FindTunableParam("LMRBase").Value())
+	}
+}
+
+// TestSearchFeatures verifies that search heuristics can be switched at
+// runtime and that the workers use them from the next search.
+func TestSearchFeatures(t *testing.T) {
+	eng := newEngine(16, 1)
+	if eng.SearchFeatures() != DefaultSearchFeatures {
+		t.Fatalf("New engine features %b, want %b", eng.SearchFeatures(), DefaultSearchFeatures)
+	}
+	if DefaultSearchFeatures.Has(FeatureNMP) != EnableNMP || DefaultSearchFeatures.Has(FeatureRFP) != EnableRFP {
+		t.Errorf("Default features differ from the Enable flags")
+	}
+	if err := eng.SetSearchFeature("NoSuchFeature", false); err == nil {
+		t.Errorf("Unknown feature was accepted")
+	}
+
+	search := func() uint64 {
+		eng.Clear()
+		eng.SearchWithLimits(board.NewPosition(), SearchLimits{Depth: 8})
+		return eng.getTotalNodes()
+	}
+	withNMP := search()
+
+	if err := eng.SetSearchFeature("nmp", false); err != nil {
+		t.Fatalf("SetSearchFeature failed: %v", err)
+	}
+	if eng.SearchFeatures().Has(FeatureNMP) {
+		t.Fatal("NMP still enabled")
+	}
+	withoutNMP := search()
+	if eng.workers[0].features.Has(FeatureNMP) {
+		t.Error("Worker did not pick up the disabled feature")
+	}
+	if withNMP == withoutNMP {
+		t.Errorf("Disabling NMP did not change the search (%d nodes)", withNMP)
+	}
+	t.Logf("Nodes at depth 8: %d with NMP, %d without", withNMP, withoutNMP)
+}
+
+// TestEvalCache verifies the evaluation cache, and that it does not change
+// the search.
+func TestEvalCache(t *testing.T) {
+	c := NewEvalCache(1000)
+	if len(c.entries) != 512 {
+		t.Fatalf("%d entries, want 512", len(c.entries))
+	}
+	if _, ok := c.Probe(0); ok {
+		t.Error("Empty slot verified")
+	}
+	c.Store(0x1234, -321)
+	if eval, ok := c.Probe(0x1234); !ok || eval != -321 {
+		t.Errorf("Probe got %d, %v, want -321", eval, ok)
+	}
+	if _, ok := c.Probe(0x1234 + 512); ok {
+		t.Error("Probe found another position in the same slot")
+	}
+	c.Clear()
+	if _, ok := c.Probe(0x1234); ok {
+		t.Error("Probe found a cleared entry")
+	}
+
+	pos, _ := board.ParseFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	search := func(cached bool) (board.Move, uint64) {
+		eng := newEngine(16, 1)
+		if !cached {
+			eng.workers[0].evalCache = nil
+		}
+		move := eng.SearchWithLimits(pos, SearchLimits{Depth: 8})
+		return move, eng.getTotalNodes()
+	}
+	move, nodes := search(true)
+	uncachedMove, uncachedNodes := search(false)
+	if move != uncachedMove || nodes != uncachedNodes {
+		t.Errorf("Search with the cache played %v in %d nodes, without %v in %d", move, nodes, uncachedMove, uncachedNodes)
+	}
+}
+
+// TestEvalCacheNNUE verifies that under NNUE an evaluation cache hit still
+// computes the accumulators, so the children of the position can update
+// theirs incrementally. Random feature transformer weights stand in for a
+// trained network.
+func TestEvalCacheNNUE(t *testing.T) {
+	nets := sfnnue.NewNetworks()
+	r := rand.New(rand.NewPCG(1, 2))
+	for _, n := range []*sfnnue.Network{nets.Big, nets.Small} {
+		for i := range n.FeatureTransformer.Weights {
+			n.FeatureTransformer.Weights[i] = int16(r.IntN(64) - 32)
+		}
+	}
+
+	w := newEngine(1, 1).workers[0]
+	w.initNNUE(nets)
+	w.useNNUE = true
+	w.InitSearch(board.NewPosition())
+	w.evaluate()
+
+	move := board.NewMove(board.E2, board.E4)
+	w.computeDirtyPieces(move)
+	w.nnuePush()
+	w.pos.MakeMove(move)
+	w.evalCache.Store(w.pos.Hash, 17)
+	if got := w.evaluate(); got != w.nnueAdjust(17) {
+		t.Fatalf("evaluate after 1.e4 = %d, want the cached %d", got, w.nnueAdjust(17))
+	}
+	for name, acc := range map[string]*sfnnue.Accumulator{"small": w.nnueAcc.CurrentSmall(), "big": w.nnueAcc.CurrentBig()} {
+		if !acc.Computed[0] || !acc.Computed[1] {
+			t.Errorf("%s accumulator computed %v after a cache hit", name, acc.Computed)
+		}
+	}
+}
+
+// TestVoteBestResult verifies the choice of the move among the workers'
+// last iterations.
+func TestVoteBestResult(t *testing.T) {
+	a := board.NewMove(board.E2, board.E4)
+	b := board.NewMove(board.D2, board.D4)
+	mate := MateScore - 9
+
+	tests := []struct {
+		name    string
+		results []WorkerResult
+		want    WorkerResult
+	}{
+		{"none", []WorkerResult{{}, {}}, WorkerResult{}},
+		{"majority over a deeper helper", []WorkerResult{
+			{WorkerID: 0, Move: a, Depth: 10, Score: 30},
+			{WorkerID: 1, Move: b, Depth: 11, Score: 40},
+			{WorkerID: 2, Move: a, Depth: 10, Score: 35},
+		}, WorkerResult{WorkerID: 2, Move: a, Depth: 10, Score: 35}},
+		{"clearly better helper", []WorkerResult{
+			{WorkerID: 0, Move: a, Depth: 10, Score: 30},
+			{WorkerID: 1, Move: b, Depth: 12, Score: 120},
+			{WorkerID: 2, Move: a, Depth: 10, Score: 30},
+		}, WorkerResult{WorkerID: 1, Move: b, Depth: 12, Score: 120}},
+		{"proven mate", []WorkerResult{
+			{WorkerID: 0, Move: a, Depth: 20, Score: 300},
+			{WorkerID: 1, Move: b, Depth: 8, Score: mate},
+			{WorkerID: 2, Move: a, Depth: 20, Score: 300},
+		}, WorkerResult{WorkerID: 1, Move: b, Depth: 8, Score: mate}},
+		{"faster mate", []WorkerResult{
+			{WorkerID: 0, Move: a, Depth: 20, Score: mate},
+			{WorkerID: 1, Move: b, Depth: 8, Score: mate + 4},
+		}, WorkerResult{WorkerID: 1, Move: b, Depth: 8, Score: mate + 4}},
+		{"proven loss", []WorkerResult{
+			{WorkerID: 0, Move: a, Depth: 10, Score: -50},
+			{WorkerID: 1, Move: b, Depth: 14, Score: -mate},
+			{WorkerID: 2, Move: b, Depth: 14, Score: -mate},
+		}, WorkerResult{WorkerID: 0, Move: a, Depth: 10, Score: -50}},
+	}
+	for _, tt := range tests {
+		got := voteBestResult(tt.results)
+		if got.WorkerID != tt.want.WorkerID || got.Move != tt.want.Move || got.Score != tt.want.Score {
+			t.Errorf("%s: got worker %d %v %d, want worker %d %v %d", tt.name,
+				got.WorkerID, got.Move, got.Score, tt.want.WorkerID, tt.want.Move, tt.want.Score)
+		}
+	}
+}
+
+// TestSearchStats verifies that statistics are collected only when enabled
+// and add up with the node count.
+func TestSearchStats(t *testing.T) {
+	eng := newEngine(16, 1)
+	eng.SearchWithLimits(board.NewPosition(), SearchLimits{Depth: 8})
+	if stats := eng.LastSearchStats(); stats != (SearchStats{}) {
+		t.Fatalf("Stats collected while disabled: %v", stats)
+	}
+
+	eng.Clear()
+	eng.SetCollectStats(true)
+	eng.SearchWithLimits(board.NewPosition(), SearchLimits{Depth: 8})
+	stats := eng.LastSearchStats()
+	if stats.Nodes+stats.QNodes != eng.getTotalNodes() {
+		t.Errorf("Stats count %d+%d nodes, search %d", stats.Nodes, stats.QNodes, eng.getTotalNodes())
+	}
+	if stats.TTProbes == 0 || stats.TTProbes > stats.Nodes || stats.TTHits == 0 || stats.TTHits > stats.TTProbes {
+		t.Errorf("TT probes %d, hits %d for %d nodes", stats.TTProbes, stats.TTHits, stats.Nodes)
+	}
+	if stats.EvalProbes == 0 || stats.EvalHits == 0 || stats.EvalHits > stats.EvalProbes {
+		t.Errorf("Eval cache probes %d, hits %d", stats.EvalProbes, stats.EvalHits)
+	}
+	if stats.NullTries == 0 || stats.NullCutoffs > stats.NullTries {
+		t.Errorf("Null move %d/%d", stats.NullCutoffs, stats.NullTries)
+	}
+	if rate := stats.FirstMoveCutoffRate(); rate < 0.5 {
+		t.Errorf("First move cutoff rate %.2f, want most cutoffs from the first move", rate)
+	}
+	t.Logf("Stats at depth 8: %v", stats)
+}
+
+// fakeProber is a three-piece tablebase in which only the white rook on d4
+// wins, and any other ending is lost for White.
+type fakeProber struct {
+	probes atomic.Int64
+}
+
+func (f *fakeProber) Probe(pos *board.Position) tablebase.ProbeResult {
+	if tablebase.CountPieces(pos) > f.MaxPieces() {
+		return tablebase.ProbeResult{}
+	}
+	f.probes.Add(1)
+	whiteWins := pos.PieceAt(board.D4) == board.WhiteRook
+	if whiteWins == (pos.SideToMove == board.White) {
+		return tablebase.ProbeResult{Found: true, WDL: tablebase.WDLWin}
+	}
+	return tablebase.ProbeResult{Found: true, WDL: tablebase.WDLLoss}
+}
+
+func (f *fakeProber) ProbeRoot(pos *board.Position) tablebase.RootResult {
+	return tablebase.RootResult{}
+}
+
+func (f *fakeProber) MaxPieces() int { return 3 }
+
+func (f *fakeProber) Available() bool { return true }
+
+// TestTablebaseSimplification verifies that captures one piece away from
+// the tablebases are ordered by their result, and that captures are probed
+// even below the probe depth.
+func TestTablebaseSimplification(t *testing.T) {
+	pos, err := board.ParseFEN("k7/8/8/8/3b4/2K5/8/3R4 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rxd4 := board.NewMove(board.D1, board.D4)
+	kxd4 := board.NewMove(board.C3, board.D4)
+
+	prober := &fakeProber{}
+	eng := newEngine(16, 1)
+	w := eng.workers[0]
+	w.SetTablebase(prober, 1)
+	w.InitSearch(pos)
+	moves := w.pos.GenerateLegalMoves()
+	scores := w.orderer.ScoreMoves(w.pos, moves, 0, board.NoMove)
+	w.orderSimplifications(moves, scores, board.NoMove)
+	for i := 0; i < moves.Len(); i++ {
+		switch m := moves.Get(i); m {
+		case rxd4:
+			if scores[i] < tbSimplifyBonus {
+				t.Errorf("Winning simplification %v scored %d", m, scores[i])
+			}
+		case kxd4:
+			if scores[i] > BadCaptureBase {
+				t.Errorf("Losing simplification %v scored %d", m, scores[i])
+			}
+		default:
+			if scores[i] >= GoodCaptureBase {
+				t.Errorf("Quiet move %v scored %d", m, scores[i])
+			}
+		}
+	}
+
+	prober.probes.Store(0)
+	eng.SetTablebase(prober)
+	eng.SetSyzygyProbeDepth(100)
+	if move := eng.SearchWithLimits(pos, SearchLimits{Depth: 4}); move != rxd4 {
+		t.Errorf("Best move %v, want %v", move, rxd4)
+	}
+	if prober.probes.Load() == 0 {
+		t.Error("No probes after captures below the probe depth")
+	}
+}
+
+// TestAvoidMoves verifies forbidden and discouraged root moves, in single
+// and Multi-PV searches.
+func TestAvoidMoves(t *testing.T) {
+	pos, err := board.ParseFEN("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rxd5 := board.NewMove(board.D2, board.D5)
+	eng := newEngine(16, 1)
+	search := func() board.Move {
+		eng.Clear()
+		return eng.SearchWithLimits(pos, SearchLimits{Depth: 5})
+	}
+
+	if move := search(); move != rxd5 {
+		t.Fatalf("Best move %v, want %v", move, rxd5)
+	}
+	eng.AvoidMoves([]board.Move{rxd5}, nil, 0)
+	if move := search(); move == rxd5 {
+		t.Error("Forbidden move was played")
+	}
+	if results := eng.SearchMultiPV(pos, SearchLimits{Depth: 4, MultiPV: 3}); len(results) == 0 || results[0].Move == rxd5 {
+		t.Errorf("Forbidden move in Multi-PV results %v", results)
+	}
+	eng.AvoidMoves(nil, []board.Move{rxd5}, 100)
+	if move := search(); move != rxd5 {
+		t.Errorf("Discouraged move worth a queen not played, got %v", move)
+	}
+	eng.AvoidMoves(nil, []board.Move{rxd5}, 2000)
+	if move := search(); move == rxd5 {
+		t.Error("Discouraged move played despite the penalty")
+	}
+	eng.AvoidMoves(nil, nil, 0)
+	if move := search(); move != rxd5 {
+		t.Errorf("Best move after clearing %v, want %v", move, rxd5)
+	}
+
+	// The only legal move is played even when forbidden
+	only, err := board.ParseFEN("k7/8/8/8/8/8/1q6/K7 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kxb2 := board.NewMove(board.A1, board.B2)
+	eng.AvoidMoves([]board.Move{kxb2}, nil, 0)
+	if move := eng.SearchWithLimits(only, SearchLimits{Depth: 3}); move != kxb2 {
+		t.Errorf("Only legal move: got %v, want %v", move, kxb2)
+	}
+}
+
+// TestWriteEvalSource verifies that the tuner output is valid Go with the current weights.
+func TestWriteEvalSource(t *testing.T) {
+	orig := bishopPairMgBonus
+	defer func() { bishopPairMgBonus = orig }()
+
+	var found bool
+	for _, w := range EvalWeights() {
+		if w.Name == "bishopPairMgBonus" {
+			*w.Values[0] = 37
+			found = true
+		}
+	}
+	if !found || bishopPairMgBonus != 37 {
+		t.Fatalf("bishopPairMgBonus weight not writable")
+	}
+
+	var sb strings.Builder
+	if err := WriteEvalSource(&sb, "test"); err != nil {
+		t.Fatalf("WriteEvalSource failed: %v", err)
+	}
+	src := sb.String()
+	if _, err := parser.ParseFile(token.NewFileSet(), "eval_tuned.go", src, 0); err != nil {
+		t.Fatalf("Generated source does not parse: %v", err)
+	}
+	if !strings.Contains(src, "bishopPairMgBonus = 37") {
+		t.Errorf("Generated source is missing the changed weight")
+	}
+}
+
+func TestBench(t *testing.T) {
+	eng := NewEngine(16)
+
+	first := eng.Bench(5, nil)
+	if first.Positions != len(BenchPositions) {
+		t.Fatalf("Searched %d positions, want %d", first.Positions, len(BenchPositions))
+	}
+	if first.Nodes == 0 {
+		t.Fatalf("Bench searched no nodes")
+	}
+
+	// The signature must not depend on previous runs
+	second := eng.Bench(5, nil)
+	t.Logf("Bench depth 5: %d nodes, %v, %d nps", first.Nodes, first.Time, first.NPS())
+	if second.Nodes != first.Nodes {
+		t.Errorf("Bench is not reproducible: %d nodes, then %d", first.Nodes, second.Nodes)
+	}
+}
+
+func TestShuffleDamp(t *testing.T) {
+	tests := []struct {
+		eval, clock, want int
+	}{
+		{300, 0, 300},
+		{300, shuffleStart, 300},
+		{300, shuffleStart + shuffleRange/2, 150},
+		{-300, shuffleStart + shuffleRange/2, -150},
+		{300, shuffleStart + shuffleRange, 0},
+		{300, 150, 0},
+	}
+	for _, tt := range tests {
+		if got := shuffleDamp(tt.eval, tt.clock); got != tt.want {
+			t.Errorf("shuffleDamp(%d, %d) = %d, want %d", tt.eval, tt.clock, got, tt.want)
+		}
+	}
+
+	// A won position with a long-running clock must still be searched sanely
+	pos, err := board.ParseFEN("8/8/4k3/8/8/3QK3/8/8 w - - 90 120")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The classical evaluation is faded; NNUE scales by the clock itself
+	w := newEngine(1, 1).workers[0]
+	w.InitSearch(pos)
+	if got, want := w.evaluate(), shuffleDamp(EvaluateWithPawnTable(pos, w.pawnTable), 90); got != want {
+		t.Errorf("Classical eval at clock 90 = %d, want %d", got, want)
+	}
+
+	eng := NewEngine(16)
+	move := eng.SearchWithLimits(pos, SearchLimits{Depth: 6})
+	if move == board.NoMove {
+		t.Errorf("No move found")
+	}
+}
+
+// TestRule50SearchScore verifies that the search score of a won position
+// shrinks toward zero as the halfmove clock nears 100.
+func TestRule50SearchScore(t *testing.T) {
+	score := func(clock int) int {
+		pos, err := board.ParseFEN(fmt.Sprintf("8/8/4k3/8/8/3QK3/8/8 w - - %d 120", clock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		eng := NewEngine(16)
+		eng.SearchWithLimits(pos, SearchLimits{Depth: 8})
+		return eng.LastSearchInfo().Score
+	}
+
+	fresh, late, last := score(0), score(90), score(98)
+	if fresh < 500 || late >= fresh/2 || last < -20 || last > 20 {
+		t.Errorf("Scores at clock 0, 90, 98: %d, %d, %d", fresh, late, last)
+	}
+}
+
+func TestEndgameScale(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want int
+	}{
+		{"R+B vs R", "8/8/4k3/8/2r5/8/3BK3/5R2 w - - 0 1", 14},
+		{"R vs B", "8/8/4k3/8/2b5/8/4K3/5R2 w - - 0 1", 4},
+		{"B vs nothing", "8/8/4k3/8/8/8/3BK3/8 w - - 0 1", scaleFactorDraw},
+		{"N+N vs K", "8/8/4k3/8/8/8/2NNK3/8 w - - 0 1", scaleFactorDraw},
+		{"Opposite bishops", "8/4kp2/2b3p1/8/3B4/5PP1/5PK1/8 w - - 0 1", 18},
+		{"One pawn, minor up", "8/8/4k3/8/8/4P3/3BK3/8 w - - 0 1", scaleFactorOnePawn},
+		{"Queen vs rook", "8/8/4k3/8/2r5/8/4K3/5Q2 w - - 0 1", scaleFactorNormal},
+		{"Pawn endgame", "8/5pk1/6p1/8/8/6P1/5PPK/8 w - - 0 1", scaleFactorNormal},
+	}
+	for _, tt := range tests {
+		pos, err := board.ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		// The side ahead in the endgame term is White in every case
+		if got := endgameScale(pos, 1); got != tt.want {
+			t.Errorf("%s: scale %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// A drawn extra piece is no longer worth a piece (only the middlegame part remains)
+	pos, _ := board.ParseFEN("8/8/4k3/8/2r5/8/3BK3/5R2 w - - 0 1")
+	if eval := Evaluate(pos); eval > BishopValue/2 {
+		t.Errorf("R+B vs R evaluates to %d", eval)
+	}
+}
+
+func TestBookLimits(t *testing.T) {
+	// One-entry book: e2e4 from the starting position, and e7e5 after it
+	start := board.NewPosition()
+	afterE4 := start.Copy()
+	afterE4.MakeMove(board.NewMove(board.E2, board.E4))
+
+	var buf bytes.Buffer
+	for _, e := range []struct {
+		key  uint64
+		move uint16
+	}{
+		{start.PolyglotHash(), 4 | 3<<3 | 4<<6 | 1<<9},   // e2e4
+		{afterE4.PolyglotHash(), 4 | 4<<3 | 4<<6 | 6<<9}, // e7e5
+	} {
+		binary.Write(&buf, binary.BigEndian, e.key)
+		binary.Write(&buf, binary.BigEndian, e.move)
+		binary.Write(&buf, binary.BigEndian, uint16(10))
+		binary.Write(&buf, binary.BigEndian, uint32(0))
+	}
+	b, err := book.LoadPolyglotReader(&buf)
+	if err != nil {
+		t.Fatalf("Failed to load book: %v", err)
+	}
+
+	eng := NewEngine(16)
+	exits := 0
+	eng.OnBookExit = func() { exits++ }
+	eng.SetBook(b)
+	eng.SetBookMaxPly(1)
+
+	if move, ok := eng.probeBook(start); !ok || move.From() != board.E2 || move.To() != board.E4 {
+		t.Errorf("Expected e2e4 from the book at ply 0, got %s (%v)", move, ok)
+	}
+	if !eng.InBook() || exits != 0 {
+		t.Errorf("Engine left the book early: InBook %v, exits %d", eng.InBook(), exits)
+	}
+
+	// e7e5 is in the book but beyond the maximum ply
+	if _, ok := eng.probeBook(afterE4); ok {
+		t.Error("Expected no book move beyond the maximum ply")
+	}
+	eng.probeBook(afterE4)
+	if eng.InBook() || exits != 1 {
+		t.Errorf("Expected one book exit, got InBook %v, exits %d", eng.InBook(), exits)
+	}
+
+	// A new game starts in the book again
+	eng.Clear()
+	eng.SetBookMaxPly(0)
+	if _, ok := eng.probeBook(afterE4); !ok || !eng.InBook() {
+		t.Error("Expected e7e5 from the book without a ply limit")
+	}
+
+	// Past the opening phase the book is left when limited to the opening
+	late := afterE4.Copy()
+	late.FullMoveNumber = 30
+	if _, ok := eng.probeBook(late); !ok {
+		t.Error("Expected e7e5 from the book without a phase limit")
+	}
+	eng.SetBookOpeningOnly(true)
+	if _, ok := eng.probeBook(late); ok {
+		t.Error("Expected no book move past the opening")
+	}
+	if _, ok := eng.probeBook(afterE4); !ok {
+		t.Error("Expected e7e5 from the book in the opening")
+	}
+
+	// Entries below the minimum weight are ignored
+	eng.SetBookMinWeight(20)
+	if _, ok := eng.probeBook(afterE4); ok {
+		t.Error("Expected no book move below the minimum weight")
+	}
+}
+
+func TestCoachNotes(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		move string
+		want []string // Notes that must be present
+	}{
+		{"threat", "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2", "g1f3",
+			[]string{"Threatens the pawn on e5."}},
+		{"capture into a fork", "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", "f3e5",
+			[]string{"Captures a pawn.", "White is ahead in material (+1.0).", "Leaves the knight on e5 undefended."}},
+		{"check", "r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4", "c4f7",
+			[]string{"Captures a pawn.", "Gives check."}},
+		{"mate", "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", "a1a8",
+			[]string{"Checkmate!"}},
+		{"escape", "rnbqkbnr/pppp1ppp/8/4p3/3N4/8/PPPPPPPP/RNBQKB1R w KQkq - 0 3", "d4f3",
+			[]string{"Moves the knight out of danger."}},
+		{"queen trade", "r1bQk2r/ppp2ppp/8/8/8/8/PPP2PPP/R1B1K2R b KQkq - 0 20", "e8d8",
+			[]string{"Captures a queen.", "The game enters the endgame."}},
+	}
+
+	for _, tt := range tests {
+		pos, err := board.ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		pos.UpdateCheckers()
+		move, err := board.ParseMove(tt.move, pos)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		hash := pos.Hash
+
+		notes := CoachNotes(pos, move)
+		if pos.Hash != hash {
+			t.Errorf("%s: CoachNotes modified the position", tt.name)
+		}
+		for _, want := range tt.want {
+			found := false
+			for _, note := range notes {
+				found = found || note == want
+			}
+			if !found {
+				t.Errorf("%s: missing note %q in %q", tt.name, want, notes)
+			}
+		}
+	}
+
+	// The knight on d4 is attacked by the e5 pawn
+	pos, _ := board.ParseFEN("rnbqkbnr/pppp1ppp/8/4p3/3N4/8/PPPPPPPP/RNBQKB1R w KQkq - 0 3")
+	pos.UpdateCheckers()
+	if threats := FindThreats(pos, board.White); !threats.ByPawn.IsSet(board.D4) {
+		t.Errorf("Expected the knight on d4 to be threatened by a pawn, got %v", threats)
+	}
+}
+
+func TestDownloadNNUE(t *testing.T) {
+	content := bytes.Repeat([]byte("network "), 20000)
+	sum := sha256.Sum256(content)
+	name := "nn-" + hex.EncodeToString(sum[:])[:12] + ".nnue"
+
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if strings.HasPrefix(r.URL.Path, "/bad") {
+			w.Write([]byte("not the network"))
+			return
+		}
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	// A partial download is resumed from where it stopped
+	dir := t.TempDir()
+	path := filepath.Join(dir, name)
+	os.WriteFile(path+".tmp", content[:1000], 0644)
+	var last NNUEDownloadProgress
+	err := DownloadNNUE(context.Background(), dir, []NNUENet{{Name: name, URL: srv.URL + "/net"}},
+		func(p NNUEDownloadProgress) { last = p })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, content) {
+		t.Errorf("downloaded %d bytes, want %d", len(got), len(content))
+	}
+	if ranges[0] != "bytes=1000-" {
+		t.Errorf("Range = %q, want a resumed download", ranges[0])
+	}
+	if last.Received != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Errorf("final progress %+v", last)
+	}
+
+	// Present networks are not downloaded again
+	ranges = nil
+	if err := DownloadNNUE(context.Background(), dir, []NNUENet{{Name: name, URL: srv.URL + "/net"}}, nil); err != nil || len(ranges) != 0 {
+		t.Errorf("re-download: %v, %d requests", err, len(ranges))
+	}
+
+	// A network that does not match its name is rejected and removed
+	dir = t.TempDir()
+	err = DownloadNNUE(context.Background(), dir, []NNUENet{{Name: name, URL: srv.URL + "/bad"}}, nil)
+	if !errors.Is(err, ErrNNUEChecksum) {
+		t.Errorf("expected ErrNNUEChecksum, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("rejected download left %d files", len(entries))
+	}
+}
+
+// TestPerftWithTable runs the perft suite twice through one shared table,
+// as "perft suite hashed" does, and checks every count.
+func TestPerftWithTable(t *testing.T) {
+	const maxDepth = 4
+	eng := NewEngine(16)
+	table := board.NewPerftTable(1) // Small, so runs evict each other's entries
+	for run := range 2 {
+		for _, c := range board.PerftSuite {
+			pos, err := board.ParseFEN(c.FEN)
+			if err != nil {
+				t.Fatalf("%s: failed to parse FEN: %v", c.Name, err)
+			}
+			for d := 3; d <= maxDepth && d <= len(c.Counts); d++ {
+				if got := eng.PerftWithTable(pos, d, table); got != c.Counts[d-1] {
+					t.Errorf("run %d, %s: perft(%d) = %d, want %d", run+1, c.Name, d, got, c.Counts[d-1])
+				}
+			}
+		}
+	}
+}
+
+// TestEvaluateBatch verifies that batched evaluations over several workers
+// match evaluating each position alone, classically and with NNUE.
+func TestEvaluateBatch(t *testing.T) {
+	pos := board.NewPosition()
+	positions := []*board.Position{pos.Copy()}
+	for _, s := range []string{"e2e4", "c7c5", "g1f3", "d7d6", "d2d4", "c5d4", "f3d4", "g8f6", "b1c3", "a7a6"} {
+		m, err := board.ParseMove(s, pos)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		pos.MakeMove(m)
+		pos.UpdateCheckers()
+		positions = append(positions, pos.Copy())
+	}
+
+	eng, single := newEngine(1, 4), newEngine(1, 1)
+	check := func(mode string) {
+		for i, eval := range eng.EvaluateBatch(positions) {
+			if want := single.EvaluateBatch([]*board.Position{positions[i]})[0]; eval != want {
+				t.Errorf("%s position %d: batch %d, alone %d", mode, i, eval, want)
+			}
+		}
+	}
+	check("Classical")
+	if got := eng.EvaluateBatch(nil); len(got) != 0 {
+		t.Errorf("Empty batch returned %v", got)
+	}
+
+	if err := eng.LoadNNUE("", ""); err != nil {
+		t.Skipf("No embedded network: %v", err)
+	}
+	single.LoadNNUE("", "")
+	eng.SetUseNNUE(true)
+	single.SetUseNNUE(true)
+	check("NNUE")
+}
+
+// TestLoadNNUEErrors verifies that network load errors wrap ErrNetworkLoad
+// and their cause.
+func TestLoadNNUEErrors(t *testing.T) {
+	eng := newEngine(1, 1)
+	dir := t.TempDir()
+	err := eng.LoadNNUE(filepath.Join(dir, "big.nnue"), filepath.Join(dir, "small.nnue"))
+	if !errors.Is(err, ErrNetworkLoad) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Missing files: got %v, want ErrNetworkLoad wrapping os.ErrNotExist", err)
+	}
+	if err := eng.LoadSmallNNUEData([]byte("not a network"), "bad.nnue"); !errors.Is(err, ErrNetworkLoad) {
+		t.Errorf("Bad data: got %v, want ErrNetworkLoad", err)
+	}
+	if eng.HasNNUE() {
+		t.Error("Failed loads left a network loaded")
+	}
+}
+
+// TestContempt verifies that contempt scores draws against the root side,
+// including stalemates, and follows the root side's optimism.
+func TestContempt(t *testing.T) {
+	eng := newEngine(16, 1)
+	eng.SetContempt(1000)
+	if eng.Contempt() != maxContempt {
+		t.Errorf("Contempt %d, want it clamped to %d", eng.Contempt(), maxContempt)
+	}
+
+	pos, err := board.ParseFEN("k7/3Q4/1K6/8/8/8/8/8 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := eng.workers[0]
+	w.InitSearch(pos)
+	if score := w.drawScore(); score != 0 {
+		t.Errorf("Draw score %d without contempt", score)
+	}
+	w.contempt = 20
+	if score := w.drawScore(); score != -20 {
+		t.Errorf("Draw score %d for the root side, want -20", score)
+	}
+
+	// Qd7-c7 leaves Black without moves
+	undo := w.pos.MakeMove(board.NewMove(board.D7, board.C7))
+	if score := w.drawScore(); score != 20 {
+		t.Errorf("Draw score %d for the opponent, want 20", score)
+	}
+	if score := w.negamax(1, 1, -Infinity, Infinity, board.NoMove, board.NoMove, false, true); score != 20 {
+		t.Errorf("Stalemate scored %d, want 20", score)
+	}
+	w.pos.UnmakeMove(board.NewMove(board.D7, board.C7), undo)
+
+	w.avgScore = 300
+	w.UpdateOptimism()
+	if score := w.drawScore(); score >= -20 {
+		t.Errorf("Draw score %d while winning, want below -20", score)
+	}
+}
@@ -22,7 +22,7 @@ var pieceValues = [7]int{PawnValue, KnightValue, BishopValue, RookValue, QueenVa
 // Index 0 = rank 2, Index 6 = rank 8 (about to promote)
 var passedPawnBonus = [8]int{0, 10, 20, 40, 70, 120, 200, 0}
 
-const (
+var (
 	passedPawnConnectedBonus = 20 // Connected passed pawns
 	passedPawnProtectedBonus = 15 // Protected by own pawn
 	passedPawnFreePathBonus  = 30 // No blockers in front
@@ -43,13 +43,13 @@ const (
 )
 
 // Bishop pair bonus (having two bishops)
-const (
+var (
 	bishopPairMgBonus = 25
 	bishopPairEgBonus = 50
 )
 
 // Rook on open/semi-open file bonuses
-const (
+var (
 	rookOpenFileMg     = 20
 	rookOpenFileEg     = 25
 	rookSemiOpenFileMg = 10
@@ -57,7 +57,7 @@ const (
 )
 
 // Pawn structure penalties
-const (
+var (
 	doubledPawnMgPenalty  = -15
 	doubledPawnEgPenalty  = -20
 	isolatedPawnMgPenalty = -20
@@ -67,7 +67,7 @@ const (
 )
 
 // Outpost bonuses
-const (
+var (
 	knightOutpostMg          = 25
 	knightOutpostEg          = 15
 	knightOutpostProtectedMg = 15
@@ -372,6 +372,9 @@ func Evaluate(pos *board.Position) int {
 	mgScore += tpMg
 	egScore += tpEg
 
+	// Scale down the endgame score in drawish material configurations
+	egScore = egScore * endgameScale(pos, egScore) / scaleFactorNormal
+
 	// Tapered evaluation (interpolate between middlegame and endgame)
 	// Maximum phase = 2*4 + 2*1 + 2*1 + 2*2 = 16 per side = 32 total
 	const maxPhase = 24
@@ -468,6 +471,8 @@ func EvaluateWithPawnTable(pos *board.Position, pawnTable *PawnTable) int {
 	mgScore += thrMg
 	egScore += thrEg
 
+	egScore = egScore * endgameScale(pos, egScore) / scaleFactorNormal
+
 	const maxPhase = 24
 	if phase > maxPhase {
 		phase = maxPhase
@@ -1773,3 +1778,88 @@ func evaluateTrappedPieces(pos *board.Position) (mgPenalty, egPenalty int) {
 
 	return mgPenalty, egPenalty
 }
+
+// Endgame scale factors (Stockfish ScaleFactor): the endgame score is
+// multiplied by scale/scaleFactorNormal before tapering
+const (
+	scaleFactorDraw    = 0
+	scaleFactorOnePawn = 48
+	scaleFactorNormal  = 64
+)
+
+// endgameScale returns the scale factor of the endgame score for drawish
+// material configurations (Stockfish evaluate.cpp scale_factor). egScore is
+// from White's perspective and decides which side is trying to win.
+func endgameScale(pos *board.Position, egScore int) int {
+	strong := board.White
+	if egScore < 0 {
+		strong = board.Black
+	}
+	weak := strong.Other()
+
+	npmStrong, npmWeak := sideNonPawnMaterial(pos, strong), sideNonPawnMaterial(pos, weak)
+	strongPawns := pos.Pieces[strong][board.Pawn].PopCount()
+
+	if strongPawns == 0 {
+		// Two knights cannot force mate against a bare king
+		if npmStrong == 2*KnightValue && pos.Pieces[strong][board.Knight].PopCount() == 2 && npmWeak == 0 {
+			return scaleFactorDraw
+		}
+		// Without pawns, a lead of at most a minor piece rarely wins (e.g. R+B vs R)
+		if npmStrong-npmWeak <= BishopValue {
+			switch {
+			case npmStrong < RookValue:
+				return scaleFactorDraw
+			case npmWeak <= BishopValue:
+				return 4
+			default:
+				return 14
+			}
+		}
+	}
+
+	// Opposite-colored bishops: pure bishop endgames are very drawish
+	if oppositeBishops(pos) {
+		if npmStrong == BishopValue && npmWeak == BishopValue {
+			passed := 0
+			for bb := pos.Pieces[strong][board.Pawn]; bb != 0; {
+				if isPassedPawn(pos, bb.PopLSB(), strong) {
+					passed++
+				}
+			}
+			return min(scaleFactorNormal, 18+4*passed)
+		}
+		return min(scaleFactorNormal, 22+3*pos.Occupied[strong].PopCount())
+	}
+
+	// A single pawn with at most a minor piece ahead can often be sacrificed for
+	if strongPawns == 1 && npmStrong-npmWeak <= BishopValue {
+		return scaleFactorOnePawn
+	}
+
+	return scaleFactorNormal
+}
+
+// sideNonPawnMaterial returns the value of one side's pieces other than pawns and king.
+func sideNonPawnMaterial(pos *board.Position, c board.Color) int {
+	total := 0
+	for pt := board.Knight; pt <= board.Queen; pt++ {
+		total += pos.Pieces[c][pt].PopCount() * pieceValues[pt]
+	}
+	return total
+}
+
+// oppositeBishops returns true if each side has exactly one bishop and they
+// move on squares of different colors.
+func oppositeBishops(pos *board.Position) bool {
+	wb, bb := pos.Pieces[board.White][board.Bishop], pos.Pieces[board.Black][board.Bishop]
+	if wb.PopCount() != 1 || bb.PopCount() != 1 {
+		return false
+	}
+	return squareColor(wb.LSB()) != squareColor(bb.LSB())
+}
+
+// squareColor returns 0 for dark squares and 1 for light squares.
+func squareColor(sq board.Square) int {
+	return (sq.File() + sq.Rank()) & 1
+}
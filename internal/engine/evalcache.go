@@ -0,0 +1,69 @@
+package engine
+
+import "sync/atomic"
+
+// evalCacheEntries is the size of the engine's evaluation cache (8 MB).
+const evalCacheEntries = 1 << 19
+
+// evalValidBit marks stored entry data, so an empty slot never verifies.
+const evalValidBit = 1 << 32
+
+// evalCacheEntry is one slot of the evaluation cache. As in the
+// transposition table the key is stored XOR the data, so a slot torn by
+// concurrent writers fails verification instead of answering for the wrong
+// position.
+type evalCacheEntry struct {
+	keyData atomic.Uint64
+	data    atomic.Uint64 // Valid bit | evaluation as uint32
+}
+
+// EvalCache is a small, lock-free cache of static evaluations keyed by
+// position hash, shared by all search workers. It holds the evaluation of
+// the position alone: what depends on the search (optimism) or on the
+// halfmove clock, which the hash leaves out, is applied after the probe.
+// Slots are always replaced.
+type EvalCache struct {
+	entries []evalCacheEntry
+	mask    uint64
+}
+
+// NewEvalCache creates a cache of the given number of entries, rounded down
+// to a power of two.
+func NewEvalCache(entries int) *EvalCache {
+	size := uint64(1)
+	for size*2 <= uint64(entries) {
+		size *= 2
+	}
+	return &EvalCache{
+		entries: make([]evalCacheEntry, size),
+		mask:    size - 1,
+	}
+}
+
+// Probe returns the cached evaluation of the position with the given hash.
+func (c *EvalCache) Probe(hash uint64) (int, bool) {
+	entry := &c.entries[hash&c.mask]
+	keyData := entry.keyData.Load()
+	data := entry.data.Load()
+	if data&evalValidBit == 0 || keyData^data != hash {
+		return 0, false
+	}
+	return int(int32(uint32(data))), true
+}
+
+// Store caches the evaluation of the position with the given hash.
+func (c *EvalCache) Store(hash uint64, eval int) {
+	entry := &c.entries[hash&c.mask]
+	data := evalValidBit | uint64(uint32(int32(eval)))
+	entry.keyData.Store(hash ^ data)
+	entry.data.Store(data)
+}
+
+// Clear empties the cache, when the evaluation changes. It must not race
+// with Probe or Store.
+func (c *EvalCache) Clear() {
+	for i := range c.entries {
+		c.entries[i].keyData.Store(0)
+		c.entries[i].data.Store(0)
+	}
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// EvalWeight is a classical evaluation weight (a scalar or a table) for offline
+// tuning with cmd/chessplay-tune. Values point at the live variables, so
+// writing through them changes Evaluate immediately.
+// Weights are shared by all engines in the process; change them only between searches.
+type EvalWeight struct {
+	Name   string // Go expression of the variable, used when writing source
+	Table  bool   // Array variable (written as a composite literal) rather than a scalar
+	Values []*int
+}
+
+// evalWeights lists the tunable classical evaluation weights.
+// Material values are excluded: they also drive SEE and move ordering.
+var evalWeights = []EvalWeight{
+	tableWeight("psts[board.Pawn]", psts[board.Pawn][:]),
+	tableWeight("psts[board.Knight]", psts[board.Knight][:]),
+	tableWeight("psts[board.Bishop]", psts[board.Bishop][:]),
+	tableWeight("psts[board.Rook]", psts[board.Rook][:]),
+	tableWeight("psts[board.Queen]", psts[board.Queen][:]),
+	tableWeight("kingMidgamePST", kingMidgamePST[:]),
+	tableWeight("kingEndgamePST", kingEndgamePST[:]),
+
+	tableWeight("passedPawnBonus", passedPawnBonus[:]),
+	scalarWeight("passedPawnConnectedBonus", &passedPawnConnectedBonus),
+	scalarWeight("passedPawnProtectedBonus", &passedPawnProtectedBonus),
+	scalarWeight("passedPawnFreePathBonus", &passedPawnFreePathBonus),
+	tableWeight("kingDistanceBonus", kingDistanceBonus[:]),
+
+	tableWeight("mobilityMgWeight", mobilityMgWeight[:]),
+	tableWeight("mobilityEgWeight", mobilityEgWeight[:]),
+	tableWeight("attackerWeight", attackerWeight[:]),
+	tableWeight("tropismWeight", tropismWeight[:]),
+
+	scalarWeight("bishopPairMgBonus", &bishopPairMgBonus),
+	scalarWeight("bishopPairEgBonus", &bishopPairEgBonus),
+	scalarWeight("rookOpenFileMg", &rookOpenFileMg),
+	scalarWeight("rookOpenFileEg", &rookOpenFileEg),
+	scalarWeight("rookSemiOpenFileMg", &rookSemiOpenFileMg),
+	scalarWeight("rookSemiOpenFileEg", &rookSemiOpenFileEg),
+
+	scalarWeight("doubledPawnMgPenalty", &doubledPawnMgPenalty),
+	scalarWeight("doubledPawnEgPenalty", &doubledPawnEgPenalty),
+	scalarWeight("isolatedPawnMgPenalty", &isolatedPawnMgPenalty),
+	scalarWeight("isolatedPawnEgPenalty", &isolatedPawnEgPenalty),
+	scalarWeight("backwardPawnMgPenalty", &backwardPawnMgPenalty),
+	scalarWeight("backwardPawnEgPenalty", &backwardPawnEgPenalty),
+
+	scalarWeight("knightOutpostMg", &knightOutpostMg),
+	scalarWeight("knightOutpostEg", &knightOutpostEg),
+	scalarWeight("knightOutpostProtectedMg", &knightOutpostProtectedMg),
+	scalarWeight("knightOutpostProtectedEg", &knightOutpostProtectedEg),
+	scalarWeight("bishopOutpostMg", &bishopOutpostMg),
+	scalarWeight("bishopOutpostEg", &bishopOutpostEg),
+}
+
+func tableWeight(name string, values []int) EvalWeight {
+	w := EvalWeight{Name: name, Table: true, Values: make([]*int, len(values))}
+	for i := range values {
+		w.Values[i] = &values[i]
+	}
+	return w
+}
+
+func scalarWeight(name string, v *int) EvalWeight {
+	return EvalWeight{Name: name, Values: []*int{v}}
+}
+
+// EvalWeights returns the tunable classical evaluation weights.
+func EvalWeights() []EvalWeight {
+	return evalWeights
+}
+
+// WriteEvalSource writes the current evaluation weights as a Go source file
+// for this package. Its init function overrides the defaults in eval.go, so
+// tuned weights can be dropped in as internal/engine/eval_tuned.go.
+func WriteEvalSource(w io.Writer, comment string) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by chessplay-tune; DO NOT EDIT.\n")
+	for _, line := range strings.Split(strings.TrimSpace(comment), "\n") {
+		if line != "" {
+			fmt.Fprintf(&buf, "// %s\n", line)
+		}
+	}
+	buf.WriteString("\npackage engine\n\n")
+	buf.WriteString("import \"github.com/hailam/chessplay/internal/board\"\n\n")
+	buf.WriteString("func init() {\n")
+	for _, ew := range evalWeights {
+		if !ew.Table {
+			fmt.Fprintf(&buf, "%s = %d\n", ew.Name, *ew.Values[0])
+			continue
+		}
+		fmt.Fprintf(&buf, "%s = [%d]int{", ew.Name, len(ew.Values))
+		for i, v := range ew.Values {
+			if len(ew.Values) == 64 && i%8 == 0 {
+				buf.WriteString("\n")
+			}
+			fmt.Fprintf(&buf, "%d,", *v)
+			if len(ew.Values) != 64 {
+				buf.WriteString(" ")
+			}
+		}
+		if len(ew.Values) == 64 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("}\n")
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SearchFeature is a pruning, reduction or extension heuristic of the search
+// that can be switched off at runtime, to bisect regressions and run A/B
+// tests without rebuilding.
+type SearchFeature uint
+
+const (
+	FeatureProbcut SearchFeature = iota
+	FeatureRazoring
+	FeatureSingularExt
+	FeatureThreatExt
+	FeatureRFP
+	FeatureLMP
+	FeatureSEEPruning
+	FeatureHistoryPruning
+	FeatureFutilityPruning
+	FeatureHindsightDepth
+	FeatureNMP
+	FeatureCaptureLMR
+	FeatureShuffleDamp
+	numSearchFeatures
+)
+
+// searchFeatureNames are the names of the features, as in the UCI options
+var searchFeatureNames = [numSearchFeatures]string{
+	"Probcut", "Razoring", "SingularExt", "ThreatExt", "RFP", "LMP", "SEEPruning",
+	"HistoryPruning", "FutilityPruning", "HindsightDepth", "NMP", "CaptureLMR", "ShuffleDamp",
+}
+
+// String returns the name of the feature.
+func (f SearchFeature) String() string {
+	if f >= numSearchFeatures {
+		return fmt.Sprintf("SearchFeature(%d)", uint(f))
+	}
+	return searchFeatureNames[f]
+}
+
+// ParseSearchFeature returns the feature of the given name, ignoring case.
+func ParseSearchFeature(name string) (SearchFeature, bool) {
+	for f, n := range searchFeatureNames {
+		if strings.EqualFold(n, name) {
+			return SearchFeature(f), true
+		}
+	}
+	return 0, false
+}
+
+// SearchFeatureNames returns the names of all features, in order.
+func SearchFeatureNames() []string {
+	return searchFeatureNames[:]
+}
+
+// SearchFeatures is a set of enabled search features.
+type SearchFeatures uint32
+
+// Has returns true if the feature is enabled.
+func (s SearchFeatures) Has(f SearchFeature) bool {
+	return s&(1<<f) != 0
+}
+
+// With returns the set with the feature enabled or disabled.
+func (s SearchFeatures) With(f SearchFeature, on bool) SearchFeatures {
+	if on {
+		return s | 1<<f
+	}
+	return s &^ (1 << f)
+}
+
+// DefaultSearchFeatures are the features enabled in a new engine, as set by
+// the Enable* flags.
+var DefaultSearchFeatures = SearchFeatures(0).
+	With(FeatureProbcut, EnableProbcut).
+	With(FeatureRazoring, EnableRazoring).
+	With(FeatureSingularExt, EnableSingularExt).
+	With(FeatureThreatExt, EnableThreatExt).
+	With(FeatureRFP, EnableRFP).
+	With(FeatureLMP, EnableLMP).
+	With(FeatureSEEPruning, EnableSEEPruning).
+	With(FeatureHistoryPruning, EnableHistoryPruning).
+	With(FeatureFutilityPruning, EnableFutilityPruning).
+	With(FeatureHindsightDepth, EnableHindsightDepth).
+	With(FeatureNMP, EnableNMP).
+	With(FeatureCaptureLMR, EnableCaptureLMR).
+	With(FeatureShuffleDamp, EnableShuffleDamp)
+
+// SetSearchFeature enables or disables a search feature by name, ignoring
+// case. Workers pick up the change at the start of the next search; a
+// running search keeps the features it started with.
+func (e *Engine) SetSearchFeature(name string, on bool) error {
+	f, ok := ParseSearchFeature(name)
+	if !ok {
+		return fmt.Errorf("unknown search feature %q", name)
+	}
+	for {
+		old := e.features.Load()
+		if e.features.CompareAndSwap(old, uint32(SearchFeatures(old).With(f, on))) {
+			return nil
+		}
+	}
+}
+
+// SearchFeatures returns the search features enabled for the next search.
+func (e *Engine) SearchFeatures() SearchFeatures {
+	return SearchFeatures(e.features.Load())
+}
@@ -328,39 +328,31 @@ func (w *Worker) ensureAccumulatorComputed(net *sfnnue.Network, acc *sfnnue.Accu
 	}
 }
 
-// nnueEvaluate performs NNUE evaluation for the worker's position.
-// Uses dual-network evaluation for better accuracy (working approach from Jan 5).
-// Adds optimism tracking for Stockfish-style score adjustments.
-func (w *Worker) nnueEvaluate() int {
-	if w.nnueNet == nil || w.nnueAcc == nil {
-		return EvaluateWithPawnTable(w.pos, w.pawnTable)
+// updateAccumulators brings the accumulators of the worker's position up to
+// date. Evaluation does this, and so must an evaluation cache hit: a child
+// can only update incrementally from a computed parent.
+func (w *Worker) updateAccumulators() {
+	w.ensureAccumulatorComputed(w.nnueNet.Small, w.nnueAcc.CurrentSmall(), true)
+	if !w.nnueNet.SmallOnly() {
+		w.ensureAccumulatorComputed(w.nnueNet.Big, w.nnueAcc.CurrentBig(), false)
 	}
+}
 
+// nnueNetworkEvaluate returns the network output for the worker's position,
+// before nnueAdjust. Uses dual-network evaluation for better accuracy
+// (working approach from Jan 5).
+func (w *Worker) nnueNetworkEvaluate() int {
 	pieceCount := countPieces(w.pos)
 	sideToMove := 0
 	if w.pos.SideToMove == board.Black {
 		sideToMove = 1
 	}
 
-	// Get accumulators for both networks
-	bigAcc := w.nnueAcc.CurrentBig()
+	w.updateAccumulators()
 	smallAcc := w.nnueAcc.CurrentSmall()
 
-	// Ensure accumulators are computed for both networks
-	w.ensureAccumulatorComputed(w.nnueNet.Big, bigAcc, false)
-	w.ensureAccumulatorComputed(w.nnueNet.Small, smallAcc, true)
-
-	// Big network evaluation
-	bigPsqt, bigPositional := w.nnueNet.Big.Evaluate(
-		bigAcc.Accumulation,
-		bigAcc.PSQTAccumulation,
-		sideToMove,
-		pieceCount,
-		w.nnueAcc.TransformBuffer[:],
-	)
-
-	// Small network evaluation (PSQT only - used for averaging)
-	smallPsqt, _ := w.nnueNet.Small.Evaluate(
+	// Small network evaluation (PSQT only unless it is the only network)
+	smallPsqt, smallPositional := w.nnueNet.Small.Evaluate(
 		smallAcc.Accumulation,
 		smallAcc.PSQTAccumulation,
 		sideToMove,
@@ -368,9 +360,37 @@ func (w *Worker) nnueEvaluate() int {
 		w.nnueAcc.TransformBuffer[:],
 	)
 
-	// Combine: use big network's positional + averaged PSQT from both networks
-	// This is the working approach from Jan 5 that beat Stockfish level 3
-	score := int(bigPositional) + int(smallPsqt+bigPsqt)/2
+	var score int
+	if w.nnueNet.SmallOnly() {
+		// Small network alone, weighted as in Stockfish evaluate.cpp
+		score = (125*int(smallPsqt) + 131*int(smallPositional)) / 128
+	} else {
+		bigAcc := w.nnueAcc.CurrentBig()
+
+		// Big network evaluation
+		bigPsqt, bigPositional := w.nnueNet.Big.Evaluate(
+			bigAcc.Accumulation,
+			bigAcc.PSQTAccumulation,
+			sideToMove,
+			pieceCount,
+			w.nnueAcc.TransformBuffer[:],
+		)
+
+		// Combine: use big network's positional + averaged PSQT from both networks
+		// This is the working approach from Jan 5 that beat Stockfish level 3
+		score = int(bigPositional) + int(smallPsqt+bigPsqt)/2
+	}
+	return score
+}
+
+// nnueAdjust turns the network output for the worker's position into its
+// evaluation: optimism for the side to move is added, scaled by material,
+// and the score shrinks as the halfmove clock runs.
+func (w *Worker) nnueAdjust(score int) int {
+	sideToMove := 0
+	if w.pos.SideToMove == board.Black {
+		sideToMove = 1
+	}
 
 	// Get optimism for side to move (Stockfish evaluate.cpp)
 	optimism := w.optimism[sideToMove]
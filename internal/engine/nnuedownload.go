@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NNUENet is a network file that can be downloaded.
+type NNUENet struct {
+	Name string // File name, nn-<first 12 hex digits of the SHA-256>.nnue
+	URL  string
+	Size int64 // Expected size in bytes, for progress before the server reports one
+}
+
+// The default networks, from the Stockfish network server
+var (
+	SmallNNUENet = NNUENet{
+		Name: "nn-37f18f62d772.nnue",
+		URL:  "https://tests.stockfishchess.org/api/nn/nn-37f18f62d772.nnue",
+		Size: 3674624, // ~3.5 MB
+	}
+	BigNNUENet = NNUENet{
+		Name: "nn-c288c895ea92.nnue",
+		URL:  "https://tests.stockfishchess.org/api/nn/nn-c288c895ea92.nnue",
+		Size: 113246144, // ~108 MB
+	}
+)
+
+// DefaultNNUENets lists the networks DownloadNNUE fetches by default, small first.
+var DefaultNNUENets = []NNUENet{SmallNNUENet, BigNNUENet}
+
+// ErrNNUEChecksum is returned when a downloaded network does not match the
+// hash in its name.
+var ErrNNUEChecksum = errors.New("network checksum mismatch")
+
+// NNUEDownloadProgress reports the progress of DownloadNNUE.
+type NNUEDownloadProgress struct {
+	Name     string // Network being downloaded
+	File     int    // 1-based number of the network
+	Files    int
+	Received int64 // Bytes of this network, including resumed ones
+	Total    int64
+}
+
+// DownloadNNUE downloads networks into dir, skipping those already present.
+// Interrupted downloads are kept as .tmp files (which ScanNNUE ignores) and
+// resumed, and each network is checked against the hash in its name before
+// it is renamed into place. progress may be nil; it is called from the
+// downloading goroutine.
+func DownloadNNUE(ctx context.Context, dir string, nets []NNUENet, progress func(NNUEDownloadProgress)) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, net := range nets {
+		p := NNUEDownloadProgress{Name: net.Name, File: i + 1, Files: len(nets), Total: net.Size}
+		if err := downloadNNUEFile(ctx, filepath.Join(dir, net.Name), net.URL, p, progress); err != nil {
+			return fmt.Errorf("%s: %w", net.Name, err)
+		}
+	}
+	return nil
+}
+
+// downloadNNUEFile downloads one network, resuming a partial download.
+func downloadNNUEFile(ctx context.Context, path, url string, p NNUEDownloadProgress, progress func(NNUEDownloadProgress)) error {
+	report := func() {
+		if progress != nil {
+			progress(p)
+		}
+	}
+	if info, err := os.Stat(path); err == nil {
+		p.Received, p.Total = info.Size(), info.Size()
+		report()
+		return nil
+	}
+
+	tmpPath := path + ".tmp"
+	if info, err := os.Stat(tmpPath); err == nil {
+		p.Received = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if p.Received > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", p.Received))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && p.Received > 0:
+		flags |= os.O_APPEND
+		if resp.ContentLength > 0 {
+			p.Total = p.Received + resp.ContentLength
+		}
+	case resp.StatusCode == http.StatusOK:
+		// The server ignored the range, so start over
+		flags |= os.O_TRUNC
+		p.Received = 0
+		if resp.ContentLength > 0 {
+			p.Total = resp.ContentLength
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already complete (or corrupt, which the
+		// checksum catches)
+		return finishNNUEFile(tmpPath, path)
+	default:
+		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	out, err := os.OpenFile(tmpPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	report()
+	buf := make([]byte, 64*1024)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := out.Write(buf[:n]); err != nil {
+				out.Close()
+				return err
+			}
+			p.Received += int64(n)
+			report()
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			// Keep the partial file to resume from
+			out.Close()
+			return readErr
+		}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return finishNNUEFile(tmpPath, path)
+}
+
+// finishNNUEFile verifies a completed download and renames it into place.
+// A file that fails verification is removed so the next attempt starts over.
+func finishNNUEFile(tmpPath, path string) error {
+	if err := VerifyNNUEChecksum(tmpPath, filepath.Base(path)); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// VerifyNNUEChecksum checks a network file against the SHA-256 prefix in
+// its Stockfish-style name (nn-<12 hex digits>.nnue). Files with other
+// names cannot be verified and are accepted.
+func VerifyNNUEChecksum(path, name string) error {
+	want, ok := strings.CutPrefix(strings.TrimSuffix(name, ".nnue"), "nn-")
+	if !ok || len(want) != 12 {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil))[:12]; got != want {
+		return fmt.Errorf("%w: sha256 %s..., want %s...", ErrNNUEChecksum, got, want)
+	}
+	return nil
+}
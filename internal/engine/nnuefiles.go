@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hailam/chessplay/sfnnue"
+)
+
+// NNUEFile is a network file found by ScanNNUE.
+type NNUEFile struct {
+	Path        string
+	Name        string // Base name of the file
+	Big         bool   // Big network architecture (false = small)
+	Description string // Description from the file header
+	Modified    time.Time
+}
+
+// ScanNNUE lists the valid .nnue files in the given directories, newest first.
+// Only the headers are read, so scanning is cheap enough to repeat whenever a
+// new game starts. Files with an unknown architecture are skipped, and a file
+// found in several directories is listed once.
+func ScanNNUE(dirs ...string) []NNUEFile {
+	var files []NNUEFile
+	seen := make(map[string]bool)
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".nnue") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if abs, err := filepath.Abs(path); err == nil {
+				if seen[abs] {
+					continue
+				}
+				seen[abs] = true
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			header, err := sfnnue.InspectNetwork(path)
+			if err != nil {
+				continue
+			}
+			files = append(files, NNUEFile{
+				Path:        path,
+				Name:        entry.Name(),
+				Big:         header.IsBig,
+				Description: header.Description,
+				Modified:    info.ModTime(),
+			})
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Modified.After(files[j].Modified)
+	})
+	return files
+}
+
+// NewestNNUE returns the newest big and small networks of a scan
+// (empty if the scan has none of that kind).
+func NewestNNUE(files []NNUEFile) (big, small string) {
+	for _, f := range files {
+		if f.Big && big == "" {
+			big = f.Path
+		}
+		if !f.Big && small == "" {
+			small = f.Path
+		}
+	}
+	return big, small
+}
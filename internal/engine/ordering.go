@@ -23,19 +23,15 @@ func (sh *SharedHistory) Get(from, to int) int {
 	return int(sh.history[from*64+to].Load())
 }
 
-// Update atomically updates the history score for a move.
+// Update atomically updates the history score for a move with the gravity
+// formula (see applyGravity).
 func (sh *SharedHistory) Update(from, to, bonus int) {
 	idx := from*64 + to
 	for {
 		old := sh.history[idx].Load()
-		newVal := old + int32(bonus)
-
-		// Clamp to prevent overflow
-		if newVal > 400000 {
-			newVal = 400000
-		} else if newVal < -400000 {
-			newVal = -400000
-		}
+		v := int(old)
+		applyGravity(&v, bonus)
+		newVal := int32(v)
 
 		if sh.history[idx].CompareAndSwap(old, newVal) {
 			break
@@ -58,6 +54,9 @@ const (
 	KillerScore1    = 900000   // First killer move
 	KillerScore2    = 800000   // Second killer move
 	BadCaptureBase  = -100000  // Losing captures
+	tbSimplifyBonus = 4000000  // Capture into a won tablebase ending (subtracted when lost)
+	rootEffortScale = 2000000  // Root move that took every node searched so far (see orderRootMoves)
+	EvasionKingBase = 700000   // King moves out of check, above blocks ordered by history
 )
 
 // MVV-LVA (Most Valuable Victim - Least Valuable Attacker) scores
@@ -236,6 +235,44 @@ func (mo *MoveOrderer) ScoreMovesWithCounter(pos *board.Position, moves *board.M
 	return scores
 }
 
+// ScoreEvasions assigns scores to the moves out of check: captures of the
+// checker by MVV-LVA first, then king moves by the safety of the square,
+// then blocks by history. Killers, often the same evasion in a sibling
+// position, keep their place after the captures; counter moves, answers to
+// moves that did not give check, are left out.
+// Returns a slice backed by the internal scoreBuffer (no allocation).
+func (mo *MoveOrderer) ScoreEvasions(pos *board.Position, moves *board.MoveList, ply int, ttMove board.Move) []int {
+	n := moves.Len()
+	scores := mo.scoreBuffer[:n]
+	us := pos.SideToMove
+	king := pos.KingSquare[us]
+
+	for i := 0; i < n; i++ {
+		m := moves.Get(i)
+		switch {
+		case m == ttMove || m.IsCapture(pos) || m.IsPromotion() || m == mo.killers[ply][0] || m == mo.killers[ply][1]:
+			// As in any position
+			scores[i] = mo.scoreMove(pos, m, ply, ttMove)
+		case m.From() == king:
+			// Fewer attacked squares around the king's new square are safer
+			attacked := 0
+			for bb := board.KingAttacks(m.To()); bb != 0; {
+				if pos.IsSquareAttacked(bb.PopLSB(), us.Other()) {
+					attacked++
+				}
+			}
+			scores[i] = EvasionKingBase - attacked*10000 + mo.history[m.From()][m.To()]/64
+		default:
+			scores[i] = mo.history[m.From()][m.To()]
+			if ply < MaxLowPly {
+				scores[i] += mo.lowPlyHistory[ply][m.From()][m.To()] / 2
+			}
+		}
+	}
+
+	return scores
+}
+
 // scoreMove returns the ordering score for a single move.
 func (mo *MoveOrderer) scoreMove(pos *board.Position, m board.Move, ply int, ttMove board.Move) int {
 	// TT move gets highest priority
@@ -345,6 +382,26 @@ func PickMove(moves *board.MoveList, scores []int, index int) {
 	}
 }
 
+// historyMax bounds every history table. Updates use the gravity formula
+// (Stockfish history.h): an entry moves by the bonus scaled down the closer
+// it already is to the bound in that direction, so scores saturate at
+// +-historyMax instead of overflowing and recent results outweigh old ones.
+const historyMax = 400000
+
+// applyGravity adds bonus to a history entry using the gravity formula.
+func applyGravity(entry *int, bonus int) {
+	*entry += bonus - *entry*abs(bonus)/historyMax
+}
+
+// historyBonus returns bonus for a move that caused a cutoff, and the
+// matching penalty (malus) for one that did not.
+func historyBonus(bonus int, isGood bool) int {
+	if isGood {
+		return bonus
+	}
+	return -bonus
+}
+
 // UpdateKillers adds a killer move at the given ply.
 func (mo *MoveOrderer) UpdateKillers(m board.Move, ply int) {
 	// Don't store captures as killers
@@ -364,27 +421,7 @@ func (mo *MoveOrderer) UpdateKillers(m board.Move, ply int) {
 
 // UpdateHistory updates the history score for a move.
 func (mo *MoveOrderer) UpdateHistory(m board.Move, depth int, isGood bool) {
-	from := m.From()
-	to := m.To()
-
-	bonus := depth * depth
-	if isGood {
-		mo.history[from][to] += bonus
-		// Prevent overflow
-		if mo.history[from][to] > 400000 {
-			// Scale down all history scores
-			for i := range mo.history {
-				for j := range mo.history[i] {
-					mo.history[i][j] /= 2
-				}
-			}
-		}
-	} else {
-		mo.history[from][to] -= bonus
-		if mo.history[from][to] < -400000 {
-			mo.history[from][to] = -400000
-		}
-	}
+	applyGravity(&mo.history[m.From()][m.To()], historyBonus(depth*depth, isGood))
 }
 
 // UpdateLowPlyHistory updates the low-ply history for moves at plies 0-4.
@@ -393,32 +430,7 @@ func (mo *MoveOrderer) UpdateLowPlyHistory(m board.Move, ply, depth int, isGood
 	if ply >= MaxLowPly {
 		return
 	}
-
-	from := m.From()
-	to := m.To()
-	bonus := depth * depth
-
-	if isGood {
-		mo.lowPlyHistory[ply][from][to] += bonus
-		if mo.lowPlyHistory[ply][from][to] > 400000 {
-			mo.scaleLowPlyHistory()
-		}
-	} else {
-		mo.lowPlyHistory[ply][from][to] -= bonus
-		if mo.lowPlyHistory[ply][from][to] < -400000 {
-			mo.lowPlyHistory[ply][from][to] = -400000
-		}
-	}
-}
-
-func (mo *MoveOrderer) scaleLowPlyHistory() {
-	for p := range mo.lowPlyHistory {
-		for i := range mo.lowPlyHistory[p] {
-			for j := range mo.lowPlyHistory[p][i] {
-				mo.lowPlyHistory[p][i][j] /= 2
-			}
-		}
-	}
+	applyGravity(&mo.lowPlyHistory[ply][m.From()][m.To()], historyBonus(depth*depth, isGood))
 }
 
 // UpdateCounterMove updates the counter move table.
@@ -460,29 +472,7 @@ func (mo *MoveOrderer) UpdateCaptureHistory(attackerPiece board.Piece, toSq boar
 	if attackerPiece == board.NoPiece || capturedType >= board.King {
 		return
 	}
-
-	bonus := depth * depth
-	if isGood {
-		mo.captureHistory[attackerPiece][toSq][capturedType] += bonus
-		if mo.captureHistory[attackerPiece][toSq][capturedType] > 400000 {
-			mo.scaleCaptureHistory()
-		}
-	} else {
-		mo.captureHistory[attackerPiece][toSq][capturedType] -= bonus
-		if mo.captureHistory[attackerPiece][toSq][capturedType] < -400000 {
-			mo.captureHistory[attackerPiece][toSq][capturedType] = -400000
-		}
-	}
-}
-
-func (mo *MoveOrderer) scaleCaptureHistory() {
-	for i := range mo.captureHistory {
-		for j := range mo.captureHistory[i] {
-			for k := range mo.captureHistory[i][j] {
-				mo.captureHistory[i][j][k] /= 2
-			}
-		}
-	}
+	applyGravity(&mo.captureHistory[attackerPiece][toSq][capturedType], historyBonus(depth*depth, isGood))
 }
 
 // GetCaptureHistoryScore returns the capture history score for a capture move.
@@ -498,34 +488,7 @@ func (mo *MoveOrderer) UpdateCountermoveHistory(prevMove, goodMove board.Move, p
 	if prevMove == board.NoMove || prevPiece == board.NoPiece || movePiece == board.NoPiece {
 		return
 	}
-
-	prevTo := prevMove.To()
-	moveTo := goodMove.To()
-	bonus := depth * depth
-
-	if isGood {
-		mo.countermoveHistory[prevPiece][prevTo][movePiece][moveTo] += bonus
-		if mo.countermoveHistory[prevPiece][prevTo][movePiece][moveTo] > 400000 {
-			mo.scaleCountermoveHistory()
-		}
-	} else {
-		mo.countermoveHistory[prevPiece][prevTo][movePiece][moveTo] -= bonus
-		if mo.countermoveHistory[prevPiece][prevTo][movePiece][moveTo] < -400000 {
-			mo.countermoveHistory[prevPiece][prevTo][movePiece][moveTo] = -400000
-		}
-	}
-}
-
-func (mo *MoveOrderer) scaleCountermoveHistory() {
-	for i := range mo.countermoveHistory {
-		for j := range mo.countermoveHistory[i] {
-			for k := range mo.countermoveHistory[i][j] {
-				for l := range mo.countermoveHistory[i][j][k] {
-					mo.countermoveHistory[i][j][k][l] /= 2
-				}
-			}
-		}
-	}
+	applyGravity(&mo.countermoveHistory[prevPiece][prevMove.To()][movePiece][goodMove.To()], historyBonus(depth*depth, isGood))
 }
 
 // GetCountermoveHistoryScore returns the CMH score for a move given the previous move.
@@ -555,7 +518,8 @@ func (mo *MoveOrderer) GetContinuationHistoryScore(prevPiece board.Piece, prevTo
 }
 
 // UpdateContinuationHistory updates the continuation history for a move pair.
-// Called when a quiet move causes a beta cutoff.
+// Called when a quiet move causes a beta cutoff, and with isGood false for
+// the quiet moves searched before it.
 // plyBack indicates how many plies back (1 = parent, 2 = grandparent, etc.)
 func (mo *MoveOrderer) UpdateContinuationHistory(prevPiece board.Piece, prevTo board.Square, movePiece board.Piece, moveTo board.Square, depth, plyBack int, isGood bool) {
 	if prevPiece == board.NoPiece || movePiece == board.NoPiece || plyBack < 1 || plyBack > 6 {
@@ -565,28 +529,6 @@ func (mo *MoveOrderer) UpdateContinuationHistory(prevPiece board.Piece, prevTo b
 	// Use weighted bonus based on ply distance (Stockfish weights)
 	weight := contHistBonusWeights[plyBack-1]
 	bonus := (depth * depth * weight) / 1024
-
-	if isGood {
-		mo.continuationHistory[prevPiece][prevTo][movePiece][moveTo] += bonus
-		if mo.continuationHistory[prevPiece][prevTo][movePiece][moveTo] > 400000 {
-			mo.scaleContinuationHistory()
-		}
-	} else {
-		mo.continuationHistory[prevPiece][prevTo][movePiece][moveTo] -= bonus
-		if mo.continuationHistory[prevPiece][prevTo][movePiece][moveTo] < -400000 {
-			mo.continuationHistory[prevPiece][prevTo][movePiece][moveTo] = -400000
-		}
-	}
+	applyGravity(&mo.continuationHistory[prevPiece][prevTo][movePiece][moveTo], historyBonus(bonus, isGood))
 }
 
-func (mo *MoveOrderer) scaleContinuationHistory() {
-	for i := range mo.continuationHistory {
-		for j := range mo.continuationHistory[i] {
-			for k := range mo.continuationHistory[i][j] {
-				for l := range mo.continuationHistory[i][j][k] {
-					mo.continuationHistory[i][j][k][l] /= 2
-				}
-			}
-		}
-	}
-}
@@ -0,0 +1,196 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// Practical chances: instead of the best play an evaluation assumes, play
+// the position out many times with fast, imperfect moves and count the
+// results. A position that is equal with perfect play but where one side has
+// the easier moves to find shows better chances for that side.
+//
+// Each playout move is chosen among all legal moves by their shallow search
+// score, with a softmax at the playout temperature: the better the move the
+// likelier it is, and moves within the temperature of the best are played
+// almost as often.
+
+// Playout defaults
+const (
+	defaultPlayouts     = 64
+	defaultPlayoutPlies = 120
+	defaultTemperature  = 50  // Centipawns
+	playoutAdjudicate   = 600 // A playout with this advantage is scored a win
+	playoutDrawMargin   = 150 // At the ply limit, a smaller advantage is a draw
+	playoutTTSizeMB     = 1
+)
+
+// PlayoutOptions configures PracticalChances. Zero fields take the defaults.
+type PlayoutOptions struct {
+	Playouts    int    // Number of games played out
+	Depth       int    // Search depth scoring each move (0 = quiescence only)
+	MaxPlies    int    // Plies played before the playout is adjudicated by evaluation
+	Temperature int    // Centipawns; higher plays more loosely
+	Seed        uint64 // Random seed; results repeat exactly only with one worker
+}
+
+// withDefaults fills in the zero fields.
+func (o PlayoutOptions) withDefaults() PlayoutOptions {
+	if o.Playouts <= 0 {
+		o.Playouts = defaultPlayouts
+	}
+	if o.MaxPlies <= 0 {
+		o.MaxPlies = defaultPlayoutPlies
+	}
+	if o.Temperature <= 0 {
+		o.Temperature = defaultTemperature
+	}
+	return o
+}
+
+// Chances counts playout results from the perspective of the side to move
+// in the starting position.
+type Chances struct {
+	Wins, Draws, Losses int
+}
+
+// Playouts returns the number of playouts counted.
+func (c Chances) Playouts() int {
+	return c.Wins + c.Draws + c.Losses
+}
+
+// Score returns the expected points, 0 to 1, or 0.5 without playouts.
+func (c Chances) Score() float64 {
+	n := c.Playouts()
+	if n == 0 {
+		return 0.5
+	}
+	return (float64(c.Wins) + float64(c.Draws)/2) / float64(n)
+}
+
+// PracticalChances plays the position out opts.Playouts times, spread over
+// NumWorkers goroutines, and returns the results counted so far when ctx is
+// cancelled. It uses its own searchers and leaves the engine's state alone,
+// so it can run beside a search.
+func PracticalChances(ctx context.Context, pos *board.Position, opts PlayoutOptions) Chances {
+	opts = opts.withDefaults()
+
+	var mu sync.Mutex
+	var chances Chances
+	next := 0
+
+	var wg sync.WaitGroup
+	for range min(NumWorkers, opts.Playouts) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := NewSearcher(NewTranspositionTable(playoutTTSizeMB))
+			for {
+				mu.Lock()
+				i := next
+				next++
+				mu.Unlock()
+				if i >= opts.Playouts || ctx.Err() != nil {
+					return
+				}
+
+				rng := rand.New(rand.NewPCG(opts.Seed, uint64(i)))
+				result, ok := playout(ctx, s, pos, opts, rng)
+				if !ok {
+					return
+				}
+				mu.Lock()
+				switch {
+				case result > 0:
+					chances.Wins++
+				case result < 0:
+					chances.Losses++
+				default:
+					chances.Draws++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return chances
+}
+
+// playout plays one game from pos and returns 1, 0 or -1 for a win, draw
+// or loss of the side to move in pos. ok is false if ctx was cancelled.
+func playout(ctx context.Context, s *Searcher, start *board.Position, opts PlayoutOptions, rng *rand.Rand) (result int, ok bool) {
+	s.Reset()
+	pos := start.Copy()
+	pos.UpdateCheckers()
+	seen := map[uint64]int{pos.Hash: 1}
+
+	// sign turns a score of the side to move into one of the starting side
+	sign := 1
+	for ply := 0; ; ply++ {
+		if ctx.Err() != nil {
+			return 0, false
+		}
+
+		moves := pos.GenerateLegalMoves()
+		switch {
+		case moves.Len() == 0 && pos.InCheck():
+			return -sign, true
+		case moves.Len() == 0 || pos.HalfMoveClock >= 100 || pos.IsInsufficientMaterial() || seen[pos.Hash] >= 3:
+			return 0, true
+		}
+
+		move, score := playoutMove(s, pos, moves, opts, rng)
+		margin := playoutAdjudicate
+		if ply >= opts.MaxPlies {
+			margin = playoutDrawMargin
+		}
+		switch {
+		case score > margin:
+			return sign, true
+		case score < -margin:
+			return -sign, true
+		case ply >= opts.MaxPlies:
+			return 0, true
+		}
+
+		pos.MakeMove(move)
+		pos.UpdateCheckers()
+		seen[pos.Hash]++
+		sign = -sign
+	}
+}
+
+// playoutMove scores every legal move with a shallow search and picks one
+// by softmax over the scores. It returns the move and the best score, from
+// the side to move's perspective.
+func playoutMove(s *Searcher, pos *board.Position, moves *board.MoveList, opts PlayoutOptions, rng *rand.Rand) (board.Move, int) {
+	scores := make([]int, moves.Len())
+	best := -Infinity
+	for i := range scores {
+		child := pos.Copy()
+		child.MakeMove(moves.Get(i))
+		child.UpdateCheckers()
+		_, score := s.Search(child, opts.Depth)
+		scores[i] = -score
+		best = max(best, scores[i])
+	}
+
+	// Weights relative to the best move, which has weight 1
+	weights := make([]float64, len(scores))
+	total := 0.0
+	for i, sc := range scores {
+		weights[i] = math.Exp(float64(sc-best) / float64(opts.Temperature))
+		total += weights[i]
+	}
+	r := rng.Float64() * total
+	for i, w := range weights {
+		if r -= w; r < 0 {
+			return moves.Get(i), best
+		}
+	}
+	return moves.Get(len(weights) - 1), best
+}
@@ -13,6 +13,16 @@ const (
 	MaxPly    = 128
 )
 
+// Quiescence depth conventions for TT entries (Stockfish types.h)
+// QS nodes store distinct negative depths so they never satisfy main-search
+// depth requirements, and check-evasion results are distinguishable from
+// captures-only results.
+const (
+	DepthQSChecks   = 0  // QS node searched with quiet checks or all evasions (in check)
+	DepthQSNoChecks = -1 // QS node searched with captures only
+	DepthNone       = -6 // Depth of an empty TT slot (never stored)
+)
+
 // Pruning constants
 const (
 	lazyEvalMargin          = 150   // Lazy eval margin for quiescence
@@ -20,9 +30,37 @@ const (
 	probcutDepth            = 3     // Minimum depth for probcut (Stockfish uses 3)
 	probcutMargin           = 200   // Probcut margin above beta
 	probcutReduction        = 4     // Probcut depth reduction
+	ttRule50Limit           = 90    // No main-search TT cutoffs from this halfmove clock on (Stockfish)
+	aspirationMinDepth      = 4     // Shallower iterations search the full window
+	aspirationDelta         = 10    // Initial aspiration half-width in centipawns
+	qsCheckPlies            = 1     // Quiescence plies that also search quiet checks
+	maxTriedMoves           = 32    // Quiet moves and captures per node penalized on a cutoff
+	maxContempt             = 100   // Limit of SetContempt in centipawns
+	dynamicContemptDiv      = 4     // Root optimism / dynamicContemptDiv is added to contempt
 	// NOTE: Multi-Cut constants removed - now integrated into Singular Extension
 )
 
+// Tunable pruning parameters (registered in tune.go; UCI options in tune builds)
+var (
+	probcutBetaMargin  = 235 // Probcut beta margin (Stockfish search.cpp:938)
+	probcutImproving   = 63  // Probcut margin reduction when improving
+	razorBase          = 485 // Razoring margin: razorBase + razorDepthCoeff*depth^2
+	razorDepthCoeff    = 281
+	rfpDepthMargin     = 80  // Reverse futility margin per ply of depth
+	rfpImprovingMargin = 20  // Reverse futility margin reduction when not improving
+	seePruningCoeff    = 20  // Captures with SEE below -seePruningCoeff*depth are pruned
+	qsFutilityMargin   = 351 // QS futility base above stand pat (Stockfish constant)
+	qsDeltaMargin      = 200 // QS delta pruning margin
+
+	// Shuffling: after shuffleStart plies without a capture or pawn move the
+	// static eval fades linearly, reaching zero shuffleRange plies later
+	shuffleStart = 20
+	shuffleRange = 80
+
+	// Futility margin by depth (Stockfish: depth <= 5)
+	futilityMargins = [6]int{0, 200, 300, 500, 700, 900}
+)
+
 // LMP (Late Move Pruning) thresholds by depth
 // At depth d, prune quiet moves after lmpThreshold[d] moves
 var lmpThreshold = [8]int{0, 3, 5, 9, 15, 23, 33, 45}
@@ -33,8 +71,16 @@ const (
 	threatExtensionThreshold = 200 // Minimum material value to trigger extension (Knight/Bishop value)
 )
 
-// Feature flags for A/B testing
-// Set to false to disable feature and measure ELO impact
+// Extension budget: extensions of more than one ply may not take the plies
+// extended on a path from the root past the root depth, and at most
+// maxMultiExtensions of them may be on one path. Without it, stacked check
+// and double or triple singular extensions grow 60+ ply subtrees at low
+// nominal depths.
+const maxMultiExtensions = 6
+
+// Feature flags for A/B testing: the defaults of DefaultSearchFeatures.
+// Set to false to disable feature and measure ELO impact, or switch them at
+// runtime with Engine.SetSearchFeature
 const (
 	// Tier 1: High-Risk Pruning
 	EnableProbcut     = true // worker.go: Probcut pruning - FIXED with Stockfish improvements
@@ -52,6 +98,14 @@ const (
 	// Tier 3: Extensions/Reductions
 	EnableHindsightDepth = true // worker.go: Hindsight depth adjustment
 	EnableNMP            = true // worker.go: Null Move Pruning
+	EnableCaptureLMR     = true // worker.go: LMR for late SEE-losing captures
+	EnableShuffleDamp    = true // worker.go: Fade classical eval while shuffling toward the 50-move rule
+)
+
+// Capture LMR constants
+const (
+	captureLMRMinMoves    = 3    // Captures are only reduced after this many moves were searched
+	captureLMRGoodHistory = 2000 // Capture history above this reduces one ply less
 )
 
 // PVTable stores the principal variation.
@@ -108,6 +162,11 @@ func (s *Searcher) SetExcludedMoves(moves []board.Move) {
 	s.worker.SetExcludedMoves(moves)
 }
 
+// SetSearchMoves restricts the root to the given moves (UCI searchmoves).
+func (s *Searcher) SetSearchMoves(moves []board.Move) {
+	s.worker.SetSearchMoves(moves)
+}
+
 // SearchWithBounds performs search with custom alpha/beta bounds (for aspiration windows).
 func (s *Searcher) SearchWithBounds(pos *board.Position, depth, alpha, beta int) (board.Move, int) {
 	s.worker.InitSearch(pos)
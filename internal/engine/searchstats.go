@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cutoffSlots is the number of move indices beta cutoffs are counted by;
+// the last slot counts all later moves.
+const cutoffSlots = 8
+
+// SearchStats counts what a search did, to guide tuning: which moves caused
+// the beta cutoffs, how often each pruning fired, and how well the
+// transposition table served. Counting costs a little speed, so it is off
+// unless enabled with SetCollectStats.
+type SearchStats struct {
+	Nodes  uint64 // Nodes of the main search
+	QNodes uint64 // Quiescence nodes
+
+	TTProbes uint64 // Main search transposition table probes
+	TTHits   uint64 // Probes that found the position
+
+	EvalProbes uint64 // Evaluation cache probes
+	EvalHits   uint64 // Probes that found the evaluation
+
+	Cutoffs [cutoffSlots]uint64 // Beta cutoffs by index of the cutting move
+
+	NullTries   uint64 // Null move searches
+	NullCutoffs uint64 // Null move searches that failed high
+
+	RFPPrunes      uint64 // Nodes cut by reverse futility pruning
+	RazorPrunes    uint64 // Nodes cut by razoring
+	ProbcutCutoffs uint64 // Nodes cut by probcut
+	FutilityPrunes uint64 // Moves skipped by futility pruning
+	SEEPrunes      uint64 // Captures skipped by SEE pruning
+	LMPPrunes      uint64 // Moves skipped by late move pruning
+	HistoryPrunes  uint64 // Moves skipped by history pruning
+
+	ExtensionsLimited uint64 // Extensions cut short by the path's extension budget
+}
+
+// add adds the counts of o.
+func (s *SearchStats) add(o *SearchStats) {
+	s.Nodes += o.Nodes
+	s.QNodes += o.QNodes
+	s.TTProbes += o.TTProbes
+	s.TTHits += o.TTHits
+	s.EvalProbes += o.EvalProbes
+	s.EvalHits += o.EvalHits
+	for i := range s.Cutoffs {
+		s.Cutoffs[i] += o.Cutoffs[i]
+	}
+	s.NullTries += o.NullTries
+	s.NullCutoffs += o.NullCutoffs
+	s.RFPPrunes += o.RFPPrunes
+	s.RazorPrunes += o.RazorPrunes
+	s.ProbcutCutoffs += o.ProbcutCutoffs
+	s.FutilityPrunes += o.FutilityPrunes
+	s.SEEPrunes += o.SEEPrunes
+	s.LMPPrunes += o.LMPPrunes
+	s.HistoryPrunes += o.HistoryPrunes
+	s.ExtensionsLimited += o.ExtensionsLimited
+}
+
+// cutoff counts a beta cutoff by the move of the given index, from 0.
+func (s *SearchStats) cutoff(index int) {
+	s.Cutoffs[min(index, cutoffSlots-1)]++
+}
+
+// ratio returns n/d, or 0 if d is 0.
+func ratio(n, d uint64) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// TTHitRate returns the share of main search probes that found the position.
+func (s SearchStats) TTHitRate() float64 {
+	return ratio(s.TTHits, s.TTProbes)
+}
+
+// EvalHitRate returns the share of evaluations answered by the cache.
+func (s SearchStats) EvalHitRate() float64 {
+	return ratio(s.EvalHits, s.EvalProbes)
+}
+
+// QuiescenceRatio returns the share of all nodes spent in quiescence.
+func (s SearchStats) QuiescenceRatio() float64 {
+	return ratio(s.QNodes, s.Nodes+s.QNodes)
+}
+
+// FirstMoveCutoffRate returns the share of beta cutoffs caused by the first
+// move searched, a measure of move ordering.
+func (s SearchStats) FirstMoveCutoffRate() float64 {
+	var total uint64
+	for _, n := range s.Cutoffs {
+		total += n
+	}
+	return ratio(s.Cutoffs[0], total)
+}
+
+// NullCutoffRate returns the share of null move searches that failed high.
+func (s SearchStats) NullCutoffRate() float64 {
+	return ratio(s.NullCutoffs, s.NullTries)
+}
+
+// String formats the stats as space-separated name and value pairs, e.g.
+// "nodes 1200 qnodes 3400 qratio 0.74 tthit 0.41 evalhit 0.22 cutoffs 900,80,30,...".
+func (s SearchStats) String() string {
+	cutoffs := make([]string, len(s.Cutoffs))
+	for i, n := range s.Cutoffs {
+		cutoffs[i] = fmt.Sprint(n)
+	}
+	return fmt.Sprintf("nodes %d qnodes %d qratio %.2f tthit %.2f evalhit %.2f cutoffs %s firstcut %.2f "+
+		"null %d/%d rfp %d razor %d probcut %d futility %d see %d lmp %d history %d extlimited %d",
+		s.Nodes, s.QNodes, s.QuiescenceRatio(), s.TTHitRate(), s.EvalHitRate(), strings.Join(cutoffs, ","), s.FirstMoveCutoffRate(),
+		s.NullCutoffs, s.NullTries, s.RFPPrunes, s.RazorPrunes, s.ProbcutCutoffs,
+		s.FutilityPrunes, s.SEEPrunes, s.LMPPrunes, s.HistoryPrunes, s.ExtensionsLimited)
+}
+
+// SetCollectStats turns search statistics on or off, from the next search.
+func (e *Engine) SetCollectStats(on bool) {
+	e.collectStats.Store(on)
+}
+
+// LastSearchStats returns the statistics of the most recent search, summed
+// over the workers. They are zero unless collected, and for book and
+// tablebase moves.
+func (e *Engine) LastSearchStats() SearchStats {
+	return e.lastStats
+}
@@ -0,0 +1,126 @@
+package engine
+
+import "github.com/hailam/chessplay/internal/board"
+
+// Analysis sessions: what the engine has learnt about a position lives in
+// its transposition table and is lost when the program exits. Exporting the
+// explored tree, every position reachable from the root that the table
+// knows with its score, depth and best move, lets a long analysis be saved
+// and stored back into the table later to resume where it stopped.
+
+// AnalysisNode is one position of an explored analysis tree.
+type AnalysisNode struct {
+	Moves []board.Move // Moves from the root position
+	Best  board.Move   // Best move found (NoMove = none)
+	Score int          // From the side to move; mate scores count from this node
+	Depth int          // Depth searched
+	Flag  TTFlag       // Whether Score is exact or a bound
+}
+
+// ExportAnalysis returns the analysis tree of pos known to the transposition
+// table, up to maxNodes positions, nearest the root first. Positions reached
+// by several move orders are listed once. It should not run beside a search.
+func (e *Engine) ExportAnalysis(pos *board.Position, maxNodes int) []AnalysisNode {
+	type item struct {
+		pos   *board.Position
+		moves []board.Move
+	}
+	var nodes []AnalysisNode
+	seen := map[uint64]bool{pos.Hash: true}
+	root := pos.Copy()
+	root.UpdateCheckers()
+	queue := []item{{root, nil}}
+	for len(queue) > 0 && len(nodes) < maxNodes {
+		it := queue[0]
+		queue = queue[1:]
+		entry, found := e.tt.Probe(it.pos.Hash)
+		if !found || entry.Depth <= 0 {
+			continue
+		}
+		nodes = append(nodes, AnalysisNode{
+			Moves: it.moves,
+			Best:  entry.BestMove,
+			Score: int(entry.Score),
+			Depth: int(entry.Depth),
+			Flag:  entry.Flag,
+		})
+
+		legal := it.pos.GenerateLegalMoves()
+		for i := 0; i < legal.Len(); i++ {
+			child := it.pos.Copy()
+			child.MakeMove(legal.Get(i))
+			child.UpdateCheckers()
+			if seen[child.Hash] {
+				continue
+			}
+			seen[child.Hash] = true
+			queue = append(queue, item{child, append(it.moves[:len(it.moves):len(it.moves)], legal.Get(i))})
+		}
+	}
+	return nodes
+}
+
+// ImportAnalysis stores an analysis tree of pos exported earlier back into
+// the transposition table, deepest positions first so the root entries are
+// the last to be written. Nodes whose moves are not legal are skipped. It
+// returns the number of nodes stored.
+func (e *Engine) ImportAnalysis(pos *board.Position, nodes []AnalysisNode) int {
+	stored := 0
+	for i := len(nodes) - 1; i >= 0; i-- {
+		n := nodes[i]
+		p, ok := playMoves(pos, n.Moves)
+		if !ok || n.Best != board.NoMove && !isLegalMove(p, n.Best) {
+			continue
+		}
+		e.tt.Store(p.Hash, n.Depth, n.Score, n.Flag, n.Best, n.Flag == TTExact)
+		stored++
+	}
+	return stored
+}
+
+// playMoves returns a copy of pos after the moves, and false if one is not
+// legal.
+func playMoves(pos *board.Position, moves []board.Move) (*board.Position, bool) {
+	p := pos.Copy()
+	p.UpdateCheckers()
+	for _, m := range moves {
+		if !isLegalMove(p, m) {
+			return nil, false
+		}
+		p.MakeMove(m)
+		p.UpdateCheckers()
+	}
+	return p, true
+}
+
+// isLegalMove returns true if m is a legal move in pos, from a saved file or
+// otherwise untrusted.
+func isLegalMove(pos *board.Position, m board.Move) bool {
+	return pos.PseudoLegal(m) && pos.IsLegal(m)
+}
+
+// LineAnalysis returns the analysis of a principal variation searched
+// elsewhere, such as a cloud evaluation, ready for ImportAnalysis: each
+// position along the line with the next move as its best, the score from
+// its side to move and the depth falling by a ply a move. A PV's scores are
+// exact. score is from the side to move at the root, and a mate score counts
+// from the root.
+func LineAnalysis(pv []board.Move, score, depth int) []AnalysisNode {
+	var nodes []AnalysisNode
+	for i, m := range pv {
+		if depth-i < 1 {
+			break
+		}
+		s := score
+		if s >= MateScore-MaxPly {
+			s += i // Mate is i plies nearer
+		} else if s <= -MateScore+MaxPly {
+			s -= i
+		}
+		if i%2 == 1 {
+			s = -s
+		}
+		nodes = append(nodes, AnalysisNode{Moves: pv[:i], Best: m, Score: s, Depth: depth - i, Flag: TTExact})
+	}
+	return nodes
+}
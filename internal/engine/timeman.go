@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/hailam/chessplay/internal/board"
@@ -14,17 +15,47 @@ type UCILimits struct {
 	MoveTime  time.Duration    // fixed time per move (overrides other time controls)
 	Depth     int              // maximum search depth
 	Nodes     uint64           // maximum nodes to search
+	Mate      int              // stop once a mate in this many moves is proven
 	Infinite  bool             // search until stopped
-	Ponder    bool             // ponder mode
+	Ponder    bool             // ponder mode: the time limits apply from PonderHit on
+	MinTime   time.Duration    // minimum thinking time per move in timed searches
+
+	SearchMoves []board.Move // restrict the root to these moves (nil = all moves)
 }
 
 // TimeManager handles time allocation for searches.
+// It is owned by the main worker (worker 0), which checks the soft bound after
+// every completed iteration; the hard bound aborts the search mid-iteration.
+// Based on Stockfish's timeman.cpp and the iteration checks in search.cpp.
 type TimeManager struct {
-	optimumTime time.Duration // Target time for this move
-	maximumTime time.Duration // Maximum time allowed
+	optimumTime time.Duration // Soft bound: target time for this move
+	maximumTime time.Duration // Hard bound: the search is aborted here
 	startTime   time.Time     // When search started
+	timed       bool          // False for infinite, depth-only and node-only searches
+	fixed       bool          // Fixed move time: only the hard bound applies
+	nodeLimit   uint64        // Node-based cutoff (0 = no limit)
+	minTime     time.Duration // Minimum thinking time (within the safety margin)
+	pondering   atomic.Bool   // Searching on the opponent's time: never stop on time
+
+	// Time counted in searched nodes (see CountNodes), nil for the clock
+	nodes     *atomic.Uint64
+	nodesTime int64 // Nodes per millisecond
+
+	// Iteration state, updated by the main worker only
+	iterations        int
+	lastBestMove      board.Move
+	lastBestMoveDepth int
+	bestMoveChanges   int    // Best move changes in 1/100 units, halved every iteration
+	prevScore         int    // Score of the previous iteration
+	iterScores        [4]int // Scores of the last four iterations (fail-low detection)
 }
 
+// Time scaling bounds (percent of optimum time)
+const (
+	fallingEvalMin = 60  // Eval rising or steady: stop early
+	fallingEvalMax = 170 // Eval dropping (fail low): extend time
+)
+
 // NewTimeManager creates a new time manager.
 func NewTimeManager() *TimeManager {
 	return &TimeManager{}
@@ -33,12 +64,18 @@ func NewTimeManager() *TimeManager {
 // Init initializes the time manager for a new search.
 // ply is the current game ply (half-move number).
 func (tm *TimeManager) Init(limits UCILimits, us board.Color, ply int) {
-	tm.startTime = time.Now()
+	*tm = TimeManager{
+		startTime: time.Now(),
+		nodeLimit: limits.Nodes,
+	}
+	tm.pondering.Store(limits.Ponder)
 
 	// Fixed move time mode
 	if limits.MoveTime > 0 {
 		tm.optimumTime = limits.MoveTime
 		tm.maximumTime = limits.MoveTime
+		tm.timed = true
+		tm.fixed = true
 		return
 	}
 
@@ -48,7 +85,7 @@ func (tm *TimeManager) Init(limits UCILimits, us board.Color, ply int) {
 		tm.maximumTime = time.Hour
 		return
 	}
-
+	tm.timed = true
 	// Calculate time allocation based on remaining time and increment
 	timeLeft := limits.Time[us]
 	inc := limits.Inc[us]
@@ -105,10 +142,57 @@ func (tm *TimeManager) Init(limits UCILimits, us board.Color, ply int) {
 	if tm.maximumTime < 50*time.Millisecond {
 		tm.maximumTime = 50 * time.Millisecond
 	}
+
+	// Minimum thinking time requested by the GUI, within the safety margin
+	if limits.MinTime > 0 {
+		tm.minTime = min(limits.MinTime, safetyMargin)
+		if tm.optimumTime < tm.minTime {
+			tm.optimumTime = tm.minTime
+		}
+		if tm.maximumTime < tm.minTime {
+			tm.maximumTime = tm.minTime
+		}
+	}
 }
 
-// Elapsed returns the time elapsed since search started.
+// phaseTimeScale is the share of the optimum time spent in each game phase,
+// in percent: less in the opening, more in the critical middlegame.
+var phaseTimeScale = [...]time.Duration{
+	board.PhaseOpening:    90,
+	board.PhaseMiddlegame: 115,
+	board.PhaseEndgame:    100,
+}
+
+// AdjustForPhase scales the optimum time of a clock-based search by the game
+// phase of the root position, never beyond the maximum time.
+func (tm *TimeManager) AdjustForPhase(phase board.GamePhase) {
+	if !tm.timed || tm.fixed {
+		return
+	}
+	tm.optimumTime = tm.optimumTime * phaseTimeScale[phase] / 100
+	if tm.optimumTime > tm.maximumTime {
+		tm.optimumTime = tm.maximumTime
+	}
+	if tm.optimumTime < tm.minTime {
+		tm.optimumTime = tm.minTime
+	}
+}
+
+// CountNodes makes the search's time run with the nodes it searches, as read
+// from counter, npmsec nodes to the millisecond, instead of the clock. The
+// time limits then stand for node budgets, so timed searches take the same
+// decisions on any hardware (Stockfish's nodestime). Call it after Init.
+func (tm *TimeManager) CountNodes(npmsec int64, counter *atomic.Uint64) {
+	tm.nodesTime = npmsec
+	tm.nodes = counter
+}
+
+// Elapsed returns the time elapsed since search started, or the searched
+// nodes as time under CountNodes.
 func (tm *TimeManager) Elapsed() time.Duration {
+	if tm.nodes != nil {
+		return time.Duration(int64(tm.nodes.Load()) * int64(time.Millisecond) / tm.nodesTime)
+	}
 	return time.Since(tm.startTime)
 }
 
@@ -132,36 +216,85 @@ func (tm *TimeManager) PastOptimum() bool {
 	return tm.Elapsed() >= tm.optimumTime
 }
 
-// AdjustForStability adjusts time allocation based on best move stability.
-// If the best move hasn't changed for several depths, we can stop earlier.
-// stability: number of consecutive depths with same best move
-func (tm *TimeManager) AdjustForStability(stability int) {
-	if stability >= 6 {
-		// Very stable: use only 40% of optimum
-		tm.optimumTime = tm.optimumTime * 40 / 100
-	} else if stability >= 4 {
-		// Stable: use only 60% of optimum
-		tm.optimumTime = tm.optimumTime * 60 / 100
-	} else if stability >= 2 {
-		// Somewhat stable: use 80% of optimum
-		tm.optimumTime = tm.optimumTime * 80 / 100
-	}
-}
-
-// AdjustForInstability increases time when best move keeps changing.
-// changes: number of best move changes in recent depths
-func (tm *TimeManager) AdjustForInstability(changes int) {
-	if changes >= 4 {
-		// Very unstable: use 200% of optimum (up to maximum)
-		tm.optimumTime = tm.optimumTime * 200 / 100
-		if tm.optimumTime > tm.maximumTime {
-			tm.optimumTime = tm.maximumTime
-		}
-	} else if changes >= 2 {
-		// Unstable: use 150% of optimum
-		tm.optimumTime = tm.optimumTime * 150 / 100
-		if tm.optimumTime > tm.maximumTime {
-			tm.optimumTime = tm.maximumTime
+// Pondering returns true while the search runs on the opponent's time.
+func (tm *TimeManager) Pondering() bool {
+	return tm.pondering.Load()
+}
+
+// Timed returns true if the search has a time limit.
+func (tm *TimeManager) Timed() bool {
+	return tm.timed
+}
+
+// StopAfterIteration is called by the main worker after each completed iteration.
+// It returns true if the search should stop: the node limit is reached, or the
+// elapsed time exceeds the optimum time scaled by eval trend and best move stability.
+func (tm *TimeManager) StopAfterIteration(depth int, move board.Move, score int, nodes uint64) bool {
+	if tm.nodeLimit > 0 && nodes >= tm.nodeLimit {
+		return true
+	}
+
+	// Seed the trend history on the first iteration
+	if tm.iterations == 0 {
+		tm.prevScore = score
+		for i := range tm.iterScores {
+			tm.iterScores[i] = score
 		}
+		tm.lastBestMove = move
+		tm.lastBestMoveDepth = depth
+	}
+	tm.iterations++
+
+	// Best move stability
+	if move != tm.lastBestMove {
+		tm.bestMoveChanges += 100
+		tm.lastBestMove = move
+		tm.lastBestMoveDepth = depth
+	}
+
+	if !tm.timed || tm.fixed {
+		tm.updateScores(depth, score)
+		return false
 	}
+
+	// Falling eval: spend more time after a fail low (Stockfish search.cpp fallingEval)
+	fallingEval := 100 + (2*(tm.prevScore-score)+(tm.iterScores[depth&3]-score))/2
+	if fallingEval < fallingEvalMin {
+		fallingEval = fallingEvalMin
+	} else if fallingEval > fallingEvalMax {
+		fallingEval = fallingEvalMax
+	}
+
+	// A best move that survived several iterations needs less confirmation
+	stableDepths := depth - tm.lastBestMoveDepth
+	stability := 120
+	if stableDepths >= 6 {
+		stability = 60
+	} else if stableDepths >= 4 {
+		stability = 75
+	} else if stableDepths >= 2 {
+		stability = 90
+	}
+
+	// Recent best move changes ask for more time (Stockfish bestMoveInstability)
+	instability := 100 + tm.bestMoveChanges
+
+	softTime := tm.optimumTime * time.Duration(fallingEval) / 100
+	softTime = softTime * time.Duration(stability) / 100
+	softTime = softTime * time.Duration(instability) / 100
+	if softTime > tm.maximumTime {
+		softTime = tm.maximumTime
+	}
+
+	tm.bestMoveChanges /= 2
+	tm.updateScores(depth, score)
+
+	// While pondering the trend is still tracked, but the search goes on
+	return !tm.pondering.Load() && tm.Elapsed() >= softTime
+}
+
+// updateScores records the score of a completed iteration for the eval trend.
+func (tm *TimeManager) updateScores(depth, score int) {
+	tm.prevScore = score
+	tm.iterScores[depth&3] = score
 }
@@ -0,0 +1,311 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/sfnnue"
+)
+
+// EvalTerm is one classical evaluation term, from White's perspective.
+type EvalTerm struct {
+	Name string
+	MG   int // Middlegame contribution
+	EG   int // Endgame contribution
+}
+
+// NNUETrace describes the NNUE output for a position, from the side to move's perspective.
+type NNUETrace struct {
+	Bucket     int // Layer stack selected by piece count
+	PieceCount int
+
+	BigPSQT         int
+	BigPositional   int
+	SmallPSQT       int
+	SmallPositional int
+	SmallOnly       bool // Big network not loaded
+
+	// Score combines big positional with the averaged PSQT of both networks,
+	// or weights the small network alone, as in nnueEvaluate (before
+	// optimism, which only exists during search).
+	Score int
+	// Final applies 50-move rule dampening to Score.
+	Final int
+}
+
+// EvalTrace is a per-term breakdown of the static evaluation.
+type EvalTrace struct {
+	SideToMove board.Color
+	Terms      []EvalTerm
+	Phase      int // 0 = pure endgame, 24 = full middlegame
+	Scale      int // Endgame scale factor, out of 64 (drawish material lowers it)
+	Tempo      int
+	Score      int        // Classical score from the side to move's perspective (same as Evaluate)
+	NNUE       *NNUETrace // nil when no networks are loaded
+}
+
+// TraceEvaluate breaks the classical evaluation of a position down into its terms.
+// It mirrors Evaluate term by term; the total always equals Evaluate(pos).
+func TraceEvaluate(pos *board.Position) *EvalTrace {
+	t := &EvalTrace{SideToMove: pos.SideToMove, Tempo: tempoBonus}
+
+	// Material and piece-square tables, kept apart for tuning
+	var material, pst EvalTerm
+	material.Name, pst.Name = "Material", "PST"
+	for c := board.White; c <= board.Black; c++ {
+		sign := 1
+		if c == board.Black {
+			sign = -1
+		}
+
+		for pt := board.Pawn; pt <= board.King; pt++ {
+			bb := pos.Pieces[c][pt]
+			for bb != 0 {
+				sq := bb.PopLSB()
+
+				material.MG += sign * pieceValues[pt]
+				material.EG += sign * pieceValues[pt]
+
+				pstSq := sq
+				if c == board.Black {
+					pstSq = sq.Mirror()
+				}
+				if pt == board.King {
+					pst.MG += sign * kingMidgamePST[pstSq]
+					pst.EG += sign * kingEndgamePST[pstSq]
+				} else {
+					pst.MG += sign * psts[pt][pstSq]
+					pst.EG += sign * psts[pt][pstSq]
+				}
+
+				switch pt {
+				case board.Knight, board.Bishop:
+					t.Phase += 1
+				case board.Rook:
+					t.Phase += 2
+				case board.Queen:
+					t.Phase += 4
+				}
+			}
+		}
+	}
+	t.Terms = append(t.Terms, material, pst)
+
+	// Remaining terms, in the order Evaluate adds them
+	add := func(name string, mg, eg int) {
+		t.Terms = append(t.Terms, EvalTerm{Name: name, MG: mg, EG: eg})
+	}
+	mg, eg := evaluatePassedPawns(pos)
+	add("Passed pawns", mg, eg)
+	mg, eg = evaluateMobility(pos)
+	add("Mobility", mg, eg)
+	add("King safety", evaluateKingSafety(pos), 0)
+	add("King tropism", evaluateKingTropism(pos), 0)
+	mg, eg = evaluateBishopPair(pos)
+	add("Bishop pair", mg, eg)
+	mg, eg = evaluateRooksOnFiles(pos)
+	add("Rooks on files", mg, eg)
+	mg, eg = evaluatePieceCoordination(pos)
+	add("Coordination", mg, eg)
+	mg, eg = evaluatePawnStructure(pos)
+	add("Pawn structure", mg, eg)
+	mg, eg = evaluateOutposts(pos)
+	add("Outposts", mg, eg)
+	mg, eg = evaluateThreats(pos)
+	add("Threats", mg, eg)
+	add("Space", evaluateSpace(pos), 0)
+	mg, eg = evaluateTrappedPieces(pos)
+	add("Trapped pieces", mg, eg)
+
+	// Tapered total, exactly as in Evaluate
+	const maxPhase = 24
+	if t.Phase > maxPhase {
+		t.Phase = maxPhase
+	}
+	var mgScore, egScore int
+	for _, term := range t.Terms {
+		mgScore += term.MG
+		egScore += term.EG
+	}
+	t.Scale = endgameScale(pos, egScore)
+	egScore = egScore * t.Scale / scaleFactorNormal
+	score := (mgScore*t.Phase+egScore*(maxPhase-t.Phase))/maxPhase + tempoBonus
+	if pos.SideToMove == board.Black {
+		score = -score
+	}
+	t.Score = score
+
+	return t
+}
+
+// TraceEvaluate breaks down the classical evaluation and, when networks are
+// loaded, adds the NNUE output with the bucket used.
+func (e *Engine) TraceEvaluate(pos *board.Position) *EvalTrace {
+	t := TraceEvaluate(pos)
+	if e.nnueNet != nil {
+		t.NNUE = traceNNUE(e.nnueNet, pos)
+	}
+	return t
+}
+
+// traceNNUE evaluates a position with the loaded networks from scratch.
+func traceNNUE(nets *sfnnue.Networks, pos *board.Position) *NNUETrace {
+	stack := sfnnue.NewAccumulatorStack()
+	bigAcc := stack.CurrentBig()
+	smallAcc := stack.CurrentSmall()
+
+	var indexBuffer [64]int
+	for perspective := 0; perspective < 2; perspective++ {
+		computeAccumulator(nets.Small, pos, smallAcc, perspective, indexBuffer[:])
+	}
+
+	pieceCount := countPieces(pos)
+	sideToMove := 0
+	if pos.SideToMove == board.Black {
+		sideToMove = 1
+	}
+
+	smallPsqt, smallPositional := nets.Small.Evaluate(smallAcc.Accumulation, smallAcc.PSQTAccumulation,
+		sideToMove, pieceCount, stack.TransformBuffer[:])
+
+	bucket := (pieceCount - 1) / 4
+	if bucket < 0 {
+		bucket = 0
+	} else if bucket >= sfnnue.LayerStacks {
+		bucket = sfnnue.LayerStacks - 1
+	}
+
+	t := &NNUETrace{
+		Bucket:          bucket,
+		PieceCount:      pieceCount,
+		SmallPSQT:       int(smallPsqt),
+		SmallPositional: int(smallPositional),
+		SmallOnly:       nets.SmallOnly(),
+	}
+	if t.SmallOnly {
+		t.Score = (125*t.SmallPSQT + 131*t.SmallPositional) / 128
+	} else {
+		for perspective := 0; perspective < 2; perspective++ {
+			computeAccumulator(nets.Big, pos, bigAcc, perspective, indexBuffer[:])
+		}
+		bigPsqt, bigPositional := nets.Big.Evaluate(bigAcc.Accumulation, bigAcc.PSQTAccumulation,
+			sideToMove, pieceCount, stack.TransformBuffer[:])
+		t.BigPSQT, t.BigPositional = int(bigPsqt), int(bigPositional)
+		t.Score = t.BigPositional + (t.SmallPSQT+t.BigPSQT)/2
+	}
+	t.Final = t.Score - t.Score*pos.HalfMoveClock/199
+	return t
+}
+
+// taper blends middlegame and endgame values by the phase of the position.
+func (t *EvalTrace) taper(mg, eg int) int {
+	return (mg*t.Phase + eg*(24-t.Phase)) / 24
+}
+
+// whiteScore returns the classical score from White's perspective.
+func (t *EvalTrace) whiteScore() int {
+	if t.SideToMove == board.Black {
+		return -t.Score
+	}
+	return t.Score
+}
+
+// PieceTerm is what a piece adds to one evaluation term, tapered by the
+// phase of the position, from White's perspective.
+type PieceTerm struct {
+	Name  string
+	Value int
+}
+
+// PieceTrace is what one piece adds to the classical evaluation, from
+// White's perspective.
+type PieceTrace struct {
+	Square board.Square
+	Piece  board.Piece
+	Terms  []PieceTerm // The terms the piece changes, in EvalTrace.Terms order
+	Score  int         // Net contribution: tapered and scaled like EvalTrace.Score
+}
+
+// Positional returns the contribution beyond the material value of the
+// piece, from its own side's perspective: above 0 for a well placed piece.
+func (p *PieceTrace) Positional() int {
+	v := p.Score
+	if p.Piece.Color() == board.Black {
+		v = -v
+	}
+	return v - pieceValues[p.Piece.Type()]
+}
+
+// TracePieces attributes the classical evaluation to the pieces: each
+// term's change when the piece is taken off the board. A king cannot be,
+// so its contribution is its square alone.
+func TracePieces(pos *board.Position) []PieceTrace {
+	full := TraceEvaluate(pos)
+	var pieces []PieceTrace
+	for bb := pos.AllOccupied; bb != 0; {
+		sq := bb.PopLSB()
+		p := PieceTrace{Square: sq, Piece: pos.PieceAt(sq)}
+
+		if p.Piece.Type() == board.King {
+			pstSq := sq
+			if p.Piece.Color() == board.Black {
+				pstSq = sq.Mirror()
+			}
+			pst := full.taper(kingMidgamePST[pstSq], kingEndgamePST[pstSq])
+			if p.Piece.Color() == board.Black {
+				pst = -pst
+			}
+			p.Terms = []PieceTerm{{Name: "PST", Value: pst}}
+			p.Score = pst
+			pieces = append(pieces, p)
+			continue
+		}
+
+		without := TraceEvaluate(pos.WithoutPiece(sq))
+		for i, term := range full.Terms {
+			if v := full.taper(term.MG-without.Terms[i].MG, term.EG-without.Terms[i].EG); v != 0 {
+				p.Terms = append(p.Terms, PieceTerm{Name: term.Name, Value: v})
+			}
+		}
+		p.Score = full.whiteScore() - without.whiteScore()
+		pieces = append(pieces, p)
+	}
+	return pieces
+}
+
+// String formats the trace as a table for the UCI "eval" command.
+func (t *EvalTrace) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("      Term      |    MG    EG\n")
+	sb.WriteString("----------------+-------------\n")
+	var mg, eg int
+	for _, term := range t.Terms {
+		fmt.Fprintf(&sb, " %-14s | %5d %5d\n", term.Name, term.MG, term.EG)
+		mg += term.MG
+		eg += term.EG
+	}
+	sb.WriteString("----------------+-------------\n")
+	fmt.Fprintf(&sb, " %-14s | %5d %5d\n", "Total", mg, eg)
+	sb.WriteString("\n")
+	fmt.Fprintf(&sb, "Phase: %d/24, endgame scale: %d/64, tempo: %d (White's perspective above)\n", t.Phase, t.Scale, t.Tempo)
+	fmt.Fprintf(&sb, "Classical evaluation: %d (side to move)\n", t.Score)
+
+	if t.NNUE != nil {
+		n := t.NNUE
+		sb.WriteString("\n")
+		fmt.Fprintf(&sb, "NNUE bucket %d (%d pieces)\n", n.Bucket, n.PieceCount)
+		if n.SmallOnly {
+			sb.WriteString("  Big network:   not loaded\n")
+		} else {
+			fmt.Fprintf(&sb, "  Big network:   psqt %5d  positional %5d\n", n.BigPSQT, n.BigPositional)
+		}
+		fmt.Fprintf(&sb, "  Small network: psqt %5d  positional %5d\n", n.SmallPSQT, n.SmallPositional)
+		fmt.Fprintf(&sb, "NNUE evaluation: %d (side to move, %d after 50-move dampening)\n", n.Score, n.Final)
+	} else {
+		sb.WriteString("NNUE evaluation: none (networks not loaded)\n")
+	}
+
+	return sb.String()
+}
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"math"
 	"sync/atomic"
 
 	"github.com/hailam/chessplay/internal/board"
@@ -27,6 +28,10 @@ type TTEntry struct {
 	IsPV     bool       // True if this entry was on the principal variation
 }
 
+// maxTTDepth is the largest depth representable in a TT entry.
+// Deeper stores (e.g. tablebase hits at MaxPly) are clamped.
+const maxTTDepth = 127
+
 // TTEntryPacked is the lock-free atomic storage format.
 // Uses XOR verification to detect torn reads/writes.
 type TTEntryPacked struct {
@@ -34,6 +39,7 @@ type TTEntryPacked struct {
 	keyData atomic.Uint64
 
 	// moveData packs: move(16) | score(16) | depth(8) | flag(4) | isPV(4) | age(8) | reserved(8)
+	// depth is stored offset by DepthNone so that 0 always means an empty slot
 	moveData atomic.Uint64
 }
 
@@ -42,7 +48,7 @@ func packMoveData(move board.Move, score int16, depth int8, flag TTFlag, isPV bo
 	var data uint64
 	data |= uint64(move) & 0xFFFF                   // bits 0-15: move
 	data |= (uint64(uint16(score)) & 0xFFFF) << 16  // bits 16-31: score
-	data |= (uint64(uint8(depth)) & 0xFF) << 32     // bits 32-39: depth
+	data |= (uint64(uint8(int(depth)-DepthNone)) & 0xFF) << 32 // bits 32-39: depth - DepthNone
 	data |= (uint64(flag) & 0xF) << 40              // bits 40-43: flag
 	isPVBit := uint64(0)
 	if isPV {
@@ -57,20 +63,32 @@ func packMoveData(move board.Move, score int16, depth int8, flag TTFlag, isPV bo
 func unpackMoveData(data uint64) (move board.Move, score int16, depth int8, flag TTFlag, isPV bool, age uint8) {
 	move = board.Move(data & 0xFFFF)
 	score = int16((data >> 16) & 0xFFFF)
-	depth = int8((data >> 32) & 0xFF)
+	depth = int8(int((data>>32)&0xFF) + DepthNone)
 	flag = TTFlag((data >> 40) & 0xF)
 	isPV = ((data >> 44) & 0x1) != 0
 	age = uint8((data >> 48) & 0xFF)
 	return
 }
 
+// ttClusterSize is the number of entries sharing one hash bucket.
+// Four 16-byte entries fill exactly one 64-byte cache line, so a probe
+// touches a single line while a position has four candidate slots.
+const ttClusterSize = 4
+
+// TTCluster is a bucket of entries that share a hash index.
+type TTCluster struct {
+	entries [ttClusterSize]TTEntryPacked
+}
+
 // TranspositionTable is a lock-free hash table for storing search results.
 // Uses atomic operations with XOR verification for thread-safety.
+// Entries are grouped into clusters; replacement picks the least valuable
+// entry of the cluster by depth and age.
 type TranspositionTable struct {
-	entries []TTEntryPacked
-	size    uint64
-	mask    uint64
-	age     atomic.Uint32
+	clusters []TTCluster
+	size     uint64 // Number of entries (clusters * ttClusterSize)
+	mask     uint64 // Cluster index mask
+	age      atomic.Uint32
 
 	// Statistics (atomic for thread-safety)
 	hits   atomic.Uint64
@@ -79,17 +97,20 @@ type TranspositionTable struct {
 
 // NewTranspositionTable creates a transposition table with the given size in MB.
 func NewTranspositionTable(sizeMB int) *TranspositionTable {
-	// Calculate number of entries
-	entrySize := uint64(16) // Two uint64 values
-	numEntries := (uint64(sizeMB) * 1024 * 1024) / entrySize
+	// Calculate number of clusters
+	clusterSize := uint64(16 * ttClusterSize) // Two uint64 values per entry
+	numClusters := (uint64(sizeMB) * 1024 * 1024) / clusterSize
+	if numClusters == 0 {
+		numClusters = 1
+	}
 
 	// Round down to power of 2 for fast modulo
-	numEntries = roundDownToPowerOf2(numEntries)
+	numClusters = roundDownToPowerOf2(numClusters)
 
 	return &TranspositionTable{
-		entries: make([]TTEntryPacked, numEntries),
-		size:    numEntries,
-		mask:    numEntries - 1,
+		clusters: make([]TTCluster, numClusters),
+		size:     numClusters * ttClusterSize,
+		mask:     numClusters - 1,
 	}
 }
 
@@ -104,105 +125,136 @@ func roundDownToPowerOf2(n uint64) uint64 {
 	return (n + 1) >> 1
 }
 
+// cluster returns the cluster for a hash.
+func (tt *TranspositionTable) cluster(hash uint64) *TTCluster {
+	return &tt.clusters[hash&tt.mask]
+}
+
+// Prefetch starts loading the cluster of a hash into the CPU cache, so a
+// Probe or Store of that position soon after does not wait on memory. Go
+// has no prefetch instruction; loading the first word brings in the whole
+// 64-byte cluster while the CPU carries on with the following work.
+func (tt *TranspositionTable) Prefetch(hash uint64) {
+	tt.cluster(hash).entries[0].keyData.Load()
+}
+
 // Probe looks up a position in the transposition table.
 // Returns the entry and true if found, otherwise returns empty entry and false.
 // Lock-free: uses atomic loads with XOR verification.
 func (tt *TranspositionTable) Probe(hash uint64) (TTEntry, bool) {
 	tt.probes.Add(1)
 
-	idx := hash & tt.mask
-	entry := &tt.entries[idx]
+	cluster := tt.cluster(hash)
+	for i := range cluster.entries {
+		entry := &cluster.entries[i]
 
-	// Atomic load of both values
-	keyData := entry.keyData.Load()
-	moveData := entry.moveData.Load()
+		// Atomic load of both values
+		keyData := entry.keyData.Load()
+		moveData := entry.moveData.Load()
 
-	// XOR verification: keyData should equal hash XOR moveData
-	// This detects torn reads where only one value was updated
-	if keyData != (hash ^ moveData) {
-		return TTEntry{}, false
-	}
+		// XOR verification: keyData should equal hash XOR moveData
+		// This detects torn reads where only one value was updated
+		if keyData != (hash ^ moveData) {
+			continue
+		}
+
+		// Unpack the data
+		move, score, depth, flag, isPV, age := unpackMoveData(moveData)
 
-	// Unpack the data
-	move, score, depth, flag, isPV, age := unpackMoveData(moveData)
+		// Verify we have valid data (empty slots unpack to DepthNone)
+		if depth <= DepthNone {
+			continue
+		}
 
-	// Verify we have valid data
-	if depth <= 0 {
-		return TTEntry{}, false
+		tt.hits.Add(1)
+		return TTEntry{
+			Key:      hash,
+			BestMove: move,
+			Score:    score,
+			Depth:    depth,
+			Flag:     flag,
+			Age:      age,
+			IsPV:     isPV,
+		}, true
 	}
 
-	tt.hits.Add(1)
-	return TTEntry{
-		Key:      hash,
-		BestMove: move,
-		Score:    score,
-		Depth:    depth,
-		Flag:     flag,
-		Age:      age,
-		IsPV:     isPV,
-	}, true
+	return TTEntry{}, false
 }
 
 // Store saves a position in the transposition table.
 // isPV indicates if this position was on the principal variation.
 // Lock-free: uses atomic stores with XOR encoding.
+//
+// Slot choice (Stockfish tt.cpp): an entry for the same position is updated in
+// place, unless it is from the current search and at least 4 plies deeper than
+// a new bound; an exact score always replaces it. Otherwise an empty slot is
+// used, or else the entry with the lowest depth - 8*relativeAge is replaced, so
+// stale entries from earlier searches are evicted before deep current ones.
 func (tt *TranspositionTable) Store(hash uint64, depth int, score int, flag TTFlag, bestMove board.Move, isPV bool) {
-	idx := hash & tt.mask
-	entry := &tt.entries[idx]
+	cluster := tt.cluster(hash)
 	currentAge := uint8(tt.age.Load())
 
-	// Read existing entry for replacement decision
-	existingKeyData := entry.keyData.Load()
-	existingMoveData := entry.moveData.Load()
+	// Clamp depth into the representable range
+	if depth > maxTTDepth {
+		depth = maxTTDepth
+	} else if depth <= DepthNone {
+		depth = DepthNone + 1
+	}
+
+	var replace *TTEntryPacked
+	replaceValue := 0
+	for i := range cluster.entries {
+		entry := &cluster.entries[i]
 
-	// Recover the stored hash using XOR: storedHash = keyData XOR moveData
-	existingKey := existingKeyData ^ existingMoveData
+		// Read existing entry for replacement decision
+		existingKeyData := entry.keyData.Load()
+		existingMoveData := entry.moveData.Load()
 
-	// Unpack existing entry data
-	_, _, existingDepth, existingFlag, existingIsPV, existingAge := unpackMoveData(existingMoveData)
+		// Recover the stored hash using XOR: storedHash = keyData XOR moveData
+		existingKey := existingKeyData ^ existingMoveData
 
-	// Check if existing entry is valid (has non-zero depth)
-	existingValid := existingDepth > 0
+		// Unpack existing entry data
+		existingMove, _, existingDepth, _, _, existingAge := unpackMoveData(existingMoveData)
 
-	// Calculate existing entry quality
-	var existingQuality int
-	if existingValid {
-		existingQuality = int(existingDepth) * 4
-		if existingAge == currentAge {
-			existingQuality += 256 // Current age bonus
+		// Empty slots unpack to DepthNone, and are used before any entry
+		if existingDepth <= DepthNone {
+			if replace == nil || replaceValue > math.MinInt {
+				replace = entry
+				replaceValue = math.MinInt
+			}
+			continue
 		}
-		if existingFlag == TTExact {
-			existingQuality += 2 // Exact score bonus
+
+		if existingKey == hash {
+			// Same position: keep a deeper current-search entry unless the new one is exact
+			if flag != TTExact && depth <= int(existingDepth)-4 && existingAge == currentAge {
+				return
+			}
+			// Keep the known best move if the new search has none
+			if bestMove == board.NoMove {
+				bestMove = existingMove
+			}
+			replace = entry
+			break
 		}
-		if existingIsPV {
-			existingQuality += 4 // PV bonus
+
+		// Lower value = better replacement candidate; age wraps, so use the 8-bit difference
+		relativeAge := int(currentAge - existingAge)
+		value := int(existingDepth) - 8*relativeAge
+		if replace == nil || value < replaceValue {
+			replace = entry
+			replaceValue = value
 		}
 	}
 
-	// Calculate new entry quality
-	newQuality := depth * 4
-	newQuality += 256 // New entry is always current age
-	if flag == TTExact {
-		newQuality += 2
-	}
-	if isPV {
-		newQuality += 4
-	}
+	// Pack and store atomically
+	newMoveData := packMoveData(bestMove, int16(score), int8(depth), flag, isPV, currentAge)
+	newKeyData := hash ^ newMoveData
 
-	// Replace if:
-	// 1. Same position (update with new info), OR
-	// 2. New entry has higher or equal quality, OR
-	// 3. Existing entry is invalid/empty
-	if existingKey == hash || newQuality >= existingQuality || !existingValid {
-		// Pack and store atomically
-		newMoveData := packMoveData(bestMove, int16(score), int8(depth), flag, isPV, currentAge)
-		newKeyData := hash ^ newMoveData
-
-		// Store in order: moveData first, then keyData
-		// This ensures that a reader seeing the new keyData will also see valid moveData
-		entry.moveData.Store(newMoveData)
-		entry.keyData.Store(newKeyData)
-	}
+	// Store in order: moveData first, then keyData
+	// This ensures that a reader seeing the new keyData will also see valid moveData
+	replace.moveData.Store(newMoveData)
+	replace.keyData.Store(newKeyData)
 }
 
 // NewSearch increments the age counter for a new search.
@@ -213,9 +265,11 @@ func (tt *TranspositionTable) NewSearch() {
 
 // Clear clears the transposition table.
 func (tt *TranspositionTable) Clear() {
-	for i := range tt.entries {
-		tt.entries[i].keyData.Store(0)
-		tt.entries[i].moveData.Store(0)
+	for c := range tt.clusters {
+		for i := range tt.clusters[c].entries {
+			tt.clusters[c].entries[i].keyData.Store(0)
+			tt.clusters[c].entries[i].moveData.Store(0)
+		}
 	}
 	tt.age.Store(0)
 	tt.hits.Store(0)
@@ -224,23 +278,25 @@ func (tt *TranspositionTable) Clear() {
 
 // HashFull returns the permille (parts per thousand) of the table that is used.
 func (tt *TranspositionTable) HashFull() int {
-	// Sample first 1000 entries
+	// Sample the first 1000 clusters, counting entries from the current search
 	used := 0
 	sampleSize := 1000
-	if uint64(sampleSize) > tt.size {
-		sampleSize = int(tt.size)
+	if uint64(sampleSize) > uint64(len(tt.clusters)) {
+		sampleSize = len(tt.clusters)
 	}
 
 	currentAge := uint8(tt.age.Load())
-	for i := 0; i < sampleSize; i++ {
-		moveData := tt.entries[i].moveData.Load()
-		_, _, depth, _, _, age := unpackMoveData(moveData)
-		if depth > 0 && age == currentAge {
-			used++
+	for c := 0; c < sampleSize; c++ {
+		for i := range tt.clusters[c].entries {
+			moveData := tt.clusters[c].entries[i].moveData.Load()
+			_, _, depth, _, _, age := unpackMoveData(moveData)
+			if depth > DepthNone && age == currentAge {
+				used++
+			}
 		}
 	}
 
-	return (used * 1000) / sampleSize
+	return (used * 1000) / (sampleSize * ttClusterSize)
 }
 
 // HitRate returns the cache hit rate as a percentage.
@@ -259,11 +315,21 @@ func (tt *TranspositionTable) Size() uint64 {
 
 // AdjustScore adjusts a score from/to the transposition table.
 // Mate scores need to be adjusted based on ply distance.
-func AdjustScoreFromTT(score int, ply int) int {
+// rule50 is the halfmove clock of the probing position. The key does not
+// include it, so a stored mate may come from the same position with a lower
+// clock: a mate that cannot be delivered before the 50-move rule draws is
+// downgraded to a win just outside the mate range (Stockfish value_from_tt).
+func AdjustScoreFromTT(score int, ply int, rule50 int) int {
 	if score > MateScore-MaxPly {
+		if score <= MateScore && MateScore-score > 100-rule50 {
+			return MateScore - MaxPly - 1
+		}
 		return score - ply
 	}
 	if score < -MateScore+MaxPly {
+		if score >= -MateScore && MateScore+score > 100-rule50 {
+			return -MateScore + MaxPly + 1
+		}
 		return score + ply
 	}
 	return score
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// TunableParam is a search or evaluation constant that can be changed at
+// runtime, e.g. by SPSA tuning through UCI options.
+// Parameters are shared by all engines in the process; change them only between searches.
+type TunableParam struct {
+	Name    string
+	Default int
+	Min     int
+	Max     int
+	Public  bool // Exposed as a UCI option in all builds, not only tune builds
+
+	get func() int
+	set func(int)
+}
+
+// Value returns the current value of the parameter.
+func (p *TunableParam) Value() int {
+	return p.get()
+}
+
+// tunableParams is the registry, in registration order
+var tunableParams []*TunableParam
+
+// tunable registers an integer variable as a tunable parameter.
+func tunable(name string, v *int, min, max int) *TunableParam {
+	return tunableFunc(name, func() int { return *v }, func(x int) { *v = x }, min, max)
+}
+
+// tunableFunc registers a parameter backed by a getter and setter.
+// The current value becomes the default.
+func tunableFunc(name string, get func() int, set func(int), min, max int) *TunableParam {
+	p := &TunableParam{Name: name, Default: get(), Min: min, Max: max, get: get, set: set}
+	tunableParams = append(tunableParams, p)
+	return p
+}
+
+func init() {
+	// LMR (already public UCI options, base coefficient scaled by 100)
+	tunableFunc("LMRBase",
+		func() int { return int(math.Round(lmrBase * 100)) },
+		func(v int) { SetLMRParams(float64(v)/100, lmrDivisor) },
+		500, 5000).Public = true
+	tunableFunc("LMRDivisor",
+		func() int { return int(lmrDivisor) },
+		func(v int) { SetLMRParams(lmrBase, float64(v)) },
+		256, 4096).Public = true
+	tunable("LMRPvOffset", &lmrPvOffset, -3, 3).Public = true
+	tunable("LMRNonPvOffset", &lmrNonPvOffset, -3, 3).Public = true
+
+	// Pruning margins
+	tunable("RazorBase", &razorBase, 100, 1000)
+	tunable("RazorDepthCoeff", &razorDepthCoeff, 50, 600)
+	tunable("RFPDepthMargin", &rfpDepthMargin, 20, 200)
+	tunable("RFPImprovingMargin", &rfpImprovingMargin, 0, 100)
+	for d := 1; d < len(futilityMargins); d++ {
+		tunable(fmt.Sprintf("FutilityMargin%d", d), &futilityMargins[d], 50, 1500)
+	}
+	tunable("SEEPruningCoeff", &seePruningCoeff, 0, 100)
+	tunable("ProbcutBetaMargin", &probcutBetaMargin, 50, 500)
+	tunable("ProbcutImproving", &probcutImproving, 0, 200)
+	tunable("QSFutilityMargin", &qsFutilityMargin, 50, 800)
+	tunable("QSDeltaMargin", &qsDeltaMargin, 50, 600)
+
+	// Shuffling detection (ShuffleStart 100 disables it)
+	tunable("ShuffleStart", &shuffleStart, 0, 100).Public = true
+	tunable("ShuffleRange", &shuffleRange, 1, 200).Public = true
+
+	// Passed pawns (bonus by relative rank, 2..7)
+	for r := 1; r <= 6; r++ {
+		tunable(fmt.Sprintf("PassedPawnRank%d", r+1), &passedPawnBonus[r], 0, 400)
+	}
+	tunable("PassedPawnConnected", &passedPawnConnectedBonus, 0, 100)
+	tunable("PassedPawnProtected", &passedPawnProtectedBonus, 0, 100)
+	tunable("PassedPawnFreePath", &passedPawnFreePathBonus, 0, 100)
+}
+
+// TunableParams returns all tunable parameters in registration order.
+func TunableParams() []*TunableParam {
+	return tunableParams
+}
+
+// FindTunableParam returns the parameter with the given name (case-insensitive), or nil.
+func FindTunableParam(name string) *TunableParam {
+	for _, p := range tunableParams {
+		if strings.EqualFold(p.Name, name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// SetTunableParam sets a parameter, rejecting unknown names and out-of-range values.
+func SetTunableParam(name string, value int) error {
+	p := FindTunableParam(name)
+	if p == nil {
+		return fmt.Errorf("unknown parameter %q", name)
+	}
+	if value < p.Min || value > p.Max {
+		return fmt.Errorf("%s: value %d out of range [%d, %d]", p.Name, value, p.Min, p.Max)
+	}
+	p.set(value)
+	return nil
+}
+
+// ResetTunableParams restores every parameter to its default.
+func ResetTunableParams() {
+	for _, p := range tunableParams {
+		p.set(p.Default)
+	}
+}
+
+// SaveTunableParams writes the current parameter set as a JSON object of name to value.
+func SaveTunableParams(path string) error {
+	values := make(map[string]int, len(tunableParams))
+	for _, p := range tunableParams {
+		values[p.Name] = p.Value()
+	}
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadTunableParams reads a parameter set written by SaveTunableParams.
+// Parameters missing from the file keep their values; the file is validated
+// completely before anything is changed.
+func LoadTunableParams(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var values map[string]int
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	names := make([]string, 0, len(values))
+	for name, v := range values {
+		p := FindTunableParam(name)
+		if p == nil {
+			return fmt.Errorf("%s: unknown parameter %q", path, name)
+		}
+		if v < p.Min || v > p.Max {
+			return fmt.Errorf("%s: %s value %d out of range [%d, %d]", path, p.Name, v, p.Min, p.Max)
+		}
+		names = append(names, name)
+	}
+
+	// Apply in a fixed order, so LMR table regeneration is deterministic
+	sort.Strings(names)
+	for _, name := range names {
+		FindTunableParam(name).set(values[name])
+	}
+	return nil
+}
+
+// SPSAString returns the parameters in the OpenBench SPSA input format:
+// name, int, value, min, max, step, learning rate.
+func SPSAString() string {
+	var sb strings.Builder
+	for _, p := range tunableParams {
+		step := max((p.Max-p.Min)/20, 1)
+		fmt.Fprintf(&sb, "%s, int, %d, %d, %d, %d, 0.002\n", p.Name, p.Value(), p.Min, p.Max, step)
+	}
+	return sb.String()
+}
@@ -0,0 +1,7 @@
+//go:build !tune
+
+package engine
+
+// TuneEnabled is false in regular builds; only the public tunable parameters
+// (LMR) are UCI options.
+const TuneEnabled = false
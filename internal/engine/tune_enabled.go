@@ -0,0 +1,7 @@
+//go:build tune
+
+package engine
+
+// TuneEnabled is true in builds with the "tune" tag, which expose every
+// tunable parameter as a UCI option (go build -tags tune).
+const TuneEnabled = true
@@ -0,0 +1,63 @@
+package engine
+
+import "github.com/hailam/chessplay/internal/board"
+
+// votingOffset is added to each worker's score above the lowest one, so the
+// worst worker still votes (Stockfish thread.cpp).
+const votingOffset = 14
+
+// voteBestResult picks the move to play from the last completed iteration
+// of each worker, Stockfish-style. Each worker votes for its move with a
+// weight that grows with its depth and with its score above the lowest
+// score; the move with the most votes wins, and among the workers playing
+// it the one with the best weight reports the score and PV. This keeps a
+// helper that fails high on an unstable move at its last iteration from
+// overruling a move most workers agree on. Proven mates are taken as they
+// are: the fastest mate wins, and a move proven lost is never preferred.
+// results is indexed by worker, the main worker first; workers that
+// completed no iteration have NoMove.
+func voteBestResult(results []WorkerResult) WorkerResult {
+	minScore := Infinity
+	for _, r := range results {
+		if r.Move != board.NoMove {
+			minScore = min(minScore, r.Score)
+		}
+	}
+	weight := func(r WorkerResult) int {
+		return (r.Score - minScore + votingOffset) * r.Depth
+	}
+	votes := make(map[board.Move]int, len(results))
+	for _, r := range results {
+		if r.Move != board.NoMove {
+			votes[r.Move] += weight(r)
+		}
+	}
+
+	best := -1
+	for i, r := range results {
+		if r.Move == board.NoMove {
+			continue
+		}
+		if best < 0 {
+			best = i
+			continue
+		}
+		b := results[best]
+		switch {
+		case abs(b.Score) >= MateScore-MaxPly:
+			// A proven result is only replaced by a better one
+			if r.Score > b.Score {
+				best = i
+			}
+		case r.Score >= MateScore-MaxPly:
+			best = i
+		case r.Score > -MateScore+MaxPly &&
+			(votes[r.Move] > votes[b.Move] || votes[r.Move] == votes[b.Move] && weight(r) > weight(b)):
+			best = i
+		}
+	}
+	if best < 0 {
+		return WorkerResult{}
+	}
+	return results[best]
+}
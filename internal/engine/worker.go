@@ -14,15 +14,46 @@ import (
 // Based on Stockfish's formula: 21.46 * log(depth) * log(moveCount) / 1024
 var lmrReductions [64][64]int
 
+// LMR tuning parameters (shared by all workers, change only between searches)
+var (
+	lmrBase        = 21.46  // Base table coefficient
+	lmrDivisor     = 1024.0 // Base table divisor
+	lmrPvOffset    = 0      // Extra reduction (plies) at PV nodes
+	lmrNonPvOffset = 0      // Extra reduction (plies) at non-PV nodes
+)
+
 func init() {
+	initLMRTable()
+}
+
+// initLMRTable (re)generates the LMR reduction table from lmrBase and lmrDivisor.
+func initLMRTable() {
 	for d := 1; d < 64; d++ {
 		for m := 1; m < 64; m++ {
 			// Stockfish-like formula
-			lmrReductions[d][m] = int(21.46 * math.Log(float64(d)) * math.Log(float64(m)) / 1024.0)
+			lmrReductions[d][m] = int(lmrBase * math.Log(float64(d)) * math.Log(float64(m)) / lmrDivisor)
 		}
 	}
 }
 
+// SetLMRParams sets the LMR table coefficients and regenerates the table.
+// Affects all engines in the process; must not be called during a search.
+func SetLMRParams(base, divisor float64) {
+	if divisor <= 0 {
+		return
+	}
+	lmrBase = base
+	lmrDivisor = divisor
+	initLMRTable()
+}
+
+// SetLMROffsets sets the extra reduction applied at PV and non-PV nodes.
+// Affects all engines in the process; must not be called during a search.
+func SetLMROffsets(pv, nonPV int) {
+	lmrPvOffset = pv
+	lmrNonPvOffset = nonPV
+}
+
 // SearchStack stores per-ply search state for continuation history tracking.
 // Ported from Stockfish's Stack structure.
 type SearchStack struct {
@@ -47,6 +78,12 @@ type SearchStack struct {
 
 	// Count of beta cutoffs at this ply (for LMR scaling)
 	cutoffCnt int
+
+	// Extension budget used on the path from the root to this ply, and the
+	// extension given to currentMove (see limitExtension)
+	extensions      int // Plies extended
+	multiExtensions int // Double and triple extensions
+	extension       int
 }
 
 // Worker represents a search worker for parallel Lazy SMP search.
@@ -75,17 +112,36 @@ type Worker struct {
 	posHistoryBuffer [768]uint64
 	posHistoryLen    int
 	rootPosHashes    []uint64
+	rootHistoryIdx   int // Index of the root position in posHistoryBuffer
+	nullHistoryIdx   int // Index of the position after the last null move on the path (0 = none)
 
 	// Multi-PV support: moves to exclude at root
 	excludedRootMoves []board.Move
 
+	// UCI searchmoves: only these moves are searched at root (empty = all)
+	allowedRootMoves []board.Move
+
+	// Root moves scored discouragePenalty lower (see Engine.AvoidMoves)
+	discouragedRootMoves []board.Move
+	discouragePenalty    int
+
+	// Nodes searched under each root move this search, by from and to square
+	rootEffort [64][64]uint64
+
 	// Shared resources (pointers to engine's shared state)
 	tt            *TranspositionTable
 	pawnTable     *PawnTable
 	sharedHistory *SharedHistory    // Shared history for Lazy SMP
 	corrHistory   *CorrectionHistory // Correction history for eval adjustment
+	evalCache     *EvalCache         // Static evaluations; nil to evaluate every time
 	stopFlag      *atomic.Bool
 
+	// Search heuristics enabled for this search (copied from the engine between searches)
+	features SearchFeatures
+
+	// Statistics of this search, nil when not collected
+	stats *SearchStats
+
 	// NNUE evaluation (per-worker for thread safety)
 	useNNUE  bool
 	nnueNet  *sfnnue.Networks
@@ -111,20 +167,48 @@ type Worker struct {
 	// Current search depth (for result reporting)
 	depth int
 
+	// Selective depth: deepest ply reached in this iteration, including quiescence
+	selDepth int
+
+	// Node count, depth and selective depth published for reports read while
+	// the worker searches. Nodes are published every nodeFlushInterval nodes
+	// and when SearchDepth returns.
+	publishedNodes    atomic.Uint64
+	publishedDepth    atomic.Int32
+	publishedSelDepth atomic.Int32
+
+	// Root move progress callback (UCI currmove/currmovenumber), main worker only
+	onCurrMove func(depth int, move board.Move, moveNumber int)
+
 	// Optimism tracking (Stockfish evaluate.cpp)
 	// Used for material scaling: includes optimism term based on running average of root scores
 	optimism [2]int // Per-side optimism: [White=0, Black=1]
 	avgScore int    // Running average of root move score (initialized to -Infinity)
 
-	// Root delta for LMR scaling (Stockfish search.cpp)
-	// Width of the initial aspiration window at root, used to scale reductions
+	// Contempt: draws cost the root side this many centipawns (see drawScore)
+	contempt  int
+	rootColor board.Color
+
+	// Root delta for LMR scaling (Stockfish search.cpp:354)
+	// Width of the current root window (beta - alpha), set by SearchDepth on every
+	// aspiration (re-)search so widened windows are reflected in reductions
 	rootDelta int
 
 	// NMP verification: minimum ply where NMP is allowed (Stockfish search.cpp:892-925)
 	// When set > 0, NMP is disabled until ply exceeds this value
 	nmpMinPly int
+
+	// Node limit enforcement ("go nodes")
+	// Local node counts are flushed into the shared counter every nodeFlushInterval nodes
+	nodeCounter  *atomic.Uint64 // Shared node counter across all workers (nil = not tracked)
+	nodeLimit    uint64         // Global node limit (0 = no limit)
+	nodesFlushed uint64         // Local nodes already added to nodeCounter
 }
 
+// nodeFlushInterval is how often (in nodes) a worker publishes its node count
+// and checks the global node limit. Must be a power of 2.
+const nodeFlushInterval = 1024
+
 // WorkerResult contains the result from a worker's search at a given depth.
 type WorkerResult struct {
 	WorkerID int
@@ -133,6 +217,7 @@ type WorkerResult struct {
 	Move     board.Move
 	PV       []board.Move
 	Nodes    uint64
+	SelDepth int
 }
 
 // NewWorker creates a new search worker.
@@ -145,6 +230,7 @@ func NewWorker(id int, tt *TranspositionTable, pawnTable *PawnTable, sharedHisto
 		sharedHistory: sharedHistory,
 		corrHistory:   NewCorrectionHistory(),
 		stopFlag:      stopFlag,
+		features:      DefaultSearchFeatures,
 	}
 }
 
@@ -168,14 +254,48 @@ func (w *Worker) ID() int {
 	return w.id
 }
 
-// Nodes returns the number of nodes searched by this worker.
+// Nodes returns the number of nodes searched by this worker. It is safe to
+// call while the worker searches.
 func (w *Worker) Nodes() uint64 {
-	return w.nodes
+	return w.publishedNodes.Load()
+}
+
+// Depth returns the depth of the current iteration. It is safe to call
+// while the worker searches.
+func (w *Worker) Depth() int {
+	return int(w.publishedDepth.Load())
+}
+
+// SelDepth returns the selective depth reached in the current iteration. It
+// is safe to call while the worker searches.
+func (w *Worker) SelDepth() int {
+	return int(w.publishedSelDepth.Load())
+}
+
+// resetSelDepth starts the selective depth of a new iteration.
+func (w *Worker) resetSelDepth() {
+	w.selDepth = 0
+	w.publishedSelDepth.Store(0)
+}
+
+// updateSelDepth records that the search reached ply.
+func (w *Worker) updateSelDepth(ply int) {
+	if ply >= w.selDepth {
+		w.selDepth = ply + 1
+		w.publishedSelDepth.Store(int32(w.selDepth))
+	}
+}
+
+// SetCurrMoveCallback sets the callback invoked as each root move is searched.
+func (w *Worker) SetCurrMoveCallback(fn func(depth int, move board.Move, moveNumber int)) {
+	w.onCurrMove = fn
 }
 
 // Reset resets the worker for a new search.
 func (w *Worker) Reset() {
 	w.nodes = 0
+	w.nodesFlushed = 0
+	w.publishedNodes.Store(0)
 	w.orderer.Clear()
 	// Reset optimism tracking for new search
 	w.avgScore = -Infinity // Will be set to first score
@@ -183,6 +303,31 @@ func (w *Worker) Reset() {
 	w.optimism[1] = 0
 }
 
+// SetNodeLimit sets the shared node counter and the global node limit for the next search.
+// A limit of 0 disables node limiting but still publishes node counts.
+func (w *Worker) SetNodeLimit(counter *atomic.Uint64, limit uint64) {
+	w.nodeCounter = counter
+	w.nodeLimit = limit
+}
+
+// countNode increments the local node counter and periodically publishes it to the
+// shared counter, raising the stop flag once the global node limit is reached.
+func (w *Worker) countNode() {
+	w.nodes++
+	if w.nodes&(nodeFlushInterval-1) != 0 {
+		return
+	}
+	w.publishedNodes.Store(w.nodes)
+	if w.nodeCounter == nil {
+		return
+	}
+	total := w.nodeCounter.Add(w.nodes - w.nodesFlushed)
+	w.nodesFlushed = w.nodes
+	if w.nodeLimit > 0 && total >= w.nodeLimit {
+		w.stopFlag.Store(true)
+	}
+}
+
 // UpdateOptimism calculates optimism for the current iteration based on avgScore.
 // Should be called before each depth in iterative deepening.
 // Ported from Stockfish search.cpp iterative deepening loop.
@@ -236,6 +381,19 @@ func (w *Worker) SetExcludedMoves(moves []board.Move) {
 	w.excludedRootMoves = moves
 }
 
+// SetSearchMoves restricts the root to the given moves (UCI searchmoves).
+// A nil or empty list allows every legal move.
+func (w *Worker) SetSearchMoves(moves []board.Move) {
+	w.allowedRootMoves = moves
+}
+
+// SetDiscouragedMoves makes the given root moves score penalty centipawns
+// lower, unless they mate or are mated.
+func (w *Worker) SetDiscouragedMoves(moves []board.Move, penalty int) {
+	w.discouragedRootMoves = moves
+	w.discouragePenalty = penalty
+}
+
 // InitSearch initializes the worker for a new search.
 // IMPORTANT: pos must be a dedicated copy for this worker (not shared with other goroutines).
 // The caller (engine.workerSearch) is responsible for providing an isolated copy.
@@ -257,9 +415,16 @@ func (w *Worker) InitSearch(pos *board.Position) {
 	} else {
 		copy(w.posHistoryBuffer[:rootLen], w.rootPosHashes)
 	}
-	// Add current position hash
-	w.posHistoryBuffer[rootLen] = w.pos.Hash
-	w.posHistoryLen = rootLen + 1
+	// Add current position hash, unless the history already ends with it
+	w.posHistoryLen = rootLen
+	if rootLen == 0 || w.posHistoryBuffer[rootLen-1] != w.pos.Hash {
+		w.posHistoryBuffer[rootLen] = w.pos.Hash
+		w.posHistoryLen++
+	}
+	w.rootHistoryIdx = w.posHistoryLen - 1
+	w.nullHistoryIdx = 0
+	w.rootEffort = [64][64]uint64{}
+	w.rootColor = pos.SideToMove
 }
 
 // Pos returns the current position (for debugging).
@@ -270,18 +435,18 @@ func (w *Worker) Pos() *board.Position {
 // SearchDepth performs search at the given depth and sends result via channel.
 func (w *Worker) SearchDepth(depth, alpha, beta int) (board.Move, int) {
 	w.depth = depth
+	w.publishedDepth.Store(int32(depth))
+	w.rootDelta = beta - alpha
 
-	// DEBUG: Verify King exists at root
+	// DEBUG: Verify the root position
 	if board.DebugMoveValidation {
-		if w.pos.Pieces[board.White][board.King] == 0 {
-			log.Printf("ROOT: White King MISSING at root! depth=%d hash=%x", depth, w.pos.Hash)
-		}
-		if w.pos.Pieces[board.Black][board.King] == 0 {
-			log.Printf("ROOT: Black King MISSING at root! depth=%d hash=%x", depth, w.pos.Hash)
+		if err := w.pos.Validate(); err != nil {
+			log.Printf("ROOT CORRUPT: depth=%d hash=%x: %v", depth, w.pos.Hash, err)
 		}
 	}
 
 	score := w.negamax(depth, 0, alpha, beta, board.NoMove, board.NoMove, false, true)
+	w.publishedNodes.Store(w.nodes)
 
 	var bestMove board.Move
 	if w.pv.length[0] > 0 {
@@ -315,12 +480,105 @@ func (w *Worker) SearchDepth(depth, alpha, beta int) (board.Move, int) {
 	return bestMove, score
 }
 
+// aspirationSearch searches the root at depth with an aspiration window
+// around the previous iteration's score (Stockfish search.cpp). The window
+// starts aspirationDelta wide on each side and widens by a quarter plus two
+// after every fail, toward the failing side. After a fail high the search is
+// repeated one ply shallower, down to depth-3: the score is already known
+// to be good and a shallower search confirms it faster.
+func (w *Worker) aspirationSearch(depth, prevScore int) (board.Move, int) {
+	delta := aspirationDelta
+	alpha := max(prevScore-delta, -Infinity)
+	beta := min(prevScore+delta, Infinity)
+	failedHigh := 0
+
+	// rootDelta is taken from the window on every (re-)search in SearchDepth
+	for {
+		move, score := w.SearchDepth(max(depth-failedHigh, 1), alpha, beta)
+		if w.stopped() {
+			return move, score
+		}
+
+		switch {
+		case score <= alpha:
+			beta = (alpha + beta) / 2
+			alpha = max(score-delta, -Infinity)
+			failedHigh = 0
+		case score >= beta:
+			beta = min(score+delta, Infinity)
+			failedHigh = min(failedHigh+1, 3)
+		default:
+			return move, score
+		}
+		delta += delta/4 + 2
+	}
+}
+
 // evaluate returns the static evaluation using cached pawn structure or NNUE.
 func (w *Worker) evaluate() int {
-	if w.useNNUE && w.nnueNet != nil {
-		return w.nnueEvaluate()
+	nnue := w.useNNUE && w.nnueNet != nil && w.nnueAcc != nil
+	eval, cached := 0, false
+	if w.evalCache != nil {
+		eval, cached = w.evalCache.Probe(w.pos.Hash)
+		if w.stats != nil {
+			w.stats.EvalProbes++
+			if cached {
+				w.stats.EvalHits++
+			}
+		}
+	}
+	if cached && nnue {
+		w.updateAccumulators()
 	}
-	return EvaluateWithPawnTable(w.pos, w.pawnTable)
+	if !cached {
+		if nnue {
+			eval = w.nnueNetworkEvaluate()
+		} else {
+			eval = EvaluateWithPawnTable(w.pos, w.pawnTable)
+		}
+		if w.evalCache != nil {
+			w.evalCache.Store(w.pos.Hash, eval)
+		}
+	}
+
+	// NNUE scales by the halfmove clock in nnueAdjust; the fade is for the
+	// classical evaluation, which does not look at the clock. Either way the
+	// search scores that come from these evaluations shrink toward zero as
+	// the clock nears 100, without scaling them again.
+	if nnue {
+		eval = w.nnueAdjust(eval)
+	} else if w.features.Has(FeatureShuffleDamp) {
+		eval = shuffleDamp(eval, int(w.pos.HalfMoveClock))
+	}
+	return eval
+}
+
+// staticEval returns the evaluation of pos as a new search would see it at
+// the root, refreshing the accumulators for it (see Engine.EvaluateBatch).
+// pos is only read.
+func (w *Worker) staticEval(pos *board.Position) int {
+	w.pos = pos
+	w.optimism = [2]int{}
+	if w.nnueAcc != nil {
+		w.nnueAcc.Reset()
+	}
+	return w.evaluate()
+}
+
+// shuffleDamp fades an evaluation toward zero when no capture or pawn move
+// has been made for a while. An advantage that is not being converted is
+// worth less the closer the 50-move rule gets, so lines that make progress
+// (pawn moves, captures, simplification toward tablebase positions) score
+// better than lines that shuffle in a fortress.
+func shuffleDamp(eval, halfMoveClock int) int {
+	excess := halfMoveClock - shuffleStart
+	if excess <= 0 {
+		return eval
+	}
+	if excess >= shuffleRange {
+		return 0
+	}
+	return eval * (shuffleRange - excess) / shuffleRange
 }
 
 // stopped returns true if search should stop.
@@ -347,7 +605,33 @@ func (w *Worker) isExcludedRootMove(move board.Move) bool {
 	return false
 }
 
-// isDraw checks for draw by repetition or 50-move rule.
+// isAllowedRootMove checks if a move passes the searchmoves restriction.
+func (w *Worker) isAllowedRootMove(move board.Move) bool {
+	if len(w.allowedRootMoves) == 0 {
+		return true
+	}
+	for _, allowed := range w.allowedRootMoves {
+		if move == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// isDiscouragedRootMove checks if a move is in the discouraged list.
+func (w *Worker) isDiscouragedRootMove(move board.Move) bool {
+	for _, m := range w.discouragedRootMoves {
+		if move == m {
+			return true
+		}
+	}
+	return false
+}
+
+// isDraw checks for draw by repetition or 50-move rule. A position repeated
+// once inside the search is a draw, as the side that could avoid it will not
+// do better by playing it a third time; one whose earlier occurrences are all
+// at or before the root needs the full threefold repetition.
 func (w *Worker) isDraw() bool {
 	// 50-move rule
 	if w.pos.HalfMoveClock >= 100 {
@@ -359,16 +643,19 @@ func (w *Worker) isDraw() bool {
 		return true
 	}
 
-	// Threefold repetition (use pre-allocated buffer)
-	if w.posHistoryLen > 0 {
-		currentHash := w.pos.Hash
-		count := 0
-		for i := 0; i < w.posHistoryLen; i++ {
-			if w.posHistoryBuffer[i] == currentHash {
-				count++
-				if count >= 2 {
-					return true
-				}
+	// Repetition: only positions since the last capture, pawn move or null
+	// move can recur, and only every other ply with the same side to move
+	cur := w.posHistoryLen - 1
+	first := w.repetitionStart()
+	count := 0
+	for i := cur - 4; i >= first; i -= 2 {
+		if w.posHistoryBuffer[i] == w.pos.Hash {
+			if i > w.rootHistoryIdx {
+				return true
+			}
+			count++
+			if count >= 2 {
+				return true
 			}
 		}
 	}
@@ -376,6 +663,55 @@ func (w *Worker) isDraw() bool {
 	return false
 }
 
+// drawScore returns the score of a draw for the side to move. Without
+// contempt draws are 0. With it the root side scores them below 0, by the
+// contempt plus a dynamic part following its optimism: the better the
+// search has been going for it, the more a draw costs, and the worse, the
+// more welcome a draw becomes. The opponent scores draws the other way.
+func (w *Worker) drawScore() int {
+	if w.contempt == 0 {
+		return 0
+	}
+	c := w.contempt + w.optimism[w.rootColor]/dynamicContemptDiv
+	if w.pos.SideToMove == w.rootColor {
+		return -c
+	}
+	return c
+}
+
+// repetitionStart returns the index of the earliest position in the history
+// that the current one can repeat.
+func (w *Worker) repetitionStart() int {
+	return max(max(w.posHistoryLen-1-w.pos.HalfMoveClock, w.nullHistoryIdx), 0)
+}
+
+// canExtend returns true if a node at ply may be extended at all. No
+// extension is given past twice the root depth (Stockfish search.cpp:1105),
+// or long check sequences keep the depth from ever decreasing.
+func (w *Worker) canExtend(ply int) bool {
+	return ply < 2*w.depth
+}
+
+// limitExtension returns the extension a move at ply may have within the
+// path's extension budget. A single ply is always allowed, as canExtend
+// already stops those at twice the root depth; cutting check extensions
+// short leaves in-check nodes to quiescence and costs far more nodes than it
+// saves. Reductions (negative extensions) are not limited.
+func (w *Worker) limitExtension(ply, extension int) int {
+	if extension <= 1 {
+		return extension
+	}
+	ss := &w.searchStack[ply]
+	limited := max(min(extension, w.depth-ss.extensions), 1)
+	if ss.multiExtensions >= maxMultiExtensions {
+		limited = 1
+	}
+	if limited < extension && w.stats != nil {
+		w.stats.ExtensionsLimited++
+	}
+	return limited
+}
+
 // negamax implements the negamax algorithm with alpha-beta pruning.
 // excludedMove is used for singular extension search - if not NoMove, this move will be skipped.
 // cutNode indicates expected node type: true if we expect a beta cutoff (most children are cut-nodes).
@@ -392,43 +728,53 @@ func (w *Worker) negamax(depth, ply int, alpha, beta int, prevMove, excludedMove
 		return 0
 	}
 
-	w.nodes++
+	w.countNode()
+	w.updateSelDepth(ply)
+	if w.stats != nil {
+		w.stats.Nodes++
+	}
 
 	// DEBUG: Comprehensive position validation at EVERY ply
 	if board.DebugMoveValidation {
-		us := w.pos.SideToMove
-		// Check that pieces for "us" are ACTUALLY in Occupied[us]
-		for pt := board.Pawn; pt <= board.King; pt++ {
-			pieceBB := w.pos.Pieces[us][pt]
-			if pieceBB&^w.pos.Occupied[us] != 0 {
-				log.Printf("NEGAMAX ENTRY CORRUPT: %v %v pieces not in Occupied[%v]! ply=%d depth=%d hash=%x prevMove=%v",
-					us, pt, us, ply, depth, w.pos.Hash, prevMove)
-				log.Printf("  PieceBB=%x Occupied[%v]=%x Diff=%x",
-					pieceBB, us, w.pos.Occupied[us], pieceBB&^w.pos.Occupied[us])
-			}
-		}
-		// Check that Occupied[us] matches sum of our pieces
-		var ourSum board.Bitboard
-		for pt := board.Pawn; pt <= board.King; pt++ {
-			ourSum |= w.pos.Pieces[us][pt]
-		}
-		if ourSum != w.pos.Occupied[us] {
-			log.Printf("NEGAMAX ENTRY CORRUPT: %v Occupied mismatch! ply=%d depth=%d hash=%x prevMove=%v",
-				us, ply, depth, w.pos.Hash, prevMove)
-			log.Printf("  Sum=%x Occupied=%x", ourSum, w.pos.Occupied[us])
+		if err := w.pos.Validate(); err != nil {
+			log.Printf("NEGAMAX ENTRY CORRUPT: ply=%d depth=%d hash=%x prevMove=%v: %v",
+				ply, depth, w.pos.Hash, prevMove, err)
 		}
 	}
 
 	// Initialize PV length for this ply
 	w.pv.length[ply] = ply
 
+	// Extensions used on the path here; null moves and probcut captures
+	// are not extended
+	ss := &w.searchStack[ply]
+	ss.extensions, ss.multiExtensions, ss.extension = 0, 0, 0
+	if ply > 0 {
+		parent := &w.searchStack[ply-1]
+		ss.extensions = parent.extensions + parent.extension
+		ss.multiExtensions = parent.multiExtensions
+		if parent.extension > 1 {
+			ss.multiExtensions++
+		}
+	}
+
 	// Check for draw
 	if ply > 0 && w.isDraw() {
-		return 0
+		return w.drawScore()
 	}
 
-	// Tablebase probing (only in endgame positions)
-	if ply > 0 && w.tbProber != nil && depth >= w.tbProbeDepth {
+	// A side that can repeat a position can at least draw
+	if ply > 0 && alpha < w.drawScore() && w.upcomingRepetition() {
+		alpha = w.drawScore()
+		if alpha >= beta {
+			return alpha
+		}
+	}
+
+	// Tablebase probing (only in endgame positions). Right after a capture or
+	// pawn move the probe depth is ignored: that is where an ending converts,
+	// so a high probe depth saving probes still sees the simplification.
+	if ply > 0 && w.tbProber != nil && (depth >= w.tbProbeDepth || w.pos.HalfMoveClock == 0) {
 		pieceCount := tablebase.CountPieces(w.pos)
 		if pieceCount <= w.tbProber.MaxPieces() {
 			tbResult := w.tbProber.Probe(w.pos)
@@ -469,31 +815,36 @@ func (w *Worker) negamax(depth, ply int, alpha, beta int, prevMove, excludedMove
 		}
 	}
 
+	// Quiescence search at depth 0. It probes the TT itself, so the entry
+	// is not probed and its move validated twice.
+	if depth <= 0 {
+		return w.quiescence(ply, alpha, beta)
+	}
+
 	// Probe transposition table
 	var ttMove board.Move
 	ttPv := false // Track if TT indicates this is a PV node
 	ttEntry, found := w.tt.Probe(w.pos.Hash)
+	if w.stats != nil {
+		w.stats.TTProbes++
+		if found {
+			w.stats.TTHits++
+		}
+	}
 	if found {
-		ttMove = ttEntry.BestMove
 		ttPv = ttEntry.IsPV
 
-		// Validate TT move immediately (like Stockfish's movepick.cpp)
-		// TT moves can be corrupted due to hash collisions or race conditions
-		if ttMove != board.NoMove && !w.pos.PseudoLegal(ttMove) {
-			ttMove = board.NoMove
-		}
-
-		// Multi-PV: don't use TT cutoffs at root if TT move is excluded
-		ttCutoffAllowed := ply > 0 || !w.isExcludedRootMove(ttMove)
+		// Never use TT bounds at the root: narrowing the root window from a stored
+		// bound can make every root move fail low, leaving no PV or best move
+		// (this also keeps excluded Multi-PV moves from being returned).
+		// Near the 50-move rule the stored score may come from a lower clock,
+		// where the draw was out of reach, so it is not trusted either.
+		ttCutoffAllowed := ply > 0 && int(w.pos.HalfMoveClock) < ttRule50Limit
 
 		if int(ttEntry.Depth) >= depth && ttCutoffAllowed {
-			score := AdjustScoreFromTT(int(ttEntry.Score), ply)
+			score := AdjustScoreFromTT(int(ttEntry.Score), ply, int(w.pos.HalfMoveClock))
 			switch ttEntry.Flag {
 			case TTExact:
-				if ply == 0 && ttMove != board.NoMove {
-					w.pv.moves[0][0] = ttMove
-					w.pv.length[0] = 1
-				}
 				return score
 			case TTLowerBound:
 				if score > alpha {
@@ -505,18 +856,15 @@ func (w *Worker) negamax(depth, ply int, alpha, beta int, prevMove, excludedMove
 				}
 			}
 			if alpha >= beta {
-				if ply == 0 && ttMove != board.NoMove {
-					w.pv.moves[0][0] = ttMove
-					w.pv.length[0] = 1
-				}
 				return score
 			}
 		}
-	}
 
-	// Quiescence search at depth 0
-	if depth <= 0 {
-		return w.quiescence(ply, alpha, beta)
+		// Validate the TT move once there was no cutoff (like Stockfish's movepick.cpp)
+		// TT moves can be corrupted due to hash collisions or race conditions
+		if ttEntry.BestMove != board.NoMove && w.pos.PseudoLegal(ttEntry.BestMove) {
+			ttMove = ttEntry.BestMove
+		}
 	}
 
 	// Check if in check
@@ -529,14 +877,16 @@ func (w *Worker) negamax(depth, ply int, alpha, beta int, prevMove, excludedMove
 		depth -= 2
 	}
 
+	canExtend := w.canExtend(ply)
+
 	// Check extension
 	extension := 0
-	if inCheck {
+	if inCheck && canExtend {
 		extension = 1
 	}
 
 	// Threat extension
-	if EnableThreatExt && extension == 0 && depth >= threatExtensionMinDepth && ply > 0 {
+	if w.features.Has(FeatureThreatExt) && canExtend && extension == 0 && depth >= threatExtensionMinDepth && ply > 0 {
 		if w.detectSeriousThreats() {
 			extension = 1
 		}
@@ -545,7 +895,13 @@ func (w *Worker) negamax(depth, ply int, alpha, beta int, prevMove, excludedMove
 	// Static evaluation for pruning decisions
 	rawEval := w.evaluate()
 	// Apply correction history adjustment
-	correction := w.corrHistory.Get(w.pos)
+	// The continuation table is keyed by the previous move (none at root or after a null move)
+	corrPrevPiece, corrPrevTo := board.NoPiece, board.NoSquare
+	if prevMove != board.NoMove && ply >= 1 {
+		corrPrevPiece = w.searchStack[ply-1].movedPiece
+		corrPrevTo = w.searchStack[ply-1].moveTo
+	}
+	correction := w.corrHistory.Get(w.pos, corrPrevPiece, corrPrevTo)
 	staticEval := rawEval + correction
 	w.evalStack[ply] = staticEval
 
@@ -564,10 +920,10 @@ func (w *Worker) negamax(depth, ply int, alpha, beta int, prevMove, excludedMove
 
 	// Hindsight depth adjustment (Stockfish search.cpp:754-757)
 	// Adjust depth based on how the previous ply's LMR prediction turned out
-	if EnableHindsightDepth && ply >= 1 {
+	if w.features.Has(FeatureHindsightDepth) && ply >= 1 {
 		priorReduction := w.searchStack[ply-1].reduction
 		// If we reduced a lot and opponent isn't getting worse, search deeper
-		if priorReduction >= 3 && !opponentWorsening {
+		if priorReduction >= 3 && !opponentWorsening && canExtend {
 			depth++
 		}
 		// If we reduced and position eval sum suggests stability, search shallower
@@ -585,25 +941,34 @@ func (w *Worker) negamax(depth, ply int, alpha, beta int, prevMove, excludedMove
 	}
 
 	// Reverse Futility Pruning
-	// Never prune at PV nodes (pvNode)
-	if EnableRFP && !inCheck && depth <= 6 && ply > 0 && !pvNode {
-		rfpMargin := 80 * depth
+	// Never prune at PV nodes (pvNode), nor with mate scores, which the
+	// static evaluation cannot prove
+	if w.features.Has(FeatureRFP) && !inCheck && depth <= 6 && ply > 0 && !pvNode &&
+		staticEval < MateScore-MaxPly && beta > -MateScore+MaxPly {
+		rfpMargin := rfpDepthMargin * depth
 		if !improving {
-			rfpMargin -= 20
+			rfpMargin -= rfpImprovingMargin
 		}
 		if staticEval-rfpMargin >= beta {
-			return beta
+			if w.stats != nil {
+				w.stats.RFPPrunes++
+			}
+			// Fail soft, halfway to the evaluation (Stockfish search.cpp:866)
+			return (staticEval + beta) / 2
 		}
 	}
 
 	// Razoring (Stockfish search.cpp:873)
-	// Use quadratic formula: 485 + 281*depth*depth (much more aggressive)
+	// Use quadratic formula: 485 + 281*depth*depth by default (much more aggressive)
 	// CRITICAL: Never razor at PV nodes (must use pvNode, NOT ttPv)
-	if EnableRazoring && depth <= 5 && !inCheck && ply > 0 && !pvNode {
-		razorMargin := 485 + 281*depth*depth
+	if w.features.Has(FeatureRazoring) && depth <= 5 && !inCheck && ply > 0 && !pvNode {
+		razorMargin := razorBase + razorDepthCoeff*depth*depth
 		if staticEval+razorMargin <= alpha {
 			score := w.quiescence(ply, alpha, beta)
 			if score <= alpha {
+				if w.stats != nil {
+					w.stats.RazorPrunes++
+				}
 				return score
 			}
 		}
@@ -611,7 +976,7 @@ func (w *Worker) negamax(depth, ply int, alpha, beta int, prevMove, excludedMove
 
 	// Null Move Pruning (Stockfish search.cpp:893-924)
 	// Don't do NMP in PV nodes to preserve principal variation
-	if EnableNMP && !inCheck && depth >= 3 && ply > 0 && !pvNode && w.pos.HasNonPawnMaterial() {
+	if w.features.Has(FeatureNMP) && !inCheck && depth >= 3 && ply > 0 && !pvNode && w.pos.HasNonPawnMaterial() {
 		// Stockfish: R = 7 + depth/3 (more aggressive than our previous 2 + depth/4)
 		R := 7 + depth/3
 		if R > depth-1 {
@@ -619,10 +984,29 @@ func (w *Worker) negamax(depth, ply int, alpha, beta int, prevMove, excludedMove
 		}
 
 		nullUndo := w.pos.MakeNullMove()
+		prevNullIdx := w.nullHistoryIdx
+		w.posHistoryBuffer[w.posHistoryLen] = w.pos.Hash
+		w.nullHistoryIdx = w.posHistoryLen
+		w.posHistoryLen++
 		nullScore := -w.negamax(depth-1-R, ply+1, -beta, -beta+1, board.NoMove, board.NoMove, !cutNode, false)
+		w.posHistoryLen--
+		w.nullHistoryIdx = prevNullIdx
 		w.pos.UnmakeNullMove(nullUndo)
 
+		if w.stats != nil {
+			w.stats.NullTries++
+			if nullScore >= beta {
+				w.stats.NullCutoffs++
+			}
+		}
 		if nullScore >= beta {
+			// A mate found after a null move is not proven, as passing is
+			// illegal, so this fails hard as in Stockfish 15. With the lazy
+			// evaluation cutoffs in quiescence it is the only exception to
+			// failing soft.
+			if nullScore >= MateScore-MaxPly {
+				return beta
+			}
 			return nullScore
 		}
 	}
@@ -634,22 +1018,22 @@ func (w *Worker) negamax(depth, ply int, alpha, beta int, prevMove, excludedMove
 	// 2. Skip if beta is decisive (mate score)
 	// 3. TT guard: skip if TT already provides a good cutoff
 	// 4. Return adjusted value: score - (probcutBeta - beta)
-	if EnableProbcut && depth >= probcutDepth && !inCheck && ply > 0 && !pvNode {
+	if w.features.Has(FeatureProbcut) && depth >= probcutDepth && !inCheck && ply > 0 && !pvNode {
 		// Skip if beta is already decisive (winning mate)
 		if abs(beta) >= MateScore-MaxPly {
 			goto skipProbcut
 		}
 
-		// Adaptive margin: 235 - 63 when improving, 235 when not
-		adaptiveMargin := 235
+		// Adaptive margin: 235 - 63 when improving, 235 when not (by default)
+		adaptiveMargin := probcutBetaMargin
 		if improving {
-			adaptiveMargin -= 63
+			adaptiveMargin -= probcutImproving
 		}
 		probcutBeta := beta + adaptiveMargin
 
 		// TT guard: skip if we already have a TT cutoff at sufficient depth
 		if found && int(ttEntry.Depth) >= depth-3 {
-			ttValue := AdjustScoreFromTT(int(ttEntry.Score), ply)
+			ttValue := AdjustScoreFromTT(int(ttEntry.Score), ply, int(w.pos.HalfMoveClock))
 			if (ttEntry.Flag == TTLowerBound || ttEntry.Flag == TTExact) && ttValue >= probcutBeta {
 				goto skipProbcut
 			}
@@ -687,6 +1071,9 @@ func (w *Worker) negamax(depth, ply int, alpha, beta int, prevMove, excludedMove
 			w.nnuePop()
 
 			if score >= probcutBeta {
+				if w.stats != nil {
+					w.stats.ProbcutCutoffs++
+				}
 				// Return adjusted value to fit within [alpha, beta] window (Stockfish fix)
 				return score - (probcutBeta - beta)
 			}
@@ -700,9 +1087,8 @@ skipProbcut:
 
 	// Futility Pruning flag (Stockfish: depth <= 5)
 	pruneQuietMoves := false
-	if EnableFutilityPruning && depth <= 5 && !inCheck && ply > 0 {
-		futilityMargin := []int{0, 200, 300, 500, 700, 900}
-		if staticEval+futilityMargin[depth] <= alpha {
+	if w.features.Has(FeatureFutilityPruning) && depth <= 5 && !inCheck && ply > 0 {
+		if staticEval+futilityMargins[depth] <= alpha {
 			pruneQuietMoves = true
 		}
 	}
@@ -710,7 +1096,7 @@ skipProbcut:
 	// Singular Extensions (Stockfish search.cpp:1129-1157)
 	// When TT move is significantly better than alternatives, extend it
 	singularExtension := 0
-	if EnableSingularExt && depth >= 6 && ttMove != board.NoMove && excludedMove == board.NoMove && found {
+	if w.features.Has(FeatureSingularExt) && canExtend && depth >= 6 && ttMove != board.NoMove && excludedMove == board.NoMove && found {
 		// Check TT entry conditions:
 		// - TT depth is recent enough
 		// - TT bound includes lower bound (we know it's at least this good)
@@ -722,7 +1108,7 @@ skipProbcut:
 			if ttPv && !isPvNode {
 				margin = 128 // 53 + 75
 			}
-			ttValue := AdjustScoreFromTT(int(ttEntry.Score), ply)
+			ttValue := AdjustScoreFromTT(int(ttEntry.Score), ply, int(w.pos.HalfMoveClock))
 			singularBeta := ttValue - margin*depth/60
 
 			// Search at reduced depth excluding the TT move
@@ -773,7 +1159,7 @@ skipProbcut:
 				// Case 3: Negative extensions (Stockfish search.cpp:1173-1180)
 				// TT move is NOT singular - other moves are also good at singularBeta but not beta
 				// Reduce depth instead of extending
-				ttValue := AdjustScoreFromTT(int(ttEntry.Score), ply)
+				ttValue := AdjustScoreFromTT(int(ttEntry.Score), ply, int(w.pos.HalfMoveClock))
 				if ttValue >= beta {
 					singularExtension = -3 // Strong reduction when TT value beats beta
 				} else if cutNode {
@@ -786,44 +1172,43 @@ skipProbcut:
 	// Generate moves
 	moves := w.pos.GenerateLegalMoves()
 
-	// DEBUG: Verify KingSquare matches King bitboard after move generation
-	if board.DebugMoveValidation {
-		whiteKingBB := w.pos.Pieces[board.White][board.King]
-		blackKingBB := w.pos.Pieces[board.Black][board.King]
-		whiteKingSq := whiteKingBB.LSB()
-		blackKingSq := blackKingBB.LSB()
-		if w.pos.KingSquare[board.White] != whiteKingSq {
-			log.Printf("KINGSQ MISMATCH after movegen! White cached=%v actual=%v ply=%d depth=%d hash=%x",
-				w.pos.KingSquare[board.White], whiteKingSq, ply, depth, w.pos.Hash)
-		}
-		if w.pos.KingSquare[board.Black] != blackKingSq {
-			log.Printf("KINGSQ MISMATCH after movegen! Black cached=%v actual=%v ply=%d depth=%d hash=%x",
-				w.pos.KingSquare[board.Black], blackKingSq, ply, depth, w.pos.Hash)
-		}
-	}
-
 	// Checkmate or stalemate
 	if moves.Len() == 0 {
 		if inCheck {
 			return -MateScore + ply
 		}
-		return 0
+		return w.drawScore()
 	}
 
-	// Score and sort moves
-	scores := w.orderer.ScoreMovesWithCounter(w.pos, moves, ply, ttMove, prevMove)
+	// Score and sort moves, out of check by their own ordering
+	var scores []int
+	if inCheck {
+		scores = w.orderer.ScoreEvasions(w.pos, moves, ply, ttMove)
+	} else {
+		scores = w.orderer.ScoreMovesWithCounter(w.pos, moves, ply, ttMove, prevMove)
+	}
+	if ply == 0 {
+		w.orderRootMoves(moves, scores, ttMove)
+	}
+	if w.tbProber != nil && depth >= w.tbProbeDepth && tablebase.CountPieces(w.pos) == w.tbProber.MaxPieces()+1 {
+		w.orderSimplifications(moves, scores, ttMove)
+	}
 
 	bestScore := -Infinity
 	bestMove := board.NoMove
 	flag := TTUpperBound
 	movesSearched := 0
 
+	// Moves searched without a cutoff, penalized if a later move cuts off
+	var quietsTried, capturesTried [maxTriedMoves]board.Move
+	numQuiets, numCaptures := 0, 0
+
 	for i := 0; i < moves.Len(); i++ {
 		PickMove(moves, scores, i)
 		move := moves.Get(i)
 
-		// Multi-PV: skip excluded moves at root
-		if ply == 0 && w.isExcludedRootMove(move) {
+		// Multi-PV / searchmoves: skip excluded or disallowed moves at root
+		if ply == 0 && (w.isExcludedRootMove(move) || !w.isAllowedRootMove(move)) {
 			continue
 		}
 
@@ -835,34 +1220,51 @@ skipProbcut:
 		isCapture := move.IsCapture(w.pos)
 		isPromotion := move.IsPromotion()
 
+		// Moves are not pruned while every move searched so far gets mated:
+		// a skipped defence would turn the upper bound into a false mate score
+		canPrune := bestScore > -MateScore+MaxPly
+
 		// Futility pruning (in move loop)
-		if EnableFutilityPruning && pruneQuietMoves && !isCapture && !isPromotion && bestMove != board.NoMove {
+		if w.features.Has(FeatureFutilityPruning) && pruneQuietMoves && canPrune && !isCapture && !isPromotion && bestMove != board.NoMove {
+			if w.stats != nil {
+				w.stats.FutilityPrunes++
+			}
 			continue
 		}
 
 		// SEE pruning - prune bad captures at low depths (Stockfish: depth <= 7)
-		if EnableSEEPruning && isCapture && depth <= 7 && !inCheck && movesSearched > 0 {
+		if w.features.Has(FeatureSEEPruning) && isCapture && depth <= 7 && !inCheck && ply > 0 && canPrune && movesSearched > 0 {
 			// Scale threshold based on depth: deeper = more permissive
-			seeThreshold := -20 * depth
+			seeThreshold := -seePruningCoeff * depth
 			if SEE(w.pos, move) < seeThreshold {
+				if w.stats != nil {
+					w.stats.SEEPrunes++
+				}
 				continue
 			}
 		}
 
-		// Late Move Pruning (LMP)
-		if EnableLMP && depth <= 7 && !inCheck && movesSearched > 0 && !isCapture && !isPromotion && move != ttMove {
+		// Late Move Pruning (LMP). Root moves are never pruned: a quiet move
+		// such as a checking one skipped at shallow depth may decide the game.
+		if w.features.Has(FeatureLMP) && depth <= 7 && !inCheck && ply > 0 && canPrune && movesSearched > 0 && !isCapture && !isPromotion && move != ttMove {
 			threshold := lmpThreshold[depth]
 			if !improving {
 				threshold = threshold * 2 / 3
 			}
 			if movesSearched >= threshold {
+				if w.stats != nil {
+					w.stats.LMPPrunes++
+				}
 				continue
 			}
 		}
 
 		// History Pruning
-		if EnableHistoryPruning && depth <= 3 && !inCheck && movesSearched > 0 && !isCapture && !isPromotion && move != ttMove {
+		if w.features.Has(FeatureHistoryPruning) && depth <= 3 && !inCheck && ply > 0 && canPrune && movesSearched > 0 && !isCapture && !isPromotion && move != ttMove {
 			if w.orderer.GetHistoryScore(move) < historyPruningThreshold {
+				if w.stats != nil {
+					w.stats.HistoryPrunes++
+				}
 				continue
 			}
 		}
@@ -870,27 +1272,6 @@ skipProbcut:
 		// SEE-based quiet pruning disabled: Our SEE only handles captures
 		// TODO: Implement proper SEE for quiet moves (check if piece is safe on destination)
 
-		// DEBUG: Verify position consistency BEFORE checking piece
-		{
-			var whiteSum, blackSum board.Bitboard
-			for pt := board.Pawn; pt <= board.King; pt++ {
-				whiteSum |= w.pos.Pieces[board.White][pt]
-				blackSum |= w.pos.Pieces[board.Black][pt]
-			}
-			if whiteSum != w.pos.Occupied[board.White] {
-				log.Printf("PRE-MOVE: White Occupied mismatch! sum=%x Occupied=%x ply=%d depth=%d move=%v hash=%x",
-					whiteSum, w.pos.Occupied[board.White], ply, depth, move, w.pos.Hash)
-			}
-			if blackSum != w.pos.Occupied[board.Black] {
-				log.Printf("PRE-MOVE: Black Occupied mismatch! sum=%x Occupied=%x ply=%d depth=%d move=%v hash=%x",
-					blackSum, w.pos.Occupied[board.Black], ply, depth, move, w.pos.Hash)
-			}
-			if (whiteSum | blackSum) != w.pos.AllOccupied {
-				log.Printf("PRE-MOVE: AllOccupied mismatch! sum=%x AllOccupied=%x ply=%d depth=%d move=%v hash=%x",
-					whiteSum|blackSum, w.pos.AllOccupied, ply, depth, move, w.pos.Hash)
-			}
-		}
-
 		// Make move
 		movingPiece := w.pos.PieceAt(move.From())
 		moveTo := move.To()
@@ -914,18 +1295,16 @@ skipProbcut:
 			continue
 		}
 
-		// DEBUG: Save hash before MakeMove to verify restoration
-		hashBeforeMove := w.pos.Hash
-
-		// DEBUG: Verify King exists BEFORE MakeMove
-		if board.DebugMoveValidation {
-			whiteKingBB := w.pos.Pieces[board.White][board.King]
-			if whiteKingBB == 0 {
-				log.Printf("KING GONE BEFORE MakeMove! ply=%d depth=%d move=%v hash=%x", ply, depth, move, w.pos.Hash)
-			}
+		// Capture LMR: late SEE-losing captures are searched at reduced depth.
+		// Computed before MakeMove since SEE and capture history need the current position.
+		captureReduction := 0
+		if w.features.Has(FeatureCaptureLMR) && isCapture && !isPromotion && movesSearched >= captureLMRMinMoves &&
+			depth >= 3 && !inCheck && move != ttMove {
+			captureReduction = w.captureReduction(move, depth, movesSearched)
 		}
 
-		w.computeDirtyPieces(move) // Track piece changes for incremental NNUE
+		w.tt.Prefetch(w.pos.KeyAfter(move)) // Load the child's TT cluster while the move is made
+		w.computeDirtyPieces(move)          // Track piece changes for incremental NNUE
 		w.nnuePush()
 		w.undoStack[ply] = w.pos.MakeMove(move)
 		if !w.undoStack[ply].Valid {
@@ -935,9 +1314,6 @@ skipProbcut:
 			continue
 		}
 
-		// Store hashBeforeMove in undoStack for verification (reusing the Hash field)
-		_ = hashBeforeMove // Will check after UnmakeMove
-
 		// Store move info in search stack for continuation history
 		w.searchStack[ply].currentMove = move
 		w.searchStack[ply].movedPiece = movingPiece
@@ -946,15 +1322,24 @@ skipProbcut:
 
 		w.posHistoryBuffer[w.posHistoryLen] = w.pos.Hash
 		w.posHistoryLen++
+
+		// Report root move progress (currmove/currmovenumber), skipping singular verification searches
+		if ply == 0 && excludedMove == board.NoMove && w.onCurrMove != nil {
+			w.onCurrMove(w.depth, move, movesSearched+1)
+		}
 		movesSearched++
+		nodesBefore := w.nodes
 
 		var score int
-		newDepth := depth - 1 + extension
+		moveExtension := extension
 
 		// Apply singular extension (positive) or negative extension (reduction)
 		if move == ttMove && singularExtension != 0 {
-			newDepth += singularExtension
+			moveExtension += singularExtension
 		}
+		moveExtension = w.limitExtension(ply, moveExtension)
+		w.searchStack[ply].extension = max(moveExtension, 0)
+		newDepth := depth - 1 + moveExtension
 
 		// Late Move Reduction (LMR) - logarithmic formula based on Stockfish
 		if movesSearched > 4 && depth >= 3 && !inCheck && !isCapture && !isPromotion {
@@ -969,13 +1354,7 @@ skipProbcut:
 			}
 			reduction := lmrReductions[d][m]
 
-			// Stockfish's rootDelta scaling (search.cpp:1736)
-			// Scales reduction inversely with aspiration window width
-			// Narrower windows (confident positions) get less reduction
-			if w.rootDelta > 0 && w.rootDelta < Infinity {
-				delta := beta - alpha
-				reduction -= delta * 608 / w.rootDelta
-			}
+			reduction -= w.rootDeltaReduction(alpha, beta)
 
 			// Adjustments based on node type and position
 			if !improving {
@@ -1008,6 +1387,13 @@ skipProbcut:
 				reduction += reduction / (depth + 1)
 			}
 
+			// Asymmetric node-type offsets (tunable via SetLMROffsets)
+			if isPvNode {
+				reduction += lmrPvOffset
+			} else {
+				reduction += lmrNonPvOffset
+			}
+
 			// cutoffCnt scaling (Stockfish search.cpp:1208-1210)
 			// If next ply had multiple cutoffs, increase reduction
 			if ply+1 < MaxPly {
@@ -1065,10 +1451,26 @@ skipProbcut:
 				reducedDepth = 1
 			}
 
-			// Store reduction for hindsight depth adjustment (Stockfish search.cpp:754-757)
-			w.searchStack[ply].reduction = reduction
+			// Store the reduction applied for hindsight depth adjustment (Stockfish search.cpp:754-757).
+			// It is cleared after the reduced search so no other child sees it.
+			w.searchStack[ply].reduction = newDepth - reducedDepth
+
+			score = -w.negamax(reducedDepth, ply+1, -alpha-1, -alpha, move, board.NoMove, !cutNode, false)
+			w.searchStack[ply].reduction = 0
+
+			if score > alpha {
+				score = -w.negamax(newDepth, ply+1, -beta, -alpha, move, board.NoMove, false, false)
+			}
+		} else if captureReduction > 0 {
+			// Capture LMR: reduced null-window search, full re-search if it beats alpha
+			reducedDepth := newDepth - captureReduction
+			if reducedDepth < 1 {
+				reducedDepth = 1
+			}
+			w.searchStack[ply].reduction = newDepth - reducedDepth
 
 			score = -w.negamax(reducedDepth, ply+1, -alpha-1, -alpha, move, board.NoMove, !cutNode, false)
+			w.searchStack[ply].reduction = 0
 
 			if score > alpha {
 				score = -w.negamax(newDepth, ply+1, -beta, -alpha, move, board.NoMove, false, false)
@@ -1088,46 +1490,30 @@ skipProbcut:
 		w.posHistoryLen--
 		w.pos.UnmakeMove(move, w.undoStack[ply])
 		w.nnuePop()
+		if ply == 0 {
+			w.rootEffort[move.From()][move.To()] += w.nodes - nodesBefore
+		}
 
-		// DEBUG: Verify King exists AFTER UnmakeMove
+		// DEBUG: Verify the position was restored by UnmakeMove
+		// The UndoInfo.Hash holds the hash from before the move was made
 		if board.DebugMoveValidation {
-			whiteKingBB := w.pos.Pieces[board.White][board.King]
-			if whiteKingBB == 0 {
-				log.Printf("KING GONE AFTER UnmakeMove! ply=%d depth=%d move=%v hash=%x undoHash=%x",
-					ply, depth, move, w.pos.Hash, w.undoStack[ply].Hash)
+			if w.pos.Hash != w.undoStack[ply].Hash {
+				log.Printf("HASH MISMATCH: Expected=%x Got=%x ply=%d move=%v depth=%d",
+					w.undoStack[ply].Hash, w.pos.Hash, ply, move, depth)
+			}
+			if err := w.pos.Validate(); err != nil {
+				log.Printf("CORRUPTION after UnmakeMove: ply=%d move=%v: %v", ply, move, err)
 			}
-		}
-
-		// DEBUG: Verify position hash was restored correctly after UnmakeMove
-		// The UndoInfo.Hash should contain the hash BEFORE the move was made
-		if w.pos.Hash != w.undoStack[ply].Hash {
-			log.Printf("HASH MISMATCH: Expected=%x Got=%x ply=%d move=%v depth=%d",
-				w.undoStack[ply].Hash, w.pos.Hash, ply, move, depth)
-		}
-
-		// Verify position consistency after UnmakeMove
-		if w.pos.AllOccupied != (w.pos.Occupied[board.White] | w.pos.Occupied[board.Black]) {
-			log.Printf("CORRUPTION: AllOccupied mismatch after UnmakeMove! ply=%d, move=%v", ply, move)
-		}
-		// Verify Occupied[color] matches sum of Pieces[color][*]
-		var whiteOcc, blackOcc board.Bitboard
-		for pt := board.Pawn; pt <= board.King; pt++ {
-			whiteOcc |= w.pos.Pieces[board.White][pt]
-			blackOcc |= w.pos.Pieces[board.Black][pt]
-		}
-		if w.pos.Occupied[board.White] != whiteOcc {
-			log.Printf("CORRUPTION: White Occupied mismatch! Occupied=%x, Pieces sum=%x, ply=%d, move=%v",
-				w.pos.Occupied[board.White], whiteOcc, ply, move)
-		}
-		if w.pos.Occupied[board.Black] != blackOcc {
-			log.Printf("CORRUPTION: Black Occupied mismatch! Occupied=%x, Pieces sum=%x, ply=%d, move=%v",
-				w.pos.Occupied[board.Black], blackOcc, ply, move)
 		}
 
 		if w.stopFlag.Load() {
 			return 0
 		}
 
+		if ply == 0 && abs(score) < MateScore-MaxPly && w.isDiscouragedRootMove(move) {
+			score -= w.discouragePenalty
+		}
+
 		if score > bestScore {
 			bestScore = score
 			bestMove = move
@@ -1146,6 +1532,9 @@ skipProbcut:
 
 		// Beta cutoff
 		if score >= beta {
+			if w.stats != nil {
+				w.stats.cutoff(movesSearched - 1)
+			}
 			// Update cutoffCnt (Stockfish search.cpp:1375)
 			// Increment when extension < 2 or at PV nodes
 			isPvNode := alpha < beta-1
@@ -1161,40 +1550,34 @@ skipProbcut:
 			w.tt.Store(w.pos.Hash, depth, AdjustScoreToTT(score, ply), TTLowerBound, bestMove, false)
 
 			if isCapture {
-				attackerPiece := w.pos.PieceAt(move.From())
-				var capturedType board.PieceType
-				if move.IsEnPassant() {
-					capturedType = board.Pawn
-				} else {
-					capturedPiece := w.pos.PieceAt(move.To())
-					if capturedPiece != board.NoPiece {
-						capturedType = capturedPiece.Type()
-					}
-				}
-				w.orderer.UpdateCaptureHistory(attackerPiece, move.To(), capturedType, depth, true)
+				w.updateCaptureHistory(move, depth, true)
 			} else {
 				w.orderer.UpdateKillers(move, ply)
-				w.orderer.UpdateHistory(move, depth, true)
-				// Update low-ply history for better root move ordering
-				w.orderer.UpdateLowPlyHistory(move, ply, depth, true)
-				// Also update shared history for Lazy SMP collective learning
-				bonus := depth * depth
-				w.sharedHistory.Update(int(move.From()), int(move.To()), bonus)
 				w.orderer.UpdateCounterMove(prevMove, move, w.pos)
+				w.updateQuietHistories(move, movingPiece, prevMove, ply, depth, true)
+			}
 
-				if prevMove != board.NoMove {
-					prevPiece := w.pos.PieceAt(prevMove.To())
-					movePiece := w.pos.PieceAt(move.To())
-					w.orderer.UpdateCountermoveHistory(prevMove, move, prevPiece, movePiece, depth, true)
-				}
-
-				// Update continuation history for multiple plies back (Stockfish style)
-				// This learns move pair patterns at different ply distances
-				w.updateContinuationHistories(ply, movingPiece, moveTo, depth, true)
+			// Malus for the moves searched before that failed to cut off
+			for _, m := range quietsTried[:numQuiets] {
+				w.updateQuietHistories(m, w.pos.PieceAt(m.From()), prevMove, ply, depth, false)
+			}
+			for _, m := range capturesTried[:numCaptures] {
+				w.updateCaptureHistory(m, depth, false)
 			}
 
 			return score
 		}
+
+		// Remember the move for a malus if a later one cuts off
+		if isCapture {
+			if numCaptures < len(capturesTried) {
+				capturesTried[numCaptures] = move
+				numCaptures++
+			}
+		} else if numQuiets < len(quietsTried) {
+			quietsTried[numQuiets] = move
+			numQuiets++
+		}
 	}
 
 	// Safety fallback
@@ -1208,7 +1591,7 @@ skipProbcut:
 	// Update correction history when we have an exact score
 	// This helps the engine learn from eval errors
 	if flag == TTExact && !inCheck && depth >= 2 {
-		w.corrHistory.Update(w.pos, bestScore, rawEval, depth)
+		w.corrHistory.Update(w.pos, corrPrevPiece, corrPrevTo, bestScore, rawEval, depth)
 	}
 
 	// isPV = true when we found an exact score (improved alpha without beta cutoff)
@@ -1218,7 +1601,8 @@ skipProbcut:
 	return bestScore
 }
 
-// quiescence searches captures to avoid horizon effect.
+// quiescence searches captures, and quiet checks at its first ply, to avoid
+// horizon effect.
 func (w *Worker) quiescence(ply int, alpha, beta int) int {
 	return w.quiescenceInternal(ply, 0, alpha, beta)
 }
@@ -1235,21 +1619,34 @@ func (w *Worker) quiescenceInternal(ply, qPly int, alpha, beta int) int {
 		return 0
 	}
 
-	w.nodes++
+	w.countNode()
+	w.updateSelDepth(ply)
+	if w.stats != nil {
+		w.stats.QNodes++
+	}
 	originalAlpha := alpha
 
+	// Check detection - critical: NO standing pat when in check
+	inCheck := w.pos.InCheck()
+
+	// Quiet checks are searched in the first quiescence plies, to see mating
+	// nets and perpetual checks that captures alone miss
+	searchChecks := !inCheck && qPly < qsCheckPlies
+
+	// QS TT depth tier: nodes searching checks or evasions are distinguishable
+	// from captures-only nodes
+	qsDepth := DepthQSNoChecks
+	if inCheck || searchChecks {
+		qsDepth = DepthQSChecks
+	}
+
 	// TT Probe - critical for QS performance
 	var ttMove board.Move
 	ttEntry, ttHit := w.tt.Probe(w.pos.Hash)
 	if ttHit {
-		ttMove = ttEntry.BestMove
-		// Validate TT move (can be corrupted by hash collision)
-		if ttMove != board.NoMove && !w.pos.PseudoLegal(ttMove) {
-			ttMove = board.NoMove
-		}
-		// TT cutoff - depth >= 0 is sufficient for QS
-		if ttEntry.Depth >= 0 {
-			score := AdjustScoreFromTT(int(ttEntry.Score), ply)
+		// TT cutoff - any entry searched at least as deep as this QS tier
+		if int(ttEntry.Depth) >= qsDepth {
+			score := AdjustScoreFromTT(int(ttEntry.Score), ply, int(w.pos.HalfMoveClock))
 			switch ttEntry.Flag {
 			case TTExact:
 				return score
@@ -1263,11 +1660,12 @@ func (w *Worker) quiescenceInternal(ply, qPly int, alpha, beta int) int {
 				}
 			}
 		}
+		// Validate TT move once there was no cutoff (can be corrupted by hash collision)
+		if ttEntry.BestMove != board.NoMove && w.pos.PseudoLegal(ttEntry.BestMove) {
+			ttMove = ttEntry.BestMove
+		}
 	}
 
-	// Check detection - critical: NO standing pat when in check
-	inCheck := w.pos.InCheck()
-
 	var standPat, bestValue int
 	var bestMove board.Move
 
@@ -1277,7 +1675,11 @@ func (w *Worker) quiescenceInternal(ply, qPly int, alpha, beta int) int {
 		bestValue = -MateScore + ply
 		standPat = bestValue
 	} else {
-		// Lazy evaluation cutoff (only when not in check)
+		// Lazy evaluation cutoff (only when not in check). Material and a
+		// margin are a guess rather than a bound, so this fails hard: a
+		// fail-soft value would carry the guess into other windows. With the
+		// null move mate clamp in negamax it is the only exception to failing
+		// soft.
 		lazyEval := EvaluateMaterial(w.pos)
 		if lazyEval-lazyEvalMargin >= beta {
 			return beta
@@ -1292,8 +1694,8 @@ func (w *Worker) quiescenceInternal(ply, qPly int, alpha, beta int) int {
 
 		if standPat >= beta {
 			// Store stand pat cutoff in TT
-			w.tt.Store(w.pos.Hash, 0, AdjustScoreToTT(standPat, ply), TTLowerBound, board.NoMove, false)
-			return beta
+			w.tt.Store(w.pos.Hash, qsDepth, AdjustScoreToTT(standPat, ply), TTLowerBound, board.NoMove, false)
+			return standPat
 		}
 
 		if standPat > alpha {
@@ -1302,7 +1704,7 @@ func (w *Worker) quiescenceInternal(ply, qPly int, alpha, beta int) int {
 
 		// Big delta pruning - if even capturing a queen can't raise alpha, give up
 		if standPat+QueenValue < alpha {
-			return alpha
+			return standPat + QueenValue
 		}
 	}
 
@@ -1317,7 +1719,12 @@ func (w *Worker) quiescenceInternal(ply, qPly int, alpha, beta int) int {
 	}
 
 	// Move ordering with TT move priority
-	scores := w.orderer.ScoreMoves(w.pos, moves, ply, ttMove)
+	var scores []int
+	if inCheck {
+		scores = w.orderer.ScoreEvasions(w.pos, moves, ply, ttMove)
+	} else {
+		scores = w.orderer.ScoreMoves(w.pos, moves, ply, ttMove)
+	}
 
 	for i := 0; i < moves.Len(); i++ {
 		PickMove(moves, scores, i)
@@ -1326,10 +1733,10 @@ func (w *Worker) quiescenceInternal(ply, qPly int, alpha, beta int) int {
 		// Pruning only when NOT in check and move is a capture
 		if !inCheck && move.IsCapture(w.pos) {
 			captureValue := qsCaptureValue(w.pos, move)
-			futilityBase := standPat + 351 // Stockfish constant
+			futilityBase := standPat + qsFutilityMargin
 
 			// Delta pruning: skip if even this capture can't reach alpha
-			if standPat+captureValue+200 < alpha && !move.IsPromotion() {
+			if standPat+captureValue+qsDeltaMargin < alpha && !move.IsPromotion() {
 				if captureValue+futilityBase > bestValue {
 					bestValue = captureValue + futilityBase
 				}
@@ -1351,20 +1758,8 @@ func (w *Worker) quiescenceInternal(ply, qPly int, alpha, beta int) int {
 			}
 		}
 
-		w.computeDirtyPieces(move)
-		w.nnuePush()
-		undo := w.pos.MakeMove(move)
-		if !undo.Valid {
-			w.pos.UnmakeMove(move, undo)
-			w.nnuePop()
-			continue
-		}
-
-		score := -w.quiescenceInternal(ply+1, qPly+1, -beta, -alpha)
-		w.pos.UnmakeMove(move, undo)
-		w.nnuePop()
-
-		if score > bestValue {
+		score, ok := w.quiescenceMove(move, ply, qPly, alpha, beta)
+		if ok && score > bestValue {
 			bestValue = score
 			bestMove = move
 
@@ -1377,6 +1772,30 @@ func (w *Worker) quiescenceInternal(ply, qPly int, alpha, beta int) int {
 		}
 	}
 
+	// Quiet checks that do not lose material, if no capture cut off
+	if searchChecks && bestValue < beta {
+		checks := w.pos.GenerateChecks()
+		for i := 0; i < checks.Len(); i++ {
+			move := checks.Get(i)
+			if SEE(w.pos, move) < 0 {
+				continue
+			}
+
+			score, ok := w.quiescenceMove(move, ply, qPly, alpha, beta)
+			if ok && score > bestValue {
+				bestValue = score
+				bestMove = move
+
+				if score > alpha {
+					alpha = score
+					if score >= beta {
+						break // Beta cutoff
+					}
+				}
+			}
+		}
+	}
+
 	// Checkmate detection: if in check and no legal moves found
 	if inCheck && bestValue == -MateScore+ply {
 		return -MateScore + ply // Checkmate
@@ -1391,11 +1810,126 @@ func (w *Worker) quiescenceInternal(ply, qPly int, alpha, beta int) int {
 	} else {
 		ttFlag = TTUpperBound
 	}
-	w.tt.Store(w.pos.Hash, 0, AdjustScoreToTT(bestValue, ply), ttFlag, bestMove, false)
+	w.tt.Store(w.pos.Hash, qsDepth, AdjustScoreToTT(bestValue, ply), ttFlag, bestMove, false)
 
 	return bestValue
 }
 
+// quiescenceMove makes a move and searches it in quiescence. ok is false if
+// the move turned out to be illegal.
+func (w *Worker) quiescenceMove(move board.Move, ply, qPly, alpha, beta int) (score int, ok bool) {
+	w.tt.Prefetch(w.pos.KeyAfter(move))
+	w.computeDirtyPieces(move)
+	w.nnuePush()
+	undo := w.pos.MakeMove(move)
+	if undo.Valid {
+		score = -w.quiescenceInternal(ply+1, qPly+1, -beta, -alpha)
+	}
+	w.pos.UnmakeMove(move, undo)
+	w.nnuePop()
+	return score, undo.Valid
+}
+
+// orderRootMoves raises the ordering score of root moves by their share of
+// the nodes searched so far (Stockfish's effort). A move the previous
+// iterations needed many nodes to refute is the likeliest to become best,
+// so iterative deepening tries it right after the TT move and the PV
+// settles sooner. Quiet moves also keep their low-ply history.
+func (w *Worker) orderRootMoves(moves *board.MoveList, scores []int, ttMove board.Move) {
+	var total uint64
+	for i := 0; i < moves.Len(); i++ {
+		m := moves.Get(i)
+		total += w.rootEffort[m.From()][m.To()]
+	}
+	if total == 0 {
+		return
+	}
+	for i := 0; i < moves.Len(); i++ {
+		m := moves.Get(i)
+		if m != ttMove {
+			scores[i] += int(w.rootEffort[m.From()][m.To()] * rootEffortScale / total)
+		}
+	}
+}
+
+// orderSimplifications reorders the captures of a position one capture away
+// from the tablebases by the tablebase result after them: captures into a
+// won ending go before all moves but the TT move, captures into a lost one
+// after all others. Captures into drawn or unknown endings keep their score.
+func (w *Worker) orderSimplifications(moves *board.MoveList, scores []int, ttMove board.Move) {
+	for i := 0; i < moves.Len(); i++ {
+		move := moves.Get(i)
+		if move == ttMove || !move.IsCapture(w.pos) {
+			continue
+		}
+		undo := w.pos.MakeMove(move)
+		if undo.Valid {
+			// The result is for the opponent, to move after the capture
+			if result := w.tbProber.Probe(w.pos); result.Found {
+				switch result.WDL {
+				case tablebase.WDLLoss:
+					scores[i] += tbSimplifyBonus
+				case tablebase.WDLWin:
+					scores[i] -= tbSimplifyBonus
+				}
+			}
+		}
+		w.pos.UnmakeMove(move, undo)
+	}
+}
+
+// rootDeltaReduction returns how many plies less LMR reduces at a node
+// searched with the window alpha..beta (Stockfish search.cpp:1736):
+// r -= 608 * delta / rootDelta in 1024 units, rounded to plies, so nodes
+// searched with (close to) the full root window reduce one ply less than
+// null-window ones.
+func (w *Worker) rootDeltaReduction(alpha, beta int) int {
+	if w.rootDelta <= 0 {
+		return 0
+	}
+	return ((beta-alpha)*608/w.rootDelta + 512) / 1024
+}
+
+// captureReduction returns the LMR reduction for a late capture: none unless
+// it loses material by SEE. Starts one ply above the quiet base reduction and
+// is adjusted by capture history.
+func (w *Worker) captureReduction(move board.Move, depth, movesSearched int) int {
+	if SEE(w.pos, move) >= 0 {
+		return 0
+	}
+
+	d := depth
+	if d > 63 {
+		d = 63
+	}
+	m := movesSearched
+	if m > 63 {
+		m = 63
+	}
+	reduction := lmrReductions[d][m] + 1
+
+	attacker := w.pos.PieceAt(move.From())
+	victim := board.Pawn
+	if !move.IsEnPassant() {
+		if captured := w.pos.PieceAt(move.To()); captured != board.NoPiece {
+			victim = captured.Type()
+		}
+	}
+
+	// Captures that have historically failed get reduced more, proven ones less
+	hist := w.orderer.GetCaptureHistoryScore(attacker, move.To(), victim)
+	if hist < 0 {
+		reduction++
+	} else if hist > captureLMRGoodHistory {
+		reduction--
+	}
+
+	if reduction < 1 {
+		reduction = 1
+	}
+	return reduction
+}
+
 // qsCaptureValue returns the material value of a capture for QS pruning.
 func qsCaptureValue(pos *board.Position, move board.Move) int {
 	var value int
@@ -1464,6 +1998,40 @@ func (w *Worker) detectSeriousThreats() bool {
 	return false
 }
 
+// updateQuietHistories updates the history tables of a quiet move by piece:
+// a bonus if it caused a beta cutoff, a malus if it was searched before the
+// move that did.
+func (w *Worker) updateQuietHistories(move board.Move, piece board.Piece, prevMove board.Move, ply, depth int, isGood bool) {
+	w.orderer.UpdateHistory(move, depth, isGood)
+	// Low-ply history for better root move ordering
+	w.orderer.UpdateLowPlyHistory(move, ply, depth, isGood)
+	// Shared history for Lazy SMP collective learning
+	w.sharedHistory.Update(int(move.From()), int(move.To()), historyBonus(depth*depth, isGood))
+
+	if prevMove != board.NoMove {
+		prevPiece := w.pos.PieceAt(prevMove.To())
+		w.orderer.UpdateCountermoveHistory(prevMove, move, prevPiece, piece, depth, isGood)
+	}
+
+	// Continuation history for multiple plies back (Stockfish style)
+	// This learns move pair patterns at different ply distances
+	w.updateContinuationHistories(ply, piece, move.To(), depth, isGood)
+}
+
+// updateCaptureHistory updates the capture history of a capture: a bonus if
+// it caused a beta cutoff, a malus if it was searched before the move that did.
+func (w *Worker) updateCaptureHistory(move board.Move, depth int, isGood bool) {
+	capturedType := board.Pawn // En passant
+	if !move.IsEnPassant() {
+		capturedPiece := w.pos.PieceAt(move.To())
+		if capturedPiece == board.NoPiece {
+			return
+		}
+		capturedType = capturedPiece.Type()
+	}
+	w.orderer.UpdateCaptureHistory(w.pos.PieceAt(move.From()), move.To(), capturedType, depth, isGood)
+}
+
 // updateContinuationHistories updates continuation history for multiple plies back.
 // Ported from Stockfish's update_continuation_histories function.
 // Updates plies 1, 2, 3, 4, 5, 6 with weighted bonuses.
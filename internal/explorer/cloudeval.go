@@ -0,0 +1,48 @@
+package explorer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CloudEval is Lichess's cloud evaluation of a position.
+type CloudEval struct {
+	FEN    string    `json:"fen"`
+	Depth  int       `json:"depth"`
+	KNodes int       `json:"knodes"` // Thousands of nodes searched
+	PVs    []CloudPV `json:"pvs"`    // Best line first
+}
+
+// CloudPV is a line of a cloud evaluation. Scores are from White's view.
+type CloudPV struct {
+	Moves string `json:"moves"` // UCI moves, separated by spaces
+	CP    int    `json:"cp"`    // Centipawns, unless Mate is set
+	Mate  int    `json:"mate"`  // Moves to mate, negative when Black mates (0 = none)
+}
+
+// UCIMoves returns the moves of the line.
+func (pv CloudPV) UCIMoves() []string {
+	return strings.Fields(pv.Moves)
+}
+
+// CloudEval returns the cloud evaluation of the position given by FEN, with
+// its best line, or ErrNotFound if the position has none. Answers are cached
+// and requests paced as for Lookup.
+func (c *Client) CloudEval(ctx context.Context, fen string) (*CloudEval, error) {
+	q := url.Values{}
+	q.Set("fen", fen)
+	v, err := c.query(ctx, cacheKey{c.CloudURL, fen}, q, func() any { return &CloudEval{} })
+	if err != nil {
+		return nil, fmt.Errorf("cloud evaluation: %w", err)
+	}
+	if v == nil {
+		return nil, ErrNotFound
+	}
+	e := v.(*CloudEval)
+	if len(e.PVs) == 0 {
+		return nil, ErrNotFound
+	}
+	return e, nil
+}
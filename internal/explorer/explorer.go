@@ -0,0 +1,260 @@
+// Package explorer looks up positions in the Lichess opening explorer: the
+// moves played from a position in masters' games or in games on Lichess,
+// with how often each was played and how those games ended. It also asks
+// Lichess for its cloud evaluations, the deep analyses of positions that
+// have been analyzed on the site.
+//
+// Lichess asks for one request at a time and a minute's pause after
+// being told to slow down (HTTP 429). A Client keeps to that, and caches
+// what it was told, so browsing back and forth through a game asks for each
+// position once.
+package explorer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Services queried by default
+const (
+	URL      = "https://explorer.lichess.ovh"       // Opening explorer
+	CloudURL = "https://lichess.org/api/cloud-eval" // Cloud evaluations
+)
+
+// Request pacing
+const (
+	minInterval = time.Second // Between requests
+	backoff     = time.Minute // After HTTP 429
+)
+
+// Limits on what is kept and read
+const (
+	maxMoves     = 12      // Moves asked for per position
+	maxCacheSize = 4096    // Positions cached
+	maxBodySize  = 1 << 20 // Response
+)
+
+// Lichess games counted: the rated speeds and ratings of players who mostly
+// play openings worth learning from
+const (
+	lichessSpeeds  = "blitz,rapid,classical"
+	lichessRatings = "1800,2000,2200,2500"
+)
+
+var (
+	// ErrRateLimited is returned while Lichess has asked for a pause.
+	ErrRateLimited = errors.New("lichess rate limit reached")
+
+	// ErrNotFound is returned by CloudEval for a position without a cloud
+	// evaluation.
+	ErrNotFound = errors.New("no cloud evaluation of the position")
+)
+
+// Source is a database of games.
+type Source int
+
+const (
+	Masters Source = iota // Over-the-board games of titled players
+	Lichess               // Rated games played on Lichess
+)
+
+// String returns the name of the source.
+func (s Source) String() string {
+	if s == Lichess {
+		return "Lichess"
+	}
+	return "Masters"
+}
+
+// path returns the explorer endpoint of the source.
+func (s Source) path() string {
+	if s == Lichess {
+		return "/lichess"
+	}
+	return "/masters"
+}
+
+// Move is a move played from a position, with the results of its games.
+type Move struct {
+	UCI           string `json:"uci"`
+	SAN           string `json:"san"`
+	White         int64  `json:"white"` // Games won by White
+	Draws         int64  `json:"draws"`
+	Black         int64  `json:"black"` // Games won by Black
+	AverageRating int    `json:"averageRating"`
+}
+
+// Games returns the number of games the move was played in.
+func (m Move) Games() int64 {
+	return m.White + m.Draws + m.Black
+}
+
+// Result is what the explorer knows of a position: its games and the
+// moves played from it, most played first.
+type Result struct {
+	White int64  `json:"white"`
+	Draws int64  `json:"draws"`
+	Black int64  `json:"black"`
+	Moves []Move `json:"moves"`
+}
+
+// Games returns the number of games that reached the position.
+func (r *Result) Games() int64 {
+	return r.White + r.Draws + r.Black
+}
+
+// cacheKey identifies a cached answer: the address asked and the position.
+type cacheKey struct {
+	url string
+	fen string
+}
+
+// Client queries the opening explorer and cloud evaluations, one request at
+// a time.
+type Client struct {
+	Client   *http.Client
+	URL      string // Explorer address, without a trailing slash
+	CloudURL string // Cloud evaluation address
+
+	mu    sync.Mutex       // Guards cache
+	cache map[cacheKey]any // *Result or *CloudEval (nil = not found)
+
+	request      sync.Mutex // Held while a request is paced and made
+	last         time.Time  // When the last request was made
+	blockedUntil time.Time  // End of the pause asked for
+}
+
+// NewClient creates a client of the Lichess opening explorer.
+func NewClient() *Client {
+	return &Client{
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		URL:      URL,
+		CloudURL: CloudURL,
+	}
+}
+
+// Lookup returns what the source knows of the position given by FEN. A
+// position asked for before is answered from the cache. Requests are spaced
+// out, and while the explorer asks for a pause Lookup returns
+// ErrRateLimited at once; other errors, such as being offline, are returned
+// as they come and nothing is cached for them.
+func (c *Client) Lookup(ctx context.Context, source Source, fen string) (*Result, error) {
+	q := url.Values{}
+	q.Set("fen", fen)
+	q.Set("moves", fmt.Sprint(maxMoves))
+	q.Set("topGames", "0")
+	if source == Lichess {
+		q.Set("variant", "standard")
+		q.Set("speeds", lichessSpeeds)
+		q.Set("ratings", lichessRatings)
+		q.Set("recentGames", "0")
+	}
+	v, err := c.query(ctx, cacheKey{c.URL + source.path(), fen}, q, func() any { return &Result{} })
+	if err != nil {
+		return nil, fmt.Errorf("opening explorer: %w", err)
+	}
+	if v == nil {
+		return &Result{}, nil // Not a position the explorer has games of
+	}
+	return v.(*Result), nil
+}
+
+// query returns the answer at key.url to the query, decoded into the value
+// made by newValue, from the cache or else asked for in turn. A position
+// not found (HTTP 404) is cached as a nil value.
+func (c *Client) query(ctx context.Context, key cacheKey, q url.Values, newValue func() any) (any, error) {
+	if v, ok := c.cached(key); ok {
+		return v, nil
+	}
+
+	c.request.Lock()
+	defer c.request.Unlock()
+	if v, ok := c.cached(key); ok {
+		return v, nil // Asked for by the request waited on
+	}
+	if time.Now().Before(c.blockedUntil) {
+		return nil, ErrRateLimited
+	}
+	if wait := time.Until(c.last.Add(minInterval)); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c.last = time.Now()
+
+	v, err := c.get(ctx, key.url+"?"+q.Encode(), newValue)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, v)
+	return v, nil
+}
+
+// get asks for the answer at url, decoded into the value made by newValue;
+// nil if not found.
+func (c *Client) get(ctx context.Context, url string, newValue func() any) (any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusTooManyRequests:
+		c.blockedUntil = time.Now().Add(backoff)
+		return nil, ErrRateLimited
+	default:
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	v := newValue()
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(v); err != nil {
+		return nil, fmt.Errorf("reading answer: %w", err)
+	}
+	return v, nil
+}
+
+// cached returns the cached answer at key, and whether there is one.
+func (c *Client) cached(key cacheKey) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.cache[key]
+	return v, ok
+}
+
+// store caches an answer. A full cache drops half its entries.
+func (c *Client) store(key cacheKey, v any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cache == nil {
+		c.cache = make(map[cacheKey]any)
+	}
+	if len(c.cache) >= maxCacheSize {
+		i := 0
+		for k := range c.cache {
+			if i >= maxCacheSize/2 {
+				break
+			}
+			delete(c.cache, k)
+			i++
+		}
+	}
+	c.cache[key] = v
+}
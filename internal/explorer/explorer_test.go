@@ -0,0 +1,150 @@
+package explorer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// explorerServer serves one result for every position, counting the
+// requests per endpoint.
+func explorerServer(t *testing.T, requests map[string]*atomic.Int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, ok := requests[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		n.Add(1)
+		if r.URL.Query().Get("fen") != startFEN {
+			t.Errorf("fen = %q, want %q", r.URL.Query().Get("fen"), startFEN)
+		}
+		json.NewEncoder(w).Encode(Result{White: 6, Draws: 3, Black: 1, Moves: []Move{
+			{UCI: "e2e4", SAN: "e4", White: 4, Draws: 1, Black: 1},
+			{UCI: "d2d4", SAN: "d4", White: 2, Draws: 2},
+		}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestLookup(t *testing.T) {
+	requests := map[string]*atomic.Int32{"/masters": {}, "/lichess": {}}
+	srv := explorerServer(t, requests)
+	c := &Client{Client: srv.Client(), URL: srv.URL}
+	ctx := context.Background()
+
+	r, err := c.Lookup(ctx, Masters, startFEN)
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if r.Games() != 10 || len(r.Moves) != 2 || r.Moves[0].SAN != "e4" || r.Moves[0].Games() != 6 {
+		t.Errorf("Lookup = %+v, want 10 games with e4 played 6 times", r)
+	}
+
+	// The same position again comes from the cache; the other source asks
+	if _, err := c.Lookup(ctx, Masters, startFEN); err != nil {
+		t.Fatalf("cached Lookup: %v", err)
+	}
+	if _, err := c.Lookup(ctx, Lichess, startFEN); err != nil {
+		t.Fatalf("Lookup Lichess: %v", err)
+	}
+	if n, l := requests["/masters"].Load(), requests["/lichess"].Load(); n != 1 || l != 1 {
+		t.Errorf("requests: masters %d, lichess %d; want 1 each", n, l)
+	}
+}
+
+func TestLookupPacing(t *testing.T) {
+	requests := map[string]*atomic.Int32{"/masters": {}}
+	srv := explorerServer(t, requests)
+	c := &Client{Client: srv.Client(), URL: srv.URL, last: time.Now()}
+
+	// A request right after another waits for its turn, or gives up
+	ctx, cancel := context.WithTimeout(context.Background(), minInterval/10)
+	defer cancel()
+	if _, err := c.Lookup(ctx, Masters, startFEN); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Lookup before the interval = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := requests["/masters"].Load(); n != 0 {
+		t.Errorf("%d requests made before the interval, want 0", n)
+	}
+}
+
+func TestLookupRateLimited(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	c := &Client{Client: srv.Client(), URL: srv.URL}
+	ctx := context.Background()
+
+	if _, err := c.Lookup(ctx, Masters, startFEN); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Lookup = %v, want %v", err, ErrRateLimited)
+	}
+	// During the pause nothing is asked
+	if _, err := c.Lookup(ctx, Lichess, startFEN); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Lookup during the pause = %v, want %v", err, ErrRateLimited)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d requests made, want 1", n)
+	}
+}
+
+func TestLookupOffline(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	c := &Client{Client: &http.Client{Timeout: time.Second}, URL: url}
+
+	if r, err := c.Lookup(context.Background(), Masters, startFEN); err == nil {
+		t.Fatalf("Lookup with no server = %+v, want an error", r)
+	}
+	if len(c.cache) != 0 {
+		t.Errorf("failed lookup cached")
+	}
+}
+
+func TestCloudEval(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("fen") != startFEN {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"fen":"` + startFEN + `","knodes":1200,"depth":36,` +
+			`"pvs":[{"moves":"e2e4 e7e5 g1f3","cp":18}]}`))
+	}))
+	defer srv.Close()
+	c := &Client{Client: srv.Client(), CloudURL: srv.URL}
+	ctx := context.Background()
+
+	e, err := c.CloudEval(ctx, startFEN)
+	if err != nil {
+		t.Fatalf("CloudEval: %v", err)
+	}
+	if e.Depth != 36 || e.PVs[0].CP != 18 || len(e.PVs[0].UCIMoves()) != 3 {
+		t.Errorf("CloudEval = %+v, want depth 36, cp 18 and 3 moves", e)
+	}
+
+	// A position without an evaluation is not asked about twice
+	const other = "8/8/8/8/8/8/8/K1k5 w - - 0 1"
+	c.last = time.Time{}
+	for range 2 {
+		if _, err := c.CloudEval(ctx, other); !errors.Is(err, ErrNotFound) {
+			t.Errorf("CloudEval of an unknown position = %v, want %v", err, ErrNotFound)
+		}
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("%d requests made, want 2", n)
+	}
+}
@@ -0,0 +1,483 @@
+// Package gamedb provides a local database of imported games, indexed by
+// position so the games reaching a position can be found quickly.
+package gamedb
+
+import (
+	"cmp"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// Key layout:
+//
+//	"n"                         next game ID (4 bytes)
+//	"g" + id (4 bytes)          game (JSON)
+//	"p" + hash (8) + id (4)     ply at which the game first reaches the position
+//	"s" + pawns (8) + id (4)    ply at which the game first has the pawn structure
+//	"m" + material (8) + id (4) ply at which the game first has the material
+//
+// Plies are stored in 2 bytes.
+const (
+	keyNextID      = "n"
+	prefixGame     = 'g'
+	prefixPosHash  = 'p'
+	prefixPawns    = 's'
+	prefixMaterial = 'm'
+)
+
+// maxSimilarCandidates limits the games compared by SearchSimilar for each
+// of the pawn structure and the material.
+const maxSimilarCandidates = 500
+
+// progressInterval is the number of games between progress reports.
+const progressInterval = 1000
+
+// ErrUnsupportedFormat is returned for database files that cannot be imported.
+var ErrUnsupportedFormat = errors.New("unsupported database format")
+
+// nativeFormats are database formats that must be exported to PGN first.
+var nativeFormats = map[string]string{
+	".si4": "SCID",
+	".si5": "SCID",
+	".cbh": "ChessBase",
+	".cbv": "ChessBase",
+}
+
+// Header is the summary of a game shown in search results.
+type Header struct {
+	ID       uint32 `json:"-"`
+	White    string `json:"white,omitempty"`
+	Black    string `json:"black,omitempty"`
+	WhiteElo int    `json:"white_elo,omitempty"`
+	BlackElo int    `json:"black_elo,omitempty"`
+	Event    string `json:"event,omitempty"`
+	Site     string `json:"site,omitempty"`
+	Date     string `json:"date,omitempty"`
+	Result   string `json:"result,omitempty"`
+	ECO      string `json:"eco,omitempty"`
+	Plies    int    `json:"plies"`
+}
+
+// Game is a stored game.
+type Game struct {
+	Header
+	FEN   string       // Start position ("" = standard)
+	Moves []board.Move // Main line
+}
+
+// gameEntry is the stored form of a game.
+type gameEntry struct {
+	Header
+	FEN   string `json:"fen,omitempty"`
+	Moves []byte `json:"moves"` // 2 bytes per move
+}
+
+// Match is a game reaching a searched position, or a similar one.
+type Match struct {
+	Header
+	Ply        int     // Ply at which the game reaches the position
+	Similarity float64 // 1 for the searched position, see Similarity
+}
+
+// ImportStats reports the progress of an import.
+type ImportStats struct {
+	Games     int // Games imported
+	Skipped   int // Games that could not be read
+	Positions int // Positions indexed
+}
+
+// DB is a game database.
+type DB struct {
+	db *badger.DB
+	mu sync.Mutex // Serializes imports
+}
+
+// Open opens the database in dir, creating it if needed.
+func Open(dir string) (*DB, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	opts := badger.DefaultOptions(dir)
+	opts.Logger = nil // Disable logging
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{db: db}, nil
+}
+
+// Close closes the database.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// ImportFile imports the games of a PGN file, optionally gzip-compressed.
+// SCID and ChessBase databases must be exported to PGN first.
+func (d *DB) ImportFile(path string, progress func(ImportStats)) (ImportStats, error) {
+	name := strings.ToLower(path)
+	if format, ok := nativeFormats[filepath.Ext(name)]; ok {
+		return ImportStats{}, fmt.Errorf("%w: %s database (export it to PGN first)", ErrUnsupportedFormat, format)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ImportStats{}, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch {
+	case strings.HasSuffix(name, ".pgn.gz"):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return ImportStats{}, err
+		}
+		defer gz.Close()
+		r = gz
+	case strings.HasSuffix(name, ".pgn"):
+	default:
+		return ImportStats{}, fmt.Errorf("%w: %s", ErrUnsupportedFormat, filepath.Ext(name))
+	}
+	return d.Import(r, progress)
+}
+
+// Import imports the games of a PGN stream. Games that cannot be read are
+// skipped and counted. progress, if not nil, is called periodically and when
+// the import finishes.
+func (d *DB) Import(r io.Reader, progress func(ImportStats)) (ImportStats, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var stats ImportStats
+	id, err := d.nextID()
+	if err != nil {
+		return stats, err
+	}
+
+	wb := d.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	pr := NewPGNReader(r)
+	for {
+		g, err := pr.Next()
+		if err == io.EOF {
+			break
+		}
+		var pgnErr *PGNError
+		if errors.As(err, &pgnErr) {
+			stats.Skipped++
+			continue
+		}
+		if err != nil {
+			return stats, err
+		}
+
+		n, err := writeGame(wb, id, g)
+		if err != nil {
+			return stats, err
+		}
+		id++
+		stats.Games++
+		stats.Positions += n
+		if stats.Games%progressInterval == 0 {
+			// Batches are committed as they fill, so keep the next ID with them
+			if err := setNextID(wb, id); err != nil {
+				return stats, err
+			}
+			if progress != nil {
+				progress(stats)
+			}
+		}
+	}
+
+	if err := setNextID(wb, id); err != nil {
+		return stats, err
+	}
+	if err := wb.Flush(); err != nil {
+		return stats, err
+	}
+	if progress != nil {
+		progress(stats)
+	}
+	return stats, nil
+}
+
+// nextID returns the ID of the next imported game.
+func (d *DB) nextID() (uint32, error) {
+	var id uint32
+	err := d.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyNextID))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			id = binary.BigEndian.Uint32(val)
+			return nil
+		})
+	})
+	return id, err
+}
+
+func setNextID(wb *badger.WriteBatch, id uint32) error {
+	return wb.Set([]byte(keyNextID), binary.BigEndian.AppendUint32(nil, id))
+}
+
+// writeGame adds a game and its positions to the batch, and returns the
+// number of positions indexed.
+func writeGame(wb *badger.WriteBatch, id uint32, g *PGNGame) (int, error) {
+	e := gameEntry{Header: headerFromTags(g.Tags), FEN: g.FEN, Moves: make([]byte, 0, 2*len(g.Moves))}
+	e.Plies = len(g.Moves)
+	for _, m := range g.Moves {
+		e.Moves = binary.BigEndian.AppendUint16(e.Moves, uint16(m))
+	}
+	data, err := json.Marshal(&e)
+	if err != nil {
+		return 0, err
+	}
+	if err := wb.Set(gameKey(id), data); err != nil {
+		return 0, err
+	}
+
+	pos, err := startPosition(g.FEN)
+	if err != nil {
+		return 0, err
+	}
+
+	// Index the first ply of each position, pawn structure and material
+	seen := make(map[uint64]bool, len(g.Moves)+1)
+	seenPawns := make(map[uint64]bool)
+	seenMaterial := make(map[uint64]bool)
+	index := func(prefix byte, key uint64, seen map[uint64]bool, ply int) error {
+		if seen[key] {
+			return nil
+		}
+		seen[key] = true
+		return wb.Set(indexKey(prefix, key, id), binary.BigEndian.AppendUint16(nil, uint16(ply)))
+	}
+	for ply := 0; ; ply++ {
+		if err := index(prefixPosHash, PositionHash(pos), seen, ply); err != nil {
+			return 0, err
+		}
+		if err := index(prefixPawns, pos.PawnKey, seenPawns, ply); err != nil {
+			return 0, err
+		}
+		if err := index(prefixMaterial, materialKey(pos), seenMaterial, ply); err != nil {
+			return 0, err
+		}
+		if ply == len(g.Moves) {
+			break
+		}
+		pos.MakeMove(g.Moves[ply])
+	}
+	return len(seen), nil
+}
+
+// headerFromTags builds a game header from its PGN tags.
+func headerFromTags(tags map[string]string) Header {
+	elo := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	return Header{
+		White:    tags["White"],
+		Black:    tags["Black"],
+		WhiteElo: elo(tags["WhiteElo"]),
+		BlackElo: elo(tags["BlackElo"]),
+		Event:    tags["Event"],
+		Site:     tags["Site"],
+		Date:     tags["Date"],
+		Result:   tags["Result"],
+		ECO:      tags["ECO"],
+	}
+}
+
+// startPosition returns the position a game starts from.
+func startPosition(fen string) (*board.Position, error) {
+	if fen == "" {
+		return board.NewPosition(), nil
+	}
+	return board.ParseFEN(fen)
+}
+
+func gameKey(id uint32) []byte {
+	return binary.BigEndian.AppendUint32([]byte{prefixGame}, id)
+}
+
+func indexKey(prefix byte, key uint64, id uint32) []byte {
+	return binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint64([]byte{prefix}, key), id)
+}
+
+// Count returns the number of games in the database.
+func (d *DB) Count() (int, error) {
+	id, err := d.nextID()
+	return int(id), err
+}
+
+// Game returns the game with the given ID.
+func (d *DB) Game(id uint32) (*Game, error) {
+	var g *Game
+	err := d.db.View(func(txn *badger.Txn) error {
+		var err error
+		g, err = readGame(txn, id)
+		return err
+	})
+	return g, err
+}
+
+// readGame reads a game in a transaction.
+func readGame(txn *badger.Txn, id uint32) (*Game, error) {
+	item, err := txn.Get(gameKey(id))
+	if err != nil {
+		return nil, err
+	}
+	var e gameEntry
+	if err := item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &e)
+	}); err != nil {
+		return nil, err
+	}
+
+	g := &Game{Header: e.Header, FEN: e.FEN, Moves: make([]board.Move, len(e.Moves)/2)}
+	g.ID = id
+	for i := range g.Moves {
+		g.Moves[i] = board.Move(binary.BigEndian.Uint16(e.Moves[2*i:]))
+	}
+	return g, nil
+}
+
+// PositionHash returns the key positions are indexed by: the Zobrist hash,
+// without the en passant file when no en passant capture is legal, so that
+// move orders reaching the same position match.
+func PositionHash(pos *board.Position) uint64 {
+	if pos.EnPassant == board.NoSquare {
+		return pos.Hash
+	}
+	legal := pos.GenerateLegalMoves()
+	for i := 0; i < legal.Len(); i++ {
+		if legal.Get(i).IsEnPassant() {
+			return pos.Hash
+		}
+	}
+	return pos.Hash ^ board.ZobristEnPassant(pos.EnPassant.File())
+}
+
+// Search returns up to limit games reaching pos, in import order, and the
+// total number of such games.
+func (d *DB) Search(pos *board.Position, limit int) ([]Match, int, error) {
+	hash := PositionHash(pos)
+	var matches []Match
+	total := 0
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = binary.BigEndian.AppendUint64([]byte{prefixPosHash}, hash)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			total++
+			if len(matches) >= limit {
+				continue
+			}
+			item := it.Item()
+			id := binary.BigEndian.Uint32(item.Key()[len(opts.Prefix):])
+			var ply int
+			if err := item.Value(func(val []byte) error {
+				ply = int(binary.BigEndian.Uint16(val))
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			g, err := readGame(txn, id)
+			if err != nil {
+				return err
+			}
+			matches = append(matches, Match{Header: g.Header, Ply: ply, Similarity: 1})
+		}
+		return nil
+	})
+	return matches, total, err
+}
+
+// SearchSimilar returns up to limit games that do not reach pos but reach a
+// position with the same pawn structure or the same material, most similar
+// first. Each match is at the game's position most similar to pos.
+func (d *DB) SearchSimilar(pos *board.Position, limit int) ([]Match, error) {
+	hash := PositionHash(pos)
+	var matches []Match
+	err := d.db.View(func(txn *badger.Txn) error {
+		ids := make(map[uint32]bool)
+		for _, prefix := range [][]byte{
+			binary.BigEndian.AppendUint64([]byte{prefixPawns}, pos.PawnKey),
+			binary.BigEndian.AppendUint64([]byte{prefixMaterial}, materialKey(pos)),
+		} {
+			opts := badger.DefaultIteratorOptions
+			opts.PrefetchValues = false
+			opts.Prefix = prefix
+			it := txn.NewIterator(opts)
+			n := 0
+			for it.Rewind(); it.Valid() && n < maxSimilarCandidates; it.Next() {
+				ids[binary.BigEndian.Uint32(it.Item().Key()[len(prefix):])] = true
+				n++
+			}
+			it.Close()
+		}
+
+		for id := range ids {
+			g, err := readGame(txn, id)
+			if err != nil {
+				return err
+			}
+			if m, ok := mostSimilar(g, pos, hash); ok {
+				matches = append(matches, m)
+			}
+		}
+		return nil
+	})
+
+	slices.SortFunc(matches, func(a, b Match) int {
+		return cmp.Or(cmp.Compare(b.Similarity, a.Similarity), cmp.Compare(a.ID, b.ID))
+	})
+	return matches[:min(limit, len(matches))], err
+}
+
+// mostSimilar finds the position of a game most similar to pos. It returns
+// false if the game reaches pos, so exact matches are not repeated.
+func mostSimilar(g *Game, pos *board.Position, hash uint64) (Match, bool) {
+	p, err := startPosition(g.FEN)
+	if err != nil {
+		return Match{}, false
+	}
+	best := Match{Header: g.Header, Similarity: -1}
+	for ply := 0; ; ply++ {
+		if PositionHash(p) == hash {
+			return Match{}, false
+		}
+		if s := Similarity(p, pos); s > best.Similarity {
+			best.Ply, best.Similarity = ply, s
+		}
+		if ply == len(g.Moves) {
+			break
+		}
+		p.MakeMove(g.Moves[ply])
+	}
+	return best, true
+}
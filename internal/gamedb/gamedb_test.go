@@ -0,0 +1,253 @@
+package gamedb
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+const testPGN = `[Event "Test"]
+[White "Alice"]
+[Black "Bob"]
+[WhiteElo "2100"]
+[Result "1-0"]
+
+1. e4 {best by test} e5 (1... c5 2. Nf3) 2. Nf3 Nc6 3. Bb5 $1 a6 4. Ba4 Nf6
+5. O-O 1-0
+
+[Event "Bad"]
+[White "Carol"]
+
+1. e4 e5 2. Ke3 Kd7 *
+
+[Event "Transposition"]
+[White "Dave"]
+[Black "Eve"]
+[Result "1/2-1/2"]
+
+1. Nf3 Nc6 2. e4 e5 ; line comment 3. d4
+3. Bc4 1/2-1/2
+
+[Event "Castling"]
+[White "Frank"]
+
+1. e4 e5 2. O-O *
+
+[FEN "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"]
+[SetUp "1"]
+[White "Grace"]
+
+1. e4 Kd7 *
+`
+
+func TestPGNReader(t *testing.T) {
+	pr := NewPGNReader(strings.NewReader(testPGN))
+	var games []*PGNGame
+	skipped := 0
+	for {
+		g, err := pr.Next()
+		if err == io.EOF {
+			break
+		}
+		var pgnErr *PGNError
+		if errors.As(err, &pgnErr) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		games = append(games, g)
+	}
+
+	if len(games) != 3 || skipped != 2 {
+		t.Fatalf("read %d games and skipped %d, want 3 and 2", len(games), skipped)
+	}
+	if g := games[0]; g.Tags["White"] != "Alice" || len(g.Moves) != 9 || g.Moves[8].String() != "e1g1" {
+		t.Errorf("first game: %v, %d moves", g.Tags, len(g.Moves))
+	}
+	if g := games[1]; len(g.Moves) != 5 {
+		t.Errorf("second game has %d moves, want 5 (the line comment ends the line)", len(g.Moves))
+	}
+	if g := games[2]; g.FEN == "" || len(g.Moves) != 2 {
+		t.Errorf("FEN game: %q, %d moves", g.FEN, len(g.Moves))
+	}
+}
+
+func TestImportAndSearch(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Import the same games twice, the second time compressed
+	stats, err := db.Import(strings.NewReader(testPGN), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Games != 3 || stats.Skipped != 2 {
+		t.Fatalf("imported %+v, want 3 games and 2 skipped", stats)
+	}
+
+	path := filepath.Join(dir, "games.pgn.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(testPGN))
+	gz.Close()
+	f.Close()
+	if _, err := db.ImportFile(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := db.Count(); n != 6 {
+		t.Fatalf("Count = %d, want 6", n)
+	}
+
+	// 1.e4 e5 2.Nf3 Nc6 is reached by both move orders
+	pos := board.NewPosition()
+	for _, s := range []string{"e2e4", "e7e5", "g1f3", "b8c6"} {
+		m, err := board.ParseMove(s, pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pos.MakeMove(m)
+	}
+	matches, total, err := db.Search(pos, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 4 || len(matches) != 3 {
+		t.Fatalf("Search found %d of %d games, want 3 of 4", len(matches), total)
+	}
+	if m := matches[0]; m.White != "Alice" || m.WhiteElo != 2100 || m.Ply != 4 || m.Plies != 9 {
+		t.Errorf("first match = %+v", m)
+	}
+	if m := matches[1]; m.White != "Dave" || m.Ply != 4 {
+		t.Errorf("second match = %+v", m)
+	}
+
+	g, err := db.Game(matches[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Moves) != 5 || g.Moves[0].String() != "g1f3" {
+		t.Errorf("Game(%d) = %v", matches[1].ID, g.Moves)
+	}
+
+	if _, err := db.ImportFile(filepath.Join(dir, "games.si4"), nil); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("ImportFile(.si4) = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	pos := func(fen string) *board.Position {
+		p, err := board.ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	start := board.NewPosition()
+	if s := Similarity(start, start); s != 1 {
+		t.Errorf("Similarity(start, start) = %v, want 1", s)
+	}
+
+	// Pieces moved, same material and pawns
+	developed := pos("r1bqkb1r/pppppppp/2n2n2/8/8/2N2N2/PPPPPPPP/R1BQKB1R w KQkq - 4 3")
+	if s := Similarity(start, developed); s != 1 {
+		t.Errorf("Similarity after developing knights = %v, want 1", s)
+	}
+
+	// A pawn moved, and a pawn moved and a knight traded
+	e4 := pos("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	traded := pos("r1bqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/R1BQKBNR b KQkq - 0 1")
+	s1, s2 := Similarity(start, e4), Similarity(start, traded)
+	if !(s1 < 1 && s2 < s1) {
+		t.Errorf("Similarity: e4 %v, e4 and knights traded %v; want 1 > e4 > traded", s1, s2)
+	}
+	if materialKey(start) != materialKey(developed) || materialKey(start) == materialKey(traded) {
+		t.Error("materialKey does not follow the material")
+	}
+}
+
+func TestSearchSimilar(t *testing.T) {
+	db, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Import(strings.NewReader(testPGN), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1.e4 e5 2.Nf3 Nf6: the pawns of both games, reached by neither
+	pos, err := board.ParseFEN("rnbqkb1r/pppp1ppp/5n2/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := db.SearchSimilar(pos, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("SearchSimilar found %d games, want 2", len(matches))
+	}
+	for _, m := range matches {
+		if m.Similarity != 1 {
+			t.Errorf("match %s: similarity %v at ply %d, want 1", m.White, m.Similarity, m.Ply)
+		}
+	}
+
+	// Games reaching the position are exact matches, not similar ones
+	if matches, _ := db.SearchSimilar(board.NewPosition(), 10); len(matches) != 0 {
+		t.Errorf("SearchSimilar(start) = %+v, want none", matches)
+	}
+}
+
+// FuzzPGNReader reads arbitrary text as a PGN file: every game is either
+// read or reported as a *PGNError, and reading always reaches the end.
+func FuzzPGNReader(f *testing.F) {
+	f.Add(testPGN)
+	f.Add("[Event \"x\"]\n\n1. e4 {unclosed comment\n")
+	f.Add("1. e4 (1. d4 (1... d5) e5 2. Nf3 *")
+	f.Add("[FEN \"8/8/8/8/8/8/8/8 w - - 0 1\"]\n\n1. Ke2 *")
+	f.Add("[White\n1. e8= 2. O-O-O+ 3. $1 ; comment\n%escape\n*")
+	f.Fuzz(func(t *testing.T, pgn string) {
+		pr := NewPGNReader(strings.NewReader(pgn))
+		for i := 0; ; i++ {
+			if i > len(pgn)+1 {
+				t.Fatal("reader does not reach the end")
+			}
+			g, err := pr.Next()
+			if err == io.EOF {
+				return
+			}
+			var pgnErr *PGNError
+			if err != nil && !errors.As(err, &pgnErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if g != nil {
+				pos := board.NewPosition()
+				if g.FEN != "" {
+					pos, _ = board.ParseFEN(g.FEN)
+				}
+				for _, m := range g.Moves {
+					if !pos.GenerateLegalMoves().Contains(m) {
+						t.Fatalf("game has illegal move %v", m)
+					}
+					pos.MakeMove(m)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package gamedb
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// PGNGame is one game read from a PGN file.
+type PGNGame struct {
+	Tags  map[string]string
+	FEN   string       // Start position ("" = standard)
+	Moves []board.Move // Main line; variations and comments are dropped
+}
+
+// PGNError is returned by PGNReader.Next for a game that cannot be read.
+// The reader has skipped the game, so reading can continue.
+type PGNError struct {
+	Line int // Line of the game's first tag
+	Err  error
+}
+
+func (e *PGNError) Error() string {
+	return fmt.Sprintf("pgn: game at line %d: %v", e.Line, e.Err)
+}
+
+func (e *PGNError) Unwrap() error {
+	return e.Err
+}
+
+// PGNReader reads the games of a PGN file one at a time, so files with
+// millions of games are not held in memory.
+type PGNReader struct {
+	r       *bufio.Reader
+	line    int
+	pending string // Tag line that started the next game
+	hasLine bool
+}
+
+// NewPGNReader returns a reader for the PGN games in r.
+func NewPGNReader(r io.Reader) *PGNReader {
+	return &PGNReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// readLine returns the next line without its line ending.
+func (pr *PGNReader) readLine() (string, error) {
+	if pr.hasLine {
+		pr.hasLine = false
+		return pr.pending, nil
+	}
+	s, err := pr.r.ReadString('\n')
+	if err == io.EOF && s != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	pr.line++
+	return strings.TrimRight(s, "\r\n"), nil
+}
+
+// Next returns the next game, or io.EOF after the last one. A game that
+// cannot be read is returned as a *PGNError; the following games can still
+// be read.
+func (pr *PGNReader) Next() (*PGNGame, error) {
+	tags := make(map[string]string)
+	var movetext strings.Builder
+	startLine := 0
+	inMoves := false
+	commentDepth := 0
+
+	for {
+		line, err := pr.readLine()
+		if err == io.EOF {
+			if startLine == 0 {
+				return nil, io.EOF
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if startLine == 0 {
+			startLine = pr.line
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if commentDepth == 0 && strings.HasPrefix(trimmed, "[") {
+			if inMoves {
+				// The next game's tags
+				pr.pending, pr.hasLine = line, true
+				break
+			}
+			if key, value, ok := parseTag(trimmed); ok {
+				tags[key] = value
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "%") || trimmed == "" && movetext.Len() == 0 {
+			continue // Escape lines, and blank lines before the moves
+		}
+		if trimmed != "" {
+			inMoves = true
+			commentDepth += strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
+			commentDepth = max(commentDepth, 0)
+		}
+		movetext.WriteString(line)
+		movetext.WriteString("\n")
+	}
+
+	g, err := parseGame(tags, movetext.String())
+	if err != nil {
+		return nil, &PGNError{Line: startLine, Err: err}
+	}
+	return g, nil
+}
+
+// parseTag parses a tag pair line: [Key "Value"].
+func parseTag(line string) (key, value string, ok bool) {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+	key, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return "", "", false
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(rest))
+	if err != nil {
+		value = strings.Trim(strings.TrimSpace(rest), "\"")
+	}
+	return key, value, true
+}
+
+// errNoMoves is returned for a game with neither tags nor moves.
+var errNoMoves = errors.New("empty game")
+
+// parseGame replays the main line of a game's movetext.
+func parseGame(tags map[string]string, movetext string) (*PGNGame, error) {
+	if len(tags) == 0 && strings.TrimSpace(movetext) == "" {
+		return nil, errNoMoves
+	}
+
+	g := &PGNGame{Tags: tags}
+	pos := board.NewPosition()
+	if fen := tags["FEN"]; fen != "" {
+		var err error
+		if pos, err = board.ParseFEN(fen); err != nil {
+			return nil, err
+		}
+		g.FEN = fen
+	}
+	pos.UpdateCheckers()
+
+	for _, tok := range sanTokens(movetext) {
+		m, err := board.ParseSAN(tok, pos)
+		if err != nil {
+			return nil, fmt.Errorf("move %d (%s): %w", len(g.Moves)+1, tok, err)
+		}
+		g.Moves = append(g.Moves, m)
+		pos.MakeMove(m)
+		pos.UpdateCheckers()
+	}
+	return g, nil
+}
+
+// sanTokens returns the SAN moves of a movetext, dropping comments,
+// variations, move numbers, NAGs, annotations and the result.
+func sanTokens(movetext string) []string {
+	var tokens []string
+	var sb strings.Builder
+	commentDepth, variationDepth := 0, 0
+	lineComment := false
+
+	emit := func() {
+		tok := strings.TrimRight(sb.String(), "+#!?")
+		sb.Reset()
+		if i := strings.LastIndexByte(tok, '.'); i >= 0 {
+			tok = tok[i+1:] // "12.e4" and "12..."
+		}
+		switch tok {
+		case "", "*", "1-0", "0-1", "1/2-1/2":
+			return
+		}
+		if tok[0] == '$' {
+			return
+		}
+		tokens = append(tokens, tok)
+	}
+
+	for _, r := range movetext {
+		switch {
+		case lineComment:
+			lineComment = r != '\n'
+		case r == '{':
+			commentDepth++
+		case r == '}':
+			commentDepth = max(commentDepth-1, 0)
+		case commentDepth > 0:
+		case r == ';':
+			emit()
+			lineComment = true
+		case r == '(':
+			emit()
+			variationDepth++
+		case r == ')':
+			variationDepth = max(variationDepth-1, 0)
+		case variationDepth > 0:
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			emit()
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	emit()
+	return tokens
+}
@@ -0,0 +1,64 @@
+package gamedb
+
+import "github.com/hailam/chessplay/internal/board"
+
+// Weights of the parts of Similarity
+const (
+	materialWeight = 0.5
+	pawnWeight     = 0.5
+)
+
+// maxMaterialDiff is the material difference, in pawns, at which positions
+// are no longer alike in material.
+const maxMaterialDiff = 12
+
+// pieceValues are the piece values used to compare material, in pawns.
+var pieceValues = [...]int{board.Pawn: 1, board.Knight: 3, board.Bishop: 3, board.Rook: 5, board.Queen: 9}
+
+// Similarity returns how alike two positions are, from 0 to 1: 1 for the same
+// material and pawn structure. Pieces other than pawns are compared by
+// material only, since their squares change from move to move while the
+// material balance and the pawns shape the plans of a position.
+func Similarity(a, b *board.Position) float64 {
+	return materialWeight*materialSimilarity(a, b) + pawnWeight*pawnSimilarity(a, b)
+}
+
+// materialSimilarity compares the pieces of each side, weighted by value.
+func materialSimilarity(a, b *board.Position) float64 {
+	diff := 0
+	for c := board.White; c <= board.Black; c++ {
+		for pt := board.Pawn; pt <= board.Queen; pt++ {
+			n := a.Pieces[c][pt].PopCount() - b.Pieces[c][pt].PopCount()
+			diff += max(n, -n) * pieceValues[pt]
+		}
+	}
+	return 1 - float64(min(diff, maxMaterialDiff))/maxMaterialDiff
+}
+
+// pawnSimilarity is the share of pawns on the same squares (the Jaccard
+// index of the pawn bitboards), averaged over both sides.
+func pawnSimilarity(a, b *board.Position) float64 {
+	sum := 0.0
+	for c := board.White; c <= board.Black; c++ {
+		pa, pb := a.Pieces[c][board.Pawn], b.Pieces[c][board.Pawn]
+		union := (pa | pb).PopCount()
+		if union == 0 {
+			sum++
+			continue
+		}
+		sum += float64((pa & pb).PopCount()) / float64(union)
+	}
+	return sum / 2
+}
+
+// materialKey packs the piece counts of a position, 4 bits per piece type
+// and side, to find the games with the same material.
+func materialKey(pos *board.Position) uint64 {
+	var key uint64
+	for c := board.White; c <= board.Black; c++ {
+		for pt := board.Pawn; pt <= board.Queen; pt++ {
+			key = key<<4 | uint64(min(pos.Pieces[c][pt].PopCount(), 15))
+		}
+	}
+	return key
+}
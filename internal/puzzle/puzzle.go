@@ -0,0 +1,81 @@
+// Package puzzle provides tactics puzzles and the puzzle rush mode: solve as
+// many puzzles as possible against the clock.
+package puzzle
+
+import (
+	"fmt"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// Puzzle is a position with a forced winning line.
+type Puzzle struct {
+	FEN   string   // Start position, solver to move
+	Moves []string // Solution in UCI notation: solver, reply, solver, ...
+}
+
+// Puzzles is the built-in puzzle set. Every solution is checked against the
+// engine by the package tests.
+var Puzzles = []Puzzle{
+	{"6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1", []string{"d1d8"}},                                              // Rd8#
+	{"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4", []string{"h5f7"}},                // Qxf7#
+	{"6rk/6pp/8/6N1/8/8/8/6K1 w - - 0 1", []string{"g5f7"}},                                                  // Nf7#
+	{"rn1qkbnr/ppp2p1p/3p2p1/4N3/2B1P3/2N5/PPPP1PPP/R1BbK2R w KQkq - 0 6", []string{"c4f7", "e8e7", "c3d5"}}, // Bxf7+ Ke7 Nd5#
+	{"4r1k1/5ppp/8/8/1q6/8/5PPP/2Q1R1K1 w - - 0 1", []string{"e1e8"}},                                        // Rxe8+
+	{"r3k3/8/8/8/8/8/5N2/4K2R w K - 0 1", []string{"h1h8"}},                                                  // Rh8+
+	{"6k1/6p1/6Kp/8/8/8/8/R7 w - - 0 1", []string{"a1a8"}},                                                   // Ra8#
+	{"3r2k1/5ppp/8/8/8/8/5PPP/3Q2K1 w - - 0 1", []string{"d1d8"}},                                            // Qxd8#
+	{"r1bqk2r/pppp1ppp/2n2n2/2b1p1N1/2B1P3/8/PPPP1PPP/RNBQK2R w KQkq - 6 5", []string{"g5f7"}},               // Nxf7
+	{"4k3/R7/4K3/8/8/8/8/8 w - - 0 1", []string{"a7a8"}},                                                     // Ra8#
+	{"2r3k1/pp3ppp/8/3N4/8/8/PP3PPP/6K1 w - - 0 1", []string{"d5e7"}},                                        // Ne7+
+	{"6k1/5ppp/8/8/8/1b6/5PPP/3R2K1 b - - 0 1", []string{"b3d1"}},                                            // Bxd1
+	{"k7/8/1K6/8/8/8/8/7R w - - 0 1", []string{"h1h8"}},                                                      // Rh8#
+	{"rnbqkbnr/ppppp2p/5p2/6p1/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3", []string{"d1h5"}},                      // Qh5#
+	{"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2", []string{"d8h4"}},                      // Qh4#
+	{"r1b2rk1/pppp1ppp/2n5/2b1p1N1/2B1P1nq/2NP4/PPP2PPP/R1BQ1RK1 b - - 0 8", []string{"h4h2"}},               // Qxh2#
+	{"2r3k1/5ppp/8/8/8/8/1q3PPP/2R3K1 b - - 0 1", []string{"c8c1"}},                                          // Rxc1#
+	{"q3k3/8/8/8/8/8/8/4K1R1 w - - 0 1", []string{"g1g8"}},                                                   // Rg8+
+	{"4r1k1/pp3ppp/8/8/8/8/PP2QPPP/6K1 b - - 0 1", []string{"e8e2"}},                                         // Rxe2
+	{"r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5Q2/PPPP1PPP/RNB1K1NR w KQkq - 2 3", []string{"f3f7"}},                 // Qxf7#
+	{"r6k/6pp/8/8/8/8/6PP/R3Q1K1 w - - 0 1", []string{"a1a8"}},                                               // Rxa8#
+	{"6k1/5ppp/8/8/8/1Q6/5PPP/6K1 w - - 0 1", []string{"b3b8"}},                                              // Qb8#
+	{"7k/5Qpp/8/8/8/8/6PP/6K1 w - - 0 1", []string{"f7e8"}},                                                  // Qe8#
+	{"3r3k/6pp/8/8/8/8/6PP/3R2K1 w - - 0 1", []string{"d1d8"}},                                               // Rxd8#
+	{"3q2k1/5ppp/8/8/8/8/5PPP/1R1Q2K1 w - - 0 1", []string{"d1d8"}},                                          // Qxd8#
+	{"2r4k/6pp/8/8/4n3/8/5PPP/2R3K1 b - - 0 1", []string{"c8c1"}},                                            // Rxc1#
+}
+
+// Position returns the start position of the puzzle.
+func (p Puzzle) Position() (*board.Position, error) {
+	pos, err := board.ParseFEN(p.FEN)
+	if err != nil {
+		return nil, err
+	}
+	pos.UpdateCheckers()
+	return pos, nil
+}
+
+// Move returns solution move i as a legal move in pos, the position after
+// the first i moves.
+func (p Puzzle) Move(i int, pos *board.Position) (board.Move, error) {
+	return legalMove(pos, p.Moves[i])
+}
+
+// legalMove finds the legal move with the given UCI notation.
+func legalMove(pos *board.Position, uci string) (board.Move, error) {
+	legal := pos.GenerateLegalMoves()
+	for i := 0; i < legal.Len(); i++ {
+		if m := legal.Get(i); m.String() == uci {
+			return m, nil
+		}
+	}
+	return board.NoMove, fmt.Errorf("puzzle: %w %s in %s", board.ErrIllegalMove, uci, pos.ToFEN())
+}
+
+// givesMate returns whether m checkmates in pos.
+func givesMate(pos *board.Position, m board.Move) bool {
+	after := pos.Copy()
+	after.MakeMove(m)
+	after.UpdateCheckers()
+	return after.IsCheckmate()
+}
@@ -0,0 +1,120 @@
+package puzzle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+// TestPuzzleSolutions checks that every solution is legal and that the engine
+// finds each solver move.
+func TestPuzzleSolutions(t *testing.T) {
+	for _, p := range Puzzles {
+		pos, err := p.Position()
+		if err != nil {
+			t.Errorf("%s: %v", p.FEN, err)
+			continue
+		}
+		if len(p.Moves)%2 == 0 {
+			t.Errorf("%s: solution must end with a solver move", p.FEN)
+		}
+		for i := range p.Moves {
+			m, err := p.Move(i, pos)
+			if err != nil {
+				t.Errorf("%s: %v", p.FEN, err)
+				break
+			}
+			if i%2 == 0 {
+				eng := engine.NewEngine(16)
+				best := eng.SearchWithLimits(pos.Copy(), engine.SearchLimits{Depth: 8})
+				if best != m && !(givesMate(pos, m) && givesMate(pos, best)) {
+					t.Errorf("%s: engine plays %v instead of %s at move %d", p.FEN, best, p.Moves[i], i+1)
+				}
+			}
+			pos.MakeMove(m)
+			pos.UpdateCheckers()
+		}
+	}
+}
+
+func TestRush(t *testing.T) {
+	start := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	r := NewRush(RushShort, start, 1)
+	if r.Over(start) || r.Remaining(start.Add(time.Minute)) != 2*time.Minute {
+		t.Fatalf("Expected a running rush with 2 minutes left, got %v", r.Remaining(start.Add(time.Minute)))
+	}
+	if !r.Over(start.Add(RushShort)) {
+		t.Errorf("Expected the rush to be over when time is up")
+	}
+
+	// Solve the first puzzle move by move
+	p := r.Puzzle()
+	pos := r.Position()
+	for i := 0; i < len(p.Moves); i += 2 {
+		m, _ := p.Move(i, pos)
+		result, reply := r.Play(m)
+		pos.MakeMove(m)
+		if i+1 == len(p.Moves) {
+			if result != Solved {
+				t.Fatalf("%s: expected Solved, got %v", p.FEN, result)
+			}
+			break
+		}
+		if result != Correct || reply.String() != p.Moves[i+1] {
+			t.Fatalf("%s: expected Correct with reply %s, got %v %v", p.FEN, p.Moves[i+1], result, reply)
+		}
+		pos.MakeMove(reply)
+		pos.UpdateCheckers()
+	}
+	if r.Score != 1 {
+		t.Errorf("Expected score 1, got %d", r.Score)
+	}
+
+	// Wrong moves count as mistakes until the rush ends
+	for r.Mistakes < MaxMistakes {
+		if !r.Next() {
+			t.Fatal("Ran out of puzzles")
+		}
+		p := r.Puzzle()
+		pos := r.Position()
+		solution, _ := p.Move(0, pos)
+		legal := pos.GenerateLegalMoves()
+		var wrong board.Move
+		for i := 0; i < legal.Len(); i++ {
+			if m := legal.Get(i); m != solution && !givesMate(pos, m) {
+				wrong = m
+				break
+			}
+		}
+		if result, _ := r.Play(wrong); result != Wrong {
+			t.Fatalf("%s: expected %v to be wrong, got %v", p.FEN, wrong, result)
+		}
+	}
+	if !r.Over(start) {
+		t.Errorf("Expected the rush to be over after %d mistakes", MaxMistakes)
+	}
+}
+
+// TestRushAlternativeMate checks that a mate other than the solution
+// solves a puzzle.
+func TestRushAlternativeMate(t *testing.T) {
+	r := NewRush(RushLong, time.Now(), 1)
+	for {
+		pos := r.Position()
+		solution, _ := r.Puzzle().Move(0, pos)
+		legal := pos.GenerateLegalMoves()
+		for i := 0; i < legal.Len(); i++ {
+			if m := legal.Get(i); m != solution && givesMate(pos, m) {
+				if result, _ := r.Play(m); result != Solved {
+					t.Errorf("%s: expected %v to solve the puzzle, got %v", r.Puzzle().FEN, m, result)
+				}
+				return
+			}
+		}
+		if !r.Next() {
+			t.Fatal("No puzzle with an alternative mate")
+		}
+	}
+}
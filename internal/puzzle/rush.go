@@ -0,0 +1,135 @@
+package puzzle
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// Rush durations offered by the GUI
+const (
+	RushShort = 3 * time.Minute
+	RushLong  = 5 * time.Minute
+)
+
+// MaxMistakes is the number of wrong moves that ends a rush.
+const MaxMistakes = 3
+
+// Result is the outcome of a move played in a rush.
+type Result int
+
+const (
+	Wrong   Result = iota // Not the solution; the puzzle is skipped
+	Correct               // Part of the solution; the reply follows
+	Solved                // Last move of the solution
+)
+
+// Rush is a timed run through shuffled puzzles. It is not safe for
+// concurrent use.
+type Rush struct {
+	Duration time.Duration
+	Score    int // Puzzles solved
+	Mistakes int
+
+	start   time.Time
+	order   []int // Indices into Puzzles
+	current int   // Position in order
+	pos     *board.Position
+	ply     int // Solution moves played in the current puzzle
+}
+
+// NewRush starts a rush of the given length at now, with the puzzle order
+// shuffled by seed.
+func NewRush(duration time.Duration, now time.Time, seed int64) *Rush {
+	r := &Rush{
+		Duration: duration,
+		start:    now,
+		order:    rand.New(rand.NewSource(seed)).Perm(len(Puzzles)),
+		current:  -1,
+	}
+	r.Next()
+	return r
+}
+
+// Remaining returns the time left at now.
+func (r *Rush) Remaining(now time.Time) time.Duration {
+	return max(r.Duration-now.Sub(r.start), 0)
+}
+
+// Over returns whether the rush has ended: time is up, MaxMistakes were
+// made or every puzzle has been played.
+func (r *Rush) Over(now time.Time) bool {
+	return r.Remaining(now) == 0 || r.Mistakes >= MaxMistakes || r.pos == nil
+}
+
+// Next moves on to the next puzzle. It returns false when none are left.
+func (r *Rush) Next() bool {
+	r.pos, r.ply = nil, 0
+	for r.current+1 < len(r.order) {
+		r.current++
+		if pos, err := Puzzles[r.order[r.current]].Position(); err == nil {
+			r.pos = pos
+			return true
+		}
+	}
+	return false
+}
+
+// Puzzle returns the current puzzle.
+func (r *Rush) Puzzle() Puzzle {
+	return Puzzles[r.order[r.current]]
+}
+
+// Position returns a copy of the current puzzle position, or nil when no
+// puzzles are left.
+func (r *Rush) Position() *board.Position {
+	if r.pos == nil {
+		return nil
+	}
+	return r.pos.Copy()
+}
+
+// Solution returns the move the solver is expected to play next, or
+// NoMove when no puzzles are left.
+func (r *Rush) Solution() board.Move {
+	if r.pos == nil {
+		return board.NoMove
+	}
+	m, _ := r.Puzzle().Move(r.ply, r.pos)
+	return m
+}
+
+// Play checks the solver's move. On Correct it plays the opponent's reply,
+// which is returned for display. Any mating move solves the puzzle, since
+// some have more than one mate.
+func (r *Rush) Play(m board.Move) (Result, board.Move) {
+	if r.pos == nil {
+		return Wrong, board.NoMove
+	}
+	p := r.Puzzle()
+	want, err := p.Move(r.ply, r.pos)
+	if err != nil || m != want && !givesMate(r.pos, m) {
+		r.Mistakes++
+		return Wrong, board.NoMove
+	}
+
+	r.pos.MakeMove(m)
+	r.pos.UpdateCheckers()
+	r.ply++
+	if r.ply >= len(p.Moves) || r.pos.GenerateLegalMoves().Len() == 0 {
+		r.Score++
+		return Solved, board.NoMove
+	}
+
+	reply, err := p.Move(r.ply, r.pos)
+	if err != nil {
+		// A broken solution line counts for the solver
+		r.Score++
+		return Solved, board.NoMove
+	}
+	r.pos.MakeMove(reply)
+	r.pos.UpdateCheckers()
+	r.ply++
+	return Correct, reply
+}
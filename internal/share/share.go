@@ -0,0 +1,186 @@
+// Package share generates and parses links to chess positions: chessplay://
+// URIs, which carry the start position and every move, and Lichess analysis
+// URLs, which open the game or position in a browser.
+package share
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// Scheme is the URI scheme of chessplay links.
+const Scheme = "chessplay"
+
+// lichessAnalysis is the Lichess analysis board URL.
+const lichessAnalysis = "https://lichess.org/analysis/"
+
+// ErrUnknownLink is returned for links that are neither chessplay URIs nor
+// Lichess analysis URLs.
+var ErrUnknownLink = errors.New("share: not a chessplay or Lichess analysis link")
+
+// Link is a shared game: a start position and the moves played from it.
+type Link struct {
+	FEN   string       // Start position
+	Moves []board.Move // Legal moves from the start position
+}
+
+// New creates a link for the moves played from a start position
+// ("" for the standard starting position).
+func New(fen string, moves []board.Move) *Link {
+	if fen == "" {
+		fen = board.StartFEN
+	}
+	return &Link{FEN: fen, Moves: moves}
+}
+
+// URI returns the link as a chessplay URI, for example
+// chessplay://position?moves=e2e4,e7e5. The FEN is left out for the
+// standard starting position.
+func (l *Link) URI() string {
+	q := url.Values{}
+	if l.FEN != board.StartFEN {
+		q.Set("fen", l.FEN)
+	}
+	if len(l.Moves) > 0 {
+		moves := make([]string, len(l.Moves))
+		for i, m := range l.Moves {
+			moves[i] = m.String()
+		}
+		q.Set("moves", strings.Join(moves, ","))
+	}
+
+	uri := Scheme + "://position"
+	if len(q) > 0 {
+		uri += "?" + q.Encode()
+	}
+	return uri
+}
+
+// LichessURL returns a Lichess analysis URL. Games from the standard
+// starting position are shared with their moves; Lichess URLs cannot carry
+// moves from another start position, so only the final position is shared.
+func (l *Link) LichessURL() (string, error) {
+	pos, err := l.Position()
+	if err != nil {
+		return "", err
+	}
+
+	if l.FEN == board.StartFEN && len(l.Moves) > 0 {
+		start, _ := board.ParseFEN(board.StartFEN)
+		san := board.MovesToSAN(start, l.Moves)
+		return lichessAnalysis + "pgn/" + url.PathEscape(strings.Join(san, "_")), nil
+	}
+	return lichessAnalysis + fenPath(pos.ToFEN()), nil
+}
+
+// Position replays the moves and returns the final position.
+func (l *Link) Position() (*board.Position, error) {
+	pos, err := board.ParseFEN(l.FEN)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range l.Moves {
+		pos.MakeMove(m)
+	}
+	pos.UpdateCheckers()
+	return pos, nil
+}
+
+// Parse reads a chessplay URI or a Lichess analysis URL. Every move is
+// checked for legality.
+func Parse(link string) (*Link, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return nil, fmt.Errorf("share: %w", err)
+	}
+
+	switch {
+	case u.Scheme == Scheme:
+		return parseURI(u)
+	case (u.Scheme == "https" || u.Scheme == "http") && strings.TrimPrefix(u.Host, "www.") == "lichess.org" &&
+		strings.HasPrefix(u.Path, "/analysis"):
+		return parseLichess(u)
+	}
+	return nil, ErrUnknownLink
+}
+
+// parseURI reads the fen and moves parameters of a chessplay URI.
+func parseURI(u *url.URL) (*Link, error) {
+	q := u.Query()
+	l := New(q.Get("fen"), nil)
+	pos, err := board.ParseFEN(l.FEN)
+	if err != nil {
+		return nil, fmt.Errorf("share: %w", err)
+	}
+
+	if moves := q.Get("moves"); moves != "" {
+		for _, s := range strings.Split(moves, ",") {
+			m, err := legalMove(pos, s)
+			if err != nil {
+				return nil, err
+			}
+			l.Moves = append(l.Moves, m)
+			pos.MakeMove(m)
+		}
+	}
+	return l, nil
+}
+
+// parseLichess reads a Lichess analysis URL: /analysis/pgn/<SAN moves> or
+// /analysis[/standard]/<FEN with underscores>.
+func parseLichess(u *url.URL) (*Link, error) {
+	path := strings.Trim(strings.TrimPrefix(u.Path, "/analysis"), "/")
+	path = strings.TrimPrefix(path, "standard")
+	path = strings.Trim(path, "/")
+
+	if sans, ok := strings.CutPrefix(path, "pgn/"); ok {
+		l := New("", nil)
+		pos, _ := board.ParseFEN(board.StartFEN)
+		for _, tok := range strings.FieldsFunc(sans, func(r rune) bool { return r == '_' || r == ' ' }) {
+			// Move numbers may be attached ("1.e4") or separate ("1.", "1...")
+			if i := strings.LastIndexByte(tok, '.'); i >= 0 {
+				tok = tok[i+1:]
+			}
+			if tok == "" {
+				continue
+			}
+			m, err := board.ParseSAN(tok, pos)
+			if err != nil {
+				return nil, fmt.Errorf("share: %w", err)
+			}
+			l.Moves = append(l.Moves, m)
+			pos.MakeMove(m)
+		}
+		return l, nil
+	}
+
+	if path == "" {
+		return New("", nil), nil
+	}
+	fen := strings.ReplaceAll(path, "_", " ")
+	if _, err := board.ParseFEN(fen); err != nil {
+		return nil, fmt.Errorf("share: %w", err)
+	}
+	return New(fen, nil), nil
+}
+
+// legalMove finds the legal move in UCI notation, with the flags (castling,
+// en passant) that board.ParseMove cannot know.
+func legalMove(pos *board.Position, s string) (board.Move, error) {
+	moves := pos.GenerateLegalMoves()
+	for i := 0; i < moves.Len(); i++ {
+		if m := moves.Get(i); m.String() == s {
+			return m, nil
+		}
+	}
+	return board.NoMove, fmt.Errorf("share: %w %q", board.ErrIllegalMove, s)
+}
+
+// fenPath writes a FEN the way Lichess URLs do, with underscores for spaces.
+func fenPath(fen string) string {
+	return strings.ReplaceAll(fen, " ", "_")
+}
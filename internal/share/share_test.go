@@ -0,0 +1,127 @@
+package share
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// playSAN plays SAN moves from a FEN and returns them as legal moves.
+func playSAN(t *testing.T, fen string, sans ...string) []board.Move {
+	t.Helper()
+	pos, err := board.ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var moves []board.Move
+	for _, san := range sans {
+		m, err := board.ParseSAN(san, pos)
+		if err != nil || m == board.NoMove {
+			t.Fatalf("Bad move %s", san)
+		}
+		moves = append(moves, m)
+		pos.MakeMove(m)
+	}
+	return moves
+}
+
+func TestURIRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		moves []string
+	}{
+		{"start", "", nil},
+		{"castling", "", []string{"e4", "e5", "Nf3", "Nc6", "Bc4", "Bc5", "O-O"}},
+		{"en passant", "", []string{"e4", "a6", "e5", "d5", "exd6"}},
+		{"custom start", "8/P7/8/8/8/8/k7/4K3 w - - 0 1", []string{"a8=Q+"}},
+	}
+
+	for _, tt := range tests {
+		start := tt.fen
+		if start == "" {
+			start = board.StartFEN
+		}
+		l := New(tt.fen, playSAN(t, start, tt.moves...))
+		uri := l.URI()
+
+		parsed, err := Parse(uri)
+		if err != nil {
+			t.Fatalf("%s: Parse(%q): %v", tt.name, uri, err)
+		}
+		want, _ := l.Position()
+		got, _ := parsed.Position()
+		if parsed.FEN != start || got.Hash != want.Hash || len(parsed.Moves) != len(l.Moves) {
+			t.Errorf("%s: %q parsed to %s, want %s", tt.name, uri, got.ToFEN(), want.ToFEN())
+		}
+	}
+
+	if uri := New("", nil).URI(); uri != "chessplay://position" {
+		t.Errorf("Start position URI = %q", uri)
+	}
+}
+
+func TestLichessURL(t *testing.T) {
+	// Games from the start position are shared with their moves
+	l := New("", playSAN(t, board.StartFEN, "e4", "e5", "Nf3"))
+	u, err := l.LichessURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "https://lichess.org/analysis/pgn/e4_e5_Nf3" {
+		t.Errorf("LichessURL = %q", u)
+	}
+	parsed, err := Parse(u)
+	if err != nil || len(parsed.Moves) != 3 {
+		t.Fatalf("Parse(%q) = %v, %v", u, parsed, err)
+	}
+
+	// Other start positions are shared as the final FEN
+	fen := "8/P7/8/8/8/8/k7/4K3 w - - 0 1"
+	l = New(fen, playSAN(t, fen, "a8=Q+"))
+	u, err = l.LichessURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "https://lichess.org/analysis/Q7/8/8/8/8/8/k7/4K3_b_-_-_0_1" {
+		t.Errorf("LichessURL = %q", u)
+	}
+	parsed, err = Parse(u)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", u, err)
+	}
+	want, _ := l.Position()
+	if got, _ := parsed.Position(); got.Hash != want.Hash {
+		t.Errorf("Parse(%q) = %s, want %s", u, got.ToFEN(), want.ToFEN())
+	}
+
+	// Links copied from the Lichess site
+	for _, link := range []string{
+		"https://lichess.org/analysis",
+		"https://lichess.org/analysis/standard/rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR_b_KQkq_-_0_1",
+		"https://lichess.org/analysis/pgn/1.e4_e5_2.Nf3",
+	} {
+		if _, err := Parse(link); err != nil {
+			t.Errorf("Parse(%q): %v", link, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, link := range []string{
+		"https://example.com/analysis",
+		"not a link",
+		"chessplay://position?moves=e2e5",
+		"chessplay://position?fen=bad",
+		"https://lichess.org/analysis/pgn/e4_e4",
+	} {
+		if _, err := Parse(link); err == nil {
+			t.Errorf("Parse(%q) succeeded", link)
+		}
+	}
+
+	if _, err := Parse("https://example.com/"); !errors.Is(err, ErrUnknownLink) {
+		t.Errorf("Expected ErrUnknownLink, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// analysisExt is the extension of analysis sessions, which sit next to the
+// PGN file of their game and are synced with it.
+const analysisExt = ".analysis.json"
+
+// AnalysisSession is the engine's analysis of a position, saved so a long
+// analysis, e.g. of an adjourned or correspondence game, can be resumed
+// after the program exits.
+type AnalysisSession struct {
+	FEN   string         `json:"fen"`   // Position analyzed
+	Saved time.Time      `json:"saved"` // When the session was saved
+	Nodes []AnalysisNode `json:"nodes"` // Explored tree, nearest the root first
+}
+
+// AnalysisNode is one position of the explored tree.
+type AnalysisNode struct {
+	Moves []string `json:"moves,omitempty"` // Moves from the session position, in UCI notation
+	Best  string   `json:"best,omitempty"`  // Best move found, in UCI notation
+	Score int      `json:"score"`           // Centipawns from the side to move; mate scores count from this node
+	Depth int      `json:"depth"`           // Depth searched
+	Bound int      `json:"bound,omitempty"` // 0 = exact score, 1 = lower bound, 2 = upper bound
+}
+
+// AnalysisSessionPath returns the path of the analysis session of the game
+// saved at pgnPath.
+func AnalysisSessionPath(pgnPath string) string {
+	return strings.TrimSuffix(pgnPath, ".pgn") + analysisExt
+}
+
+// SaveAnalysisSession writes the analysis session of the game saved at
+// pgnPath, replacing the file atomically like SaveGameRecord.
+func SaveAnalysisSession(pgnPath string, s *AnalysisSession) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	path := AnalysisSessionPath(pgnPath)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadAnalysisSession reads the analysis session of the game saved at
+// pgnPath. It returns nil without an error if none was saved.
+func LoadAnalysisSession(pgnPath string) (*AnalysisSession, error) {
+	data, err := os.ReadFile(AnalysisSessionPath(pgnPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &AnalysisSession{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+)
+
+// gameRecordExt is the extension of game records, which sit next to the PGN
+// files in the games directory and are synced with them.
+const gameRecordExt = ".json"
+
+// GameRecord is the database entry of a saved game: the moves to replay it
+// and the analysis added to it. The PGN file is the export of the same game.
+type GameRecord struct {
+	FEN         string              `json:"fen,omitempty"`         // Start position ("" = standard)
+	Moves       []string            `json:"moves"`                 // Moves in UCI notation
+	Annotations map[int]*Annotation `json:"annotations,omitempty"` // By index into Moves
+	ECO         string              `json:"eco,omitempty"`         // Opening code, if the opening was named
+	Opening     string              `json:"opening,omitempty"`     // Opening name
+}
+
+// Annotation is the analysis of one move.
+type Annotation struct {
+	Comments   []string   `json:"comments,omitempty"`
+	Eval       *int       `json:"eval,omitempty"`       // Centipawns after the move, from White's view
+	Variations [][]string `json:"variations,omitempty"` // Alternatives to the move, in SAN
+}
+
+// Annotation returns the annotation of move i, creating it if needed.
+func (r *GameRecord) Annotation(i int) *Annotation {
+	if r.Annotations == nil {
+		r.Annotations = make(map[int]*Annotation)
+	}
+	a := r.Annotations[i]
+	if a == nil {
+		a = &Annotation{}
+		r.Annotations[i] = a
+	}
+	return a
+}
+
+// GameRecordPath returns the path of the record of the game saved at pgnPath.
+func GameRecordPath(pgnPath string) string {
+	return strings.TrimSuffix(pgnPath, ".pgn") + gameRecordExt
+}
+
+// SaveGameRecord writes the record of the game saved at pgnPath. The file is
+// replaced atomically so an interrupted auto-save keeps the previous record.
+func SaveGameRecord(pgnPath string, r *GameRecord) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := GameRecordPath(pgnPath)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadGameRecord reads the record of the game saved at pgnPath. It returns
+// nil without an error if the game has no record, e.g. a PGN file copied
+// into the games directory.
+func LoadGameRecord(pgnPath string) (*GameRecord, error) {
+	data, err := os.ReadFile(GameRecordPath(pgnPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &GameRecord{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// keyEngineMatches holds the results of the engine-vs-engine games played in
+// the GUI. They are local to this machine and not synced.
+const keyEngineMatches = "engine_matches"
+
+// MaxEngineMatchResults is the number of engine game results kept.
+const MaxEngineMatchResults = 200
+
+// EngineMatchResult is the result of one engine-vs-engine game.
+type EngineMatchResult struct {
+	White  string    `json:"white"`  // Engine configuration, e.g. "NNUE Hard"
+	Black  string    `json:"black"`  // Engine configuration
+	Result string    `json:"result"` // "1-0", "0-1" or "1/2-1/2"
+	Plies  int       `json:"plies"`
+	Date   time.Time `json:"date"`
+}
+
+// Adjudication are the rules that end an engine game once its result is
+// clear, as match managers such as cutechess do. Evaluations are those the
+// engines report for their moves; a rule with no moves is off.
+type Adjudication struct {
+	ResignScore int  `json:"resign_score"` // Centipawns both engines see one side behind by
+	ResignMoves int  `json:"resign_moves"` // Moves in a row, by each engine, to adjudicate a loss
+	DrawScore   int  `json:"draw_score"`   // Centipawns both engines see the game within
+	DrawMoves   int  `json:"draw_moves"`   // Moves in a row, by each engine, to adjudicate a draw
+	DrawFrom    int  `json:"draw_from"`    // Move number from which draws are adjudicated
+	Tablebases  bool `json:"tablebases"`   // Adjudicate positions the Syzygy tablebases hold
+}
+
+// DefaultAdjudication returns the adjudication rules of new profiles.
+func DefaultAdjudication() Adjudication {
+	return Adjudication{
+		ResignScore: 1000,
+		ResignMoves: 3,
+		DrawScore:   10,
+		DrawMoves:   8,
+		DrawFrom:    40,
+		Tablebases:  true,
+	}
+}
+
+// SaveEngineMatchResult records the result of an engine game for the active
+// profile, dropping the oldest results beyond MaxEngineMatchResults.
+func (s *Storage) SaveEngineMatchResult(r EngineMatchResult) error {
+	if err := s.ensureProfile(DefaultPreferences().Username); err != nil {
+		return err
+	}
+
+	return s.update(func(txn *badger.Txn) error {
+		results, err := loadEngineMatchResults(txn, s.profileID)
+		if err != nil {
+			return err
+		}
+		results = append(results, r)
+		if len(results) > MaxEngineMatchResults {
+			results = results[len(results)-MaxEngineMatchResults:]
+		}
+
+		data, err := json.Marshal(results)
+		if err != nil {
+			return err
+		}
+		return txn.Set(profileKey(s.profileID, keyEngineMatches), data)
+	})
+}
+
+// EngineMatchResults returns the engine game results of the active profile,
+// oldest first.
+func (s *Storage) EngineMatchResults() ([]EngineMatchResult, error) {
+	var results []EngineMatchResult
+	err := s.db.View(func(txn *badger.Txn) error {
+		var err error
+		results, err = loadEngineMatchResults(txn, s.profileID)
+		return err
+	})
+	return results, err
+}
+
+// MatchScore totals the points of two engine configurations in the games
+// they played against each other, with either color.
+func MatchScore(results []EngineMatchResult, a, b string) (scoreA, scoreB float64, games int) {
+	for _, r := range results {
+		var white, black *float64
+		switch {
+		case r.White == a && r.Black == b:
+			white, black = &scoreA, &scoreB
+		case r.White == b && r.Black == a:
+			white, black = &scoreB, &scoreA
+		default:
+			continue
+		}
+		games++
+		switch r.Result {
+		case "1-0":
+			*white++
+		case "0-1":
+			*black++
+		default:
+			*white += 0.5
+			*black += 0.5
+		}
+	}
+	return scoreA, scoreB, games
+}
+
+// loadEngineMatchResults reads the engine game results of a profile.
+func loadEngineMatchResults(txn *badger.Txn, profileID string) ([]EngineMatchResult, error) {
+	item, err := txn.Get(profileKey(profileID, keyEngineMatches))
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var results []EngineMatchResult
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &results)
+	})
+	return results, err
+}
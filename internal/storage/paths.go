@@ -10,11 +10,30 @@ import (
 
 const appName = "chessplay"
 
+// dataDirOverride replaces the platform data directory (see SetDataDir).
+var dataDirOverride string
+
+// SetDataDir makes the application keep all its data (preferences, games,
+// NNUE networks, books and logs) in dir instead of the platform directory,
+// as the -data-dir flag of the commands does. An empty dir restores the
+// platform directory. Call it before anything else of this package.
+func SetDataDir(dir string) {
+	dataDirOverride = dir
+}
+
 // GetDataDir returns the platform-specific data directory for the application.
 // - macOS: ~/Library/Application Support/chessplay/
-// - Linux: ~/.local/share/chessplay/
+// - Linux: ~/.local/share/chessplay/ ($XDG_DATA_HOME/chessplay/)
 // - Windows: %APPDATA%/chessplay/
+// SetDataDir overrides it.
 func GetDataDir() (string, error) {
+	if dataDirOverride != "" {
+		if err := os.MkdirAll(dataDirOverride, 0755); err != nil {
+			return "", err
+		}
+		return dataDirOverride, nil
+	}
+
 	var baseDir string
 
 	switch runtime.GOOS {
@@ -75,6 +94,106 @@ func GetNNUEDir() (string, error) {
 	return nnueDir, nil
 }
 
+// NNUESearchDirs returns the directories searched for NNUE networks, in
+// order: the data directory's nnue folder (where downloads go), the legacy
+// ~/.chessplay/nnue, then ./nnue and the working directory.
+func NNUESearchDirs() []string {
+	var dirs []string
+	if dir, err := GetNNUEDir(); err == nil {
+		dirs = append(dirs, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".chessplay", "nnue"))
+	}
+	return append(dirs, "./nnue", ".")
+}
+
+// GetSyzygyDir returns the directory for downloaded Syzygy tablebase files.
+func GetSyzygyDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	syzygyDir := filepath.Join(dataDir, "syzygy")
+	if err := os.MkdirAll(syzygyDir, 0755); err != nil {
+		return "", err
+	}
+
+	return syzygyDir, nil
+}
+
+// GetBookPath returns the path of the GUI's Polyglot opening book, which is
+// used when the file exists.
+func GetBookPath() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "book.bin"), nil
+}
+
+// GetBooksDir returns the directory for Polyglot opening books, where book
+// files given by name alone are looked up.
+func GetBooksDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	booksDir := filepath.Join(dataDir, "books")
+	if err := os.MkdirAll(booksDir, 0755); err != nil {
+		return "", err
+	}
+
+	return booksDir, nil
+}
+
+// GetLogsDir returns the directory for log files.
+func GetLogsDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	logsDir := filepath.Join(dataDir, "logs")
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		return "", err
+	}
+
+	return logsDir, nil
+}
+
+// GetGamesDir returns the directory for exported PGN games.
+func GetGamesDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	gamesDir := filepath.Join(dataDir, "games")
+	if err := os.MkdirAll(gamesDir, 0755); err != nil {
+		return "", err
+	}
+
+	return gamesDir, nil
+}
+
+// GetGameDBDir returns the directory of the imported games database.
+func GetGameDBDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	gameDBDir := filepath.Join(dataDir, "gamedb")
+	if err := os.MkdirAll(gameDBDir, 0755); err != nil {
+		return "", err
+	}
+
+	return gameDBDir, nil
+}
+
 // GetDatabaseDir returns the directory for storing the BadgerDB database.
 func GetDatabaseDir() (string, error) {
 	dataDir, err := GetDataDir()
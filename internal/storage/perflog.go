@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// perfLogFile is the local engine performance log in the data directory.
+// It is never synced or sent anywhere; it is a CSV file so it can be opened
+// in a spreadsheet to compare settings on this machine.
+const perfLogFile = "performance.csv"
+
+// perfLogHeader is the CSV header, in PerfEntry field order
+var perfLogHeader = []string{
+	"date", "result", "engine_moves", "avg_depth", "avg_seldepth", "nps",
+	"engine_time_ms", "threads", "hash_mb", "eval", "difficulty",
+}
+
+// PerfEntry is the engine performance over one game
+type PerfEntry struct {
+	Date        time.Time
+	Result      string        // PGN result token ("*" if abandoned)
+	EngineMoves int           // Searched moves (book and tablebase moves are not counted)
+	AvgDepth    float64       // Average completed depth per move
+	AvgSelDepth float64       // Average selective depth per move
+	NPS         uint64        // Nodes per second over all searched moves
+	EngineTime  time.Duration // Total search time
+	Threads     int
+	HashMB      int
+	Eval        string // "classical" or "nnue"
+	Difficulty  string
+}
+
+// PerfLogPath returns the path of the performance log.
+func PerfLogPath() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, perfLogFile), nil
+}
+
+// AppendPerfEntry adds a game to the performance log.
+func AppendPerfEntry(entry PerfEntry) error {
+	path, err := PerfLogPath()
+	if err != nil {
+		return err
+	}
+	return appendPerfEntry(path, entry)
+}
+
+// appendPerfEntry adds a row to the log at path, writing the header to a new file.
+func appendPerfEntry(path string, entry PerfEntry) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	if info.Size() == 0 {
+		if err := w.Write(perfLogHeader); err != nil {
+			return err
+		}
+	}
+	err = w.Write([]string{
+		entry.Date.Format(time.RFC3339),
+		entry.Result,
+		strconv.Itoa(entry.EngineMoves),
+		strconv.FormatFloat(entry.AvgDepth, 'f', 1, 64),
+		strconv.FormatFloat(entry.AvgSelDepth, 'f', 1, 64),
+		strconv.FormatUint(entry.NPS, 10),
+		strconv.FormatInt(entry.EngineTime.Milliseconds(), 10),
+		strconv.Itoa(entry.Threads),
+		strconv.Itoa(entry.HashMB),
+		entry.Eval,
+		entry.Difficulty,
+	})
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// LoadPerfLog reads all entries of the performance log, oldest first.
+func LoadPerfLog() ([]PerfEntry, error) {
+	path, err := PerfLogPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadPerfLog(path)
+}
+
+// loadPerfLog reads the log at path; a missing file is an empty log.
+func loadPerfLog(path string) ([]PerfEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = len(perfLogHeader)
+	if _, err := r.Read(); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", perfLogFile, err)
+	}
+
+	var entries []PerfEntry
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", perfLogFile, err)
+		}
+
+		entry, err := parsePerfRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", perfLogFile, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// parsePerfRow converts a CSV row into an entry.
+func parsePerfRow(row []string) (PerfEntry, error) {
+	var e PerfEntry
+	var err error
+	if e.Date, err = time.Parse(time.RFC3339, row[0]); err != nil {
+		return e, err
+	}
+	e.Result = row[1]
+	if e.EngineMoves, err = strconv.Atoi(row[2]); err != nil {
+		return e, err
+	}
+	if e.AvgDepth, err = strconv.ParseFloat(row[3], 64); err != nil {
+		return e, err
+	}
+	if e.AvgSelDepth, err = strconv.ParseFloat(row[4], 64); err != nil {
+		return e, err
+	}
+	if e.NPS, err = strconv.ParseUint(row[5], 10, 64); err != nil {
+		return e, err
+	}
+	ms, err := strconv.ParseInt(row[6], 10, 64)
+	if err != nil {
+		return e, err
+	}
+	e.EngineTime = time.Duration(ms) * time.Millisecond
+	if e.Threads, err = strconv.Atoi(row[7]); err != nil {
+		return e, err
+	}
+	if e.HashMB, err = strconv.Atoi(row[8]); err != nil {
+		return e, err
+	}
+	e.Eval = row[9]
+	e.Difficulty = row[10]
+	return e, nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// keyRushScores holds the puzzle rush high scores of a profile. They are
+// local to this machine and not synced.
+const keyRushScores = "rush_scores"
+
+// MaxRushScores is the number of high scores kept per rush duration.
+const MaxRushScores = 10
+
+// RushScore is the result of one puzzle rush
+type RushScore struct {
+	Score    int           `json:"score"`    // Puzzles solved
+	Duration time.Duration `json:"duration"` // Rush length (3 or 5 minutes)
+	Date     time.Time     `json:"date"`
+}
+
+// SaveRushScore adds a rush result to the high scores of the active profile.
+// It returns the 1-based rank of the score among those of the same duration,
+// or 0 if it did not make the table.
+func (s *Storage) SaveRushScore(score RushScore) (int, error) {
+	if err := s.ensureProfile(DefaultPreferences().Username); err != nil {
+		return 0, err
+	}
+
+	rank := 0
+	err := s.update(func(txn *badger.Txn) error {
+		scores, err := loadRushScores(txn, s.profileID)
+		if err != nil {
+			return err
+		}
+
+		// Insert after equal scores, so earlier results keep their place
+		var table []RushScore
+		for _, rs := range scores {
+			if rs.Duration == score.Duration {
+				table = append(table, rs)
+			}
+		}
+		pos := sort.Search(len(table), func(i int) bool { return table[i].Score < score.Score })
+		if pos >= MaxRushScores {
+			return nil
+		}
+		rank = pos + 1
+
+		table = append(table[:pos], append([]RushScore{score}, table[pos:]...)...)
+		if len(table) > MaxRushScores {
+			table = table[:MaxRushScores]
+		}
+		kept := table
+		for _, rs := range scores {
+			if rs.Duration != score.Duration {
+				kept = append(kept, rs)
+			}
+		}
+
+		data, err := json.Marshal(kept)
+		if err != nil {
+			return err
+		}
+		return txn.Set(profileKey(s.profileID, keyRushScores), data)
+	})
+	return rank, err
+}
+
+// RushScores returns the high scores of the active profile for a rush
+// duration, best first.
+func (s *Storage) RushScores(duration time.Duration) ([]RushScore, error) {
+	var table []RushScore
+	err := s.db.View(func(txn *badger.Txn) error {
+		scores, err := loadRushScores(txn, s.profileID)
+		if err != nil {
+			return err
+		}
+		for _, rs := range scores {
+			if rs.Duration == duration {
+				table = append(table, rs)
+			}
+		}
+		return nil
+	})
+	return table, err
+}
+
+// loadRushScores reads all rush scores of a profile.
+func loadRushScores(txn *badger.Txn, profileID string) ([]RushScore, error) {
+	item, err := txn.Get(profileKey(profileID, keyRushScores))
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var scores []RushScore
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &scores)
+	})
+	return scores, err
+}
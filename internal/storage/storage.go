@@ -2,18 +2,47 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
 )
 
 // Storage keys
+// Preferences and stats are scoped to a profile ("profile/<id>/preferences").
+// Schema version 1 stored them unscoped; they are migrated into a profile on open.
 const (
-	keyPreferences = "preferences"
-	keyStats       = "stats"
-	keyFirstLaunch = "first_launch"
+	keyPreferences   = "preferences"
+	keyStats         = "stats"
+	keyFirstLaunch   = "first_launch"
+	keySchemaVersion = "schema_version"
+	keyProfiles      = "profiles"
+	keyActiveProfile = "active_profile"
 )
 
+// schemaVersion is the current storage schema (2 = multiple profiles)
+const schemaVersion = "2"
+
+// MaxProfiles is the maximum number of user profiles.
+const MaxProfiles = 6
+
+// Profile errors
+var (
+	ErrTooManyProfiles = errors.New("storage: too many profiles")
+	ErrProfileNotFound = errors.New("storage: profile not found")
+)
+
+// Profile is a named user with its own preferences, stats and saved games
+type Profile struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+}
+
 // EvalMode represents the evaluation engine mode
 type EvalMode int
 
@@ -55,7 +84,39 @@ type UserPreferences struct {
 	EvalMode     EvalMode    `json:"eval_mode"`
 	PlayerColor  PlayerColor `json:"player_color"`
 	SoundEnabled bool        `json:"sound_enabled"`
+	AutoFlip     bool        `json:"auto_flip"`               // Flip the board after each move in Human vs Human
+	Coach        bool        `json:"coach"`                   // Show plain-language commentary after each move
+	NNUENetwork  string      `json:"nnue_network,omitempty"`  // Big network file to use ("" = newest detected)
+	TBMirror     string      `json:"tb_mirror,omitempty"`     // Syzygy download mirror ("" = first built-in mirror)
+	BoardTheme   string      `json:"board_theme,omitempty"`   // Board colors ("" = default)
+	PieceSet     string      `json:"piece_set,omitempty"`     // Piece images ("" = default)
+	SoundPack    string      `json:"sound_pack,omitempty"`    // Sound effects ("" = default)
+	SpeakMoves   bool        `json:"speak_moves,omitempty"`   // Announce moves with text-to-speech
+	HintLimit    int         `json:"hint_limit,omitempty"`    // Hints per game outside Easy mode (0 = no limit)
+	Chances      bool        `json:"chances,omitempty"`       // Estimate practical chances with playouts in hints
+	Ponder       bool        `json:"ponder,omitempty"`        // Let the engine think on the player's time
+	CheckUpdates bool        `json:"check_updates,omitempty"` // Look for a new release at startup
+	InstantMoves bool        `json:"instant_moves,omitempty"` // Moves snap into place instead of sliding
 	LastPlayed   time.Time   `json:"last_played"`
+
+	// Engine process: the computer's moves come from a UCI engine run as a
+	// child process, this one's chessplay-uci unless EnginePath names another
+	EngineProcess bool   `json:"engine_process,omitempty"`
+	EnginePath    string `json:"engine_path,omitempty"` // "" = chessplay-uci beside the game
+
+	// Sparring: games against an external UCI engine in place of the
+	// built-in difficulties, with the options set for that engine
+	Sparring        bool              `json:"sparring,omitempty"`         // Sparring is the opponent
+	SparringEngine  string            `json:"sparring_engine,omitempty"`  // Engine program
+	SparringOptions map[string]string `json:"sparring_options,omitempty"` // UCI option values by name
+
+	// Opening explorer: a tab next to the moves showing the moves played
+	// from the position, looked up online in the Lichess opening explorer
+	Explorer        bool `json:"explorer,omitempty"`
+	ExplorerLichess bool `json:"explorer_lichess,omitempty"` // Lichess games rather than masters'
+
+	// Engine matches: rules that end a game once its result is clear
+	Adjudication Adjudication `json:"adjudication"`
 }
 
 // DefaultPreferences returns default user preferences
@@ -68,6 +129,7 @@ func DefaultPreferences() *UserPreferences {
 		PlayerColor:  ColorWhite,
 		SoundEnabled: true,
 		LastPlayed:   time.Now(),
+		Adjudication: DefaultAdjudication(),
 	}
 }
 
@@ -104,25 +166,326 @@ type GameResult struct {
 
 // Storage wraps BadgerDB for persistent storage
 type Storage struct {
-	db *badger.DB
+	db        *badger.DB
+	profileID string       // Active profile ("" until one is created)
+	gamesDir  string       // Root of the per-profile saved games directories
+	onWrite   func() error // Called after each change (the web build saves to localStorage)
 }
 
-// NewStorage creates a new storage instance
-func NewStorage() (*Storage, error) {
-	dbDir, err := GetDatabaseDir()
+// openStorage opens the database in dir, migrating older schemas.
+func openStorage(dir, gamesDir string) (*Storage, error) {
+	opts := badger.DefaultOptions(dir)
+	opts.Logger = nil // Disable logging
+	return openDB(opts, gamesDir, nil)
+}
+
+// openDB opens a database, adds records restored from elsewhere (may be
+// nil) and migrates older schemas.
+func openDB(opts badger.Options, gamesDir string, records map[string][]byte) (*Storage, error) {
+	db, err := badger.Open(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	opts := badger.DefaultOptions(dbDir)
-	opts.Logger = nil // Disable logging
+	if len(records) > 0 {
+		err := db.Update(func(txn *badger.Txn) error {
+			for key, value := range records {
+				if err := txn.Set([]byte(key), value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
 
-	db, err := badger.Open(opts)
+	s := &Storage{db: db, gamesDir: gamesDir}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Restore the active profile
+	err = s.db.View(func(txn *badger.Txn) error {
+		val, err := getValue(txn, keyActiveProfile)
+		s.profileID = string(val)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// getValue returns a copy of the value for key, or nil if the key does not exist.
+func getValue(txn *badger.Txn, key string) ([]byte, error) {
+	item, err := txn.Get([]byte(key))
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+// update runs a read-write transaction, then reports the change.
+func (s *Storage) update(fn func(txn *badger.Txn) error) error {
+	if err := s.db.Update(fn); err != nil {
+		return err
+	}
+	if s.onWrite != nil {
+		return s.onWrite()
+	}
+	return nil
+}
+
+// profileKey returns the key of a per-profile record.
+func profileKey(id, name string) []byte {
+	return []byte("profile/" + id + "/" + name)
+}
+
+// migrate upgrades the database to the current schema.
+// Version 1 preferences and stats become the first profile.
+func (s *Storage) migrate() error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		version, err := getValue(txn, keySchemaVersion)
+		if err != nil || string(version) == schemaVersion {
+			return err
+		}
+
+		prefsData, err := getValue(txn, keyPreferences)
+		if err != nil {
+			return err
+		}
+		statsData, err := getValue(txn, keyStats)
+		if err != nil {
+			return err
+		}
+
+		var profiles []Profile
+		if prefsData != nil || statsData != nil {
+			prefs := DefaultPreferences()
+			if prefsData != nil {
+				if err := json.Unmarshal(prefsData, prefs); err != nil {
+					return err
+				}
+			}
+			p := Profile{ID: newProfileID(), Name: prefs.Username, Created: time.Now()}
+			profiles = append(profiles, p)
+			if err := touch(txn, profileSyncKey(p.ID), false); err != nil {
+				return err
+			}
+
+			if prefsData != nil {
+				if err := setSynced(txn, profileKey(p.ID, keyPreferences), prefsData); err != nil {
+					return err
+				}
+				if err := txn.Delete([]byte(keyPreferences)); err != nil {
+					return err
+				}
+			}
+			if statsData != nil {
+				if err := setSynced(txn, profileKey(p.ID, keyStats), statsData); err != nil {
+					return err
+				}
+				if err := txn.Delete([]byte(keyStats)); err != nil {
+					return err
+				}
+			}
+			if err := txn.Set([]byte(keyActiveProfile), []byte(p.ID)); err != nil {
+				return err
+			}
+		}
+
+		if err := setProfiles(txn, profiles); err != nil {
+			return err
+		}
+		return txn.Set([]byte(keySchemaVersion), []byte(schemaVersion))
+	})
+}
+
+// profileSeq disambiguates profile IDs created within one clock tick
+var profileSeq atomic.Uint32
+
+// newProfileID returns a unique profile identifier.
+func newProfileID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + strconv.FormatUint(uint64(profileSeq.Add(1)), 36)
+}
+
+// loadProfiles reads the profile list inside a transaction.
+func loadProfiles(txn *badger.Txn) ([]Profile, error) {
+	data, err := getValue(txn, keyProfiles)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var profiles []Profile
+	err = json.Unmarshal(data, &profiles)
+	return profiles, err
+}
+
+// setProfiles writes the profile list inside a transaction.
+func setProfiles(txn *badger.Txn, profiles []Profile) error {
+	data, err := json.Marshal(profiles)
+	if err != nil {
+		return err
+	}
+	return txn.Set([]byte(keyProfiles), data)
+}
+
+// Profiles returns all user profiles in creation order
+func (s *Storage) Profiles() ([]Profile, error) {
+	var profiles []Profile
+	err := s.db.View(func(txn *badger.Txn) error {
+		var err error
+		profiles, err = loadProfiles(txn)
+		return err
+	})
+	return profiles, err
+}
+
+// ActiveProfileID returns the ID of the active profile ("" if none exists yet)
+func (s *Storage) ActiveProfileID() string {
+	return s.profileID
+}
+
+// CreateProfile adds a new profile with default preferences under the given name.
+// The active profile is not changed.
+func (s *Storage) CreateProfile(name string) (*Profile, error) {
+	p := &Profile{ID: newProfileID(), Name: name, Created: time.Now()}
+
+	prefs := DefaultPreferences()
+	prefs.Username = name
+	prefsData, err := json.Marshal(prefs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.update(func(txn *badger.Txn) error {
+		profiles, err := loadProfiles(txn)
+		if err != nil {
+			return err
+		}
+		if len(profiles) >= MaxProfiles {
+			return ErrTooManyProfiles
+		}
+		if err := setSynced(txn, profileKey(p.ID, keyPreferences), prefsData); err != nil {
+			return err
+		}
+		if err := touch(txn, profileSyncKey(p.ID), false); err != nil {
+			return err
+		}
+		return setProfiles(txn, append(profiles, *p))
+	})
 	if err != nil {
 		return nil, err
 	}
+	return p, nil
+}
 
-	return &Storage{db: db}, nil
+// SwitchProfile makes the given profile active
+func (s *Storage) SwitchProfile(id string) error {
+	err := s.update(func(txn *badger.Txn) error {
+		profiles, err := loadProfiles(txn)
+		if err != nil {
+			return err
+		}
+		for _, p := range profiles {
+			if p.ID == id {
+				return txn.Set([]byte(keyActiveProfile), []byte(id))
+			}
+		}
+		return ErrProfileNotFound
+	})
+	if err == nil {
+		s.profileID = id
+	}
+	return err
+}
+
+// DeleteProfile removes a profile with its preferences and stats.
+// Deleting the active profile activates the first remaining one.
+func (s *Storage) DeleteProfile(id string) error {
+	active := s.profileID
+	err := s.update(func(txn *badger.Txn) error {
+		profiles, err := loadProfiles(txn)
+		if err != nil {
+			return err
+		}
+
+		remaining := profiles[:0]
+		for _, p := range profiles {
+			if p.ID != id {
+				remaining = append(remaining, p)
+			}
+		}
+		if len(remaining) == len(profiles) {
+			return ErrProfileNotFound
+		}
+
+		if err := deleteSynced(txn, profileKey(id, keyPreferences)); err != nil {
+			return err
+		}
+		if err := deleteSynced(txn, profileKey(id, keyStats)); err != nil {
+			return err
+		}
+		if err := txn.Delete(profileKey(id, keyRushScores)); err != nil {
+			return err
+		}
+		if err := txn.Delete(profileKey(id, keyEngineMatches)); err != nil {
+			return err
+		}
+		if err := touch(txn, profileSyncKey(id), true); err != nil {
+			return err
+		}
+		if err := setProfiles(txn, remaining); err != nil {
+			return err
+		}
+
+		if active == id {
+			active = ""
+			if len(remaining) > 0 {
+				active = remaining[0].ID
+			}
+			return txn.Set([]byte(keyActiveProfile), []byte(active))
+		}
+		return nil
+	})
+	if err == nil {
+		s.profileID = active
+	}
+	return err
+}
+
+// ensureProfile creates and activates a profile if none is active yet,
+// so preferences saved before a profile was chosen are not lost.
+func (s *Storage) ensureProfile(name string) error {
+	if s.profileID != "" {
+		return nil
+	}
+	p, err := s.CreateProfile(name)
+	if err != nil {
+		return err
+	}
+	return s.SwitchProfile(p.ID)
+}
+
+// GamesDir returns the saved games directory of the active profile
+func (s *Storage) GamesDir() (string, error) {
+	if s.profileID == "" {
+		return s.gamesDir, nil
+	}
+
+	profileDir := filepath.Join(s.gamesDir, s.profileID)
+	if err := os.MkdirAll(profileDir, 0755); err != nil {
+		return "", err
+	}
+	return profileDir, nil
 }
 
 // Close closes the database
@@ -155,13 +518,17 @@ func (s *Storage) IsFirstLaunch() (bool, error) {
 
 // MarkFirstLaunchComplete marks that first launch setup is complete
 func (s *Storage) MarkFirstLaunchComplete() error {
-	return s.db.Update(func(txn *badger.Txn) error {
+	return s.update(func(txn *badger.Txn) error {
 		return txn.Set([]byte(keyFirstLaunch), []byte("done"))
 	})
 }
 
-// SavePreferences saves user preferences
+// SavePreferences saves user preferences of the active profile.
+// The profile name follows the preferred username.
 func (s *Storage) SavePreferences(prefs *UserPreferences) error {
+	if err := s.ensureProfile(prefs.Username); err != nil {
+		return err
+	}
 	prefs.LastPlayed = time.Now()
 
 	data, err := json.Marshal(prefs)
@@ -169,17 +536,34 @@ func (s *Storage) SavePreferences(prefs *UserPreferences) error {
 		return err
 	}
 
-	return s.db.Update(func(txn *badger.Txn) error {
-		return txn.Set([]byte(keyPreferences), data)
+	return s.update(func(txn *badger.Txn) error {
+		if err := setSynced(txn, profileKey(s.profileID, keyPreferences), data); err != nil {
+			return err
+		}
+
+		profiles, err := loadProfiles(txn)
+		if err != nil {
+			return err
+		}
+		for i := range profiles {
+			if profiles[i].ID == s.profileID && profiles[i].Name != prefs.Username {
+				profiles[i].Name = prefs.Username
+				if err := touch(txn, profileSyncKey(s.profileID), false); err != nil {
+					return err
+				}
+				return setProfiles(txn, profiles)
+			}
+		}
+		return nil
 	})
 }
 
-// LoadPreferences loads user preferences, returns defaults if not found
+// LoadPreferences loads user preferences of the active profile, returns defaults if not found
 func (s *Storage) LoadPreferences() (*UserPreferences, error) {
 	prefs := DefaultPreferences()
 
 	err := s.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(keyPreferences))
+		item, err := txn.Get(profileKey(s.profileID, keyPreferences))
 		if err == badger.ErrKeyNotFound {
 			return nil // Use defaults
 		}
@@ -195,24 +579,27 @@ func (s *Storage) LoadPreferences() (*UserPreferences, error) {
 	return prefs, err
 }
 
-// SaveStats saves game statistics
+// SaveStats saves game statistics of the active profile
 func (s *Storage) SaveStats(stats *GameStats) error {
+	if err := s.ensureProfile(DefaultPreferences().Username); err != nil {
+		return err
+	}
 	data, err := json.Marshal(stats)
 	if err != nil {
 		return err
 	}
 
-	return s.db.Update(func(txn *badger.Txn) error {
-		return txn.Set([]byte(keyStats), data)
+	return s.update(func(txn *badger.Txn) error {
+		return setSynced(txn, profileKey(s.profileID, keyStats), data)
 	})
 }
 
-// LoadStats loads game statistics, returns empty stats if not found
+// LoadStats loads game statistics of the active profile, returns empty stats if not found
 func (s *Storage) LoadStats() (*GameStats, error) {
 	stats := NewGameStats()
 
 	err := s.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(keyStats))
+		item, err := txn.Get(profileKey(s.profileID, keyStats))
 		if err == badger.ErrKeyNotFound {
 			return nil // Use empty stats
 		}
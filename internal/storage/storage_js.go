@@ -0,0 +1,80 @@
+//go:build js
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"syscall/js"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// localStorageKey is the browser localStorage item the database is saved in.
+const localStorageKey = "chessplay"
+
+// NewStorage creates a new storage instance. In the browser there is no
+// file system: the database is kept in memory, restored from localStorage
+// on open and saved back after every change. Without localStorage (a
+// private window may not have it) nothing outlives the page.
+func NewStorage() (*Storage, error) {
+	opts := badger.DefaultOptions("").WithInMemory(true)
+	opts.Logger = nil // Disable logging
+
+	ls := js.Global().Get("localStorage")
+	if !ls.Truthy() {
+		log.Printf("[Storage] localStorage not available; nothing will be saved")
+		return openDB(opts, "", nil)
+	}
+
+	var records map[string][]byte
+	if item := ls.Call("getItem", localStorageKey); item.Type() == js.TypeString {
+		if err := json.Unmarshal([]byte(item.String()), &records); err != nil {
+			log.Printf("[Storage] Ignoring unreadable saved data: %v", err)
+			records = nil
+		}
+	}
+
+	s, err := openDB(opts, "", records)
+	if err != nil {
+		return nil, err
+	}
+	s.onWrite = func() error { return s.saveLocalStorage(ls) }
+	return s, nil
+}
+
+// saveLocalStorage writes every record to localStorage as one JSON object.
+func (s *Storage) saveLocalStorage(ls js.Value) (err error) {
+	records := make(map[string][]byte)
+	err = s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			records[string(item.KeyCopy(nil))] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	// setItem throws when the quota is used up
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("saving to localStorage: %v", r)
+		}
+	}()
+	ls.Call("setItem", localStorageKey, string(data))
+	return nil
+}
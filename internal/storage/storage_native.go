@@ -0,0 +1,16 @@
+//go:build !js
+
+package storage
+
+// NewStorage creates a new storage instance
+func NewStorage() (*Storage, error) {
+	dbDir, err := GetDatabaseDir()
+	if err != nil {
+		return nil, err
+	}
+	gamesDir, err := GetGamesDir()
+	if err != nil {
+		return nil, err
+	}
+	return openStorage(dbDir, gamesDir)
+}
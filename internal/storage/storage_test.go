@@ -1,9 +1,19 @@
 package storage
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
 )
 
 func TestStorage(t *testing.T) {
@@ -35,6 +45,9 @@ func TestStorage(t *testing.T) {
 		if !prefs.SoundEnabled {
 			t.Errorf("Expected sound enabled by default")
 		}
+		if prefs.Adjudication != DefaultAdjudication() {
+			t.Errorf("Expected default adjudication rules, got %+v", prefs.Adjudication)
+		}
 	})
 
 	t.Run("NewGameStats", func(t *testing.T) {
@@ -77,4 +90,561 @@ func TestDataPaths(t *testing.T) {
 	}
 
 	t.Logf("Data directory: %s", dataDir)
+
+	// An overridden data directory holds all the others
+	override := filepath.Join(t.TempDir(), "data")
+	SetDataDir(override)
+	defer SetDataDir("")
+	for _, get := range []func() (string, error){GetDataDir, GetNNUEDir, GetBooksDir, GetLogsDir, GetGamesDir} {
+		dir, err := get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(dir, override) {
+			t.Errorf("%s is outside the data directory %s", dir, override)
+		}
+		if _, err := os.Stat(dir); err != nil {
+			t.Error(err)
+		}
+	}
+	if dirs := NNUESearchDirs(); dirs[0] != filepath.Join(override, "nnue") {
+		t.Errorf("NNUE search dirs %v do not start with the data directory", dirs)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+
+	// Write a version 1 database: unscoped preferences, no profiles
+	opts := badger.DefaultOptions(dir)
+	opts.Logger = nil
+	db, err := badger.Open(opts)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	legacy := DefaultPreferences()
+	legacy.Username = "Alice"
+	data, _ := json.Marshal(legacy)
+	if err := db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(keyPreferences), data)
+	}); err != nil {
+		t.Fatalf("Failed to write legacy preferences: %v", err)
+	}
+	db.Close()
+
+	s, err := openStorage(dir, t.TempDir())
+	if err != nil {
+		t.Fatalf("openStorage failed: %v", err)
+	}
+	defer s.Close()
+
+	// The legacy preferences become the first, active profile
+	profiles, err := s.Profiles()
+	if err != nil {
+		t.Fatalf("Profiles failed: %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].Name != "Alice" || s.ActiveProfileID() != profiles[0].ID {
+		t.Fatalf("Expected migrated active profile 'Alice', got %+v (active %q)", profiles, s.ActiveProfileID())
+	}
+
+	// A second profile has its own preferences
+	bob, err := s.CreateProfile("Bob")
+	if err != nil {
+		t.Fatalf("CreateProfile failed: %v", err)
+	}
+	if err := s.SwitchProfile(bob.ID); err != nil {
+		t.Fatalf("SwitchProfile failed: %v", err)
+	}
+	prefs, err := s.LoadPreferences()
+	if err != nil {
+		t.Fatalf("LoadPreferences failed: %v", err)
+	}
+	if prefs.Username != "Bob" {
+		t.Errorf("Expected username 'Bob', got '%s'", prefs.Username)
+	}
+	prefs.Difficulty = DifficultyHard
+	prefs.BoardTheme, prefs.PieceSet, prefs.SoundPack = "blue", "minimal", "soft"
+	if err := s.SavePreferences(prefs); err != nil {
+		t.Fatalf("SavePreferences failed: %v", err)
+	}
+	if prefs, _ = s.LoadPreferences(); prefs.BoardTheme != "blue" || prefs.PieceSet != "minimal" || prefs.SoundPack != "soft" {
+		t.Errorf("Expected Bob's theme to be saved, got %+v", prefs)
+	}
+
+	if err := s.SwitchProfile(profiles[0].ID); err != nil {
+		t.Fatalf("SwitchProfile failed: %v", err)
+	}
+	prefs, _ = s.LoadPreferences()
+	if prefs.Username != "Alice" || prefs.Difficulty != DifficultyMedium || prefs.BoardTheme != "" {
+		t.Errorf("Expected Alice's own preferences, got %+v", prefs)
+	}
+
+	// Deleting the active profile activates the remaining one
+	if err := s.DeleteProfile(profiles[0].ID); err != nil {
+		t.Fatalf("DeleteProfile failed: %v", err)
+	}
+	if s.ActiveProfileID() != bob.ID {
+		t.Errorf("Expected Bob to become active, got %q", s.ActiveProfileID())
+	}
+	if err := s.SwitchProfile(profiles[0].ID); err != ErrProfileNotFound {
+		t.Errorf("Expected ErrProfileNotFound, got %v", err)
+	}
+
+	for i := 1; i < MaxProfiles; i++ {
+		if _, err := s.CreateProfile("Extra"); err != nil {
+			t.Fatalf("CreateProfile %d failed: %v", i, err)
+		}
+	}
+	if _, err := s.CreateProfile("One too many"); err != ErrTooManyProfiles {
+		t.Errorf("Expected ErrTooManyProfiles, got %v", err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	a, err := openStorage(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("openStorage failed: %v", err)
+	}
+	defer a.Close()
+	b, err := openStorage(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("openStorage failed: %v", err)
+	}
+	defer b.Close()
+
+	backend := &DirBackend{Path: t.TempDir()}
+
+	// Machine A creates a profile and a saved game
+	prefs := DefaultPreferences()
+	prefs.Username = "Alice"
+	if err := a.SavePreferences(prefs); err != nil {
+		t.Fatalf("SavePreferences failed: %v", err)
+	}
+	gamesDir, _ := a.GamesDir()
+	if err := os.WriteFile(filepath.Join(gamesDir, "game.pgn"), []byte("1. e4 *"), 0644); err != nil {
+		t.Fatalf("Failed to write game: %v", err)
+	}
+	if res, err := a.Sync(backend); err != nil || res.Pushed == 0 {
+		t.Fatalf("Sync A failed: %+v %v", res, err)
+	}
+
+	// Machine B pulls everything
+	if res, err := b.Sync(backend); err != nil || res.Pulled == 0 {
+		t.Fatalf("Sync B failed: %+v %v", res, err)
+	}
+	profiles, _ := b.Profiles()
+	if len(profiles) != 1 || profiles[0].Name != "Alice" {
+		t.Fatalf("Expected profile 'Alice' on B, got %+v", profiles)
+	}
+	if err := b.SwitchProfile(profiles[0].ID); err != nil {
+		t.Fatalf("SwitchProfile failed: %v", err)
+	}
+	bGames, _ := b.GamesDir()
+	if data, err := os.ReadFile(filepath.Join(bGames, "game.pgn")); err != nil || string(data) != "1. e4 *" {
+		t.Errorf("Expected synced game on B, got %q (%v)", data, err)
+	}
+
+	// The newer change wins: A changes difficulty, then B changes it again
+	prefs.Difficulty = DifficultyEasy
+	a.SavePreferences(prefs)
+	time.Sleep(10 * time.Millisecond)
+	bPrefs, _ := b.LoadPreferences()
+	bPrefs.Difficulty = DifficultyHard
+	b.SavePreferences(bPrefs)
+
+	a.Sync(backend)
+	b.Sync(backend)
+	a.Sync(backend)
+	aPrefs, _ := a.LoadPreferences()
+	if aPrefs.Difficulty != DifficultyHard {
+		t.Errorf("Expected B's newer difficulty on A, got %d", aPrefs.Difficulty)
+	}
+
+	// Deleting the profile on B removes it from A
+	if err := b.DeleteProfile(profiles[0].ID); err != nil {
+		t.Fatalf("DeleteProfile failed: %v", err)
+	}
+	b.Sync(backend)
+	a.Sync(backend)
+	if profiles, _ := a.Profiles(); len(profiles) != 0 {
+		t.Errorf("Expected deleted profile to be gone on A, got %+v", profiles)
+	}
+	if a.ActiveProfileID() != "" {
+		t.Errorf("Expected no active profile on A, got %q", a.ActiveProfileID())
+	}
+}
+
+func TestSyncGameDeletion(t *testing.T) {
+	a, err := openStorage(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("openStorage failed: %v", err)
+	}
+	defer a.Close()
+	b, err := openStorage(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("openStorage failed: %v", err)
+	}
+	defer b.Close()
+
+	backend := &DirBackend{Path: t.TempDir()}
+
+	// Both machines have the game after a sync
+	if err := a.SavePreferences(DefaultPreferences()); err != nil {
+		t.Fatalf("SavePreferences failed: %v", err)
+	}
+	aGames, _ := a.GamesDir()
+	aGame := filepath.Join(aGames, "game.pgn")
+	if err := os.WriteFile(aGame, []byte("1. e4 *"), 0644); err != nil {
+		t.Fatalf("Failed to write game: %v", err)
+	}
+	a.Sync(backend)
+	b.Sync(backend)
+	profiles, _ := b.Profiles()
+	if err := b.SwitchProfile(profiles[0].ID); err != nil {
+		t.Fatalf("SwitchProfile failed: %v", err)
+	}
+	bGames, _ := b.GamesDir()
+	bGame := filepath.Join(bGames, "game.pgn")
+	if _, err := os.Stat(bGame); err != nil {
+		t.Fatalf("Expected synced game on B: %v", err)
+	}
+
+	// A deletes it: the sync does not bring it back, and B deletes it too
+	if err := os.Remove(aGame); err != nil {
+		t.Fatalf("Failed to delete game: %v", err)
+	}
+	if res, err := a.Sync(backend); err != nil || res.Pushed == 0 {
+		t.Fatalf("Sync A failed: %+v %v", res, err)
+	}
+	a.Sync(backend)
+	if _, err := os.Stat(aGame); !os.IsNotExist(err) {
+		t.Errorf("Expected deleted game to stay deleted on A, got %v", err)
+	}
+	b.Sync(backend)
+	if _, err := os.Stat(bGame); !os.IsNotExist(err) {
+		t.Errorf("Expected deleted game to be gone on B, got %v", err)
+	}
+}
+
+func TestWebDAVBackend(t *testing.T) {
+	var stored []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(stored)
+		case http.MethodPut:
+			stored, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	backend := NewWebDAVBackend(server.URL+"/chessplay-sync.json", "user", "secret")
+	if snap, err := backend.Load(); err != nil || snap != nil {
+		t.Fatalf("Expected empty backend, got %+v %v", snap, err)
+	}
+
+	snap := &SyncSnapshot{Version: syncSnapshotVersion, Records: map[string]SyncRecord{
+		"profile/x": {Data: []byte(`{"id":"x"}`), Modified: time.Now()},
+	}}
+	if err := backend.Save(snap); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := backend.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if string(loaded.Records["profile/x"].Data) != `{"id":"x"}` {
+		t.Errorf("Unexpected record after round trip: %+v", loaded.Records)
+	}
+
+	wrong := NewWebDAVBackend(server.URL, "user", "wrong")
+	if _, err := wrong.Load(); err == nil {
+		t.Error("Expected an error with wrong credentials")
+	}
+}
+
+func TestLoadSyncConfig(t *testing.T) {
+	dir := t.TempDir()
+	SetDataDir(dir)
+	defer SetDataDir("")
+	t.Setenv(SyncPasswordEnv, "")
+
+	if cfg, err := LoadSyncConfig(); err != nil || cfg != nil {
+		t.Fatalf("Expected no sync config, got %+v %v", cfg, err)
+	}
+
+	path := filepath.Join(dir, syncConfigFile)
+	data := `{"backend":"webdav","url":"https://dav.example/sync.json","username":"user","password":"secret"}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadSyncConfig()
+	if err != nil || cfg.Password != "secret" {
+		t.Fatalf("Expected the stored password, got %+v %v", cfg, err)
+	}
+	if info, err := os.Stat(path); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0600) {
+		t.Errorf("Expected sync.json holding a password to be 0600, got %v %v", info.Mode().Perm(), err)
+	}
+	if s := fmt.Sprint(cfg); strings.Contains(s, "secret") {
+		t.Errorf("Sync config printed its password: %s", s)
+	}
+	backend, _ := cfg.NewBackend()
+	if s := fmt.Sprint(backend); strings.Contains(s, "secret") {
+		t.Errorf("Sync backend printed its password: %s", s)
+	}
+
+	// The environment takes precedence over the file
+	t.Setenv(SyncPasswordEnv, "app-password")
+	if cfg, err := LoadSyncConfig(); err != nil || cfg.Password != "app-password" {
+		t.Errorf("Expected the password from %s, got %+v %v", SyncPasswordEnv, cfg, err)
+	}
+}
+
+func TestPerfLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), perfLogFile)
+
+	entries, err := loadPerfLog(path)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Expected empty log, got %v entries, err %v", len(entries), err)
+	}
+
+	want := PerfEntry{
+		Date:        time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+		Result:      "1-0",
+		EngineMoves: 31,
+		AvgDepth:    12.5,
+		AvgSelDepth: 20.3,
+		NPS:         1500000,
+		EngineTime:  62 * time.Second,
+		Threads:     8,
+		HashMB:      64,
+		Eval:        "nnue",
+		Difficulty:  "Hard",
+	}
+	for i := 0; i < 2; i++ {
+		if err := appendPerfEntry(path, want); err != nil {
+			t.Fatalf("appendPerfEntry failed: %v", err)
+		}
+	}
+
+	entries, err = loadPerfLog(path)
+	if err != nil {
+		t.Fatalf("loadPerfLog failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if got := entries[1]; !got.Date.Equal(want.Date) || got.Result != want.Result ||
+		got.EngineMoves != want.EngineMoves || got.AvgDepth != want.AvgDepth ||
+		got.AvgSelDepth != want.AvgSelDepth || got.NPS != want.NPS ||
+		got.EngineTime != want.EngineTime || got.Threads != want.Threads ||
+		got.HashMB != want.HashMB || got.Eval != want.Eval || got.Difficulty != want.Difficulty {
+		t.Errorf("Entry mismatch: got %+v, want %+v", got, want)
+	}
+}
+
+func TestRushScores(t *testing.T) {
+	s, err := openStorage(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("openStorage failed: %v", err)
+	}
+	defer s.Close()
+
+	const three, five = 3 * time.Minute, 5 * time.Minute
+	date := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	for i, score := range []int{12, 20, 12} {
+		rank, err := s.SaveRushScore(RushScore{Score: score, Duration: three, Date: date.Add(time.Duration(i) * time.Hour)})
+		if err != nil {
+			t.Fatalf("SaveRushScore failed: %v", err)
+		}
+		if want := []int{1, 1, 3}[i]; rank != want {
+			t.Errorf("Score %d: expected rank %d, got %d", score, want, rank)
+		}
+	}
+	if _, err := s.SaveRushScore(RushScore{Score: 30, Duration: five, Date: date}); err != nil {
+		t.Fatalf("SaveRushScore failed: %v", err)
+	}
+
+	scores, err := s.RushScores(three)
+	if err != nil {
+		t.Fatalf("RushScores failed: %v", err)
+	}
+	if len(scores) != 3 || scores[0].Score != 20 || scores[1].Score != 12 || scores[2].Score != 12 {
+		t.Fatalf("Expected 3-minute scores 20, 12, 12, got %+v", scores)
+	}
+	if !scores[1].Date.Equal(date) {
+		t.Errorf("Expected the earlier equal score first, got %v", scores[1].Date)
+	}
+	if scores, _ := s.RushScores(five); len(scores) != 1 || scores[0].Score != 30 {
+		t.Errorf("Expected one 5-minute score of 30, got %+v", scores)
+	}
+
+	// Only the best MaxRushScores are kept
+	for i := 0; i < MaxRushScores; i++ {
+		s.SaveRushScore(RushScore{Score: 25, Duration: three, Date: date})
+	}
+	if rank, _ := s.SaveRushScore(RushScore{Score: 1, Duration: three, Date: date}); rank != 0 {
+		t.Errorf("Expected a low score not to rank, got rank %d", rank)
+	}
+	if scores, _ := s.RushScores(three); len(scores) != MaxRushScores || scores[MaxRushScores-1].Score != 25 {
+		t.Errorf("Expected %d scores of 25, got %+v", MaxRushScores, scores)
+	}
+}
+
+func TestEngineMatchResults(t *testing.T) {
+	s, err := openStorage(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("openStorage failed: %v", err)
+	}
+	defer s.Close()
+
+	date := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, r := range []EngineMatchResult{
+		{White: "NNUE Hard", Black: "Classical Hard", Result: "1-0", Date: date},
+		{White: "Classical Hard", Black: "NNUE Hard", Result: "1/2-1/2", Date: date},
+		{White: "Classical Easy", Black: "NNUE Hard", Result: "0-1", Date: date},
+	} {
+		if err := s.SaveEngineMatchResult(r); err != nil {
+			t.Fatalf("SaveEngineMatchResult failed: %v", err)
+		}
+	}
+
+	results, err := s.EngineMatchResults()
+	if err != nil {
+		t.Fatalf("EngineMatchResults failed: %v", err)
+	}
+	if len(results) != 3 || results[0].Result != "1-0" {
+		t.Fatalf("Expected 3 results oldest first, got %+v", results)
+	}
+	a, b, games := MatchScore(results, "NNUE Hard", "Classical Hard")
+	if a != 1.5 || b != 0.5 || games != 2 {
+		t.Errorf("Expected NNUE 1.5 - 0.5 in 2 games, got %v - %v in %d", a, b, games)
+	}
+
+	// Only the latest MaxEngineMatchResults are kept
+	for i := 0; i < MaxEngineMatchResults; i++ {
+		s.SaveEngineMatchResult(EngineMatchResult{White: "A", Black: "B", Result: "0-1", Date: date})
+	}
+	if results, _ := s.EngineMatchResults(); len(results) != MaxEngineMatchResults || results[0].White != "A" {
+		t.Errorf("Expected %d results of A vs B, got %d", MaxEngineMatchResults, len(results))
+	}
+}
+
+func TestGameRecord(t *testing.T) {
+	pgnPath := filepath.Join(t.TempDir(), "2026-01-02_150405.pgn")
+	if r, err := LoadGameRecord(pgnPath); r != nil || err != nil {
+		t.Fatalf("Expected no record, got %+v, err %v", r, err)
+	}
+
+	eval := 35
+	want := &GameRecord{FEN: "8/8/8/8/8/8/8/K6k w - - 0 1", Moves: []string{"e2e4", "e7e5"}, ECO: "C20", Opening: "King's Pawn Game"}
+	a := want.Annotation(1)
+	a.Comments = []string{"Symmetrical reply."}
+	a.Eval = &eval
+	a.Variations = [][]string{{"c5", "Nf3"}}
+	if want.Annotation(1) != a {
+		t.Fatal("Annotation created twice")
+	}
+	if err := SaveGameRecord(pgnPath, want); err != nil {
+		t.Fatalf("SaveGameRecord failed: %v", err)
+	}
+	if GameRecordPath(pgnPath) != strings.TrimSuffix(pgnPath, ".pgn")+".json" {
+		t.Errorf("Unexpected record path %s", GameRecordPath(pgnPath))
+	}
+
+	got, err := LoadGameRecord(pgnPath)
+	if err != nil {
+		t.Fatalf("LoadGameRecord failed: %v", err)
+	}
+	if got.FEN != want.FEN || len(got.Moves) != 2 || got.Moves[1] != "e7e5" {
+		t.Errorf("Game mismatch: got %+v", got)
+	}
+	ga := got.Annotations[1]
+	if ga == nil || len(ga.Comments) != 1 || ga.Eval == nil || *ga.Eval != eval ||
+		len(ga.Variations) != 1 || ga.Variations[0][1] != "Nf3" {
+		t.Errorf("Annotation mismatch: got %+v", ga)
+	}
+	if got.Annotations[0] != nil {
+		t.Errorf("Expected no annotation on the first move, got %+v", got.Annotations[0])
+	}
+}
+
+func TestAnalysisSession(t *testing.T) {
+	pgnPath := filepath.Join(t.TempDir(), "2026-01-02_150405.pgn")
+	if s, err := LoadAnalysisSession(pgnPath); s != nil || err != nil {
+		t.Fatalf("Expected no session, got %+v, err %v", s, err)
+	}
+
+	want := &AnalysisSession{
+		FEN:   "8/8/8/8/8/8/8/K6k w - - 0 1",
+		Saved: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+		Nodes: []AnalysisNode{
+			{Best: "a1b2", Score: 0, Depth: 20},
+			{Moves: []string{"a1b2"}, Best: "h1g2", Score: -5, Depth: 18, Bound: 2},
+		},
+	}
+	if err := SaveAnalysisSession(pgnPath, want); err != nil {
+		t.Fatalf("SaveAnalysisSession failed: %v", err)
+	}
+	if AnalysisSessionPath(pgnPath) == GameRecordPath(pgnPath) {
+		t.Errorf("Session and game record share the path %s", AnalysisSessionPath(pgnPath))
+	}
+
+	got, err := LoadAnalysisSession(pgnPath)
+	if err != nil {
+		t.Fatalf("LoadAnalysisSession failed: %v", err)
+	}
+	if got.FEN != want.FEN || !got.Saved.Equal(want.Saved) || len(got.Nodes) != 2 {
+		t.Fatalf("Session mismatch: got %+v", got)
+	}
+	if n := got.Nodes[1]; len(n.Moves) != 1 || n.Moves[0] != "a1b2" || n.Best != "h1g2" || n.Score != -5 || n.Depth != 18 || n.Bound != 2 {
+		t.Errorf("Node mismatch: got %+v", n)
+	}
+}
+
+// TestInMemoryRestore covers the browser's storage: an in-memory database
+// restored from saved records, reporting every change so it can be saved
+// again.
+func TestInMemoryRestore(t *testing.T) {
+	opts := badger.DefaultOptions("").WithInMemory(true)
+	opts.Logger = nil
+
+	prefs := DefaultPreferences()
+	prefs.Username = "Alice"
+	data, _ := json.Marshal(prefs)
+	s, err := openDB(opts, "", map[string][]byte{keyPreferences: data})
+	if err != nil {
+		t.Fatalf("openDB failed: %v", err)
+	}
+	defer s.Close()
+
+	// The restored version 1 preferences are migrated into a profile
+	loaded, err := s.LoadPreferences()
+	if err != nil {
+		t.Fatalf("LoadPreferences failed: %v", err)
+	}
+	if loaded.Username != "Alice" {
+		t.Errorf("Expected restored username 'Alice', got %q", loaded.Username)
+	}
+
+	writes := 0
+	s.onWrite = func() error { writes++; return nil }
+	if err := s.SavePreferences(loaded); err != nil {
+		t.Fatalf("SavePreferences failed: %v", err)
+	}
+	if err := s.MarkFirstLaunchComplete(); err != nil {
+		t.Fatalf("MarkFirstLaunchComplete failed: %v", err)
+	}
+	if writes != 2 {
+		t.Errorf("Expected 2 reported writes, got %d", writes)
+	}
 }
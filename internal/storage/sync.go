@@ -0,0 +1,402 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// Sync keys
+// Every synchronized record has a "sync/<key>" entry holding its modification
+// time, so the newer side wins when two machines changed the same record.
+// Records are the profile entries ("profile/<id>"), their preferences and stats
+// ("profile/<id>/preferences"), and saved games ("games/<id>/<file>").
+// Saved games are files, so their entries are written by each sync; a file
+// deleted since then leaves a tombstone. The active profile and first launch
+// flag stay local to each machine.
+const (
+	syncMetaPrefix = "sync/"
+	syncGamePrefix = "games/"
+)
+
+// syncSnapshotVersion is the format of SyncSnapshot
+const syncSnapshotVersion = 1
+
+// SyncBackend stores the synchronized snapshot outside this machine.
+// Implementations are user-provided endpoints such as a WebDAV server or a
+// folder shared by a file sync service.
+type SyncBackend interface {
+	// Load returns the remote snapshot, or nil if nothing was synced yet
+	Load() (*SyncSnapshot, error)
+	// Save replaces the remote snapshot
+	Save(snap *SyncSnapshot) error
+}
+
+// SyncRecord is one synchronized value with its last modification time
+type SyncRecord struct {
+	Data     []byte    `json:"data,omitempty"`
+	Modified time.Time `json:"modified"`
+	Deleted  bool      `json:"deleted,omitempty"` // Tombstone, so deletions propagate
+}
+
+// SyncSnapshot is the synchronized state of a storage database
+type SyncSnapshot struct {
+	Version int                   `json:"version"`
+	Records map[string]SyncRecord `json:"records"`
+}
+
+// SyncResult reports what a sync changed
+type SyncResult struct {
+	Pulled int // Records taken from the backend
+	Pushed int // Records sent to the backend
+}
+
+// syncMeta is the stored modification state of one record
+type syncMeta struct {
+	Modified time.Time `json:"modified"`
+	Deleted  bool      `json:"deleted,omitempty"`
+}
+
+// profileSyncKey returns the sync key of a profile entry.
+func profileSyncKey(id string) string {
+	return "profile/" + id
+}
+
+// touch records that key was modified (or deleted) now.
+func touch(txn *badger.Txn, key string, deleted bool) error {
+	return setMeta(txn, key, syncMeta{Modified: time.Now(), Deleted: deleted})
+}
+
+// setMeta writes the modification state of key.
+func setMeta(txn *badger.Txn, key string, meta syncMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return txn.Set([]byte(syncMetaPrefix+key), data)
+}
+
+// setSynced writes a synchronized record and marks it modified.
+func setSynced(txn *badger.Txn, key, data []byte) error {
+	if err := txn.Set(key, data); err != nil {
+		return err
+	}
+	return touch(txn, string(key), false)
+}
+
+// deleteSynced deletes a synchronized record and leaves a tombstone.
+func deleteSynced(txn *badger.Txn, key []byte) error {
+	if err := txn.Delete(key); err != nil {
+		return err
+	}
+	return touch(txn, string(key), true)
+}
+
+// Sync exchanges records with the backend. For every record the most
+// recently modified side wins; the merged snapshot is written back when
+// the backend is missing anything.
+func (s *Storage) Sync(backend SyncBackend) (SyncResult, error) {
+	var result SyncResult
+
+	remote, err := backend.Load()
+	if err != nil {
+		return result, err
+	}
+	if remote == nil {
+		remote = &SyncSnapshot{Records: map[string]SyncRecord{}}
+	}
+
+	local, err := s.Snapshot()
+	if err != nil {
+		return result, err
+	}
+
+	// Remote records that are newer than ours are applied locally
+	pull := make(map[string]SyncRecord)
+	for key, r := range remote.Records {
+		if !syncable(key) {
+			continue
+		}
+		if l, ok := local.Records[key]; !ok || r.Modified.After(l.Modified) {
+			pull[key] = r
+			local.Records[key] = r
+		}
+	}
+	if err := s.applyRecords(pull); err != nil {
+		return result, err
+	}
+	result.Pulled = len(pull)
+	if err := s.recordGames(local); err != nil {
+		return result, err
+	}
+
+	// Local records that are newer than the backend's are pushed
+	for key, l := range local.Records {
+		if r, ok := remote.Records[key]; !ok || l.Modified.After(r.Modified) {
+			result.Pushed++
+		}
+	}
+	if result.Pushed > 0 {
+		if err := backend.Save(local); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// Snapshot returns all synchronized records of this database.
+func (s *Storage) Snapshot() (*SyncSnapshot, error) {
+	snap := &SyncSnapshot{Version: syncSnapshotVersion, Records: map[string]SyncRecord{}}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		profiles, err := loadProfiles(txn)
+		if err != nil {
+			return err
+		}
+		byID := make(map[string]Profile, len(profiles))
+		for _, p := range profiles {
+			byID[p.ID] = p
+		}
+
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(syncMetaPrefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			key := strings.TrimPrefix(string(item.Key()), syncMetaPrefix)
+
+			var meta syncMeta
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &meta)
+			}); err != nil {
+				return err
+			}
+
+			record := SyncRecord{Modified: meta.Modified, Deleted: meta.Deleted}
+			if strings.HasPrefix(key, syncGamePrefix) {
+				if s.gamesDir == "" {
+					continue
+				}
+				// A synced game is deleted unless snapshotGames finds its file
+				if !meta.Deleted {
+					record = SyncRecord{Modified: time.Now(), Deleted: true}
+				}
+			} else if !meta.Deleted {
+				if id, ok := profileEntryID(key); ok {
+					record.Data, err = json.Marshal(byID[id])
+				} else {
+					record.Data, err = getValue(txn, key)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			snap.Records[key] = record
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.snapshotGames(snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// profileEntryID returns the profile ID if key is a profile entry ("profile/<id>").
+func profileEntryID(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, "profile/")
+	if !ok || strings.Contains(rest, "/") {
+		return "", false
+	}
+	return rest, true
+}
+
+// snapshotGames adds the saved game files, using their modification times.
+func (s *Storage) snapshotGames(snap *SyncSnapshot) error {
+	if s.gamesDir == "" {
+		return nil
+	}
+
+	return filepath.WalkDir(s.gamesDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(s.gamesDir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		snap.Records[syncGamePrefix+filepath.ToSlash(rel)] = SyncRecord{Data: data, Modified: info.ModTime()}
+		return nil
+	})
+}
+
+// recordGames writes the sync entries of the saved games in snap, so a game
+// file deleted before the next sync is known to be deleted.
+func (s *Storage) recordGames(snap *SyncSnapshot) error {
+	if s.gamesDir == "" {
+		return nil
+	}
+
+	return s.update(func(txn *badger.Txn) error {
+		for key, r := range snap.Records {
+			if !strings.HasPrefix(key, syncGamePrefix) {
+				continue
+			}
+			if err := setMeta(txn, key, syncMeta{Modified: r.Modified, Deleted: r.Deleted}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// applyRecords writes records pulled from a backend, keeping their modification times.
+func (s *Storage) applyRecords(records map[string]SyncRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	active := s.profileID
+	err := s.update(func(txn *badger.Txn) error {
+		profiles, err := loadProfiles(txn)
+		if err != nil {
+			return err
+		}
+
+		for key, r := range records {
+			if rel, ok := strings.CutPrefix(key, syncGamePrefix); ok {
+				if err := s.applyGame(rel, r); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if id, ok := profileEntryID(key); ok {
+				profiles = removeProfile(profiles, id)
+				if !r.Deleted {
+					var p Profile
+					if err := json.Unmarshal(r.Data, &p); err != nil {
+						return err
+					}
+					profiles = append(profiles, p)
+				}
+			} else if r.Deleted {
+				if err := txn.Delete([]byte(key)); err != nil {
+					return err
+				}
+			} else if err := txn.Set([]byte(key), r.Data); err != nil {
+				return err
+			}
+
+			if err := setMeta(txn, key, syncMeta{Modified: r.Modified, Deleted: r.Deleted}); err != nil {
+				return err
+			}
+		}
+
+		// Keep creation order stable across machines
+		sort.SliceStable(profiles, func(i, j int) bool {
+			return profiles[i].Created.Before(profiles[j].Created)
+		})
+		if err := setProfiles(txn, profiles); err != nil {
+			return err
+		}
+
+		// The active profile may have been deleted on another machine,
+		// or this machine had none before the first sync
+		if !hasProfile(profiles, active) {
+			active = ""
+			if len(profiles) > 0 {
+				active = profiles[0].ID
+			}
+			return txn.Set([]byte(keyActiveProfile), []byte(active))
+		}
+		return nil
+	})
+	if err == nil {
+		s.profileID = active
+	}
+	return err
+}
+
+// applyGame writes or removes a saved game file.
+func (s *Storage) applyGame(rel string, r SyncRecord) error {
+	if s.gamesDir == "" {
+		return nil
+	}
+
+	path := filepath.Join(s.gamesDir, filepath.FromSlash(rel))
+	if !strings.HasPrefix(path, filepath.Clean(s.gamesDir)+string(filepath.Separator)) {
+		return nil // Ignore paths escaping the games directory
+	}
+	if r.Deleted {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, r.Data, 0644); err != nil {
+		return err
+	}
+	return os.Chtimes(path, r.Modified, r.Modified)
+}
+
+// removeProfile returns profiles without the given ID.
+func removeProfile(profiles []Profile, id string) []Profile {
+	out := profiles[:0]
+	for _, p := range profiles {
+		if p.ID != id {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// hasProfile returns true if a profile with the given ID exists.
+func hasProfile(profiles []Profile, id string) bool {
+	for _, p := range profiles {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// syncable returns true if a record key may be written by a sync.
+// Anything else in a snapshot (e.g. the active profile) is ignored.
+func syncable(key string) bool {
+	if strings.HasPrefix(key, syncGamePrefix) {
+		return true
+	}
+	if _, ok := profileEntryID(key); ok {
+		return true
+	}
+	rest, ok := strings.CutPrefix(key, "profile/")
+	if !ok {
+		return false
+	}
+	_, name, _ := strings.Cut(rest, "/")
+	return name == keyPreferences || name == keyStats
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// syncConfigFile is the opt-in sync configuration in the data directory.
+// Sync is disabled unless this file exists.
+const syncConfigFile = "sync.json"
+
+// syncFileName is the snapshot file written by the backends
+const syncFileName = "chessplay-sync.json"
+
+// SyncPasswordEnv names the environment variable holding the WebDAV
+// password. It takes precedence over the password in sync.json, so an app
+// password need not be stored on disk at all.
+const SyncPasswordEnv = "CHESSPLAY_SYNC_PASSWORD"
+
+// SyncConfig selects and configures a sync backend
+type SyncConfig struct {
+	Backend  string `json:"backend"`            // "webdav" or "dir"
+	URL      string `json:"url,omitempty"`      // WebDAV (or any HTTP PUT/GET) URL of the snapshot file
+	Username string `json:"username,omitempty"` // WebDAV basic auth
+	// Password is stored in plain text. Prefer an app password in
+	// $CHESSPLAY_SYNC_PASSWORD; a sync.json holding one is kept at 0600.
+	Password string `json:"password,omitempty"`
+	Path     string `json:"path,omitempty"` // Shared folder for the "dir" backend
+}
+
+// String describes the configuration without its password, so it is safe to log.
+func (c *SyncConfig) String() string {
+	if c.Backend == "dir" {
+		return fmt.Sprintf("dir sync at %s", c.Path)
+	}
+	return fmt.Sprintf("%s sync at %s as %q", c.Backend, c.URL, c.Username)
+}
+
+// LoadSyncConfig reads the sync configuration, returning nil if sync is not set up.
+func LoadSyncConfig() (*SyncConfig, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dataDir, syncConfigFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &SyncConfig{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", syncConfigFile, err)
+	}
+
+	// A password on disk is readable by the owner only
+	if cfg.Password != "" && runtime.GOOS != "windows" {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+			if err := os.Chmod(path, 0600); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if password := os.Getenv(SyncPasswordEnv); password != "" {
+		cfg.Password = password
+	}
+	return cfg, nil
+}
+
+// NewBackend creates the backend described by the configuration.
+func (c *SyncConfig) NewBackend() (SyncBackend, error) {
+	switch c.Backend {
+	case "webdav":
+		if c.URL == "" {
+			return nil, fmt.Errorf("webdav sync backend requires a url")
+		}
+		return NewWebDAVBackend(c.URL, c.Username, c.Password), nil
+	case "dir":
+		if c.Path == "" {
+			return nil, fmt.Errorf("dir sync backend requires a path")
+		}
+		return &DirBackend{Path: c.Path}, nil
+	default:
+		return nil, fmt.Errorf("unknown sync backend %q", c.Backend)
+	}
+}
+
+// DirBackend keeps the snapshot in a folder, e.g. one shared by a file sync service.
+type DirBackend struct {
+	Path string
+}
+
+// Load reads the snapshot file from the folder.
+func (b *DirBackend) Load() (*SyncSnapshot, error) {
+	data, err := os.ReadFile(filepath.Join(b.Path, syncFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeSnapshot(data)
+}
+
+// Save writes the snapshot file atomically.
+func (b *DirBackend) Save(snap *SyncSnapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(b.Path, 0755); err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(b.Path, syncFileName+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(b.Path, syncFileName))
+}
+
+// WebDAVBackend keeps the snapshot at a URL using plain GET and PUT,
+// which works with WebDAV servers and pre-signed object storage URLs.
+type WebDAVBackend struct {
+	URL      string
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+// String describes the backend without its password, so it is safe to log.
+func (b *WebDAVBackend) String() string {
+	return fmt.Sprintf("webdav sync at %s as %q", b.URL, b.Username)
+}
+
+// NewWebDAVBackend creates a WebDAV backend for the snapshot file at url.
+func NewWebDAVBackend(url, username, password string) *WebDAVBackend {
+	return &WebDAVBackend{
+		URL:      url,
+		Username: username,
+		Password: password,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Load downloads the snapshot.
+func (b *WebDAVBackend) Load() (*SyncSnapshot, error) {
+	resp, err := b.do(http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sync download failed: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSnapshot(data)
+}
+
+// Save uploads the snapshot.
+func (b *WebDAVBackend) Save(snap *SyncSnapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.do(http.MethodPut, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sync upload failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// do sends a request for the snapshot URL.
+func (b *WebDAVBackend) do(method string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, b.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if b.Username != "" {
+		req.SetBasicAuth(b.Username, b.Password)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return b.Client.Do(req)
+}
+
+// decodeSnapshot parses a snapshot, rejecting unknown formats.
+func decodeSnapshot(data []byte) (*SyncSnapshot, error) {
+	snap := &SyncSnapshot{}
+	if err := json.Unmarshal(data, snap); err != nil {
+		return nil, err
+	}
+	if snap.Version != syncSnapshotVersion {
+		return nil, fmt.Errorf("unsupported sync snapshot version %d", snap.Version)
+	}
+	if snap.Records == nil {
+		snap.Records = map[string]SyncRecord{}
+	}
+	return snap, nil
+}
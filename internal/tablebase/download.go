@@ -1,6 +1,7 @@
 package tablebase
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,7 +15,7 @@ import (
 // SyzygyDownloader downloads Syzygy tablebase files from Lichess CDN.
 type SyzygyDownloader struct {
 	CacheDir string // Directory to cache files (e.g., ~/.chessplay/syzygy/)
-	BaseURL  string // Base URL for downloading (e.g., https://tablebase.lichess.ovh/tables/)
+	BaseURL  string // Mirror directory holding the .rtbw and .rtbz files (see Mirrors)
 	Client   *http.Client
 }
 
@@ -22,7 +23,7 @@ type SyzygyDownloader struct {
 func NewSyzygyDownloader(cacheDir string) *SyzygyDownloader {
 	return &SyzygyDownloader{
 		CacheDir: cacheDir,
-		BaseURL:  "https://tablebase.lichess.ovh/tables/standard/",
+		BaseURL:  Mirrors[0],
 		Client:   &http.Client{Timeout: 5 * time.Minute},
 	}
 }
@@ -41,64 +42,76 @@ func (d *SyzygyDownloader) EnsureCacheDir() error {
 	return os.MkdirAll(d.CacheDir, 0755)
 }
 
-// 5-piece tablebase file names (145 files, ~939MB total)
-var FivePieceFiles = []string{
-	// KXvK endings
-	"KQvK", "KRvK", "KBvK", "KNvK", "KPvK",
-	// KXXvK endings
-	"KQQvK", "KQRvK", "KQBvK", "KQNvK", "KQPvK",
-	"KRRvK", "KRBvK", "KRNvK", "KRPvK",
-	"KBBvK", "KBNvK", "KBPvK",
-	"KNNvK", "KNPvK",
-	"KPPvK",
-	// KXvKX endings
-	"KQvKQ", "KQvKR", "KQvKB", "KQvKN", "KQvKP",
-	"KRvKR", "KRvKB", "KRvKN", "KRvKP",
-	"KBvKB", "KBvKN", "KBvKP",
-	"KNvKN", "KNvKP",
-	"KPvKP",
-	// KXXvKX endings
-	"KQQvKQ", "KQQvKR", "KQQvKB", "KQQvKN", "KQQvKP",
-	"KQRvKQ", "KQRvKR", "KQRvKB", "KQRvKN", "KQRvKP",
-	"KQBvKQ", "KQBvKR", "KQBvKB", "KQBvKN", "KQBvKP",
-	"KQNvKQ", "KQNvKR", "KQNvKB", "KQNvKN", "KQNvKP",
-	"KQPvKQ", "KQPvKR", "KQPvKB", "KQPvKN", "KQPvKP",
-	"KRRvKQ", "KRRvKR", "KRRvKB", "KRRvKN", "KRRvKP",
-	"KRBvKQ", "KRBvKR", "KRBvKB", "KRBvKN", "KRBvKP",
-	"KRNvKQ", "KRNvKR", "KRNvKB", "KRNvKN", "KRNvKP",
-	"KRPvKQ", "KRPvKR", "KRPvKB", "KRPvKN", "KRPvKP",
-	"KBBvKQ", "KBBvKR", "KBBvKB", "KBBvKN", "KBBvKP",
-	"KBNvKQ", "KBNvKR", "KBNvKB", "KBNvKN", "KBNvKP",
-	"KBPvKQ", "KBPvKR", "KBPvKB", "KBPvKN", "KBPvKP",
-	"KNNvKQ", "KNNvKR", "KNNvKB", "KNNvKN", "KNNvKP",
-	"KNPvKQ", "KNPvKR", "KNPvKB", "KNPvKN", "KNPvKP",
-	"KPPvKQ", "KPPvKR", "KPPvKB", "KPPvKN", "KPPvKP",
-	// KXvKXX endings (symmetric)
-	"KQvKQQ", "KQvKQR", "KQvKQB", "KQvKQN", "KQvKQP",
-	"KQvKRR", "KQvKRB", "KQvKRN", "KQvKRP",
-	"KQvKBB", "KQvKBN", "KQvKBP",
-	"KQvKNN", "KQvKNP",
-	"KQvKPP",
-	"KRvKQR", "KRvKQB", "KRvKQN", "KRvKQP",
-	"KRvKRR", "KRvKRB", "KRvKRN", "KRvKRP",
-	"KRvKBB", "KRvKBN", "KRvKBP",
-	"KRvKNN", "KRvKNP",
-	"KRvKPP",
-	"KBvKQB", "KBvKQN", "KBvKQP",
-	"KBvKRB", "KBvKRN", "KBvKRP",
-	"KBvKBB", "KBvKBN", "KBvKBP",
-	"KBvKNN", "KBvKNP",
-	"KBvKPP",
-	"KNvKQN", "KNvKQP",
-	"KNvKRN", "KNvKRP",
-	"KNvKBN", "KNvKBP",
-	"KNvKNN", "KNvKNP",
-	"KNvKPP",
-	"KPvKQP",
-	"KPvKRP",
-	"KPvKBP",
-	"KPvKNP",
-	"KPvKPP",
+// Mirrors lists Syzygy mirrors that serve the 3- to 5-piece WDL (.rtbw)
+// and DTZ (.rtbz) files from a single directory. The first is the default.
+var Mirrors = []string{
+	"https://tablebase.lichess.ovh/tables/standard/3-4-5/",
+	"http://tablebase.sesse.net/syzygy/3-4-5/",
+}
+
+// tablePieces lists the non-king pieces in Syzygy name order.
+const tablePieces = "QRBNP"
+
+// TableNames returns the Syzygy table names with the given number of pieces
+// (3 to 5, kings included), e.g. "KQvK" or "KRPvKR". The stronger side is
+// named first, as in the file names on the mirrors.
+func TableNames(pieces int) []string {
+	if pieces < 3 || pieces > 5 {
+		return nil
+	}
+	var names []string
+	for strong := pieces - 2; strong*2 >= pieces-2; strong-- {
+		strongSets := pieceSets(strong)
+		weakSets := pieceSets(pieces - 2 - strong)
+		for i, w := range strongSets {
+			for j, b := range weakSets {
+				// Equal sides are only named with the stronger set first
+				if strong*2 == pieces-2 && j < i {
+					continue
+				}
+				names = append(names, "K"+w+"vK"+b)
+			}
+		}
+	}
+	return names
+}
+
+// pieceSets returns every multiset of n non-king pieces, each written
+// strongest first ("QQ", "QR", ..., "PP").
+func pieceSets(n int) []string {
+	if n == 0 {
+		return []string{""}
+	}
+	var sets []string
+	var build func(prefix string, from, left int)
+	build = func(prefix string, from, left int) {
+		if left == 0 {
+			sets = append(sets, prefix)
+			return
+		}
+		for i := from; i < len(tablePieces); i++ {
+			build(prefix+tablePieces[i:i+1], i, left-1)
+		}
+	}
+	build("", 0, n)
+	return sets
+}
+
+// FivePieceFiles lists the 3- to 5-piece tables (145 tables, ~939MB total).
+var FivePieceFiles = append(append(TableNames(3), TableNames(4)...), TableNames(5)...)
+
+// TableSetSize returns the approximate download size of the WDL and DTZ
+// files of all tables with the given number of pieces.
+func TableSetSize(pieces int) int64 {
+	switch pieces {
+	case 3:
+		return 64 * 1024
+	case 4:
+		return 38 * 1024 * 1024
+	case 5:
+		return 901 * 1024 * 1024
+	}
+	return 0
 }
 
 // DownloadProgress tracks download progress.
@@ -123,28 +136,26 @@ func (d *SyzygyDownloader) HasFile(name string) bool {
 
 // DownloadFile downloads a single tablebase (both WDL and DTZ).
 func (d *SyzygyDownloader) DownloadFile(name string, progress chan<- DownloadProgress) error {
+	return d.DownloadFileContext(context.Background(), name, progress)
+}
+
+// DownloadFileContext downloads a single tablebase (both WDL and DTZ),
+// stopping when ctx is cancelled.
+func (d *SyzygyDownloader) DownloadFileContext(ctx context.Context, name string, progress chan<- DownloadProgress) error {
 	if err := d.EnsureCacheDir(); err != nil {
 		return err
 	}
 
-	// Download WDL file (.rtbw)
-	wdlURL := d.BaseURL + "wdl/" + name + ".rtbw"
-	wdlPath := filepath.Join(d.CacheDir, name+".rtbw")
-	if err := d.downloadSingleFile(wdlURL, wdlPath, name+".rtbw", progress); err != nil {
-		return fmt.Errorf("downloading WDL: %w", err)
-	}
-
-	// Download DTZ file (.rtbz)
-	dtzURL := d.BaseURL + "dtz/" + name + ".rtbz"
-	dtzPath := filepath.Join(d.CacheDir, name+".rtbz")
-	if err := d.downloadSingleFile(dtzURL, dtzPath, name+".rtbz", progress); err != nil {
-		return fmt.Errorf("downloading DTZ: %w", err)
+	for _, ext := range []string{".rtbw", ".rtbz"} {
+		path := filepath.Join(d.CacheDir, name+ext)
+		if err := d.downloadSingleFile(ctx, d.BaseURL+name+ext, path, name+ext, progress); err != nil {
+			return fmt.Errorf("downloading %s: %w", name+ext, err)
+		}
 	}
-
 	return nil
 }
 
-func (d *SyzygyDownloader) downloadSingleFile(url, path, name string, progress chan<- DownloadProgress) error {
+func (d *SyzygyDownloader) downloadSingleFile(ctx context.Context, url, path, name string, progress chan<- DownloadProgress) error {
 	// Check if already exists
 	if _, err := os.Stat(path); err == nil {
 		if progress != nil {
@@ -162,7 +173,12 @@ func (d *SyzygyDownloader) downloadSingleFile(url, path, name string, progress c
 	defer out.Close()
 
 	// Start download
-	resp, err := d.Client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	resp, err := d.Client.Do(req)
 	if err != nil {
 		os.Remove(tmpPath)
 		return err
@@ -202,7 +218,11 @@ func (d *SyzygyDownloader) downloadSingleFile(url, path, name string, progress c
 		}
 	}
 
-	// Rename to final path
+	// Rename to final path (the file must be closed first on Windows)
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
 	if err := os.Rename(tmpPath, path); err != nil {
 		os.Remove(tmpPath)
 		return err
@@ -227,6 +247,50 @@ func (d *SyzygyDownloader) Download5Piece(progress chan<- DownloadProgress) erro
 	return nil
 }
 
+// DownloadSet downloads the tables with the given number of pieces,
+// skipping files that are already present. A Done update is sent for every
+// WDL and DTZ file, downloaded or not.
+func (d *SyzygyDownloader) DownloadSet(ctx context.Context, pieces int, progress chan<- DownloadProgress) error {
+	for _, name := range TableNames(pieces) {
+		if err := d.DownloadFileContext(ctx, name, progress); err != nil {
+			return fmt.Errorf("downloading %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// InstalledCount returns how many tables with the given number of pieces
+// are complete in the cache directory.
+func (d *SyzygyDownloader) InstalledCount(pieces int) int {
+	count := 0
+	for _, name := range TableNames(pieces) {
+		if d.HasFile(name) {
+			count++
+		}
+	}
+	return count
+}
+
+// DiskUsage returns the total size of the tablebase files in the cache
+// directory.
+func (d *SyzygyDownloader) DiskUsage() int64 {
+	entries, err := os.ReadDir(d.CacheDir)
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".rtbw") && !strings.HasSuffix(name, ".rtbz") {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
 // GetAvailableFiles returns the list of available tablebase files in cache.
 func (d *SyzygyDownloader) GetAvailableFiles() []string {
 	var files []string
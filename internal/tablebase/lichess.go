@@ -40,8 +40,16 @@ type lichessResponse struct {
 }
 
 func (lp *LichessProber) Probe(pos *board.Position) ProbeResult {
+	result, _ := lp.TryProbe(pos)
+	return result
+}
+
+// TryProbe is Probe, returning an error if the lookup failed (network
+// error, timeout, rate limit or a bad response) rather than finding the
+// position not in the tablebase.
+func (lp *LichessProber) TryProbe(pos *board.Position) (ProbeResult, error) {
 	if CountPieces(pos) > lp.maxPieces {
-		return ProbeResult{Found: false}
+		return ProbeResult{Found: false}, nil
 	}
 
 	fen := pos.ToFEN()
@@ -51,24 +59,24 @@ func (lp *LichessProber) Probe(pos *board.Position) ProbeResult {
 	url := fmt.Sprintf("https://tablebase.lichess.ovh/standard?fen=%s", fen)
 	resp, err := lp.client.Get(url)
 	if err != nil {
-		return ProbeResult{Found: false}
+		return ProbeResult{Found: false}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return ProbeResult{Found: false}
+		return ProbeResult{Found: false}, fmt.Errorf("lichess tablebase: %s", resp.Status)
 	}
 
 	var result lichessResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return ProbeResult{Found: false}
+		return ProbeResult{Found: false}, err
 	}
 
 	return ProbeResult{
 		Found: true,
 		WDL:   categoryToWDL(result.Category),
 		DTZ:   result.DTZ,
-	}
+	}, nil
 }
 
 func (lp *LichessProber) ProbeRoot(pos *board.Position) RootResult {
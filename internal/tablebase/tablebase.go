@@ -47,6 +47,15 @@ type Prober interface {
 	Available() bool
 }
 
+// FallibleProber is a Prober whose lookups can fail for a while, such as
+// an online one. TryProbe returns an error instead of a result not found
+// when the lookup itself failed, so callers do not remember the failure as
+// the position's result.
+type FallibleProber interface {
+	Prober
+	TryProbe(pos *board.Position) (ProbeResult, error)
+}
+
 // WDLToScore converts a WDL result to a search score.
 // Uses the convention: positive = winning, negative = losing.
 func WDLToScore(wdl WDL, ply int) int {
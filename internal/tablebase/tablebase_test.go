@@ -1,6 +1,13 @@
 package tablebase
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/hailam/chessplay/internal/board"
@@ -65,3 +72,197 @@ func TestWDLToScore(t *testing.T) {
 		}
 	}
 }
+
+func TestTableNames(t *testing.T) {
+	// The 3-4-5 set has 145 tables
+	for pieces, want := range map[int]int{3: 5, 4: 30, 5: 110} {
+		names := TableNames(pieces)
+		if len(names) != want {
+			t.Errorf("TableNames(%d) has %d tables, want %d", pieces, len(names), want)
+		}
+		seen := make(map[string]bool)
+		for _, name := range names {
+			if seen[name] || countPiecesFromName(name) != pieces {
+				t.Errorf("TableNames(%d): bad or duplicate table %s", pieces, name)
+			}
+			seen[name] = true
+		}
+	}
+
+	// The stronger side comes first
+	names := strings.Join(TableNames(4), " ")
+	for _, name := range []string{"KQvKR", "KRPvK", "KPvKP"} {
+		if !strings.Contains(names, name) {
+			t.Errorf("TableNames(4) is missing %s", name)
+		}
+	}
+	if strings.Contains(names, "KRvKQ") {
+		t.Error("TableNames(4) lists KRvKQ, want KQvKR only")
+	}
+}
+
+func TestDownloadSet(t *testing.T) {
+	var requests int
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("table " + r.URL.Path))
+	}))
+	defer mirror.Close()
+
+	d := NewSyzygyDownloader(t.TempDir())
+	d.BaseURL = mirror.URL + "/3-4-5/"
+
+	progress := make(chan DownloadProgress, 100)
+	doneFiles := make(chan int)
+	go func() {
+		done := 0
+		for p := range progress {
+			if p.Done {
+				done++
+			}
+		}
+		doneFiles <- done
+	}()
+	err := d.DownloadSet(context.Background(), 3, progress)
+	close(progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done := <-doneFiles; done != 10 {
+		t.Errorf("%d files reported done, want 10", done)
+	}
+
+	if got := d.InstalledCount(3); got != 5 {
+		t.Errorf("InstalledCount(3) = %d, want 5", got)
+	}
+	if d.InstalledCount(4) != 0 {
+		t.Error("InstalledCount(4) should be 0")
+	}
+	if requests != 10 || d.DiskUsage() == 0 {
+		t.Errorf("%d requests and %d bytes on disk, want 10 requests", requests, d.DiskUsage())
+	}
+
+	// Files already present are not downloaded again
+	if err := d.DownloadSet(context.Background(), 3, nil); err != nil || requests != 10 {
+		t.Errorf("Second download: %v, %d requests", err, requests)
+	}
+
+	// A cancelled download stops without installing anything
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.DownloadSet(ctx, 4, nil); err == nil {
+		t.Error("Cancelled download succeeded")
+	}
+	if d.InstalledCount(4) != 0 {
+		t.Error("Cancelled download installed tables")
+	}
+}
+
+// countingProber finds every position, with a result derived from its hash.
+type countingProber struct {
+	probes atomic.Int64
+}
+
+func (c *countingProber) Probe(pos *board.Position) ProbeResult {
+	c.probes.Add(1)
+	return ProbeResult{Found: true, WDL: WDL(pos.Hash%5) - 2, DTZ: -int(pos.Hash % 100)}
+}
+
+func (c *countingProber) ProbeRoot(pos *board.Position) RootResult { return RootResult{} }
+func (c *countingProber) MaxPieces() int                           { return 5 }
+func (c *countingProber) Available() bool                          { return true }
+
+func TestWDLCache(t *testing.T) {
+	for _, r := range []ProbeResult{
+		{},
+		{Found: true, WDL: WDLLoss, DTZ: -37},
+		{Found: true, WDL: WDLWin, DTZ: 1000},
+		{Found: true, WDL: WDLBlessedLoss},
+	} {
+		if got := unpackWDL(packWDL(r)); got != r {
+			t.Errorf("Round trip of %+v gave %+v", r, got)
+		}
+	}
+
+	inner := &countingProber{}
+	cache := NewWDLCache(inner, 1000)
+	if len(cache.entries) != 512 {
+		t.Errorf("Cache of %d entries, want 512", len(cache.entries))
+	}
+	pos, err := board.ParseFEN("8/8/4k3/8/8/3K4/3R4/8 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := inner.Probe(pos)
+	inner.probes.Store(0)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if got := cache.Probe(pos); got != want {
+					t.Errorf("Probe gave %+v, want %+v", got, want)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	stats := cache.Stats()
+	if stats.Hits+stats.Misses != 800 || stats.Found != 800 || stats.Misses != uint64(inner.probes.Load()) {
+		t.Errorf("Stats %+v after 800 probes, %d passed on", stats, inner.probes.Load())
+	}
+	if stats.Misses > 8 {
+		t.Errorf("%d misses for one position", stats.Misses)
+	}
+
+	// A position mapping to the same slot replaces the entry
+	other := pos.Copy()
+	other.Hash += uint64(len(cache.entries))
+	cache.Probe(other)
+	cache.ResetStats()
+	cache.Probe(pos)
+	if stats := cache.Stats(); stats.Misses != 1 {
+		t.Errorf("Stats %+v after probing a replaced entry", stats)
+	}
+
+	cache.Clear()
+	if stats := cache.Stats(); stats != (WDLCacheStats{}) {
+		t.Errorf("Stats %+v after Clear", stats)
+	}
+}
+
+// flakyProber fails its lookups until up is set, then finds every position.
+type flakyProber struct {
+	countingProber
+	up bool
+}
+
+func (f *flakyProber) TryProbe(pos *board.Position) (ProbeResult, error) {
+	if !f.up {
+		f.probes.Add(1)
+		return ProbeResult{}, errors.New("lookup failed")
+	}
+	return f.Probe(pos), nil
+}
+
+func TestWDLCacheFailedProbe(t *testing.T) {
+	inner := &flakyProber{}
+	cache := NewWDLCache(inner, 1000)
+	pos, err := board.ParseFEN("8/8/4k3/8/8/3K4/3R4/8 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cache.Probe(pos); got.Found {
+		t.Errorf("Failed lookup gave %+v", got)
+	}
+	inner.up = true
+	if got := cache.Probe(pos); !got.Found {
+		t.Errorf("Probe after a failed lookup gave %+v, want it probed again", got)
+	}
+	if got := cache.Probe(pos); !got.Found || inner.probes.Load() != 2 {
+		t.Errorf("Probe gave %+v after %d lookups, want the found result cached", got, inner.probes.Load())
+	}
+}
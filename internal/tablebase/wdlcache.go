@@ -0,0 +1,166 @@
+package tablebase
+
+import (
+	"sync/atomic"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// DefaultWDLCacheEntries is the WDL cache size used by the engine (16 MB).
+const DefaultWDLCacheEntries = 1 << 20
+
+// wdlCacheEntry is one slot of the WDL cache. Like the engine's
+// transposition table it stores key XOR data next to the data, so a slot
+// torn by concurrent writers fails verification instead of answering for
+// the wrong position.
+type wdlCacheEntry struct {
+	keyData atomic.Uint64
+	data    atomic.Uint64
+}
+
+// Layout of the packed entry data: valid(1) | found(1) | wdl+2(3) | dtz(16)
+const (
+	wdlValidBit = 1 << 0
+	wdlFoundBit = 1 << 1
+	wdlShift    = 2
+	dtzShift    = 16
+)
+
+// packWDL packs a probe result. The valid bit keeps the data of a stored
+// entry nonzero, so an empty slot never verifies.
+func packWDL(r ProbeResult) uint64 {
+	data := uint64(wdlValidBit)
+	if r.Found {
+		data |= wdlFoundBit
+	}
+	data |= (uint64(r.WDL+2) & 0x7) << wdlShift
+	data |= uint64(uint16(int16(r.DTZ))) << dtzShift
+	return data
+}
+
+// unpackWDL unpacks a probe result packed by packWDL.
+func unpackWDL(data uint64) ProbeResult {
+	return ProbeResult{
+		Found: data&wdlFoundBit != 0,
+		WDL:   WDL((data>>wdlShift)&0x7) - 2,
+		DTZ:   int(int16(uint16(data >> dtzShift))),
+	}
+}
+
+// WDLCacheStats counts the probes of a WDLCache.
+type WDLCacheStats struct {
+	Hits   uint64 // Probes answered from the cache
+	Misses uint64 // Probes passed to the wrapped prober
+	Found  uint64 // Probes of either kind that found the position
+}
+
+// WDLCache wraps a prober with a fixed-size, lock-free cache of Probe
+// results keyed by position hash. It is meant to be shared by all search
+// workers: slots are always replaced, and entries are verified on read, so
+// no locking is needed. Results that were not found are cached too, as
+// they cost a full probe as well, but failed lookups of a FallibleProber
+// are not. ProbeRoot is not cached.
+type WDLCache struct {
+	inner   Prober
+	entries []wdlCacheEntry
+	mask    uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	found  atomic.Uint64
+}
+
+// NewWDLCache creates a cache of the given number of entries, rounded down
+// to a power of two, in front of inner.
+func NewWDLCache(inner Prober, entries int) *WDLCache {
+	size := uint64(1)
+	for size*2 <= uint64(entries) {
+		size *= 2
+	}
+	return &WDLCache{
+		inner:   inner,
+		entries: make([]wdlCacheEntry, size),
+		mask:    size - 1,
+	}
+}
+
+// Probe returns the cached result for the position, probing the wrapped
+// prober on a miss.
+func (c *WDLCache) Probe(pos *board.Position) ProbeResult {
+	entry := &c.entries[pos.Hash&c.mask]
+	keyData := entry.keyData.Load()
+	data := entry.data.Load()
+	if data != 0 && keyData^data == pos.Hash {
+		c.hits.Add(1)
+		result := unpackWDL(data)
+		if result.Found {
+			c.found.Add(1)
+		}
+		return result
+	}
+
+	c.misses.Add(1)
+	result, ok := c.probeInner(pos)
+	if result.Found {
+		c.found.Add(1)
+	}
+	if !ok {
+		return result
+	}
+	data = packWDL(result)
+	entry.keyData.Store(pos.Hash ^ data)
+	entry.data.Store(data)
+	return result
+}
+
+// probeInner probes the wrapped prober. ok is false if the lookup failed
+// and its result must not be cached.
+func (c *WDLCache) probeInner(pos *board.Position) (result ProbeResult, ok bool) {
+	if fp, fallible := c.inner.(FallibleProber); fallible {
+		result, err := fp.TryProbe(pos)
+		return result, err == nil
+	}
+	return c.inner.Probe(pos), true
+}
+
+func (c *WDLCache) ProbeRoot(pos *board.Position) RootResult {
+	return c.inner.ProbeRoot(pos)
+}
+
+func (c *WDLCache) MaxPieces() int {
+	return c.inner.MaxPieces()
+}
+
+func (c *WDLCache) Available() bool {
+	return c.inner.Available()
+}
+
+// Inner returns the wrapped prober.
+func (c *WDLCache) Inner() Prober {
+	return c.inner
+}
+
+// Stats returns the probe counts since creation or the last ResetStats.
+func (c *WDLCache) Stats() WDLCacheStats {
+	return WDLCacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Found:  c.found.Load(),
+	}
+}
+
+// ResetStats zeroes the probe counts.
+func (c *WDLCache) ResetStats() {
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.found.Store(0)
+}
+
+// Clear empties the cache. It must not race with Probe.
+func (c *WDLCache) Clear() {
+	for i := range c.entries {
+		c.entries[i].keyData.Store(0)
+		c.entries[i].data.Store(0)
+	}
+	c.ResetStats()
+}
@@ -0,0 +1,456 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+// Client timeouts
+const (
+	clientHandshakeTimeout = 10 * time.Second // uciok and readyok
+	clientStopTimeout      = 5 * time.Second  // bestmove after stop
+	clientQuitTimeout      = 2 * time.Second  // Exit after quit, before the process is killed
+)
+
+// ErrEngineExited is returned by Client calls once the engine process has
+// exited, as after a crash.
+var ErrEngineExited = errors.New("engine process exited")
+
+// Option is an option an engine declares in its "uci" reply.
+type Option struct {
+	Name    string
+	Type    string // check, spin, combo, button or string
+	Default string
+	Min     int // Spin bounds
+	Max     int
+	Vars    []string // Combo values
+}
+
+// Client runs a UCI engine as a child process: this engine's chessplay-uci,
+// so a crashing search cannot take the caller down with it, or any other
+// engine such as Stockfish. Its methods must not be called concurrently.
+type Client struct {
+	Name    string   // From "id name"
+	Author  string   // From "id author"
+	Options []Option // Declared options, in order
+
+	// OnInfo, if not nil, is called with each search report of a Go call
+	OnInfo func(engine.SearchInfo)
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	lines  chan string   // Engine output; closed when the process exits
+	exited chan struct{} // Closed when the process has exited
+
+	mu       sync.Mutex
+	lastInfo engine.SearchInfo
+}
+
+// StartClient starts the engine at path with args, and waits for it to
+// identify itself and be ready.
+func StartClient(path string, args ...string) (*Client, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		cmd:    cmd,
+		stdin:  stdin,
+		lines:  make(chan string, 256),
+		exited: make(chan struct{}),
+	}
+	go c.readLoop(stdout)
+
+	if err := c.send("uci"); err != nil {
+		c.Close()
+		return nil, err
+	}
+	err = c.waitFor("uciok", clientHandshakeTimeout, func(line string) {
+		switch {
+		case strings.HasPrefix(line, "id name "):
+			c.Name = strings.TrimSpace(strings.TrimPrefix(line, "id name "))
+		case strings.HasPrefix(line, "id author "):
+			c.Author = strings.TrimSpace(strings.TrimPrefix(line, "id author "))
+		case strings.HasPrefix(line, "option "):
+			if o, ok := parseOption(line); ok {
+				c.Options = append(c.Options, o)
+			}
+		}
+	})
+	if err == nil {
+		err = c.IsReady()
+	}
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// readLoop passes the engine's output lines on until it exits.
+func (c *Client) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		c.lines <- strings.TrimSpace(scanner.Text())
+	}
+	c.cmd.Wait()
+	close(c.exited)
+	close(c.lines)
+}
+
+// send writes a command to the engine.
+func (c *Client) send(command string) error {
+	select {
+	case <-c.exited:
+		return ErrEngineExited
+	default:
+	}
+	if _, err := io.WriteString(c.stdin, command+"\n"); err != nil {
+		return ErrEngineExited
+	}
+	return nil
+}
+
+// waitFor reads output lines until one equals token, passing the others to
+// handle (which may be nil).
+func (c *Client) waitFor(token string, timeout time.Duration, handle func(string)) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				return ErrEngineExited
+			}
+			if line == token {
+				return nil
+			}
+			if handle != nil {
+				handle(line)
+			}
+		case <-timer.C:
+			return fmt.Errorf("no %q from the engine after %v", token, timeout)
+		}
+	}
+}
+
+// Option returns the declared option with the given name, which UCI
+// compares without case.
+func (c *Client) Option(name string) (Option, bool) {
+	for _, o := range c.Options {
+		if strings.EqualFold(o.Name, name) {
+			return o, true
+		}
+	}
+	return Option{}, false
+}
+
+// SetOption sets an engine option; an empty value presses a button.
+func (c *Client) SetOption(name, value string) error {
+	if value == "" {
+		return c.send("setoption name " + name)
+	}
+	return c.send("setoption name " + name + " value " + value)
+}
+
+// IsReady waits until the engine has processed the commands sent so far.
+func (c *Client) IsReady() error {
+	if err := c.send("isready"); err != nil {
+		return err
+	}
+	return c.waitFor("readyok", clientHandshakeTimeout, nil)
+}
+
+// NewGame tells the engine the next search is from a new game.
+func (c *Client) NewGame() error {
+	if err := c.send("ucinewgame"); err != nil {
+		return err
+	}
+	return c.IsReady()
+}
+
+// Go searches the position reached by moves from start within limits, and
+// returns the engine's best move and the move it expects in reply (NoMove
+// if none). Cancelling ctx stops the search; the best move so far is still
+// returned. The engine's reports go to OnInfo as they arrive.
+func (c *Client) Go(ctx context.Context, start *board.Position, moves []board.Move, limits engine.UCILimits) (best, ponder board.Move, err error) {
+	pos := start.Copy()
+	var cmd strings.Builder
+	if fen := start.ToFEN(); fen == board.StartFEN {
+		cmd.WriteString("position startpos")
+	} else {
+		cmd.WriteString("position fen " + fen)
+	}
+	if len(moves) > 0 {
+		cmd.WriteString(" moves")
+		for _, m := range moves {
+			cmd.WriteString(" " + m.String())
+			pos.MakeMove(m)
+			pos.UpdateCheckers()
+		}
+	}
+	if err := c.send(cmd.String()); err != nil {
+		return board.NoMove, board.NoMove, err
+	}
+	if err := c.send(goCommand(limits)); err != nil {
+		return board.NoMove, board.NoMove, err
+	}
+	c.mu.Lock()
+	c.lastInfo = engine.SearchInfo{}
+	c.mu.Unlock()
+
+	var stopTimer <-chan time.Time
+	done := ctx.Done()
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				return board.NoMove, board.NoMove, ErrEngineExited
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "info":
+				if info, ok := parseInfo(fields[1:], pos); ok {
+					c.mu.Lock()
+					c.lastInfo = info
+					c.mu.Unlock()
+					if c.OnInfo != nil {
+						c.OnInfo(info)
+					}
+				}
+			case "bestmove":
+				if len(fields) > 1 {
+					best = parseClientMove(fields[1], pos)
+				}
+				if len(fields) > 3 && fields[2] == "ponder" && best != board.NoMove {
+					after := pos.Copy()
+					after.MakeMove(best)
+					after.UpdateCheckers()
+					ponder = parseClientMove(fields[3], after)
+				}
+				return best, ponder, nil
+			}
+		case <-done:
+			done = nil
+			if err := c.send("stop"); err != nil {
+				return board.NoMove, board.NoMove, err
+			}
+			stopTimer = time.After(clientStopTimeout)
+		case <-stopTimer:
+			c.cmd.Process.Kill()
+			return board.NoMove, board.NoMove, fmt.Errorf("no bestmove from the engine %v after stop", clientStopTimeout)
+		}
+	}
+}
+
+// LastInfo returns the last search report of the latest Go call.
+func (c *Client) LastInfo() engine.SearchInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastInfo
+}
+
+// Exited returns a channel that is closed once the engine process exits.
+func (c *Client) Exited() <-chan struct{} {
+	return c.exited
+}
+
+// Close asks the engine to quit, killing it if it does not.
+func (c *Client) Close() {
+	c.send("quit")
+	c.stdin.Close()
+	go func() {
+		for range c.lines {
+			// Unread output must not hold up the exit
+		}
+	}()
+	select {
+	case <-c.exited:
+	case <-time.After(clientQuitTimeout):
+		c.cmd.Process.Kill()
+		<-c.exited
+	}
+}
+
+// goCommand builds the go command of a search.
+func goCommand(limits engine.UCILimits) string {
+	parts := []string{"go"}
+	if limits.Infinite {
+		parts = append(parts, "infinite")
+	}
+	if limits.Ponder {
+		parts = append(parts, "ponder")
+	}
+	for _, tc := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"wtime", limits.Time[board.White]}, {"btime", limits.Time[board.Black]},
+		{"winc", limits.Inc[board.White]}, {"binc", limits.Inc[board.Black]},
+		{"movetime", limits.MoveTime},
+	} {
+		if tc.d > 0 {
+			parts = append(parts, tc.name, strconv.FormatInt(tc.d.Milliseconds(), 10))
+		}
+	}
+	if limits.MovesToGo > 0 {
+		parts = append(parts, "movestogo", strconv.Itoa(limits.MovesToGo))
+	}
+	if limits.Depth > 0 {
+		parts = append(parts, "depth", strconv.Itoa(limits.Depth))
+	}
+	if limits.Nodes > 0 {
+		parts = append(parts, "nodes", strconv.FormatUint(limits.Nodes, 10))
+	}
+	if limits.Mate > 0 {
+		parts = append(parts, "mate", strconv.Itoa(limits.Mate))
+	}
+	if len(limits.SearchMoves) > 0 {
+		parts = append(parts, "searchmoves")
+		for _, m := range limits.SearchMoves {
+			parts = append(parts, m.String())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseInfo reads the fields of an info line after "info" into a search
+// report for pos. Lines without a score, and lines of other PVs than the
+// first in MultiPV mode, are not reports.
+func parseInfo(fields []string, pos *board.Position) (engine.SearchInfo, bool) {
+	var info engine.SearchInfo
+	scored := false
+	for i := 0; i < len(fields); i++ {
+		next := func() int {
+			if i+1 >= len(fields) {
+				return 0
+			}
+			i++
+			n, _ := strconv.Atoi(fields[i])
+			return n
+		}
+		switch fields[i] {
+		case "depth":
+			info.Depth = next()
+		case "seldepth":
+			info.SelDepth = next()
+		case "multipv":
+			if next() > 1 {
+				return info, false
+			}
+		case "score":
+			if i+2 >= len(fields) {
+				return info, false
+			}
+			kind := fields[i+1]
+			i++
+			n := next()
+			switch {
+			case kind == "cp":
+				info.Score = n
+			case kind == "mate" && n > 0:
+				info.Score = engine.MateScore - (2*n - 1)
+			case kind == "mate":
+				info.Score = -engine.MateScore - 2*n
+			default:
+				return info, false
+			}
+			scored = true
+		case "nodes":
+			info.Nodes = uint64(max(next(), 0))
+		case "time":
+			info.Time = time.Duration(next()) * time.Millisecond
+		case "hashfull":
+			info.HashFull = next()
+		case "pv":
+			p := pos.Copy()
+			for _, s := range fields[i+1:] {
+				m := parseClientMove(s, p)
+				if m == board.NoMove {
+					break
+				}
+				info.PV = append(info.PV, m)
+				p.MakeMove(m)
+				p.UpdateCheckers()
+			}
+			i = len(fields)
+		case "string":
+			i = len(fields)
+		}
+	}
+	return info, scored
+}
+
+// parseClientMove parses a move of the engine in UCI notation, returning
+// NoMove if it is not a legal move of pos.
+func parseClientMove(s string, pos *board.Position) board.Move {
+	m, err := board.ParseMove(s, pos)
+	if err != nil || !pos.GenerateLegalMoves().Contains(m) {
+		return board.NoMove
+	}
+	return m
+}
+
+// parseOption parses an "option" line of the uci reply. Names and values
+// may contain spaces, so fields run to the next keyword.
+func parseOption(line string) (Option, bool) {
+	keywords := map[string]bool{"name": true, "type": true, "default": true, "min": true, "max": true, "var": true}
+	var o Option
+	key := ""
+	var value []string
+	flush := func() {
+		v := strings.Join(value, " ")
+		switch key {
+		case "name":
+			o.Name = v
+		case "type":
+			o.Type = v
+		case "default":
+			if v != "<empty>" {
+				o.Default = v
+			}
+		case "min":
+			o.Min, _ = strconv.Atoi(v)
+		case "max":
+			o.Max, _ = strconv.Atoi(v)
+		case "var":
+			o.Vars = append(o.Vars, v)
+		}
+		value = nil
+	}
+	for _, f := range strings.Fields(line)[1:] {
+		// A keyword inside a name (as in "Skill Level") is not taken as one
+		if keywords[f] && !(key == "name" && f != "type") {
+			flush()
+			key = f
+			continue
+		}
+		value = append(value, f)
+	}
+	flush()
+	return o, o.Name != "" && o.Type != ""
+}
@@ -0,0 +1,103 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/explorer"
+)
+
+// Cloud evaluation: with the CloudEval option set, an analysis ("go
+// infinite") first asks Lichess for its cloud evaluation of the position.
+// One found is reported at once as the first info line, and its line is
+// stored in the transposition table as exact entries at the depth Lichess
+// searched, for the search to build on. Offline, or for a position Lichess
+// has not analyzed, the search starts as usual after at most
+// cloudEvalTimeout.
+
+// cloudEvalTimeout bounds the wait for a cloud evaluation.
+const cloudEvalTimeout = 2 * time.Second
+
+// seedCloudEval reports the cloud evaluation of pos and stores its line in
+// the transposition table, if Lichess has one.
+func (u *UCI) seedCloudEval(ctx context.Context, pos *board.Position) {
+	if u.cloudClient == nil {
+		u.cloudClient = explorer.NewClient()
+	}
+	ctx, cancel := context.WithTimeout(ctx, cloudEvalTimeout)
+	defer cancel()
+
+	e, err := u.cloudClient.CloudEval(ctx, pos.ToFEN())
+	if err != nil {
+		if !errors.Is(err, explorer.ErrNotFound) && !errors.Is(err, context.Canceled) {
+			fmt.Printf("info string cloud eval unavailable: %v\n", err)
+		}
+		return
+	}
+	pv := cloudLine(pos, e.PVs[0].UCIMoves())
+	if len(pv) == 0 {
+		return
+	}
+	score := cloudScore(e.PVs[0], pos.SideToMove)
+	u.engine.ImportAnalysis(pos, engine.LineAnalysis(pv, score, e.Depth))
+	u.sendInfo(engine.SearchInfo{Depth: e.Depth, Score: score, Nodes: uint64(e.KNodes) * 1000, PV: pv})
+}
+
+// cloudScore returns the score of a cloud line from the side to move.
+// Lichess scores from White's side, and counts a mate in moves of the
+// mating side: n moves take 2n-1 plies when that side is to move, and 2n
+// when it is the other side's turn.
+func cloudScore(pv explorer.CloudPV, us board.Color) int {
+	if pv.Mate == 0 {
+		if us == board.Black {
+			return -pv.CP
+		}
+		return pv.CP
+	}
+
+	mating, moves := board.White, pv.Mate
+	if moves < 0 {
+		mating, moves = board.Black, -moves
+	}
+	if mating == us {
+		return engine.MateScore - (2*moves - 1)
+	}
+	return -(engine.MateScore - 2*moves)
+}
+
+// cloudLine returns the legal moves of a cloud line from pos, up to the
+// first that is not.
+func cloudLine(pos *board.Position, moves []string) []board.Move {
+	p := pos.Copy()
+	var pv []board.Move
+	for _, s := range moves {
+		m := cloudMove(p, s)
+		if m == board.NoMove {
+			break
+		}
+		pv = append(pv, m)
+		p.MakeMove(m)
+		p.UpdateCheckers()
+	}
+	return pv
+}
+
+// cloudMove returns the legal move of pos in UCI notation, or NoMove.
+// Lichess may write castling as the king taking its rook, e.g. e1h1.
+func cloudMove(pos *board.Position, s string) board.Move {
+	legal := pos.GenerateLegalMoves()
+	for i := 0; i < legal.Len(); i++ {
+		m := legal.Get(i)
+		if m.String() == s {
+			return m
+		}
+		if m.IsCastling() && m.From().String()+pos.CastlingRook(pos.SideToMove, m.IsKingSideCastling()).String() == s {
+			return m
+		}
+	}
+	return board.NoMove
+}
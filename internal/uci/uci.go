@@ -2,9 +2,12 @@ package uci
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime/pprof"
+	"slices"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -12,7 +15,10 @@ import (
 
 	"github.com/hailam/chessplay/internal/board"
 	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/explorer"
+	"github.com/hailam/chessplay/internal/storage"
 	"github.com/hailam/chessplay/internal/tablebase"
+	"github.com/hailam/chessplay/sfnnue"
 )
 
 // UCI implements the Universal Chess Interface protocol.
@@ -26,27 +32,64 @@ type UCI struct {
 	// NNUE configuration
 	nnueBigPath   string
 	nnueSmallPath string
+	nnueDirs      []string           // Rescanned for new networks on ucinewgame
+	smallNetOnly  bool               // Evaluate with the small network alone
+	nnueDownload  context.CancelFunc // Cancels the DownloadNNUE download
+	nnueFetching  atomic.Bool        // DownloadNNUE download running
 
 	// Syzygy tablebase configuration
 	syzygyPath       string
 	syzygyProbeDepth int
 	syzygyProber     *tablebase.SyzygyProber
 
+	// Practical play settings for match managers
+	ponderMoves     bool          // Ponder option: suggest a move to ponder on
+	minThinkingTime time.Duration // MinimumThinkingTime option
+	resignScore     int           // Resign at this many centipawns down...
+	resignMoves     int           // ...for this many moves in a row (0 = never)
+	drawScore       int           // Offer a draw within this many centipawns of equality...
+	drawMoves       int           // ...for this many moves in a row (0 = never)
+	losingMoves     int           // Moves in a row scored as lost, this game
+	drawnMoves      int           // Moves in a row scored as drawn, this game
+
 	// Search state
 	searching     bool
 	searchDone    chan struct{}
 	stopRequested atomic.Bool
+	pondering     atomic.Bool   // A "go ponder" search holds its bestmove...
+	ponderRelease chan struct{} // ...until ponderhit or stop close this
+	ponderMissed  atomic.Bool   // The ponder search was stopped, not hit
 
 	// CPU profiling
 	profileFile *os.File
+
+	// SearchStats option: report search statistics after each search
+	searchStats bool
+
+	// CloudEval option: analyses start from Lichess's cloud evaluation
+	cloudEval   bool
+	cloudClient *explorer.Client   // Created when first used
+	cloudCancel context.CancelFunc // Stops the running cloud lookup
 }
 
 // New creates a new UCI protocol handler.
 func New(eng *engine.Engine) *UCI {
-	return &UCI{
-		engine:   eng,
-		position: board.NewPosition(),
+	u := &UCI{
+		engine:      eng,
+		position:    board.NewPosition(),
+		resignScore: defaultResignScore,
+		drawScore:   defaultDrawScore,
 	}
+	eng.OnBookExit = func() {
+		fmt.Println("info string out of book")
+	}
+	return u
+}
+
+// SetNNUEDirs sets the directories that are rescanned for new .nnue files
+// whenever a new game starts.
+func (u *UCI) SetNNUEDirs(dirs []string) {
+	u.nnueDirs = dirs
 }
 
 // Run starts the UCI main loop.
@@ -79,15 +122,23 @@ func (u *UCI) Run() {
 			u.handleGo(args)
 		case "stop":
 			u.handleStop()
+		case "ponderhit":
+			u.handlePonderHit()
 		case "quit":
 			u.handleQuit()
 		case "setoption":
 			u.handleSetOption(args)
 		// Debug commands
 		case "d":
-			fmt.Println(u.position.String())
+			u.handleDisplay(args)
+		case "eval":
+			fmt.Print(u.engine.TraceEvaluate(u.position).String())
 		case "perft":
 			u.handlePerft(args)
+		case "tune":
+			u.handleTune(args)
+		case "bench":
+			u.Bench(args)
 		}
 	}
 }
@@ -101,8 +152,35 @@ func (u *UCI) handleUCI() {
 	fmt.Println("option name UseNNUE type check default false")
 	fmt.Println("option name EvalFile type string default <empty>")
 	fmt.Println("option name EvalFileSmall type string default <empty>")
+	fmt.Println("option name SmallNetOnly type check default false")
+	fmt.Println("option name DownloadNNUE type check default false")
+	fmt.Println("option name BookFile type string default <empty>")
+	fmt.Println("option name BookMaxPly type spin default 0 min 0 max 400")
+	fmt.Println("option name BookMinWeight type spin default 0 min 0 max 65535")
+	fmt.Println("option name BookOpeningOnly type check default false")
 	fmt.Println("option name SyzygyPath type string default <empty>")
 	fmt.Println("option name SyzygyProbeDepth type spin default 1 min 1 max 100")
+	fmt.Println("option name Ponder type check default false")
+	fmt.Println("option name MinimumThinkingTime type spin default 0 min 0 max 5000")
+	fmt.Println("option name nodestime type spin default 0 min 0 max 10000")
+	fmt.Printf("option name ResignScore type spin default %d min 100 max 10000\n", defaultResignScore)
+	fmt.Println("option name ResignMoves type spin default 0 min 0 max 100")
+	fmt.Printf("option name DrawScore type spin default %d min 0 max 100\n", defaultDrawScore)
+	fmt.Println("option name DrawMoves type spin default 0 min 0 max 100")
+	fmt.Println("option name Contempt type spin default 0 min -100 max 100")
+	fmt.Println("option name SearchStats type check default false")
+	fmt.Println("option name CloudEval type check default false")
+	// Search heuristics, for bisecting and A/B tests without rebuilding
+	for _, name := range engine.SearchFeatureNames() {
+		f, _ := engine.ParseSearchFeature(name)
+		fmt.Printf("option name Use%s type check default %t\n", name, engine.DefaultSearchFeatures.Has(f))
+	}
+	// Tunable parameters: LMR always, everything else in tune builds
+	for _, p := range engine.TunableParams() {
+		if p.Public || engine.TuneEnabled {
+			fmt.Printf("option name %s type spin default %d min %d max %d\n", p.Name, p.Default, p.Min, p.Max)
+		}
+	}
 	fmt.Println("uciok")
 }
 
@@ -111,6 +189,40 @@ func (u *UCI) handleNewGame() {
 	u.engine.Clear()
 	u.position = board.NewPosition()
 	u.positionHashes = []uint64{u.position.Hash}
+	u.losingMoves, u.drawnMoves = 0, 0
+	u.rescanNNUE()
+}
+
+// rescanNNUE switches to the newest networks in the NNUE directories, so
+// files dropped there are picked up without a restart. Without a big
+// network (or with SmallNetOnly) the small network is used alone, falling
+// back to the embedded one. Networks set with EvalFile or EvalFileSmall are
+// never replaced.
+func (u *UCI) rescanNNUE() {
+	if u.nnueBigPath != "" || u.nnueSmallPath != "" {
+		return
+	}
+	big, small := engine.NewestNNUE(engine.ScanNNUE(u.nnueDirs...))
+	if u.smallNetOnly {
+		big = ""
+	}
+	if small == "" && !sfnnue.HasEmbeddedNetwork() {
+		return
+	}
+	loadedBig, loadedSmall := u.engine.NNUEPaths()
+	if u.engine.HasNNUE() && big == loadedBig && small == loadedSmall {
+		return
+	}
+
+	hadNNUE := u.engine.HasNNUE()
+	if err := u.engine.LoadNNUE(big, small); err != nil {
+		fmt.Fprintf(os.Stderr, "info string %v\n", err)
+		return
+	}
+	if !hadNNUE {
+		u.engine.SetUseNNUE(true)
+	}
+	u.printNNUEInfo()
 }
 
 // handlePosition parses and sets up a position.
@@ -150,7 +262,7 @@ func (u *UCI) handlePosition(args []string) {
 		fenStr := strings.Join(args[1:fenEnd], " ")
 		pos, err := board.ParseFEN(fenStr)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "info string Invalid FEN: %v\n", err)
+			fmt.Fprintf(os.Stderr, "info string %v\n", err)
 			return
 		}
 		u.position = pos
@@ -173,9 +285,9 @@ func (u *UCI) handlePosition(args []string) {
 	// Apply moves
 	if moveStart < len(args) {
 		for _, moveStr := range args[moveStart:] {
-			move := u.parseMove(moveStr)
-			if move == board.NoMove {
-				fmt.Fprintf(os.Stderr, "info string Invalid move: %s\n", moveStr)
+			move, err := u.parseMove(moveStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "info string %v\n", err)
 				return
 			}
 			u.position.MakeMove(move)
@@ -196,10 +308,11 @@ func (u *UCI) handlePosition(args []string) {
 	}
 }
 
-// parseMove converts a UCI move string to a board.Move.
-func (u *UCI) parseMove(moveStr string) board.Move {
+// parseMove converts a UCI move string to a legal board.Move. The error
+// wraps board.ErrInvalidMove or board.ErrIllegalMove.
+func (u *UCI) parseMove(moveStr string) (board.Move, error) {
 	if len(moveStr) < 4 {
-		return board.NoMove
+		return board.NoMove, fmt.Errorf("%w %q", board.ErrInvalidMove, moveStr)
 	}
 
 	fromFile := int(moveStr[0] - 'a')
@@ -209,7 +322,7 @@ func (u *UCI) parseMove(moveStr string) board.Move {
 
 	if fromFile < 0 || fromFile > 7 || fromRank < 0 || fromRank > 7 ||
 		toFile < 0 || toFile > 7 || toRank < 0 || toRank > 7 {
-		return board.NoMove
+		return board.NoMove, fmt.Errorf("%w %q", board.ErrInvalidMove, moveStr)
 	}
 
 	from := board.NewSquare(fromFile, fromRank)
@@ -237,15 +350,15 @@ func (u *UCI) parseMove(moveStr string) board.Move {
 		if m.From() == from && m.To() == to {
 			if promo != 0 {
 				if m.IsPromotion() && m.Promotion() == promo {
-					return m
+					return m, nil
 				}
 			} else if !m.IsPromotion() {
-				return m
+				return m, nil
 			}
 		}
 	}
 
-	return board.NoMove
+	return board.NoMove, fmt.Errorf("%w %q", board.ErrIllegalMove, moveStr)
 }
 
 // GoOptions holds parsed "go" command options.
@@ -259,6 +372,10 @@ type GoOptions struct {
 	WInc      time.Duration
 	BInc      time.Duration
 	MovesToGo int
+	Mate      int
+	Ponder    bool
+
+	SearchMoves []board.Move // restrict the root to these moves
 }
 
 // handleGo starts a search with the given parameters.
@@ -272,22 +389,54 @@ func (u *UCI) handleGo(args []string) {
 	u.engine.OnInfo = func(info engine.SearchInfo) {
 		u.sendInfo(info)
 	}
+	u.engine.OnCurrMove = func(info engine.CurrMoveInfo) {
+		fmt.Printf("info depth %d currmove %s currmovenumber %d\n", info.Depth, info.Move.String(), info.MoveNumber)
+	}
+	u.engine.OnStats = func(info engine.SearchInfo) {
+		u.sendStats(info)
+	}
 
 	// Calculate search limits
 	limits := u.calculateLimits(opts)
+	ply := u.gamePly()
 
 	// Start search in goroutine
 	u.searching = true
 	u.stopRequested.Store(false)
 	u.searchDone = make(chan struct{})
+	u.pondering.Store(opts.Ponder)
+	u.ponderMissed.Store(false)
+	u.ponderRelease = make(chan struct{})
+	release := u.ponderRelease
 
 	pos := u.position.Copy()
+	cloud := u.cloudEval && opts.Infinite && !opts.Ponder
+	ctx, cancel := context.WithCancel(context.Background())
+	u.cloudCancel = cancel
 
 	go func() {
 		defer close(u.searchDone)
+		defer cancel()
 
-		bestMove := u.engine.SearchWithLimits(pos, limits)
+		if cloud {
+			u.seedCloudEval(ctx, pos)
+			if u.stopRequested.Load() {
+				// Stopped while waiting: answer from what is known
+				limits = engine.UCILimits{Depth: 1}
+			}
+		}
+		bestMove := u.engine.SearchWithUCILimits(pos, limits, ply)
+		if stats := u.engine.LastSearchStats(); u.searchStats && stats.Nodes > 0 {
+			fmt.Printf("info string stats %v\n", stats)
+		}
+		if tb := u.engine.LastSearchInfo().TB; u.searchStats && tb.Hits+tb.Misses > 0 {
+			fmt.Printf("info string tbcache hits %d misses %d\n", tb.Hits, tb.Misses)
+		}
 
+		// A ponder search may not answer before the GUI resolves it
+		if u.pondering.Load() {
+			<-release
+		}
 		u.searching = false
 
 		// Validate move is legal before sending
@@ -306,7 +455,10 @@ func (u *UCI) handleGo(args []string) {
 				if board.DebugMoveValidation {
 					fmt.Fprintf(os.Stderr, "info string DEBUG: Sending bestmove %s (hash=%016x)\n", bestMove.String(), validationPos.Hash)
 				}
-				fmt.Printf("bestmove %s\n", bestMove.String())
+				if !u.ponderMissed.Load() {
+					u.sendPracticalInfo()
+				}
+				u.sendBestMove(validationPos, bestMove)
 				return
 			}
 			// Move not legal - log detailed warning
@@ -332,6 +484,67 @@ func (u *UCI) handleGo(args []string) {
 	}()
 }
 
+// Defaults of the resign and draw thresholds, in centipawns.
+const (
+	defaultResignScore = 600
+	defaultDrawScore   = 10
+)
+
+// sendBestMove sends the chosen move, with the expected reply to ponder on
+// when the Ponder option is set and the PV has a legal one.
+func (u *UCI) sendBestMove(pos *board.Position, bestMove board.Move) {
+	pv := u.engine.LastSearchInfo().PV
+	if !u.ponderMoves || len(pv) < 2 || pv[0] != bestMove {
+		fmt.Printf("bestmove %s\n", bestMove.String())
+		return
+	}
+
+	after := pos.Copy()
+	after.MakeMove(bestMove)
+	after.UpdateCheckers()
+	legal := after.GenerateLegalMoves()
+	for i := 0; i < legal.Len(); i++ {
+		if legal.Get(i) == pv[1] {
+			fmt.Printf("bestmove %s ponder %s\n", bestMove.String(), pv[1].String())
+			return
+		}
+	}
+	fmt.Printf("bestmove %s\n", bestMove.String())
+}
+
+// sendPracticalInfo tells a match manager that the engine would resign or
+// accept a draw, once the search score has stayed past the ResignScore or
+// within the DrawScore for ResignMoves or DrawMoves moves in a row. Book and
+// tablebase moves carry no score and leave the counts as they are.
+func (u *UCI) sendPracticalInfo() {
+	info := u.engine.LastSearchInfo()
+	if info.Depth == 0 {
+		return
+	}
+
+	u.losingMoves++
+	if info.Score > -u.resignScore {
+		u.losingMoves = 0
+	}
+	u.drawnMoves++
+	if info.Score > u.drawScore || info.Score < -u.drawScore {
+		u.drawnMoves = 0
+	}
+
+	if u.resignMoves > 0 && u.losingMoves >= u.resignMoves {
+		fmt.Printf("info string resign score cp %d\n", info.Score)
+	} else if u.drawMoves > 0 && u.drawnMoves >= u.drawMoves {
+		fmt.Printf("info string draw score cp %d\n", info.Score)
+	}
+}
+
+// goKeywords are the tokens that terminate a "go searchmoves" move list.
+var goKeywords = map[string]bool{
+	"searchmoves": true, "ponder": true, "wtime": true, "btime": true,
+	"winc": true, "binc": true, "movestogo": true, "depth": true,
+	"nodes": true, "mate": true, "movetime": true, "infinite": true,
+}
+
 // parseGoOptions parses "go" command arguments.
 func (u *UCI) parseGoOptions(args []string) GoOptions {
 	opts := GoOptions{}
@@ -357,6 +570,8 @@ func (u *UCI) parseGoOptions(args []string) GoOptions {
 			}
 		case "infinite":
 			opts.Infinite = true
+		case "ponder":
+			opts.Ponder = true
 		case "wtime":
 			if i+1 < len(args) {
 				ms, _ := strconv.Atoi(args[i+1])
@@ -386,91 +601,50 @@ func (u *UCI) parseGoOptions(args []string) GoOptions {
 				opts.MovesToGo, _ = strconv.Atoi(args[i+1])
 				i++
 			}
+		case "mate":
+			if i+1 < len(args) {
+				opts.Mate, _ = strconv.Atoi(args[i+1])
+				i++
+			}
+		case "searchmoves":
+			// Moves follow until the next go keyword; illegal moves are ignored
+			for i+1 < len(args) && !goKeywords[args[i+1]] {
+				if move, err := u.parseMove(args[i+1]); err == nil {
+					opts.SearchMoves = append(opts.SearchMoves, move)
+				}
+				i++
+			}
 		}
 	}
 
 	return opts
 }
 
-// calculateLimits converts GoOptions to engine.SearchLimits.
-func (u *UCI) calculateLimits(opts GoOptions) engine.SearchLimits {
-	limits := engine.SearchLimits{}
-
-	if opts.Infinite {
-		limits.Infinite = true
-		return limits
-	}
-
-	if opts.Depth > 0 {
-		limits.Depth = opts.Depth
-	}
-
-	if opts.Nodes > 0 {
-		limits.Nodes = opts.Nodes
-	}
-
-	if opts.MoveTime > 0 {
-		limits.MoveTime = opts.MoveTime
-	} else if opts.WTime > 0 || opts.BTime > 0 {
-		// Time control - calculate time for this move
-		limits.MoveTime = u.calculateTimeForMove(opts)
+// calculateLimits converts "go" options into engine limits.
+// Time allocation itself is left to the engine's time manager.
+func (u *UCI) calculateLimits(opts GoOptions) engine.UCILimits {
+	return engine.UCILimits{
+		Time:        [2]time.Duration{opts.WTime, opts.BTime},
+		Inc:         [2]time.Duration{opts.WInc, opts.BInc},
+		MovesToGo:   opts.MovesToGo,
+		MoveTime:    opts.MoveTime,
+		Depth:       opts.Depth,
+		Nodes:       opts.Nodes,
+		Mate:        opts.Mate,
+		Infinite:    opts.Infinite,
+		Ponder:      opts.Ponder,
+		MinTime:     u.minThinkingTime,
+		SearchMoves: opts.SearchMoves,
 	}
-
-	return limits
 }
 
-// calculateTimeForMove determines how much time to spend on this move.
-func (u *UCI) calculateTimeForMove(opts GoOptions) time.Duration {
-	var ourTime, ourInc time.Duration
-
-	if u.position.SideToMove == board.White {
-		ourTime = opts.WTime
-		ourInc = opts.WInc
-	} else {
-		ourTime = opts.BTime
-		ourInc = opts.BInc
-	}
-
-	// Estimate moves remaining
-	movesRemaining := opts.MovesToGo
-	if movesRemaining == 0 {
-		movesRemaining = u.estimateMovesRemaining()
-	}
-
-	// Base time allocation
-	baseTime := ourTime / time.Duration(movesRemaining)
-
-	// Add increment (use 90%)
-	moveTime := baseTime + (ourInc * 90 / 100)
-
-	// Safety: never use more than 90% of remaining time
-	maxTime := ourTime * 90 / 100
-	if moveTime > maxTime {
-		moveTime = maxTime
-	}
-
-	// Minimum time
-	if moveTime < 10*time.Millisecond {
-		moveTime = 10 * time.Millisecond
-	}
-
-	// Debug logging for time management diagnosis
-	fmt.Printf("info string time_allocated=%dms moves_remaining=%d our_time=%dms our_inc=%dms\n",
-		moveTime.Milliseconds(), movesRemaining, ourTime.Milliseconds(), ourInc.Milliseconds())
-
-	return moveTime
-}
-
-// estimateMovesRemaining estimates remaining moves based on piece count.
-func (u *UCI) estimateMovesRemaining() int {
-	totalPieces := u.position.AllOccupied.PopCount()
-
-	if totalPieces > 24 {
-		return 40 // Opening/early middlegame
-	} else if totalPieces > 12 {
-		return 30 // Middlegame
+// gamePly returns the number of half-moves played in the current game.
+func (u *UCI) gamePly() int {
+	ply := 2 * (u.position.FullMoveNumber - 1)
+	if u.position.SideToMove == board.Black {
+		ply++
 	}
-	return 20 // Endgame
+	return ply
 }
 
 // sendInfo outputs search info in UCI format.
@@ -478,6 +652,9 @@ func (u *UCI) sendInfo(info engine.SearchInfo) {
 	var parts []string
 
 	parts = append(parts, fmt.Sprintf("depth %d", info.Depth))
+	if info.SelDepth > 0 {
+		parts = append(parts, fmt.Sprintf("seldepth %d", info.SelDepth))
+	}
 
 	// Score
 	if info.Score > engine.MateScore-100 {
@@ -503,6 +680,9 @@ func (u *UCI) sendInfo(info engine.SearchInfo) {
 	if info.HashFull > 0 {
 		parts = append(parts, fmt.Sprintf("hashfull %d", info.HashFull))
 	}
+	if info.TB.Found > 0 {
+		parts = append(parts, fmt.Sprintf("tbhits %d", info.TB.Found))
+	}
 
 	// PV - validate moves to prevent outputting illegal sequences
 	if len(info.PV) > 0 {
@@ -532,15 +712,51 @@ func (u *UCI) sendInfo(info engine.SearchInfo) {
 	fmt.Printf("info %s\n", strings.Join(parts, " "))
 }
 
+// sendStats outputs a periodic progress line (no score or PV).
+func (u *UCI) sendStats(info engine.SearchInfo) {
+	var parts []string
+
+	parts = append(parts, fmt.Sprintf("depth %d", info.Depth))
+	if info.SelDepth > 0 {
+		parts = append(parts, fmt.Sprintf("seldepth %d", info.SelDepth))
+	}
+	parts = append(parts, fmt.Sprintf("nodes %d", info.Nodes))
+	parts = append(parts, fmt.Sprintf("time %d", info.Time.Milliseconds()))
+	if info.Time > 0 {
+		nps := uint64(float64(info.Nodes) / info.Time.Seconds())
+		parts = append(parts, fmt.Sprintf("nps %d", nps))
+	}
+	parts = append(parts, fmt.Sprintf("hashfull %d", info.HashFull))
+	if info.TB.Found > 0 {
+		parts = append(parts, fmt.Sprintf("tbhits %d", info.TB.Found))
+	}
+
+	fmt.Printf("info %s\n", strings.Join(parts, " "))
+}
+
 // handleStop stops the current search.
 func (u *UCI) handleStop() {
 	if u.searching {
 		u.stopRequested.Store(true)
+		u.cloudCancel()
 		u.engine.Stop()
+		if u.pondering.CompareAndSwap(true, false) {
+			u.ponderMissed.Store(true)
+			close(u.ponderRelease)
+		}
 		<-u.searchDone // Wait for search to finish
 	}
 }
 
+// handlePonderHit switches a ponder search to a normal search: the opponent
+// played the expected move, so the engine's clock is running.
+func (u *UCI) handlePonderHit() {
+	if u.searching && u.pondering.CompareAndSwap(true, false) {
+		u.engine.PonderHit()
+		close(u.ponderRelease)
+	}
+}
+
 // handleQuit exits the program.
 func (u *UCI) handleQuit() {
 	u.handleStop()
@@ -594,7 +810,7 @@ func (u *UCI) handleSetOption(args []string) {
 			// Load networks if not already loaded
 			if !u.engine.HasNNUE() {
 				if err := u.engine.LoadNNUE(u.nnueBigPath, u.nnueSmallPath); err != nil {
-					fmt.Fprintf(os.Stderr, "info string Failed to load NNUE: %v\n", err)
+					fmt.Fprintf(os.Stderr, "info string %v\n", err)
 					return
 				}
 			}
@@ -606,15 +822,75 @@ func (u *UCI) handleSetOption(args []string) {
 	case "evalfilesmall":
 		u.nnueSmallPath = value
 		u.tryLoadNNUE()
+	case "downloadnnue":
+		if strings.ToLower(value) == "true" {
+			u.startNNUEDownload()
+		} else if u.nnueDownload != nil {
+			u.nnueDownload()
+		}
+	case "smallnetonly":
+		u.smallNetOnly = strings.ToLower(value) == "true"
+		if u.nnueBigPath != "" || u.nnueSmallPath != "" {
+			u.tryLoadNNUE()
+		} else {
+			u.rescanNNUE()
+		}
+	case "bookfile":
+		u.loadBook(value)
+	case "bookmaxply":
+		if ply, err := strconv.Atoi(value); err == nil && ply >= 0 {
+			u.engine.SetBookMaxPly(ply)
+		}
+	case "bookopeningonly":
+		u.engine.SetBookOpeningOnly(strings.ToLower(value) == "true")
+	case "bookminweight":
+		if w, err := strconv.Atoi(value); err == nil && w >= 0 {
+			u.engine.SetBookMinWeight(w)
+		}
 	case "syzygypath":
 		u.syzygyPath = value
 		u.initSyzygy()
+	case "ponder":
+		u.ponderMoves = strings.ToLower(value) == "true"
+	case "minimumthinkingtime":
+		if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+			u.minThinkingTime = time.Duration(ms) * time.Millisecond
+		}
+	case "nodestime":
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			u.engine.SetNodesTime(min(n, 10000))
+		}
+	case "resignscore":
+		if cp, err := strconv.Atoi(value); err == nil && cp > 0 {
+			u.resignScore = cp
+		}
+	case "resignmoves":
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			u.resignMoves = n
+		}
+	case "drawscore":
+		if cp, err := strconv.Atoi(value); err == nil && cp >= 0 {
+			u.drawScore = cp
+		}
+	case "drawmoves":
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			u.drawMoves = n
+		}
+	case "contempt":
+		if cp, err := strconv.Atoi(value); err == nil {
+			u.engine.SetContempt(cp)
+		}
 	case "syzygyprobedepth":
 		depth, err := strconv.Atoi(value)
 		if err == nil && depth >= 1 {
 			u.syzygyProbeDepth = depth
 			u.engine.SetSyzygyProbeDepth(depth)
 		}
+	case "searchstats":
+		u.searchStats = strings.ToLower(value) == "true"
+		u.engine.SetCollectStats(u.searchStats)
+	case "cloudeval":
+		u.cloudEval = strings.ToLower(value) == "true"
 	case "debug":
 		enabled := strings.ToLower(value) == "true"
 		board.DebugMoveValidation = enabled
@@ -644,18 +920,167 @@ func (u *UCI) handleSetOption(args []string) {
 			u.profileFile = f
 			fmt.Fprintf(os.Stderr, "info string CPU profiling to %s\n", value)
 		}
+	default:
+		// Search heuristics: Use<Feature>, taking effect at the next search
+		if feature, ok := strings.CutPrefix(strings.ToLower(name), "use"); ok {
+			if _, ok := engine.ParseSearchFeature(feature); ok {
+				u.engine.SetSearchFeature(feature, strings.ToLower(value) == "true")
+				return
+			}
+		}
+		// Tunable parameters (LMR in all builds, the rest in tune builds)
+		p := engine.FindTunableParam(name)
+		if p == nil || !(p.Public || engine.TuneEnabled) {
+			return
+		}
+		v, err := strconv.Atoi(value)
+		if err == nil {
+			err = engine.SetTunableParam(p.Name, v)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "info string Invalid value for %s: %v\n", p.Name, err)
+			return
+		}
+		u.engine.ClearEvalCache() // The evaluation weights may have changed
+	}
+}
+
+// handleTune processes the "tune" debug command.
+// "tune" lists the tunable parameters in SPSA input format,
+// "tune save <file>" and "tune load <file>" store and restore parameter sets.
+func (u *UCI) handleTune(args []string) {
+	if len(args) == 0 {
+		fmt.Print(engine.SPSAString())
+		return
+	}
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "info string Usage: tune [save|load <file>]\n")
+		return
+	}
+
+	path := strings.Join(args[1:], " ")
+	switch args[0] {
+	case "save":
+		if err := engine.SaveTunableParams(path); err != nil {
+			fmt.Fprintf(os.Stderr, "info string Failed to save parameters: %v\n", err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "info string Parameters saved to %s\n", path)
+	case "load":
+		if err := engine.LoadTunableParams(path); err != nil {
+			fmt.Fprintf(os.Stderr, "info string Failed to load parameters: %v\n", err)
+			return
+		}
+		u.engine.ClearEvalCache()
+		fmt.Fprintf(os.Stderr, "info string Parameters loaded from %s\n", path)
+	default:
+		fmt.Fprintf(os.Stderr, "info string Usage: tune [save|load <file>]\n")
 	}
 }
 
-// tryLoadNNUE attempts to load NNUE networks if both paths are set.
+// startNNUEDownload downloads the default networks into the NNUE data
+// directory in the background, reporting progress on stderr. The networks
+// are picked up by the rescan on the next ucinewgame.
+func (u *UCI) startNNUEDownload() {
+	if !u.nnueFetching.CompareAndSwap(false, true) {
+		fmt.Fprintf(os.Stderr, "info string NNUE download already running\n")
+		return
+	}
+	dir, err := storage.GetNNUEDir()
+	if err != nil {
+		u.nnueFetching.Store(false)
+		fmt.Fprintf(os.Stderr, "info string NNUE download failed: %v\n", err)
+		return
+	}
+	if !slices.Contains(u.nnueDirs, dir) {
+		u.nnueDirs = append([]string{dir}, u.nnueDirs...)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u.nnueDownload = cancel
+	go func() {
+		defer u.nnueFetching.Store(false)
+		defer cancel()
+
+		// Report every 10% of each file
+		lastFile, lastStep := 0, -1
+		err := engine.DownloadNNUE(ctx, dir, engine.DefaultNNUENets, func(p engine.NNUEDownloadProgress) {
+			pct := 100
+			if p.Total > 0 {
+				pct = int(p.Received * 100 / p.Total)
+			}
+			if p.File != lastFile || pct/10 != lastStep {
+				fmt.Fprintf(os.Stderr, "info string NNUE download %d/%d %s: %d%% (%d/%d MB)\n",
+					p.File, p.Files, p.Name, pct, p.Received>>20, p.Total>>20)
+			}
+			lastFile, lastStep = p.File, pct/10
+		})
+		switch {
+		case ctx.Err() != nil:
+			fmt.Fprintf(os.Stderr, "info string NNUE download cancelled (resumes on the next DownloadNNUE)\n")
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "info string NNUE download failed: %v\n", err)
+		default:
+			fmt.Fprintf(os.Stderr, "info string NNUE download complete, networks load on the next ucinewgame\n")
+		}
+	}()
+}
+
+// tryLoadNNUE attempts to load NNUE networks if both paths are set, or only
+// the small one with SmallNetOnly. A missing small network path falls back
+// to the embedded network.
 func (u *UCI) tryLoadNNUE() {
-	if u.nnueBigPath != "" && u.nnueSmallPath != "" {
-		if err := u.engine.LoadNNUE(u.nnueBigPath, u.nnueSmallPath); err != nil {
-			fmt.Fprintf(os.Stderr, "info string Failed to load NNUE: %v\n", err)
-		} else {
-			fmt.Fprintf(os.Stderr, "info string NNUE networks loaded\n")
+	big, small := u.nnueBigPath, u.nnueSmallPath
+	if u.smallNetOnly {
+		big = ""
+	} else if big == "" {
+		return
+	}
+	if small == "" && !sfnnue.HasEmbeddedNetwork() {
+		return
+	}
+	if err := u.engine.LoadNNUE(big, small); err != nil {
+		fmt.Fprintf(os.Stderr, "info string %v\n", err)
+	} else {
+		u.printNNUEInfo()
+	}
+}
+
+// printNNUEInfo reports the loaded networks the way Stockfish does, e.g.
+// "info string NNUE evaluation using nn-37f18f62d772.nnue (6MiB, (22528, 128, 15, 32, 1))".
+func (u *UCI) printNNUEInfo() {
+	big, small, ok := u.engine.NNUEInfo()
+	if !ok {
+		return
+	}
+	for _, info := range []sfnnue.NetworkFileInfo{big, small} {
+		if info.Name == "" {
+			continue // Small network only
+		}
+		fmt.Fprintf(os.Stderr, "info string NNUE evaluation using %s (%dMiB, (%d, %d, %d, %d, 1))\n",
+			info.Name, info.Size>>20, info.Arch.Inputs, info.Arch.L1, info.Arch.L2, info.Arch.L3)
+	}
+}
+
+// loadBook loads a Polyglot opening book ("" or <empty> unloads it).
+func (u *UCI) loadBook(path string) {
+	if path == "" || path == "<empty>" {
+		u.engine.CloseBook()
+		return
+	}
+	// A book given by name alone may be in the books directory
+	if _, err := os.Stat(path); err != nil && filepath.Base(path) == path {
+		if dir, dirErr := storage.GetBooksDir(); dirErr == nil {
+			if _, err := os.Stat(filepath.Join(dir, path)); err == nil {
+				path = filepath.Join(dir, path)
+			}
 		}
 	}
+	if err := u.engine.LoadBook(path); err != nil {
+		fmt.Fprintf(os.Stderr, "info string Failed to load book: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "info string Book loaded: %s\n", path)
 }
 
 // initSyzygy initializes Syzygy tablebase probing.
@@ -676,8 +1101,78 @@ func (u *UCI) initSyzygy() {
 	fmt.Fprintf(os.Stderr, "info string Syzygy tablebase initialized at %s\n", u.syzygyPath)
 }
 
-// handlePerft runs a perft test.
+// handleDisplay prints the board and position details, like Stockfish's "d".
+// Arguments select the style: "unicode" or "ascii" (default) pieces,
+// "nocoords" to hide coordinates and "flip" to show Black at the bottom.
+func (u *UCI) handleDisplay(args []string) {
+	opts := board.DiagramOptions{Coordinates: true}
+	for _, arg := range args {
+		switch strings.ToLower(arg) {
+		case "unicode":
+			opts.Unicode = true
+		case "ascii":
+			opts.Unicode = false
+		case "nocoords":
+			opts.Coordinates = false
+		case "coords":
+			opts.Coordinates = true
+		case "flip":
+			opts.Flipped = true
+		}
+	}
+
+	pos := u.position.Copy()
+	pos.UpdateCheckers()
+
+	fmt.Print(pos.Diagram(opts))
+	fmt.Println()
+	fmt.Printf("Fen: %s\n", pos.ToFEN())
+	fmt.Printf("Key: %016X\n", pos.Hash)
+	fmt.Printf("Pawn key: %016X\n", pos.PawnKey)
+
+	var checkers []string
+	for bb := pos.Checkers; bb != 0; {
+		checkers = append(checkers, bb.PopLSB().String())
+	}
+	fmt.Printf("Checkers: %s\n", strings.Join(checkers, " "))
+
+	// Evaluations are reported from White's side, like Stockfish
+	trace := u.engine.TraceEvaluate(pos)
+	sign := 1
+	if pos.SideToMove == board.Black {
+		sign = -1
+	}
+	fmt.Printf("Classical evaluation: %+.2f (white side)\n", float64(sign*trace.Score)/100)
+	if trace.NNUE != nil {
+		fmt.Printf("NNUE evaluation: %+.2f (white side)\n", float64(sign*trace.NNUE.Final)/100)
+	}
+}
+
+// perftHashMB is the default perft table size of "perft hashed", in MB.
+const perftHashMB = 256
+
+// handlePerft runs a perft test. The root moves are split over the engine's
+// search workers.
+// Formats:
+//   - perft <depth>
+//   - perft divide <depth> (node count per root move)
+//   - perft hashed <depth> [MB] (cache subtree counts in a perft table)
+//   - perft suite [hashed] [max depth] [epd file] (validate against known counts)
 func (u *UCI) handlePerft(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "divide":
+			u.handlePerftDivide(args[1:])
+			return
+		case "hashed":
+			u.handlePerftHashed(args[1:])
+			return
+		case "suite":
+			u.handlePerftSuite(args[1:])
+			return
+		}
+	}
+
 	depth := 5
 	if len(args) > 0 {
 		depth, _ = strconv.Atoi(args[0])
@@ -687,6 +1182,133 @@ func (u *UCI) handlePerft(args []string) {
 	nodes := u.engine.Perft(u.position, depth)
 	elapsed := time.Since(start)
 
+	printPerftStats(nodes, elapsed)
+}
+
+// handlePerftHashed runs a perft test with a perft hash table.
+func (u *UCI) handlePerftHashed(args []string) {
+	depth, sizeMB := 5, perftHashMB
+	if len(args) > 0 {
+		depth, _ = strconv.Atoi(args[0])
+	}
+	if len(args) > 1 {
+		if mb, err := strconv.Atoi(args[1]); err == nil && mb > 0 {
+			sizeMB = mb
+		}
+	}
+
+	start := time.Now()
+	nodes := u.engine.PerftHashed(u.position, depth, sizeMB)
+	elapsed := time.Since(start)
+
+	printPerftStats(nodes, elapsed)
+}
+
+// handlePerftDivide prints the perft node count below each root move.
+func (u *UCI) handlePerftDivide(args []string) {
+	depth := 5
+	if len(args) > 0 {
+		depth, _ = strconv.Atoi(args[0])
+	}
+
+	start := time.Now()
+	var nodes uint64
+	for _, e := range u.engine.PerftDivide(u.position, depth) {
+		fmt.Printf("%s: %d\n", e.Move.String(), e.Nodes)
+		nodes += e.Nodes
+	}
+	elapsed := time.Since(start)
+
+	fmt.Println()
+	printPerftStats(nodes, elapsed)
+}
+
+// handlePerftSuite checks perft counts of the standard suite, or of an EPD
+// file with ";D1 <nodes> ;D2 <nodes> ..." entries, up to a maximum depth.
+// With "hashed" all runs share one perft table, which makes deep suites
+// (depth 7 or 8) practical.
+func (u *UCI) handlePerftSuite(args []string) {
+	var table *board.PerftTable
+	if len(args) > 0 && args[0] == "hashed" {
+		table = board.NewPerftTable(perftHashMB)
+		args = args[1:]
+	}
+	maxDepth := 4
+	if len(args) > 0 {
+		if d, err := strconv.Atoi(args[0]); err == nil && d > 0 {
+			maxDepth = d
+			args = args[1:]
+		}
+	}
+
+	suite := board.PerftSuite
+	if len(args) > 0 {
+		var err error
+		suite, err = loadPerftSuite(strings.Join(args, " "))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "info string Failed to load perft suite: %v\n", err)
+			return
+		}
+	}
+
+	start := time.Now()
+	var nodes uint64
+	passed, failed := 0, 0
+	for _, c := range suite {
+		pos, err := board.ParseFEN(c.FEN)
+		if err != nil {
+			fmt.Printf("%s: %v\n", c.Name, err)
+			failed++
+			continue
+		}
+		for d := 1; d <= maxDepth && d <= len(c.Counts); d++ {
+			var got uint64
+			if table != nil {
+				got = u.engine.PerftWithTable(pos, d, table)
+			} else {
+				got = u.engine.Perft(pos, d)
+			}
+			nodes += got
+			if got == c.Counts[d-1] {
+				passed++
+				fmt.Printf("%s depth %d: %d OK\n", c.Name, d, got)
+			} else {
+				failed++
+				fmt.Printf("%s depth %d: %d FAIL (expected %d)\n", c.Name, d, got, c.Counts[d-1])
+			}
+		}
+	}
+	elapsed := time.Since(start)
+
+	fmt.Println()
+	fmt.Printf("Perft suite: %d passed, %d failed\n", passed, failed)
+	printPerftStats(nodes, elapsed)
+}
+
+// loadPerftSuite reads a perft EPD file, skipping blank lines and comments.
+func loadPerftSuite(path string) ([]board.PerftCase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var suite []board.PerftCase
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		c, err := board.ParsePerftEPD(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		suite = append(suite, c)
+	}
+	return suite, nil
+}
+
+// printPerftStats prints the node count, time and speed of a perft run.
+func printPerftStats(nodes uint64, elapsed time.Duration) {
 	fmt.Printf("Nodes: %d\n", nodes)
 	fmt.Printf("Time: %v\n", elapsed)
 	if elapsed > 0 {
@@ -694,3 +1316,31 @@ func (u *UCI) handlePerft(args []string) {
 		fmt.Printf("NPS: %.0f\n", nps)
 	}
 }
+
+// Bench handles the "bench [depth]" command, also run by "chessplay-uci bench".
+// It searches the built-in bench positions and prints the total node count,
+// which is a signature of the search: it only changes when the search does.
+func (u *UCI) Bench(args []string) {
+	depth := engine.BenchDepth
+	if len(args) > 0 {
+		if d, err := strconv.Atoi(args[0]); err == nil && d > 0 {
+			depth = d
+		}
+	}
+
+	evalMode := "classical"
+	if u.engine.UseNNUE() && u.engine.HasNNUE() {
+		evalMode = "nnue"
+	}
+	fmt.Printf("Bench: %d positions, depth %d, %s evaluation\n", len(engine.BenchPositions), depth, evalMode)
+
+	result := u.engine.Bench(depth, func(i int, fen string, info engine.SearchInfo) {
+		fmt.Printf("Position %2d/%d: %10d nodes %8d ms  %s\n",
+			i+1, len(engine.BenchPositions), info.Nodes, info.Time.Milliseconds(), fen)
+	})
+
+	fmt.Println("===========================")
+	fmt.Printf("Total time (ms) : %d\n", result.Time.Milliseconds())
+	fmt.Printf("Nodes searched  : %d\n", result.Nodes)
+	fmt.Printf("Nodes/second    : %d\n", result.NPS())
+}
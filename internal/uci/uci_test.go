@@ -0,0 +1,238 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/explorer"
+)
+
+// TestMain runs the test binary as a UCI engine when started by the client
+// tests with CHESSPLAY_UCI_CHILD set: this engine, or one that crashes when
+// asked to search.
+func TestMain(m *testing.M) {
+	switch os.Getenv("CHESSPLAY_UCI_CHILD") {
+	case "engine":
+		New(engine.NewEngine(16)).Run()
+		os.Exit(0)
+	case "crash":
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			switch cmd, _, _ := strings.Cut(scanner.Text(), " "); cmd {
+			case "uci":
+				os.Stdout.WriteString("id name Crasher\nuciok\n")
+			case "isready":
+				os.Stdout.WriteString("readyok\n")
+			case "go":
+				os.Exit(3)
+			}
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// startChild starts the test binary as a UCI engine of the given kind.
+func startChild(t *testing.T, kind string) *Client {
+	t.Helper()
+	t.Setenv("CHESSPLAY_UCI_CHILD", kind)
+	c, err := StartClient(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+// TestClient plays searches through the client against this engine run as
+// a child process.
+func TestClient(t *testing.T) {
+	c := startChild(t, "engine")
+	if c.Name != "ChessPlay" {
+		t.Errorf("Engine name %q", c.Name)
+	}
+	if o, ok := c.Option("hash"); !ok || o.Type != "spin" || o.Default != "64" || o.Max != 4096 {
+		t.Errorf("Hash option %+v, %v", o, ok)
+	}
+	if o, ok := c.Option("EvalFile"); !ok || o.Default != "" {
+		t.Errorf("EvalFile option %+v, %v", o, ok)
+	}
+	if err := c.SetOption("Hash", "16"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.NewGame(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := board.NewPosition()
+	e4 := board.NewMove(board.E2, board.E4)
+	after := start.Copy()
+	after.MakeMove(e4)
+	after.UpdateCheckers()
+	var reports int
+	c.OnInfo = func(info engine.SearchInfo) {
+		reports++
+		p := after.Copy()
+		for _, m := range info.PV {
+			if !p.GenerateLegalMoves().Contains(m) {
+				t.Errorf("Illegal PV move %v in %+v", m, info)
+			}
+			p.MakeMove(m)
+			p.UpdateCheckers()
+		}
+	}
+	best, _, err := c.Go(context.Background(), start, []board.Move{e4}, engine.UCILimits{Depth: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.GenerateLegalMoves().Contains(best) {
+		t.Errorf("Best move %v is not legal", best)
+	}
+	if info := c.LastInfo(); reports == 0 || info.Depth != 5 || len(info.PV) == 0 {
+		t.Errorf("%d reports, last %+v", reports, info)
+	}
+
+	// An infinite search ends when the context is cancelled
+	c.OnInfo = nil
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	best, _, err = c.Go(ctx, start, nil, engine.UCILimits{Infinite: true})
+	if err != nil || !start.GenerateLegalMoves().Contains(best) {
+		t.Errorf("Stopped search returned %v, %v", best, err)
+	}
+}
+
+// TestClientCrash verifies that a crashing engine is reported, not waited on.
+func TestClientCrash(t *testing.T) {
+	c := startChild(t, "crash")
+	if c.Name != "Crasher" {
+		t.Errorf("Engine name %q", c.Name)
+	}
+	if _, _, err := c.Go(context.Background(), board.NewPosition(), nil, engine.UCILimits{Depth: 1}); !errors.Is(err, ErrEngineExited) {
+		t.Errorf("Go on a crashed engine returned %v", err)
+	}
+	select {
+	case <-c.Exited():
+	case <-time.After(time.Second):
+		t.Error("Exited not closed")
+	}
+	if err := c.NewGame(); !errors.Is(err, ErrEngineExited) {
+		t.Errorf("NewGame on a crashed engine returned %v", err)
+	}
+}
+
+// TestParseOption verifies option lines, including names with spaces.
+func TestParseOption(t *testing.T) {
+	tests := []struct {
+		line string
+		want Option
+	}{
+		{"option name Skill Level type spin default 20 min 0 max 20",
+			Option{Name: "Skill Level", Type: "spin", Default: "20", Max: 20}},
+		{"option name Style type combo default Normal var Solid var Normal var Risky",
+			Option{Name: "Style", Type: "combo", Default: "Normal", Vars: []string{"Solid", "Normal", "Risky"}}},
+		{"option name Clear Hash type button",
+			Option{Name: "Clear Hash", Type: "button"}},
+	}
+	for _, tt := range tests {
+		got, ok := parseOption(tt.line)
+		if !ok || got.Name != tt.want.Name || got.Type != tt.want.Type || got.Default != tt.want.Default ||
+			got.Max != tt.want.Max || strings.Join(got.Vars, ",") != strings.Join(tt.want.Vars, ",") {
+			t.Errorf("%q: got %+v", tt.line, got)
+		}
+	}
+	if _, ok := parseOption("option name Broken"); ok {
+		t.Error("Option without a type accepted")
+	}
+}
+
+// FuzzCommandArgs feeds arbitrary "position" and "go" arguments to the
+// parsers: bad input is reported or ignored, and only legal moves are
+// played or searched.
+// Run with go test -fuzz=FuzzCommandArgs ./internal/uci
+func FuzzCommandArgs(f *testing.F) {
+	for _, s := range []string{
+		"startpos moves e2e4 e7e5 g1f3",
+		"startpos moves e2e4 e2e4",
+		"fen " + board.StartFEN + " moves e2e4",
+		"fen 8/8/8/8/8/8/8/8 w - - 0 1",
+		"fen rnbqkbnr/pppppppp/8/8/8/8 moves",
+		"startpos moves e7e8q a1a9 e2",
+		"moves startpos fen",
+	} {
+		f.Add(s, "depth 1 searchmoves e2e4 d2d4 wtime 1000")
+	}
+	f.Add("startpos", "searchmoves")
+	f.Add("startpos", "wtime -5 btime x movestogo 99999999999999999999 nodes -1")
+
+	u := New(engine.NewEngine(1))
+	f.Fuzz(func(t *testing.T, position, goArgs string) {
+		u.handlePosition(strings.Fields(position))
+		legal := u.position.GenerateLegalMoves()
+		opts := u.parseGoOptions(strings.Fields(goArgs))
+		for _, m := range opts.SearchMoves {
+			if !legal.Contains(m) {
+				t.Fatalf("searchmoves has illegal move %v", m)
+			}
+		}
+	})
+}
+
+// TestParseMoveErrors verifies that bad moves in commands are told apart
+// from illegal ones.
+func TestParseMoveErrors(t *testing.T) {
+	u := New(engine.NewEngine(1))
+	tests := []struct {
+		move string
+		want error
+	}{
+		{"e2e4", nil},
+		{"e7e8q", board.ErrIllegalMove},
+		{"e2e5", board.ErrIllegalMove},
+		{"e2", board.ErrInvalidMove},
+		{"a1a9", board.ErrInvalidMove},
+	}
+	for _, tt := range tests {
+		if _, err := u.parseMove(tt.move); !errors.Is(err, tt.want) {
+			t.Errorf("parseMove(%q): got %v, want %v", tt.move, err, tt.want)
+		}
+	}
+}
+
+// TestCloudLine verifies that cloud evaluation lines are read from the side
+// to move, with castling written either way.
+func TestCloudLine(t *testing.T) {
+	pos, err := board.ParseFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pv := cloudLine(pos, []string{"e1h1", "e8c8", "a1a8", "zz"})
+	if len(pv) != 3 || !pv[0].IsCastling() || !pv[1].IsCastling() {
+		t.Errorf("cloudLine = %v, want both castlings and a1a8", pv)
+	}
+
+	tests := []struct {
+		pv   explorer.CloudPV
+		us   board.Color
+		want int
+	}{
+		{explorer.CloudPV{CP: 35}, board.White, 35},
+		{explorer.CloudPV{CP: 35}, board.Black, -35},
+		{explorer.CloudPV{Mate: 2}, board.White, engine.MateScore - 3},
+		{explorer.CloudPV{Mate: 2}, board.Black, -(engine.MateScore - 4)},
+		{explorer.CloudPV{Mate: -1}, board.White, -(engine.MateScore - 2)},
+		{explorer.CloudPV{Mate: -1}, board.Black, engine.MateScore - 1},
+	}
+	for _, tt := range tests {
+		if got := cloudScore(tt.pv, tt.us); got != tt.want {
+			t.Errorf("cloudScore(%+v, %v) = %d, want %d", tt.pv, tt.us, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/storage"
+	"github.com/hailam/chessplay/internal/tablebase"
+)
+
+// Adjudication ends engine games whose result is clear before they are
+// played out, by the rules of the Adjudication preferences: a win once both
+// engines have agreed one side is lost for a number of moves, a draw once
+// they have agreed it is level late in the game, and the tablebase result
+// once few enough pieces are left.
+
+// tbProbe is a tablebase probe of an engine game position. It runs in the
+// background, as the prober may ask the Lichess API.
+type tbProbe struct {
+	hash   uint64 // The position probed
+	result chan tablebase.ProbeResult
+}
+
+// adjudicationPending adjudicates the engine game before the next engine
+// move, ending it if a rule applies. It returns true while the move must
+// wait for a tablebase probe.
+func (g *Game) adjudicationPending() bool {
+	if g.gameOver {
+		return false
+	}
+	a := g.prefs.Adjudication
+	if winner, ok := resignAdjudication(g.evals, len(g.moveHistory), a); ok {
+		g.adjudicateWin(winner)
+		return false
+	}
+	if g.position.FullMoveNumber > a.DrawFrom && drawAdjudication(g.evals, len(g.moveHistory), a) {
+		g.endInDraw("adjudication")
+		return false
+	}
+	return g.tablebaseAdjudicationPending()
+}
+
+// resignAdjudication returns the winner if the evaluations of the last
+// ResignMoves moves of both sides, from White's view, all give one side at
+// least ResignScore. Book and tablebase moves without an evaluation break
+// the run.
+func resignAdjudication(evals map[int]int, plies int, a storage.Adjudication) (winner board.Color, ok bool) {
+	n := 2 * a.ResignMoves
+	if n == 0 || plies < n {
+		return board.White, false
+	}
+	white, black := true, true
+	for i := plies - n; i < plies; i++ {
+		eval, found := evals[i]
+		white = white && found && eval >= a.ResignScore
+		black = black && found && eval <= -a.ResignScore
+	}
+	switch {
+	case white:
+		return board.White, true
+	case black:
+		return board.Black, true
+	}
+	return board.White, false
+}
+
+// drawAdjudication returns true if the evaluations of the last DrawMoves
+// moves of both sides are all within DrawScore of level.
+func drawAdjudication(evals map[int]int, plies int, a storage.Adjudication) bool {
+	n := 2 * a.DrawMoves
+	if n == 0 || plies < n {
+		return false
+	}
+	for i := plies - n; i < plies; i++ {
+		if eval, found := evals[i]; !found || eval > a.DrawScore || eval < -a.DrawScore {
+			return false
+		}
+	}
+	return true
+}
+
+// tablebaseAdjudicationPending ends the game with its tablebase result once
+// the local tablebases cover the position. It returns true while the probe
+// runs.
+func (g *Game) tablebaseAdjudicationPending() bool {
+	pos := g.position
+	if !g.prefs.Adjudication.Tablebases || g.tablebase == nil || pos.CastlingRights != board.NoCastling ||
+		tablebase.CountPieces(pos) > g.tablebase.MaxPieces() {
+		return false
+	}
+
+	p := g.matchTBProbe
+	if p == nil || p.hash != pos.Hash {
+		p = &tbProbe{hash: pos.Hash, result: make(chan tablebase.ProbeResult, 1)}
+		g.matchTBProbe = p
+		prober, probed := g.tablebase, pos.Copy()
+		go func() {
+			p.result <- prober.Probe(probed)
+		}()
+	}
+
+	var r tablebase.ProbeResult
+	select {
+	case r = <-p.result:
+		g.matchTBProbe = nil
+	default:
+		return true
+	}
+	if !r.Found {
+		return false // Played on
+	}
+	switch r.WDL {
+	case tablebase.WDLWin:
+		g.adjudicateWin(pos.SideToMove)
+	case tablebase.WDLLoss:
+		g.adjudicateWin(pos.SideToMove.Other())
+	default:
+		// Cursed wins and blessed losses are drawn by the 50-move rule
+		g.endInDraw("adjudication")
+	}
+	return false
+}
+
+// adjudicateWin ends the engine game as a win for the given side.
+func (g *Game) adjudicateWin(winner board.Color) {
+	if winner == board.White {
+		g.gameResult = "White wins by adjudication"
+		g.resultToken = "1-0"
+	} else {
+		g.gameResult = "Black wins by adjudication"
+		g.resultToken = "0-1"
+	}
+	g.feedback.OnAdjudicated(winner)
+	g.finishGame()
+}
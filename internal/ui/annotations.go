@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// ShapeColor is the color of a board annotation, picked with modifier keys
+// as on Lichess.
+type ShapeColor int
+
+const (
+	ShapeGreen  ShapeColor = iota // No modifier
+	ShapeRed                      // Shift
+	ShapeBlue                     // Alt
+	ShapeYellow                   // Shift+Alt
+)
+
+// Shape is an annotation drawn on the board: an arrow, or a circled square
+// when From == To.
+type Shape struct {
+	From, To board.Square
+	Color    ShapeColor
+}
+
+// shapeColorFromKeys returns the color selected by the held modifier keys.
+func shapeColorFromKeys() ShapeColor {
+	shift := IsKeyPressed(ebiten.KeyShift)
+	alt := IsKeyPressed(ebiten.KeyAlt)
+	switch {
+	case shift && alt:
+		return ShapeYellow
+	case shift:
+		return ShapeRed
+	case alt:
+		return ShapeBlue
+	}
+	return ShapeGreen
+}
+
+// handleAnnotationInput draws annotations with the right mouse button: a
+// click circles a square and a drag draws an arrow. Drawing the same shape
+// again removes it. A left click on the board clears the annotations.
+// Annotations belong to the position they were drawn on, so they disappear
+// when a move is made and come back when it is taken back.
+func (g *Game) handleAnnotationInput() {
+	mx, my := g.input.MousePosition()
+	onBoard := mx < BoardSize && my < BoardSize
+
+	// Right-click during a piece drag cancels the drag instead
+	if g.input.IsRightJustPressed() && onBoard && !g.dragging {
+		g.shapeFrom = g.renderer.ScreenToSquare(mx, my)
+		g.shapeColor = shapeColorFromKeys()
+	}
+	if g.input.IsRightJustReleased() && g.shapeFrom != board.NoSquare {
+		if to := g.renderer.ScreenToSquare(mx, my); onBoard && to != board.NoSquare {
+			g.toggleShape(Shape{From: g.shapeFrom, To: to, Color: g.shapeColor})
+		}
+		g.shapeFrom = board.NoSquare
+	}
+
+	if g.input.IsLeftJustPressed() && onBoard {
+		delete(g.shapes, g.position.Hash)
+	}
+}
+
+// toggleShape adds a shape to the current position, recolors an existing
+// shape between the same squares, or removes it if it has the same color.
+func (g *Game) toggleShape(s Shape) {
+	key := g.position.Hash
+	shapes := g.shapes[key]
+	i := slices.IndexFunc(shapes, func(o Shape) bool { return o.From == s.From && o.To == s.To })
+	switch {
+	case i < 0:
+		shapes = append(shapes, s)
+	case shapes[i].Color == s.Color:
+		shapes = slices.Delete(shapes, i, i+1)
+	default:
+		shapes[i].Color = s.Color
+	}
+
+	if len(shapes) == 0 {
+		delete(g.shapes, key)
+		return
+	}
+	if g.shapes == nil {
+		g.shapes = make(map[uint64][]Shape)
+	}
+	g.shapes[key] = shapes
+}
+
+// clearShapes removes the annotations of every position.
+func (g *Game) clearShapes() {
+	g.shapes = nil
+	g.shapeFrom = board.NoSquare
+}
+
+// currentShapes returns the annotations of the current position, including
+// the one being drawn.
+func (g *Game) currentShapes() []Shape {
+	shapes := g.shapes[g.position.Hash]
+	if g.shapeFrom == board.NoSquare {
+		return shapes
+	}
+	mx, my := g.input.MousePosition()
+	if mx >= BoardSize || my >= BoardSize {
+		return shapes
+	}
+	to := g.renderer.ScreenToSquare(mx, my)
+	if to == board.NoSquare {
+		return shapes
+	}
+	return append(slices.Clip(shapes), Shape{From: g.shapeFrom, To: to, Color: g.shapeColor})
+}
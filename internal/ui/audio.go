@@ -3,6 +3,7 @@ package ui
 
 import (
 	"math"
+	"slices"
 
 	"github.com/hajimehoshi/ebiten/v2/audio"
 )
@@ -23,10 +24,30 @@ const (
 	sampleRate = 44100
 )
 
+// SoundPack is a set of sound effects.
+type SoundPack struct {
+	ID   string // Saved in the preferences
+	Name string
+}
+
+// SoundPacks are the available sound packs; the first is the default.
+var SoundPacks = []SoundPack{
+	{ID: "wood", Name: "Wood"},
+	{ID: "soft", Name: "Soft"},
+	{ID: "digital", Name: "Digital"},
+}
+
+// soundPackIndex returns the index of a sound pack in SoundPacks, or 0 (the
+// default) for an unknown id.
+func soundPackIndex(id string) int {
+	return max(slices.IndexFunc(SoundPacks, func(sp SoundPack) bool { return sp.ID == id }), 0)
+}
+
 // AudioManager handles sound effect playback.
 type AudioManager struct {
 	context *audio.Context
 	sounds  map[SoundType][]byte
+	pack    string // ID of the generated sound pack
 	enabled bool
 	volume  float64
 }
@@ -36,6 +57,7 @@ func NewAudioManager() *AudioManager {
 	am := &AudioManager{
 		context: audio.NewContext(sampleRate),
 		sounds:  make(map[SoundType][]byte),
+		pack:    SoundPacks[0].ID,
 		enabled: true,
 		volume:  0.5,
 	}
@@ -43,8 +65,52 @@ func NewAudioManager() *AudioManager {
 	return am
 }
 
-// generateSounds creates procedural sounds for each event type.
+// SetPack switches to another sound pack (see SoundPacks). Unknown ids
+// select the default pack.
+func (am *AudioManager) SetPack(id string) {
+	id = SoundPacks[soundPackIndex(id)].ID
+	if id == am.pack {
+		return
+	}
+	am.pack = id
+	am.generateSounds()
+}
+
+// generateSounds creates procedural sounds for each event type in the
+// current sound pack.
 func (am *AudioManager) generateSounds() {
+	switch am.pack {
+	case "soft":
+		am.generateSoftSounds()
+	case "digital":
+		am.generateDigitalSounds()
+	default:
+		am.generateWoodSounds()
+	}
+}
+
+// generateSoftSounds creates quiet, rounded tones.
+func (am *AudioManager) generateSoftSounds() {
+	am.sounds[SoundMove] = am.generateTone(520, 0.09, 0.18)
+	am.sounds[SoundCapture] = am.generateTone(390, 0.12, 0.25)
+	am.sounds[SoundCheck] = am.generateTone(660, 0.2, 0.25)
+	am.sounds[SoundCastle] = joinSounds(am.generateTone(520, 0.07, 0.18), silence(0.04), am.generateTone(585, 0.07, 0.15))
+	am.sounds[SoundInvalid] = am.generateTone(200, 0.12, 0.2)
+	am.sounds[SoundGameEnd] = am.generateChord(0.6, 0.35)
+}
+
+// generateDigitalSounds creates short square-wave blips.
+func (am *AudioManager) generateDigitalSounds() {
+	am.sounds[SoundMove] = am.generateBlip(880, 0.05, 0.15)
+	am.sounds[SoundCapture] = am.generateBlip(660, 0.08, 0.22)
+	am.sounds[SoundCheck] = joinSounds(am.generateBlip(990, 0.06, 0.18), silence(0.03), am.generateBlip(1320, 0.08, 0.18))
+	am.sounds[SoundCastle] = joinSounds(am.generateBlip(880, 0.04, 0.15), silence(0.03), am.generateBlip(990, 0.04, 0.15))
+	am.sounds[SoundInvalid] = am.generateBlip(110, 0.12, 0.2)
+	am.sounds[SoundGameEnd] = joinSounds(am.generateBlip(523, 0.1, 0.18), am.generateBlip(659, 0.1, 0.18), am.generateBlip(784, 0.2, 0.18))
+}
+
+// generateWoodSounds creates the wooden clicks of the default pack.
+func (am *AudioManager) generateWoodSounds() {
 	// Move sound: short click (wood on wood)
 	am.sounds[SoundMove] = am.generateClick(440, 0.08, 0.3)
 
@@ -149,6 +215,39 @@ func (am *AudioManager) generateBuzz(freq float64, duration float64, amplitude f
 	return data
 }
 
+// generateBlip creates a square-wave blip with a fast decay.
+func (am *AudioManager) generateBlip(freq float64, duration float64, amplitude float64) []byte {
+	samples := int(sampleRate * duration)
+	data := make([]byte, samples*4)
+
+	for i := 0; i < samples; i++ {
+		t := float64(i) / sampleRate
+		envelope := math.Exp(-t * 20)
+		wave := 1.0
+		if math.Sin(2*math.Pi*freq*t) < 0 {
+			wave = -1
+		}
+		sample := wave * envelope * amplitude
+
+		val := int16(sample * 32767)
+		data[i*4] = byte(val)
+		data[i*4+1] = byte(val >> 8)
+		data[i*4+2] = byte(val)
+		data[i*4+3] = byte(val >> 8)
+	}
+	return data
+}
+
+// silence returns duration seconds of silence.
+func silence(duration float64) []byte {
+	return make([]byte, int(sampleRate*duration)*4)
+}
+
+// joinSounds plays sounds one after the other.
+func joinSounds(parts ...[]byte) []byte {
+	return slices.Concat(parts...)
+}
+
 // generateChord creates a simple major chord.
 func (am *AudioManager) generateChord(duration float64, amplitude float64) []byte {
 	samples := int(sampleRate * duration)
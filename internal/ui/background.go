@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"log"
+	"time"
+
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+// Background analysis: with "Think on your time" set, the engine goes on
+// searching the player's position while they think, filling its
+// transposition table. Its reply after the player's move then starts from
+// what it already found and reaches deeper in the same time. The search
+// stops as soon as the engine is wanted for anything else.
+
+// backgroundStopPoll is how often stopping a background search is retried,
+// in case it had not yet started when first asked to stop.
+const backgroundStopPoll = 5 * time.Millisecond
+
+// backgroundSearch is the state of the background analysis.
+type backgroundSearch struct {
+	done chan struct{} // Closed when the search returns; nil when none runs
+	hash uint64        // Position searched to the end, not searched again
+}
+
+// wantBackgroundSearch returns true if the engine may think on the player's
+// time now: on their turn against the computer, with no other engine work
+// and no settings open that could reconfigure the engine. It never may in
+// the web build.
+func (g *Game) wantBackgroundSearch() bool {
+	return backgroundSearchSupported && g.prefs.Ponder && g.mode == ModeHumanVsComputer && g.rush == nil &&
+		!g.usingEngineProcess() &&
+		!g.gameOver && !g.aiThinking && !g.assistRunning &&
+		g.position.SideToMove == g.playerColor && !g.settingsModal.IsVisible()
+}
+
+// updateBackgroundSearch starts or stops the background analysis as the
+// game calls for it.
+func (g *Game) updateBackgroundSearch() {
+	running := g.background.done != nil
+	if running && g.background.hash != g.position.Hash {
+		g.stopBackgroundSearch() // The player moved
+		running = false
+	}
+	switch want := g.wantBackgroundSearch(); {
+	case want && !running && g.background.hash != g.position.Hash:
+		g.startBackgroundSearch()
+	case !want && running:
+		g.stopBackgroundSearch()
+	}
+}
+
+// startBackgroundSearch searches the current position until stopped.
+func (g *Game) startBackgroundSearch() {
+	log.Printf("[Background] Thinking on the player's time")
+	done := make(chan struct{})
+	g.background = backgroundSearch{done: done, hash: g.position.Hash}
+	g.liveSearch.start(g.position)
+
+	pos := g.position.Copy()
+	ply := len(g.moveHistory)
+	g.engine.SetPositionHistory(g.positionHashes)
+	go func() {
+		defer close(done)
+		g.engine.SearchWithUCILimits(pos, engine.UCILimits{Infinite: true}, ply)
+	}()
+}
+
+// stopBackgroundSearch stops the background analysis, if running, and waits
+// for it so the engine is free. An interrupted search may be started again
+// for the same position; one that finished by itself is not.
+func (g *Game) stopBackgroundSearch() {
+	done := g.background.done
+	if done == nil {
+		return
+	}
+	select {
+	case <-done:
+	default:
+		g.background.hash = 0
+		for stopped := false; !stopped; {
+			g.engine.Stop()
+			select {
+			case <-done:
+				stopped = true
+			case <-time.After(backgroundStopPoll):
+			}
+		}
+	}
+	g.background.done = nil
+	if info := g.engine.LastSearchInfo(); info.Depth > 0 {
+		log.Printf("[Background] Stopped at depth %d, %d nodes", info.Depth, info.Nodes)
+	}
+}
@@ -0,0 +1,154 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+// Coach commentary section layout
+const (
+	CoachSectionH  = 140 // Label and text box, taken from the bottom of the move list
+	coachLineH     = 18
+	coachTextInset = 6
+)
+
+// CoachComment is the coach's commentary on one move.
+type CoachComment struct {
+	Ply   int    // Game ply of the move (0 = White's first move)
+	SAN   string // The move, for the heading
+	Notes []string
+}
+
+// Heading returns the move number and move, e.g. "12. Nf3" or "12... Nf6".
+func (c CoachComment) Heading() string {
+	if c.Ply%2 == 0 {
+		return fmt.Sprintf("%d. %s", c.Ply/2+1, c.SAN)
+	}
+	return fmt.Sprintf("%d... %s", c.Ply/2+1, c.SAN)
+}
+
+// addCommentary records the coach's notes on a move about to be played.
+// Must be called before the move is made on g.position.
+func (g *Game) addCommentary(m board.Move, san string) {
+	notes := engine.CoachNotes(g.position, m)
+	if len(notes) == 0 {
+		notes = []string{"A quiet move."}
+	}
+	g.commentary = append(g.commentary, CoachComment{
+		Ply:   g.startPly() + len(g.sanHistory) - 1,
+		SAN:   san,
+		Notes: notes,
+	})
+}
+
+// coachLine is one wrapped line of the commentary text box.
+type coachLine struct {
+	text    string
+	heading bool
+}
+
+// coachLines wraps the commentary to the text box width.
+func coachLines(comments []CoachComment, width int) []coachLine {
+	var lines []coachLine
+	for _, c := range comments {
+		lines = append(lines, coachLine{text: c.Heading(), heading: true})
+		for _, note := range c.Notes {
+			for _, l := range wrapText(note, width) {
+				lines = append(lines, coachLine{text: l})
+			}
+		}
+	}
+	return lines
+}
+
+// wrapText splits text into lines no wider than width, breaking at spaces.
+func wrapText(s string, width int) []string {
+	face := GetRegularFace()
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if w, _ := MeasureText(candidate, face); line != "" && int(w) > width {
+			lines = append(lines, line)
+			candidate = word
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// coachSectionY returns the top of the coach section.
+func (p *Panel) coachSectionY() int {
+	return ScreenHeight - 70 - CoachSectionH - p.gameActionsHeight()
+}
+
+// historyEndY returns the bottom of the move list, which makes room for the
+// coach section when commentary is on.
+func (p *Panel) historyEndY() int {
+	if p.game.CoachEnabled() {
+		return p.coachSectionY()
+	}
+	return ScreenHeight - 70 - p.gameActionsHeight()
+}
+
+// coachBox returns the commentary text box.
+func (p *Panel) coachBox() (x, y, w, h int) {
+	x = BoardSize + PanelPadding - 4
+	y = p.coachSectionY() + SectionLabelH + 4
+	w = PanelWidth - PanelPadding*2 + 8
+	h = CoachSectionH - SectionLabelH - 4 - 14 // Clear of the status bar
+	return x, y, w, h
+}
+
+// drawCoach draws the coach commentary section. New comments scroll into
+// view; the wheel scrolls back through earlier ones.
+func (p *Panel) drawCoach(screen *ebiten.Image) {
+	// Cover the last move list row, which may reach into the section
+	vector.DrawFilledRect(screen, p.s(BoardSize), p.s(p.coachSectionY()),
+		p.s(PanelWidth), p.s(SectionLabelH+4), panelBg, false)
+	p.drawSectionLabel(screen, "Coach", BoardSize+PanelPadding, p.coachSectionY())
+
+	x, y, w, h := p.coachBox()
+	vector.DrawFilledRect(screen, p.s(x), p.s(y), p.s(w), p.s(h), sectionBg, false)
+
+	comments := p.game.Commentary()
+	if len(comments) == 0 {
+		p.drawText(screen, "Commentary appears after each move", x+coachTextInset, y+4, textMuted)
+		p.coach.Reset()
+		p.coachCount = 0
+		return
+	}
+
+	lines := coachLines(comments, w-coachTextInset*2-8)
+	p.coach.SetContent(Rect{X: x, Y: y, W: w, H: h}, len(lines)*coachLineH+8)
+	if len(comments) != p.coachCount {
+		p.coachCount = len(comments)
+		p.coach.ScrollToEnd()
+	}
+
+	for i, l := range lines {
+		ly := y + 4 + i*coachLineH - p.coach.Offset
+		if ly < y || ly+coachLineH > y+h {
+			continue
+		}
+		c := textPrimary
+		if l.heading {
+			c = accentColor
+		}
+		p.drawText(screen, l.text, x+coachTextInset, ly, c)
+	}
+
+	// Scroll bar, as for the move list
+	p.drawScrollBar(screen, &p.coach)
+}
@@ -0,0 +1,396 @@
+package ui
+
+import (
+	"image/color"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Panel components: the buttons, tabs, labels, scrolling lists and tooltips
+// the side panel is built from. Geometry is in logical pixels; the panel
+// scales it when drawing.
+
+// Rect is an area of the panel.
+type Rect struct {
+	X, Y, W, H int
+}
+
+// Contains returns true if the point is inside the area.
+func (r Rect) Contains(x, y int) bool {
+	return x >= r.X && x < r.X+r.W && y >= r.Y && y < r.Y+r.H
+}
+
+// Columns splits the area into n columns gap apart. The last column takes
+// what is left over from the division.
+func (r Rect) Columns(n, gap int) []Rect {
+	if n <= 0 {
+		return nil
+	}
+	w := (r.W - gap*(n-1)) / n
+	cols := make([]Rect, n)
+	for i := range cols {
+		cols[i] = Rect{X: r.X + i*(w+gap), Y: r.Y, W: w, H: r.H}
+	}
+	cols[n-1].W = r.X + r.W - cols[n-1].X
+	return cols
+}
+
+// Below returns an area of height h under this one, gap below it and as
+// wide.
+func (r Rect) Below(gap, h int) Rect {
+	return Rect{X: r.X, Y: r.Y + r.H + gap, W: r.W, H: h}
+}
+
+// Button represents a clickable UI element.
+type Button struct {
+	Rect
+	Label    string
+	Tooltip  string // Shown while hovered ("" = none)
+	OnClick  func()
+	hovered  bool
+	pressed  bool
+	disabled bool // Drawn dimmed and not clickable
+}
+
+// Update tracks hover and press from the input. It returns true if the
+// button was clicked; the caller runs OnClick.
+func (b *Button) Update(input *InputHandler) bool {
+	mx, my := input.MousePosition()
+	b.hovered = !b.disabled && b.Contains(mx, my)
+	b.pressed = b.hovered && input.IsLeftPressed()
+	return b.hovered && input.IsLeftJustPressed()
+}
+
+// updateButtons updates every button, then runs OnClick of the one
+// clicked. It returns true if one was.
+func updateButtons(input *InputHandler, buttons []*Button) bool {
+	var clicked *Button
+	for _, b := range buttons {
+		if b.Update(input) {
+			clicked = b
+		}
+	}
+	if clicked == nil {
+		return false
+	}
+	if clicked.OnClick != nil {
+		clicked.OnClick()
+	}
+	return true
+}
+
+// Tabs is a row of buttons of which one is selected.
+type Tabs struct {
+	Buttons  []*Button
+	Selected func() int // Index of the selected tab
+}
+
+// NewTabs lays out one tab per label across the area. Clicking a tab
+// calls onSelect with its index.
+func NewTabs(r Rect, labels []string, selected func() int, onSelect func(int)) *Tabs {
+	t := &Tabs{Selected: selected}
+	for i, col := range r.Columns(len(labels), 0) {
+		t.Buttons = append(t.Buttons, &Button{Rect: col, Label: labels[i], OnClick: func() { onSelect(i) }})
+	}
+	return t
+}
+
+// Rect returns the area of the row.
+func (t *Tabs) Rect() Rect {
+	first, last := t.Buttons[0], t.Buttons[len(t.Buttons)-1]
+	return Rect{X: first.X, Y: first.Y, W: last.X + last.W - first.X, H: first.H}
+}
+
+// Label is a line of text at a fixed place.
+type Label struct {
+	X, Y  int
+	Text  string
+	Color color.Color
+}
+
+// drawText draws text in the regular face with its top-left corner at
+// (x, y). Unlike the panel's, modal geometry is scaled by UIScale.
+func drawText(screen *ebiten.Image, s string, x, y int, c color.Color) {
+	face := GetRegularFace()
+	if face == nil {
+		return
+	}
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(scaleD(x), scaleD(y))
+	op.ColorScale.ScaleWithColor(c)
+	text.Draw(screen, s, face, op)
+}
+
+// drawTextRight draws text like drawText, with its top-right corner at
+// (x, y).
+func drawTextRight(screen *ebiten.Image, s string, x, y int, c color.Color) {
+	face := GetRegularFace()
+	if face == nil {
+		return
+	}
+	w, _ := MeasureText(s, face)
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(scaleD(x)-w, scaleD(y))
+	op.ColorScale.ScaleWithColor(c)
+	text.Draw(screen, s, face, op)
+}
+
+// scrollStep is how far one wheel notch scrolls a list.
+const scrollStep = 30
+
+// scrollBarX is the left edge of the scroll bars, at the panel's right edge.
+const scrollBarX = BoardSize + PanelWidth - 8
+
+// ScrollList is a view onto content taller than it. The wheel scrolls it
+// while over the view, and a scroll bar at the panel edge can be dragged.
+// The owner sets the view and content height when drawing and draws the
+// visible part of the content shifted up by Offset.
+type ScrollList struct {
+	View     Rect
+	ContentH int
+	Offset   int // Pixels scrolled down
+
+	hovered         bool // Over the scroll bar
+	dragging        bool
+	dragStartY      int
+	dragStartOffset int
+}
+
+// SetContent sets the view and the content height, keeping the offset in
+// range.
+func (s *ScrollList) SetContent(view Rect, contentH int) {
+	s.View, s.ContentH = view, contentH
+	s.Offset = max(0, min(s.Offset, s.MaxOffset()))
+}
+
+// MaxOffset returns the largest offset, 0 when the content fits.
+func (s *ScrollList) MaxOffset() int {
+	return max(0, s.ContentH-s.View.H)
+}
+
+// ScrollToEnd scrolls to the bottom of the content.
+func (s *ScrollList) ScrollToEnd() {
+	s.Offset = s.MaxOffset()
+}
+
+// Reset scrolls back to the top and forgets the content.
+func (s *ScrollList) Reset() {
+	*s = ScrollList{View: s.View}
+}
+
+// bar returns the area of the scroll bar: as tall as the share of the
+// content in view, and no shorter than 20.
+func (s *ScrollList) bar() Rect {
+	h := max(20, s.View.H*s.View.H/max(s.ContentH, 1))
+	y := s.View.Y
+	if maxOffset := s.MaxOffset(); maxOffset > 0 {
+		y += (s.View.H - h) * s.Offset / maxOffset
+	}
+	return Rect{X: scrollBarX, Y: y, W: 8, H: h}
+}
+
+// Update scrolls with the wheel and drags the scroll bar. It returns true
+// while the bar is dragged, so the drag is not taken as other input.
+func (s *ScrollList) Update(input *InputHandler) bool {
+	mx, my := input.MousePosition()
+	if s.MaxOffset() == 0 {
+		s.hovered, s.dragging = false, false
+		return false
+	}
+
+	if _, wheelY := ebiten.Wheel(); wheelY != 0 && s.View.Contains(mx, my) {
+		s.Offset = max(0, min(s.Offset-int(wheelY*scrollStep), s.MaxOffset()))
+	}
+
+	bar := s.bar()
+	s.hovered = bar.Contains(mx, my)
+	if input.IsLeftJustPressed() && s.hovered {
+		s.dragging = true
+		s.dragStartY = my
+		s.dragStartOffset = s.Offset
+	}
+	if !s.dragging {
+		return false
+	}
+	if !input.IsLeftPressed() {
+		s.dragging = false
+		return false
+	}
+	// Convert the pixels dragged to content pixels
+	if scrollRange := s.View.H - bar.H; scrollRange > 0 {
+		s.Offset = s.dragStartOffset + (my-s.dragStartY)*s.MaxOffset()/scrollRange
+		s.Offset = max(0, min(s.Offset, s.MaxOffset()))
+	}
+	return true
+}
+
+// tooltipDelay is how long a button is hovered before its tooltip shows.
+const tooltipDelay = 500 * time.Millisecond
+
+// Tooltip sizes: text is wrapped to tooltipMaxW, one line per tooltipLineH.
+const (
+	tooltipMaxW  = 260
+	tooltipLineH = 20
+)
+
+// TipArea is an area that shows a tooltip while hovered without being a
+// button, such as a badge or a disabled button.
+type TipArea struct {
+	Rect
+	Tooltip string
+}
+
+// Tooltip shows the tooltip text of the hovered button or area after a
+// delay.
+type Tooltip struct {
+	text  string
+	area  Rect      // The button or area the text belongs to
+	since time.Time // When the area was first hovered
+}
+
+// Update follows the hovered button among buttons, or else the area under
+// the mouse among areas.
+func (t *Tooltip) Update(input *InputHandler, buttons []*Button, areas []TipArea) {
+	var text string
+	var area Rect
+	for _, b := range buttons {
+		if b.hovered && b.Tooltip != "" {
+			text, area = b.Tooltip, b.Rect
+		}
+	}
+	if text == "" {
+		mx, my := input.MousePosition()
+		for _, a := range areas {
+			if a.Tooltip != "" && a.Contains(mx, my) {
+				text, area = a.Tooltip, a.Rect
+			}
+		}
+	}
+	if text != t.text || area != t.area {
+		t.text, t.area, t.since = text, area, time.Now()
+	}
+}
+
+// Hide forgets the hovered button, as when the mouse is taken by a drag.
+func (t *Tooltip) Hide() {
+	*t = Tooltip{}
+}
+
+// Pending returns true while a button is hovered but its tooltip is not
+// shown yet.
+func (t *Tooltip) Pending() bool {
+	return t.text != "" && time.Since(t.since) < tooltipDelay
+}
+
+// Text returns the tooltip to show and the area it belongs to. ok is false
+// before the delay has passed.
+func (t *Tooltip) Text() (text string, area Rect, ok bool) {
+	if t.text == "" || time.Since(t.since) < tooltipDelay {
+		return "", Rect{}, false
+	}
+	return t.text, t.area, true
+}
+
+// Tooltip colors
+var (
+	tooltipBg     = color.RGBA{28, 30, 34, 240}
+	tooltipBorder = color.RGBA{90, 95, 104, 255}
+)
+
+// drawTabs draws a tab row with the selected tab highlighted.
+func (p *Panel) drawTabs(screen *ebiten.Image, t *Tabs) {
+	selected := t.Selected()
+	for i, btn := range t.Buttons {
+		isActive := i == selected
+
+		bgColor := tabInactiveBg
+		if isActive {
+			bgColor = tabActiveBg
+		} else if btn.pressed {
+			bgColor = buttonPressedBg
+		} else if btn.hovered {
+			bgColor = tabHoverBg
+		}
+
+		// Draw background
+		vector.DrawFilledRect(screen, p.s(btn.X), p.s(btn.Y), p.s(btn.W), p.s(btn.H), bgColor, false)
+
+		// Draw border - highlight on hover, green on active
+		borderC := buttonBorder
+		if isActive {
+			borderC = tabActiveBg
+		} else if btn.hovered {
+			borderC = accentColor
+		}
+		vector.StrokeRect(screen, p.s(btn.X), p.s(btn.Y), p.s(btn.W), p.s(btn.H), float32(p.scale), borderC, false)
+
+		textColor := textSecondary
+		if isActive {
+			textColor = textPrimary
+		}
+		p.drawTextCentered(screen, btn.Label, btn.X+btn.W/2, btn.Y+btn.H/2, textColor)
+	}
+}
+
+// drawDisabledButton draws a button that cannot be used now.
+func (p *Panel) drawDisabledButton(screen *ebiten.Image, btn *Button) {
+	vector.DrawFilledRect(screen, p.s(btn.X), p.s(btn.Y), p.s(btn.W), p.s(btn.H), tabInactiveBg, false)
+	p.drawTextCentered(screen, btn.Label, btn.X+btn.W/2, btn.Y+btn.H/2, textMuted)
+}
+
+// drawLabel draws a label.
+func (p *Panel) drawLabel(screen *ebiten.Image, l Label) {
+	p.drawText(screen, l.Text, l.X, l.Y, l.Color)
+}
+
+// drawScrollBar draws the scroll bar of a list whose content does not fit:
+// highlighted while hovered, in the accent color while dragged.
+func (p *Panel) drawScrollBar(screen *ebiten.Image, s *ScrollList) {
+	if s.MaxOffset() == 0 {
+		return
+	}
+	bar := s.bar()
+	c := textMuted
+	if s.dragging {
+		c = accentColor
+	} else if s.hovered {
+		c = textSecondary
+	}
+	vector.DrawFilledRect(screen, p.s(bar.X), p.s(bar.Y), p.s(4), p.s(bar.H), c, false)
+}
+
+// drawTooltip draws the tooltip of the hovered button below it, or above
+// it near the bottom of the screen, kept on the screen. Long text is
+// wrapped.
+func (p *Panel) drawTooltip(screen *ebiten.Image, t *Tooltip) {
+	tip, area, ok := t.Text()
+	if !ok {
+		return
+	}
+	face := GetRegularFace()
+	if face == nil {
+		return
+	}
+	lines := wrapText(tip, p.si(tooltipMaxW))
+	textW := 0.0
+	for _, l := range lines {
+		lw, _ := MeasureText(l, face)
+		textW = max(textW, lw)
+	}
+	w := int(textW/p.scale) + 16
+	h := len(lines)*tooltipLineH + 8
+
+	x := min(max(area.X+area.W/2-w/2, 4), ScreenWidth-w-4)
+	y := area.Y + area.H + 6
+	if y+h > ScreenHeight-4 {
+		y = area.Y - h - 6
+	}
+	vector.DrawFilledRect(screen, p.s(x), p.s(y), p.s(w), p.s(h), tooltipBg, false)
+	vector.StrokeRect(screen, p.s(x), p.s(y), p.s(w), p.s(h), float32(p.scale), tooltipBorder, false)
+	for i, l := range lines {
+		p.drawText(screen, l, x+8, y+4+i*tooltipLineH, textPrimary)
+	}
+}
@@ -1,31 +1,19 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"image/color"
-	"io"
-	"net/http"
 	"os"
-	"path/filepath"
 	"sync"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/engine"
 	"github.com/hailam/chessplay/internal/storage"
 )
 
-// NNUE network file URLs and sizes
-const (
-	SmallNetURL  = "https://tests.stockfishchess.org/api/nn/nn-37f18f62d772.nnue"
-	SmallNetName = "nn-37f18f62d772.nnue"
-	SmallNetSize = 3674624 // ~3.5 MB
-
-	BigNetURL  = "https://tests.stockfishchess.org/api/nn/nn-c288c895ea92.nnue"
-	BigNetName = "nn-c288c895ea92.nnue"
-	BigNetSize = 113246144 // ~108 MB
-)
-
 // DownloadState represents the current download state.
 type DownloadState int
 
@@ -69,8 +57,8 @@ type Downloader struct {
 	onComplete func()
 	onCancel   func()
 
-	// Cancel channel
-	cancelCh chan struct{}
+	// Cancels the download
+	cancel context.CancelFunc
 }
 
 // NewDownloader creates a new downloader.
@@ -97,17 +85,18 @@ func (d *Downloader) Show(onComplete func(), onCancel func()) {
 	d.needsCapture = true // Capture background on first draw
 	d.onComplete = onComplete
 	d.onCancel = onCancel
-	d.cancelCh = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
 
 	d.mu.Lock()
 	d.progress = DownloadProgress{
 		State:      DownloadInProgress,
-		TotalFiles: 2,
+		TotalFiles: len(engine.DefaultNNUENets),
 	}
 	d.mu.Unlock()
 
 	// Start download in background
-	go d.downloadNetworks()
+	go d.downloadNetworks(ctx)
 }
 
 // Hide closes the downloader.
@@ -117,8 +106,8 @@ func (d *Downloader) Hide() {
 
 // Cancel cancels the download.
 func (d *Downloader) Cancel() {
-	if d.cancelCh != nil {
-		close(d.cancelCh)
+	if d.cancel != nil {
+		d.cancel()
 	}
 	if d.onCancel != nil {
 		d.onCancel()
@@ -126,31 +115,20 @@ func (d *Downloader) Cancel() {
 	d.Hide()
 }
 
-// downloadNetworks downloads both NNUE network files.
-func (d *Downloader) downloadNetworks() {
+// downloadNetworks downloads both NNUE network files. A cancelled download
+// is resumed the next time.
+func (d *Downloader) downloadNetworks(ctx context.Context) {
 	nnueDir, err := storage.GetNNUEDir()
 	if err != nil {
 		d.setError(fmt.Errorf("failed to get NNUE directory: %w", err))
 		return
 	}
 
-	// Download small network first
-	d.updateProgress(SmallNetName, 1, SmallNetSize)
-	if err := d.downloadFile(SmallNetURL, filepath.Join(nnueDir, SmallNetName), SmallNetSize); err != nil {
-		d.setError(err)
-		return
-	}
-
-	// Check for cancel
-	select {
-	case <-d.cancelCh:
+	err = engine.DownloadNNUE(ctx, nnueDir, engine.DefaultNNUENets, d.updateProgress)
+	if ctx.Err() != nil {
 		return
-	default:
 	}
-
-	// Download big network
-	d.updateProgress(BigNetName, 2, BigNetSize)
-	if err := d.downloadFile(BigNetURL, filepath.Join(nnueDir, BigNetName), BigNetSize); err != nil {
+	if err != nil {
 		d.setError(err)
 		return
 	}
@@ -166,13 +144,13 @@ func (d *Downloader) downloadNetworks() {
 }
 
 // updateProgress updates the current download progress.
-func (d *Downloader) updateProgress(filename string, fileNo int, totalBytes int64) {
+func (d *Downloader) updateProgress(p engine.NNUEDownloadProgress) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	d.progress.CurrentFile = filename
-	d.progress.CurrentFileNo = fileNo
-	d.progress.TotalBytes = totalBytes
-	d.progress.BytesReceived = 0
+	d.progress.CurrentFile = p.Name
+	d.progress.CurrentFileNo = p.File
+	d.progress.TotalBytes = p.Total
+	d.progress.BytesReceived = p.Received
 }
 
 // setError sets the download error state.
@@ -183,81 +161,6 @@ func (d *Downloader) setError(err error) {
 	d.progress.Error = err
 }
 
-// downloadFile downloads a single file with progress tracking.
-func (d *Downloader) downloadFile(url, destPath string, expectedSize int64) error {
-	// Check if file already exists with reasonable size (> half expected size)
-	// Using threshold instead of exact size to handle minor size variations
-	if info, err := os.Stat(destPath); err == nil && info.Size() > expectedSize/2 {
-		// File exists and has reasonable size, skip download
-		d.mu.Lock()
-		d.progress.BytesReceived = info.Size()
-		d.mu.Unlock()
-		return nil
-	}
-
-	// Create request
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to download %s: %w", filepath.Base(destPath), err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
-	}
-
-	// Create destination file
-	tmpPath := destPath + ".tmp"
-	out, err := os.Create(tmpPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-
-	// Download with progress
-	buf := make([]byte, 32*1024)
-	for {
-		select {
-		case <-d.cancelCh:
-			out.Close()
-			os.Remove(tmpPath)
-			return fmt.Errorf("download cancelled")
-		default:
-		}
-
-		n, err := resp.Body.Read(buf)
-		if n > 0 {
-			if _, writeErr := out.Write(buf[:n]); writeErr != nil {
-				out.Close()
-				os.Remove(tmpPath)
-				return fmt.Errorf("failed to write: %w", writeErr)
-			}
-
-			d.mu.Lock()
-			d.progress.BytesReceived += int64(n)
-			d.mu.Unlock()
-		}
-
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			out.Close()
-			os.Remove(tmpPath)
-			return fmt.Errorf("download error: %w", err)
-		}
-	}
-
-	out.Close()
-
-	// Rename temp file to final name
-	if err := os.Rename(tmpPath, destPath); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to rename file: %w", err)
-	}
-
-	return nil
-}
-
 // Update handles input for the downloader.
 func (d *Downloader) Update(input *InputHandler) bool {
 	if !d.visible {
@@ -406,34 +309,31 @@ func (d *Downloader) drawCenteredText(screen *ebiten.Image, s string, centerX, c
 	text.Draw(screen, s, face, op)
 }
 
-// CheckNNUENetworks checks if NNUE networks are available.
-func CheckNNUENetworks() (smallExists, bigExists bool, err error) {
-	nnueDir, err := storage.GetNNUEDir()
-	if err != nil {
-		return false, false, err
-	}
-
-	smallPath := filepath.Join(nnueDir, SmallNetName)
-	bigPath := filepath.Join(nnueDir, BigNetName)
-
-	// Check if files exist with reasonable size (> 1MB for small, > 50MB for big)
-	// Using thresholds instead of exact sizes to handle minor size variations
-	if info, err := os.Stat(smallPath); err == nil && info.Size() > 1*1024*1024 {
-		smallExists = true
-	}
-	if info, err := os.Stat(bigPath); err == nil && info.Size() > 50*1024*1024 {
-		bigExists = true
-	}
+// nnueSearchDirs returns the directories scanned for NNUE networks, the
+// same as the UCI engine's: the download directory first.
+func nnueSearchDirs() []string {
+	return storage.NNUESearchDirs()
+}
 
-	return smallExists, bigExists, nil
+// DetectNNUENetworks returns the networks found in the NNUE directories,
+// newest first. Downloads are written to a .tmp file first, so partial
+// downloads are never listed.
+func DetectNNUENetworks() []engine.NNUEFile {
+	return engine.ScanNNUE(nnueSearchDirs()...)
 }
 
-// GetNNUEPaths returns the paths to the NNUE network files.
-func GetNNUEPaths() (smallPath, bigPath string, err error) {
-	nnueDir, err := storage.GetNNUEDir()
-	if err != nil {
-		return "", "", err
+// GetNNUEPaths returns the networks to load: the preferred big network if it
+// is still present (the newest one otherwise) and the newest small network.
+func GetNNUEPaths(preferredBig string) (smallPath, bigPath string, err error) {
+	files := DetectNNUENetworks()
+	bigPath, smallPath = engine.NewestNNUE(files)
+	for _, f := range files {
+		if f.Big && f.Path == preferredBig {
+			bigPath = f.Path
+		}
 	}
-
-	return filepath.Join(nnueDir, SmallNetName), filepath.Join(nnueDir, BigNetName), nil
+	if bigPath == "" || smallPath == "" {
+		return "", "", os.ErrNotExist
+	}
+	return smallPath, bigPath, nil
 }
@@ -0,0 +1,414 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/uci"
+)
+
+// Engine options modal dimensions
+const (
+	EngineOptionsWidth  = 600
+	EngineOptionsHeight = 560
+	EngineOptionsPadX   = 24
+	EngineOptionsPadY   = 20
+
+	engineOptionsPerPage = 10
+	engineOptionRowH     = 40
+	engineOptionLabelW   = 260 // Width of the option names
+)
+
+// engineOptionRow edits one option of the engine: a checkbox, a dropdown
+// of its choices, or a text input for numbers and strings.
+type engineOptionRow struct {
+	opt      uci.Option
+	check    *Checkbox
+	dropdown *Dropdown
+	input    *TextInput
+}
+
+// optionDefault returns the default value of an option, which engines
+// write as "<empty>" for an empty string.
+func optionDefault(o uci.Option) string {
+	if o.Default == "<empty>" {
+		return ""
+	}
+	return o.Default
+}
+
+// newEngineOptionRow creates the editor of an option at (x, y), with w
+// for the widget. Buttons have no value, so they get no row.
+func newEngineOptionRow(o uci.Option, x, y, w int) *engineOptionRow {
+	r := &engineOptionRow{opt: o}
+	switch o.Type {
+	case "check":
+		r.check = NewCheckbox(x, y+8, "", false)
+	case "combo":
+		options := make([]DropdownOption, len(o.Vars))
+		for i, v := range o.Vars {
+			options[i] = DropdownOption{Label: v, Value: v}
+		}
+		r.dropdown = NewDropdown(x, y+2, w, 32, options, 0)
+	case "spin", "string":
+		r.input = NewTextInput(x, y+2, w, 32, optionDefault(o), 260)
+	default:
+		return nil
+	}
+	return r
+}
+
+// value returns the value entered. A number out of range is brought into
+// it, and one that cannot be read is the default.
+func (r *engineOptionRow) value() string {
+	switch {
+	case r.check != nil:
+		return strconv.FormatBool(r.check.Checked)
+	case r.dropdown != nil:
+		return r.dropdown.Value()
+	case r.opt.Type == "spin":
+		n, err := strconv.Atoi(strings.TrimSpace(r.input.Value))
+		if err != nil {
+			return optionDefault(r.opt)
+		}
+		return strconv.Itoa(max(r.opt.Min, min(n, r.opt.Max)))
+	}
+	return r.input.Value
+}
+
+// setValue shows a value in the editor.
+func (r *engineOptionRow) setValue(v string) {
+	switch {
+	case r.check != nil:
+		r.check.Checked = v == "true"
+	case r.dropdown != nil:
+		r.dropdown.SetOptions(r.dropdown.Options, v)
+	default:
+		r.input.Value = v
+	}
+}
+
+// EngineOptionsModal edits the UCI options of the sparring engine, a page
+// of options at a time. Only values that differ from the engine's defaults
+// are kept.
+type EngineOptionsModal struct {
+	visible      bool
+	needsCapture bool // Set true when opening to capture background
+
+	// Position (centered on screen)
+	x, y int
+
+	engineName string
+	rows       []*engineOptionRow
+	page       int
+
+	prevBtn   *ModalButton
+	nextBtn   *ModalButton
+	resetBtn  *ModalButton
+	cancelBtn *ModalButton
+	saveBtn   *ModalButton
+
+	onSave func(values map[string]string)
+}
+
+// NewEngineOptionsModal creates a new engine options modal.
+func NewEngineOptionsModal() *EngineOptionsModal {
+	om := &EngineOptionsModal{}
+	om.x = (ScreenWidth - EngineOptionsWidth) / 2
+	om.y = (ScreenHeight - EngineOptionsHeight) / 2
+
+	btnW, btnH := 100, 38
+	btnY := om.y + EngineOptionsHeight - EngineOptionsPadY - btnH
+	right := om.x + EngineOptionsWidth - EngineOptionsPadX
+	om.saveBtn = NewModalButton(right-btnW, btnY, btnW, btnH, "Save", true, nil)
+	om.cancelBtn = NewModalButton(right-btnW*2-12, btnY, btnW, btnH, "Cancel", false, nil)
+	om.resetBtn = NewModalButton(om.x+EngineOptionsPadX, btnY, btnW, btnH, "Defaults", false, nil)
+	om.prevBtn = NewModalButton(om.x+EngineOptionsPadX+btnW+12, btnY, 40, btnH, "‹", false, nil)
+	om.nextBtn = NewModalButton(om.x+EngineOptionsPadX+btnW+60, btnY, 40, btnH, "›", false, nil)
+	om.saveBtn.OnClick = om.handleSave
+	om.cancelBtn.OnClick = om.Hide
+	om.resetBtn.OnClick = om.handleReset
+	om.prevBtn.OnClick = func() { om.page = max(0, om.page-1) }
+	om.nextBtn.OnClick = func() { om.page = min(om.pages()-1, om.page+1) }
+	return om
+}
+
+// Show opens the modal with the options an engine declares and the values
+// set for them. onSave receives the values that differ from the defaults.
+func (om *EngineOptionsModal) Show(engineName string, options []uci.Option, values map[string]string, onSave func(map[string]string)) {
+	om.visible = true
+	om.needsCapture = true
+	om.engineName = engineName
+	om.onSave = onSave
+	om.page = 0
+
+	om.rows = nil
+	widgetX := om.x + EngineOptionsPadX + engineOptionLabelW
+	widgetW := EngineOptionsWidth - EngineOptionsPadX*2 - engineOptionLabelW
+	for _, o := range options {
+		y := om.y + 60 + len(om.rows)%engineOptionsPerPage*engineOptionRowH
+		if r := newEngineOptionRow(o, widgetX, y, widgetW); r != nil {
+			v, ok := values[o.Name]
+			if !ok {
+				v = optionDefault(o)
+			}
+			r.setValue(v)
+			om.rows = append(om.rows, r)
+		}
+	}
+}
+
+// Hide closes the modal.
+func (om *EngineOptionsModal) Hide() {
+	om.visible = false
+	for _, r := range om.rows {
+		if r.input != nil {
+			r.input.SetFocused(false)
+		}
+	}
+}
+
+// IsVisible returns true if the modal is visible.
+func (om *EngineOptionsModal) IsVisible() bool {
+	return om.visible
+}
+
+// pages returns the number of pages of options.
+func (om *EngineOptionsModal) pages() int {
+	return max(1, (len(om.rows)+engineOptionsPerPage-1)/engineOptionsPerPage)
+}
+
+// pageRows returns the rows of the page shown.
+func (om *EngineOptionsModal) pageRows() []*engineOptionRow {
+	start := om.page * engineOptionsPerPage
+	return om.rows[start:min(start+engineOptionsPerPage, len(om.rows))]
+}
+
+// handleSave passes on the values that differ from the defaults and closes
+// the modal.
+func (om *EngineOptionsModal) handleSave() {
+	values := make(map[string]string)
+	for _, r := range om.rows {
+		if v := r.value(); v != optionDefault(r.opt) {
+			values[r.opt.Name] = v
+		}
+	}
+	om.Hide()
+	if om.onSave != nil {
+		om.onSave(values)
+	}
+}
+
+// handleReset shows the default of every option.
+func (om *EngineOptionsModal) handleReset() {
+	for _, r := range om.rows {
+		r.setValue(optionDefault(r.opt))
+	}
+}
+
+// openDropdown returns the dropdown of the page whose list is open, if any.
+func (om *EngineOptionsModal) openDropdown() *Dropdown {
+	for _, r := range om.pageRows() {
+		if r.dropdown != nil && r.dropdown.IsOpen() {
+			return r.dropdown
+		}
+	}
+	return nil
+}
+
+// inputFocused returns true if a text input of the page has the keyboard.
+func (om *EngineOptionsModal) inputFocused() bool {
+	for _, r := range om.pageRows() {
+		if r.input != nil && r.input.IsFocused() {
+			return true
+		}
+	}
+	return false
+}
+
+// Update handles input for the engine options modal.
+func (om *EngineOptionsModal) Update(input *InputHandler) bool {
+	if !om.visible {
+		return false
+	}
+
+	// An open list takes all input, and Escape closes it first
+	if dd := om.openDropdown(); dd != nil {
+		if IsKeyJustPressed(ebiten.KeyEscape) {
+			dd.Close()
+		} else {
+			dd.Update(input)
+		}
+		return true
+	}
+	if IsKeyJustPressed(ebiten.KeyEscape) {
+		om.Hide()
+		return true
+	}
+	if IsKeyJustPressed(ebiten.KeyEnter) && !om.inputFocused() {
+		om.handleSave()
+		return true
+	}
+
+	for _, r := range om.pageRows() {
+		switch {
+		case r.check != nil:
+			r.check.Update(input)
+		case r.dropdown != nil:
+			r.dropdown.Update(input)
+		default:
+			r.input.Update(input)
+		}
+	}
+	om.saveBtn.Update(input)
+	om.cancelBtn.Update(input)
+	om.resetBtn.Update(input)
+	if om.pages() > 1 {
+		om.prevBtn.Update(input)
+		om.nextBtn.Update(input)
+	}
+
+	// Modal consumes all input
+	return true
+}
+
+// AnyButtonHovered returns true if any button in the modal is hovered.
+func (om *EngineOptionsModal) AnyButtonHovered() bool {
+	if !om.visible {
+		return false
+	}
+	for _, r := range om.pageRows() {
+		if r.check != nil && r.check.hovered || r.dropdown != nil && (r.dropdown.hovered || r.dropdown.hoveredOpt >= 0) {
+			return true
+		}
+	}
+	return om.saveBtn.IsHovered() || om.cancelBtn.IsHovered() || om.resetBtn.IsHovered() ||
+		om.pages() > 1 && (om.prevBtn.IsHovered() || om.nextBtn.IsHovered())
+}
+
+// Draw renders the engine options modal.
+func (om *EngineOptionsModal) Draw(screen *ebiten.Image, glass *GlassEffect) {
+	if !om.visible {
+		return
+	}
+
+	// Capture background once when modal first opens (fixes flicker)
+	if om.needsCapture && glass != nil && glass.IsEnabled() {
+		glass.CaptureForModal(screen, 3.0)
+		om.needsCapture = false
+	}
+
+	if glass != nil && glass.IsEnabled() {
+		glass.DrawModalBackground(screen, 0.4)
+	} else {
+		vector.DrawFilledRect(screen, 0, 0, scaleF(ScreenWidth), scaleF(ScreenHeight), modalOverlay, false)
+	}
+
+	// Modal background, border and header
+	vector.DrawFilledRect(screen, scaleF(om.x), scaleF(om.y), scaleF(EngineOptionsWidth), scaleF(EngineOptionsHeight), modalBg, false)
+	vector.StrokeRect(screen, scaleF(om.x), scaleF(om.y), scaleF(EngineOptionsWidth), scaleF(EngineOptionsHeight), float32(UIScale*2), modalBorder, false)
+	vector.DrawFilledRect(screen, scaleF(om.x), scaleF(om.y), scaleF(EngineOptionsWidth), scaleF(44), modalHeader, false)
+	om.drawTitle(screen)
+
+	labelX := om.x + EngineOptionsPadX
+	if len(om.rows) == 0 {
+		drawText(screen, "This engine has no options to set.", labelX, om.y+64, textMuted)
+	}
+	for _, r := range om.pageRows() {
+		var y int
+		switch {
+		case r.check != nil:
+			y = r.check.Y + 2
+			r.check.Draw(screen)
+		case r.dropdown != nil:
+			y = r.dropdown.Y + 8
+			r.dropdown.Draw(screen)
+		default:
+			y = r.input.Y + 8
+			r.input.Draw(screen)
+		}
+		label := r.opt.Name
+		if r.opt.Type == "spin" {
+			label += fmt.Sprintf(" (%d-%d)", r.opt.Min, r.opt.Max)
+		}
+		drawText(screen, label, labelX, y, textSecondary)
+	}
+
+	om.saveBtn.Draw(screen)
+	om.cancelBtn.Draw(screen)
+	om.resetBtn.Draw(screen)
+	if om.pages() > 1 {
+		om.prevBtn.Draw(screen)
+		om.nextBtn.Draw(screen)
+		drawText(screen, fmt.Sprintf("%d/%d", om.page+1, om.pages()),
+			om.nextBtn.X+om.nextBtn.W+12, om.nextBtn.Y+10, textMuted)
+	}
+
+	// Last, so an open list covers the rows below it
+	if dd := om.openDropdown(); dd != nil {
+		dd.Draw(screen)
+	}
+}
+
+// drawTitle draws the modal title.
+func (om *EngineOptionsModal) drawTitle(screen *ebiten.Image) {
+	face := GetBoldFace()
+	if face == nil {
+		return
+	}
+
+	title := "Engine Options"
+	if om.engineName != "" {
+		title = om.engineName + " Options"
+	}
+	title = ellipsize(title, face, scaleD(EngineOptionsWidth-EngineOptionsPadX*2))
+	w, h := MeasureText(title, face)
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(scaleD(om.x)+scaleD(EngineOptionsWidth)/2-w/2, scaleD(om.y)+scaleD(22)-h/2)
+	op.ColorScale.ScaleWithColor(textPrimary)
+	text.Draw(screen, title, face, op)
+}
+
+// ShowEngineOptions opens the options of the sparring engine, as declared
+// by the running engine or by one started to ask. Saved values apply from
+// the engine's next start, which follows at once while sparring.
+func (g *Game) ShowEngineOptions() {
+	path := g.prefs.SparringEngine
+	if path == "" {
+		g.feedback.OnSparringEngineMissing()
+		return
+	}
+
+	var name string
+	var options []uci.Option
+	if p := g.engineProc; p != nil && p.path == path {
+		name, options = p.client.Name, p.client.Options
+	} else {
+		client, err := uci.StartClient(path)
+		if err != nil {
+			log.Printf("Warning: Failed to start %s: %v", path, err)
+			g.feedback.OnEngineStartFailed(err)
+			return
+		}
+		name, options = client.Name, client.Options
+		go client.Close()
+	}
+
+	g.engineOptions.Show(name, options, g.prefs.SparringOptions, func(values map[string]string) {
+		if maps.Equal(values, g.prefs.SparringOptions) {
+			return
+		}
+		g.prefs.SparringOptions = values
+		g.savePreferences()
+		if p := g.engineProc; p != nil && p.sparring {
+			g.closeEngineProcess()
+			g.updateEngineProcess()
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package ui
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/uci"
+)
+
+// Engine process: with the EngineProcess preference set, the computer's
+// moves against the player come from a UCI engine run as a child process,
+// chessplay-uci by default. A crash in its search then ends that process
+// instead of the game: the built-in engine plays the move and the rest of
+// the game. Any other UCI engine, such as Stockfish, can be played against
+// the same way. Hints, analysis, thinking on the player's time and engine
+// matches stay with the built-in engine.
+//
+// Sparring runs the same way: the opponent is the sparring engine chosen in
+// Settings, with the options set for it, at a fixed time per move.
+
+// sparringMoveTime is the sparring engine's time per move. Its strength is
+// set with its own options, such as Skill Level or UCI_Elo.
+const sparringMoveTime = 2 * time.Second
+
+// sparringPVMoves is how much of the sparring engine's PV the panel shows.
+const sparringPVMoves = 6
+
+// engineProcess is the running engine process.
+type engineProcess struct {
+	client   *uci.Client
+	path     string // What was started, to restart when the preferences change
+	sparring bool   // Started as the sparring engine, with its options
+
+	// Searches run one at a time: a cancelled one may still be ending when
+	// the next starts or the process closes
+	mu      sync.Mutex
+	useNNUE string // Last UseNNUE value sent ("" = none yet)
+
+	cancel context.CancelFunc // Stops the running search; nil when none runs
+}
+
+// enginePath returns the engine the process runs: the preference, or else
+// chessplay-uci beside the game, or else the one on the PATH.
+func (g *Game) enginePath() string {
+	if g.prefs.EnginePath != "" {
+		return g.prefs.EnginePath
+	}
+	name := "chessplay-uci"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if exe, err := os.Executable(); err == nil {
+		path := filepath.Join(filepath.Dir(exe), name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path
+	}
+	return name
+}
+
+// updateEngineProcess starts, restarts or closes the engine process to
+// follow the preferences: the sparring engine while sparring, or else
+// chessplay-uci or the engine named with the EngineProcess preference.
+func (g *Game) updateEngineProcess() {
+	path, sparring := "", g.prefs.Sparring
+	switch {
+	case !engineProcessSupported:
+	case sparring:
+		path = g.prefs.SparringEngine
+	case g.prefs.EngineProcess:
+		path = g.enginePath()
+	}
+	if path == "" {
+		g.closeEngineProcess()
+		return
+	}
+	if p := g.engineProc; p != nil && p.path == path && p.sparring == sparring {
+		return
+	}
+	g.closeEngineProcess()
+
+	client, err := uci.StartClient(path)
+	if err != nil {
+		log.Printf("[AI] Could not start the engine process: %v", err)
+		g.feedback.OnEngineProcessFailed(false, err)
+		return
+	}
+	if sparring {
+		for _, o := range client.Options {
+			if v, ok := g.prefs.SparringOptions[o.Name]; ok {
+				if err := client.SetOption(o.Name, v); err != nil {
+					log.Printf("[AI] Could not set %s: %v", o.Name, err)
+				}
+			}
+		}
+	}
+	client.OnInfo = g.liveSearch.set
+	log.Printf("[AI] Engine process started: %s (%s)", client.Name, path)
+	g.engineProc = &engineProcess{client: client, path: path, sparring: sparring}
+}
+
+// closeEngineProcess stops the engine process, if one runs. A search that
+// is still ending is stopped first and the process closed once it has.
+func (g *Game) closeEngineProcess() {
+	p := g.engineProc
+	if p == nil {
+		return
+	}
+	g.engineProc = nil
+	if p.cancel != nil {
+		p.cancel()
+	}
+	go func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.client.Close()
+	}()
+}
+
+// engineProcessFailed handles an engine process that exited, as by a
+// crash: the built-in engine takes over.
+func (g *Game) engineProcessFailed() {
+	log.Printf("[AI] Engine process exited - the built-in engine plays on")
+	g.closeEngineProcess()
+	g.feedback.OnEngineProcessFailed(true, nil)
+}
+
+// usingEngineProcess returns true if the engine process plays the next
+// computer move.
+func (g *Game) usingEngineProcess() bool {
+	return g.engineProc != nil && g.mode == ModeHumanVsComputer
+}
+
+// startEngineProcessSearch searches pos with the engine process at the
+// engine's difficulty, or the sparring time, sending the move to aiMove. If
+// the process fails the built-in engine searches instead.
+func (g *Game) startEngineProcessSearch(pos *board.Position) {
+	p := g.engineProc
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	// The process gets the game from its start, for repetitions
+	start := g.startPosition()
+	moves := append([]board.Move(nil), g.moveHistory...)
+	difficulty := engine.DifficultySettings[g.engine.Difficulty()]
+	limits := engine.UCILimits{MoveTime: difficulty.MoveTime, Depth: difficulty.Depth}
+	useNNUE := "false"
+	if g.evalMode == EvalNNUE {
+		useNNUE = "true"
+	}
+	if p.sparring {
+		// The sparring engine plays with its own options
+		limits = engine.UCILimits{MoveTime: sparringMoveTime}
+		useNNUE = ""
+	}
+
+	go func() {
+		defer cancel()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if _, ok := p.client.Option("UseNNUE"); ok && useNNUE != "" && p.useNNUE != useNNUE {
+			p.client.SetOption("UseNNUE", useNNUE)
+			p.useNNUE = useNNUE
+		}
+		move, _, err := p.client.Go(ctx, start, moves, limits)
+		if err != nil {
+			log.Printf("[AI] Engine process search failed: %v", err)
+			move = g.engine.Search(pos)
+		}
+		g.aiMove <- move // Always send, even if NoMove (game over)
+	}()
+}
+
+// stopEngineProcessSearch stops the engine process's search, if one runs.
+func (g *Game) stopEngineProcessSearch() {
+	if g.engineProc != nil && g.engineProc.cancel != nil {
+		g.engineProc.cancel()
+		g.engineProc.cancel = nil
+	}
+}
+
+// lastAISearchInfo returns the report of the search that chose the
+// computer's last move.
+func (g *Game) lastAISearchInfo() engine.SearchInfo {
+	if g.usingEngineProcess() {
+		select {
+		case <-g.engineProc.client.Exited():
+			// The built-in engine searched instead
+		default:
+			return g.engineProc.client.LastInfo()
+		}
+	}
+	return g.engine.LastSearchInfo()
+}
+
+// Sparring returns true if the player's opponent is the sparring engine.
+func (g *Game) Sparring() bool {
+	return g.mode == ModeHumanVsComputer && g.engineProc != nil && g.engineProc.sparring
+}
+
+// SetSparring makes the sparring engine the player's opponent, or the
+// built-in engine again. Without a sparring engine Settings open to choose
+// one.
+func (g *Game) SetSparring(on bool) {
+	if on && g.prefs.SparringEngine == "" {
+		g.feedback.OnSparringEngineMissing()
+		g.ShowSettings()
+		return
+	}
+	if on == g.prefs.Sparring && on == g.Sparring() {
+		return
+	}
+	g.prefs.Sparring = on
+	g.updateEngineProcess()
+	if on && !g.Sparring() {
+		// It did not start, as reported
+		g.prefs.Sparring = false
+		g.updateEngineProcess()
+	}
+	g.savePreferences()
+}
+
+// OpponentName returns the name of the computer opponent: the sparring
+// engine's, or "chessplay".
+func (g *Game) OpponentName() string {
+	if g.Sparring() && g.engineProc.client.Name != "" {
+		return g.engineProc.client.Name
+	}
+	return "chessplay"
+}
+
+// SparringReport returns the sparring engine's latest search report and
+// its score from White's view, with the first moves of its PV in SAN.
+func (g *Game) SparringReport() (info engine.SearchInfo, whiteScore int, pv string) {
+	g.liveSearch.mu.Lock()
+	info = g.liveSearch.info
+	g.liveSearch.mu.Unlock()
+	whiteScore, _ = g.liveSearch.whiteScore()
+	return info, whiteScore, g.liveSearch.pvSAN(sparringPVMoves)
+}
@@ -0,0 +1,349 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/explorer"
+)
+
+// Opening explorer: with the Explorer preference set, a tab next to the move
+// list shows the moves played from the position on the board in masters'
+// games or on Lichess, how often each was played and how its games ended.
+// Clicking a move plays it. Positions are looked up in the background, one
+// at a time and only while the tab is open; offline, or while Lichess asks
+// for a pause, the tab says so and tries again later.
+
+// explorerRetry is how long a failed lookup is shown before it is retried.
+const explorerRetry = 30 * time.Second
+
+// Explorer tab layout
+const (
+	explorerRowH   = 22
+	explorerSANW   = 56 // Move column
+	explorerGamesW = 52 // Games column; the results bar takes the rest
+	explorerBarH   = 16
+)
+
+// Results bar colors
+var (
+	explorerWhiteBg = color.RGBA{225, 225, 228, 255}
+	explorerDrawBg  = color.RGBA{120, 125, 135, 255}
+	explorerBlackBg = color.RGBA{20, 22, 26, 255}
+	explorerWhiteFg = color.RGBA{30, 30, 34, 255}
+)
+
+// explorerKey is a position of a source.
+type explorerKey struct {
+	source explorer.Source
+	fen    string
+}
+
+// explorerAnswer is the outcome of a lookup.
+type explorerAnswer struct {
+	key    explorerKey
+	result *explorer.Result
+	err    error
+	at     time.Time
+}
+
+// explorerState is the state of the opening explorer tab.
+type explorerState struct {
+	client  *explorer.Client    // Created when first used
+	pending chan explorerAnswer // Lookup running (nil = none)
+	answer  explorerAnswer      // Last lookup answered
+}
+
+// ExplorerEnabled returns true if the opening explorer tab is offered. It
+// is not during a puzzle rush, where it would give answers away.
+func (g *Game) ExplorerEnabled() bool {
+	return g.prefs.Explorer && g.rush == nil
+}
+
+// ExplorerSource returns the games the explorer tab shows.
+func (g *Game) ExplorerSource() explorer.Source {
+	if g.prefs.ExplorerLichess {
+		return explorer.Lichess
+	}
+	return explorer.Masters
+}
+
+// SetExplorerSource sets the games the explorer tab shows.
+func (g *Game) SetExplorerSource(s explorer.Source) {
+	g.prefs.ExplorerLichess = s == explorer.Lichess
+	g.savePreferences()
+}
+
+// explorerKey returns the lookup of the position shown.
+func (g *Game) explorerKey() explorerKey {
+	return explorerKey{source: g.ExplorerSource(), fen: g.shownPosition().ToFEN()}
+}
+
+// pollExplorer looks up the position shown while the explorer tab is open,
+// and takes the answer when it arrives. One lookup runs at a time: while
+// moves are browsed quickly, the position reached is looked up once the
+// running lookup ends, skipping those passed through.
+func (g *Game) pollExplorer() {
+	x := &g.explorer
+	if x.pending != nil {
+		select {
+		case a := <-x.pending:
+			x.pending = nil
+			x.answer = a
+			g.redraw = true
+		default:
+			return
+		}
+	}
+	if !g.ExplorerEnabled() || !g.panel.ShowingExplorer() {
+		return
+	}
+	key := g.explorerKey()
+	if x.answer.key == key && (x.answer.err == nil || time.Since(x.answer.at) < explorerRetry) {
+		return
+	}
+
+	if x.client == nil {
+		x.client = explorer.NewClient()
+	}
+	client, pending := x.client, make(chan explorerAnswer, 1)
+	x.pending = pending
+	go func() {
+		r, err := client.Lookup(context.Background(), key.source, key.fen)
+		if err != nil && !errors.Is(err, explorer.ErrRateLimited) {
+			log.Printf("Warning: Opening explorer lookup failed: %v", err)
+		}
+		pending <- explorerAnswer{key: key, result: r, err: err, at: time.Now()}
+	}()
+}
+
+// ExplorerResult returns what the explorer knows of the position shown, or
+// the error of its lookup. Both are nil while it is looked up.
+func (g *Game) ExplorerResult() (*explorer.Result, error) {
+	a := g.explorer.answer
+	if a.key != g.explorerKey() {
+		return nil, nil
+	}
+	return a.result, a.err
+}
+
+// PlayExplorerMove plays a move of the explorer tab, as if entered by the
+// player.
+func (g *Game) PlayExplorerMove(em explorer.Move) {
+	if g.Browsing() {
+		g.rejectMoveInput("Return to the last move to play")
+		return
+	}
+	if !g.humanToMove() {
+		g.rejectMoveInput("Not your turn")
+		return
+	}
+	m := g.explorerMove(em)
+	if m == board.NoMove {
+		g.rejectMoveInput("No legal move " + em.SAN)
+		return
+	}
+	g.closeMoveInput()
+	g.clearSelection()
+	g.playerMove(m)
+}
+
+// explorerMove returns the legal move of the game position an explorer
+// move names, matched by SAN, or else NoMove.
+func (g *Game) explorerMove(em explorer.Move) board.Move {
+	san := normalizeMoveText(em.SAN)
+	moves := g.position.GenerateLegalMoves()
+	for i := 0; i < moves.Len(); i++ {
+		if m := moves.Get(i); normalizeMoveText(g.moveToSAN(m)) == san {
+			return m
+		}
+	}
+	return board.NoMove
+}
+
+// ShowingExplorer returns true if the explorer tab is shown in place of the
+// move list.
+func (p *Panel) ShowingExplorer() bool {
+	return p.explorerView && p.game.ExplorerEnabled()
+}
+
+// createExplorerTabs creates the Moves/Explorer tabs, which replace the move
+// list label, and the source tabs of the explorer. Both are placed when
+// drawn.
+func (p *Panel) createExplorerTabs() {
+	p.viewTabs = NewTabs(Rect{W: 144, H: SectionLabelH}, []string{"Moves", "Explorer"},
+		func() int {
+			if p.explorerView {
+				return 1
+			}
+			return 0
+		},
+		func(i int) { p.explorerView = i == 1 })
+	setTooltips(p.viewTabs.Buttons, []string{
+		"The moves of this game",
+		"Moves played from this position by masters or on Lichess (looked up online)",
+	})
+
+	p.sourceTabs = NewTabs(Rect{W: PanelWidth - PanelPadding*2, H: explorerRowH}, []string{"Masters", "Lichess"},
+		func() int { return int(p.game.ExplorerSource()) },
+		func(i int) { p.game.SetExplorerSource(explorer.Source(i)) })
+	setTooltips(p.sourceTabs.Buttons, []string{
+		"Over-the-board games of titled players",
+		"Rated blitz, rapid and classical games on Lichess between players rated 1800 and up",
+	})
+}
+
+// historyList returns the scrolled list in the move list area: the moves,
+// or the explorer's.
+func (p *Panel) historyList() *ScrollList {
+	if p.ShowingExplorer() {
+		return &p.explorerList
+	}
+	return &p.history
+}
+
+// placeTabs moves a tab row to the area.
+func placeTabs(t *Tabs, r Rect) {
+	for i, col := range r.Columns(len(t.Buttons), 0) {
+		t.Buttons[i].Rect = col
+	}
+}
+
+// drawViewTabs draws the Moves/Explorer tabs in the move list label row.
+func (p *Panel) drawViewTabs(screen *ebiten.Image, y int) {
+	placeTabs(p.viewTabs, Rect{X: BoardSize + PanelPadding, Y: y - 2, W: 144, H: SectionLabelH})
+	p.drawTabs(screen, p.viewTabs)
+}
+
+// drawExplorer draws the explorer tab from startY down to the end of the
+// move list area: the source tabs, then a row per move with its games and
+// a bar of its results, and the position's total last. Rows scroll as the
+// move list does; each is a button playing its move.
+func (p *Panel) drawExplorer(screen *ebiten.Image, startY int) {
+	x := BoardSize + PanelPadding
+	w := PanelWidth - PanelPadding*2
+	placeTabs(p.sourceTabs, Rect{X: x, Y: startY, W: w, H: explorerRowH})
+	p.drawTabs(screen, p.sourceTabs)
+
+	listY := startY + explorerRowH + 6
+	maxY := p.historyEndY()
+	view := Rect{X: BoardSize, Y: listY, W: PanelWidth, H: maxY - listY}
+	rows := 0
+	defer func() { p.explorerRows = p.explorerRows[:rows] }()
+
+	result, err := p.game.ExplorerResult()
+	var status string
+	switch {
+	case errors.Is(err, explorer.ErrRateLimited):
+		status = "Lichess asked for a pause; retrying soon"
+	case err != nil:
+		status = "Explorer unavailable - are you offline?"
+	case result == nil:
+		status = "Looking up..."
+	case len(result.Moves) == 0:
+		status = "No games from this position"
+	}
+	if status != "" || view.H < explorerRowH {
+		p.explorerList.SetContent(view, 0)
+		p.drawText(screen, status, x, listY+5, textMuted)
+		return
+	}
+
+	p.explorerList.SetContent(view, (len(result.Moves)+1)*explorerRowH)
+	y := listY - p.explorerList.Offset
+	for _, m := range result.Moves {
+		if y >= listY && y+explorerRowH <= maxY {
+			// Row buttons are kept from frame to frame, for their hover
+			if rows == len(p.explorerRows) {
+				p.explorerRows = append(p.explorerRows, &Button{})
+			}
+			btn := p.explorerRows[rows]
+			rows++
+			btn.Rect = Rect{X: x - 4, Y: y - 2, W: w + 8, H: explorerRowH}
+			btn.Tooltip = explorerMoveTooltip(m)
+			btn.OnClick = func() { p.game.PlayExplorerMove(m) }
+			p.drawExplorerRow(screen, btn, m.SAN, m.Games(), m.White, m.Draws, m.Black)
+		}
+		y += explorerRowH
+	}
+	if y >= listY && y+explorerRowH <= maxY {
+		total := &Button{Rect: Rect{X: x - 4, Y: y - 2, W: w + 8, H: explorerRowH}}
+		p.drawExplorerRow(screen, total, "Total", result.Games(), result.White, result.Draws, result.Black)
+	}
+	p.drawScrollBar(screen, &p.explorerList)
+}
+
+// drawExplorerRow draws a row of the explorer: the move, its games and the
+// share of them won by White, drawn and won by Black.
+func (p *Panel) drawExplorerRow(screen *ebiten.Image, row *Button, san string, games, white, draws, black int64) {
+	if row.hovered {
+		vector.DrawFilledRect(screen, p.s(row.X), p.s(row.Y), p.s(row.W), p.s(row.H), moveRowAlt, false)
+	}
+	x, y := row.X+4, row.Y+2
+	sanColor := textPrimary
+	if row.OnClick == nil {
+		sanColor = textSecondary
+	}
+	p.drawText(screen, san, x, y, sanColor)
+	p.drawText(screen, formatGames(games), x+explorerSANW, y, textSecondary)
+	if games == 0 {
+		return
+	}
+
+	barX := x + explorerSANW + explorerGamesW
+	barW := row.X + row.W - 4 - barX
+	barY := y + (explorerRowH-explorerBarH)/2 - 2
+	segments := []struct {
+		n      int64
+		bg, fg color.Color
+	}{
+		{white, explorerWhiteBg, explorerWhiteFg},
+		{draws, explorerDrawBg, textPrimary},
+		{black, explorerBlackBg, textPrimary},
+	}
+	sx := barX
+	for i, s := range segments {
+		sw := int(int64(barW) * s.n / games)
+		if i == len(segments)-1 {
+			sw = barX + barW - sx // The rounding left over
+		}
+		if sw <= 0 {
+			continue
+		}
+		vector.DrawFilledRect(screen, p.s(sx), p.s(barY), p.s(sw), p.s(explorerBarH), s.bg, false)
+		if sw >= 34 {
+			pct := fmt.Sprintf("%d%%", (s.n*100+games/2)/games)
+			p.drawTextCentered(screen, pct, sx+sw/2, barY+explorerBarH/2, s.fg)
+		}
+		sx += sw
+	}
+}
+
+// explorerMoveTooltip describes a move of the explorer tab.
+func explorerMoveTooltip(m explorer.Move) string {
+	tip := fmt.Sprintf("Play %s: %d games", m.SAN, m.Games())
+	if m.AverageRating > 0 {
+		tip += fmt.Sprintf(", average rating %d", m.AverageRating)
+	}
+	return tip
+}
+
+// formatGames writes a number of games briefly, e.g. 950, 12.3k or 1.2M.
+func formatGames(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 10_000:
+		return fmt.Sprintf("%dk", n/1000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	}
+	return fmt.Sprint(n)
+}
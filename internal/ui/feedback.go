@@ -2,14 +2,22 @@
 package ui
 
 import (
+	"errors"
+	"fmt"
 	"image/color"
 	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
 	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/puzzle"
+	"github.com/hailam/chessplay/sfnnue"
 )
 
 // InvalidMoveReason represents why a move was rejected.
@@ -21,6 +29,7 @@ const (
 	ReasonBlockedByOwnPiece
 	ReasonInvalidPieceMovement
 	ReasonNotYourTurn
+	ReasonPiecePinned
 )
 
 // ToastType represents the type of toast notification.
@@ -294,12 +303,24 @@ func NewFeedbackManager() *FeedbackManager {
 	}
 }
 
+// SetSound enables or disables sound effects and selects the sound pack
+// (see SoundPacks).
+func (fm *FeedbackManager) SetSound(enabled bool, pack string) {
+	fm.audio.SetEnabled(enabled)
+	fm.audio.SetPack(pack)
+}
+
 // Update updates all feedback systems.
 func (fm *FeedbackManager) Update() {
 	fm.toasts.Update()
 	fm.animations.Update()
 }
 
+// Animating returns true while a toast or animation is on the screen.
+func (fm *FeedbackManager) Animating() bool {
+	return len(fm.toasts.toasts) > 0 || len(fm.animations.shakes) > 0 || len(fm.animations.flashes) > 0
+}
+
 // Draw renders all feedback overlays.
 func (fm *FeedbackManager) Draw(screen *ebiten.Image, renderer *Renderer, glass *GlassEffect) {
 	fm.animations.DrawFlashes(screen, renderer)
@@ -312,7 +333,8 @@ func (fm *FeedbackManager) Animations() *AnimationManager {
 }
 
 // OnInvalidMove handles an invalid move attempt.
-func (fm *FeedbackManager) OnInvalidMove(from, to board.Square, reason InvalidMoveReason) {
+// A non-empty detail replaces the generic message for the reason.
+func (fm *FeedbackManager) OnInvalidMove(from, to board.Square, reason InvalidMoveReason, detail string) {
 	var message string
 	switch reason {
 	case ReasonWouldLeaveKingInCheck:
@@ -323,9 +345,14 @@ func (fm *FeedbackManager) OnInvalidMove(from, to board.Square, reason InvalidMo
 		message = "Invalid move for this piece"
 	case ReasonNotYourTurn:
 		message = "Not your turn"
+	case ReasonPiecePinned:
+		message = "Illegal move - piece is pinned to the king"
 	default:
 		message = "Invalid move"
 	}
+	if detail != "" {
+		message = detail
+	}
 
 	fm.toasts.Show(message, ToastWarning, 2*time.Second)
 	fm.animations.StartShake(from)
@@ -333,6 +360,12 @@ func (fm *FeedbackManager) OnInvalidMove(from, to board.Square, reason InvalidMo
 	fm.audio.Play(SoundInvalid)
 }
 
+// OnMoveInputRejected handles a typed move that could not be played.
+func (fm *FeedbackManager) OnMoveInputRejected(message string) {
+	fm.toasts.Show(message, ToastWarning, 2*time.Second)
+	fm.audio.Play(SoundInvalid)
+}
+
 // OnCheck handles a check event.
 func (fm *FeedbackManager) OnCheck() {
 	fm.toasts.Show("Check!", ToastWarning, 2*time.Second)
@@ -363,6 +396,42 @@ func (fm *FeedbackManager) OnDraw(reason string) {
 	fm.audio.Play(SoundGameEnd)
 }
 
+// OnResign handles a resignation.
+func (fm *FeedbackManager) OnResign(loser board.Color) {
+	message := "White resigns - Black wins"
+	if loser == board.Black {
+		message = "Black resigns - White wins"
+	}
+	fm.toasts.Show(message, ToastInfo, 5*time.Second)
+	fm.audio.Play(SoundGameEnd)
+}
+
+// OnAdjudicated handles an engine game adjudicated as won.
+func (fm *FeedbackManager) OnAdjudicated(winner board.Color) {
+	message := "White wins by adjudication"
+	if winner == board.Black {
+		message = "Black wins by adjudication"
+	}
+	fm.toasts.Show(message, ToastInfo, 5*time.Second)
+	fm.audio.Play(SoundGameEnd)
+}
+
+// OnDrawOffered handles a draw offer to the other player.
+func (fm *FeedbackManager) OnDrawOffered(by board.Color) {
+	message := "White offers a draw"
+	if by == board.Black {
+		message = "Black offers a draw"
+	}
+	fm.toasts.Show(message, ToastInfo, 3*time.Second)
+}
+
+// OnDrawOfferAnswered handles the engine's answer to a draw offer.
+func (fm *FeedbackManager) OnDrawOfferAnswered(accepted bool) {
+	if !accepted {
+		fm.toasts.Show("Draw offer declined", ToastWarning, 2*time.Second)
+	}
+}
+
 // OnMoveMade handles a successful move.
 func (fm *FeedbackManager) OnMoveMade(isCapture, isCastling bool) {
 	if isCastling {
@@ -374,6 +443,124 @@ func (fm *FeedbackManager) OnMoveMade(isCapture, isCastling bool) {
 	}
 }
 
+// OnPGNExported reports the result of a PGN export.
+func (fm *FeedbackManager) OnPGNExported(path string, err error) {
+	if err != nil {
+		fm.toasts.Show("PGN export failed", ToastError, 3*time.Second)
+		return
+	}
+	fm.toasts.Show("Saved "+filepath.Base(path), ToastSuccess, 3*time.Second)
+}
+
+// OnLinkShared reports the result of copying a share link.
+func (fm *FeedbackManager) OnLinkShared(what string, err error) {
+	if err != nil {
+		fm.toasts.Show("Copy failed - link written to the log", ToastError, 3*time.Second)
+		return
+	}
+	fm.toasts.Show(what+" copied to clipboard", ToastSuccess, 3*time.Second)
+}
+
+// OnGameOpened handles opening a game from the games database.
+func (fm *FeedbackManager) OnGameOpened(err error) {
+	if err != nil {
+		fm.toasts.Show("Could not open the game"+errorReason(err), ToastError, 3*time.Second)
+		return
+	}
+	fm.toasts.Show("Game opened", ToastSuccess, 2*time.Second)
+}
+
+// OnHeatmapToggled handles showing or hiding the evaluation heatmap.
+func (fm *FeedbackManager) OnHeatmapToggled(on bool) {
+	message := "Evaluation heatmap off"
+	if on {
+		message = "Evaluation heatmap on - hover a piece for details"
+	}
+	fm.toasts.Show(message, ToastInfo, 2*time.Second)
+}
+
+// OnNetworkLoadFailed handles an NNUE network that could not be loaded.
+func (fm *FeedbackManager) OnNetworkLoadFailed(err error) {
+	fm.toasts.Show("Could not load the NNUE network"+errorReason(err), ToastError, 3*time.Second)
+}
+
+// OnEngineProcessFailed handles an engine process that could not be
+// started, or that stopped during a search. The built-in engine plays on.
+func (fm *FeedbackManager) OnEngineProcessFailed(started bool, err error) {
+	message := "Could not start the engine" + errorReason(err)
+	if started {
+		message = "The engine stopped"
+	}
+	fm.toasts.Show(message+" - using the built-in engine", ToastError, 5*time.Second)
+}
+
+// OnEngineStartFailed handles an engine that could not be started to read
+// its options.
+func (fm *FeedbackManager) OnEngineStartFailed(err error) {
+	fm.toasts.Show("Could not start the engine"+errorReason(err), ToastError, 3*time.Second)
+}
+
+// OnSparringEngineMissing asks for a sparring engine to be chosen.
+func (fm *FeedbackManager) OnSparringEngineMissing() {
+	fm.toasts.Show("Choose a sparring engine in Settings first", ToastInfo, 3*time.Second)
+}
+
+// OnUpdateAvailable announces a newer release, downloaded if staged.
+func (fm *FeedbackManager) OnUpdateAvailable(version string, staged bool) {
+	if staged {
+		fm.toasts.Show("ChessPlay "+version+" downloaded - it installs when you quit", ToastInfo, 5*time.Second)
+		return
+	}
+	fm.toasts.Show("ChessPlay "+version+" is available", ToastInfo, 5*time.Second)
+}
+
+// errorReason returns what went wrong for the user, as ": ..." to follow
+// a message, or "" for errors with no better explanation than the message.
+func errorReason(err error) string {
+	switch {
+	case errors.Is(err, board.ErrInvalidFEN):
+		return ": the position is not valid"
+	case errors.Is(err, board.ErrIllegalMove):
+		return ": it has an illegal move"
+	case errors.Is(err, board.ErrInvalidMove):
+		return ": it has a move that cannot be read"
+	case errors.Is(err, sfnnue.ErrUnsupportedNetwork):
+		return ": this network version is not supported"
+	case errors.Is(err, exec.ErrNotFound):
+		return ": the program was not found"
+	case errors.Is(err, os.ErrNotExist):
+		return ": the file is missing"
+	case errors.Is(err, engine.ErrNetworkLoad):
+		return ": the file is damaged"
+	}
+	return ""
+}
+
+// OnPuzzleSolved handles a solved rush puzzle.
+func (fm *FeedbackManager) OnPuzzleSolved(score int) {
+	fm.toasts.Show(fmt.Sprintf("Solved! %d so far", score), ToastSuccess, time.Second)
+	fm.audio.Play(SoundCheck)
+}
+
+// OnPuzzleFailed handles a wrong move in a rush puzzle, showing the
+// solution and the mistakes made.
+func (fm *FeedbackManager) OnPuzzleFailed(solution string, mistakes int) {
+	fm.toasts.Show(fmt.Sprintf("Wrong - the answer was %s (%d/%d)", solution, mistakes, puzzle.MaxMistakes),
+		ToastError, 2*time.Second)
+	fm.audio.Play(SoundInvalid)
+}
+
+// OnRushOver handles the end of a puzzle rush. rank is the high score
+// table rank, or 0.
+func (fm *FeedbackManager) OnRushOver(score, rank int) {
+	message := fmt.Sprintf("Rush over - %d puzzles solved", score)
+	if rank == 1 && score > 0 {
+		message += " - new high score!"
+	}
+	fm.toasts.Show(message, ToastInfo, 4*time.Second)
+	fm.audio.Play(SoundGameEnd)
+}
+
 // Audio returns the audio manager for settings access.
 func (fm *FeedbackManager) Audio() *AudioManager {
 	return fm.audio
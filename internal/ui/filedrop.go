@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/gamedb"
+	"github.com/hailam/chessplay/internal/share"
+)
+
+// File drop: a .pgn or .fen file dropped onto the window opens on the board
+// for replay and analysis, with both sides played at the board so the engine
+// does not answer. A PGN file with several games asks which one to open.
+
+// errNoGames is returned for a dropped file without a game or position.
+var errNoGames = errors.New("no games in the file")
+
+// errUnknownFile is returned for a dropped file that is not PGN or FEN.
+var errUnknownFile = errors.New("not a .pgn or .fen file")
+
+// handleDroppedFiles opens the first file dropped onto the window this
+// update, unless a modal is open.
+func (g *Game) handleDroppedFiles() {
+	files := ebiten.DroppedFiles()
+	if files == nil || g.modalVisible() {
+		return
+	}
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		log.Printf("Warning: Failed to read dropped files: %v", err)
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		data, err := fs.ReadFile(files, e.Name())
+		if err == nil {
+			err = g.openDroppedFile(e.Name(), data)
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to open dropped file %s: %v", e.Name(), err)
+			g.feedback.OnGameOpened(err)
+		}
+		return
+	}
+}
+
+// openDroppedFile opens the game of a dropped file, or shows the game picker
+// for a file with several.
+func (g *Game) openDroppedFile(name string, data []byte) error {
+	games, skipped, err := readGames(name, data)
+	if err != nil {
+		return err
+	}
+	if skipped > 0 {
+		log.Printf("[Drop] Skipped %d unreadable games in %s", skipped, name)
+	}
+	if len(games) == 1 {
+		err := g.openDroppedGame(games[0])
+		if err == nil {
+			g.feedback.OnGameOpened(nil)
+		}
+		return err
+	}
+	g.gamePicker.Show(filepath.Base(name), games, func(pg *gamedb.PGNGame) {
+		err := g.openDroppedGame(pg)
+		if err != nil {
+			log.Printf("Warning: Failed to open game from %s: %v", name, err)
+		}
+		g.feedback.OnGameOpened(err)
+	})
+	return nil
+}
+
+// openDroppedGame opens a game of a dropped file in Human vs Human mode.
+func (g *Game) openDroppedGame(pg *gamedb.PGNGame) error {
+	g.SetModeAction(ModeHumanVsHuman)
+	return g.OpenLink(share.New(pg.FEN, pg.Moves))
+}
+
+// readGames reads the games of a file by its extension: the position on the
+// first line of a .fen file, or every game of a .pgn file. Games that cannot
+// be read are skipped and counted; if none can, the error of the first is
+// returned.
+func readGames(name string, data []byte) (games []*gamedb.PGNGame, skipped int, err error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".fen":
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			fen := strings.TrimSpace(sc.Text())
+			if fen == "" {
+				continue
+			}
+			if _, err := board.ParseFEN(fen); err != nil {
+				return nil, 0, err
+			}
+			return []*gamedb.PGNGame{{FEN: fen}}, 0, nil
+		}
+		return nil, 0, errNoGames
+
+	case ".pgn":
+		var firstErr error
+		r := gamedb.NewPGNReader(bytes.NewReader(data))
+		for {
+			pg, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			var pgnErr *gamedb.PGNError
+			if errors.As(err, &pgnErr) {
+				skipped++
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			if err != nil {
+				return nil, 0, err
+			}
+			games = append(games, pg)
+		}
+		if len(games) == 0 && firstErr != nil {
+			return nil, skipped, firstErr
+		}
+		if len(games) == 0 {
+			return nil, 0, errNoGames
+		}
+		return games, skipped, nil
+	}
+	return nil, 0, errUnknownFile
+}
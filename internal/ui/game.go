@@ -3,12 +3,18 @@ package ui
 import (
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/eco"
 	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/puzzle"
+	"github.com/hailam/chessplay/internal/share"
 	"github.com/hailam/chessplay/internal/storage"
+	"github.com/hailam/chessplay/internal/tablebase"
 )
 
 // UI Constants
@@ -30,6 +36,7 @@ type GameMode int
 const (
 	ModeHumanVsHuman GameMode = iota
 	ModeHumanVsComputer
+	ModeComputerVsComputer // Engine match; not saved in the preferences
 )
 
 // Difficulty represents AI difficulty levels.
@@ -49,10 +56,12 @@ const (
 	EvalNNUE
 )
 
-// AssistResult holds the analysis result for Easy mode hints.
+// AssistResult holds the analysis behind a hint.
 type AssistResult struct {
-	Evaluation int        // Centipawn score
-	BestMove   board.Move // Suggested move
+	Evaluation int                   // Centipawn score of the best move, from White's view
+	BestMove   board.Move            // Suggested move
+	Lines      []engine.SearchResult // Top moves, best first (scores for the side to move)
+	Chances    *engine.Chances       // Playout results for the side to move (nil when off)
 }
 
 // Game implements ebiten.Game interface.
@@ -61,7 +70,20 @@ type Game struct {
 	position       *board.Position
 	moveHistory    []board.Move
 	sanHistory     []string
-	positionHashes []uint64 // History of position hashes for repetition detection
+	sanCache       board.SANCache  // SAN of the moves of the current position
+	moveTimes      []time.Duration // Time spent on each move, parallel to sanHistory
+	commentary     []CoachComment  // Coach notes on the moves played while the coach was on
+	startFEN       string          // Start position of a shared game ("" = standard)
+	positionHashes []uint64        // History of position hashes for repetition detection
+
+	// Saved game and its analysis; a saved game is auto-saved after each move
+	gamePath   string           // PGN file in the games directory ("" = not saved)
+	evals      map[int]int      // Engine evaluation after move i, from White's view
+	variations map[int][]string // Hint move the player did not play instead of move i, SAN
+
+	// Clock: time used by each side, counted from when its turn started
+	clocks    [2]time.Duration
+	turnStart time.Time
 
 	// UI state
 	selectedSquare board.Square
@@ -71,6 +93,23 @@ type Game struct {
 	dragSquare     board.Square
 	lastMove       board.Move
 
+	// History browsing (see Browsing): the position shown after the first
+	// viewPly moves, or nil at the live position
+	viewPos *board.Position
+	viewPly int
+
+	// Opening named after the latest known position (see updateOpening)
+	opening eco.Opening
+
+	// Premove: a move queued while the engine thinks (NoSquare = none)
+	premoveFrom  board.Square
+	premoveTo    board.Square
+	premovePiece board.Piece
+
+	// Board annotations drawn with the right mouse button, by position hash
+	shapes     map[uint64][]Shape
+	shapeFrom  board.Square // Start of the shape being drawn (NoSquare = none)
+	shapeColor ShapeColor
 	// Game settings
 	mode        GameMode
 	difficulty  Difficulty
@@ -89,30 +128,86 @@ type Game struct {
 	feedback *FeedbackManager
 
 	// Modals
-	settingsModal *SettingsModal
-	welcomeScreen *WelcomeScreen
-	downloader    *Downloader
+	settingsModal   *SettingsModal
+	welcomeScreen   *WelcomeScreen
+	tour            *Tour
+	downloader      *Downloader
+	tablebaseModal  *TablebaseModal
+	rushModal       *RushModal
+	gameSearchModal *GameSearchModal
+	gamePicker      *GamePickerModal
+	matchModal      *MatchModal
+	engineOptions   *EngineOptionsModal
 
 	// Visual effects
 	glass *GlassEffect
 
 	// AI Engine
-	engine     *engine.Engine
-	aiThinking bool
-	aiMove     chan board.Move
-
-	// Easy mode assistance
+	engine       *engine.Engine
+	tablebase    tablebase.Prober // The engine's local tablebases (nil = none)
+	aiThinking   bool
+	aiMove       chan board.Move
+	aiResearches int      // Re-searches after an illegal engine move
+	perf         gamePerf // Engine statistics of this game for the performance log
+
+	// Engine process playing the computer's moves (see engineproc.go)
+	engineProc *engineProcess // nil = the built-in engine plays
+
+	// Keyboard play: typed move entry and the arrow-key square cursor
+	moveInput       string
+	moveInputActive bool
+	keyCursor       board.Square // NoSquare when hidden
+	speaker         Speaker      // Reads moves aloud when SpeakMoves is on
+
+	// Computer vs Computer: the engine plays both sides, one configuration each
+	matchConfigs [2]EngineConfig // By color
+	matchDelay   time.Duration   // Pause before each engine move
+	matchMoveAt  time.Time       // When to start the next engine move (zero = none)
+	matchGame    bool            // The game is an engine game whose result is not recorded yet
+	matchScore   matchScore
+	matchTBProbe *tbProbe   // Tablebase adjudication the next engine move waits for
+	liveSearch   liveSearch // Latest report of the running search
+	background   backgroundSearch
+	updates      updateCheck
+
+	// Puzzle rush (nil = normal game)
+	rush      *puzzle.Rush
+	rushAt    time.Time  // When to play rushReply or the next puzzle (zero = waiting for the player)
+	rushReply board.Move // Opponent reply to show at rushAt
+
+	// Hints: shown on every move in Easy mode, on request otherwise
 	assistResult  *AssistResult
 	assistRunning bool
 	assistCh      chan *AssistResult
 	showHints     bool // Toggle for hint visibility
+	hintRequested bool // The player asked for a hint on this move
+	hintsUsed     int  // Hints asked for in this game
+
+	// Evaluation heatmap (see heatmap.go)
+	showHeatmap bool
+	heatmap     heatmap
+
+	// Opening explorer tab (see explorer.go)
+	explorer explorerState
 
 	// Game state
-	gameOver   bool
-	gameResult string
+	gameOver     bool
+	gameResult   string
+	resultToken  string      // PGN result of a game resigned or agreed drawn ("" = from the board)
+	drawOffered  bool        // A draw offer stands...
+	drawOfferBy  board.Color // ...from this side, until the opponent moves
+	drawDeclined bool        // The engine declined a draw this game, so it avoids repetitions
 
 	// HiDPI scaling
 	scale float64
+
+	// Low-power idle mode (see updateFrameRate)
+	idle       bool      // Updating at idleTPS and skipping unchanged frames
+	redraw     bool      // The next frame is drawn even while idle
+	lastActive time.Time // Last input or animation
+	lastDraw   time.Time
+	layoutW    int // Window size of the last Layout, to redraw on resize
+	layoutH    int
 }
 
 // NewGame creates a new chess game.
@@ -120,6 +215,10 @@ func NewGame() *Game {
 	g := &Game{
 		position:       board.NewPosition(),
 		selectedSquare: board.NoSquare,
+		premoveFrom:    board.NoSquare,
+		premoveTo:      board.NoSquare,
+		shapeFrom:      board.NoSquare,
+		keyCursor:      board.NoSquare,
 		mode:           ModeHumanVsComputer,
 		difficulty:     DifficultyMedium,
 		evalMode:       EvalClassical,
@@ -127,11 +226,14 @@ func NewGame() *Game {
 		playerColor:    board.White, // Human plays White by default
 		renderer:       NewRenderer(BoardSize, SquareSize),
 		input:          NewInputHandler(),
-		engine:         engine.NewEngine(64), // 64MB hash table
+		engine:         engine.NewEngine(engineHashMB),
 		aiMove:         make(chan board.Move, 1),
 		assistCh:       make(chan *AssistResult, 1),
 		showHints:      true, // Enable hints by default in Easy mode
+		matchConfigs:   [2]EngineConfig{{EvalClassical, DifficultyMedium}, {EvalClassical, DifficultyHard}},
+		matchDelay:     defaultMatchDelay,
 	}
+	g.engine.OnInfo = g.liveSearch.set
 
 	// Initialize storage
 	var err error
@@ -140,22 +242,45 @@ func NewGame() *Game {
 		log.Printf("Warning: Failed to initialize storage: %v", err)
 	}
 
+	// Pull changes from other machines before preferences are applied
+	g.syncStorage()
+
 	// Load preferences
 	g.loadPreferences()
 
+	// Use the opening book if one has been placed in the data directory
+	g.loadBook()
+
+	// Use downloaded endgame tablebases
+	g.loadTablebases()
+
 	g.panel = NewPanel(g)
 	g.feedback = NewFeedbackManager()
 	g.glass = NewGlassEffect()
+	g.applyAppearance()
+	g.updateEngineProcess()
 
 	// Initialize modals
 	g.settingsModal = NewSettingsModal()
 	g.welcomeScreen = NewWelcomeScreen()
+	g.tour = NewTour(g.panel)
 	g.downloader = NewDownloader()
+	g.tablebaseModal = NewTablebaseModal()
+	g.rushModal = NewRushModal()
+	g.gameSearchModal = NewGameSearchModal()
+	g.gamePicker = NewGamePickerModal()
+	g.matchModal = NewMatchModal()
+	g.engineOptions = NewEngineOptionsModal()
 
 	g.position.UpdateCheckers()
 
 	// Initialize position hash history with starting position
 	g.positionHashes = []uint64{g.position.Hash}
+	g.turnStart = time.Now()
+
+	// Draw fills the whole screen, so a frame skipped while idle keeps the last
+	g.lastActive = time.Now()
+	ebiten.SetScreenClearedEveryFrame(false)
 
 	// Check for first launch
 	g.checkFirstLaunch()
@@ -163,6 +288,39 @@ func NewGame() *Game {
 	return g
 }
 
+// loadBook loads the Polyglot opening book from the data directory, if present.
+func (g *Game) loadBook() {
+	path, err := storage.GetBookPath()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	if err := g.engine.LoadBook(path); err != nil {
+		log.Printf("Warning: Failed to load opening book %s: %v", path, err)
+		return
+	}
+	log.Printf("Loaded opening book %s", path)
+}
+
+// loadTablebases points the engine at the downloaded Syzygy tables, as the
+// UCI SyzygyPath option does. Probes still go through the Lichess API (see
+// tablebase.SyzygyProber.Probe), so only nodes with depth left are probed.
+func (g *Game) loadTablebases() {
+	dir, err := storage.GetSyzygyDir()
+	if err != nil {
+		return
+	}
+	prober := tablebase.NewSyzygyProber(dir)
+	if !prober.HasLocalFiles() {
+		return
+	}
+	g.engine.SetTablebase(prober)
+	g.engine.SetSyzygyProbeDepth(tablebaseProbeDepth)
+	g.tablebase = prober
+}
+
 // loadPreferences loads user preferences from storage.
 func (g *Game) loadPreferences() {
 	if g.storage == nil {
@@ -211,6 +369,14 @@ func (g *Game) loadPreferences() {
 	}
 }
 
+// applyAppearance applies the board theme, piece set and sound settings of
+// the preferences.
+func (g *Game) applyAppearance() {
+	g.renderer.SetTheme(g.prefs.BoardTheme, g.prefs.PieceSet)
+	g.renderer.SetMoveAnimations(!g.prefs.InstantMoves)
+	g.feedback.SetSound(g.prefs.SoundEnabled, g.prefs.SoundPack)
+}
+
 // savePreferences saves current preferences to storage.
 func (g *Game) savePreferences() {
 	if g.storage == nil {
@@ -221,6 +387,9 @@ func (g *Game) savePreferences() {
 	g.prefs.Difficulty = storage.Difficulty(g.difficulty)
 	g.prefs.EvalMode = storage.EvalMode(g.evalMode)
 	g.prefs.GameMode = storage.GameMode(g.mode)
+	if g.mode == ModeComputerVsComputer {
+		g.prefs.GameMode = storage.ModeHumanVsComputer // Engine matches are not resumed
+	}
 
 	// Convert board.Color to storage.PlayerColor
 	if g.playerColor == board.Black {
@@ -234,7 +403,8 @@ func (g *Game) savePreferences() {
 	}
 }
 
-// checkFirstLaunch shows welcome screen on first launch.
+// checkFirstLaunch shows welcome screen on first launch, or the profile
+// picker when several profiles exist.
 func (g *Game) checkFirstLaunch() {
 	if g.storage == nil {
 		return
@@ -246,29 +416,87 @@ func (g *Game) checkFirstLaunch() {
 		return
 	}
 
-	if isFirst {
-		g.welcomeScreen.Show(func(name string, evalMode storage.EvalMode) {
-			g.username = name
-			g.prefs.Username = name
-			g.prefs.EvalMode = evalMode
+	profiles, err := g.storage.Profiles()
+	if err != nil {
+		log.Printf("Warning: Failed to load profiles: %v", err)
+		return
+	}
+
+	if isFirst || len(profiles) > 1 {
+		g.ShowProfiles()
+	}
+}
+
+// ShowProfiles opens the welcome screen as a profile picker.
+func (g *Game) ShowProfiles() {
+	if g.storage == nil {
+		return
+	}
+
+	profiles, err := g.storage.Profiles()
+	if err != nil {
+		log.Printf("Warning: Failed to load profiles: %v", err)
+		return
+	}
 
-			if err := g.storage.MarkFirstLaunchComplete(); err != nil {
-				log.Printf("Warning: Failed to mark first launch complete: %v", err)
+	g.welcomeScreen.Show(profiles, g.storage.ActiveProfileID(), func(profileID, name string, evalMode storage.EvalMode) {
+		// Show new players around once the welcome screen closes
+		if first, _ := g.storage.IsFirstLaunch(); first {
+			g.tour.Start()
+		}
+		if err := g.storage.MarkFirstLaunchComplete(); err != nil {
+			log.Printf("Warning: Failed to mark first launch complete: %v", err)
+		}
+
+		// Existing profile: just switch to it
+		if profileID != "" {
+			g.switchProfile(profileID)
+			return
+		}
+
+		// New profile (the first one is created on the first save)
+		if g.storage.ActiveProfileID() != "" {
+			p, err := g.storage.CreateProfile(name)
+			if err != nil {
+				log.Printf("Warning: Failed to create profile: %v", err)
+				return
 			}
+			g.switchProfile(p.ID)
+		}
 
-			// If NNUE selected, check if we need to download
-			if evalMode == storage.EvalNNUE {
-				smallExists, bigExists, err := CheckNNUENetworks()
-				if err != nil || !smallExists || !bigExists {
-					g.savePreferences()
-					g.showNNUEDownload()
-					return
-				}
+		g.username = name
+		g.prefs.Username = name
+		g.prefs.EvalMode = evalMode
+
+		// If NNUE selected, check if we need to download
+		if evalMode == storage.EvalNNUE {
+			smallExists, bigExists, err := CheckNNUENetworks()
+			if err != nil || !smallExists || !bigExists {
+				g.savePreferences()
+				g.showNNUEDownload()
+				return
 			}
+		}
+
+		g.setEvalMode(EvalMode(evalMode))
+		g.savePreferences()
+	})
+}
 
-			g.setEvalMode(EvalMode(evalMode))
-			g.savePreferences()
-		})
+// switchProfile activates another profile and applies its preferences.
+func (g *Game) switchProfile(id string) {
+	if err := g.storage.SwitchProfile(id); err != nil {
+		log.Printf("Warning: Failed to switch profile: %v", err)
+		return
+	}
+
+	g.loadPreferences()
+	g.applyAppearance()
+	g.updateEngineProcess()
+
+	// loadPreferences only loads networks that exist; make the engine follow the profile's mode
+	if g.evalMode == EvalClassical || g.engine.HasNNUE() {
+		g.setEvalMode(g.evalMode)
 	}
 }
 
@@ -277,12 +505,24 @@ func (g *Game) Update() error {
 	// Update input
 	g.input.Update()
 
+	// Leave or enter the low-power idle mode
+	g.updateFrameRate()
+
 	// Update feedback animations
 	g.feedback.Update()
 
 	// Update glass effect animation
 	g.glass.Update()
 
+	// Announce a new release once checked
+	g.pollUpdateCheck()
+
+	// Look up the position in the opening explorer while its tab is open
+	g.pollExplorer()
+
+	// Open files dropped onto the window
+	g.handleDroppedFiles()
+
 	// Handle welcome screen first (blocks other input)
 	if g.welcomeScreen.IsVisible() {
 		g.welcomeScreen.Update(g.input)
@@ -297,6 +537,48 @@ func (g *Game) Update() error {
 		return nil
 	}
 
+	// Handle tablebase downloads (blocks other input)
+	if g.tablebaseModal.IsVisible() {
+		g.tablebaseModal.Update(g.input)
+		g.updateCursor()
+		return nil
+	}
+
+	// Handle puzzle rush modal (blocks other input)
+	if g.rushModal.IsVisible() {
+		g.rushModal.Update(g.input)
+		g.updateCursor()
+		return nil
+	}
+
+	// Handle engine match modal (blocks other input)
+	if g.matchModal.IsVisible() {
+		g.matchModal.Update(g.input)
+		g.updateCursor()
+		return nil
+	}
+
+	// Handle engine options modal (blocks other input)
+	if g.engineOptions.IsVisible() {
+		g.engineOptions.Update(g.input)
+		g.updateCursor()
+		return nil
+	}
+
+	// Handle game search modal (blocks other input)
+	if g.gameSearchModal.IsVisible() {
+		g.gameSearchModal.Update(g.input)
+		g.updateCursor()
+		return nil
+	}
+
+	// Handle the game picker of a dropped file (blocks other input)
+	if g.gamePicker.IsVisible() {
+		g.gamePicker.Update(g.input)
+		g.updateCursor()
+		return nil
+	}
+
 	// Handle settings modal (blocks other input)
 	if g.settingsModal.IsVisible() {
 		g.settingsModal.Update(g.input)
@@ -304,15 +586,59 @@ func (g *Game) Update() error {
 		return nil
 	}
 
+	// Handle the onboarding tour (blocks other input)
+	if g.tour.IsVisible() {
+		g.tour.Update(g.input)
+		g.updateCursor()
+		return nil
+	}
+
+	// Advance board flip and move animations
+	g.renderer.UpdateFlip()
+	g.renderer.UpdateMoveAnimation()
+
+	// The move entry takes typed letters before the shortcuts
+	typing := g.handleKeyboardInput()
+
+	// F flips the board in any mode
+	if !typing && IsKeyJustPressed(ebiten.KeyF) {
+		g.FlipBoardAction()
+	}
+
+	// H shows the evaluation heatmap
+	if !typing && IsKeyJustPressed(ebiten.KeyH) {
+		g.ToggleHeatmap()
+	}
+
+	// Ctrl+S exports the game as PGN
+	if IsKeyJustPressed(ebiten.KeyS) && (IsKeyPressed(ebiten.KeyControl) || IsKeyPressed(ebiten.KeyMeta)) {
+		path, err := g.ExportPGN()
+		if err != nil {
+			log.Printf("Warning: Failed to export PGN: %v", err)
+		} else {
+			g.saveAnalysisSession()
+		}
+		g.feedback.OnPGNExported(path, err)
+	}
+
 	// Handle panel interactions
 	if g.panel.HandleInput(g.input) {
 		g.updateCursor()
 		return nil // Panel handled the input
 	}
 
+	// Run the puzzle rush clock
+	g.updateRush()
+
+	// Play the next engine move of a Computer vs Computer game
+	g.updateEngineMatch()
+
 	// Handle board interactions
 	g.handleBoardInput()
 
+	// Load a network fetched in the background (web build)
+	g.updatePlatform()
+
 	// Check for AI move
 	g.checkAIMove()
 
@@ -322,6 +648,9 @@ func (g *Game) Update() error {
 	// Start assist analysis if it's user's turn in Easy mode
 	g.startAssistAnalysis()
 
+	// Think on the player's time while the engine is otherwise idle
+	g.updateBackgroundSearch()
+
 	// Update cursor based on hover state
 	g.updateCursor()
 
@@ -335,8 +664,22 @@ func (g *Game) updateCursor() {
 	// Check all interactive elements
 	if g.welcomeScreen.IsVisible() {
 		anyHovered = g.welcomeScreen.AnyButtonHovered()
+	} else if g.tablebaseModal.IsVisible() {
+		anyHovered = g.tablebaseModal.AnyButtonHovered()
+	} else if g.rushModal.IsVisible() {
+		anyHovered = g.rushModal.AnyButtonHovered()
+	} else if g.gameSearchModal.IsVisible() {
+		anyHovered = g.gameSearchModal.AnyButtonHovered()
+	} else if g.gamePicker.IsVisible() {
+		anyHovered = g.gamePicker.AnyButtonHovered()
+	} else if g.matchModal.IsVisible() {
+		anyHovered = g.matchModal.AnyButtonHovered()
+	} else if g.engineOptions.IsVisible() {
+		anyHovered = g.engineOptions.AnyButtonHovered()
 	} else if g.settingsModal.IsVisible() {
 		anyHovered = g.settingsModal.AnyButtonHovered()
+	} else if g.tour.IsVisible() {
+		anyHovered = g.tour.AnyButtonHovered()
 	} else {
 		anyHovered = g.panel.AnyButtonHovered()
 	}
@@ -350,6 +693,11 @@ func (g *Game) updateCursor() {
 
 // Draw renders the game.
 func (g *Game) Draw(screen *ebiten.Image) {
+	// While idle the last frame stays on the screen
+	if g.skipDraw() {
+		return
+	}
+
 	// Set HiDPI scale factor for all rendering components
 	g.renderer.SetScale(g.scale)
 	g.panel.SetScale(g.scale)
@@ -360,6 +708,13 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	// Draw board
 	g.renderer.DrawBoard(screen)
 
+	// An earlier position of the game is shown read-only
+	if g.Browsing() {
+		g.drawBrowsedBoard(screen)
+		g.drawOverlays(screen)
+		return
+	}
+
 	// Draw highlights for check
 	if g.position.InCheck() {
 		g.renderer.DrawCheck(screen, g.position.KingSquare[g.position.SideToMove])
@@ -367,29 +722,70 @@ func (g *Game) Draw(screen *ebiten.Image) {
 
 	// Draw highlights (last move, selection, legal moves)
 	g.renderer.DrawHighlights(screen, g.selectedSquare, g.legalMoves, g.lastMove)
+	if g.hasPremove() {
+		g.renderer.DrawPremove(screen, g.premoveFrom, g.premoveTo)
+	}
+
+	// Draw the keyboard cursor
+	g.renderer.DrawKeyCursor(screen, g.keyCursor)
 
-	// Draw hint arrow (Easy mode only)
-	if g.difficulty == DifficultyEasy && g.showHints && g.assistResult != nil {
-		g.renderer.DrawHintArrow(screen, g.assistResult.BestMove.From(), g.assistResult.BestMove.To())
+	// Color the pieces' squares by their evaluation
+	g.renderer.DrawHeatmap(screen, g.heatmapPieces())
+
+	// Draw hint arrows
+	if g.hintVisible() {
+		g.renderer.DrawHintArrows(screen, g.assistResult.Moves())
 	}
 
 	// Draw pieces with shake animations
 	g.renderer.DrawPiecesWithAnimations(screen, g.position, g.dragging, g.dragSquare, g.feedback.Animations())
 
+	// Draw the player's arrows and circles above the pieces
+	g.renderer.DrawShapes(screen, g.currentShapes())
+
 	// Draw dragged piece
 	if g.dragging {
 		mx, my := g.input.MousePosition()
 		g.renderer.DrawDraggedPiece(screen, g.dragPiece, mx, my)
 	}
 
+	g.drawOverlays(screen)
+}
+
+// drawBrowsedBoard draws the position shown while browsing the history,
+// grayed out, with its last move and check but none of the live game's
+// selection, hints or annotations.
+func (g *Game) drawBrowsedBoard(screen *ebiten.Image) {
+	pos := g.shownPosition()
+	if pos.InCheck() {
+		g.renderer.DrawCheck(screen, pos.KingSquare[pos.SideToMove])
+	}
+	g.renderer.DrawHighlights(screen, board.NoSquare, nil, g.shownLastMove())
+	g.renderer.DrawPiecesWithAnimations(screen, pos, false, board.NoSquare, g.feedback.Animations())
+	g.renderer.DrawBrowseOverlay(screen)
+}
+
+// drawOverlays draws what covers the board: the flip fade, feedback, the
+// panel and the modals.
+func (g *Game) drawOverlays(screen *ebiten.Image) {
+	// Fade the board during a flip
+	g.renderer.DrawFlipOverlay(screen)
+
 	// Draw feedback overlays (animations, toasts)
 	g.feedback.Draw(screen, g.renderer, g.glass)
 
 	// Draw panel
 	g.panel.Draw(screen, g.renderer, g.glass)
+	g.panel.drawTour(screen, g.tour)
 
 	// Draw modals on top (with glass effect)
 	g.settingsModal.Draw(screen, g.glass)
+	g.tablebaseModal.Draw(screen, g.glass)
+	g.rushModal.Draw(screen, g.glass)
+	g.gameSearchModal.Draw(screen, g.glass)
+	g.gamePicker.Draw(screen, g.glass)
+	g.matchModal.Draw(screen, g.glass)
+	g.engineOptions.Draw(screen, g.glass)
 	g.downloader.Draw(screen, g.glass)
 	g.welcomeScreen.Draw(screen, g.glass)
 }
@@ -407,6 +803,12 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 	// Update global scale for widgets and modals
 	UIScale = g.scale
 
+	// A resized screen starts blank
+	if outsideWidth != g.layoutW || outsideHeight != g.layoutH {
+		g.layoutW, g.layoutH = outsideWidth, outsideHeight
+		g.redraw = true
+	}
+
 	if g.panel != nil && g.panel.Collapsed() {
 		return int(float64(BoardSize+CollapsedWidth) * g.scale), int(float64(ScreenHeight) * g.scale)
 	}
@@ -415,22 +817,49 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 
 // handleBoardInput processes mouse interactions with the board.
 func (g *Game) handleBoardInput() {
+	// The board is read-only while browsing the history
+	if g.Browsing() {
+		return
+	}
+
+	g.handleAnnotationInput()
+
 	if g.gameOver {
 		return
 	}
 
-	// Don't allow moves while AI is thinking
+	// The engines play both sides
+	if g.mode == ModeComputerVsComputer {
+		return
+	}
+
+	// While the AI is thinking, moves are queued as premoves
 	if g.aiThinking {
+		if g.mode == ModeHumanVsComputer {
+			g.handlePremoveInput()
+		}
+		return
+	}
+
+	// During a puzzle rush, wait for the reply or the next puzzle
+	if g.rush != nil && !g.rushAt.IsZero() {
 		return
 	}
 
 	// Only allow moves for human player in human vs computer mode
-	if g.mode == ModeHumanVsComputer && g.position.SideToMove != g.playerColor {
+	if g.rush == nil && g.mode == ModeHumanVsComputer && g.position.SideToMove != g.playerColor {
 		return
 	}
 
 	mx, my := g.input.MousePosition()
 
+	// Right-click cancels an active drag and clears the selection (anywhere on screen,
+	// so a drag carried off the board can still be cancelled)
+	if g.input.IsRightJustPressed() && (g.dragging || mx < BoardSize && my < BoardSize) {
+		g.clearSelection()
+		return
+	}
+
 	// Check if mouse is on the board
 	if mx >= BoardSize || my >= BoardSize {
 		return
@@ -456,7 +885,7 @@ func (g *Game) handleBoardInput() {
 		if g.selectedSquare != board.NoSquare && g.legalMoves != nil {
 			move := g.findMove(g.selectedSquare, sq)
 			if move != board.NoMove {
-				g.makeMove(move)
+				g.playerMove(move)
 				return
 			}
 		}
@@ -504,14 +933,18 @@ func (g *Game) handleDragRelease(mx, my int) {
 	if targetSq != board.NoSquare && g.legalMoves != nil {
 		move := g.findMove(g.dragSquare, targetSq)
 		if move != board.NoMove {
-			g.makeMove(move)
+			g.playerMove(move)
 			return
 		}
 
 		// Move was attempted but not valid - determine why and show feedback
 		if g.dragSquare != targetSq {
 			reason := g.determineInvalidMoveReason(g.dragSquare, targetSq)
-			g.feedback.OnInvalidMove(g.dragSquare, targetSq, reason)
+			detail := ""
+			if reason == ReasonPiecePinned {
+				detail = g.pinExplanation(g.dragSquare)
+			}
+			g.feedback.OnInvalidMove(g.dragSquare, targetSq, reason, detail)
 		}
 	}
 
@@ -537,6 +970,13 @@ func (g *Game) determineInvalidMoveReason(src, dst board.Square) InvalidMoveReas
 	for i := 0; i < pseudoMoves.Len(); i++ {
 		m := pseudoMoves.Get(i)
 		if m.From() == src && m.To() == dst {
+			// Leaving the pin line exposes the king to the pinning piece
+			if pinner := g.position.PinnerOf(src); pinner != board.NoSquare {
+				ksq := g.position.KingSquare[g.position.SideToMove]
+				if !board.Line(ksq, pinner).IsSet(dst) {
+					return ReasonPiecePinned
+				}
+			}
 			// Move was generated but filtered as illegal - leaves king in check
 			return ReasonWouldLeaveKingInCheck
 		}
@@ -546,26 +986,43 @@ func (g *Game) determineInvalidMoveReason(src, dst board.Square) InvalidMoveReas
 	return ReasonInvalidPieceMovement
 }
 
+// pinExplanation describes the pin on the piece at sq, e.g.
+// "Your bishop is pinned to the king by the rook on a4".
+func (g *Game) pinExplanation(sq board.Square) string {
+	pinner := g.position.PinnerOf(sq)
+	if pinner == board.NoSquare {
+		return ""
+	}
+	piece := g.position.PieceAt(sq)
+	attacker := g.position.PieceAt(pinner)
+	return fmt.Sprintf("Your %s is pinned to the king by the %s on %s",
+		strings.ToLower(piece.Type().String()), strings.ToLower(attacker.Type().String()), pinner)
+}
+
+// debugLog enables the GUI's verbose logging of selections and moves. It is
+// off unless CHESSPLAY_DEBUG is set, as it logs on every click.
+var debugLog = os.Getenv("CHESSPLAY_DEBUG") != ""
+
+// debugf logs a message when debug logging is enabled.
+func debugf(format string, args ...any) {
+	if debugLog {
+		log.Printf(format, args...)
+	}
+}
+
 // getLegalMovesFrom returns all legal moves from the given square.
 func (g *Game) getLegalMovesFrom(sq board.Square) *board.MoveList {
-	fmt.Printf("DEBUG: Getting legal moves from square %v\n", sq)
-	fmt.Printf("DEBUG: Piece at square: %v\n", g.position.PieceAt(sq))
-	fmt.Printf("DEBUG: Board state:\n%s\n", g.position.String())
-
 	allMoves := g.position.GenerateLegalMoves()
-	fmt.Printf("DEBUG: Total legal moves for position: %d\n", allMoves.Len())
-
 	filtered := board.NewMoveList()
 
 	for i := 0; i < allMoves.Len(); i++ {
 		move := allMoves.Get(i)
 		if move.From() == sq {
 			filtered.Add(move)
-			fmt.Printf("DEBUG: Found move: %v\n", move)
 		}
 	}
 
-	fmt.Printf("DEBUG: Filtered moves from %v: %d\n", sq, filtered.Len())
+	debugf("[Select] %v on %v: %d of %d legal moves", g.position.PieceAt(sq), sq, filtered.Len(), allMoves.Len())
 	return filtered
 }
 
@@ -594,14 +1051,8 @@ func (g *Game) findMove(src, dst board.Square) board.Move {
 		// Handle castling: allow dragging King to Rook square
 		// Users naturally castle by moving King to Rook, but internal moves use King's destination
 		if move.IsCastling() && move.From() == src {
-			// Kingside: E1→H1 (White) or E8→H8 (Black) should match E1→G1 / E8→G8
-			if (src == board.E1 && dst == board.H1 && move.To() == board.G1) ||
-				(src == board.E8 && dst == board.H8 && move.To() == board.G8) {
-				return move
-			}
-			// Queenside: E1→A1 (White) or E8→A8 (Black) should match E1→C1 / E8→C8
-			if (src == board.E1 && dst == board.A1 && move.To() == board.C1) ||
-				(src == board.E8 && dst == board.A8 && move.To() == board.C8) {
+			// E1→H1 should match E1→G1, E1→A1 should match E1→C1
+			if dst == g.position.CastlingRook(g.position.SideToMove, move.IsKingSideCastling()) {
 				return move
 			}
 		}
@@ -610,12 +1061,23 @@ func (g *Game) findMove(src, dst board.Square) board.Move {
 	return board.NoMove
 }
 
+// playerMove plays a move made on the board: in the game, or as an answer
+// to a rush puzzle.
+func (g *Game) playerMove(m board.Move) {
+	if g.rush != nil {
+		g.playRushMove(m)
+		return
+	}
+	g.makeMove(m)
+}
+
 // makeMove applies a move to the game.
 func (g *Game) makeMove(m board.Move) {
-	// Debug logging - before move
-	log.Printf("[MOVE] Before: SideToMove=%v, Move=%v (from=%v to=%v)",
-		g.position.SideToMove, m, m.From(), m.To())
-	log.Printf("[MOVE] Piece at from=%v: %v", m.From(), g.position.PieceAt(m.From()))
+	debugf("[MOVE] Before: SideToMove=%v, Move=%v, Piece=%v",
+		g.position.SideToMove, m, g.position.PieceAt(m.From()))
+
+	// A move still sliding in lands at once
+	g.renderer.StopMoveAnimation()
 
 	// Determine move properties before making the move
 	isCapture := m.IsCapture(g.position)
@@ -625,13 +1087,30 @@ func (g *Game) makeMove(m board.Move) {
 	san := g.moveToSAN(m)
 	g.sanHistory = append(g.sanHistory, san)
 
+	// Coach commentary needs the position before the move
+	if g.prefs.Coach {
+		g.addCommentary(m, san)
+	}
+	g.recordVariation(len(g.moveHistory), m)
+
+	// Charge the elapsed turn time to the side that moved
+	spent := time.Since(g.turnStart)
+	g.moveTimes = append(g.moveTimes, spent)
+	g.clocks[g.position.SideToMove] += spent
+	g.turnStart = time.Now()
+
+	// Moving instead of accepting declines a draw offer
+	if g.drawOffered && g.drawOfferBy != g.position.SideToMove {
+		g.drawOffered = false
+	}
+
 	// Make the move
 	g.position.MakeMove(m)
 
-	// Debug logging - after move
-	log.Printf("[MOVE] After: SideToMove=%v", g.position.SideToMove)
+	debugf("[MOVE] After: SideToMove=%v", g.position.SideToMove)
 	g.moveHistory = append(g.moveHistory, m)
 	g.lastMove = m
+	g.updateOpening()
 
 	// Record position hash for repetition detection
 	g.positionHashes = append(g.positionHashes, g.position.Hash)
@@ -650,16 +1129,30 @@ func (g *Game) makeMove(m board.Move) {
 
 	// Check for game end
 	g.checkGameEnd()
+	g.autoSaveGame()
+	g.announceMove(san)
+
+	// Hot-seat play: turn the board towards the side to move
+	if !g.gameOver && g.mode == ModeHumanVsHuman && g.prefs.AutoFlip {
+		g.renderer.AnimateFlip(g.position.SideToMove == board.Black)
+	}
 
 	// Start AI thinking if it's computer's turn
 	if !g.gameOver && g.mode == ModeHumanVsComputer && g.position.SideToMove != g.playerColor {
 		g.startAIThinking()
 	}
+
+	// In an engine match, the other side moves after the move delay
+	if !g.gameOver && g.mode == ModeComputerVsComputer {
+		g.matchMoveAt = time.Now().Add(g.matchDelay)
+	}
 }
 
-// moveToSAN converts a move to SAN notation.
+// moveToSAN converts a move in the current position to SAN notation. The
+// move entry and hints ask for the same moves on every frame, so the SAN is
+// kept until the position changes.
 func (g *Game) moveToSAN(m board.Move) string {
-	return m.ToSAN(g.position)
+	return g.sanCache.ToSAN(g.position, m)
 }
 
 // checkGameEnd checks if the game is over.
@@ -677,58 +1170,46 @@ func (g *Game) checkGameEnd() {
 		g.gameOver = true
 		g.gameResult = "Draw by stalemate"
 		g.feedback.OnStalemate()
-	} else if g.isThreefoldRepetition() {
+	} else if g.repetitions() >= autoRepetitions {
 		g.gameOver = true
-		g.gameResult = "Draw by threefold repetition"
-		g.feedback.OnDraw("threefold repetition")
-	} else if g.position.HalfMoveClock >= 100 {
+		g.gameResult = "Draw by fivefold repetition"
+		g.feedback.OnDraw("fivefold repetition")
+	} else if g.position.HalfMoveClock >= autoHalfMoves {
 		g.gameOver = true
-		g.gameResult = "Draw by 50-move rule"
-		g.feedback.OnDraw("50-move rule")
+		g.gameResult = "Draw by 75-move rule"
+		g.feedback.OnDraw("75-move rule")
+	} else if reason := g.drawClaim(); reason != "" && (g.mode == ModeComputerVsComputer || g.engineClaimsDraw()) {
+		// Players claim draws from the panel; engines claim them here
+		g.gameOver = true
+		g.gameResult = "Draw by " + reason
+		g.feedback.OnDraw(reason)
 	} else if g.position.InCheck() {
 		// Show check notification (not game over)
 		g.feedback.OnCheck()
 	}
-}
-
-// isThreefoldRepetition checks if the current position has occurred 3 times.
-func (g *Game) isThreefoldRepetition() bool {
-	if len(g.positionHashes) < 5 {
-		// Need at least 5 positions (4 half-moves) for threefold repetition
-		return false
-	}
-
-	currentHash := g.position.Hash
-	count := 0
-	matchIndices := []int{}
 
-	for i, h := range g.positionHashes {
-		if h == currentHash {
-			count++
-			matchIndices = append(matchIndices, i)
-			if count >= 3 {
-				// Debug logging to diagnose false positives
-				log.Printf("[THREEFOLD] Detected! Hash=%x Count=%d MatchIndices=%v TotalPositions=%d",
-					currentHash, count, matchIndices, len(g.positionHashes))
-				log.Printf("[THREEFOLD] Current FEN: %s", g.position.ToFEN())
-				return true
-			}
-		}
+	if g.gameOver {
+		g.flushPerfLog()
+		g.recordEngineMatch()
 	}
-	return false
 }
 
 // startAIThinking starts the AI search in a goroutine.
 func (g *Game) startAIThinking() {
 	// Assertion: AI should only think when it's computer's turn
-	if g.position.SideToMove == g.playerColor {
+	if g.mode != ModeComputerVsComputer && g.position.SideToMove == g.playerColor {
 		log.Printf("ERROR: startAIThinking called but SideToMove is %v (player's turn)!",
 			g.position.SideToMove)
 		return
 	}
+	if g.mode == ModeComputerVsComputer {
+		g.applyMatchConfig(g.position.SideToMove)
+	}
 
 	log.Printf("[AI] Starting AI search - SideToMove=%v", g.position.SideToMove)
+	g.stopBackgroundSearch()
 	g.aiThinking = true
+	g.liveSearch.start(g.position)
 
 	// Copy position for the search
 	pos := g.position.Copy()
@@ -736,6 +1217,15 @@ func (g *Game) startAIThinking() {
 	// Pass position history for repetition detection
 	g.engine.SetPositionHistory(g.positionHashes)
 
+	// Having declined a draw, the engine does not hand the player one
+	if g.drawDeclined {
+		g.engine.AvoidMoves(nil, g.repeatingMoves(), repetitionPenalty)
+	}
+
+	if g.usingEngineProcess() {
+		g.startEngineProcessSearch(pos)
+		return
+	}
 	go func() {
 		move := g.engine.Search(pos)
 		g.aiMove <- move // Always send, even if NoMove (game over)
@@ -753,13 +1243,35 @@ func (g *Game) checkAIMove() {
 		log.Printf("[AI] Received move from engine: %v (from=%v to=%v)", move, move.From(), move.To())
 		log.Printf("[AI] Current position SideToMove: %v", g.position.SideToMove)
 		g.aiThinking = false
-		if move == board.NoMove {
+		g.engine.AvoidMoves(nil, nil, 0)
+		info := g.lastAISearchInfo()
+		g.perf.add(info)
+		if g.engineProc != nil {
+			g.engineProc.cancel = nil
+			select {
+			case <-g.engineProc.client.Exited():
+				g.engineProcessFailed()
+			default:
+			}
+		}
+		if move == board.NoMove && g.position.GenerateLegalMoves().Len() == 0 {
 			// AI has no valid move - game should be over (checkmate/stalemate)
 			log.Printf("[AI] No valid move - checking game end")
 			g.checkGameEnd()
 			return
 		}
+		if !g.isLegalAIMove(move) {
+			g.rejectAIMove(move)
+			return
+		}
+		g.aiResearches = 0
+		g.recordEval(len(g.moveHistory), info)
+		before := g.position.Copy()
 		g.makeMove(move)
+		if !g.Browsing() {
+			g.renderer.AnimateMove(before, move, false)
+		}
+		g.playPremove()
 	default:
 		// Still thinking
 	}
@@ -767,16 +1279,144 @@ func (g *Game) checkAIMove() {
 
 // NewGameAction resets the game to starting position.
 func (g *Game) NewGameAction() {
-	g.position = board.NewPosition()
+	if g.mode == ModeComputerVsComputer {
+		g.nextEngineMatchGame()
+		return
+	}
+
+	g.startFEN = ""
+	g.resetGame(board.NewPosition())
+
+	// If player chose Black, AI (White) moves first
+	if g.mode == ModeHumanVsComputer && g.playerColor == board.Black {
+		g.startAIThinking()
+	}
+}
+
+// OpenLink starts a new game at a shared position: the link's start
+// position with its moves already played.
+func (g *Game) OpenLink(l *share.Link) error {
+	start, err := board.ParseFEN(l.FEN)
+	if err != nil {
+		return err
+	}
+	g.startFEN = ""
+	if l.FEN != board.StartFEN {
+		g.startFEN = l.FEN
+	}
+	g.resetGame(start)
+
+	for _, m := range l.Moves {
+		g.sanHistory = append(g.sanHistory, g.moveToSAN(m))
+		g.moveTimes = append(g.moveTimes, 0)
+		g.position.MakeMove(m)
+		g.moveHistory = append(g.moveHistory, m)
+		g.positionHashes = append(g.positionHashes, g.position.Hash)
+		g.lastMove = m
+		g.updateOpening()
+	}
+	g.position.UpdateCheckers()
+	g.checkGameEnd()
+
+	if g.mode == ModeHumanVsHuman && g.prefs.AutoFlip {
+		g.renderer.SetFlipped(g.position.SideToMove == board.Black)
+	}
+	if !g.gameOver && (g.mode == ModeComputerVsComputer || g.mode == ModeHumanVsComputer && g.position.SideToMove != g.playerColor) {
+		g.startAIThinking()
+	}
+	return nil
+}
+
+// ShareAction copies a link to the current game: a chessplay:// link, or a
+// Lichess analysis URL when lichess is set.
+func (g *Game) ShareAction(lichess bool) {
+	l := share.New(g.startFEN, g.moveHistory)
+	what, link := "Link", l.URI()
+	if lichess {
+		var err error
+		if link, err = l.LichessURL(); err != nil {
+			log.Printf("Warning: Failed to create Lichess link: %v", err)
+			g.feedback.OnLinkShared("", err)
+			return
+		}
+		what = "Lichess link"
+	}
+
+	log.Printf("Share link: %s", link)
+	err := copyToClipboard(link)
+	if err != nil {
+		log.Printf("Warning: Failed to copy link: %v", err)
+	}
+	g.feedback.OnLinkShared(what, err)
+}
+
+// startPly returns the game ply of the start position (0 for the standard one).
+func (g *Game) startPly() int {
+	if g.startFEN == "" {
+		return 0
+	}
+	pos, err := board.ParseFEN(g.startFEN)
+	if err != nil {
+		return 0
+	}
+	ply := 2 * (pos.FullMoveNumber - 1)
+	if pos.SideToMove == board.Black {
+		ply++
+	}
+	return ply
+}
+
+// resetGame clears the game state and starts from the given position.
+func (g *Game) resetGame(start *board.Position) {
+	// Log the abandoned game (finished games were logged when they ended)
+	g.flushPerfLog()
+
+	// Pick up networks added since the last game and restart from the book,
+	// unless the engine is busy
+	g.stopBackgroundSearch()
+	g.background.hash = 0
+	g.saveAnalysisSession()
+	g.stopEngineProcessSearch()
+	if !g.aiThinking && !g.assistRunning {
+		if g.evalMode == EvalNNUE {
+			g.loadNNUENetworks()
+		}
+		g.engine.Clear()
+	}
+
+	g.position = start
+	g.stopBrowsing()
 	g.moveHistory = nil
 	g.sanHistory = nil
+	g.moveTimes = nil
+	g.commentary = nil
+	g.gamePath = ""
+	g.evals = nil
+	g.variations = nil
+	g.opening = eco.Opening{}
+	g.clocks = [2]time.Duration{}
+	g.turnStart = time.Now()
 	g.positionHashes = []uint64{g.position.Hash} // Reset with starting position
 	g.lastMove = board.NoMove
 	g.clearSelection()
+	g.clearPremove()
+	g.clearShapes()
+	g.closeMoveInput()
 	g.clearAssist()
 	g.gameOver = false
 	g.gameResult = ""
+	g.resultToken = ""
+	g.drawOffered = false
+	g.drawDeclined = false
+	g.hintsUsed = 0
 	g.aiThinking = false
+	g.aiResearches = 0
+	g.rush = nil
+	g.rushAt = time.Time{}
+	g.rushReply = board.NoMove
+	g.matchGame = false
+	g.matchMoveAt = time.Time{}
+	g.matchTBProbe = nil
 	g.position.UpdateCheckers()
 
 	// Clear AI channel
@@ -785,21 +1425,36 @@ func (g *Game) NewGameAction() {
 	default:
 	}
 
-	// If player chose Black, AI (White) moves first
-	if g.mode == ModeHumanVsComputer && g.playerColor == board.Black {
-		g.startAIThinking()
+	// Auto-flip restarts from White's side
+	if g.mode == ModeHumanVsHuman && g.prefs.AutoFlip {
+		g.renderer.SetFlipped(false)
 	}
 }
 
-// ToggleModeAction toggles between Human vs Human and Human vs Computer.
-func (g *Game) ToggleModeAction() {
-	if g.mode == ModeHumanVsHuman {
-		g.mode = ModeHumanVsComputer
-	} else {
-		g.mode = ModeHumanVsHuman
+// SetModeAction switches between Human vs Human and Human vs Computer.
+// Computer vs Computer is started from the engine match setup.
+func (g *Game) SetModeAction(mode GameMode) {
+	if mode == g.mode || mode == ModeComputerVsComputer {
+		return
+	}
+	if g.mode == ModeComputerVsComputer {
+		// The game goes on with the player, so it is no longer an engine game
+		g.matchGame = false
+		g.matchMoveAt = time.Time{}
+		g.restoreEngineConfig()
+	}
+	g.mode = mode
+	if mode == ModeHumanVsComputer {
+		// Restore the player's perspective after hot-seat auto-flipping
+		g.renderer.SetFlipped(g.playerColor == board.Black)
 	}
 }
 
+// FlipBoardAction flips the board orientation, regardless of game mode.
+func (g *Game) FlipBoardAction() {
+	g.renderer.AnimateFlip(!g.renderer.IsFlipped())
+}
+
 // SetPlayerColor sets which color the human player controls.
 // When set to Black, the board will be flipped and AI will move first.
 func (g *Game) SetPlayerColor(color board.Color) {
@@ -842,6 +1497,20 @@ func (g *Game) SANHistory() []string {
 	return g.sanHistory
 }
 
+// MoveTimes returns the time spent on each move, parallel to SANHistory.
+func (g *Game) MoveTimes() []time.Duration {
+	return g.moveTimes
+}
+
+// Clock returns the total time used by a side, including its current turn.
+func (g *Game) Clock(c board.Color) time.Duration {
+	used := g.clocks[c]
+	if !g.gameOver && g.position.SideToMove == c {
+		used += time.Since(g.turnStart)
+	}
+	return used
+}
+
 // GameMode returns the current game mode.
 func (g *Game) GameMode() GameMode {
 	return g.mode
@@ -872,6 +1541,26 @@ func (g *Game) Username() string {
 	return g.username
 }
 
+// HasBook returns true if the engine has an opening book.
+func (g *Game) HasBook() bool {
+	return g.engine.HasBook()
+}
+
+// InBook returns true while the engine is still playing from its opening book.
+func (g *Game) InBook() bool {
+	return g.engine.InBook()
+}
+
+// CoachEnabled returns true if coach commentary is shown.
+func (g *Game) CoachEnabled() bool {
+	return g.prefs.Coach
+}
+
+// Commentary returns the coach's notes on the moves played so far.
+func (g *Game) Commentary() []CoachComment {
+	return g.commentary
+}
+
 // EvalMode returns the current evaluation mode.
 func (g *Game) EvalMode() EvalMode {
 	return g.evalMode
@@ -888,6 +1577,31 @@ func (g *Game) ShowSettings() {
 		g.prefs.Difficulty = prefs.Difficulty
 		g.prefs.EvalMode = prefs.EvalMode
 		g.prefs.PlayerColor = prefs.PlayerColor
+		g.prefs.AutoFlip = prefs.AutoFlip
+		g.prefs.Coach = prefs.Coach
+		g.prefs.NNUENetwork = prefs.NNUENetwork
+		g.prefs.BoardTheme = prefs.BoardTheme
+		g.prefs.PieceSet = prefs.PieceSet
+		g.prefs.SoundPack = prefs.SoundPack
+		g.prefs.SpeakMoves = prefs.SpeakMoves
+		g.prefs.HintLimit = prefs.HintLimit
+		g.prefs.Chances = prefs.Chances
+		g.prefs.Ponder = prefs.Ponder
+		g.prefs.CheckUpdates = prefs.CheckUpdates
+		g.prefs.InstantMoves = prefs.InstantMoves
+		g.prefs.Explorer = prefs.Explorer
+		g.prefs.EngineProcess = prefs.EngineProcess
+		g.prefs.EnginePath = prefs.EnginePath
+		if prefs.SparringEngine != g.prefs.SparringEngine {
+			// Options set for one engine are not another's
+			g.prefs.SparringEngine = prefs.SparringEngine
+			g.prefs.SparringOptions = nil
+		}
+		if g.prefs.SparringEngine == "" {
+			g.prefs.Sparring = false
+		}
+		g.applyAppearance()
+		g.updateEngineProcess()
 
 		// Apply player color (convert from storage.PlayerColor to board.Color)
 		if prefs.PlayerColor == storage.ColorBlack {
@@ -913,7 +1627,24 @@ func (g *Game) ShowSettings() {
 		// Update eval mode (either Classical, or NNUE with files ready)
 		g.setEvalMode(EvalMode(prefs.EvalMode))
 		g.savePreferences()
-	}, nil)
+	}, nil, g.ShowProfiles, g.ShowTablebases, g.ShowEngineOptions)
+}
+
+// ShowTablebases opens the endgame tablebase downloads.
+func (g *Game) ShowTablebases() {
+	dir, err := storage.GetSyzygyDir()
+	if err != nil {
+		log.Printf("Warning: Failed to get tablebase directory: %v", err)
+		return
+	}
+	g.tablebaseModal.Show(dir, g.prefs.TBMirror, func(mirror string) {
+		if mirror == tablebase.Mirrors[0] {
+			mirror = ""
+		}
+		g.prefs.TBMirror = mirror
+		g.savePreferences()
+		g.loadTablebases()
+	})
 }
 
 // showNNUEDownload shows the NNUE download dialog.
@@ -931,32 +1662,11 @@ func (g *Game) showNNUEDownload() {
 	})
 }
 
-// loadNNUENetworks loads NNUE network files into the engine.
-func (g *Game) loadNNUENetworks() {
-	smallPath, bigPath, err := GetNNUEPaths()
-	if err != nil {
-		log.Printf("Warning: Failed to get NNUE paths: %v", err)
-		return
-	}
-
-	if err := g.engine.LoadNNUE(bigPath, smallPath); err != nil {
-		log.Printf("Warning: Failed to load NNUE networks: %v", err)
-		return
-	}
-
-	g.engine.SetUseNNUE(true)
-	log.Printf("NNUE networks loaded successfully")
-}
-
 // setEvalMode sets the evaluation mode and updates the engine.
 func (g *Game) setEvalMode(mode EvalMode) {
 	g.evalMode = mode
 	if mode == EvalNNUE {
-		if g.engine.HasNNUE() {
-			g.engine.SetUseNNUE(true)
-		} else {
-			g.loadNNUENetworks()
-		}
+		g.loadNNUENetworks()
 	} else {
 		g.engine.SetUseNNUE(false)
 	}
@@ -964,24 +1674,60 @@ func (g *Game) setEvalMode(mode EvalMode) {
 
 // Close cleans up game resources.
 func (g *Game) Close() {
+	g.closeEngineProcess()
+	g.flushPerfLog()
+	g.saveAnalysisSession()
+	g.installUpdate()
 	if g.storage != nil {
+		g.syncStorage()
 		g.storage.Close()
 	}
 }
 
+// syncStorage exchanges profiles, preferences, stats and saved games with the
+// sync backend. Sync is opt-in: it only runs when sync.json is configured.
+func (g *Game) syncStorage() {
+	if g.storage == nil {
+		return
+	}
+
+	cfg, err := storage.LoadSyncConfig()
+	if err != nil {
+		log.Printf("Warning: Failed to read sync config: %v", err)
+		return
+	}
+	if cfg == nil {
+		return
+	}
+
+	backend, err := cfg.NewBackend()
+	if err != nil {
+		log.Printf("Warning: Invalid sync config: %v", err)
+		return
+	}
+
+	result, err := g.storage.Sync(backend)
+	if err != nil {
+		log.Printf("Warning: Sync failed: %v", err)
+		return
+	}
+	log.Printf("[Sync] Pulled %d and pushed %d records", result.Pulled, result.Pushed)
+}
+
 // startAssistAnalysis starts background analysis for Easy mode hints.
-// Only runs when it's the user's turn and difficulty is Easy.
+// Only runs when it's the user's turn and difficulty is Easy, or when they
+// asked for a hint.
 func (g *Game) startAssistAnalysis() {
-	// Only in Easy mode
-	if g.difficulty != DifficultyEasy {
+	// Only in Easy mode, unless asked for
+	if g.difficulty != DifficultyEasy && !g.hintRequested {
 		return
 	}
 	// Only when it's human's turn in HvC mode
-	if g.mode == ModeHumanVsComputer && g.position.SideToMove != g.playerColor {
+	if g.mode == ModeHumanVsComputer && g.position.SideToMove != g.playerColor || g.mode == ModeComputerVsComputer {
 		return
 	}
-	// Don't run if game is over or AI is thinking
-	if g.gameOver || g.aiThinking {
+	// Don't run if game is over or AI is thinking, or give away rush puzzles
+	if g.gameOver || g.aiThinking || g.rush != nil {
 		return
 	}
 	// Don't run if already have a result (wait until move is made)
@@ -993,23 +1739,14 @@ func (g *Game) startAssistAnalysis() {
 		return
 	}
 
-	log.Printf("[Assist] Starting analysis for Easy mode hint")
+	log.Printf("[Assist] Starting hint analysis")
+	g.stopBackgroundSearch()
 	g.assistRunning = true
 
+	pos := g.position.Copy()
+	chances := g.prefs.Chances
 	go func() {
-		// Quick search to find best move
-		limits := engine.SearchLimits{
-			Depth:    5,
-			MoveTime: 500 * time.Millisecond,
-		}
-		pos := g.position.Copy()
-		bestMove := g.engine.SearchWithLimits(pos, limits)
-		eval := g.engine.Evaluate(pos)
-
-		g.assistCh <- &AssistResult{
-			Evaluation: eval,
-			BestMove:   bestMove,
-		}
+		g.assistCh <- g.analyzeHint(pos, chances)
 	}()
 }
 
@@ -1033,6 +1770,7 @@ func (g *Game) checkAssistResult() {
 func (g *Game) clearAssist() {
 	g.assistResult = nil
 	g.assistRunning = false
+	g.hintRequested = false
 	// Drain channel if anything pending
 	select {
 	case <-g.assistCh:
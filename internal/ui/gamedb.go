@@ -0,0 +1,254 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/share"
+	"github.com/hailam/chessplay/internal/storage"
+)
+
+// analysisSessionNodes is the most positions of an analysis saved with a
+// game.
+const analysisSessionNodes = 5000
+
+// errNoGameRecord is returned when opening a PGN file saved without a record.
+var errNoGameRecord = errors.New("no game record (only games saved by chessplay can be opened)")
+
+// recordEval stores the engine's evaluation of its move i, from White's view.
+// Must be called before the move is made on g.position.
+func (g *Game) recordEval(i int, info engine.SearchInfo) {
+	if info.Depth == 0 {
+		return // Book or tablebase move
+	}
+	score := info.Score
+	if g.position.SideToMove == board.Black {
+		score = -score
+	}
+	if g.evals == nil {
+		g.evals = make(map[int]int)
+	}
+	g.evals[i] = score
+}
+
+// recordVariation stores the hint move as a variation when the player
+// chose another move. Must be called before the move is made on g.position.
+func (g *Game) recordVariation(i int, m board.Move) {
+	if g.assistResult == nil || g.assistResult.BestMove == board.NoMove || g.assistResult.BestMove == m {
+		return
+	}
+	if g.variations == nil {
+		g.variations = make(map[int][]string)
+	}
+	g.variations[i] = []string{g.moveToSAN(g.assistResult.BestMove)}
+}
+
+// moveComments returns the coach notes on move i.
+func (g *Game) moveComments(i int) []string {
+	ply := g.startPly() + i
+	for _, c := range g.commentary {
+		if c.Ply == ply {
+			return c.Notes
+		}
+	}
+	return nil
+}
+
+// gameRecord returns the database record of the game and its annotations.
+func (g *Game) gameRecord() *storage.GameRecord {
+	r := &storage.GameRecord{FEN: g.startFEN, Moves: make([]string, len(g.moveHistory))}
+	if o, ok := g.Opening(); ok {
+		r.ECO, r.Opening = o.ECO, o.Name
+	}
+	for i, m := range g.moveHistory {
+		r.Moves[i] = m.String()
+		if notes := g.moveComments(i); len(notes) > 0 {
+			r.Annotation(i).Comments = notes
+		}
+		if eval, ok := g.evals[i]; ok {
+			r.Annotation(i).Eval = &eval
+		}
+		if v, ok := g.variations[i]; ok {
+			r.Annotation(i).Variations = [][]string{v}
+		}
+	}
+	return r
+}
+
+// autoSaveGame rewrites a saved game after each move, so its annotations
+// are kept without saving again.
+func (g *Game) autoSaveGame() {
+	if g.gamePath == "" {
+		return
+	}
+	if _, err := g.ExportPGN(); err != nil {
+		log.Printf("Warning: Failed to auto-save game: %v", err)
+	}
+}
+
+// OpenGame reopens a game saved in the games directory, with its
+// annotations. Moves made afterwards are saved back to the same game.
+func (g *Game) OpenGame(pgnPath string) error {
+	r, err := storage.LoadGameRecord(pgnPath)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return errNoGameRecord
+	}
+
+	fen := r.FEN
+	if fen == "" {
+		fen = board.StartFEN
+	}
+	pos, err := board.ParseFEN(fen)
+	if err != nil {
+		return err
+	}
+	link := &share.Link{FEN: fen}
+	for _, s := range r.Moves {
+		m, err := legalMove(pos, s)
+		if err != nil {
+			return err
+		}
+		link.Moves = append(link.Moves, m)
+		pos.MakeMove(m)
+	}
+	if err := g.OpenLink(link); err != nil {
+		return err
+	}
+
+	g.gamePath = pgnPath
+	for i, a := range r.Annotations {
+		if i < 0 || i >= len(g.sanHistory) {
+			continue
+		}
+		if len(a.Comments) > 0 {
+			g.commentary = append(g.commentary, CoachComment{Ply: g.startPly() + i, SAN: g.sanHistory[i], Notes: a.Comments})
+		}
+		if a.Eval != nil {
+			if g.evals == nil {
+				g.evals = make(map[int]int)
+			}
+			g.evals[i] = *a.Eval
+		}
+		if len(a.Variations) > 0 {
+			if g.variations == nil {
+				g.variations = make(map[int][]string)
+			}
+			g.variations[i] = a.Variations[0]
+		}
+	}
+	slices.SortStableFunc(g.commentary, func(a, b CoachComment) int { return a.Ply - b.Ply })
+	g.restoreAnalysisSession(pgnPath)
+	return nil
+}
+
+// saveAnalysisSession saves what the engine has learnt about the current
+// position next to the open saved game, so the analysis resumes when the
+// game is opened again. It does nothing while the engine is busy.
+func (g *Game) saveAnalysisSession() {
+	if g.gamePath == "" {
+		return
+	}
+	g.stopBackgroundSearch()
+	if g.aiThinking || g.assistRunning {
+		return
+	}
+	nodes := g.engine.ExportAnalysis(g.position, analysisSessionNodes)
+	if len(nodes) == 0 {
+		return
+	}
+
+	s := &storage.AnalysisSession{FEN: g.position.ToFEN(), Saved: time.Now(), Nodes: make([]storage.AnalysisNode, len(nodes))}
+	for i, n := range nodes {
+		sn := storage.AnalysisNode{Score: n.Score, Depth: n.Depth, Bound: int(n.Flag), Moves: make([]string, len(n.Moves))}
+		if n.Best != board.NoMove {
+			sn.Best = n.Best.String()
+		}
+		for j, m := range n.Moves {
+			sn.Moves[j] = m.String()
+		}
+		s.Nodes[i] = sn
+	}
+	if err := storage.SaveAnalysisSession(g.gamePath, s); err != nil {
+		log.Printf("Warning: Failed to save analysis: %v", err)
+		return
+	}
+	log.Printf("[Analysis] Saved %d positions", len(nodes))
+}
+
+// restoreAnalysisSession gives the engine back the analysis saved with the
+// game at pgnPath, if it is of the current position.
+func (g *Game) restoreAnalysisSession(pgnPath string) {
+	s, err := storage.LoadAnalysisSession(pgnPath)
+	if err != nil {
+		log.Printf("Warning: Failed to read analysis: %v", err)
+		return
+	}
+	if s == nil || s.FEN != g.position.ToFEN() {
+		return
+	}
+
+	var nodes []engine.AnalysisNode
+	for _, sn := range s.Nodes {
+		n, ok := analysisNode(g.position, sn)
+		if ok {
+			nodes = append(nodes, n)
+		}
+	}
+	stored := g.engine.ImportAnalysis(g.position, nodes)
+	log.Printf("[Analysis] Restored %d positions saved %s", stored, s.Saved.Format(time.DateTime))
+}
+
+// analysisNode reads a saved node of an analysis of pos, and returns false
+// if its moves are not legal there.
+func analysisNode(pos *board.Position, sn storage.AnalysisNode) (engine.AnalysisNode, bool) {
+	n := engine.AnalysisNode{Score: sn.Score, Depth: sn.Depth, Flag: engine.TTFlag(sn.Bound)}
+	p := pos.Copy()
+	for _, s := range sn.Moves {
+		m, err := legalMove(p, s)
+		if err != nil {
+			return n, false
+		}
+		n.Moves = append(n.Moves, m)
+		p.MakeMove(m)
+	}
+	if sn.Best != "" {
+		m, err := legalMove(p, sn.Best)
+		if err != nil {
+			return n, false
+		}
+		n.Best = m
+	}
+	return n, true
+}
+
+// legalMove finds the legal move with the given UCI notation.
+func legalMove(pos *board.Position, uci string) (board.Move, error) {
+	legal := pos.GenerateLegalMoves()
+	for i := 0; i < legal.Len(); i++ {
+		if m := legal.Get(i); m.String() == uci {
+			return m, nil
+		}
+	}
+	return board.NoMove, fmt.Errorf("%w %s in %s", board.ErrIllegalMove, uci, pos.ToFEN())
+}
+
+// formatEval formats an evaluation for a PGN [%eval] command: pawns, or
+// #N for a forced mate (negative when Black mates).
+func formatEval(score int) string {
+	if abs := max(score, -score); abs > engine.MateScore-engine.MaxPly {
+		n := (engine.MateScore - abs) / 2
+		if score < 0 {
+			n = -n
+		}
+		return fmt.Sprintf("#%d", n)
+	}
+	return fmt.Sprintf("%.2f", float64(score)/100)
+}
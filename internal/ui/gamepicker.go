@@ -0,0 +1,219 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/gamedb"
+)
+
+// gamePickerRows is the number of games listed at once; the wheel scrolls
+// through the rest.
+const gamePickerRows = 16
+
+// GamePickerModal lists the games of a PGN file and opens the one clicked.
+// It shares the layout of the game search modal.
+type GamePickerModal struct {
+	visible      bool
+	needsCapture bool // Set true when opening to capture background
+
+	// Position (centered on screen)
+	x, y int
+
+	closeBtn *ModalButton
+	hovered  int // Row under the mouse, as an index into games (-1 = none)
+	scroll   int // Index of the first game listed
+
+	file   string
+	games  []*gamedb.PGNGame
+	onOpen func(pg *gamedb.PGNGame)
+}
+
+// NewGamePickerModal creates a new game picker modal.
+func NewGamePickerModal() *GamePickerModal {
+	gp := &GamePickerModal{hovered: -1}
+	gp.x = (ScreenWidth - GameSearchWidth) / 2
+	gp.y = (ScreenHeight - GameSearchHeight) / 2
+
+	btnW, btnH := 100, 38
+	gp.closeBtn = NewModalButton(gp.x+GameSearchWidth-GameSearchPadX-btnW, gp.y+GameSearchHeight-GameSearchPadY-btnH,
+		btnW, btnH, "Close", false, nil)
+	gp.closeBtn.OnClick = gp.Hide
+	return gp
+}
+
+// Show opens the modal with the games of the named file. onOpen opens the
+// game picked.
+func (gp *GamePickerModal) Show(file string, games []*gamedb.PGNGame, onOpen func(pg *gamedb.PGNGame)) {
+	gp.visible = true
+	gp.needsCapture = true
+	gp.hovered = -1
+	gp.scroll = 0
+	gp.file = file
+	gp.games = games
+	gp.onOpen = onOpen
+}
+
+// Hide closes the modal.
+func (gp *GamePickerModal) Hide() {
+	gp.visible = false
+}
+
+// IsVisible returns true if the modal is visible.
+func (gp *GamePickerModal) IsVisible() bool {
+	return gp.visible
+}
+
+// listY returns the top of the game list.
+func (gp *GamePickerModal) listY() int {
+	return gp.y + 60 + 24
+}
+
+// rowAt returns the index of the game listed at (mx, my), or -1.
+func (gp *GamePickerModal) rowAt(mx, my int) int {
+	if mx < gp.x+GameSearchPadX || mx >= gp.x+GameSearchWidth-GameSearchPadX || my < gp.listY() {
+		return -1
+	}
+	row := (my - gp.listY()) / gameSearchRowH
+	if i := gp.scroll + row; row < gamePickerRows && i < len(gp.games) {
+		return i
+	}
+	return -1
+}
+
+// Update handles input for the game picker modal.
+func (gp *GamePickerModal) Update(input *InputHandler) bool {
+	if !gp.visible {
+		return false
+	}
+
+	if IsKeyJustPressed(ebiten.KeyEscape) {
+		gp.Hide()
+		return true
+	}
+
+	if _, wy := ebiten.Wheel(); wy != 0 {
+		gp.scroll -= int(wy)
+		gp.scroll = max(0, min(gp.scroll, len(gp.games)-gamePickerRows))
+	}
+
+	mx, my := input.MousePosition()
+	gp.hovered = gp.rowAt(mx, my)
+	if gp.hovered >= 0 && input.IsLeftJustPressed() {
+		pg := gp.games[gp.hovered]
+		gp.Hide()
+		if gp.onOpen != nil {
+			gp.onOpen(pg)
+		}
+		return true
+	}
+	gp.closeBtn.Update(input)
+
+	// Modal consumes all input
+	return true
+}
+
+// AnyButtonHovered returns true if any button or game in the modal is hovered.
+func (gp *GamePickerModal) AnyButtonHovered() bool {
+	if !gp.visible {
+		return false
+	}
+	return gp.hovered >= 0 || gp.closeBtn.IsHovered()
+}
+
+// Draw renders the game picker modal.
+func (gp *GamePickerModal) Draw(screen *ebiten.Image, glass *GlassEffect) {
+	if !gp.visible {
+		return
+	}
+
+	// Capture background once when modal first opens (fixes flicker)
+	if gp.needsCapture && glass != nil && glass.IsEnabled() {
+		glass.CaptureForModal(screen, 3.0)
+		gp.needsCapture = false
+	}
+
+	if glass != nil && glass.IsEnabled() {
+		glass.DrawModalBackground(screen, 0.4)
+	} else {
+		vector.DrawFilledRect(screen, 0, 0, scaleF(ScreenWidth), scaleF(ScreenHeight), modalOverlay, false)
+	}
+
+	// Modal background, border and header
+	vector.DrawFilledRect(screen, scaleF(gp.x), scaleF(gp.y), scaleF(GameSearchWidth), scaleF(GameSearchHeight), modalBg, false)
+	vector.StrokeRect(screen, scaleF(gp.x), scaleF(gp.y), scaleF(GameSearchWidth), scaleF(GameSearchHeight), float32(UIScale*2), modalBorder, false)
+	vector.DrawFilledRect(screen, scaleF(gp.x), scaleF(gp.y), scaleF(GameSearchWidth), scaleF(44), modalHeader, false)
+	gp.drawTitle(screen)
+
+	contentX := gp.x + GameSearchPadX
+	rightX := gp.x + GameSearchWidth - GameSearchPadX
+
+	file := gp.file
+	if len(file) > 40 {
+		file = file[:40] + "..."
+	}
+	drawText(screen, file, contentX, gp.y+60, textMuted)
+	info := fmt.Sprintf("%d games", len(gp.games))
+	if len(gp.games) > gamePickerRows {
+		last := min(gp.scroll+gamePickerRows, len(gp.games))
+		info = fmt.Sprintf("%d-%d of %d games", gp.scroll+1, last, len(gp.games))
+	}
+	drawTextRight(screen, info, rightX, gp.y+60, textMuted)
+
+	y := gp.listY()
+	for i := gp.scroll; i < len(gp.games) && i < gp.scroll+gamePickerRows; i++ {
+		pg := gp.games[i]
+		if i == gp.hovered {
+			vector.DrawFilledRect(screen, scaleF(contentX-6), scaleF(y), scaleF(rightX-contentX+12), scaleF(gameSearchRowH), gameSearchRowHover, false)
+		}
+
+		players := pgnPlayers(pg)
+		if len(players) > 40 {
+			players = players[:40] + "..."
+		}
+		drawText(screen, players, contentX, y+3, textPrimary)
+
+		result := pg.Tags["Result"]
+		if result == "" {
+			result = "*"
+		}
+		drawTextRight(screen, fmt.Sprintf("%s  %d moves", result, (len(pg.Moves)+1)/2), rightX, y+3, textSecondary)
+		y += gameSearchRowH
+	}
+
+	gp.closeBtn.Draw(screen)
+}
+
+// pgnPlayers formats the players of a PGN game, with their ratings and the
+// year, as matchPlayers does for imported games.
+func pgnPlayers(pg *gamedb.PGNGame) string {
+	elo := func(tag string) int {
+		n, _ := strconv.Atoi(pg.Tags[tag])
+		return n
+	}
+	return matchPlayers(&gamedb.Match{Header: gamedb.Header{
+		White:    pg.Tags["White"],
+		Black:    pg.Tags["Black"],
+		WhiteElo: elo("WhiteElo"),
+		BlackElo: elo("BlackElo"),
+		Date:     pg.Tags["Date"],
+	}})
+}
+
+// drawTitle draws the modal title.
+func (gp *GamePickerModal) drawTitle(screen *ebiten.Image) {
+	face := GetBoldFace()
+	if face == nil {
+		return
+	}
+
+	title := "Choose a Game"
+	w, h := MeasureText(title, face)
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(scaleD(gp.x)+scaleD(GameSearchWidth)/2-w/2, scaleD(gp.y)+scaleD(22)-h/2)
+	op.ColorScale.ScaleWithColor(textPrimary)
+	text.Draw(screen, title, face, op)
+}
@@ -0,0 +1,344 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/gamedb"
+	"github.com/hailam/chessplay/internal/share"
+	"github.com/hailam/chessplay/internal/storage"
+)
+
+// Game search modal dimensions
+const (
+	GameSearchWidth  = 520
+	GameSearchHeight = 540
+	GameSearchPadX   = 24
+	GameSearchPadY   = 20
+)
+
+// Result rows shown per section, and their height
+const (
+	gameSearchRows = 7
+	gameSearchRowH = 24
+)
+
+// gameSearchRowHover is the background of the row under the mouse.
+var gameSearchRowHover = color.RGBA{255, 255, 255, 20}
+
+// gameSearch is one search of the games database. The search goroutine
+// fills it; the modal reads it each frame.
+type gameSearch struct {
+	mu      sync.Mutex
+	done    bool
+	exact   []gamedb.Match // Games reaching the position
+	total   int            // Games reaching the position, including those not listed
+	similar []gamedb.Match // Games reaching a similar position
+	count   int            // Games in the database
+	err     error
+}
+
+// GameSearchModal lists the imported games that reach the current position
+// or a similar one, and opens the one clicked.
+type GameSearchModal struct {
+	visible      bool
+	needsCapture bool // Set true when opening to capture background
+
+	// Position (centered on screen)
+	x, y int
+
+	closeBtn *ModalButton
+	hovered  *gamedb.Match // Row under the mouse (nil = none)
+
+	search *gameSearch
+	onOpen func(id uint32)
+}
+
+// NewGameSearchModal creates a new game search modal.
+func NewGameSearchModal() *GameSearchModal {
+	gm := &GameSearchModal{}
+	gm.x = (ScreenWidth - GameSearchWidth) / 2
+	gm.y = (ScreenHeight - GameSearchHeight) / 2
+
+	btnW, btnH := 100, 38
+	gm.closeBtn = NewModalButton(gm.x+GameSearchWidth-GameSearchPadX-btnW, gm.y+GameSearchHeight-GameSearchPadY-btnH,
+		btnW, btnH, "Close", false, nil)
+	gm.closeBtn.OnClick = gm.Hide
+	return gm
+}
+
+// Show opens the modal and searches the database in dir for pos.
+// onOpen opens a game of the results.
+func (gm *GameSearchModal) Show(dir string, pos *board.Position, onOpen func(id uint32)) {
+	gm.visible = true
+	gm.needsCapture = true
+	gm.hovered = nil
+	gm.onOpen = onOpen
+
+	s := &gameSearch{}
+	gm.search = s
+	go func() {
+		exact, total, similar, count, err := searchGames(dir, pos)
+		s.mu.Lock()
+		s.done = true
+		s.exact, s.total, s.similar, s.count, s.err = exact, total, similar, count, err
+		s.mu.Unlock()
+	}()
+}
+
+// searchGames finds the games reaching pos and those reaching a similar
+// position. The database is only open during the search, so
+// chessplay-import can add games while the GUI runs.
+func searchGames(dir string, pos *board.Position) (exact []gamedb.Match, total int, similar []gamedb.Match, count int, err error) {
+	db, err := gamedb.Open(dir)
+	if err != nil {
+		return nil, 0, nil, 0, err
+	}
+	defer db.Close()
+
+	if count, err = db.Count(); err != nil || count == 0 {
+		return nil, 0, nil, count, err
+	}
+	if exact, total, err = db.Search(pos, gameSearchRows); err != nil {
+		return nil, 0, nil, count, err
+	}
+	similar, err = db.SearchSimilar(pos, gameSearchRows)
+	return exact, total, similar, count, err
+}
+
+// Hide closes the modal.
+func (gm *GameSearchModal) Hide() {
+	gm.visible = false
+}
+
+// IsVisible returns true if the modal is visible.
+func (gm *GameSearchModal) IsVisible() bool {
+	return gm.visible
+}
+
+// results returns the search results once the search has finished.
+func (gm *GameSearchModal) results() (s *gameSearch, done bool) {
+	gm.search.mu.Lock()
+	defer gm.search.mu.Unlock()
+	return gm.search, gm.search.done
+}
+
+// exactY returns the top of the exact matches section.
+func (gm *GameSearchModal) exactY() int {
+	return gm.y + 60
+}
+
+// similarY returns the top of the similar positions section.
+func (gm *GameSearchModal) similarY() int {
+	return gm.exactY() + 24 + gameSearchRows*gameSearchRowH + 30
+}
+
+// rowAt returns the result row at (mx, my), or nil.
+func (gm *GameSearchModal) rowAt(mx, my int) *gamedb.Match {
+	s, done := gm.results()
+	if !done || s.err != nil || mx < gm.x+GameSearchPadX || mx >= gm.x+GameSearchWidth-GameSearchPadX {
+		return nil
+	}
+	for _, sec := range []struct {
+		y       int
+		matches []gamedb.Match
+	}{{gm.exactY(), s.exact}, {gm.similarY(), s.similar}} {
+		i := (my - sec.y - 24) / gameSearchRowH
+		if my >= sec.y+24 && i < len(sec.matches) {
+			return &sec.matches[i]
+		}
+	}
+	return nil
+}
+
+// Update handles input for the game search modal.
+func (gm *GameSearchModal) Update(input *InputHandler) bool {
+	if !gm.visible {
+		return false
+	}
+
+	if IsKeyJustPressed(ebiten.KeyEscape) {
+		gm.Hide()
+		return true
+	}
+
+	mx, my := input.MousePosition()
+	gm.hovered = gm.rowAt(mx, my)
+	if gm.hovered != nil && input.IsLeftJustPressed() {
+		id := gm.hovered.ID
+		gm.Hide()
+		if gm.onOpen != nil {
+			gm.onOpen(id)
+		}
+		return true
+	}
+	gm.closeBtn.Update(input)
+
+	// Modal consumes all input
+	return true
+}
+
+// AnyButtonHovered returns true if any button or result in the modal is hovered.
+func (gm *GameSearchModal) AnyButtonHovered() bool {
+	if !gm.visible {
+		return false
+	}
+	return gm.hovered != nil || gm.closeBtn.IsHovered()
+}
+
+// Draw renders the game search modal.
+func (gm *GameSearchModal) Draw(screen *ebiten.Image, glass *GlassEffect) {
+	if !gm.visible {
+		return
+	}
+
+	// Capture background once when modal first opens (fixes flicker)
+	if gm.needsCapture && glass != nil && glass.IsEnabled() {
+		glass.CaptureForModal(screen, 3.0)
+		gm.needsCapture = false
+	}
+
+	if glass != nil && glass.IsEnabled() {
+		glass.DrawModalBackground(screen, 0.4)
+	} else {
+		vector.DrawFilledRect(screen, 0, 0, scaleF(ScreenWidth), scaleF(ScreenHeight), modalOverlay, false)
+	}
+
+	// Modal background, border and header
+	vector.DrawFilledRect(screen, scaleF(gm.x), scaleF(gm.y), scaleF(GameSearchWidth), scaleF(GameSearchHeight), modalBg, false)
+	vector.StrokeRect(screen, scaleF(gm.x), scaleF(gm.y), scaleF(GameSearchWidth), scaleF(GameSearchHeight), float32(UIScale*2), modalBorder, false)
+	vector.DrawFilledRect(screen, scaleF(gm.x), scaleF(gm.y), scaleF(GameSearchWidth), scaleF(44), modalHeader, false)
+	gm.drawTitle(screen)
+
+	contentX := gm.x + GameSearchPadX
+	rightX := gm.x + GameSearchWidth - GameSearchPadX
+
+	s, done := gm.results()
+	switch {
+	case !done:
+		drawText(screen, "Searching...", contentX, gm.exactY(), textSecondary)
+	case s.err != nil:
+		msg := s.err.Error()
+		if len(msg) > 56 {
+			msg = msg[:56] + "..."
+		}
+		drawText(screen, "Search failed", contentX, gm.exactY(), tbErrorColor)
+		drawText(screen, msg, contentX, gm.exactY()+22, textSecondary)
+	case s.count == 0:
+		drawText(screen, "No games imported yet.", contentX, gm.exactY(), textSecondary)
+		drawText(screen, "Import PGN files with chessplay-import.", contentX, gm.exactY()+22, textMuted)
+	default:
+		drawText(screen, fmt.Sprintf("This position (%d of %d games)", s.total, s.count), contentX, gm.exactY(), textMuted)
+		gm.drawMatches(screen, s.exact, gm.exactY()+24, false)
+		drawText(screen, "Similar positions", contentX, gm.similarY(), textMuted)
+		drawTextRight(screen, "material and pawns", rightX, gm.similarY(), textMuted)
+		gm.drawMatches(screen, s.similar, gm.similarY()+24, true)
+	}
+
+	gm.closeBtn.Draw(screen)
+}
+
+// drawMatches draws result rows from y, or a note when there are none.
+func (gm *GameSearchModal) drawMatches(screen *ebiten.Image, matches []gamedb.Match, y int, similar bool) {
+	contentX := gm.x + GameSearchPadX
+	rightX := gm.x + GameSearchWidth - GameSearchPadX
+	if len(matches) == 0 {
+		drawText(screen, "No games", contentX, y+2, textSecondary)
+		return
+	}
+
+	for i := range matches {
+		m := &matches[i]
+		if gm.hovered != nil && gm.hovered.ID == m.ID {
+			vector.DrawFilledRect(screen, scaleF(contentX-6), scaleF(y), scaleF(rightX-contentX+12), scaleF(gameSearchRowH), gameSearchRowHover, false)
+		}
+
+		players := matchPlayers(m)
+		if len(players) > 40 {
+			players = players[:40] + "..."
+		}
+		drawText(screen, players, contentX, y+3, textPrimary)
+
+		info := fmt.Sprintf("%s  move %d", m.Result, m.Ply/2+1)
+		if similar {
+			info = fmt.Sprintf("%s  %d%%", m.Result, int(m.Similarity*100))
+		}
+		drawTextRight(screen, info, rightX, y+3, textSecondary)
+		y += gameSearchRowH
+	}
+}
+
+// matchPlayers formats the players of a game, with their ratings and the year.
+func matchPlayers(m *gamedb.Match) string {
+	player := func(name string, elo int) string {
+		if name == "" {
+			name = "?"
+		}
+		if elo > 0 {
+			return fmt.Sprintf("%s (%d)", name, elo)
+		}
+		return name
+	}
+	s := player(m.White, m.WhiteElo) + " - " + player(m.Black, m.BlackElo)
+	if len(m.Date) >= 4 && m.Date[:4] != "????" {
+		s += ", " + m.Date[:4]
+	}
+	return s
+}
+
+// drawTitle draws the modal title.
+func (gm *GameSearchModal) drawTitle(screen *ebiten.Image) {
+	face := GetBoldFace()
+	if face == nil {
+		return
+	}
+
+	title := "Games Database"
+	w, h := MeasureText(title, face)
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(scaleD(gm.x)+scaleD(GameSearchWidth)/2-w/2, scaleD(gm.y)+scaleD(22)-h/2)
+	op.ColorScale.ScaleWithColor(textPrimary)
+	text.Draw(screen, title, face, op)
+}
+
+// ShowGameSearch searches the imported games for the current position.
+func (g *Game) ShowGameSearch() {
+	dir, err := storage.GetGameDBDir()
+	if err != nil {
+		log.Printf("Warning: Failed to find the games database: %v", err)
+		return
+	}
+	// Search a copy, as the game goes on while the search runs
+	pos, err := board.ParseFEN(g.position.ToFEN())
+	if err != nil {
+		log.Printf("Warning: Failed to copy the position: %v", err)
+		return
+	}
+	g.gameSearchModal.Show(dir, pos, func(id uint32) {
+		err := g.openDatabaseGame(dir, id)
+		if err != nil {
+			log.Printf("Warning: Failed to open game %d: %v", id, err)
+		}
+		g.feedback.OnGameOpened(err)
+	})
+}
+
+// openDatabaseGame replays an imported game on the board.
+func (g *Game) openDatabaseGame(dir string, id uint32) error {
+	db, err := gamedb.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	dbGame, err := db.Game(id)
+	if err != nil {
+		return err
+	}
+	return g.OpenLink(share.New(dbGame.FEN, dbGame.Moves))
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"image"
 	"image/color"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
@@ -172,7 +173,8 @@ type GlassEffect struct {
 	dimming *ebiten.Shader // For modal backgrounds
 	tempH   *ebiten.Image  // Horizontal blur result
 	tempV   *ebiten.Image  // Vertical blur result
-	time    float64
+	start   time.Time      // Animation time is measured from here
+	time    float64        // Seconds since start
 	enabled bool
 
 	// Cached modal background (captured once when modal opens)
@@ -184,6 +186,7 @@ type GlassEffect struct {
 // NewGlassEffect creates a new glass effect manager
 func NewGlassEffect() *GlassEffect {
 	ge := &GlassEffect{
+		start:   time.Now(),
 		enabled: true,
 	}
 
@@ -225,7 +228,7 @@ func (ge *GlassEffect) Update() {
 	if ge == nil {
 		return
 	}
-	ge.time += 1.0 / 60.0 // Assuming 60 FPS
+	ge.time = time.Since(ge.start).Seconds() // Whatever the update rate
 }
 
 // ensureImages creates or resizes offscreen images as needed
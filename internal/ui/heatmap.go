@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+// Evaluation heatmap: H colors the square of every piece by how well it is
+// placed, that is what it adds to the classical evaluation beyond its
+// material value, green when it helps its own side and red when it hurts.
+// Hovering a piece explains its contribution term by term.
+
+// heatmapFullColor is the placement, in centipawns, shown at full strength.
+const heatmapFullColor = 60
+
+// Heatmap colors
+var (
+	heatmapGood = color.RGBA{60, 200, 90, 0}
+	heatmapBad  = color.RGBA{220, 60, 60, 0}
+)
+
+// heatmap caches the piece contributions of one position.
+type heatmap struct {
+	hash   uint64
+	pieces []engine.PieceTrace
+}
+
+// ToggleHeatmap shows or hides the evaluation heatmap.
+func (g *Game) ToggleHeatmap() {
+	g.showHeatmap = !g.showHeatmap
+	g.feedback.OnHeatmapToggled(g.showHeatmap)
+}
+
+// heatmapPieces returns the contributions of the pieces of the current
+// position, or nil while the heatmap is hidden. It is hidden in a puzzle
+// rush, where it would give the answer away.
+func (g *Game) heatmapPieces() []engine.PieceTrace {
+	if !g.showHeatmap || g.rush != nil {
+		return nil
+	}
+	if g.heatmap.pieces == nil || g.heatmap.hash != g.position.Hash {
+		g.heatmap = heatmap{hash: g.position.Hash, pieces: engine.TracePieces(g.position)}
+	}
+	return g.heatmap.pieces
+}
+
+// DrawHeatmap colors the square of each piece by its placement.
+func (r *Renderer) DrawHeatmap(screen *ebiten.Image, pieces []engine.PieceTrace) {
+	for _, p := range pieces {
+		v := p.Positional()
+		c := heatmapGood
+		if v < 0 {
+			c, v = heatmapBad, -v
+		}
+		c.A = uint8(40 + 120*min(v, heatmapFullColor)/heatmapFullColor)
+		x, y := r.SquareToScreen(p.Square)
+		vector.DrawFilledRect(screen, r.s(x), r.s(y), r.s(r.squareSize), r.s(r.squareSize), c, false)
+	}
+}
+
+// heatmapTipAreas returns one tooltip area per piece while the heatmap is
+// shown.
+func (p *Panel) heatmapTipAreas() []TipArea {
+	pieces := p.game.heatmapPieces()
+	areas := make([]TipArea, 0, len(pieces))
+	for _, pt := range pieces {
+		x, y := p.game.renderer.SquareToScreen(pt.Square)
+		size := p.game.renderer.SquareSize()
+		areas = append(areas, TipArea{Rect: Rect{X: x, Y: y, W: size, H: size}, Tooltip: heatmapTooltip(pt)})
+	}
+	return areas
+}
+
+// heatmapTooltip explains a piece's contribution from its own side, in
+// pawns, e.g. "Knight on f3: worth 3.41, 0.21 above its value. PST +0.10,
+// Mobility +0.11".
+func heatmapTooltip(pt engine.PieceTrace) string {
+	sign := 1
+	if pt.Piece.Color() == board.Black {
+		sign = -1
+	}
+	if pt.Piece.Type() == board.King {
+		return fmt.Sprintf("King on %v: %+.2f for its square", pt.Square, float64(sign*pt.Score)/100)
+	}
+
+	placement, dir := pt.Positional(), "above"
+	if placement < 0 {
+		placement, dir = -placement, "below"
+	}
+	var terms []string
+	for _, term := range pt.Terms {
+		if term.Name != "Material" {
+			terms = append(terms, fmt.Sprintf("%s %+.2f", term.Name, float64(sign*term.Value)/100))
+		}
+	}
+	return fmt.Sprintf("%v on %v: worth %.2f, %.2f %s its value. %s", pt.Piece.Type(), pt.Square,
+		float64(sign*pt.Score)/100, float64(placement)/100, dir, strings.Join(terms, ", "))
+}
@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+// Hint analysis: a short MultiPV search of the player's position
+const (
+	hintLines    = 3                      // Top moves shown
+	hintDepth    = 6                      // Depth limit of each line
+	hintLineTime = 300 * time.Millisecond // Time limit of each line
+	hintPlayouts = 32                     // Playouts estimating practical chances
+	hintPlayTime = 2 * time.Second        // Time limit of the playouts
+)
+
+// HintLimits are the choices for the number of hints per game (0 = no limit).
+var HintLimits = []int{0, 5, 3, 1}
+
+// hintLimitIndex returns the index of a hint limit in HintLimits (0 if unknown).
+func hintLimitIndex(limit int) int {
+	for i, l := range HintLimits {
+		if l == limit {
+			return i
+		}
+	}
+	return 0
+}
+
+// hintLimitName returns the settings label of a hint limit.
+func hintLimitName(limit int) string {
+	if limit == 0 {
+		return "No limit"
+	}
+	return strconv.Itoa(limit)
+}
+
+// autoHints returns true if hints are shown without asking, as in Easy mode.
+func (g *Game) autoHints() bool {
+	return g.difficulty == DifficultyEasy && g.showHints
+}
+
+// HintsLeft returns the hints the player may still ask for in this game,
+// or -1 without a limit.
+func (g *Game) HintsLeft() int {
+	if g.prefs.HintLimit == 0 {
+		return -1
+	}
+	return max(g.prefs.HintLimit-g.hintsUsed, 0)
+}
+
+// canRequestHint returns true if the Hint button may be used now.
+func (g *Game) canRequestHint() bool {
+	return !g.autoHints() && !g.hintRequested && g.HintsLeft() != 0 && g.humanToMove()
+}
+
+// RequestHint analyzes the player's position and shows the best moves until
+// they move, using up one of the hints of the game.
+func (g *Game) RequestHint() {
+	if !g.canRequestHint() {
+		return
+	}
+	g.hintRequested = true
+	g.hintsUsed++
+	g.startAssistAnalysis()
+}
+
+// Moves returns the hint moves, best first.
+func (a *AssistResult) Moves() []board.Move {
+	moves := make([]board.Move, len(a.Lines))
+	for i, l := range a.Lines {
+		moves[i] = l.Move
+	}
+	return moves
+}
+
+// hintVisible returns true if the hint analysis is to be shown.
+func (g *Game) hintVisible() bool {
+	return g.assistResult != nil && (g.hintRequested || g.autoHints())
+}
+
+// analyzeHint runs the hint search on a copy of the position, and the
+// playouts estimating practical chances if asked for.
+func (g *Game) analyzeHint(pos *board.Position, chances bool) *AssistResult {
+	lines := g.engine.SearchMultiPV(pos, engine.SearchLimits{
+		Depth:    hintDepth,
+		MoveTime: hintLineTime,
+		MultiPV:  hintLines,
+	})
+	result := &AssistResult{Lines: lines}
+	if len(lines) > 0 {
+		result.BestMove = lines[0].Move
+		result.Evaluation = lines[0].Score
+		if pos.SideToMove == board.Black {
+			result.Evaluation = -result.Evaluation
+		}
+	}
+	if chances && len(lines) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), hintPlayTime)
+		c := engine.PracticalChances(ctx, pos, engine.PlayoutOptions{Playouts: hintPlayouts})
+		cancel()
+		if c.Playouts() > 0 {
+			result.Chances = &c
+		}
+	}
+	return result
+}
+
+// chancesText describes the practical chances of the player, e.g.
+// "Practical chances 62% (32 games)".
+func chancesText(c *engine.Chances) string {
+	return fmt.Sprintf("Practical chances %.0f%% (%d games)", c.Score()*100, c.Playouts())
+}
+
+// hintLineText describes a hint line: the move, its evaluation from White's
+// view, and for the other lines how much worse than the best move it is for
+// the player, e.g. "Nf3  0.35" or "e4  0.12  (-0.23)".
+func (g *Game) hintLineText(lines []engine.SearchResult, i int) string {
+	l := lines[i]
+	eval := l.Score
+	if g.position.SideToMove == board.Black {
+		eval = -eval
+	}
+	s := fmt.Sprintf("%d. %s  %s", i+1, g.moveToSAN(l.Move), formatEval(eval))
+	if i > 0 {
+		s += fmt.Sprintf("  (%+.2f)", float64(l.Score-lines[0].Score)/100)
+	}
+	return s
+}
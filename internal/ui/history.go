@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// History browsing: the player can step back through the earlier positions
+// of the game and forward again. The game goes on at the live position
+// meanwhile (the engine may still move); the board shows the browsed
+// position read-only, grayed out, until the player returns to the last move.
+
+// browseOverlay grays out the board while an earlier position is shown.
+var browseOverlay = color.RGBA{60, 64, 72, 90}
+
+// startPosition returns the position the game started from.
+func (g *Game) startPosition() *board.Position {
+	if g.startFEN != "" {
+		if pos, err := board.ParseFEN(g.startFEN); err == nil {
+			return pos
+		}
+	}
+	return board.NewPosition()
+}
+
+// Browsing returns true while an earlier position of the game is shown.
+func (g *Game) Browsing() bool {
+	return g.viewPos != nil
+}
+
+// ShownPly returns the number of game moves played in the position shown.
+func (g *Game) ShownPly() int {
+	if g.viewPos == nil {
+		return len(g.moveHistory)
+	}
+	return g.viewPly
+}
+
+// shownPosition returns the position on the board: the browsed one, or
+// else the live one.
+func (g *Game) shownPosition() *board.Position {
+	if g.viewPos != nil {
+		return g.viewPos
+	}
+	return g.position
+}
+
+// shownLastMove returns the move that led to the position shown.
+func (g *Game) shownLastMove() board.Move {
+	if g.viewPos == nil {
+		return g.lastMove
+	}
+	if g.viewPly == 0 {
+		return board.NoMove
+	}
+	return g.moveHistory[g.viewPly-1]
+}
+
+// browseTo shows the position after the first n moves of the game,
+// replaying them from the start position. Showing the last move returns to
+// the live position. A single step is animated.
+func (g *Game) browseTo(n int) {
+	n = min(max(n, 0), len(g.moveHistory))
+	from := g.ShownPly()
+	if n == from {
+		return
+	}
+	shown := g.shownPosition()
+
+	if n == len(g.moveHistory) {
+		g.viewPos = nil
+	} else {
+		pos := g.startPosition()
+		for _, m := range g.moveHistory[:n] {
+			pos.MakeMove(m)
+		}
+		pos.UpdateCheckers()
+		g.viewPos = pos
+		g.viewPly = n
+		g.clearSelection()
+		g.closeMoveInput()
+	}
+
+	switch n - from {
+	case 1:
+		g.renderer.AnimateMove(shown, g.moveHistory[from], false)
+	case -1:
+		g.renderer.AnimateMove(g.shownPosition(), g.moveHistory[n], true)
+	default:
+		g.renderer.StopMoveAnimation()
+	}
+}
+
+// stopBrowsing returns to the live position at once.
+func (g *Game) stopBrowsing() {
+	g.viewPos = nil
+}
+
+// HistoryFirstAction shows the start position of the game.
+func (g *Game) HistoryFirstAction() {
+	g.browseTo(0)
+}
+
+// HistoryBackAction steps one move back.
+func (g *Game) HistoryBackAction() {
+	g.browseTo(g.ShownPly() - 1)
+}
+
+// HistoryForwardAction steps one move forward.
+func (g *Game) HistoryForwardAction() {
+	g.browseTo(g.ShownPly() + 1)
+}
+
+// HistoryLastAction returns to the live position.
+func (g *Game) HistoryLastAction() {
+	g.browseTo(len(g.moveHistory))
+}
+
+// handleHistoryKeys steps through the game with Ctrl (Cmd) and the left and
+// right arrows, and jumps to its start and end with Home and End. The plain
+// arrows move the keyboard square cursor, so it returns true while Ctrl is
+// held or a history key was pressed.
+func (g *Game) handleHistoryKeys() bool {
+	ctrl := IsKeyPressed(ebiten.KeyControl) || IsKeyPressed(ebiten.KeyMeta)
+	switch {
+	case IsKeyJustPressed(ebiten.KeyHome):
+		g.HistoryFirstAction()
+	case IsKeyJustPressed(ebiten.KeyEnd):
+		g.HistoryLastAction()
+	case ctrl && IsKeyJustPressed(ebiten.KeyArrowLeft):
+		g.HistoryBackAction()
+	case ctrl && IsKeyJustPressed(ebiten.KeyArrowRight):
+		g.HistoryForwardAction()
+	default:
+		return ctrl
+	}
+	return true
+}
+
+// DrawBrowseOverlay grays out the board while browsing.
+func (r *Renderer) DrawBrowseOverlay(screen *ebiten.Image) {
+	vector.DrawFilledRect(screen, 0, 0, r.s(r.boardSize), r.s(r.boardSize), browseOverlay, false)
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Low-power idle mode: while nothing on the screen changes, the game updates
+// at idleTPS instead of activeTPS and skips drawing, leaving the last frame
+// on the screen, so an open window costs next to no CPU or GPU. Input,
+// animations, toasts, modals and engine work bring back the full rate.
+const (
+	activeTPS  = 60
+	idleTPS    = 20              // Still sees a quick click between two updates
+	idleDelay  = 2 * time.Second // Full rate for this long after the last activity
+	idleRedraw = time.Second     // Redraw this often while idle, for the clocks
+)
+
+// updateFrameRate switches between the active and idle update rates and
+// decides whether the next frame is drawn. Call once per update, after the
+// input.
+func (g *Game) updateFrameRate() {
+	now := time.Now()
+	if g.input.Active() || g.busy() {
+		g.lastActive = now
+	}
+
+	idle := now.Sub(g.lastActive) >= idleDelay
+	if idle != g.idle {
+		g.idle = idle
+		if idle {
+			ebiten.SetTPS(idleTPS)
+		} else {
+			ebiten.SetTPS(activeTPS)
+		}
+	}
+	if now.Sub(g.lastDraw) >= idleRedraw {
+		g.redraw = true
+	}
+}
+
+// busy returns true while the screen changes without input: during an
+// animation, toast or modal, an engine search, or a timed puzzle rush or
+// engine match.
+func (g *Game) busy() bool {
+	return g.feedback.Animating() || g.renderer.Animating() || g.panel.Animating() ||
+		g.modalVisible() || g.aiThinking || g.assistRunning ||
+		g.rush != nil || !g.matchMoveAt.IsZero()
+}
+
+// modalVisible returns true if a modal, the welcome screen or the tour is
+// open.
+func (g *Game) modalVisible() bool {
+	return g.welcomeScreen.IsVisible() || g.downloader.IsVisible() || g.tablebaseModal.IsVisible() ||
+		g.rushModal.IsVisible() || g.matchModal.IsVisible() || g.gameSearchModal.IsVisible() || g.gamePicker.IsVisible() ||
+		g.engineOptions.IsVisible() || g.settingsModal.IsVisible() || g.tour.IsVisible()
+}
+
+// skipDraw returns true if the frame need not be drawn: the game is idle
+// and the last frame is still current. Otherwise it marks the frame drawn.
+func (g *Game) skipDraw() bool {
+	if g.idle && !g.redraw {
+		return true
+	}
+	g.redraw = false
+	g.lastDraw = time.Now()
+	return false
+}
@@ -11,6 +11,12 @@ type InputHandler struct {
 	leftPressed      bool
 	leftJustPressed  bool
 	leftJustReleased bool
+
+	rightPressed      bool
+	rightJustPressed  bool
+	rightJustReleased bool
+
+	active bool // Any input this update, see Active
 }
 
 // NewInputHandler creates a new input handler.
@@ -28,12 +34,30 @@ func (ih *InputHandler) Update() {
 	if scale < 1.0 {
 		scale = 1.0
 	}
-	ih.mouseX = int(float64(rawX) / scale)
-	ih.mouseY = int(float64(rawY) / scale)
+	mouseX, mouseY := int(float64(rawX)/scale), int(float64(rawY)/scale)
+	ih.active = mouseX != ih.mouseX || mouseY != ih.mouseY
+	ih.mouseX, ih.mouseY = mouseX, mouseY
 
 	ih.leftJustPressed = inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
 	ih.leftJustReleased = inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft)
 	ih.leftPressed = ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+
+	ih.rightJustPressed = inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight)
+	ih.rightJustReleased = inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonRight)
+	ih.rightPressed = ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight)
+
+	if !ih.active {
+		wheelX, wheelY := ebiten.Wheel()
+		ih.active = ih.leftPressed || ih.rightPressed || ih.leftJustReleased || ih.rightJustReleased ||
+			wheelX != 0 || wheelY != 0 || len(inpututil.AppendPressedKeys(nil)) > 0 ||
+			len(inpututil.AppendJustReleasedKeys(nil)) > 0
+	}
+}
+
+// Active returns true if the mouse moved or a button, key or the wheel was
+// used this update.
+func (ih *InputHandler) Active() bool {
+	return ih.active
 }
 
 // MousePosition returns the current mouse position in logical coordinates.
@@ -66,6 +90,21 @@ func (ih *InputHandler) IsLeftPressed() bool {
 	return ih.leftPressed
 }
 
+// IsRightJustPressed returns true if the right mouse button was just pressed.
+func (ih *InputHandler) IsRightJustPressed() bool {
+	return ih.rightJustPressed
+}
+
+// IsRightJustReleased returns true if the right mouse button was just released.
+func (ih *InputHandler) IsRightJustReleased() bool {
+	return ih.rightJustReleased
+}
+
+// IsRightPressed returns true if the right mouse button is currently pressed.
+func (ih *InputHandler) IsRightPressed() bool {
+	return ih.rightPressed
+}
+
 // IsInBounds returns true if the mouse is within the given rectangle.
 func (ih *InputHandler) IsInBounds(x, y, w, h int) bool {
 	return ih.mouseX >= x && ih.mouseX < x+w && ih.mouseY >= y && ih.mouseY < y+h
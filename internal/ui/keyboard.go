@@ -0,0 +1,285 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// maxMoveInput is the longest move text accepted, e.g. "Nbxd7+" or "e7e8q".
+const maxMoveInput = 8
+
+// handleKeyboardInput lets the game be played without a mouse:
+//   - Enter opens the move entry; type a move in SAN ("Nf3") or coordinates
+//     ("g1f3"), Tab completes it and Enter plays it. Esc closes the entry.
+//   - The arrow keys move a square cursor over the board, and Space selects
+//     the piece under it or moves the selected piece there.
+//
+// It returns true while the move entry is open, so typed letters are not
+// taken as shortcuts.
+func (g *Game) handleKeyboardInput() bool {
+	if g.moveInputActive {
+		g.updateMoveInput()
+		return true
+	}
+
+	if IsKeyJustPressed(ebiten.KeyEnter) || IsKeyJustPressed(ebiten.KeyNumpadEnter) {
+		g.moveInputActive = true
+		g.moveInput = ""
+		g.announce("Enter a move")
+		return true
+	}
+
+	if g.handleHistoryKeys() {
+		return false
+	}
+	g.updateKeyCursor()
+	return false
+}
+
+// updateMoveInput edits the move entry.
+func (g *Game) updateMoveInput() {
+	switch {
+	case IsKeyJustPressed(ebiten.KeyEscape):
+		g.closeMoveInput()
+		return
+	case IsKeyJustPressed(ebiten.KeyBackspace) && g.moveInput != "":
+		g.moveInput = g.moveInput[:len(g.moveInput)-1]
+	case IsKeyJustPressed(ebiten.KeyTab):
+		g.completeMoveInput()
+	case IsKeyJustPressed(ebiten.KeyEnter) || IsKeyJustPressed(ebiten.KeyNumpadEnter):
+		g.submitMoveInput()
+		return
+	}
+
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if len(g.moveInput) < maxMoveInput && r < 128 && r > ' ' {
+			g.moveInput += string(r)
+		}
+	}
+}
+
+// closeMoveInput closes the move entry.
+func (g *Game) closeMoveInput() {
+	g.moveInputActive = false
+	g.moveInput = ""
+}
+
+// completeMoveInput extends the entry to the longest text shared by the
+// matching moves, or to the move itself when only one matches.
+func (g *Game) completeMoveInput() {
+	sans := g.MoveInputCandidates()
+	if len(sans) == 0 {
+		return
+	}
+	prefix := sans[0]
+	for _, san := range sans[1:] {
+		for !strings.HasPrefix(san, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > len(g.moveInput) {
+		g.moveInput = prefix
+	}
+}
+
+// submitMoveInput plays the entered move if it names exactly one legal move.
+// An empty entry just closes.
+func (g *Game) submitMoveInput() {
+	if g.moveInput == "" {
+		g.closeMoveInput()
+		return
+	}
+	if g.Browsing() {
+		g.rejectMoveInput("Return to the last move to play")
+		return
+	}
+	if !g.humanToMove() {
+		g.rejectMoveInput("Not your turn")
+		return
+	}
+
+	moves := g.matchMoveInput(g.moveInput)
+	switch len(moves) {
+	case 0:
+		g.rejectMoveInput("No legal move " + g.moveInput)
+		return
+	case 1:
+	default:
+		g.rejectMoveInput("Ambiguous move " + g.moveInput)
+		return
+	}
+
+	g.closeMoveInput()
+	g.clearSelection()
+	g.playerMove(moves[0])
+}
+
+// rejectMoveInput tells the player why the entered move was not played.
+func (g *Game) rejectMoveInput(message string) {
+	g.feedback.OnMoveInputRejected(message)
+	g.announce(message)
+}
+
+// MoveInputCandidates returns the SAN of the legal moves matching the move
+// entry so far, for auto-completion.
+func (g *Game) MoveInputCandidates() []string {
+	var sans []string
+	for _, m := range g.matchMoveInput(g.moveInput) {
+		sans = append(sans, g.moveToSAN(m))
+	}
+	return sans
+}
+
+// MoveInput returns the text of the move entry, and whether it is open.
+func (g *Game) MoveInput() (string, bool) {
+	return g.moveInput, g.moveInputActive
+}
+
+// matchMoveInput returns the legal moves whose SAN or coordinate notation
+// starts with text. A move matched exactly is returned alone, so "Nf3" is
+// not ambiguous with "Nf3+" and "e8=Q" not with the other promotions.
+// Capture and check marks are optional, piece letters may be typed in lower
+// case except the bishop (which would be the b-file), and 0 may stand for
+// the O of castling.
+func (g *Game) matchMoveInput(text string) []board.Move {
+	text = normalizeMoveText(text)
+	if text == "" {
+		return nil
+	}
+
+	var matches []board.Move
+	moves := g.position.GenerateLegalMoves()
+	for i := 0; i < moves.Len(); i++ {
+		m := moves.Get(i)
+		san := normalizeMoveText(g.moveToSAN(m))
+		coords := m.String()
+		if san == text || coords == strings.ToLower(text) {
+			return []board.Move{m}
+		}
+		if strings.HasPrefix(san, text) || strings.HasPrefix(coords, strings.ToLower(text)) {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// normalizeMoveText drops the optional marks of a move and capitalizes
+// piece letters and castling.
+func normalizeMoveText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case 'x', '+', '#', '=', '-', '!', '?':
+			return -1
+		case '0', 'o':
+			return 'O'
+		}
+		return r
+	}, s)
+	if s != "" && strings.ContainsRune("kqrn", rune(s[0])) {
+		s = strings.ToUpper(s[:1]) + s[1:]
+	}
+	// A promotion piece comes last, after the rank
+	if n := len(s); n >= 3 && (s[n-2] == '8' || s[n-2] == '1') && strings.ContainsRune("qrbn", rune(s[n-1])) {
+		s = s[:n-1] + strings.ToUpper(s[n-1:])
+	}
+	return s
+}
+
+// humanToMove returns true if the player may move now.
+func (g *Game) humanToMove() bool {
+	switch {
+	case g.gameOver, g.aiThinking, g.mode == ModeComputerVsComputer, g.Browsing():
+		return false
+	case g.rush != nil:
+		return g.rushAt.IsZero()
+	}
+	return g.mode != ModeHumanVsComputer || g.position.SideToMove == g.playerColor
+}
+
+// updateKeyCursor moves the keyboard square cursor with the arrow keys, as
+// seen on the screen, and selects or moves with Space.
+func (g *Game) updateKeyCursor() {
+	df, dr := 0, 0
+	switch {
+	case IsKeyJustPressed(ebiten.KeyArrowUp):
+		dr = 1
+	case IsKeyJustPressed(ebiten.KeyArrowDown):
+		dr = -1
+	case IsKeyJustPressed(ebiten.KeyArrowLeft):
+		df = -1
+	case IsKeyJustPressed(ebiten.KeyArrowRight):
+		df = 1
+	}
+	if df != 0 || dr != 0 {
+		g.moveKeyCursor(df, dr)
+		return
+	}
+
+	if g.keyCursor == board.NoSquare {
+		return
+	}
+	if IsKeyJustPressed(ebiten.KeyEscape) {
+		g.keyCursor = board.NoSquare
+		g.clearSelection()
+		return
+	}
+	if IsKeyJustPressed(ebiten.KeySpace) && g.humanToMove() {
+		g.selectWithKeyCursor()
+	}
+}
+
+// moveKeyCursor moves the cursor by files and ranks as seen on the screen.
+// The cursor first appears on the king of the side to move.
+func (g *Game) moveKeyCursor(df, dr int) {
+	if g.keyCursor == board.NoSquare {
+		g.keyCursor = g.position.KingSquare[g.position.SideToMove]
+	} else {
+		if g.renderer.IsFlipped() {
+			df, dr = -df, -dr
+		}
+		file := min(max(g.keyCursor.File()+df, 0), 7)
+		rank := min(max(g.keyCursor.Rank()+dr, 0), 7)
+		g.keyCursor = board.NewSquare(file, rank)
+	}
+
+	text := g.keyCursor.String()
+	if p := g.position.PieceAt(g.keyCursor); p != board.NoPiece {
+		side := "White"
+		if p.Color() == board.Black {
+			side = "Black"
+		}
+		text += ", " + side + " " + strings.ToLower(p.Type().String())
+	}
+	g.announce(text)
+}
+
+// selectWithKeyCursor selects the player's piece under the cursor, or moves
+// the selected piece to the cursor, like a click there.
+func (g *Game) selectWithKeyCursor() {
+	sq := g.keyCursor
+	if piece := g.position.PieceAt(sq); piece != board.NoPiece && piece.Color() == g.position.SideToMove {
+		g.selectSquare(sq)
+		g.announce("Selected " + sq.String())
+		return
+	}
+	if g.selectedSquare != board.NoSquare {
+		if move := g.findMove(g.selectedSquare, sq); move != board.NoMove {
+			g.playerMove(move)
+			return
+		}
+		reason := g.determineInvalidMoveReason(g.selectedSquare, sq)
+		detail := ""
+		if reason == ReasonPiecePinned {
+			detail = g.pinExplanation(g.selectedSquare)
+		}
+		g.feedback.OnInvalidMove(g.selectedSquare, sq, reason, detail)
+	}
+	g.clearSelection()
+}
+
+// KeyCursor returns the square of the keyboard cursor (NoSquare if hidden).
+func (g *Game) KeyCursor() board.Square {
+	return g.keyCursor
+}
@@ -0,0 +1,516 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/storage"
+)
+
+// Match modal dimensions
+const (
+	MatchWidth  = 400
+	MatchHeight = 600
+	MatchPadX   = 24
+	MatchPadY   = 20
+)
+
+// Move delay slider range, in milliseconds
+const (
+	matchMaxDelayMs  = 3000
+	matchDelayStepMs = 100
+)
+
+// defaultMatchDelay is the pause between engine moves, so the game can be
+// followed.
+const defaultMatchDelay = 500 * time.Millisecond
+
+// EngineConfig is the engine setup of one side in a Computer vs Computer game.
+type EngineConfig struct {
+	Eval       EvalMode
+	Difficulty Difficulty
+}
+
+// Name returns the configuration's name, e.g. "NNUE Hard".
+func (c EngineConfig) Name() string {
+	eval := "Classical"
+	if c.Eval == EvalNNUE {
+		eval = "NNUE"
+	}
+	return eval + " " + [...]string{"Easy", "Medium", "Hard"}[c.Difficulty]
+}
+
+// engineDifficulty returns the engine difficulty of a UI difficulty.
+func engineDifficulty(d Difficulty) engine.Difficulty {
+	switch d {
+	case DifficultyEasy:
+		return engine.Easy
+	case DifficultyHard:
+		return engine.Hard
+	}
+	return engine.Medium
+}
+
+// liveSearch is the latest search report of the engine, written by the
+// search goroutine and shown by the panel.
+type liveSearch struct {
+	mu   sync.Mutex
+	info engine.SearchInfo
+	side board.Color     // Side the engine searches for
+	pos  *board.Position // Position searched, for the PV
+}
+
+// set stores a search report.
+func (ls *liveSearch) set(info engine.SearchInfo) {
+	ls.mu.Lock()
+	ls.info = info
+	ls.mu.Unlock()
+}
+
+// start clears the report for a search of pos.
+func (ls *liveSearch) start(pos *board.Position) {
+	ls.mu.Lock()
+	ls.info = engine.SearchInfo{}
+	ls.side = pos.SideToMove
+	ls.pos = pos.Copy()
+	ls.mu.Unlock()
+}
+
+// pvSAN returns up to n moves of the latest PV in SAN.
+func (ls *liveSearch) pvSAN(n int) string {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.pos == nil {
+		return ""
+	}
+	pos := ls.pos.Copy()
+	var sans []string
+	for _, m := range ls.info.PV[:min(n, len(ls.info.PV))] {
+		sans = append(sans, m.ToSAN(pos))
+		pos.MakeMove(m)
+	}
+	return strings.Join(sans, " ")
+}
+
+// whiteScore returns the latest score from White's view, and the depth it
+// was found at (0 = no report yet).
+func (ls *liveSearch) whiteScore() (score, depth int) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	score = ls.info.Score
+	if ls.side == board.Black {
+		score = -score
+	}
+	return score, ls.info.Depth
+}
+
+// matchScore is the score between the configurations of an engine match.
+type matchScore struct {
+	white, black float64 // Points of the configurations playing White and Black now
+	games        int
+}
+
+// MatchModal sets up a Computer vs Computer game: the engine configuration of
+// each side, the delay between moves and the adjudication rules.
+type MatchModal struct {
+	visible      bool
+	needsCapture bool // Set true when opening to capture background
+
+	// Position (centered on screen)
+	x, y int
+
+	// Widgets, by color
+	evalGroups  [2]*ButtonGroup
+	levelGroups [2]*ButtonGroup
+	delaySlider *Slider
+	startBtn    *ModalButton
+	closeBtn    *ModalButton
+
+	// Adjudication rules
+	resignScoreInput *TextInput
+	resignMovesInput *TextInput
+	drawScoreInput   *TextInput
+	drawMovesInput   *TextInput
+	drawFromInput    *TextInput
+	tablebaseCheck   *Checkbox
+	shown            storage.Adjudication // Rules the modal opened with, kept for invalid entries
+
+	onStart func(white, black EngineConfig, delay time.Duration, adj storage.Adjudication)
+}
+
+// NewMatchModal creates a new engine match modal.
+func NewMatchModal() *MatchModal {
+	mm := &MatchModal{}
+	mm.x = (ScreenWidth - MatchWidth) / 2
+	mm.y = (ScreenHeight - MatchHeight) / 2
+
+	contentX := mm.x + MatchPadX
+	contentW := MatchWidth - MatchPadX*2
+	for c := range 2 {
+		y := mm.y + 84 + c*112
+		mm.evalGroups[c] = NewButtonGroup(contentX, y, []string{"Classical", "NNUE"}, 0, contentW/2, 30)
+		mm.levelGroups[c] = NewButtonGroup(contentX, y+38, []string{"Easy", "Medium", "Hard"}, 1, contentW/3, 30)
+	}
+	mm.delaySlider = NewSlider(contentX+8, mm.y+330, contentW-16, 0, matchMaxDelayMs, matchDelayStepMs,
+		int(defaultMatchDelay/time.Millisecond))
+
+	scoreX, movesX := contentX+130, contentX+254
+	rowY := mm.y + 400
+	mm.resignScoreInput = NewTextInput(scoreX, rowY, 64, 32, "cp", 5)
+	mm.resignMovesInput = NewTextInput(movesX, rowY, 48, 32, "", 3)
+	mm.drawScoreInput = NewTextInput(scoreX, rowY+44, 64, 32, "cp", 5)
+	mm.drawMovesInput = NewTextInput(movesX, rowY+44, 48, 32, "", 3)
+	mm.drawFromInput = NewTextInput(scoreX, rowY+88, 64, 32, "", 3)
+	mm.tablebaseCheck = NewCheckbox(movesX-50, rowY+92, "Tablebases", true)
+
+	btnW, btnH := 100, 38
+	btnY := mm.y + MatchHeight - MatchPadY - btnH
+	mm.closeBtn = NewModalButton(mm.x+MatchWidth-MatchPadX-btnW*2-12, btnY, btnW, btnH, "Close", false, nil)
+	mm.startBtn = NewModalButton(mm.x+MatchWidth-MatchPadX-btnW, btnY, btnW, btnH, "Start", true, nil)
+	mm.closeBtn.OnClick = mm.Hide
+	mm.startBtn.OnClick = mm.handleStart
+	return mm
+}
+
+// Show opens the modal with the configurations and adjudication rules of the
+// last match. onStart starts a game between the chosen configurations.
+func (mm *MatchModal) Show(white, black EngineConfig, delay time.Duration, adj storage.Adjudication,
+	onStart func(white, black EngineConfig, delay time.Duration, adj storage.Adjudication)) {
+	mm.visible = true
+	mm.needsCapture = true
+	mm.onStart = onStart
+	for c, cfg := range []EngineConfig{white, black} {
+		mm.evalGroups[c].Selected = int(cfg.Eval)
+		mm.levelGroups[c].Selected = int(cfg.Difficulty)
+	}
+	mm.delaySlider.Value = int(delay / time.Millisecond)
+
+	mm.shown = adj
+	mm.resignScoreInput.Value = strconv.Itoa(adj.ResignScore)
+	mm.resignMovesInput.Value = strconv.Itoa(adj.ResignMoves)
+	mm.drawScoreInput.Value = strconv.Itoa(adj.DrawScore)
+	mm.drawMovesInput.Value = strconv.Itoa(adj.DrawMoves)
+	mm.drawFromInput.Value = strconv.Itoa(adj.DrawFrom)
+	mm.tablebaseCheck.Checked = adj.Tablebases
+}
+
+// Hide closes the modal.
+func (mm *MatchModal) Hide() {
+	mm.visible = false
+}
+
+// IsVisible returns true if the modal is visible.
+func (mm *MatchModal) IsVisible() bool {
+	return mm.visible
+}
+
+// config returns the configuration chosen for a color.
+func (mm *MatchModal) config(c board.Color) EngineConfig {
+	return EngineConfig{Eval: EvalMode(mm.evalGroups[c].Selected), Difficulty: Difficulty(mm.levelGroups[c].Selected)}
+}
+
+// delay returns the chosen delay between moves.
+func (mm *MatchModal) delay() time.Duration {
+	return time.Duration(mm.delaySlider.Value) * time.Millisecond
+}
+
+// adjudication returns the chosen adjudication rules. An entry that is not
+// a number of at least 0 keeps its value from when the modal opened.
+func (mm *MatchModal) adjudication() storage.Adjudication {
+	number := func(ti *TextInput, shown int) int {
+		n, err := strconv.Atoi(strings.TrimSpace(ti.Value))
+		if err != nil || n < 0 {
+			return shown
+		}
+		return n
+	}
+	return storage.Adjudication{
+		ResignScore: number(mm.resignScoreInput, mm.shown.ResignScore),
+		ResignMoves: number(mm.resignMovesInput, mm.shown.ResignMoves),
+		DrawScore:   number(mm.drawScoreInput, mm.shown.DrawScore),
+		DrawMoves:   number(mm.drawMovesInput, mm.shown.DrawMoves),
+		DrawFrom:    number(mm.drawFromInput, mm.shown.DrawFrom),
+		Tablebases:  mm.tablebaseCheck.Checked,
+	}
+}
+
+// inputs returns the modal's text inputs.
+func (mm *MatchModal) inputs() []*TextInput {
+	return []*TextInput{mm.resignScoreInput, mm.resignMovesInput, mm.drawScoreInput, mm.drawMovesInput, mm.drawFromInput}
+}
+
+// handleStart starts the match.
+func (mm *MatchModal) handleStart() {
+	mm.Hide()
+	if mm.onStart != nil {
+		mm.onStart(mm.config(board.White), mm.config(board.Black), mm.delay(), mm.adjudication())
+	}
+}
+
+// Update handles input for the match modal.
+func (mm *MatchModal) Update(input *InputHandler) bool {
+	if !mm.visible {
+		return false
+	}
+
+	if IsKeyJustPressed(ebiten.KeyEscape) {
+		mm.Hide()
+		return true
+	}
+	if IsKeyJustPressed(ebiten.KeyEnter) {
+		mm.handleStart()
+		return true
+	}
+
+	for c := range 2 {
+		mm.evalGroups[c].Update(input)
+		mm.levelGroups[c].Update(input)
+	}
+	mm.delaySlider.Update(input)
+	for _, ti := range mm.inputs() {
+		ti.Update(input)
+	}
+	mm.tablebaseCheck.Update(input)
+	mm.startBtn.Update(input)
+	mm.closeBtn.Update(input)
+
+	// Modal consumes all input
+	return true
+}
+
+// AnyButtonHovered returns true if any button in the modal is hovered.
+func (mm *MatchModal) AnyButtonHovered() bool {
+	if !mm.visible {
+		return false
+	}
+	for c := range 2 {
+		if mm.evalGroups[c].hovered >= 0 || mm.levelGroups[c].hovered >= 0 {
+			return true
+		}
+	}
+	for _, ti := range mm.inputs() {
+		if ti.hovered {
+			return true
+		}
+	}
+	return mm.delaySlider.IsHovered() || mm.tablebaseCheck.hovered || mm.startBtn.IsHovered() || mm.closeBtn.IsHovered()
+}
+
+// Draw renders the match modal.
+func (mm *MatchModal) Draw(screen *ebiten.Image, glass *GlassEffect) {
+	if !mm.visible {
+		return
+	}
+
+	// Capture background once when modal first opens (fixes flicker)
+	if mm.needsCapture && glass != nil && glass.IsEnabled() {
+		glass.CaptureForModal(screen, 3.0)
+		mm.needsCapture = false
+	}
+
+	if glass != nil && glass.IsEnabled() {
+		glass.DrawModalBackground(screen, 0.4)
+	} else {
+		vector.DrawFilledRect(screen, 0, 0, scaleF(ScreenWidth), scaleF(ScreenHeight), modalOverlay, false)
+	}
+
+	// Modal background, border and header
+	vector.DrawFilledRect(screen, scaleF(mm.x), scaleF(mm.y), scaleF(MatchWidth), scaleF(MatchHeight), modalBg, false)
+	vector.StrokeRect(screen, scaleF(mm.x), scaleF(mm.y), scaleF(MatchWidth), scaleF(MatchHeight), float32(UIScale*2), modalBorder, false)
+	vector.DrawFilledRect(screen, scaleF(mm.x), scaleF(mm.y), scaleF(MatchWidth), scaleF(44), modalHeader, false)
+	mm.drawTitle(screen)
+
+	contentX := mm.x + MatchPadX
+	rightX := mm.x + MatchWidth - MatchPadX
+
+	drawText(screen, "Two engines play each other on this computer.", contentX, mm.y+56, textMuted)
+	for c, label := range []string{"White", "Black"} {
+		drawText(screen, label, contentX, mm.evalGroups[c].Y-22, textSecondary)
+		mm.evalGroups[c].Draw(screen)
+		mm.levelGroups[c].Draw(screen)
+	}
+
+	drawText(screen, "Move delay", contentX, mm.delaySlider.Y-30, textSecondary)
+	drawTextRight(screen, fmt.Sprintf("%.1f s", mm.delay().Seconds()), rightX, mm.delaySlider.Y-30, textPrimary)
+	mm.delaySlider.Draw(screen)
+
+	// Adjudication rules, in rows of "<label> [cp] cp for [moves] moves"
+	drawText(screen, "Adjudication", contentX, mm.resignScoreInput.Y-30, textSecondary)
+	rows := []struct {
+		label        string
+		score, moves *TextInput
+	}{
+		{"Win at", mm.resignScoreInput, mm.resignMovesInput},
+		{"Draw within", mm.drawScoreInput, mm.drawMovesInput},
+	}
+	for _, r := range rows {
+		textY := r.score.Y + 7
+		drawText(screen, r.label, contentX, textY, textMuted)
+		r.score.Draw(screen)
+		drawText(screen, "cp for", r.score.X+r.score.W+10, textY, textMuted)
+		r.moves.Draw(screen)
+		drawText(screen, "moves", r.moves.X+r.moves.W+10, textY, textMuted)
+	}
+	drawText(screen, "Draws from move", contentX, mm.drawFromInput.Y+7, textMuted)
+	mm.drawFromInput.Draw(screen)
+	mm.tablebaseCheck.Draw(screen)
+
+	mm.closeBtn.Draw(screen)
+	mm.startBtn.Draw(screen)
+}
+
+// drawTitle draws the modal title.
+func (mm *MatchModal) drawTitle(screen *ebiten.Image) {
+	face := GetBoldFace()
+	if face == nil {
+		return
+	}
+
+	title := "Computer vs Computer"
+	w, h := MeasureText(title, face)
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(scaleD(mm.x)+scaleD(MatchWidth)/2-w/2, scaleD(mm.y)+scaleD(22)-h/2)
+	op.ColorScale.ScaleWithColor(textPrimary)
+	text.Draw(screen, title, face, op)
+}
+
+// ShowEngineMatch opens the Computer vs Computer setup.
+func (g *Game) ShowEngineMatch() {
+	g.matchModal.Show(g.matchConfigs[board.White], g.matchConfigs[board.Black], g.matchDelay,
+		g.prefs.Adjudication, g.startEngineMatch)
+}
+
+// startEngineMatch switches to Computer vs Computer and starts a game
+// between two engine configurations, adjudicated by the given rules.
+func (g *Game) startEngineMatch(white, black EngineConfig, delay time.Duration, adj storage.Adjudication) {
+	g.matchConfigs = [2]EngineConfig{white, black}
+	g.matchDelay = delay
+	if adj != g.prefs.Adjudication {
+		g.prefs.Adjudication = adj
+		g.savePreferences()
+	}
+	g.mode = ModeComputerVsComputer
+	g.renderer.SetFlipped(false)
+	g.startEngineMatchGame()
+}
+
+// startEngineMatchGame starts a new engine game from the standard position.
+func (g *Game) startEngineMatchGame() {
+	g.startFEN = ""
+	g.resetGame(board.NewPosition())
+	g.matchGame = true
+	g.refreshMatchScore()
+	g.startAIThinking()
+}
+
+// nextEngineMatchGame starts the next game of the match with colors swapped,
+// so each configuration plays both sides.
+func (g *Game) nextEngineMatchGame() {
+	g.matchConfigs[board.White], g.matchConfigs[board.Black] = g.matchConfigs[board.Black], g.matchConfigs[board.White]
+	g.startEngineMatchGame()
+}
+
+// applyMatchConfig sets up the engine for the side to move. The sides share
+// the engine's workers, so the hash table is cleared between configurations
+// to keep one side from using the other's search.
+func (g *Game) applyMatchConfig(side board.Color) {
+	cfg := g.matchConfigs[side]
+	if g.matchConfigs[board.White] != g.matchConfigs[board.Black] {
+		g.engine.Clear()
+	}
+	g.engine.SetDifficulty(engineDifficulty(cfg.Difficulty))
+	if cfg.Eval == EvalNNUE {
+		g.loadNNUENetworks()
+	} else {
+		g.engine.SetUseNNUE(false)
+	}
+}
+
+// restoreEngineConfig sets the engine back to the player's settings after
+// an engine match.
+func (g *Game) restoreEngineConfig() {
+	g.SetDifficulty(g.difficulty)
+	g.setEvalMode(g.evalMode)
+}
+
+// updateEngineMatch starts the next engine move once the move delay has
+// passed, unless the game is adjudicated first.
+func (g *Game) updateEngineMatch() {
+	if g.mode != ModeComputerVsComputer || g.matchMoveAt.IsZero() || time.Now().Before(g.matchMoveAt) {
+		return
+	}
+	if g.adjudicationPending() {
+		return
+	}
+	g.matchMoveAt = time.Time{}
+	if !g.gameOver && !g.aiThinking {
+		g.startAIThinking()
+	}
+}
+
+// recordEngineMatch saves the result of a finished engine game and the game
+// itself.
+func (g *Game) recordEngineMatch() {
+	if !g.matchGame {
+		return
+	}
+	g.matchGame = false
+
+	result := storage.EngineMatchResult{
+		White:  g.matchConfigs[board.White].Name(),
+		Black:  g.matchConfigs[board.Black].Name(),
+		Result: g.pgnResult(),
+		Plies:  len(g.moveHistory),
+		Date:   time.Now(),
+	}
+	if g.storage != nil {
+		if err := g.storage.SaveEngineMatchResult(result); err != nil {
+			log.Printf("Warning: Failed to save engine match result: %v", err)
+		}
+	}
+	if _, err := g.ExportPGN(); err != nil {
+		log.Printf("Warning: Failed to save engine game: %v", err)
+	}
+	g.refreshMatchScore()
+}
+
+// refreshMatchScore totals the games between the current configurations.
+func (g *Game) refreshMatchScore() {
+	g.matchScore = matchScore{}
+	if g.storage == nil {
+		return
+	}
+	results, err := g.storage.EngineMatchResults()
+	if err != nil {
+		log.Printf("Warning: Failed to load engine match results: %v", err)
+		return
+	}
+	g.matchScore.white, g.matchScore.black, g.matchScore.games = storage.MatchScore(results,
+		g.matchConfigs[board.White].Name(), g.matchConfigs[board.Black].Name())
+}
+
+// MatchScore returns the points of the current configurations in their games
+// against each other, by color in the current game.
+func (g *Game) MatchScore() (white, black float64, games int) {
+	return g.matchScore.white, g.matchScore.black, g.matchScore.games
+}
+
+// MatchConfig returns the engine configuration of a side in an engine match.
+func (g *Game) MatchConfig(c board.Color) EngineConfig {
+	return g.matchConfigs[c]
+}
+
+// LiveEval returns the engine's current evaluation from White's view, and
+// its depth (0 = none yet).
+func (g *Game) LiveEval() (score, depth int) {
+	return g.liveSearch.whiteScore()
+}
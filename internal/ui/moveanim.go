@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// moveAnimDuration is how long a piece takes to slide to its new square.
+const moveAnimDuration = 150 * time.Millisecond
+
+// pieceSlide is a piece sliding from one square to another.
+type pieceSlide struct {
+	piece    board.Piece
+	from, to board.Square
+}
+
+// moveAnimation slides the pieces of a move (two when castling) to their
+// squares, while a captured piece fades out, or back in when the move is
+// taken back.
+type moveAnimation struct {
+	start  time.Time
+	slides [2]pieceSlide
+	n      int // Slides in use
+
+	fadePiece board.Piece // Captured piece, or NoPiece
+	fadeSq    board.Square
+	fadeIn    bool // The capture is taken back
+}
+
+// newMoveAnimation returns the animation of m played (or taken back) in
+// before, the position the move is played from.
+func newMoveAnimation(before *board.Position, m board.Move, back bool) *moveAnimation {
+	a := &moveAnimation{start: time.Now(), fadePiece: board.NoPiece}
+	add := func(piece board.Piece, from, to board.Square) {
+		if back {
+			from, to = to, from
+		}
+		a.slides[a.n] = pieceSlide{piece, from, to}
+		a.n++
+	}
+
+	from, to := m.From(), m.To()
+	add(before.PieceAt(from), from, to)
+	if m.IsCastling() {
+		kingSide := m.IsKingSideCastling()
+		rookFrom := before.CastlingRook(before.SideToMove, kingSide)
+		rookTo := board.NewSquare(3, from.Rank())
+		if kingSide {
+			rookTo = board.NewSquare(5, from.Rank())
+		}
+		add(before.PieceAt(rookFrom), rookFrom, rookTo)
+	}
+
+	if m.IsCapture(before) {
+		a.fadeSq = to
+		if m.IsEnPassant() {
+			a.fadeSq = board.NewSquare(to.File(), from.Rank())
+		}
+		a.fadePiece = before.PieceAt(a.fadeSq)
+		a.fadeIn = back
+	}
+	return a
+}
+
+// progress returns how far the animation is, from 0 to 1.
+func (a *moveAnimation) progress() float64 {
+	return min(float64(time.Since(a.start))/float64(moveAnimDuration), 1)
+}
+
+// covers returns true if the piece on sq in the position shown is drawn by
+// the animation instead.
+func (a *moveAnimation) covers(sq board.Square) bool {
+	for _, s := range a.slides[:a.n] {
+		if s.to == sq {
+			return true
+		}
+	}
+	return a.fadePiece != board.NoPiece && a.fadeSq == sq
+}
+
+// easeOutCubic starts fast and slows down as the piece lands.
+func easeOutCubic(t float64) float64 {
+	u := 1 - t
+	return 1 - u*u*u
+}
+
+// SetMoveAnimations enables or disables sliding moves. While disabled,
+// AnimateMove does nothing and pieces snap to their squares.
+func (r *Renderer) SetMoveAnimations(enabled bool) {
+	r.animateMoves = enabled
+	if !enabled {
+		r.moveAnim = nil
+	}
+}
+
+// AnimateMove slides the pieces of m to their squares. before is the
+// position m is played from; call it before the game position changes.
+// With back set the move is taken back instead: the pieces slide from the
+// move's destination to its origin and a captured piece fades in.
+func (r *Renderer) AnimateMove(before *board.Position, m board.Move, back bool) {
+	if !r.animateMoves || m == board.NoMove {
+		return
+	}
+	r.moveAnim = newMoveAnimation(before, m, back)
+}
+
+// StopMoveAnimation ends a running move animation, leaving the pieces on
+// their squares.
+func (r *Renderer) StopMoveAnimation() {
+	r.moveAnim = nil
+}
+
+// UpdateMoveAnimation ends the move animation once it is over. Call once
+// per frame.
+func (r *Renderer) UpdateMoveAnimation() {
+	if r.moveAnim != nil && r.moveAnim.progress() >= 1 {
+		r.moveAnim = nil
+	}
+}
+
+// drawMoveAnimation draws the captured piece fading and the moving pieces
+// on their way.
+func (r *Renderer) drawMoveAnimation(screen *ebiten.Image) {
+	a := r.moveAnim
+	t := a.progress()
+	if a.fadePiece != board.NoPiece {
+		alpha := 1 - t
+		if a.fadeIn {
+			alpha = t
+		}
+		x, y := r.SquareToScreen(a.fadeSq)
+		r.sprites.DrawPieceFaded(screen, a.fadePiece, int(r.s(x)), int(r.s(y)), float32(alpha))
+	}
+
+	e := easeOutCubic(t)
+	for _, s := range a.slides[:a.n] {
+		fx, fy := r.SquareToScreen(s.from)
+		tx, ty := r.SquareToScreen(s.to)
+		x := float64(fx) + float64(tx-fx)*e
+		y := float64(fy) + float64(ty-fy)*e
+		r.sprites.DrawPieceAt(screen, s.piece, int(x*r.scale), int(y*r.scale))
+	}
+}
@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"github.com/hailam/chessplay/internal/eco"
+)
+
+// openingPlies is how long the opening is followed and shown: about the
+// first 15 moves of each side.
+const openingPlies = 30
+
+// updateOpening names the opening after a move, keeping the last named one
+// when the game leaves the known lines.
+func (g *Game) updateOpening() {
+	if len(g.moveHistory) > openingPlies {
+		return
+	}
+	if o, ok := eco.Lookup(g.position); ok {
+		g.opening = o
+	}
+}
+
+// Opening returns the opening of the game, if it has been named.
+func (g *Game) Opening() (eco.Opening, bool) {
+	return g.opening, g.opening.ECO != ""
+}
+
+// showOpening returns true while the opening name is shown above the moves.
+func (g *Game) showOpening() bool {
+	return g.opening.ECO != "" && len(g.moveHistory) <= openingPlies
+}
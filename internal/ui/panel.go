@@ -3,12 +3,14 @@ package ui
 import (
 	"fmt"
 	"image/color"
-	"log"
+	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
-	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/puzzle"
 )
 
 // Panel dimensions
@@ -21,6 +23,8 @@ const (
 	CollapseButtonW = 16
 	CollapseButtonH = 48
 	SectionLabelH   = 20
+	OpeningRowH     = 22 // Opening name above the moves
+	sparringInfoH   = 44 // Sparring engine report below the difficulty tabs
 )
 
 // Panel colors
@@ -48,16 +52,6 @@ var (
 	collapseButtonC = color.RGBA{60, 65, 72, 255}    // Collapse button (lighter for visibility)
 )
 
-// Button represents a clickable UI element.
-type Button struct {
-	X, Y, W, H int
-	Label      string
-	OnClick    func()
-	hovered    bool
-	pressed    bool
-	active     bool
-}
-
 // Panel represents the side panel with controls and move history.
 type Panel struct {
 	game      *Game
@@ -67,18 +61,32 @@ type Panel struct {
 	collapseBtn *Button
 	newGameBtn  *Button
 	settingsBtn *Button
-	modeTabs    []*Button // [0] = vs Human, [1] = vs Computer
-	diffTabs    []*Button // [0] = Easy, [1] = Medium, [2] = Hard
-
-	// Move history scroll
-	scrollY    int
-	maxScrollY int
-
-	// Scrollbar drag state
-	scrollDragging      bool
-	scrollDragStartY    int
-	scrollDragStartVal  int
-	scrollbarHovered    bool
+	rushBtn     *Button
+	gamesBtn    *Button
+	shareBtn    *Button
+	modeLabel   Label
+	modeTabs    *Tabs // vs Human, vs Computer, Engines
+	diffLabel   Label
+	diffTabs    *Tabs     // Easy, Medium, Hard, and Sparring where engines can run as processes
+	actionBtns  []*Button // Game actions: resign, draw offers and claims
+	navBtns     []*Button // History navigation: first, back, forward, last
+	viewTabs    *Tabs     // Moves or Explorer, when the explorer is on
+	sourceTabs  *Tabs     // Masters or Lichess games in the explorer
+	tooltip     Tooltip
+	hintArea    Rect // Hint lines drawn last frame (empty without hints)
+	chancesArea Rect // Practical chances line drawn last frame (empty without)
+
+	resignArmedAt time.Time // First click on Resign, awaiting confirmation
+
+	// Scrolled move history and coach commentary
+	history    ScrollList
+	coach      ScrollList
+	coachCount int // Comments shown last frame, to follow new ones
+
+	// Opening explorer tab (see explorer.go)
+	explorerView bool // Shown in place of the move list
+	explorerList ScrollList
+	explorerRows []*Button // Moves drawn last frame
 
 	// HiDPI scaling
 	scale float64
@@ -119,248 +127,194 @@ func NewPanel(g *Game) *Panel {
 // createButtons initializes all panel buttons.
 func (p *Panel) createButtons() {
 	// Collapse/expand button - integrated tab at panel edge
-	tabY := (ScreenHeight - CollapseButtonH) / 2 // Vertically centered
+	collapseX := BoardSize // Flush with panel left edge
 	if p.collapsed {
-		p.collapseBtn = &Button{
-			X: BoardSize + 2,
-			Y: tabY,
-			W: CollapseButtonW, H: CollapseButtonH,
-			OnClick: func() { p.toggleCollapse() },
-		}
-	} else {
-		p.collapseBtn = &Button{
-			X: BoardSize, // Flush with panel left edge
-			Y: tabY,
-			W: CollapseButtonW, H: CollapseButtonH,
-			OnClick: func() { p.toggleCollapse() },
-		}
+		collapseX = BoardSize + 2
+	}
+	p.collapseBtn = &Button{
+		Rect:    Rect{X: collapseX, Y: (ScreenHeight - CollapseButtonH) / 2, W: CollapseButtonW, H: CollapseButtonH},
+		Tooltip: "Hide the panel",
+		OnClick: p.toggleCollapse,
+	}
+	if p.collapsed {
+		p.collapseBtn.Tooltip = "Show the panel"
 	}
 
 	// Content area - full width, collapse button doesn't take space
-	contentX := BoardSize + PanelPadding
-	contentW := PanelWidth - PanelPadding*2
+	content := Rect{X: BoardSize + PanelPadding, Y: PanelPadding + 8, W: PanelWidth - PanelPadding*2, H: ButtonHeight}
 
 	// New Game button (full width, prominent)
-	newGameY := PanelPadding + 8
-	p.newGameBtn = &Button{
-		X: contentX, Y: newGameY,
-		W: contentW, H: ButtonHeight,
-		Label:   "New Game",
-		OnClick: p.game.NewGameAction,
+	p.newGameBtn = &Button{Rect: content, Label: "New Game", Tooltip: "Start over in the current mode", OnClick: p.game.NewGameAction}
+
+	// Settings, Games, Rush and Share buttons (below New Game), Settings the widest
+	row := content.Below(8, ButtonHeight-6)
+	settings := row
+	settings.W = row.W - (row.W-24)/4*3 - 24
+	others := Rect{X: settings.X + settings.W + 8, Y: row.Y, W: row.W - settings.W - 8, H: row.H}.Columns(3, 8)
+	p.settingsBtn = &Button{Rect: settings, Label: "Settings", Tooltip: "Profile, board, sound, hints and engine settings", OnClick: p.game.ShowSettings}
+	p.gamesBtn = &Button{Rect: others[0], Label: "Games", Tooltip: "Search and replay saved games", OnClick: p.game.ShowGameSearch}
+	p.rushBtn = &Button{Rect: others[1], Label: "Rush", Tooltip: "Solve as many puzzles as you can against the clock", OnClick: p.game.ShowRush}
+	p.shareBtn = &Button{
+		Rect:    others[2],
+		Label:   "Share",
+		Tooltip: "Copy a link to this position; Shift+click for a Lichess analysis link",
+		// Shift+click shares a Lichess analysis URL instead
+		OnClick: func() { p.game.ShareAction(IsKeyPressed(ebiten.KeyShift)) },
 	}
 
-	// Settings button (below New Game)
-	settingsY := newGameY + ButtonHeight + 8
-	p.settingsBtn = &Button{
-		X: contentX, Y: settingsY,
-		W: contentW, H: ButtonHeight - 6,
-		Label:   "Settings",
-		OnClick: p.game.ShowSettings,
+	// Mode section: label + tabs
+	modeLabelY := row.Y + row.H + SectionSpacing - 8
+	p.modeLabel = Label{X: content.X, Y: modeLabelY, Text: "Game Mode", Color: textMuted}
+	p.modeTabs = NewTabs(Rect{X: content.X, Y: modeLabelY + SectionLabelH, W: content.W, H: TabHeight},
+		[]string{"vs Human", "vs Computer", "Engines"},
+		func() int { return int(p.game.GameMode()) },
+		func(i int) {
+			if GameMode(i) == ModeComputerVsComputer {
+				p.game.ShowEngineMatch()
+				return
+			}
+			p.game.SetModeAction(GameMode(i))
+		})
+	setTooltips(p.modeTabs.Buttons, modeTooltips)
+
+	// Difficulty section: label + tabs (only visible in vs Computer mode).
+	// The last tab, Sparring, plays the external engine chosen in Settings.
+	diffLabelY := p.modeTabs.Rect().Y + TabHeight + SectionSpacing
+	p.diffLabel = Label{X: content.X, Y: diffLabelY, Text: "Difficulty", Color: textMuted}
+	levels := []string{"Easy", "Medium", "Hard"}
+	if engineProcessSupported {
+		levels = append(levels, "Sparring")
+	}
+	sparringTab := 3
+	p.diffTabs = NewTabs(Rect{X: content.X, Y: diffLabelY + SectionLabelH, W: content.W, H: TabHeight - 2},
+		levels,
+		func() int {
+			if p.game.Sparring() {
+				return sparringTab
+			}
+			return int(p.game.Difficulty())
+		},
+		func(i int) {
+			p.game.SetSparring(i == sparringTab)
+			if i != sparringTab {
+				p.game.SetDifficulty(Difficulty(i))
+			}
+		})
+	setTooltips(p.diffTabs.Buttons, difficultyTooltips)
+
+	// History navigation, placed next to the move list label when drawn
+	p.navBtns = []*Button{
+		{Label: "«", Tooltip: "Start position (Home)", OnClick: p.game.HistoryFirstAction},
+		{Label: "‹", Tooltip: "Previous move (Ctrl+Left)", OnClick: p.game.HistoryBackAction},
+		{Label: "›", Tooltip: "Next move (Ctrl+Right)", OnClick: p.game.HistoryForwardAction},
+		{Label: "»", Tooltip: "Current position (End)", OnClick: p.game.HistoryLastAction},
 	}
+	p.createExplorerTabs()
+}
 
-	// Mode section: label + tabs
-	modeLabelY := settingsY + ButtonHeight - 6 + SectionSpacing - 8
-	modeTabY := modeLabelY + SectionLabelH
-	tabW := contentW / 2
-	p.modeTabs = []*Button{
-		{X: contentX, Y: modeTabY, W: tabW, H: TabHeight, Label: "vs Human",
-			OnClick: func() {
-				if p.game.GameMode() != ModeHumanVsHuman {
-					p.game.ToggleModeAction()
-				}
-			}},
-		{X: contentX + tabW, Y: modeTabY, W: tabW, H: TabHeight, Label: "vs Computer",
-			OnClick: func() {
-				if p.game.GameMode() != ModeHumanVsComputer {
-					p.game.ToggleModeAction()
-				}
-			}},
+// Tab tooltips, in tab order
+var (
+	modeTooltips = []string{
+		"Two players take turns at this board",
+		"Play against the engine at the difficulty below",
+		"Watch two engine configurations play each other",
+	}
+	difficultyTooltips = []string{
+		"Searches a few moves ahead in half a second; hints can be shown on every move",
+		"Searches deeper for up to two seconds a move",
+		"Full strength, up to seven seconds a move",
+		"Play the sparring engine chosen in Settings, with the options set for it",
 	}
+)
 
-	// Difficulty section: label + tabs (only visible in vs Computer mode)
-	diffLabelY := modeTabY + TabHeight + SectionSpacing
-	diffTabY := diffLabelY + SectionLabelH
-	diffTabW := contentW / 3
-	p.diffTabs = []*Button{
-		{X: contentX, Y: diffTabY, W: diffTabW, H: TabHeight - 2, Label: "Easy",
-			OnClick: func() { p.game.SetDifficulty(DifficultyEasy) }},
-		{X: contentX + diffTabW, Y: diffTabY, W: diffTabW, H: TabHeight - 2, Label: "Medium",
-			OnClick: func() { p.game.SetDifficulty(DifficultyMedium) }},
-		{X: contentX + diffTabW*2, Y: diffTabY, W: diffTabW, H: TabHeight - 2, Label: "Hard",
-			OnClick: func() { p.game.SetDifficulty(DifficultyHard) }},
+// setTooltips gives each button the tooltip at its index.
+func setTooltips(buttons []*Button, tips []string) {
+	for i, b := range buttons {
+		if i < len(tips) {
+			b.Tooltip = tips[i]
+		}
 	}
 }
 
+// buttons returns the buttons shown in the panel.
+func (p *Panel) buttons() []*Button {
+	if p.collapsed {
+		return []*Button{p.collapseBtn}
+	}
+	btns := []*Button{p.collapseBtn, p.newGameBtn, p.settingsBtn, p.gamesBtn, p.rushBtn, p.shareBtn}
+	btns = append(btns, p.navBtns...)
+	if p.game.ExplorerEnabled() {
+		btns = append(btns, p.viewTabs.Buttons...)
+	}
+	if p.ShowingExplorer() {
+		btns = append(btns, p.sourceTabs.Buttons...)
+		btns = append(btns, p.explorerRows...)
+	}
+	btns = append(btns, p.modeTabs.Buttons...)
+	if p.game.GameMode() == ModeHumanVsComputer {
+		btns = append(btns, p.diffTabs.Buttons...)
+	}
+	return append(btns, p.actionBtns...)
+}
+
 // HandleInput processes input for the panel. Returns true if input was handled.
 func (p *Panel) HandleInput(input *InputHandler) bool {
-	mx, my := input.MousePosition()
-
-	// Always check collapse button
-	p.collapseBtn.hovered = p.isInside(mx, my, p.collapseBtn)
-	p.collapseBtn.pressed = input.IsLeftPressed() && p.collapseBtn.hovered
-
-	if input.IsLeftJustPressed() && p.collapseBtn.hovered {
-		p.collapseBtn.OnClick() // toggleCollapse handles button recreation and window resize
+	// A dragged scroll bar keeps the mouse until released
+	if !p.collapsed && (p.historyList().Update(input) || p.game.CoachEnabled() && p.coach.Update(input)) {
+		p.tooltip.Hide()
 		return true
 	}
 
-	if p.collapsed {
-		return false
-	}
-
-	// Handle scroll wheel for move history
-	_, wheelY := ebiten.Wheel()
-	if wheelY != 0 {
-		historyY := p.getHistoryStartY()
-		// Check if mouse is in move history area
-		if mx >= BoardSize && my >= historyY && my < ScreenHeight-70 {
-			p.scrollY -= int(wheelY * 30) // 30px per scroll tick
-			if p.scrollY < 0 {
-				p.scrollY = 0
-			}
-			if p.scrollY > p.maxScrollY {
-				p.scrollY = p.maxScrollY
-			}
-		}
+	if !p.collapsed {
+		p.layoutGameActions()
 	}
+	buttons := p.buttons()
+	clicked := updateButtons(input, buttons) // toggleCollapse recreates the buttons
+	p.tooltip.Update(input, buttons, p.tipAreas())
+	return clicked
+}
 
-	// Handle scrollbar drag
-	if p.maxScrollY > 0 {
-		historyY := p.getHistoryStartY()
-		maxY := ScreenHeight - 70
-		visibleHeight := maxY - historyY
-
-		// Calculate scrollbar indicator position and size
-		moves := p.game.SANHistory()
-		totalRows := (len(moves) + 1) / 2
-		rowHeight := 22
-		contentHeight := totalRows * rowHeight
-		if contentHeight == 0 {
-			p.maxScrollY = 0 // Reset scroll state when no moves
-			p.scrollY = 0
-			goto skipScrollbar
-		}
-		indicatorH := visibleHeight * visibleHeight / contentHeight
-		if indicatorH < 20 {
-			indicatorH = 20
-		}
-		scrollPct := float64(p.scrollY) / float64(p.maxScrollY)
-		indicatorY := historyY + int(scrollPct*float64(visibleHeight-indicatorH))
-		indicatorX := BoardSize + PanelWidth - 8
-
-		// Check if mouse is over scrollbar
-		p.scrollbarHovered = mx >= indicatorX && mx < indicatorX+8 &&
-			my >= indicatorY && my < indicatorY+indicatorH
-
-		// Start drag
-		if input.IsLeftJustPressed() && p.scrollbarHovered {
-			p.scrollDragging = true
-			p.scrollDragStartY = my
-			p.scrollDragStartVal = p.scrollY
-		}
+// Status bar badges
+var (
+	evalBadge = Rect{X: BoardSize + PanelPadding + 130, Y: ScreenHeight - 70, W: 70, H: 20}
+	bookBadge = Rect{X: BoardSize + PanelPadding + 200, Y: ScreenHeight - 48, W: 90, H: 20}
+)
 
-		// Continue drag
-		if p.scrollDragging {
-			if input.IsLeftPressed() {
-				deltaY := my - p.scrollDragStartY
-				// Convert pixel delta to scroll amount
-				scrollRange := visibleHeight - indicatorH
-				if scrollRange > 0 {
-					p.scrollY = p.scrollDragStartVal + deltaY*p.maxScrollY/scrollRange
-				}
-				// Clamp
-				if p.scrollY < 0 {
-					p.scrollY = 0
-				}
-				if p.scrollY > p.maxScrollY {
-					p.scrollY = p.maxScrollY
-				}
-				return true
-			} else {
-				p.scrollDragging = false
-			}
-		}
+// tipAreas returns the parts of the panel other than buttons that explain
+// themselves when hovered: the status bar badges, the hint evaluations and
+// the game actions that cannot be used now.
+func (p *Panel) tipAreas() []TipArea {
+	if p.collapsed {
+		return p.heatmapTipAreas()
 	}
-skipScrollbar:
-
-	// Check other buttons for hover
-	p.newGameBtn.hovered = p.isInside(mx, my, p.newGameBtn)
-	p.settingsBtn.hovered = p.isInside(mx, my, p.settingsBtn)
-	for _, btn := range p.modeTabs {
-		btn.hovered = p.isInside(mx, my, btn)
+	evalTip := "Classical evaluation: hand-tuned rules. Switch to NNUE in Settings."
+	if p.game.EvalMode() == EvalNNUE {
+		evalTip = "NNUE evaluation: a neural network trained on engine games"
 	}
-	for _, btn := range p.diffTabs {
-		btn.hovered = p.isInside(mx, my, btn)
+	areas := []TipArea{{Rect: evalBadge, Tooltip: evalTip}}
+	if p.game.HasBook() {
+		areas = append(areas, TipArea{Rect: bookBadge,
+			Tooltip: "Whether the engine still plays from its opening book or thinks for itself"})
 	}
-
-	// Track pressed state (mouse down on button)
-	if input.IsLeftPressed() {
-		p.newGameBtn.pressed = p.newGameBtn.hovered
-		p.settingsBtn.pressed = p.settingsBtn.hovered
-		for _, btn := range p.modeTabs {
-			btn.pressed = btn.hovered
-		}
-		for _, btn := range p.diffTabs {
-			btn.pressed = btn.hovered
-		}
-	} else {
-		// Clear pressed state when mouse released
-		p.newGameBtn.pressed = false
-		p.settingsBtn.pressed = false
-		for _, btn := range p.modeTabs {
-			btn.pressed = false
-		}
-		for _, btn := range p.diffTabs {
-			btn.pressed = false
-		}
+	if p.hintArea.H > 0 {
+		areas = append(areas, TipArea{Rect: p.hintArea,
+			Tooltip: "Best moves with their evaluation in pawns from White's side (+ favours White); the bracket shows how much worse than the best move"})
 	}
-
-	// Handle clicks
-	if input.IsLeftJustPressed() {
-		if p.newGameBtn.hovered {
-			p.newGameBtn.OnClick()
-			return true
-		}
-		if p.settingsBtn.hovered {
-			p.settingsBtn.OnClick()
-			return true
-		}
-		for _, btn := range p.modeTabs {
-			if btn.hovered {
-				btn.OnClick()
-				return true
-			}
-		}
-		if p.game.GameMode() == ModeHumanVsComputer {
-			for _, btn := range p.diffTabs {
-				if btn.hovered {
-					btn.OnClick()
-					return true
-				}
-			}
+	if p.chancesArea.H > 0 {
+		areas = append(areas, TipArea{Rect: p.chancesArea,
+			Tooltip: "Your expected score when the game is played out many times with quick, imperfect moves: how easy the position is to play, not how good it is with best play"})
+	}
+	for _, b := range p.actionBtns {
+		if b.disabled {
+			areas = append(areas, TipArea{Rect: b.Rect, Tooltip: b.Tooltip})
 		}
 	}
-
-	return false
+	return append(areas, p.heatmapTipAreas()...)
 }
 
 // AnyButtonHovered returns true if any button in the panel is hovered.
 func (p *Panel) AnyButtonHovered() bool {
-	if p.collapseBtn.hovered {
-		return true
-	}
-	if p.collapsed {
-		return false
-	}
-	if p.newGameBtn.hovered || p.settingsBtn.hovered {
-		return true
-	}
-	for _, btn := range p.modeTabs {
-		if btn.hovered {
-			return true
-		}
-	}
-	for _, btn := range p.diffTabs {
+	for _, btn := range p.buttons() {
 		if btn.hovered {
 			return true
 		}
@@ -368,10 +322,6 @@ func (p *Panel) AnyButtonHovered() bool {
 	return false
 }
 
-func (p *Panel) isInside(mx, my int, btn *Button) bool {
-	return mx >= btn.X && mx < btn.X+btn.W && my >= btn.Y && my < btn.Y+btn.H
-}
-
 // Draw renders the panel.
 func (p *Panel) Draw(screen *ebiten.Image, r *Renderer, glass *GlassEffect) {
 	panelX := p.s(BoardSize)
@@ -380,6 +330,7 @@ func (p *Panel) Draw(screen *ebiten.Image, r *Renderer, glass *GlassEffect) {
 		// Draw collapsed state - just a thin bar with expand button
 		vector.DrawFilledRect(screen, panelX, 0, p.s(CollapsedWidth), p.s(ScreenHeight), panelBg, false)
 		p.drawCollapseButton(screen, true)
+		p.drawTooltip(screen, &p.tooltip)
 		return
 	}
 
@@ -394,41 +345,82 @@ func (p *Panel) Draw(screen *ebiten.Image, r *Renderer, glass *GlassEffect) {
 
 	// Draw Settings button
 	p.drawSecondaryButton(screen, p.settingsBtn)
+	p.drawSecondaryButton(screen, p.gamesBtn)
+	p.drawSecondaryButton(screen, p.rushBtn)
+	p.drawSecondaryButton(screen, p.shareBtn)
 
 	// Draw mode section
-	modeLabelY := p.modeTabs[0].Y - SectionLabelH
-	p.drawSectionLabel(screen, "Game Mode", BoardSize+PanelPadding, modeLabelY)
-	p.drawModeTabs(screen)
+	p.drawLabel(screen, p.modeLabel)
+	p.drawTabs(screen, p.modeTabs)
 
 	// Draw difficulty section (only in vs Computer mode)
 	if p.game.GameMode() == ModeHumanVsComputer {
-		diffLabelY := p.diffTabs[0].Y - SectionLabelH
-		p.drawSectionLabel(screen, "Difficulty", BoardSize+PanelPadding, diffLabelY)
-		p.drawDifficultyTabs(screen)
+		p.drawLabel(screen, p.diffLabel)
+		p.drawTabs(screen, p.diffTabs)
+	}
+	if p.game.Sparring() {
+		p.drawSparring(screen)
 	}
 
-	// Draw hint section (Easy mode only, when hints are available)
+	// Draw engine match section (only in Computer vs Computer mode)
+	if p.game.GameMode() == ModeComputerVsComputer {
+		p.drawEngineMatch(screen)
+	}
+
+	// Draw hint section (Easy mode, or when the player asked for a hint)
 	hintSectionH := 0
-	if p.game.difficulty == DifficultyEasy && p.game.showHints && p.game.assistResult != nil {
-		log.Printf("[Panel] Drawing hint section: eval=%d, move=%v", p.game.assistResult.Evaluation, p.game.assistResult.BestMove)
+	p.hintArea = Rect{}
+	p.chancesArea = Rect{}
+	if p.game.hintVisible() {
 		hintY := p.getHistoryStartY()
 		hintSectionH = p.drawAssistance(screen, hintY)
 	}
 
 	// Draw move history section
 	historyY := p.getHistoryStartY() + hintSectionH
-	p.drawSectionLabel(screen, "Moves", BoardSize+PanelPadding, historyY)
-	p.drawMoveHistory(screen, historyY+SectionLabelH+4)
+	if p.game.ExplorerEnabled() {
+		p.drawViewTabs(screen, historyY)
+	} else {
+		p.drawSectionLabel(screen, "Moves", BoardSize+PanelPadding, historyY)
+	}
+	p.drawHistoryNav(screen, historyY)
+	movesY := historyY + SectionLabelH + 4
+	if p.game.showOpening() {
+		p.drawOpening(screen, movesY)
+		movesY += OpeningRowH
+	}
+	if p.ShowingExplorer() {
+		p.drawExplorer(screen, movesY)
+	} else {
+		p.drawMoveHistory(screen, movesY)
+	}
+
+	// Draw coach commentary below the move list
+	if p.game.CoachEnabled() {
+		p.drawCoach(screen)
+	}
+
+	p.drawGameActions(screen)
 
 	// Draw status bar at bottom with glass effect
 	p.drawStatusBar(screen, glass)
+
+	p.drawTooltip(screen, &p.tooltip)
 }
 
 func (p *Panel) getHistoryStartY() int {
-	if p.game.GameMode() == ModeHumanVsComputer {
-		return p.diffTabs[0].Y + p.diffTabs[0].H + SectionSpacing - 4
+	switch p.game.GameMode() {
+	case ModeHumanVsComputer:
+		r := p.diffTabs.Rect()
+		if p.game.Sparring() {
+			return r.Y + r.H + sparringInfoH + SectionSpacing - 4
+		}
+		return r.Y + r.H + SectionSpacing - 4
+	case ModeComputerVsComputer:
+		return p.diffTabs.Rect().Y + 44 + SectionSpacing - 4
 	}
-	return p.modeTabs[0].Y + p.modeTabs[0].H + SectionSpacing - 4
+	r := p.modeTabs.Rect()
+	return r.Y + r.H + SectionSpacing - 4
 }
 
 func (p *Panel) drawCollapseButton(screen *ebiten.Image, expand bool) {
@@ -498,70 +490,47 @@ func (p *Panel) drawSecondaryButton(screen *ebiten.Image, btn *Button) {
 	p.drawTextCentered(screen, btn.Label, btn.X+btn.W/2, btn.Y+btn.H/2, textSecondary)
 }
 
-func (p *Panel) drawModeTabs(screen *ebiten.Image) {
-	for i, btn := range p.modeTabs {
-		isActive := (i == 0 && p.game.GameMode() == ModeHumanVsHuman) ||
-			(i == 1 && p.game.GameMode() == ModeHumanVsComputer)
-
-		bgColor := tabInactiveBg
-		if isActive {
-			bgColor = tabActiveBg
-		} else if btn.pressed {
-			bgColor = buttonPressedBg
-		} else if btn.hovered {
-			bgColor = tabHoverBg
-		}
-
-		// Draw background
-		vector.DrawFilledRect(screen, p.s(btn.X), p.s(btn.Y), p.s(btn.W), p.s(btn.H), bgColor, false)
+// drawEngineMatch draws the engine configurations of a Computer vs Computer
+// game, their score against each other and the live evaluation, in place of
+// the difficulty tabs.
+func (p *Panel) drawEngineMatch(screen *ebiten.Image) {
+	x := BoardSize + PanelPadding
+	y := p.diffTabs.Rect().Y
+	p.drawSectionLabel(screen, "Engine Match", x, y-SectionLabelH)
 
-		// Draw border - highlight on hover, green on active
-		borderC := buttonBorder
-		if isActive {
-			borderC = tabActiveBg
-		} else if btn.hovered {
-			borderC = accentColor
-		}
-		vector.StrokeRect(screen, p.s(btn.X), p.s(btn.Y), p.s(btn.W), p.s(btn.H), float32(p.scale), borderC, false)
+	white, black := p.game.MatchConfig(board.White), p.game.MatchConfig(board.Black)
+	p.drawText(screen, white.Name()+" vs "+black.Name(), x, y, textPrimary)
 
-		textColor := textSecondary
-		if isActive {
-			textColor = textPrimary
-		}
-		p.drawTextCentered(screen, btn.Label, btn.X+btn.W/2, btn.Y+btn.H/2, textColor)
+	whiteScore, blackScore, games := p.game.MatchScore()
+	line := fmt.Sprintf("Score %g-%g", whiteScore, blackScore)
+	if games == 0 {
+		line = "First game"
+	}
+	if score, depth := p.game.LiveEval(); depth > 0 {
+		line += fmt.Sprintf("   Eval %s (depth %d)", formatEval(score), depth)
 	}
+	p.drawText(screen, line, x, y+22, textSecondary)
 }
 
-func (p *Panel) drawDifficultyTabs(screen *ebiten.Image) {
-	for i, btn := range p.diffTabs {
-		isActive := Difficulty(i) == p.game.Difficulty()
-
-		bgColor := tabInactiveBg
-		if isActive {
-			bgColor = tabActiveBg
-		} else if btn.pressed {
-			bgColor = buttonPressedBg
-		} else if btn.hovered {
-			bgColor = tabHoverBg
-		}
-
-		// Draw background
-		vector.DrawFilledRect(screen, p.s(btn.X), p.s(btn.Y), p.s(btn.W), p.s(btn.H), bgColor, false)
-
-		// Draw border - highlight on hover, green on active
-		borderC := buttonBorder
-		if isActive {
-			borderC = tabActiveBg
-		} else if btn.hovered {
-			borderC = accentColor
-		}
-		vector.StrokeRect(screen, p.s(btn.X), p.s(btn.Y), p.s(btn.W), p.s(btn.H), float32(p.scale), borderC, false)
-
-		textColor := textSecondary
-		if isActive {
-			textColor = textPrimary
+// drawSparring draws the sparring engine's name and its latest search report
+// below the difficulty tabs: depth, score and speed, then the start of its
+// principal variation.
+func (p *Panel) drawSparring(screen *ebiten.Image) {
+	x := BoardSize + PanelPadding
+	r := p.diffTabs.Rect()
+	y := r.Y + r.H + 8
+
+	line := p.game.OpponentName()
+	info, score, pv := p.game.SparringReport()
+	if info.Depth > 0 {
+		line += fmt.Sprintf("   %d/%d  %s", info.Depth, info.SelDepth, formatEval(score))
+		if info.Time > 0 {
+			line += fmt.Sprintf("  %d kN/s", uint64(float64(info.Nodes)/info.Time.Seconds()/1000))
 		}
-		p.drawTextCentered(screen, btn.Label, btn.X+btn.W/2, btn.Y+btn.H/2, textColor)
+	}
+	p.drawText(screen, line, x, y, textSecondary)
+	if pv != "" {
+		p.drawText(screen, pv, x, y+20, textMuted)
 	}
 }
 
@@ -571,35 +540,32 @@ func (p *Panel) drawSectionLabel(screen *ebiten.Image, label string, x, y int) {
 
 func (p *Panel) drawMoveHistory(screen *ebiten.Image, startY int) {
 	moves := p.game.SANHistory()
-	if len(moves) == 0 {
-		p.drawText(screen, "No moves yet", BoardSize+PanelPadding, startY+5, textMuted)
-		return
-	}
-
+	times := p.game.MoveTimes()
 	x := BoardSize + PanelPadding
 	rowHeight := 22
-	maxY := ScreenHeight - 70 // Leave room for status bar
-	visibleHeight := maxY - startY
-
-	// Calculate total content height and max scroll
+	maxY := p.historyEndY() // Leave room for the coach and status bar
+	view := Rect{X: BoardSize, Y: startY, W: PanelWidth, H: maxY - startY}
 	totalRows := (len(moves) + 1) / 2
-	contentHeight := totalRows * rowHeight
-	p.maxScrollY = contentHeight - visibleHeight
-	if p.maxScrollY < 0 {
-		p.maxScrollY = 0
+	if view.H < rowHeight {
+		// The hint and coach sections leave no room
+		totalRows = 0
 	}
+	p.history.SetContent(view, totalRows*rowHeight)
 
-	// Clamp scroll position
-	if p.scrollY > p.maxScrollY {
-		p.scrollY = p.maxScrollY
+	if len(moves) == 0 {
+		p.drawText(screen, "No moves yet", x, startY+5, textMuted)
+		return
+	}
+	if totalRows == 0 {
+		return
 	}
 
 	// Calculate starting row based on scroll
-	startRow := p.scrollY / rowHeight
+	startRow := p.history.Offset / rowHeight
 	startMoveIdx := startRow * 2
 
 	// Y position adjusted for partial scroll
-	y := startY - (p.scrollY % rowHeight)
+	y := startY - (p.history.Offset % rowHeight)
 
 	for i := startMoveIdx; i < len(moves); i += 2 {
 		// Skip if above visible area
@@ -627,34 +593,58 @@ func (p *Panel) drawMoveHistory(screen *ebiten.Image, startY int) {
 			moveNum := (i / 2) + 1
 			numStr := fmt.Sprintf("%d.", moveNum)
 			p.drawText(screen, numStr, x, y, textMuted)
-			p.drawText(screen, moves[i], x+30, y, textPrimary)
+			p.drawText(screen, moves[i], x+30, y, p.moveColor(i))
+			if i < len(times) {
+				p.drawText(screen, formatMoveTime(times[i]), x+82, y, textMuted)
+			}
 			if i+1 < len(moves) {
-				p.drawText(screen, moves[i+1], x+100, y, textPrimary)
+				p.drawText(screen, moves[i+1], x+140, y, p.moveColor(i+1))
+				if i+1 < len(times) {
+					p.drawText(screen, formatMoveTime(times[i+1]), x+192, y, textMuted)
+				}
 			}
 		}
 
 		y += rowHeight
 	}
 
-	// Show scroll indicator if there's more content
-	if p.maxScrollY > 0 {
-		// Draw a small scroll indicator on the right
-		scrollPct := float32(p.scrollY) / float32(p.maxScrollY)
-		indicatorH := p.s(visibleHeight) * float32(visibleHeight) / float32(contentHeight)
-		if indicatorH < p.s(20) {
-			indicatorH = p.s(20)
-		}
-		indicatorY := p.s(startY) + scrollPct*(p.s(visibleHeight)-indicatorH)
-		indicatorX := p.s(BoardSize + PanelWidth - 8)
-
-		// Color based on state: dragging > hovered > normal
-		scrollColor := textMuted
-		if p.scrollDragging {
-			scrollColor = accentColor
-		} else if p.scrollbarHovered {
-			scrollColor = textSecondary
+	// Show the scroll bar if there's more content
+	p.drawScrollBar(screen, &p.history)
+}
+
+// moveColor returns the text color of move i of the list: the accent color
+// for the move leading to the position shown while browsing.
+func (p *Panel) moveColor(i int) color.Color {
+	if p.game.Browsing() && i == p.game.ShownPly()-1 {
+		return accentColor
+	}
+	return textPrimary
+}
+
+// drawOpening draws the ECO code and name of the opening above the moves.
+func (p *Panel) drawOpening(screen *ebiten.Image, y int) {
+	o, _ := p.game.Opening()
+	x := BoardSize + PanelPadding
+	p.drawText(screen, o.ECO, x, y, accentColor)
+	name := ellipsize(o.Name, GetRegularFace(), float64(p.si(PanelWidth-PanelPadding*2-40)))
+	p.drawText(screen, name, x+40, y, textSecondary)
+}
+
+// drawHistoryNav lays out and draws the history navigation buttons at the
+// right end of the move list label row.
+func (p *Panel) drawHistoryNav(screen *ebiten.Image, y int) {
+	const w, gap = 28, 4
+	x := BoardSize + PanelWidth - PanelPadding - len(p.navBtns)*(w+gap) + gap
+	atStart := p.game.ShownPly() == 0
+	atEnd := !p.game.Browsing()
+	for i, btn := range p.navBtns {
+		btn.Rect = Rect{X: x + i*(w+gap), Y: y - 2, W: w, H: SectionLabelH}
+		btn.disabled = atStart && i < 2 || atEnd && i >= 2
+		if btn.disabled {
+			p.drawDisabledButton(screen, btn)
+			continue
 		}
-		vector.DrawFilledRect(screen, indicatorX, indicatorY, p.s(4), indicatorH, scrollColor, false)
+		p.drawSecondaryButton(screen, btn)
 	}
 }
 
@@ -701,9 +691,19 @@ func (p *Panel) drawStatusBar(screen *ebiten.Image, glass *GlassEffect) {
 	var statusText string
 	var statusColor color.RGBA
 
+	remaining, solved, mistakes, rushing := p.game.RushStatus()
 	if p.game.GameOver() {
 		statusText = p.game.GameResult()
 		statusColor = statusGameOver
+	} else if rushing {
+		statusText = "Puzzle rush: find the best move"
+		statusColor = textPrimary
+	} else if p.game.IsAIThinking() && p.game.GameMode() == ModeComputerVsComputer {
+		statusText = p.game.MatchConfig(p.game.Position().SideToMove).Name() + " thinking..."
+		statusColor = statusThinking
+	} else if p.game.IsAIThinking() && p.game.Sparring() {
+		statusText = p.game.OpponentName() + " thinking..."
+		statusColor = statusThinking
 	} else if p.game.IsAIThinking() {
 		statusText = "AI thinking..."
 		statusColor = statusThinking
@@ -717,6 +717,41 @@ func (p *Panel) drawStatusBar(screen *ebiten.Image, glass *GlassEffect) {
 	}
 
 	p.drawText(screen, statusText, x, statusY+22, statusColor)
+
+	// Book badge, so users can see when the engine starts thinking for itself
+	if p.game.HasBook() {
+		if p.game.InBook() {
+			p.drawText(screen, "Book", x+200, statusY+22, accentColor)
+		} else {
+			p.drawText(screen, "Out of book", x+200, statusY+22, textSecondary)
+		}
+	}
+
+	// Time used by each side, or the rush clock and score
+	if rushing {
+		secs := int(remaining.Round(time.Second) / time.Second)
+		rushText := fmt.Sprintf("%d:%02d   Solved %d   Mistakes %d/%d",
+			secs/60, secs%60, solved, mistakes, puzzle.MaxMistakes)
+		rushColor := textMuted
+		if remaining < 10*time.Second {
+			rushColor = statusGameOver
+		}
+		p.drawText(screen, rushText, x, statusY+44, rushColor)
+		return
+	}
+	// The move entry and its completions replace the clocks while it is open
+	if input, active := p.game.MoveInput(); active {
+		line := "Move: " + input + "_"
+		if input != "" {
+			sans := p.game.MoveInputCandidates()
+			line += "   " + strings.Join(sans[:min(len(sans), 5)], " ")
+		}
+		p.drawText(screen, line, x, statusY+44, accentColor)
+		return
+	}
+	clockText := fmt.Sprintf("White %s   Black %s",
+		formatClock(p.game.Clock(board.White)), formatClock(p.game.Clock(board.Black)))
+	p.drawText(screen, clockText, x, statusY+44, textMuted)
 }
 
 // Text drawing helpers
@@ -750,6 +785,13 @@ func (p *Panel) Collapsed() bool {
 	return p.collapsed
 }
 
+// Animating returns true while the panel changes on its own: the Resign
+// button waits for its confirmation, then reverts, or a tooltip is about
+// to show.
+func (p *Panel) Animating() bool {
+	return time.Since(p.resignArmedAt) < resignConfirmTime || p.tooltip.Pending()
+}
+
 // toggleCollapse toggles the panel collapsed state and resizes the window.
 func (p *Panel) toggleCollapse() {
 	p.collapsed = !p.collapsed
@@ -763,11 +805,11 @@ func (p *Panel) toggleCollapse() {
 	}
 }
 
-// drawAssistance draws the Easy mode hint section.
-// Returns the height of the section (for layout purposes).
+// drawAssistance draws the hint section: the top moves with their
+// evaluations. Returns the height of the section (for layout purposes).
 func (p *Panel) drawAssistance(screen *ebiten.Image, y int) int {
 	assist := p.game.assistResult
-	if assist == nil {
+	if assist == nil || len(assist.Lines) == 0 {
 		return 0
 	}
 
@@ -775,20 +817,34 @@ func (p *Panel) drawAssistance(screen *ebiten.Image, y int) int {
 	startY := y
 
 	// Section label
-	p.drawSectionLabel(screen, "Hint", contentX, y)
+	label := "Hint"
+	if left := p.game.HintsLeft(); left >= 0 && !p.game.autoHints() {
+		label = fmt.Sprintf("Hint (%d left)", left)
+	}
+	p.drawSectionLabel(screen, label, contentX, y)
 	y += SectionLabelH + 4
 
-	// Section background
-	sectionH := 52
+	// Section background: one row per line, and one for practical chances
+	rows := len(assist.Lines)
+	if assist.Chances != nil {
+		rows++
+	}
+	sectionH := 8 + 22*rows
+	p.hintArea = Rect{X: contentX - 4, Y: y, W: PanelWidth - PanelPadding*2 + 8, H: 4 + 22*len(assist.Lines)}
 	vector.DrawFilledRect(screen, p.s(contentX-4), p.s(y), p.s(PanelWidth-PanelPadding*2+8), p.s(sectionH), sectionBg, false)
 
-	// Evaluation score
-	scoreStr := engine.ScoreToString(assist.Evaluation)
-	p.drawText(screen, "Eval: "+scoreStr, contentX, y+4, textPrimary)
-
-	// Best move suggestion
-	moveStr := assist.BestMove.String()
-	p.drawText(screen, "Try: "+moveStr, contentX, y+26, accentColor)
+	for i := range assist.Lines {
+		c := textSecondary
+		if i == 0 {
+			c = accentColor
+		}
+		p.drawText(screen, p.game.hintLineText(assist.Lines, i), contentX, y+4+22*i, c)
+	}
+	if assist.Chances != nil {
+		rowY := y + 4 + 22*len(assist.Lines)
+		p.chancesArea = Rect{X: contentX - 4, Y: rowY, W: PanelWidth - PanelPadding*2 + 8, H: 22}
+		p.drawText(screen, chancesText(assist.Chances), contentX, rowY, textMuted)
+	}
 
 	return y + sectionH + SectionSpacing - startY
 }
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"log"
+	"time"
+
+	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/storage"
+)
+
+// engineHashMB is the transposition table size of the GUI engine
+const engineHashMB = 64
+
+// gamePerf accumulates engine search statistics over the current game
+// for the local performance log.
+type gamePerf struct {
+	moves       int
+	depthSum    int
+	selDepthSum int
+	nodes       uint64
+	time        time.Duration
+}
+
+// add records one engine search. Book and tablebase moves are skipped.
+func (p *gamePerf) add(info engine.SearchInfo) {
+	if info.Depth == 0 {
+		return
+	}
+	p.moves++
+	p.depthSum += info.Depth
+	p.selDepthSum += info.SelDepth
+	p.nodes += info.Nodes
+	p.time += info.Time
+}
+
+// flushPerfLog appends the engine performance of the current game to the
+// local performance log and starts a new tally. Nothing leaves this machine.
+func (g *Game) flushPerfLog() {
+	p := g.perf
+	g.perf = gamePerf{}
+	if p.moves == 0 {
+		return
+	}
+
+	var nps uint64
+	if p.time > 0 {
+		nps = uint64(float64(p.nodes) / p.time.Seconds())
+	}
+	eval := "classical"
+	if g.engine.UseNNUE() {
+		eval = "nnue"
+	}
+
+	err := storage.AppendPerfEntry(storage.PerfEntry{
+		Date:        time.Now(),
+		Result:      g.pgnResult(),
+		EngineMoves: p.moves,
+		AvgDepth:    float64(p.depthSum) / float64(p.moves),
+		AvgSelDepth: float64(p.selDepthSum) / float64(p.moves),
+		NPS:         nps,
+		EngineTime:  p.time,
+		Threads:     engine.NumWorkers,
+		HashMB:      engineHashMB,
+		Eval:        eval,
+		Difficulty:  []string{"Easy", "Medium", "Hard"}[g.difficulty],
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to write performance log: %v", err)
+	}
+}
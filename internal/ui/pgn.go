@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/storage"
+)
+
+// PGN returns the current game in PGN format.
+// Each move carries the time spent on it as an [%emt] (elapsed move time)
+// comment; there is no time control, so no remaining clock to write as
+// [%clk]. Engine evaluations, coach notes and hint moves not played are added
+// as [%eval] commands, comments and variations.
+func (g *Game) PGN() string {
+	var sb strings.Builder
+
+	white, black := g.username, g.username
+	switch g.mode {
+	case ModeHumanVsComputer:
+		if g.playerColor == board.White {
+			black = g.OpponentName()
+		} else {
+			white = g.OpponentName()
+		}
+	case ModeComputerVsComputer:
+		white = "chessplay " + g.matchConfigs[board.White].Name()
+		black = "chessplay " + g.matchConfigs[board.Black].Name()
+	}
+	result := g.pgnResult()
+
+	fmt.Fprintf(&sb, "[Event \"Casual game\"]\n")
+	fmt.Fprintf(&sb, "[Site \"chessplay\"]\n")
+	fmt.Fprintf(&sb, "[Date \"%s\"]\n", time.Now().Format("2006.01.02"))
+	fmt.Fprintf(&sb, "[White \"%s\"]\n", white)
+	fmt.Fprintf(&sb, "[Black \"%s\"]\n", black)
+	fmt.Fprintf(&sb, "[Result \"%s\"]\n", result)
+	if g.startFEN != "" {
+		fmt.Fprintf(&sb, "[SetUp \"1\"]\n")
+		fmt.Fprintf(&sb, "[FEN \"%s\"]\n", g.startFEN)
+	}
+	if o, ok := g.Opening(); ok {
+		fmt.Fprintf(&sb, "[ECO \"%s\"]\n", o.ECO)
+		fmt.Fprintf(&sb, "[Opening \"%s\"]\n", o.Name)
+	}
+	sb.WriteString("\n")
+
+	start := g.startPly()
+	interrupted := true // Black's move needs its number after a comment or variation
+	for i, san := range g.sanHistory {
+		ply := start + i
+		sb.WriteString(pgnMoveNumber(ply, interrupted))
+		sb.WriteString(san)
+
+		var comment []string
+		if i < len(g.moveTimes) {
+			comment = append(comment, fmt.Sprintf("[%%emt %s]", formatClock(g.moveTimes[i])))
+		}
+		if eval, ok := g.evals[i]; ok {
+			comment = append(comment, fmt.Sprintf("[%%eval %s]", formatEval(eval)))
+		}
+		comment = append(comment, g.moveComments(i)...)
+		if len(comment) > 0 {
+			fmt.Fprintf(&sb, " {%s}", strings.Join(comment, " "))
+		}
+
+		v, ok := g.variations[i]
+		if ok {
+			fmt.Fprintf(&sb, " (%s%s)", pgnMoveNumber(ply, true), strings.Join(v, " "))
+		}
+		sb.WriteString(" ")
+		interrupted = ok || len(comment) > 0
+	}
+	sb.WriteString(result)
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// pgnMoveNumber returns the move number before the move at ply: "12. " for
+// White, and "12... " for Black when the move does not follow White's.
+func pgnMoveNumber(ply int, interrupted bool) string {
+	if ply%2 == 0 {
+		return fmt.Sprintf("%d. ", ply/2+1)
+	}
+	if interrupted {
+		return fmt.Sprintf("%d... ", ply/2+1)
+	}
+	return ""
+}
+
+// pgnResult returns the PGN result token for the current game state.
+func (g *Game) pgnResult() string {
+	if !g.gameOver {
+		return "*"
+	}
+	if g.resultToken != "" {
+		return g.resultToken
+	}
+	if g.position.IsCheckmate() {
+		if g.position.SideToMove == board.White {
+			return "0-1"
+		}
+		return "1-0"
+	}
+	return "1/2-1/2"
+}
+
+// ExportPGN writes the current game to the games directory of the active
+// profile and returns the file path. The game's record with its annotations
+// is saved next to it, and the game is auto-saved to the same file from then
+// on.
+func (g *Game) ExportPGN() (string, error) {
+	if g.gamePath != "" {
+		return g.gamePath, g.writeGame(g.gamePath)
+	}
+
+	var dir string
+	var err error
+	if g.storage != nil {
+		dir, err = g.storage.GamesDir()
+	} else {
+		dir, err = storage.GetGamesDir()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, time.Now().Format("2006-01-02_150405")+".pgn")
+	if err := g.writeGame(path); err != nil {
+		return "", err
+	}
+	g.gamePath = path
+	return path, nil
+}
+
+// writeGame writes the game's PGN file and record.
+func (g *Game) writeGame(path string) error {
+	if err := os.WriteFile(path, []byte(g.PGN()), 0644); err != nil {
+		return err
+	}
+	return storage.SaveGameRecord(path, g.gameRecord())
+}
+
+// formatClock formats a duration as h:mm:ss for PGN clock comments.
+func formatClock(d time.Duration) string {
+	secs := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", secs/3600, (secs/60)%60, secs%60)
+}
+
+// formatMoveTime formats a move duration compactly for the history panel.
+func formatMoveTime(d time.Duration) string {
+	switch {
+	case d < 10*time.Second:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	default:
+		secs := int(d / time.Second)
+		return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+	}
+}
@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPGNMoveComments(t *testing.T) {
+	g := &Game{
+		sanHistory: []string{"e4", "e5", "Nf3", "Nc6"},
+		moveTimes:  []time.Duration{5 * time.Second, 3 * time.Second, 62 * time.Second, time.Hour},
+		evals:      map[int]int{0: 35, 2: -120},
+	}
+
+	want := "1. e4 {[%emt 0:00:05] [%eval 0.35]} 1... e5 {[%emt 0:00:03]} " +
+		"2. Nf3 {[%emt 0:01:02] [%eval -1.20]} 2... Nc6 {[%emt 1:00:00]} *\n"
+	if pgn := g.PGN(); !strings.HasSuffix(pgn, "\n\n"+want) {
+		t.Errorf("PGN movetext:\n%s\nwant:\n%s", pgn, want)
+	}
+
+	// From a position with Black to move, the first move is Black's
+	g = &Game{
+		startFEN:   "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+		sanHistory: []string{"e5", "Nf3"},
+		moveTimes:  []time.Duration{4 * time.Second, 2 * time.Second},
+	}
+	want = "1... e5 {[%emt 0:00:04]} 2. Nf3 {[%emt 0:00:02]} *\n"
+	if pgn := g.PGN(); !strings.HasSuffix(pgn, "\n\n"+want) {
+		t.Errorf("PGN movetext from a FEN:\n%s\nwant:\n%s", pgn, want)
+	}
+}
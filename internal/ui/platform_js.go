@@ -0,0 +1,125 @@
+//go:build js
+
+package ui
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sync"
+	"syscall/js"
+
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+// The web build runs in a browser: there are no network files on disk, so
+// the small network is fetched from the page's nnue/ directory and the big
+// one is not used.
+
+func init() {
+	// Go runs WebAssembly on one thread; more workers than the browser
+	// reports cores only split the same time more ways.
+	cores := js.Global().Get("navigator").Get("hardwareConcurrency")
+	if cores.Type() == js.TypeNumber {
+		engine.NumWorkers = max(1, min(runtime.GOMAXPROCS(0), cores.Int()))
+	}
+}
+
+// backgroundSearchSupported is false: a search on the one WebAssembly
+// thread runs until it ends, so one without an end would freeze the page.
+const backgroundSearchSupported = false
+
+// engineProcessSupported is false: a browser cannot start processes.
+const engineProcessSupported = false
+
+// webNet is the small network fetched from the page.
+var webNet struct {
+	sync.Mutex
+	started bool
+	data    []byte
+}
+
+// CheckNNUENetworks reports the networks as available: the page serves the
+// small network, which the engine evaluates with on its own.
+func CheckNNUENetworks() (smallExists, bigExists bool, err error) {
+	return true, true, nil
+}
+
+// loadNNUENetworks starts fetching the small network, or loads it once it
+// has arrived. A fetch cannot block the update: the browser only answers it
+// once control returns to the page.
+func (g *Game) loadNNUENetworks() {
+	if g.engine.HasNNUE() {
+		g.engine.SetUseNNUE(true)
+		return
+	}
+
+	webNet.Lock()
+	defer webNet.Unlock()
+	switch {
+	case webNet.data != nil:
+		if err := g.engine.LoadSmallNNUEData(webNet.data, engine.SmallNNUENet.Name); err != nil {
+			log.Printf("Warning: Failed to load NNUE network: %v", err)
+			webNet.data = nil // Not tried again
+			g.feedback.OnNetworkLoadFailed(err)
+			return
+		}
+		webNet.data = nil // The engine keeps its own copy of the weights
+		g.engine.SetUseNNUE(true)
+		log.Printf("NNUE network loaded successfully")
+	case !webNet.started:
+		webNet.started = true
+		go fetchWebNet()
+	}
+}
+
+// fetchWebNet downloads the small network from the page's nnue/ directory.
+func fetchWebNet() {
+	data, err := fetchPageFile("nnue/" + engine.SmallNNUENet.Name)
+	if err != nil {
+		log.Printf("Warning: Failed to fetch NNUE network: %v", err)
+	}
+
+	webNet.Lock()
+	defer webNet.Unlock()
+	webNet.data = data
+}
+
+// fetchPageFile fetches a file by its path relative to the page.
+func fetchPageFile(path string) ([]byte, error) {
+	base, err := url.Parse(js.Global().Get("location").Get("href").String())
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Get(base.ResolveReference(ref).String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", path, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// updatePlatform loads the fetched network once it has arrived, while the
+// engine is not searching.
+func (g *Game) updatePlatform() {
+	if g.evalMode != EvalNNUE || g.engine.HasNNUE() || g.aiThinking || g.assistRunning {
+		return
+	}
+	webNet.Lock()
+	ready := webNet.data != nil
+	webNet.Unlock()
+	if ready {
+		g.loadNNUENetworks()
+	}
+}
@@ -0,0 +1,55 @@
+//go:build !js
+
+package ui
+
+import (
+	"log"
+
+	"github.com/hailam/chessplay/internal/engine"
+	"github.com/hailam/chessplay/internal/storage"
+)
+
+// backgroundSearchSupported is true: the engine can think on the player's
+// time beside the interface.
+const backgroundSearchSupported = true
+
+// engineProcessSupported is true: the engine can run as a child process.
+const engineProcessSupported = true
+
+// CheckNNUENetworks checks if NNUE networks are available.
+func CheckNNUENetworks() (smallExists, bigExists bool, err error) {
+	if _, err := storage.GetNNUEDir(); err != nil {
+		return false, false, err
+	}
+
+	bigPath, smallPath := engine.NewestNNUE(DetectNNUENetworks())
+	return smallPath != "", bigPath != "", nil
+}
+
+// loadNNUENetworks loads the selected NNUE networks into the engine.
+// Networks that are already loaded are not read again.
+func (g *Game) loadNNUENetworks() {
+	g.stopBackgroundSearch()
+	smallPath, bigPath, err := GetNNUEPaths(g.prefs.NNUENetwork)
+	if err != nil {
+		log.Printf("Warning: Failed to get NNUE paths: %v", err)
+		return
+	}
+	if loadedBig, loadedSmall := g.engine.NNUEPaths(); loadedBig == bigPath && loadedSmall == smallPath {
+		g.engine.SetUseNNUE(true)
+		return
+	}
+
+	if err := g.engine.LoadNNUE(bigPath, smallPath); err != nil {
+		log.Printf("Warning: Failed to load NNUE networks: %v", err)
+		g.feedback.OnNetworkLoadFailed(err)
+		return
+	}
+
+	g.engine.SetUseNNUE(true)
+	log.Printf("NNUE networks loaded successfully")
+}
+
+// updatePlatform does nothing on the desktop, where networks are loaded
+// from disk when selected.
+func (g *Game) updatePlatform() {}
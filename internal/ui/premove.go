@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"log"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// handlePremoveInput lets the player queue a move while the engine is
+// thinking. Squares come from the renderer, so premoves work with the board
+// either way up. Right-click cancels the premove.
+func (g *Game) handlePremoveInput() {
+	mx, my := g.input.MousePosition()
+
+	if g.input.IsRightJustPressed() && (g.dragging || mx < BoardSize && my < BoardSize) {
+		g.clearPremove()
+		g.clearSelection()
+		return
+	}
+	if mx >= BoardSize || my >= BoardSize {
+		if g.dragging && g.input.IsLeftJustReleased() {
+			g.clearSelection()
+		}
+		return
+	}
+
+	if g.input.IsLeftJustPressed() {
+		sq := g.renderer.ScreenToSquare(mx, my)
+		if sq == board.NoSquare {
+			return
+		}
+
+		// Pick up one of the player's pieces; the legal moves are not
+		// known until the engine has moved
+		if piece := g.position.PieceAt(sq); piece != board.NoPiece && piece.Color() == g.playerColor {
+			g.selectedSquare = sq
+			g.legalMoves = nil
+			g.startDrag(sq)
+			return
+		}
+		if g.selectedSquare != board.NoSquare {
+			g.setPremove(g.selectedSquare, sq)
+		}
+		g.clearSelection()
+	}
+
+	if g.dragging && g.input.IsLeftJustReleased() {
+		if sq := g.renderer.ScreenToSquare(mx, my); sq != board.NoSquare && sq != g.dragSquare {
+			g.setPremove(g.dragSquare, sq)
+			g.clearSelection()
+			return
+		}
+		// Dropped back on its square: keep the selection for click-to-move
+		g.dragging = false
+		g.dragPiece = board.NoPiece
+		g.dragSquare = board.NoSquare
+	}
+}
+
+// setPremove queues a move of the player's piece on from to to.
+func (g *Game) setPremove(from, to board.Square) {
+	g.premoveFrom = from
+	g.premoveTo = to
+	g.premovePiece = g.position.PieceAt(from)
+}
+
+// clearPremove cancels the queued move.
+func (g *Game) clearPremove() {
+	g.premoveFrom = board.NoSquare
+	g.premoveTo = board.NoSquare
+	g.premovePiece = board.NoPiece
+}
+
+// hasPremove returns true if a move is queued.
+func (g *Game) hasPremove() bool {
+	return g.premoveFrom != board.NoSquare
+}
+
+// playPremove plays the queued move after the engine has replied, if it is
+// still legal with the same piece. Otherwise the premove is dropped.
+func (g *Game) playPremove() {
+	if !g.hasPremove() {
+		return
+	}
+	from, to, piece := g.premoveFrom, g.premoveTo, g.premovePiece
+	g.clearPremove()
+	if g.gameOver || g.position.SideToMove != g.playerColor || g.position.PieceAt(from) != piece {
+		return
+	}
+
+	g.legalMoves = g.getLegalMovesFrom(from)
+	m := g.findMove(from, to)
+	if m == board.NoMove {
+		log.Printf("[Premove] %v%v is not legal after the engine's move, dropped", from, to)
+		g.clearSelection()
+		return
+	}
+	g.makeMove(m)
+}
@@ -3,6 +3,8 @@ package ui
 import (
 	"image/color"
 	"math"
+	"slices"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
@@ -11,12 +13,15 @@ import (
 
 // Theme defines the color scheme for the board.
 type Theme struct {
+	ID             string // Saved in the preferences
+	Name           string
 	LightSquare    color.RGBA
 	DarkSquare     color.RGBA
 	SelectedSquare color.RGBA
 	LegalMoveColor color.RGBA
 	LastMoveColor  color.RGBA
 	CheckColor     color.RGBA
+	PremoveColor   color.RGBA
 	Background     color.RGBA
 	TextColor      color.RGBA
 	ButtonColor    color.RGBA
@@ -26,12 +31,15 @@ type Theme struct {
 // DefaultTheme returns the default color theme.
 func DefaultTheme() *Theme {
 	return &Theme{
+		ID:             "wood",
+		Name:           "Wood",
 		LightSquare:    color.RGBA{240, 217, 181, 255}, // Tan
 		DarkSquare:     color.RGBA{181, 136, 99, 255},  // Brown
 		SelectedSquare: color.RGBA{247, 247, 105, 180}, // Yellow highlight
 		LegalMoveColor: color.RGBA{130, 151, 105, 200}, // Green dots
 		LastMoveColor:  color.RGBA{180, 190, 100, 90},  // Softer yellow-green (reduced alpha)
 		CheckColor:     color.RGBA{255, 100, 100, 180}, // Red
+		PremoveColor:   color.RGBA{90, 130, 200, 150},  // Blue
 		Background:     color.RGBA{40, 44, 52, 255},    // Dark gray
 		TextColor:      color.RGBA{220, 220, 220, 255}, // Light gray
 		ButtonColor:    color.RGBA{60, 64, 72, 255},    // Medium gray
@@ -39,6 +47,28 @@ func DefaultTheme() *Theme {
 	}
 }
 
+// BoardThemes are the available board themes; the first is the default.
+// They differ in the square colors only, so highlights look the same on all.
+var BoardThemes = []*Theme{
+	DefaultTheme(),
+	boardTheme("blue", "Blue", color.RGBA{222, 227, 230, 255}, color.RGBA{140, 162, 173, 255}),
+	boardTheme("green", "Green", color.RGBA{238, 238, 210, 255}, color.RGBA{118, 150, 86, 255}),
+}
+
+// boardTheme returns the default theme with other square colors.
+func boardTheme(id, name string, light, dark color.RGBA) *Theme {
+	t := DefaultTheme()
+	t.ID, t.Name = id, name
+	t.LightSquare, t.DarkSquare = light, dark
+	return t
+}
+
+// boardThemeIndex returns the index of a theme in BoardThemes, or 0 (the
+// default) for an unknown id.
+func boardThemeIndex(id string) int {
+	return max(slices.IndexFunc(BoardThemes, func(t *Theme) bool { return t.ID == id }), 0)
+}
+
 // Renderer handles all drawing operations.
 type Renderer struct {
 	sprites    *SpriteManager
@@ -47,16 +77,28 @@ type Renderer struct {
 	squareSize int
 	scale      float64 // HiDPI scale factor
 	flipped    bool    // True when board is flipped (Black's perspective)
+
+	// Flip animation: the board fades out, the orientation switches at the midpoint, then fades back in
+	flipStart  time.Time // Zero when no flip is animating
+	flipTarget bool      // Orientation to switch to at the midpoint
+
+	// Move animation (see AnimateMove); nil when no move is animating
+	moveAnim     *moveAnimation
+	animateMoves bool
 }
 
+// flipDuration is the length of the board flip animation.
+const flipDuration = 300 * time.Millisecond
+
 // NewRenderer creates a new renderer.
 func NewRenderer(boardSize, squareSize int) *Renderer {
 	return &Renderer{
-		sprites:    NewSpriteManager(squareSize),
-		theme:      DefaultTheme(),
-		boardSize:  boardSize,
-		squareSize: squareSize,
-		scale:      1.0,
+		sprites:      NewSpriteManager(squareSize),
+		theme:        DefaultTheme(),
+		boardSize:    boardSize,
+		squareSize:   squareSize,
+		scale:        1.0,
+		animateMoves: true,
 	}
 }
 
@@ -67,15 +109,66 @@ func (r *Renderer) SetScale(scale float64) {
 }
 
 // SetFlipped sets whether the board is flipped (Black's perspective at bottom).
+// Cancels any running flip animation.
 func (r *Renderer) SetFlipped(flipped bool) {
 	r.flipped = flipped
+	r.flipStart = time.Time{}
 }
 
 // IsFlipped returns whether the board is flipped.
+// While a flip animation is running this is the orientation it will end in.
 func (r *Renderer) IsFlipped() bool {
+	if !r.flipStart.IsZero() {
+		return r.flipTarget
+	}
 	return r.flipped
 }
 
+// AnimateFlip switches the board orientation with a short fade animation.
+func (r *Renderer) AnimateFlip(flipped bool) {
+	if flipped == r.IsFlipped() {
+		return
+	}
+	r.flipTarget = flipped
+	if r.flipStart.IsZero() {
+		r.flipStart = time.Now()
+	}
+}
+
+// UpdateFlip advances the flip animation. Call once per frame.
+func (r *Renderer) UpdateFlip() {
+	if r.flipStart.IsZero() {
+		return
+	}
+	elapsed := time.Since(r.flipStart)
+	if elapsed >= flipDuration/2 {
+		r.flipped = r.flipTarget
+	}
+	if elapsed >= flipDuration {
+		r.flipStart = time.Time{}
+	}
+}
+
+// Animating returns true while the flip or a move animation is running.
+func (r *Renderer) Animating() bool {
+	return !r.flipStart.IsZero() || r.moveAnim != nil
+}
+
+// DrawFlipOverlay dims the board while a flip animation is running.
+func (r *Renderer) DrawFlipOverlay(screen *ebiten.Image) {
+	if r.flipStart.IsZero() {
+		return
+	}
+	t := float64(time.Since(r.flipStart)) / float64(flipDuration)
+	if t > 1 {
+		t = 1
+	}
+	// Peaks at the midpoint, when the orientation switches
+	overlay := r.theme.Background
+	overlay.A = uint8(math.Sin(t*math.Pi) * 255)
+	vector.DrawFilledRect(screen, 0, 0, r.s(r.boardSize), r.s(r.boardSize), overlay, false)
+}
+
 // s returns the scaled value for rendering.
 func (r *Renderer) s(v int) float32 {
 	return float32(float64(v) * r.scale)
@@ -136,6 +229,12 @@ func (r *Renderer) DrawHighlights(screen *ebiten.Image, selected board.Square, l
 	}
 }
 
+// DrawPremove highlights the squares of a queued premove.
+func (r *Renderer) DrawPremove(screen *ebiten.Image, from, to board.Square) {
+	r.highlightSquare(screen, from, r.theme.PremoveColor)
+	r.highlightSquare(screen, to, r.theme.PremoveColor)
+}
+
 // DrawCheck highlights the king's square if in check.
 func (r *Renderer) DrawCheck(screen *ebiten.Image, kingSq board.Square) {
 	if kingSq != board.NoSquare {
@@ -152,6 +251,17 @@ func (r *Renderer) highlightSquare(screen *ebiten.Image, sq board.Square, c colo
 	vector.DrawFilledRect(screen, r.s(x), r.s(y), r.s(r.squareSize), r.s(r.squareSize), c, false)
 }
 
+// DrawKeyCursor outlines the square of the keyboard cursor.
+func (r *Renderer) DrawKeyCursor(screen *ebiten.Image, sq board.Square) {
+	if sq == board.NoSquare {
+		return
+	}
+	x, y := r.SquareToScreen(sq)
+	inset := r.s(2)
+	vector.StrokeRect(screen, r.s(x)+inset, r.s(y)+inset, r.s(r.squareSize)-inset*2, r.s(r.squareSize)-inset*2,
+		r.s(3), r.theme.PremoveColor, false)
+}
+
 // drawLegalMoveIndicator draws a circle on legal move squares.
 func (r *Renderer) drawLegalMoveIndicator(screen *ebiten.Image, sq board.Square) {
 	x, y := r.SquareToScreen(sq)
@@ -167,7 +277,8 @@ func (r *Renderer) DrawPieces(screen *ebiten.Image, pos *board.Position, draggin
 	r.DrawPiecesWithAnimations(screen, pos, dragging, dragSquare, nil)
 }
 
-// DrawPiecesWithAnimations draws all pieces with optional shake animations.
+// DrawPiecesWithAnimations draws all pieces with optional shake animations,
+// and the running move animation.
 func (r *Renderer) DrawPiecesWithAnimations(screen *ebiten.Image, pos *board.Position, dragging bool, dragSquare board.Square, anims *AnimationManager) {
 	for sq := board.A1; sq <= board.H8; sq++ {
 		// Skip the dragged piece
@@ -175,6 +286,11 @@ func (r *Renderer) DrawPiecesWithAnimations(screen *ebiten.Image, pos *board.Pos
 			continue
 		}
 
+		// Moving pieces are drawn on their way below
+		if r.moveAnim != nil && r.moveAnim.covers(sq) {
+			continue
+		}
+
 		piece := pos.PieceAt(sq)
 		if piece == board.NoPiece {
 			continue
@@ -192,6 +308,10 @@ func (r *Renderer) DrawPiecesWithAnimations(screen *ebiten.Image, pos *board.Pos
 		// Scale coordinates for HiDPI
 		r.sprites.DrawPieceAt(screen, piece, int(r.s(x)), int(r.s(y)))
 	}
+
+	if r.moveAnim != nil {
+		r.drawMoveAnimation(screen)
+	}
 }
 
 // DrawDraggedPiece draws the piece being dragged at the mouse position.
@@ -246,6 +366,13 @@ func (r *Renderer) SquareSize() int {
 	return r.squareSize
 }
 
+// SetTheme switches to a board theme (see BoardThemes) and piece set (see
+// PieceSets). Unknown ids select the defaults.
+func (r *Renderer) SetTheme(themeID, pieceSet string) {
+	r.theme = BoardThemes[boardThemeIndex(themeID)]
+	r.sprites.SetPieceSet(pieceSet)
+}
+
 // Theme returns the current theme.
 func (r *Renderer) Theme() *Theme {
 	return r.theme
@@ -261,6 +388,9 @@ var HintColor = color.RGBA{76, 175, 120, 180}       // Green
 var HintHighlightFrom = color.RGBA{76, 175, 120, 60}  // Light green for source
 var HintHighlightTo = color.RGBA{76, 175, 120, 100}   // Slightly darker for destination
 
+// HintAltColor is the color of the arrows of the other hint moves.
+var HintAltColor = color.RGBA{76, 175, 120, 90}
+
 // DrawHintArrow draws a visual hint arrow from one square to another.
 func (r *Renderer) DrawHintArrow(screen *ebiten.Image, from, to board.Square) {
 	if from == board.NoSquare || to == board.NoSquare {
@@ -271,16 +401,55 @@ func (r *Renderer) DrawHintArrow(screen *ebiten.Image, from, to board.Square) {
 	r.highlightSquare(screen, from, HintHighlightFrom)
 	r.highlightSquare(screen, to, HintHighlightTo)
 
+	r.drawArrow(screen, from, to, HintColor)
+}
+
+// DrawHintArrows draws the best of the hint moves like DrawHintArrow, and
+// the others as fainter arrows.
+func (r *Renderer) DrawHintArrows(screen *ebiten.Image, moves []board.Move) {
+	for i := len(moves) - 1; i > 0; i-- {
+		r.drawArrow(screen, moves[i].From(), moves[i].To(), HintAltColor)
+	}
+	if len(moves) > 0 {
+		r.DrawHintArrow(screen, moves[0].From(), moves[0].To())
+	}
+}
+
+// drawArrow draws an arrow between the centers of two squares.
+func (r *Renderer) drawArrow(screen *ebiten.Image, from, to board.Square, c color.RGBA) {
 	// Get center positions
 	fx, fy := r.squareCenter(from)
 	tx, ty := r.squareCenter(to)
 
 	// Draw arrow line
 	lineWidth := r.s(r.squareSize) * 0.08
-	vector.StrokeLine(screen, fx, fy, tx, ty, lineWidth, HintColor, false)
+	vector.StrokeLine(screen, fx, fy, tx, ty, lineWidth, c, false)
 
 	// Draw arrowhead
-	r.drawArrowhead(screen, fx, fy, tx, ty, HintColor)
+	r.drawArrowhead(screen, fx, fy, tx, ty, c)
+}
+
+// ShapeColors are the colors of board annotations, indexed by ShapeColor.
+var ShapeColors = [...]color.RGBA{
+	ShapeGreen:  {21, 120, 27, 200},
+	ShapeRed:    {136, 32, 32, 200},
+	ShapeBlue:   {0, 48, 136, 200},
+	ShapeYellow: {230, 143, 0, 200},
+}
+
+// DrawShapes draws the player's annotations: arrows, and circles around
+// single squares.
+func (r *Renderer) DrawShapes(screen *ebiten.Image, shapes []Shape) {
+	for _, s := range shapes {
+		c := ShapeColors[s.Color]
+		if s.From == s.To {
+			cx, cy := r.squareCenter(s.From)
+			width := r.s(r.squareSize) * 0.06
+			vector.StrokeCircle(screen, cx, cy, r.s(r.squareSize)/2-width, width, c, true)
+			continue
+		}
+		r.drawArrow(screen, s.From, s.To, c)
+	}
 }
 
 // squareCenter returns the center coordinates of a square (scaled).
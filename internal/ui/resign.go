@@ -0,0 +1,336 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/engine"
+)
+
+// Draw rules. A threefold repetition or 50 moves without a pawn move or
+// capture let the player to move claim a draw; a fivefold repetition or 75
+// such moves end the game on their own.
+const (
+	claimRepetitions = 3
+	autoRepetitions  = 5
+	claimHalfMoves   = 100
+	autoHalfMoves    = 150
+)
+
+// drawAcceptEval is the largest advantage, in centipawns from its own view,
+// at which the engine still accepts or claims a draw.
+const drawAcceptEval = 25
+
+// repetitionPenalty is what the engine gives up, in centipawns, to avoid a
+// move that lets the player claim a repetition after it declined a draw.
+const repetitionPenalty = 50
+
+// resignConfirmTime is how long the Resign button waits for the second click.
+const resignConfirmTime = 3 * time.Second
+
+// GameAction is an action on the game in progress offered in the panel.
+type GameAction int
+
+const (
+	ActionResign GameAction = iota
+	ActionOfferDraw
+	ActionAcceptDraw
+	ActionClaimDraw
+	ActionHint
+)
+
+// GameActions returns the actions shown in the panel while a game is played
+// by a person. A draw that can be claimed replaces the draw offer, and the
+// Hint button is left out while hints are shown on every move.
+func (g *Game) GameActions() []GameAction {
+	if g.gameOver || g.rush != nil || g.mode == ModeComputerVsComputer {
+		return nil
+	}
+
+	actions := []GameAction{ActionResign, ActionOfferDraw}
+	switch {
+	case g.drawClaim() != "":
+		actions[1] = ActionClaimDraw
+	case g.drawOffered && g.drawOfferBy != g.position.SideToMove:
+		actions[1] = ActionAcceptDraw
+	}
+	if !g.autoHints() {
+		actions = append(actions, ActionHint)
+	}
+	return actions
+}
+
+// GameActionEnabled returns true if the player may use an action now: only
+// on their turn, and hints only while some are left.
+func (g *Game) GameActionEnabled(a GameAction) bool {
+	if a == ActionHint {
+		return g.canRequestHint()
+	}
+	return g.humanToMove()
+}
+
+// DoGameAction carries out a panel action for the player to move.
+func (g *Game) DoGameAction(a GameAction) {
+	if !g.GameActionEnabled(a) {
+		return
+	}
+	switch a {
+	case ActionResign:
+		g.resign(g.position.SideToMove)
+	case ActionOfferDraw:
+		g.offerDraw()
+	case ActionAcceptDraw:
+		g.endInDraw("agreement")
+	case ActionClaimDraw:
+		if reason := g.drawClaim(); reason != "" {
+			g.endInDraw(reason)
+		}
+	case ActionHint:
+		g.RequestHint()
+	}
+}
+
+// resign ends the game as a loss for the given side.
+func (g *Game) resign(loser board.Color) {
+	if loser == board.White {
+		g.gameResult = "Black wins by resignation"
+		g.resultToken = "0-1"
+	} else {
+		g.gameResult = "White wins by resignation"
+		g.resultToken = "1-0"
+	}
+	g.feedback.OnResign(loser)
+	g.finishGame()
+}
+
+// offerDraw offers a draw to the opponent. The engine answers at once by
+// its evaluation; a person accepts on their next turn, and the offer lapses
+// once they move.
+func (g *Game) offerDraw() {
+	if g.mode == ModeHumanVsComputer {
+		accepted := g.engineEval() <= drawAcceptEval
+		g.feedback.OnDrawOfferAnswered(accepted)
+		if accepted {
+			g.endInDraw("agreement")
+		}
+		g.drawDeclined = !accepted
+		return
+	}
+	g.drawOffered = true
+	g.drawOfferBy = g.position.SideToMove
+	g.feedback.OnDrawOffered(g.drawOfferBy)
+}
+
+// endInDraw ends the game as a draw for the given reason.
+func (g *Game) endInDraw(reason string) {
+	g.gameResult = "Draw by " + reason
+	g.resultToken = "1/2-1/2"
+	g.feedback.OnDraw(reason)
+	g.finishGame()
+}
+
+// finishGame ends a game decided by the players rather than on the board,
+// and records the result as checkGameEnd does.
+func (g *Game) finishGame() {
+	g.gameOver = true
+	g.drawOffered = false
+	g.clearSelection()
+	g.clearPremove()
+	g.flushPerfLog()
+	g.recordEngineMatch()
+	g.autoSaveGame()
+	g.announce(g.gameResult)
+}
+
+// drawClaim returns the rule under which the side to move may claim a draw,
+// or "" if there is none.
+func (g *Game) drawClaim() string {
+	switch {
+	case g.repetitions() >= claimRepetitions:
+		return "threefold repetition"
+	case g.position.HalfMoveClock >= claimHalfMoves:
+		return "50-move rule"
+	}
+	return ""
+}
+
+// engineClaimsDraw returns true if the engine, on its turn against the
+// player, takes a draw that can be claimed: it does unless it is better.
+func (g *Game) engineClaimsDraw() bool {
+	if g.mode != ModeHumanVsComputer || g.position.SideToMove == g.playerColor || g.drawClaim() == "" {
+		return false
+	}
+	return -g.engineEval() <= drawAcceptEval
+}
+
+// engineEval returns the engine's view of the position in centipawns, from
+// its own side: the evaluation of its last search, or the static evaluation
+// before it has searched.
+func (g *Game) engineEval() int {
+	eval, ok := 0, false
+	for i := len(g.moveHistory) - 1; i >= 0 && !ok; i-- {
+		eval, ok = g.evals[i]
+	}
+	if !ok {
+		eval = engine.Evaluate(g.position)
+	}
+	if g.playerColor == board.White {
+		eval = -eval // The engine plays Black
+	}
+	return eval
+}
+
+// repetitions returns how often the current position has occurred in the
+// game, counting the current occurrence.
+func (g *Game) repetitions() int {
+	return g.occurrences(g.position.Hash)
+}
+
+// occurrences returns how often a position has occurred in the game.
+func (g *Game) occurrences(hash uint64) int {
+	count := 0
+	for _, h := range g.positionHashes {
+		if h == hash {
+			count++
+		}
+	}
+	return count
+}
+
+// repeatingMoves returns the moves of the side to move after which the
+// opponent could claim a threefold repetition.
+func (g *Game) repeatingMoves() []board.Move {
+	var moves []board.Move
+	legal := g.position.GenerateLegalMoves()
+	for i := 0; i < legal.Len(); i++ {
+		pos := g.position.Copy()
+		pos.MakeMove(legal.Get(i))
+		if g.occurrences(pos.Hash)+1 >= claimRepetitions {
+			moves = append(moves, legal.Get(i))
+		}
+	}
+	return moves
+}
+
+// Game actions row, between the move list (or coach) and the status bar
+const (
+	gameActionsH    = 40
+	gameActionsBtnH = 26
+)
+
+// gameActionsHeight returns the height taken by the game actions row.
+func (p *Panel) gameActionsHeight() int {
+	if len(p.game.GameActions()) == 0 {
+		return 0
+	}
+	return gameActionsH
+}
+
+// layoutGameActions places one button per game action in a row.
+func (p *Panel) layoutGameActions() {
+	actions := p.game.GameActions()
+	if len(actions) != len(p.actionBtns) {
+		p.actionBtns = make([]*Button, len(actions))
+		for i := range p.actionBtns {
+			p.actionBtns[i] = &Button{}
+		}
+	}
+
+	row := Rect{X: BoardSize + PanelPadding, Y: ScreenHeight - 70 - gameActionsH, W: PanelWidth - PanelPadding*2, H: gameActionsBtnH}
+	for i, col := range row.Columns(len(actions), 8) {
+		a := actions[i]
+		btn := p.actionBtns[i]
+		btn.Rect = col
+		btn.Label = p.gameActionLabel(a)
+		btn.Tooltip = p.gameActionTooltip(a, !p.game.GameActionEnabled(a))
+		btn.disabled = !p.game.GameActionEnabled(a)
+		btn.OnClick = func() { p.clickGameAction(a) }
+	}
+}
+
+// gameActionLabel returns the button label of an action.
+func (p *Panel) gameActionLabel(a GameAction) string {
+	switch a {
+	case ActionResign:
+		if time.Since(p.resignArmedAt) < resignConfirmTime {
+			return "Confirm?"
+		}
+		return "Resign"
+	case ActionOfferDraw:
+		return "Offer draw"
+	case ActionAcceptDraw:
+		return "Accept draw"
+	case ActionClaimDraw:
+		return "Claim draw"
+	case ActionHint:
+		if left := p.game.HintsLeft(); left >= 0 {
+			return fmt.Sprintf("Hint (%d)", left)
+		}
+		return "Hint"
+	}
+	return ""
+}
+
+// gameActionTooltip returns the tooltip of an action, or why it cannot be
+// used when disabled.
+func (p *Panel) gameActionTooltip(a GameAction, disabled bool) string {
+	switch {
+	case disabled && a == ActionHint && p.game.HintsLeft() == 0:
+		return "No hints left in this game"
+	case disabled && a == ActionHint && p.game.hintRequested:
+		return "The hint is shown until you move"
+	case disabled:
+		return "Only on your turn"
+	}
+	switch a {
+	case ActionResign:
+		return "Give up the game; click again to confirm"
+	case ActionOfferDraw:
+		if p.game.GameMode() == ModeHumanVsComputer {
+			return "The engine accepts unless it is better"
+		}
+		return "Your opponent may accept on their turn"
+	case ActionAcceptDraw:
+		return "End the game in a draw as offered"
+	case ActionClaimDraw:
+		return "Draw by " + p.game.drawClaim()
+	case ActionHint:
+		return "Show the engine's best moves for this position"
+	}
+	return ""
+}
+
+// clickGameAction runs an action; resigning takes a second click.
+func (p *Panel) clickGameAction(a GameAction) {
+	if a == ActionResign && time.Since(p.resignArmedAt) >= resignConfirmTime {
+		p.resignArmedAt = time.Now()
+		return
+	}
+	p.resignArmedAt = time.Time{}
+	log.Printf("[Panel] Game action %d", a)
+	p.game.DoGameAction(a)
+}
+
+// drawGameActions draws the game action buttons, dimmed while the player
+// may not use them.
+func (p *Panel) drawGameActions(screen *ebiten.Image) {
+	if len(p.game.GameActions()) == 0 {
+		return
+	}
+	// Cover the last move list row, which may reach into the row
+	vector.DrawFilledRect(screen, p.s(BoardSize), p.s(ScreenHeight-70-gameActionsH),
+		p.s(PanelWidth), p.s(gameActionsBtnH), panelBg, false)
+
+	p.layoutGameActions()
+	for _, btn := range p.actionBtns {
+		if btn.disabled {
+			p.drawDisabledButton(screen, btn)
+			continue
+		}
+		p.drawSecondaryButton(screen, btn)
+	}
+}
@@ -0,0 +1,384 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/board"
+	"github.com/hailam/chessplay/internal/puzzle"
+	"github.com/hailam/chessplay/internal/storage"
+)
+
+// Rush modal dimensions
+const (
+	RushWidth  = 380
+	RushHeight = 520
+	RushPadX   = 24
+	RushPadY   = 20
+)
+
+// Delays before the opponent's reply and the next puzzle, so the player
+// sees each move
+const (
+	rushReplyDelay  = 400 * time.Millisecond
+	rushSolvedDelay = 700 * time.Millisecond
+	rushFailedDelay = 1500 * time.Millisecond
+)
+
+// rushDurations are the rush lengths offered, in ButtonGroup order.
+var rushDurations = []time.Duration{puzzle.RushShort, puzzle.RushLong}
+
+// rushResult is the outcome of the last rush, shown when the modal reopens.
+type rushResult struct {
+	score    int
+	duration time.Duration
+	rank     int // High score table rank (0 = not ranked)
+}
+
+// RushModal starts a puzzle rush and shows the high scores.
+type RushModal struct {
+	visible      bool
+	needsCapture bool // Set true when opening to capture background
+
+	// Position (centered on screen)
+	x, y int
+
+	// Widgets
+	durationGroup *ButtonGroup
+	startBtn      *ModalButton
+	closeBtn      *ModalButton
+
+	result *rushResult         // Last rush (nil = none)
+	scores []storage.RushScore // High scores of the selected duration
+
+	loadScores func(time.Duration) []storage.RushScore
+	onStart    func(time.Duration)
+}
+
+// NewRushModal creates a new puzzle rush modal.
+func NewRushModal() *RushModal {
+	rm := &RushModal{}
+	rm.x = (ScreenWidth - RushWidth) / 2
+	rm.y = (ScreenHeight - RushHeight) / 2
+
+	contentW := RushWidth - RushPadX*2
+	options := make([]string, len(rushDurations))
+	for i, d := range rushDurations {
+		options[i] = fmt.Sprintf("%d minutes", int(d.Minutes()))
+	}
+	rm.durationGroup = NewButtonGroup(rm.x+RushPadX, rm.y+106, options, 0, contentW/len(options), 32)
+
+	btnW, btnH := 100, 38
+	btnY := rm.y + RushHeight - RushPadY - btnH
+	rm.closeBtn = NewModalButton(rm.x+RushWidth-RushPadX-btnW*2-12, btnY, btnW, btnH, "Close", false, rm.Hide)
+	rm.startBtn = NewModalButton(rm.x+RushWidth-RushPadX-btnW, btnY, btnW, btnH, "Start", true, nil)
+	rm.startBtn.OnClick = rm.handleStart
+	return rm
+}
+
+// Show opens the modal. result is the rush that just ended, or nil.
+// loadScores returns the high scores of a duration; onStart starts a rush.
+func (rm *RushModal) Show(result *rushResult, loadScores func(time.Duration) []storage.RushScore, onStart func(time.Duration)) {
+	rm.visible = true
+	rm.needsCapture = true
+	rm.result = result
+	rm.loadScores = loadScores
+	rm.onStart = onStart
+	if result != nil {
+		for i, d := range rushDurations {
+			if d == result.duration {
+				rm.durationGroup.Selected = i
+			}
+		}
+	}
+	rm.refresh()
+}
+
+// Hide closes the modal.
+func (rm *RushModal) Hide() {
+	rm.visible = false
+}
+
+// IsVisible returns true if the modal is visible.
+func (rm *RushModal) IsVisible() bool {
+	return rm.visible
+}
+
+// duration returns the selected rush length.
+func (rm *RushModal) duration() time.Duration {
+	return rushDurations[rm.durationGroup.Selected]
+}
+
+// refresh loads the high scores of the selected duration.
+func (rm *RushModal) refresh() {
+	rm.scores = nil
+	if rm.loadScores != nil {
+		rm.scores = rm.loadScores(rm.duration())
+	}
+}
+
+// handleStart closes the modal and starts a rush.
+func (rm *RushModal) handleStart() {
+	rm.Hide()
+	if rm.onStart != nil {
+		rm.onStart(rm.duration())
+	}
+}
+
+// Update handles input for the rush modal.
+func (rm *RushModal) Update(input *InputHandler) bool {
+	if !rm.visible {
+		return false
+	}
+
+	if IsKeyJustPressed(ebiten.KeyEscape) {
+		rm.Hide()
+		return true
+	}
+	if IsKeyJustPressed(ebiten.KeyEnter) {
+		rm.handleStart()
+		return true
+	}
+
+	if rm.durationGroup.Update(input) {
+		rm.result = nil
+		rm.refresh()
+	}
+	rm.startBtn.Update(input)
+	rm.closeBtn.Update(input)
+
+	// Modal consumes all input
+	return true
+}
+
+// AnyButtonHovered returns true if any button in the modal is hovered.
+func (rm *RushModal) AnyButtonHovered() bool {
+	if !rm.visible {
+		return false
+	}
+	return rm.durationGroup.hovered >= 0 || rm.startBtn.IsHovered() || rm.closeBtn.IsHovered()
+}
+
+// Draw renders the rush modal.
+func (rm *RushModal) Draw(screen *ebiten.Image, glass *GlassEffect) {
+	if !rm.visible {
+		return
+	}
+
+	// Capture background once when modal first opens (fixes flicker)
+	if rm.needsCapture && glass != nil && glass.IsEnabled() {
+		glass.CaptureForModal(screen, 3.0)
+		rm.needsCapture = false
+	}
+
+	if glass != nil && glass.IsEnabled() {
+		glass.DrawModalBackground(screen, 0.4)
+	} else {
+		vector.DrawFilledRect(screen, 0, 0, scaleF(ScreenWidth), scaleF(ScreenHeight), modalOverlay, false)
+	}
+
+	// Modal background, border and header
+	vector.DrawFilledRect(screen, scaleF(rm.x), scaleF(rm.y), scaleF(RushWidth), scaleF(RushHeight), modalBg, false)
+	vector.StrokeRect(screen, scaleF(rm.x), scaleF(rm.y), scaleF(RushWidth), scaleF(RushHeight), float32(UIScale*2), modalBorder, false)
+	vector.DrawFilledRect(screen, scaleF(rm.x), scaleF(rm.y), scaleF(RushWidth), scaleF(44), modalHeader, false)
+	rm.drawTitle(screen)
+
+	contentX := rm.x + RushPadX
+	rightX := rm.x + RushWidth - RushPadX
+
+	drawText(screen, "Solve as many puzzles as you can before", contentX, rm.y+56, textMuted)
+	drawText(screen, fmt.Sprintf("time runs out. %d mistakes end the run.", puzzle.MaxMistakes), contentX, rm.y+76, textMuted)
+	rm.durationGroup.Draw(screen)
+
+	y := rm.durationGroup.Y + rm.durationGroup.ButtonH + 20
+	if r := rm.result; r != nil {
+		drawText(screen, fmt.Sprintf("You solved %d puzzles", r.score), contentX, y, textPrimary)
+		switch {
+		case r.rank == 1:
+			drawTextRight(screen, "New best!", rightX, y, tbSuccessColor)
+		case r.rank > 1:
+			drawTextRight(screen, fmt.Sprintf("#%d", r.rank), rightX, y, accentColor)
+		}
+		y += 32
+	}
+
+	// High scores of the selected duration
+	drawText(screen, "High Scores", contentX, y, textMuted)
+	y += 24
+	if len(rm.scores) == 0 {
+		drawText(screen, "No runs yet", contentX, y, textSecondary)
+	}
+	for i, s := range rm.scores {
+		c := textSecondary
+		if r := rm.result; r != nil && r.rank == i+1 {
+			c = accentColor
+		}
+		drawText(screen, fmt.Sprintf("%2d.  %d", i+1, s.Score), contentX, y, c)
+		drawTextRight(screen, s.Date.Local().Format("2 Jan 2006 15:04"), rightX, y, c)
+		y += 20
+	}
+
+	rm.closeBtn.Draw(screen)
+	rm.startBtn.Draw(screen)
+}
+
+// drawTitle draws the modal title.
+func (rm *RushModal) drawTitle(screen *ebiten.Image) {
+	face := GetBoldFace()
+	if face == nil {
+		return
+	}
+
+	title := "Puzzle Rush"
+	w, h := MeasureText(title, face)
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(scaleD(rm.x)+scaleD(RushWidth)/2-w/2, scaleD(rm.y)+scaleD(22)-h/2)
+	op.ColorScale.ScaleWithColor(textPrimary)
+	text.Draw(screen, title, face, op)
+}
+
+// ShowRush opens the puzzle rush modal.
+func (g *Game) ShowRush() {
+	g.rushModal.Show(nil, g.rushScores, g.startRush)
+}
+
+// rushScores returns the high scores of a rush duration.
+func (g *Game) rushScores(d time.Duration) []storage.RushScore {
+	if g.storage == nil {
+		return nil
+	}
+	scores, err := g.storage.RushScores(d)
+	if err != nil {
+		log.Printf("Warning: Failed to load rush scores: %v", err)
+	}
+	return scores
+}
+
+// startRush starts a puzzle rush of the given length.
+func (g *Game) startRush(d time.Duration) {
+	// The player solves the puzzles, so an engine match ends here
+	if g.mode == ModeComputerVsComputer {
+		g.SetModeAction(ModeHumanVsComputer)
+	}
+	now := time.Now()
+	g.showRushPuzzle(puzzle.NewRush(d, now, now.UnixNano()))
+}
+
+// showRushPuzzle sets up the board for the current puzzle of a rush.
+// resetGame ends any rush, so the rush is set again afterwards.
+func (g *Game) showRushPuzzle(r *puzzle.Rush) {
+	pos := r.Position()
+	if pos == nil {
+		g.rush = r
+		g.endRush()
+		return
+	}
+	g.startFEN = pos.ToFEN()
+	g.resetGame(pos)
+	g.rush = r
+	g.renderer.SetFlipped(pos.SideToMove == board.Black)
+}
+
+// playRushMove checks the player's move against the puzzle solution.
+func (g *Game) playRushMove(m board.Move) {
+	solution := g.rush.Solution()
+	solutionSAN := ""
+	if solution != board.NoMove {
+		solutionSAN = g.moveToSAN(solution)
+	}
+
+	result, reply := g.rush.Play(m)
+	now := time.Now()
+	switch result {
+	case puzzle.Wrong:
+		g.clearSelection()
+		g.feedback.OnPuzzleFailed(solutionSAN, g.rush.Mistakes)
+		g.rushAt = now.Add(rushFailedDelay)
+	case puzzle.Correct:
+		g.showRushMove(m)
+		g.rushReply = reply
+		g.rushAt = now.Add(rushReplyDelay)
+	case puzzle.Solved:
+		g.showRushMove(m)
+		g.feedback.OnPuzzleSolved(g.rush.Score)
+		g.rushAt = now.Add(rushSolvedDelay)
+	}
+}
+
+// showRushMove plays a move on the board without the game-end checks and
+// engine turn of makeMove.
+func (g *Game) showRushMove(m board.Move) {
+	isCapture := m.IsCapture(g.position)
+	isCastling := m.IsCastling()
+	g.sanHistory = append(g.sanHistory, g.moveToSAN(m))
+	g.moveTimes = append(g.moveTimes, 0)
+	g.position.MakeMove(m)
+	g.position.UpdateCheckers()
+	g.moveHistory = append(g.moveHistory, m)
+	g.positionHashes = append(g.positionHashes, g.position.Hash)
+	g.lastMove = m
+	g.clearSelection()
+	g.feedback.OnMoveMade(isCapture, isCastling)
+}
+
+// updateRush runs the rush clock and plays pending replies and puzzles.
+func (g *Game) updateRush() {
+	if g.rush == nil {
+		return
+	}
+	now := time.Now()
+	if g.rush.Remaining(now) == 0 {
+		g.endRush()
+		return
+	}
+	if g.rushAt.IsZero() || now.Before(g.rushAt) {
+		return
+	}
+
+	g.rushAt = time.Time{}
+	if g.rushReply != board.NoMove {
+		g.showRushMove(g.rushReply)
+		g.rushReply = board.NoMove
+		return
+	}
+	if g.rush.Over(now) || !g.rush.Next() {
+		g.endRush()
+		return
+	}
+	g.showRushPuzzle(g.rush)
+}
+
+// endRush finishes the rush, records the score and shows the high scores.
+func (g *Game) endRush() {
+	r := g.rush
+	g.rush = nil
+	g.rushAt = time.Time{}
+	g.rushReply = board.NoMove
+	g.clearSelection()
+	g.gameOver = true
+	g.gameResult = fmt.Sprintf("Puzzle rush over: %d solved", r.Score)
+
+	rank := 0
+	if g.storage != nil {
+		var err error
+		rank, err = g.storage.SaveRushScore(storage.RushScore{Score: r.Score, Duration: r.Duration, Date: time.Now()})
+		if err != nil {
+			log.Printf("Warning: Failed to save rush score: %v", err)
+		}
+	}
+	g.feedback.OnRushOver(r.Score, rank)
+	g.rushModal.Show(&rushResult{score: r.Score, duration: r.Duration, rank: rank}, g.rushScores, g.startRush)
+}
+
+// RushStatus returns the time left, puzzles solved and mistakes of a
+// running rush; ok is false when no rush is running.
+func (g *Game) RushStatus() (remaining time.Duration, score, mistakes int, ok bool) {
+	if g.rush == nil {
+		return 0, 0, 0, false
+	}
+	return g.rush.Remaining(time.Now()), g.rush.Score, g.rush.Mistakes, true
+}
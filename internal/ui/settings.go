@@ -2,19 +2,27 @@ package ui
 
 import (
 	"image/color"
+	"path/filepath"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/board"
 	"github.com/hailam/chessplay/internal/storage"
 )
 
 // Settings modal dimensions
 const (
-	SettingsWidth  = 380
-	SettingsHeight = 540 // Increased for player color option
+	SettingsWidth  = 940 // Game options, appearance and the engine process in columns
+	SettingsHeight = 628 // Increased for player color and board options
 	SettingsPadX   = 24
 	SettingsPadY   = 20
+
+	settingsColumnW   = 332 // Width of the game options column
+	settingsColumnGap = 36
+	settingsThemeW    = 284 // Width of the appearance column
+	themePreviewCols  = 6   // Squares per row of the theme preview
 )
 
 // Settings modal colors
@@ -39,12 +47,38 @@ type SettingsModal struct {
 	playerColorRadio *RadioGroup
 	difficultyBtns   *ButtonGroup
 	soundCheckbox    *Checkbox
+	autoFlipCheckbox *Checkbox
+	coachCheckbox    *Checkbox
+	animateCheckbox  *Checkbox
+	networkDropdown  *Dropdown
+	boardThemeBtns   *ButtonGroup
+	pieceSetBtns     *ButtonGroup
+	soundPackBtns    *ButtonGroup
+	speakCheckbox    *Checkbox
+	hintLimitBtns    *ButtonGroup
+	chancesCheckbox  *Checkbox
+	ponderCheckbox   *Checkbox
+	updatesCheckbox  *Checkbox
+	explorerCheckbox *Checkbox
+	processCheckbox  *Checkbox      // Run the engine as a child process
+	enginePathInput  *TextInput     // Engine the process runs ("" = chessplay-uci)
+	sparringInput    *TextInput     // Sparring engine program
+	engineOptionsBtn *ModalButton   // Options of the sparring engine
+	previewSprites   *SpriteManager // Pieces of the theme preview
+	previewX         int
+	previewY         int
+	previewSquare    int
+	tablebasesBtn    *ModalButton
 	saveBtn          *ModalButton
 	cancelBtn        *ModalButton
+	profilesBtn      *ModalButton
 
 	// Callbacks
-	onSave   func(prefs *storage.UserPreferences)
-	onCancel func()
+	onSave       func(prefs *storage.UserPreferences)
+	onCancel     func()
+	onProfiles   func()
+	onTablebases func()
+	onOptions    func()
 
 	// Original values (for cancel)
 	originalPrefs *storage.UserPreferences
@@ -67,7 +101,7 @@ func (sm *SettingsModal) calculatePosition() {
 // createWidgets initializes all settings widgets.
 func (sm *SettingsModal) createWidgets() {
 	contentX := sm.x + SettingsPadX
-	contentW := SettingsWidth - SettingsPadX*2
+	contentW := settingsColumnW
 
 	// Username input (below header)
 	inputY := sm.y + 60
@@ -96,6 +130,64 @@ func (sm *SettingsModal) createWidgets() {
 	checkY := diffY + 70
 	sm.soundCheckbox = NewCheckbox(contentX, checkY, "Sound Effects", true)
 
+	// Auto-flip checkbox
+	flipY := checkY + 60
+	sm.autoFlipCheckbox = NewCheckbox(contentX, flipY, "Auto-flip board in Human vs Human", false)
+
+	// Coach commentary checkbox
+	sm.coachCheckbox = NewCheckbox(contentX, flipY+28, "Coach commentary", false)
+
+	// Move animation checkbox
+	sm.animateCheckbox = NewCheckbox(contentX, flipY+56, "Animate moves", true)
+
+	// NNUE network dropdown (options are filled in by Show), with the
+	// tablebase downloads next to it
+	networkY := flipY + 100
+	tbBtnW := 110
+	sm.networkDropdown = NewDropdown(contentX, networkY, contentW-tbBtnW-8, 32, nil, 0)
+	sm.tablebasesBtn = NewModalButton(contentX+contentW-tbBtnW, networkY, tbBtnW, 32, "Tablebases", false, nil)
+
+	// Appearance column: board theme, piece set with a preview, sound pack
+	themeX := contentX + settingsColumnW + settingsColumnGap
+	themeW := settingsThemeW
+	var names []string
+	for _, t := range BoardThemes {
+		names = append(names, t.Name)
+	}
+	sm.boardThemeBtns = NewButtonGroup(themeX, inputY, names, 0, themeW/len(names), 34)
+	names = nil
+	for _, ps := range PieceSets {
+		names = append(names, ps.Name)
+	}
+	sm.pieceSetBtns = NewButtonGroup(themeX, inputY+70, names, 0, themeW/len(names), 34)
+	sm.previewX, sm.previewY = themeX, inputY+124
+	sm.previewSquare = themeW / themePreviewCols
+	sm.previewSprites = NewSpriteManager(sm.previewSquare)
+	names = nil
+	for _, sp := range SoundPacks {
+		names = append(names, sp.Name)
+	}
+	sm.soundPackBtns = NewButtonGroup(themeX, sm.previewY+sm.previewSquare*2+44, names, 0, themeW/len(names), 34)
+	sm.speakCheckbox = NewCheckbox(themeX, sm.soundPackBtns.Y+50, "Announce moves aloud", false)
+	names = nil
+	for _, l := range HintLimits {
+		names = append(names, hintLimitName(l))
+	}
+	sm.hintLimitBtns = NewButtonGroup(themeX, sm.speakCheckbox.Y+60, names, 0, themeW/len(names), 34)
+	sm.chancesCheckbox = NewCheckbox(themeX, sm.hintLimitBtns.Y+50, "Show practical chances", false)
+	sm.ponderCheckbox = NewCheckbox(themeX, sm.chancesCheckbox.Y+28, "Think on your time", false)
+	sm.updatesCheckbox = NewCheckbox(themeX, sm.ponderCheckbox.Y+28, "Check for updates", false)
+	sm.explorerCheckbox = NewCheckbox(themeX, sm.updatesCheckbox.Y+28, "Opening explorer (Lichess)", false)
+
+	// Engine column: the computer's moves from a child process, so a crash
+	// in the search cannot end the game, optionally from another engine
+	engineX := themeX + themeW + settingsColumnGap
+	engineW := SettingsWidth - SettingsPadX - (engineX - sm.x)
+	sm.processCheckbox = NewCheckbox(engineX, inputY+8, "Separate process", false)
+	sm.enginePathInput = NewTextInput(engineX, inputY+70, engineW, 36, "chessplay-uci", 260)
+	sm.sparringInput = NewTextInput(engineX, inputY+140, engineW, 36, "e.g. stockfish", 260)
+	sm.engineOptionsBtn = NewModalButton(engineX, inputY+186, engineW, 32, "Engine Options", false, nil)
+
 	// Buttons at bottom
 	btnW = 100
 	btnH := 38
@@ -110,14 +202,21 @@ func (sm *SettingsModal) createWidgets() {
 		sm.x+SettingsWidth-SettingsPadX-btnW,
 		btnY, btnW, btnH, "Save", true, nil,
 	)
+	sm.profilesBtn = NewModalButton(sm.x+SettingsPadX, btnY, btnW, btnH, "Profiles", false, nil)
 }
 
 // Show displays the settings modal with the given preferences.
-func (sm *SettingsModal) Show(prefs *storage.UserPreferences, onSave func(*storage.UserPreferences), onCancel func()) {
+// onProfiles is called when the user asks to switch profiles, onTablebases
+// when they open the tablebase downloads, onOptions when they open the
+// sparring engine's options.
+func (sm *SettingsModal) Show(prefs *storage.UserPreferences, onSave func(*storage.UserPreferences), onCancel func(), onProfiles func(), onTablebases func(), onOptions func()) {
 	sm.visible = true
 	sm.needsCapture = true // Capture background on first draw
 	sm.onSave = onSave
 	sm.onCancel = onCancel
+	sm.onProfiles = onProfiles
+	sm.onTablebases = onTablebases
+	sm.onOptions = onOptions
 
 	// Store original for cancel
 	sm.originalPrefs = &storage.UserPreferences{
@@ -126,6 +225,24 @@ func (sm *SettingsModal) Show(prefs *storage.UserPreferences, onSave func(*stora
 		EvalMode:     prefs.EvalMode,
 		PlayerColor:  prefs.PlayerColor,
 		SoundEnabled: prefs.SoundEnabled,
+		AutoFlip:     prefs.AutoFlip,
+		Coach:        prefs.Coach,
+		NNUENetwork:  prefs.NNUENetwork,
+		BoardTheme:   prefs.BoardTheme,
+		PieceSet:     prefs.PieceSet,
+		SoundPack:    prefs.SoundPack,
+		SpeakMoves:   prefs.SpeakMoves,
+		HintLimit:    prefs.HintLimit,
+		Chances:      prefs.Chances,
+		Ponder:       prefs.Ponder,
+		CheckUpdates: prefs.CheckUpdates,
+		InstantMoves: prefs.InstantMoves,
+		Explorer:     prefs.Explorer,
+
+		EngineProcess: prefs.EngineProcess,
+		EnginePath:    prefs.EnginePath,
+
+		SparringEngine: prefs.SparringEngine,
 	}
 
 	// Load current values into widgets
@@ -134,16 +251,46 @@ func (sm *SettingsModal) Show(prefs *storage.UserPreferences, onSave func(*stora
 	sm.evalModeRadio.Selected = int(prefs.EvalMode)
 	sm.difficultyBtns.Selected = int(prefs.Difficulty)
 	sm.soundCheckbox.Checked = prefs.SoundEnabled
+	sm.autoFlipCheckbox.Checked = prefs.AutoFlip
+	sm.coachCheckbox.Checked = prefs.Coach
+	sm.animateCheckbox.Checked = !prefs.InstantMoves
+	sm.boardThemeBtns.Selected = boardThemeIndex(prefs.BoardTheme)
+	sm.pieceSetBtns.Selected = pieceSetIndex(prefs.PieceSet)
+	sm.soundPackBtns.Selected = soundPackIndex(prefs.SoundPack)
+	sm.speakCheckbox.Checked = prefs.SpeakMoves
+	sm.hintLimitBtns.Selected = hintLimitIndex(prefs.HintLimit)
+	sm.chancesCheckbox.Checked = prefs.Chances
+	sm.ponderCheckbox.Checked = prefs.Ponder
+	sm.updatesCheckbox.Checked = prefs.CheckUpdates
+	sm.explorerCheckbox.Checked = prefs.Explorer
+	sm.processCheckbox.Checked = prefs.EngineProcess
+	sm.enginePathInput.Value = prefs.EnginePath
+	sm.sparringInput.Value = prefs.SparringEngine
+
+	// Networks are detected each time the modal opens, so new files show up
+	options := []DropdownOption{{Label: "Auto (newest)", Value: ""}}
+	for _, f := range DetectNNUENetworks() {
+		if f.Big {
+			options = append(options, DropdownOption{Label: filepath.Base(f.Path), Value: f.Path})
+		}
+	}
+	sm.networkDropdown.SetOptions(options, prefs.NNUENetwork)
 
 	// Set button callbacks
 	sm.saveBtn.OnClick = sm.handleSave
 	sm.cancelBtn.OnClick = sm.handleCancel
+	sm.profilesBtn.OnClick = sm.handleProfiles
+	sm.tablebasesBtn.OnClick = sm.handleTablebases
+	sm.engineOptionsBtn.OnClick = sm.handleOptions
 }
 
 // Hide closes the settings modal.
 func (sm *SettingsModal) Hide() {
 	sm.visible = false
 	sm.usernameInput.SetFocused(false)
+	sm.enginePathInput.SetFocused(false)
+	sm.sparringInput.SetFocused(false)
+	sm.networkDropdown.Close()
 }
 
 // IsVisible returns true if the modal is visible.
@@ -159,6 +306,24 @@ func (sm *SettingsModal) handleSave() {
 		EvalMode:     storage.EvalMode(sm.evalModeRadio.Selected),
 		PlayerColor:  storage.PlayerColor(sm.playerColorRadio.Selected),
 		SoundEnabled: sm.soundCheckbox.Checked,
+		AutoFlip:     sm.autoFlipCheckbox.Checked,
+		Coach:        sm.coachCheckbox.Checked,
+		NNUENetwork:  sm.networkDropdown.Value(),
+		BoardTheme:   BoardThemes[sm.boardThemeBtns.Selected].ID,
+		PieceSet:     PieceSets[sm.pieceSetBtns.Selected].ID,
+		SoundPack:    SoundPacks[sm.soundPackBtns.Selected].ID,
+		SpeakMoves:   sm.speakCheckbox.Checked,
+		HintLimit:    HintLimits[sm.hintLimitBtns.Selected],
+		Chances:      sm.chancesCheckbox.Checked,
+		Ponder:       sm.ponderCheckbox.Checked,
+		CheckUpdates: sm.updatesCheckbox.Checked,
+		InstantMoves: !sm.animateCheckbox.Checked,
+		Explorer:     sm.explorerCheckbox.Checked,
+
+		EngineProcess: sm.processCheckbox.Checked,
+		EnginePath:    strings.TrimSpace(sm.enginePathInput.Value),
+
+		SparringEngine: strings.TrimSpace(sm.sparringInput.Value),
 	}
 
 	// Use default name if empty
@@ -180,32 +345,87 @@ func (sm *SettingsModal) handleCancel() {
 	sm.Hide()
 }
 
+// handleProfiles discards changes and opens the profile switcher.
+func (sm *SettingsModal) handleProfiles() {
+	sm.Hide()
+	if sm.onProfiles != nil {
+		sm.onProfiles()
+	}
+}
+
+// handleTablebases discards changes and opens the tablebase downloads.
+func (sm *SettingsModal) handleTablebases() {
+	sm.Hide()
+	if sm.onTablebases != nil {
+		sm.onTablebases()
+	}
+}
+
+// handleOptions saves settings, so a sparring engine just entered is the
+// one asked, and opens the engine's options.
+func (sm *SettingsModal) handleOptions() {
+	sm.handleSave()
+	if sm.onOptions != nil {
+		sm.onOptions()
+	}
+}
+
 // Update handles input for the settings modal.
 func (sm *SettingsModal) Update(input *InputHandler) bool {
 	if !sm.visible {
 		return false
 	}
 
-	// Handle escape key to close
+	// Handle escape key to close (the network list first, if open)
+	if IsKeyJustPressed(ebiten.KeyEscape) && sm.networkDropdown.IsOpen() {
+		sm.networkDropdown.Close()
+		return true
+	}
 	if IsKeyJustPressed(ebiten.KeyEscape) {
 		sm.handleCancel()
 		return true
 	}
 
 	// Handle enter key to save
-	if IsKeyJustPressed(ebiten.KeyEnter) && !sm.usernameInput.IsFocused() {
+	if IsKeyJustPressed(ebiten.KeyEnter) && !sm.usernameInput.IsFocused() && !sm.enginePathInput.IsFocused() &&
+		!sm.sparringInput.IsFocused() {
 		sm.handleSave()
 		return true
 	}
 
+	// An open network list covers the widgets below it
+	if sm.networkDropdown.Update(input) {
+		return true
+	}
+
 	// Update widgets
 	sm.usernameInput.Update(input)
 	sm.playerColorRadio.Update(input)
 	sm.evalModeRadio.Update(input)
 	sm.difficultyBtns.Update(input)
 	sm.soundCheckbox.Update(input)
+	sm.autoFlipCheckbox.Update(input)
+	sm.coachCheckbox.Update(input)
+	sm.animateCheckbox.Update(input)
+	sm.boardThemeBtns.Update(input)
+	sm.pieceSetBtns.Update(input)
+	sm.soundPackBtns.Update(input)
+	sm.speakCheckbox.Update(input)
+	sm.hintLimitBtns.Update(input)
+	sm.chancesCheckbox.Update(input)
+	sm.ponderCheckbox.Update(input)
+	sm.updatesCheckbox.Update(input)
+	sm.explorerCheckbox.Update(input)
+	if engineProcessSupported {
+		sm.processCheckbox.Update(input)
+		sm.enginePathInput.Update(input)
+		sm.sparringInput.Update(input)
+		sm.engineOptionsBtn.Update(input)
+	}
 	sm.saveBtn.Update(input)
 	sm.cancelBtn.Update(input)
+	sm.profilesBtn.Update(input)
+	sm.tablebasesBtn.Update(input)
 
 	// Modal consumes all input
 	return true
@@ -216,9 +436,14 @@ func (sm *SettingsModal) AnyButtonHovered() bool {
 	if !sm.visible {
 		return false
 	}
-	return sm.saveBtn.IsHovered() || sm.cancelBtn.IsHovered() ||
+	return sm.saveBtn.IsHovered() || sm.cancelBtn.IsHovered() || sm.profilesBtn.IsHovered() ||
+		sm.tablebasesBtn.IsHovered() ||
 		sm.playerColorRadio.hovered >= 0 || sm.evalModeRadio.hovered >= 0 ||
-		sm.difficultyBtns.hovered >= 0 || sm.soundCheckbox.hovered
+		sm.difficultyBtns.hovered >= 0 || sm.soundCheckbox.hovered || sm.autoFlipCheckbox.hovered ||
+		sm.coachCheckbox.hovered || sm.animateCheckbox.hovered || sm.boardThemeBtns.hovered >= 0 || sm.pieceSetBtns.hovered >= 0 ||
+		sm.soundPackBtns.hovered >= 0 || sm.speakCheckbox.hovered || sm.hintLimitBtns.hovered >= 0 ||
+		sm.chancesCheckbox.hovered || sm.ponderCheckbox.hovered || sm.updatesCheckbox.hovered || sm.explorerCheckbox.hovered || sm.processCheckbox.hovered || sm.engineOptionsBtn.IsHovered() ||
+		sm.networkDropdown.hovered || sm.networkDropdown.hoveredOpt >= 0
 }
 
 // Draw renders the settings modal.
@@ -260,6 +485,17 @@ func (sm *SettingsModal) Draw(screen *ebiten.Image, glass *GlassEffect) {
 	sm.drawSectionLabel(screen, "Engine Mode", contentX, sm.playerColorRadio.Y+sm.playerColorRadio.ItemH*len(sm.playerColorRadio.Options)+8)
 	sm.drawSectionLabel(screen, "Difficulty", contentX, sm.evalModeRadio.Y+sm.evalModeRadio.ItemH*len(sm.evalModeRadio.Options)+8)
 	sm.drawSectionLabel(screen, "Audio", contentX, sm.difficultyBtns.Y+sm.difficultyBtns.ButtonH+16)
+	sm.drawSectionLabel(screen, "Board", contentX, sm.autoFlipCheckbox.Y-20)
+	sm.drawSectionLabel(screen, "NNUE Network", contentX, sm.networkDropdown.Y-20)
+	sm.drawSectionLabel(screen, "Board Theme", sm.boardThemeBtns.X, sm.y+52)
+	sm.drawSectionLabel(screen, "Pieces", sm.pieceSetBtns.X, sm.pieceSetBtns.Y-24)
+	sm.drawSectionLabel(screen, "Sound Pack", sm.soundPackBtns.X, sm.soundPackBtns.Y-24)
+	sm.drawSectionLabel(screen, "Hints per Game", sm.hintLimitBtns.X, sm.hintLimitBtns.Y-24)
+	if engineProcessSupported {
+		sm.drawSectionLabel(screen, "Engine", sm.processCheckbox.X, sm.y+52)
+		sm.drawSectionLabel(screen, "Engine Program", sm.enginePathInput.X, sm.enginePathInput.Y-24)
+		sm.drawSectionLabel(screen, "Sparring Engine", sm.sparringInput.X, sm.sparringInput.Y-24)
+	}
 
 	// Draw widgets
 	sm.usernameInput.Draw(screen)
@@ -267,8 +503,51 @@ func (sm *SettingsModal) Draw(screen *ebiten.Image, glass *GlassEffect) {
 	sm.evalModeRadio.Draw(screen)
 	sm.difficultyBtns.Draw(screen)
 	sm.soundCheckbox.Draw(screen)
+	sm.autoFlipCheckbox.Draw(screen)
+	sm.coachCheckbox.Draw(screen)
+	sm.animateCheckbox.Draw(screen)
+	sm.boardThemeBtns.Draw(screen)
+	sm.pieceSetBtns.Draw(screen)
+	sm.drawThemePreview(screen)
+	sm.soundPackBtns.Draw(screen)
+	sm.speakCheckbox.Draw(screen)
+	sm.hintLimitBtns.Draw(screen)
+	sm.chancesCheckbox.Draw(screen)
+	sm.ponderCheckbox.Draw(screen)
+	sm.updatesCheckbox.Draw(screen)
+	sm.explorerCheckbox.Draw(screen)
+	if engineProcessSupported {
+		sm.processCheckbox.Draw(screen)
+		sm.enginePathInput.Draw(screen)
+		sm.sparringInput.Draw(screen)
+		sm.engineOptionsBtn.Draw(screen)
+	}
 	sm.saveBtn.Draw(screen)
 	sm.cancelBtn.Draw(screen)
+	sm.profilesBtn.Draw(screen)
+	sm.tablebasesBtn.Draw(screen)
+	sm.networkDropdown.Draw(screen) // Last, so the open list covers other widgets
+}
+
+// drawThemePreview draws two rows of squares in the selected board theme,
+// with the white and black pieces of the selected piece set.
+func (sm *SettingsModal) drawThemePreview(screen *ebiten.Image) {
+	theme := BoardThemes[sm.boardThemeBtns.Selected]
+	sm.previewSprites.SetPieceSet(PieceSets[sm.pieceSetBtns.Selected].ID)
+	sm.previewSprites.SetScale(UIScale)
+
+	pieceTypes := []board.PieceType{board.King, board.Queen, board.Rook, board.Bishop, board.Knight, board.Pawn}
+	for row, c := range []board.Color{board.White, board.Black} {
+		for col, pt := range pieceTypes {
+			x, y := sm.previewX+col*sm.previewSquare, sm.previewY+row*sm.previewSquare
+			sq := theme.LightSquare
+			if (row+col)%2 == 1 {
+				sq = theme.DarkSquare
+			}
+			vector.DrawFilledRect(screen, scaleF(x), scaleF(y), scaleF(sm.previewSquare), scaleF(sm.previewSquare), sq, false)
+			sm.previewSprites.DrawPieceAt(screen, board.NewPiece(pt, c), int(scaleD(x)), int(scaleD(y)))
+		}
+	}
 }
 
 // drawTitle draws the modal title.
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// Speaker reads announcements aloud with the platform's text-to-speech, so
+// the game can be followed without looking at the board.
+type Speaker struct {
+	mu      sync.Mutex
+	cmd     *exec.Cmd // Announcement being spoken
+	missing bool      // No text-to-speech tool was found (logged once)
+}
+
+// Say speaks text, cutting off the announcement still being spoken.
+func (s *Speaker) Say(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cmd != nil && s.cmd.Process != nil {
+		s.cmd.Process.Kill()
+	}
+	s.cmd = speechCommand(text)
+	if s.cmd == nil {
+		if !s.missing {
+			log.Printf("Warning: No text-to-speech tool found")
+			s.missing = true
+		}
+		return
+	}
+	if err := s.cmd.Start(); err != nil {
+		log.Printf("Warning: Failed to speak: %v", err)
+		s.cmd = nil
+		return
+	}
+	go s.cmd.Wait() // Reap the process
+}
+
+// speechCommand returns the command that speaks text on this platform, or
+// nil if there is none.
+func speechCommand(text string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("say", text)
+	case "windows":
+		script := "Add-Type -AssemblyName System.Speech; " +
+			"(New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak('" + strings.ReplaceAll(text, "'", "''") + "')"
+		return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	}
+	for _, tool := range []string{"spd-say", "espeak-ng", "espeak"} {
+		if _, err := exec.LookPath(tool); err == nil {
+			return exec.Command(tool, text)
+		}
+	}
+	return nil
+}
+
+// pieceNames are the spoken names of the SAN piece letters.
+var pieceNames = map[byte]string{'K': "King", 'Q': "Queen", 'R': "Rook", 'B': "Bishop", 'N': "Knight"}
+
+// spokenSAN turns a SAN move into words, e.g. "Nxf3+" into
+// "Knight takes f3, check".
+func spokenSAN(san string) string {
+	suffix := ""
+	switch {
+	case strings.HasSuffix(san, "#"):
+		suffix = ", checkmate"
+	case strings.HasSuffix(san, "+"):
+		suffix = ", check"
+	}
+	san = strings.TrimRight(san, "+#")
+
+	switch san {
+	case "O-O":
+		return "Castles kingside" + suffix
+	case "O-O-O":
+		return "Castles queenside" + suffix
+	}
+
+	var words []string
+	if name, ok := pieceNames[san[0]]; ok {
+		words = append(words, name)
+		san = san[1:]
+	}
+	promotion := ""
+	if i := strings.IndexByte(san, '='); i >= 0 && i+1 < len(san) {
+		promotion = " promotes to " + strings.ToLower(pieceNames[san[i+1]])
+		san = san[:i]
+	}
+	if from, to, ok := strings.Cut(san, "x"); ok {
+		if from != "" {
+			words = append(words, from)
+		}
+		words = append(words, "takes", to)
+	} else {
+		words = append(words, san)
+	}
+	return strings.Join(words, " ") + promotion + suffix
+}
+
+// announceMove speaks a move just played, and the result if it ended the
+// game, when announcements are enabled.
+func (g *Game) announceMove(san string) {
+	if !g.prefs.SpeakMoves {
+		return
+	}
+	side := "White"
+	if g.position.SideToMove == board.White {
+		side = "Black" // The side that just moved
+	}
+	text := side + ": " + spokenSAN(san)
+	if g.gameOver {
+		text += ". " + g.gameResult
+	}
+	g.speaker.Say(text)
+}
+
+// announce speaks a message when announcements are enabled.
+func (g *Game) announce(text string) {
+	if g.prefs.SpeakMoves {
+		g.speaker.Say(text)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"embed"
 	"image"
 	"log"
+	"math"
+	"slices"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hailam/chessplay/internal/board"
@@ -13,61 +15,136 @@ import (
 	"github.com/srwiley/rasterx"
 )
 
-//go:embed assets/pieces/*.svg
+//go:embed assets/pieces/*/*.svg
 var pieceAssets embed.FS
 
-// SpriteManager manages piece sprites.
+// PieceSet is a set of piece images, one directory of assets/pieces.
+type PieceSet struct {
+	ID   string // Directory name, saved in the preferences
+	Name string
+}
+
+// PieceSets are the available piece sets; the first is the default.
+var PieceSets = []PieceSet{
+	{ID: "classic", Name: "Classic"},
+	{ID: "minimal", Name: "Minimal"},
+}
+
+// pieceSetIndex returns the index of a piece set in PieceSets, or 0 (the
+// default) for an unknown id.
+func pieceSetIndex(id string) int {
+	return max(slices.IndexFunc(PieceSets, func(ps PieceSet) bool { return ps.ID == id }), 0)
+}
+
+// maxSpriteSizes is how many pixel sizes of the piece set are kept, so
+// moving the window between displays does not rasterize every frame.
+const maxSpriteSizes = 3
+
+// SpriteManager manages piece sprites. Pieces are rasterized from their SVG
+// sources at the exact pixel size of the current HiDPI scale and drawn
+// without scaling, so they stay crisp at any scale factor.
 type SpriteManager struct {
-	pieces       map[board.Piece]*ebiten.Image
-	size         int     // Display size (e.g., 80)
-	renderScale  float64 // Render at higher resolution for quality (e.g., 3.0)
-	displayScale float64 // HiDPI display scale factor
+	icons        map[board.Piece]*oksvg.SvgIcon
+	cache        map[int]map[board.Piece]*ebiten.Image // Sprites by pixel size
+	cacheOrder   []int                                 // Cached pixel sizes, oldest first
+	size         int                                   // Display size in logical pixels (e.g., 80)
+	displayScale float64                               // HiDPI display scale factor
+	pieceSet     string                                // ID of the loaded piece set
 }
 
 // NewSpriteManager creates a new sprite manager with pieces of the given size.
 func NewSpriteManager(size int) *SpriteManager {
 	sm := &SpriteManager{
-		pieces:       make(map[board.Piece]*ebiten.Image),
+		icons:        make(map[board.Piece]*oksvg.SvgIcon),
+		cache:        make(map[int]map[board.Piece]*ebiten.Image),
 		size:         size,
-		renderScale:  3.0, // Render at 3x resolution for sharp scaling
 		displayScale: 1.0,
+		pieceSet:     PieceSets[0].ID,
 	}
 	sm.loadPieces()
 	return sm
 }
 
+// SetPieceSet switches to another piece set (see PieceSets). Unknown ids
+// select the default set.
+func (sm *SpriteManager) SetPieceSet(id string) {
+	id = PieceSets[pieceSetIndex(id)].ID
+	if id == sm.pieceSet {
+		return
+	}
+	sm.pieceSet = id
+	for _, sprites := range sm.cache {
+		for _, img := range sprites {
+			img.Deallocate()
+		}
+	}
+	clear(sm.cache)
+	sm.cacheOrder = sm.cacheOrder[:0]
+	clear(sm.icons)
+	sm.loadPieces()
+}
+
 // SetScale sets the HiDPI display scale factor.
 func (sm *SpriteManager) SetScale(scale float64) {
 	sm.displayScale = scale
 }
 
-// GetPiece returns the sprite for a piece.
+// pixelSize returns the sprite size in device pixels at the current scale.
+func (sm *SpriteManager) pixelSize() int {
+	return max(int(math.Round(float64(sm.size)*sm.displayScale)), 1)
+}
+
+// GetPiece returns the sprite for a piece at the current scale.
 func (sm *SpriteManager) GetPiece(p board.Piece) *ebiten.Image {
-	return sm.pieces[p]
+	return sm.spritesFor(sm.pixelSize())[p]
+}
+
+// spritesFor returns the piece set rasterized at the given pixel size,
+// rendering it on first use and evicting the oldest cached size.
+func (sm *SpriteManager) spritesFor(px int) map[board.Piece]*ebiten.Image {
+	if sprites, ok := sm.cache[px]; ok {
+		return sprites
+	}
+
+	if len(sm.cacheOrder) >= maxSpriteSizes {
+		oldest := sm.cacheOrder[0]
+		for _, img := range sm.cache[oldest] {
+			img.Deallocate()
+		}
+		delete(sm.cache, oldest)
+		sm.cacheOrder = sm.cacheOrder[1:]
+	}
+
+	sprites := make(map[board.Piece]*ebiten.Image, len(sm.icons))
+	for piece, icon := range sm.icons {
+		sprites[piece] = rasterizeIcon(icon, px)
+	}
+	sm.cache[px] = sprites
+	sm.cacheOrder = append(sm.cacheOrder, px)
+	return sprites
 }
 
-// pieceFiles maps pieces to their asset file paths.
+// pieceFiles maps pieces to their file names in a piece set directory.
 var pieceFiles = map[board.Piece]string{
-	board.NewPiece(board.Pawn, board.White):   "assets/pieces/wP.svg",
-	board.NewPiece(board.Knight, board.White): "assets/pieces/wN.svg",
-	board.NewPiece(board.Bishop, board.White): "assets/pieces/wB.svg",
-	board.NewPiece(board.Rook, board.White):   "assets/pieces/wR.svg",
-	board.NewPiece(board.Queen, board.White):  "assets/pieces/wQ.svg",
-	board.NewPiece(board.King, board.White):   "assets/pieces/wK.svg",
-	board.NewPiece(board.Pawn, board.Black):   "assets/pieces/bP.svg",
-	board.NewPiece(board.Knight, board.Black): "assets/pieces/bN.svg",
-	board.NewPiece(board.Bishop, board.Black): "assets/pieces/bB.svg",
-	board.NewPiece(board.Rook, board.Black):   "assets/pieces/bR.svg",
-	board.NewPiece(board.Queen, board.Black):  "assets/pieces/bQ.svg",
-	board.NewPiece(board.King, board.Black):   "assets/pieces/bK.svg",
-}
-
-// loadPieces loads all piece sprites from embedded SVG files.
-func (sm *SpriteManager) loadPieces() {
-	// Render at higher resolution for better quality when scaled
-	renderSize := int(float64(sm.size) * sm.renderScale)
+	board.NewPiece(board.Pawn, board.White):   "wP.svg",
+	board.NewPiece(board.Knight, board.White): "wN.svg",
+	board.NewPiece(board.Bishop, board.White): "wB.svg",
+	board.NewPiece(board.Rook, board.White):   "wR.svg",
+	board.NewPiece(board.Queen, board.White):  "wQ.svg",
+	board.NewPiece(board.King, board.White):   "wK.svg",
+	board.NewPiece(board.Pawn, board.Black):   "bP.svg",
+	board.NewPiece(board.Knight, board.Black): "bN.svg",
+	board.NewPiece(board.Bishop, board.Black): "bB.svg",
+	board.NewPiece(board.Rook, board.Black):   "bR.svg",
+	board.NewPiece(board.Queen, board.Black):  "bQ.svg",
+	board.NewPiece(board.King, board.Black):   "bK.svg",
+}
 
-	for piece, path := range pieceFiles {
+// loadPieces parses the embedded SVG files of the current piece set.
+// Sprites are rasterized lazily by spritesFor.
+func (sm *SpriteManager) loadPieces() {
+	for piece, name := range pieceFiles {
+		path := "assets/pieces/" + sm.pieceSet + "/" + name
 		data, err := pieceAssets.ReadFile(path)
 		if err != nil {
 			log.Printf("Failed to read piece asset %s: %v", path, err)
@@ -80,21 +157,24 @@ func (sm *SpriteManager) loadPieces() {
 			log.Printf("Failed to parse SVG %s: %v", path, err)
 			continue
 		}
+		sm.icons[piece] = icon
+	}
+}
 
-		// Set target size at higher resolution for quality
-		icon.SetTarget(0, 0, float64(renderSize), float64(renderSize))
+// rasterizeIcon renders an SVG icon into a px x px image with anti-aliasing.
+func rasterizeIcon(icon *oksvg.SvgIcon, px int) *ebiten.Image {
+	icon.SetTarget(0, 0, float64(px), float64(px))
 
-		// Create RGBA image and render with anti-aliasing at high resolution
-		rgba := image.NewRGBA(image.Rect(0, 0, renderSize, renderSize))
-		scanner := rasterx.NewScannerGV(renderSize, renderSize, rgba, rgba.Bounds())
-		raster := rasterx.NewDasher(renderSize, renderSize, scanner)
-		icon.Draw(raster, 1.0)
+	rgba := image.NewRGBA(image.Rect(0, 0, px, px))
+	scanner := rasterx.NewScannerGV(px, px, rgba, rgba.Bounds())
+	raster := rasterx.NewDasher(px, px, scanner)
+	icon.Draw(raster, 1.0)
 
-		sm.pieces[piece] = ebiten.NewImageFromImage(rgba)
-	}
+	return ebiten.NewImageFromImage(rgba)
 }
 
 // DrawPieceAt draws a piece at the given pixel coordinates (already scaled for HiDPI).
+// The sprite already has the device pixel size, so it is drawn unscaled.
 func (sm *SpriteManager) DrawPieceAt(screen *ebiten.Image, p board.Piece, x, y int) {
 	if p == board.NoPiece {
 		return
@@ -104,13 +184,23 @@ func (sm *SpriteManager) DrawPieceAt(screen *ebiten.Image, p board.Piece, x, y i
 		return
 	}
 	op := &ebiten.DrawImageOptions{}
-	// Scale: render resolution -> display size -> HiDPI
-	// Combined scale = displayScale / renderScale
-	scale := sm.displayScale / sm.renderScale
-	op.GeoM.Scale(scale, scale)
 	op.GeoM.Translate(float64(x), float64(y))
-	// Use linear filtering for smooth scaling
-	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(sprite, op)
+}
+
+// DrawPieceFaded draws a piece like DrawPieceAt, with the given opacity
+// from 0 (invisible) to 1.
+func (sm *SpriteManager) DrawPieceFaded(screen *ebiten.Image, p board.Piece, x, y int, alpha float32) {
+	if p == board.NoPiece {
+		return
+	}
+	sprite := sm.GetPiece(p)
+	if sprite == nil {
+		return
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(x), float64(y))
+	op.ColorScale.ScaleAlpha(alpha)
 	screen.DrawImage(sprite, op)
 }
 
@@ -0,0 +1,418 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/color"
+	"net/url"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hailam/chessplay/internal/tablebase"
+)
+
+// Tablebase modal dimensions
+const (
+	TablebaseWidth  = 420
+	TablebaseHeight = 430
+	TablebasePadX   = 24
+	TablebasePadY   = 20
+)
+
+// tablebaseProbeDepth is the GUI engine's SyzygyProbeDepth.
+const tablebaseProbeDepth = 6
+
+// tablebaseSets are the table sets offered for download, by piece count.
+var tablebaseSets = []int{3, 4, 5}
+
+// Tablebase modal status colors
+var (
+	tbSuccessColor = color.RGBA{76, 175, 120, 255}
+	tbErrorColor   = color.RGBA{255, 100, 100, 255}
+)
+
+// tablebaseDownload is one run of the downloader. The download goroutine
+// updates it; the modal reads it each frame.
+type tablebaseDownload struct {
+	mu            sync.Mutex
+	filesDone     int
+	filesTotal    int
+	file          string
+	bytesReceived int64
+	totalBytes    int64
+	done          bool
+	err           error
+	cancel        context.CancelFunc
+	reported      bool // Result handled by the modal (game thread only)
+}
+
+// TablebaseModal downloads Syzygy endgame tablebases into the data
+// directory, where the engine picks them up.
+type TablebaseModal struct {
+	visible      bool
+	needsCapture bool // Set true when opening to capture background
+
+	// Position (centered on screen)
+	x, y int
+
+	// Widgets
+	setCheckboxes  []*Checkbox
+	mirrorDropdown *Dropdown
+	downloadBtn    *ModalButton
+	closeBtn       *ModalButton
+
+	dir       string
+	installed map[int]int // Complete tables per set
+	diskUsage int64
+
+	download *tablebaseDownload // Current or last download (nil = none)
+
+	// Called on the game thread after a successful download
+	onDownloaded func(mirror string)
+}
+
+// NewTablebaseModal creates a new tablebase modal.
+func NewTablebaseModal() *TablebaseModal {
+	tm := &TablebaseModal{installed: make(map[int]int)}
+	tm.x = (ScreenWidth - TablebaseWidth) / 2
+	tm.y = (ScreenHeight - TablebaseHeight) / 2
+
+	contentX := tm.x + TablebasePadX
+	contentW := TablebaseWidth - TablebasePadX*2
+
+	for i, pieces := range tablebaseSets {
+		label := fmt.Sprintf("%d-piece (%d tables, ~%s)", pieces,
+			len(tablebase.TableNames(pieces)), tablebase.FormatBytes(tablebase.TableSetSize(pieces)))
+		tm.setCheckboxes = append(tm.setCheckboxes, NewCheckbox(contentX, tm.y+80+i*30, label, false))
+	}
+
+	tm.mirrorDropdown = NewDropdown(contentX, tm.y+200, contentW, 32, nil, 0)
+
+	btnW, btnH := 100, 38
+	btnY := tm.y + TablebaseHeight - TablebasePadY - btnH
+	tm.closeBtn = NewModalButton(tm.x+TablebaseWidth-TablebasePadX-btnW*2-12, btnY, btnW, btnH, "Close", false, nil)
+	tm.downloadBtn = NewModalButton(tm.x+TablebaseWidth-TablebasePadX-btnW, btnY, btnW, btnH, "Download", true, nil)
+	tm.closeBtn.OnClick = tm.handleClose
+	tm.downloadBtn.OnClick = tm.startDownload
+	return tm
+}
+
+// Show opens the modal for the tablebases in dir. mirror is the saved
+// download mirror ("" for the default); a mirror that is not built in is
+// offered as a custom option.
+func (tm *TablebaseModal) Show(dir, mirror string, onDownloaded func(mirror string)) {
+	tm.visible = true
+	tm.needsCapture = true
+	tm.dir = dir
+	tm.onDownloaded = onDownloaded
+
+	options := make([]DropdownOption, 0, len(tablebase.Mirrors)+1)
+	for _, m := range tablebase.Mirrors {
+		options = append(options, DropdownOption{Label: mirrorHost(m), Value: m})
+	}
+	if mirror != "" {
+		custom := true
+		for _, m := range tablebase.Mirrors {
+			custom = custom && m != mirror
+		}
+		if custom {
+			options = append(options, DropdownOption{Label: "Custom: " + mirrorHost(mirror), Value: mirror})
+		}
+	}
+	tm.mirrorDropdown.SetOptions(options, mirror)
+
+	// A finished download is not shown again
+	if tm.download != nil && !tm.downloading() {
+		tm.download = nil
+	}
+	tm.refresh()
+
+	// Preselect the small sets that are not complete yet
+	for i, pieces := range tablebaseSets {
+		tm.setCheckboxes[i].Checked = tm.installed[pieces] < len(tablebase.TableNames(pieces)) && pieces < 5
+	}
+}
+
+// mirrorHost returns the host name of a mirror URL, for the mirror list.
+func mirrorHost(mirror string) string {
+	if u, err := url.Parse(mirror); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return mirror
+}
+
+// Hide closes the modal.
+func (tm *TablebaseModal) Hide() {
+	tm.visible = false
+	tm.mirrorDropdown.Close()
+}
+
+// IsVisible returns true if the modal is visible.
+func (tm *TablebaseModal) IsVisible() bool {
+	return tm.visible
+}
+
+// refresh counts the installed tables and their disk usage.
+func (tm *TablebaseModal) refresh() {
+	d := tablebase.NewSyzygyDownloader(tm.dir)
+	for _, pieces := range tablebaseSets {
+		tm.installed[pieces] = d.InstalledCount(pieces)
+	}
+	tm.diskUsage = d.DiskUsage()
+}
+
+// downloading returns true while a download is running.
+func (tm *TablebaseModal) downloading() bool {
+	if tm.download == nil {
+		return false
+	}
+	tm.download.mu.Lock()
+	defer tm.download.mu.Unlock()
+	return !tm.download.done
+}
+
+// startDownload downloads the checked sets from the selected mirror.
+func (tm *TablebaseModal) startDownload() {
+	if tm.downloading() {
+		return
+	}
+	var sets []int
+	total := 0
+	for i, pieces := range tablebaseSets {
+		if tm.setCheckboxes[i].Checked {
+			sets = append(sets, pieces)
+			total += 2 * len(tablebase.TableNames(pieces)) // WDL and DTZ
+		}
+	}
+	if len(sets) == 0 {
+		return
+	}
+
+	d := tablebase.NewSyzygyDownloader(tm.dir)
+	d.BaseURL = tm.mirrorDropdown.Value()
+	ctx, cancel := context.WithCancel(context.Background())
+	dl := &tablebaseDownload{filesTotal: total, cancel: cancel}
+	tm.download = dl
+
+	go func() {
+		var err error
+		progress := make(chan tablebase.DownloadProgress, 64)
+		go func() {
+			defer close(progress)
+			for _, pieces := range sets {
+				if err = d.DownloadSet(ctx, pieces, progress); err != nil {
+					return
+				}
+			}
+		}()
+
+		for p := range progress {
+			dl.mu.Lock()
+			if p.Done {
+				dl.filesDone++
+				dl.bytesReceived, dl.totalBytes = 0, 0
+			} else {
+				dl.file, dl.bytesReceived, dl.totalBytes = p.File, p.BytesReceived, p.TotalBytes
+			}
+			dl.mu.Unlock()
+		}
+
+		dl.mu.Lock()
+		dl.done, dl.err = true, err
+		dl.mu.Unlock()
+		cancel()
+	}()
+}
+
+// handleClose cancels a running download, or closes the modal.
+func (tm *TablebaseModal) handleClose() {
+	if tm.downloading() {
+		tm.download.cancel()
+		return
+	}
+	tm.Hide()
+}
+
+// Update handles input for the tablebase modal.
+func (tm *TablebaseModal) Update(input *InputHandler) bool {
+	if !tm.visible {
+		return false
+	}
+
+	// Report a finished download once, on the game thread
+	if dl := tm.download; dl != nil && !dl.reported && !tm.downloading() {
+		dl.reported = true
+		tm.refresh()
+		if dl.err == nil && tm.onDownloaded != nil {
+			tm.onDownloaded(tm.mirrorDropdown.Value())
+		}
+	}
+
+	if IsKeyJustPressed(ebiten.KeyEscape) {
+		if tm.mirrorDropdown.IsOpen() {
+			tm.mirrorDropdown.Close()
+		} else {
+			tm.handleClose()
+		}
+		return true
+	}
+
+	busy := tm.downloading()
+	if busy {
+		tm.closeBtn.Label = "Cancel"
+	} else {
+		tm.closeBtn.Label = "Close"
+	}
+
+	if !busy {
+		if tm.mirrorDropdown.Update(input) {
+			return true
+		}
+		for _, cb := range tm.setCheckboxes {
+			cb.Update(input)
+		}
+		tm.downloadBtn.Update(input)
+	}
+	tm.closeBtn.Update(input)
+
+	// Modal consumes all input
+	return true
+}
+
+// AnyButtonHovered returns true if any button in the modal is hovered.
+func (tm *TablebaseModal) AnyButtonHovered() bool {
+	if !tm.visible {
+		return false
+	}
+	for _, cb := range tm.setCheckboxes {
+		if cb.hovered {
+			return true
+		}
+	}
+	return tm.downloadBtn.IsHovered() || tm.closeBtn.IsHovered() ||
+		tm.mirrorDropdown.hovered || tm.mirrorDropdown.hoveredOpt >= 0
+}
+
+// Draw renders the tablebase modal.
+func (tm *TablebaseModal) Draw(screen *ebiten.Image, glass *GlassEffect) {
+	if !tm.visible {
+		return
+	}
+
+	// Capture background once when modal first opens (fixes flicker)
+	if tm.needsCapture && glass != nil && glass.IsEnabled() {
+		glass.CaptureForModal(screen, 3.0)
+		tm.needsCapture = false
+	}
+
+	if glass != nil && glass.IsEnabled() {
+		glass.DrawModalBackground(screen, 0.4)
+	} else {
+		vector.DrawFilledRect(screen, 0, 0, scaleF(ScreenWidth), scaleF(ScreenHeight), modalOverlay, false)
+	}
+
+	// Modal background, border and header
+	vector.DrawFilledRect(screen, scaleF(tm.x), scaleF(tm.y), scaleF(TablebaseWidth), scaleF(TablebaseHeight), modalBg, false)
+	vector.StrokeRect(screen, scaleF(tm.x), scaleF(tm.y), scaleF(TablebaseWidth), scaleF(TablebaseHeight), float32(UIScale*2), modalBorder, false)
+	vector.DrawFilledRect(screen, scaleF(tm.x), scaleF(tm.y), scaleF(TablebaseWidth), scaleF(44), modalHeader, false)
+	tm.drawTitle(screen)
+
+	contentX := tm.x + TablebasePadX
+	rightX := tm.x + TablebaseWidth - TablebasePadX
+
+	// Table sets with their installed counts
+	drawText(screen, "Syzygy Table Sets", contentX, tm.y+56, textMuted)
+	for i, pieces := range tablebaseSets {
+		cb := tm.setCheckboxes[i]
+		cb.Draw(screen)
+
+		total := len(tablebase.TableNames(pieces))
+		status, c := fmt.Sprintf("%d/%d", tm.installed[pieces], total), textMuted
+		if tm.installed[pieces] == total {
+			status, c = "Installed", tbSuccessColor
+		}
+		drawTextRight(screen, status, rightX, cb.Y+2, c)
+	}
+
+	drawText(screen, "Mirror", contentX, tm.mirrorDropdown.Y-20, textMuted)
+
+	// Download status
+	statusY := tm.mirrorDropdown.Y + tm.mirrorDropdown.H + 20
+	tm.drawStatus(screen, contentX, statusY)
+
+	drawText(screen, "Disk usage: "+tablebase.FormatBytes(tm.diskUsage), contentX, statusY+70, textSecondary)
+	drawText(screen, "The engine uses installed tables automatically.", contentX, statusY+94, textMuted)
+
+	tm.closeBtn.Draw(screen)
+	tm.downloadBtn.Draw(screen)
+	tm.mirrorDropdown.Draw(screen) // Last, so the open list covers other widgets
+}
+
+// drawStatus draws the progress bar of a running download, or the result of
+// the last one.
+func (tm *TablebaseModal) drawStatus(screen *ebiten.Image, x, y int) {
+	dl := tm.download
+	if dl == nil {
+		return
+	}
+	dl.mu.Lock()
+	filesDone, filesTotal, file := dl.filesDone, dl.filesTotal, dl.file
+	received, size := dl.bytesReceived, dl.totalBytes
+	done, err := dl.done, dl.err
+	dl.mu.Unlock()
+
+	switch {
+	case done && errors.Is(err, context.Canceled):
+		drawText(screen, "Download cancelled", x, y, textSecondary)
+		return
+	case done && err != nil:
+		msg := err.Error()
+		if len(msg) > 48 {
+			msg = msg[:48] + "..."
+		}
+		drawText(screen, "Download failed", x, y, tbErrorColor)
+		drawText(screen, msg, x, y+22, textSecondary)
+		return
+	case done:
+		drawText(screen, "Download complete", x, y, tbSuccessColor)
+		return
+	}
+
+	// Progress bar: finished files plus the file being downloaded
+	barW := TablebaseWidth - TablebasePadX*2
+	barH := 20
+	vector.DrawFilledRect(screen, scaleF(x), scaleF(y), scaleF(barW), scaleF(barH), widgetBg, false)
+	vector.StrokeRect(screen, scaleF(x), scaleF(y), scaleF(barW), scaleF(barH), float32(UIScale), widgetBorder, false)
+	fraction := float32(filesDone)
+	if size > 0 {
+		fraction += float32(received) / float32(size)
+	}
+	if filesTotal > 0 {
+		fraction /= float32(filesTotal)
+		vector.DrawFilledRect(screen, scaleF(x+2), scaleF(y+2), scaleF(barW-4)*min(fraction, 1), scaleF(barH-4), accentColor, false)
+	}
+
+	info := fmt.Sprintf("File %d of %d", min(filesDone+1, filesTotal), filesTotal)
+	if file != "" {
+		info += ": " + file
+	}
+	drawText(screen, info, x, y+barH+8, textSecondary)
+}
+
+// drawTitle draws the modal title.
+func (tm *TablebaseModal) drawTitle(screen *ebiten.Image) {
+	face := GetBoldFace()
+	if face == nil {
+		return
+	}
+
+	title := "Endgame Tablebases"
+	w, h := MeasureText(title, face)
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(scaleD(tm.x)+scaleD(TablebaseWidth)/2-w/2, scaleD(tm.y)+scaleD(22)-h/2)
+	op.ColorScale.ScaleWithColor(textPrimary)
+	text.Draw(screen, title, face, op)
+}
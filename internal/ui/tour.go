@@ -0,0 +1,229 @@
+package ui
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Onboarding tour: after the welcome screen on first launch, a few steps
+// point out the parts of the window one at a time. Each step dims all but
+// its target and explains it in a callout with Next and Skip buttons.
+
+// Callout dimensions
+const (
+	tourCalloutW = 280
+	tourPad      = 14
+	tourBtnH     = 30
+)
+
+// tourDim covers everything but the target of a step.
+var tourDim = color.RGBA{0, 0, 0, 150}
+
+// tourStep is one stop of the tour.
+type tourStep struct {
+	title  string
+	text   string
+	target func(p *Panel) Rect // Empty when not on screen; the step is skipped
+}
+
+// tourSteps are the stops of the tour, in order.
+var tourSteps = []tourStep{
+	{
+		title:  "The board",
+		text:   "Drag or click a piece to move it. Press Enter to type a move such as Nf3, F to flip the board and H to see how well each piece is placed.",
+		target: func(p *Panel) Rect { return Rect{W: BoardSize, H: BoardSize} },
+	},
+	{
+		title:  "New game",
+		text:   "Starts over in the current mode, keeping your settings.",
+		target: func(p *Panel) Rect { return p.tourTarget(p.newGameBtn.Rect) },
+	},
+	{
+		title:  "Game mode",
+		text:   "Play a friend at this board, play the engine, or watch two engines play each other.",
+		target: func(p *Panel) Rect { return p.tourTarget(p.modeTabs.Rect()) },
+	},
+	{
+		title: "Difficulty",
+		text:  "How hard the engine plays. Easy can also show the best moves while you think.",
+		target: func(p *Panel) Rect {
+			if p.game.GameMode() != ModeHumanVsComputer {
+				return Rect{}
+			}
+			return p.tourTarget(p.diffTabs.Rect())
+		},
+	},
+	{
+		title: "More",
+		text:  "Settings, your saved games, puzzle rush, and a link to share the position.",
+		target: func(p *Panel) Rect {
+			r := p.settingsBtn.Rect
+			r.W = p.shareBtn.X + p.shareBtn.W - r.X
+			return p.tourTarget(r)
+		},
+	},
+	{
+		title:  "Moves",
+		text:   "The moves of the game with the time each took. Scroll with the wheel when the list grows.",
+		target: func(p *Panel) Rect { return p.tourTarget(p.history.View) },
+	},
+	{
+		title: "Status",
+		text:  "Who is to move, the clocks and how the engine evaluates. Hover over anything in the panel to learn more.",
+		target: func(p *Panel) Rect {
+			return p.tourTarget(Rect{X: BoardSize, Y: ScreenHeight - 84, W: PanelWidth, H: 84})
+		},
+	},
+}
+
+// tourTarget returns a panel area as the target of a step, or an empty one
+// while the panel is collapsed.
+func (p *Panel) tourTarget(r Rect) Rect {
+	if p.collapsed {
+		return Rect{}
+	}
+	return r
+}
+
+// Tour walks a new player through the window.
+type Tour struct {
+	panel   *Panel
+	visible bool
+	step    int // Index into tourSteps
+
+	nextBtn *Button
+	skipBtn *Button
+}
+
+// NewTour creates a tour of the panel's window.
+func NewTour(p *Panel) *Tour {
+	t := &Tour{panel: p}
+	t.nextBtn = &Button{Label: "Next", OnClick: func() { t.advance(t.step + 1) }}
+	t.skipBtn = &Button{Label: "Skip", OnClick: t.Hide}
+	return t
+}
+
+// Start shows the tour from its first step.
+func (t *Tour) Start() {
+	t.visible = true
+	t.advance(0)
+}
+
+// Hide ends the tour.
+func (t *Tour) Hide() {
+	t.visible = false
+}
+
+// IsVisible returns true while the tour is shown.
+func (t *Tour) IsVisible() bool {
+	return t.visible
+}
+
+// advance moves to the first step from i on whose target is on screen, and
+// ends the tour after the last.
+func (t *Tour) advance(i int) {
+	for i < len(tourSteps) && tourSteps[i].target(t.panel).W == 0 {
+		i++
+	}
+	if i >= len(tourSteps) {
+		t.Hide()
+		return
+	}
+	t.step = i
+	t.nextBtn.Label = "Next"
+	if t.last() {
+		t.nextBtn.Label = "Done"
+	}
+}
+
+// last returns true if no later step is on screen.
+func (t *Tour) last() bool {
+	for _, s := range tourSteps[t.step+1:] {
+		if s.target(t.panel).W > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// callout returns the area of the callout of the current step: to the left
+// of a panel target, or in the middle of the board.
+func (t *Tour) callout() Rect {
+	step := tourSteps[t.step]
+	lines := wrapText(step.text, t.panel.si(tourCalloutW-tourPad*2))
+	h := tourPad*2 + 24 + len(lines)*20 + 10 + tourBtnH
+
+	target := step.target(t.panel)
+	if target.X >= BoardSize {
+		y := min(max(target.Y+target.H/2-h/2, 8), ScreenHeight-h-8)
+		return Rect{X: BoardSize - tourCalloutW - 16, Y: y, W: tourCalloutW, H: h}
+	}
+	return Rect{X: target.X + (target.W-tourCalloutW)/2, Y: target.Y + (target.H-h)/2, W: tourCalloutW, H: h}
+}
+
+// layout places the buttons at the bottom of the callout.
+func (t *Tour) layout() {
+	c := t.callout()
+	row := Rect{X: c.X + tourPad, Y: c.Y + c.H - tourPad - tourBtnH, W: c.W - tourPad*2, H: tourBtnH}
+	cols := row.Columns(2, 8)
+	t.skipBtn.Rect, t.nextBtn.Rect = cols[0], cols[1]
+}
+
+// Update handles input for the tour: the buttons, Enter or Right for the
+// next step and Escape to end the tour. The tour consumes all input.
+func (t *Tour) Update(input *InputHandler) bool {
+	if !t.visible {
+		return false
+	}
+	switch {
+	case IsKeyJustPressed(ebiten.KeyEscape):
+		t.Hide()
+		return true
+	case IsKeyJustPressed(ebiten.KeyEnter) || IsKeyJustPressed(ebiten.KeyArrowRight):
+		t.advance(t.step + 1)
+		return true
+	}
+	t.layout()
+	updateButtons(input, []*Button{t.nextBtn, t.skipBtn})
+	return true
+}
+
+// AnyButtonHovered returns true if a tour button is hovered.
+func (t *Tour) AnyButtonHovered() bool {
+	return t.visible && (t.nextBtn.hovered || t.skipBtn.hovered)
+}
+
+// drawTour draws the current step of the tour: the window dimmed around the
+// target, and the callout.
+func (p *Panel) drawTour(screen *ebiten.Image, t *Tour) {
+	if !t.visible {
+		return
+	}
+	step := tourSteps[t.step]
+	target := step.target(p)
+
+	// Dim above, below, left and right of the target
+	vector.DrawFilledRect(screen, 0, 0, p.s(ScreenWidth), p.s(target.Y), tourDim, false)
+	vector.DrawFilledRect(screen, 0, p.s(target.Y+target.H), p.s(ScreenWidth), p.s(ScreenHeight-target.Y-target.H), tourDim, false)
+	vector.DrawFilledRect(screen, 0, p.s(target.Y), p.s(target.X), p.s(target.H), tourDim, false)
+	vector.DrawFilledRect(screen, p.s(target.X+target.W), p.s(target.Y), p.s(ScreenWidth-target.X-target.W), p.s(target.H), tourDim, false)
+	vector.StrokeRect(screen, p.s(target.X), p.s(target.Y), p.s(target.W), p.s(target.H), float32(p.scale*2), accentColor, false)
+
+	t.layout()
+	c := t.callout()
+	vector.DrawFilledRect(screen, p.s(c.X), p.s(c.Y), p.s(c.W), p.s(c.H), modalBg, false)
+	vector.StrokeRect(screen, p.s(c.X), p.s(c.Y), p.s(c.W), p.s(c.H), float32(p.scale), modalBorder, false)
+
+	x, y := c.X+tourPad, c.Y+tourPad
+	p.drawText(screen, step.title, x, y, textPrimary)
+	y += 24
+	for _, l := range wrapText(step.text, p.si(tourCalloutW-tourPad*2)) {
+		p.drawText(screen, l, x, y, textSecondary)
+		y += 20
+	}
+
+	p.drawSecondaryButton(screen, t.skipBtn)
+	p.drawPrimaryButton(screen, t.nextBtn)
+}
@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/hailam/chessplay/internal/storage"
+	"github.com/hailam/chessplay/internal/update"
+)
+
+// Update check: with "Check for updates" set, the releases are queried once
+// per run. A newer release is announced and, where the executable can be
+// replaced, downloaded and verified in the background and installed on exit.
+
+// updateCheckTimeout bounds the check and the download.
+const updateCheckTimeout = 10 * time.Minute
+
+// updateResult is the outcome of the update check.
+type updateResult struct {
+	release *update.Release // Newer release (nil = up to date)
+	staged  string          // Path of the verified download ("" = none)
+	err     error
+}
+
+// updateCheck is the state of the update check.
+type updateCheck struct {
+	started bool
+	result  chan updateResult // Receives the outcome once; nil when done
+	staged  string            // Update installed on exit
+}
+
+// pollUpdateCheck starts the update check once enabled, and announces its
+// outcome when it arrives.
+func (g *Game) pollUpdateCheck() {
+	u := &g.updates
+	if !u.started {
+		if g.prefs.CheckUpdates {
+			u.started = true
+			u.result = make(chan updateResult, 1)
+			go func() { u.result <- checkForUpdate() }()
+		}
+		return
+	}
+	if u.result == nil {
+		return
+	}
+	select {
+	case r := <-u.result:
+		u.result = nil
+		switch {
+		case r.err != nil:
+			log.Printf("Warning: Update check failed: %v", r.err)
+		case r.release != nil:
+			log.Printf("[Update] %s is available: %s", r.release.Version, r.release.URL)
+			u.staged = r.staged
+			g.feedback.OnUpdateAvailable(r.release.Version, r.staged != "")
+		}
+	default:
+	}
+}
+
+// checkForUpdate looks for a newer release and stages it where possible.
+func checkForUpdate() updateResult {
+	ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+	defer cancel()
+
+	update.Cleanup()
+	c := update.NewChecker()
+	r, err := c.Check(ctx)
+	if err != nil || r == nil || !update.Supported() {
+		return updateResult{release: r, err: err}
+	}
+
+	dataDir, err := storage.GetDataDir()
+	if err != nil {
+		log.Printf("Warning: Cannot stage update: %v", err)
+		return updateResult{release: r}
+	}
+	staged, err := c.Stage(ctx, r, filepath.Join(dataDir, "updates"))
+	if err != nil && !errors.Is(err, update.ErrNoPublicKey) {
+		log.Printf("Warning: Cannot stage update %s: %v", r.Version, err)
+	}
+	return updateResult{release: r, staged: staged}
+}
+
+// installUpdate replaces the executable with the staged update, if any.
+func (g *Game) installUpdate() {
+	if g.updates.staged == "" {
+		return
+	}
+	if err := update.Apply(g.updates.staged); err != nil {
+		log.Printf("Warning: Failed to install update: %v", err)
+		return
+	}
+	log.Printf("[Update] Installed %s", g.updates.staged)
+}
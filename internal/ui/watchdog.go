@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"log"
+	"strings"
+
+	"github.com/hailam/chessplay/internal/board"
+)
+
+// maxAIResearches is how many times an illegal engine move is searched
+// again before falling back to the first legal move.
+const maxAIResearches = 2
+
+// isLegalAIMove returns whether the engine's move is legal in the game
+// position, so a move from a corrupted search is never played.
+func (g *Game) isLegalAIMove(m board.Move) bool {
+	legal := g.position.GenerateLegalMoves()
+	for i := 0; i < legal.Len(); i++ {
+		if legal.Get(i) == m {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectAIMove handles an engine move that is not legal in the game
+// position: it logs the state needed to debug the search and searches
+// again with a cleared hash table, or plays the first legal move once the
+// re-searches are used up. Mirrors the bestmove check in the UCI layer.
+func (g *Game) rejectAIMove(m board.Move) {
+	g.logAIState(m)
+
+	legal := g.position.GenerateLegalMoves()
+	if legal.Len() == 0 {
+		g.checkGameEnd()
+		return
+	}
+	if g.aiResearches < maxAIResearches {
+		g.aiResearches++
+		log.Printf("[AI] Re-searching (%d/%d) with a cleared hash table", g.aiResearches, maxAIResearches)
+		g.engine.Clear()
+		g.startAIThinking()
+		return
+	}
+
+	log.Printf("[AI] Still no legal move after %d re-searches, playing %s", maxAIResearches, legal.Get(0))
+	g.aiResearches = 0
+	g.makeMove(legal.Get(0))
+}
+
+// logAIState logs the game and search state when the engine returns a move
+// that is not legal in the game position.
+func (g *Game) logAIState(m board.Move) {
+	log.Printf("[AI] CRITICAL: engine returned illegal move %v (from=%v to=%v)", m, m.From(), m.To())
+	log.Printf("[AI]   FEN: %s", g.position.ToFEN())
+	if hash := g.position.ComputeHash(); hash != g.position.Hash {
+		log.Printf("[AI]   Hash mismatch: stored %016x, computed %016x", g.position.Hash, hash)
+	}
+	if err := g.position.Verify(); err != nil {
+		log.Printf("[AI]   Position inconsistent: %v", err)
+	}
+
+	moves := make([]string, len(g.moveHistory))
+	for i, hm := range g.moveHistory {
+		moves[i] = hm.String()
+	}
+	log.Printf("[AI]   Start: %q, moves: %s", g.startFEN, strings.Join(moves, " "))
+
+	legal := g.position.GenerateLegalMoves()
+	legalStrs := make([]string, legal.Len())
+	for i := range legalStrs {
+		legalStrs[i] = legal.Get(i).String()
+	}
+	log.Printf("[AI]   Legal moves (%d): %s", legal.Len(), strings.Join(legalStrs, " "))
+
+	info := g.engine.LastSearchInfo()
+	pv := make([]string, len(info.PV))
+	for i, pm := range info.PV {
+		pv[i] = pm.String()
+	}
+	log.Printf("[AI]   Search: depth %d score %d nodes %d pv %s", info.Depth, info.Score, info.Nodes, strings.Join(pv, " "))
+}
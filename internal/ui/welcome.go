@@ -12,49 +12,78 @@ import (
 // Welcome screen dimensions
 const (
 	WelcomeWidth  = 400
-	WelcomeHeight = 380
+	WelcomeHeight = 380 // Without the profile list
 	WelcomePadX   = 32
 	WelcomePadY   = 24
 )
 
-// WelcomeScreen is shown on first launch.
+// WelcomeScreen is shown on first launch, and as the profile picker when
+// several profiles exist.
 type WelcomeScreen struct {
 	visible      bool
 	needsCapture bool // Set true when opening to capture background
 
-	// Position (centered on screen)
+	// Position (centered on screen) and height (grows with the profile list)
 	x, y int
+	h    int
+
+	// Existing profiles (empty on first launch)
+	profiles []storage.Profile
 
 	// Widgets
+	profileRadio  *RadioGroup
 	nameInput     *TextInput
 	evalModeRadio *RadioGroup
 	startBtn      *ModalButton
 
-	// Callback
-	onComplete func(name string, evalMode storage.EvalMode)
+	// Callback: profileID is empty when a new profile should be created
+	onComplete func(profileID, name string, evalMode storage.EvalMode)
 }
 
 // NewWelcomeScreen creates a new welcome screen.
 func NewWelcomeScreen() *WelcomeScreen {
 	ws := &WelcomeScreen{}
-	ws.calculatePosition()
-	ws.createWidgets()
+	ws.layout()
 	return ws
 }
 
-// calculatePosition centers the screen.
-func (ws *WelcomeScreen) calculatePosition() {
+// layout sizes and centers the screen for the current profile list,
+// then creates the widgets.
+func (ws *WelcomeScreen) layout() {
+	profileH := 0
+	if len(ws.profiles) > 0 {
+		profileH = len(ws.profileOptions())*30 + 36
+	}
+	ws.h = WelcomeHeight + profileH
 	ws.x = (ScreenWidth - WelcomeWidth) / 2
-	ws.y = (ScreenHeight - WelcomeHeight) / 2
+	ws.y = (ScreenHeight - ws.h) / 2
+	ws.createWidgets(profileH)
+}
+
+// profileOptions lists the existing profiles, plus a new-profile entry
+// while there is room for one.
+func (ws *WelcomeScreen) profileOptions() []RadioOption {
+	options := make([]RadioOption, 0, len(ws.profiles)+1)
+	for i, p := range ws.profiles {
+		options = append(options, RadioOption{Label: p.Name, Value: i})
+	}
+	if len(ws.profiles) < storage.MaxProfiles {
+		options = append(options, RadioOption{Label: "New profile", Value: len(ws.profiles)})
+	}
+	return options
 }
 
 // createWidgets initializes all welcome screen widgets.
-func (ws *WelcomeScreen) createWidgets() {
+// profileH is the height taken by the profile list.
+func (ws *WelcomeScreen) createWidgets(profileH int) {
 	contentX := ws.x + WelcomePadX
 	contentW := WelcomeWidth - WelcomePadX*2
 
+	// Profile list
+	ws.profileRadio = NewRadioGroup(contentX, ws.y+140, ws.profileOptions(), 0)
+
 	// Name input
-	inputY := ws.y + 140
+	inputY := ws.y + 140 + profileH
 	ws.nameInput = NewTextInput(contentX, inputY, contentW, 40, "Enter your name", 20)
 
 	// Eval mode radio
@@ -68,15 +97,23 @@ func (ws *WelcomeScreen) createWidgets() {
 	btnW := 160
 	btnH := 44
 	btnX := ws.x + (WelcomeWidth-btnW)/2
-	btnY := ws.y + WelcomeHeight - WelcomePadY - btnH
+	btnY := ws.y + ws.h - WelcomePadY - btnH
 	ws.startBtn = NewModalButton(btnX, btnY, btnW, btnH, "Start Playing", true, nil)
 }
 
-// Show displays the welcome screen.
-func (ws *WelcomeScreen) Show(onComplete func(name string, evalMode storage.EvalMode)) {
+// Show displays the welcome screen with the existing profiles to choose from.
+func (ws *WelcomeScreen) Show(profiles []storage.Profile, activeID string, onComplete func(profileID, name string, evalMode storage.EvalMode)) {
+	ws.profiles = profiles
+	ws.layout()
+
 	ws.visible = true
 	ws.needsCapture = true // Capture background on first draw
 	ws.onComplete = onComplete
+	for i, p := range profiles {
+		if p.ID == activeID {
+			ws.profileRadio.Selected = i
+		}
+	}
 	ws.nameInput.Value = ""
 	ws.evalModeRadio.Selected = 0
 	ws.startBtn.OnClick = ws.handleStart
@@ -93,8 +130,22 @@ func (ws *WelcomeScreen) IsVisible() bool {
 	return ws.visible
 }
 
+// creatingProfile returns true if the new-profile entry is selected.
+func (ws *WelcomeScreen) creatingProfile() bool {
+	return ws.profileRadio.Selected >= len(ws.profiles)
+}
+
 // handleStart handles the start button click.
 func (ws *WelcomeScreen) handleStart() {
+	if !ws.creatingProfile() {
+		p := ws.profiles[ws.profileRadio.Selected]
+		if ws.onComplete != nil {
+			ws.onComplete(p.ID, p.Name, 0)
+		}
+		ws.Hide()
+		return
+	}
+
 	name := ws.nameInput.Value
 	if name == "" {
 		name = "Player"
@@ -102,7 +153,7 @@ func (ws *WelcomeScreen) handleStart() {
 	evalMode := storage.EvalMode(ws.evalModeRadio.Selected)
 
 	if ws.onComplete != nil {
-		ws.onComplete(name, evalMode)
+		ws.onComplete("", name, evalMode)
 	}
 	ws.Hide()
 }
@@ -119,9 +170,14 @@ func (ws *WelcomeScreen) Update(input *InputHandler) bool {
 		return true
 	}
 
-	// Update widgets
-	ws.nameInput.Update(input)
-	ws.evalModeRadio.Update(input)
+	// Update widgets (the new-profile fields only while creating one)
+	if len(ws.profiles) > 0 {
+		ws.profileRadio.Update(input)
+	}
+	if ws.creatingProfile() {
+		ws.nameInput.Update(input)
+		ws.evalModeRadio.Update(input)
+	}
 	ws.startBtn.Update(input)
 
 	// Welcome screen consumes all input
@@ -133,7 +189,7 @@ func (ws *WelcomeScreen) AnyButtonHovered() bool {
 	if !ws.visible {
 		return false
 	}
-	return ws.startBtn.IsHovered() || ws.evalModeRadio.hovered >= 0
+	return ws.startBtn.IsHovered() || ws.evalModeRadio.hovered >= 0 || ws.profileRadio.hovered >= 0
 }
 
 // Draw renders the welcome screen.
@@ -157,10 +213,10 @@ func (ws *WelcomeScreen) Draw(screen *ebiten.Image, glass *GlassEffect) {
 	}
 
 	// Modal background
-	vector.DrawFilledRect(screen, scaleF(ws.x), scaleF(ws.y), scaleF(WelcomeWidth), scaleF(WelcomeHeight), modalBg, false)
+	vector.DrawFilledRect(screen, scaleF(ws.x), scaleF(ws.y), scaleF(WelcomeWidth), scaleF(ws.h), modalBg, false)
 
 	// Modal border
-	vector.StrokeRect(screen, scaleF(ws.x), scaleF(ws.y), scaleF(WelcomeWidth), scaleF(WelcomeHeight), float32(UIScale*2), modalBorder, false)
+	vector.StrokeRect(screen, scaleF(ws.x), scaleF(ws.y), scaleF(WelcomeWidth), scaleF(ws.h), float32(UIScale*2), modalBorder, false)
 
 	// Draw chess piece icon (king)
 	ws.drawChessIcon(screen)
@@ -171,16 +227,22 @@ func (ws *WelcomeScreen) Draw(screen *ebiten.Image, glass *GlassEffect) {
 	// Draw subtitle
 	ws.drawSubtitle(screen)
 
-	// Section label for name
 	contentX := ws.x + WelcomePadX
-	ws.drawSectionLabel(screen, "Your Name", contentX, ws.nameInput.Y-20)
 
-	// Section label for eval mode
-	ws.drawSectionLabel(screen, "Engine Mode", contentX, ws.evalModeRadio.Y-20)
+	// Profile list
+	if len(ws.profiles) > 0 {
+		ws.drawSectionLabel(screen, "Profile", contentX, ws.profileRadio.Y-20)
+		ws.profileRadio.Draw(screen)
+	}
+
+	// New profile fields
+	if ws.creatingProfile() {
+		ws.drawSectionLabel(screen, "Your Name", contentX, ws.nameInput.Y-20)
+		ws.drawSectionLabel(screen, "Engine Mode", contentX, ws.evalModeRadio.Y-20)
+		ws.nameInput.Draw(screen)
+		ws.evalModeRadio.Draw(screen)
+	}
 
-	// Draw widgets
-	ws.nameInput.Draw(screen)
-	ws.evalModeRadio.Draw(screen)
 	ws.startBtn.Draw(screen)
 }
 
@@ -226,6 +288,9 @@ func (ws *WelcomeScreen) drawSubtitle(screen *ebiten.Image) {
 	}
 
 	subtitle := "Welcome! Set up your preferences."
+	if len(ws.profiles) > 0 {
+		subtitle = "Welcome back! Choose your profile."
+	}
 	w, _ := MeasureText(subtitle, face)
 	centerX := scaleD(ws.x) + scaleD(WelcomeWidth)/2 - w/2
 
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"image/color"
+	"time"
 	"unicode/utf8"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -46,7 +47,7 @@ type TextInput struct {
 	MaxLength    int
 	focused      bool
 	hovered      bool
-	cursorBlink  int
+	focusedAt    time.Time // The cursor blink starts here
 }
 
 // NewTextInput creates a new text input widget.
@@ -65,6 +66,9 @@ func (ti *TextInput) Update(input *InputHandler) bool {
 
 	// Handle click to focus
 	if input.IsLeftJustPressed() {
+		if ti.hovered && !ti.focused {
+			ti.focusedAt = time.Now()
+		}
 		ti.focused = ti.hovered
 	}
 
@@ -72,11 +76,6 @@ func (ti *TextInput) Update(input *InputHandler) bool {
 		return false
 	}
 
-	ti.cursorBlink++
-	if ti.cursorBlink > 60 {
-		ti.cursorBlink = 0
-	}
-
 	// Handle text input
 	chars := ebiten.AppendInputChars(nil)
 	for _, c := range chars {
@@ -129,15 +128,21 @@ func (ti *TextInput) Draw(screen *ebiten.Image) {
 	textY := ti.Y + ti.H/2
 
 	if ti.Value != "" {
+		// A value wider than the box shows its end, where the cursor is
+		shown := ti.Value
+		for w, _ := MeasureText(shown, face); w > scaleD(ti.W-24) && len(shown) > 0; w, _ = MeasureText(shown, face) {
+			_, size := utf8.DecodeRuneInString(shown)
+			shown = shown[size:]
+		}
 		op := &text.DrawOptions{}
 		_, h := MeasureText(ti.Value, face)
 		op.GeoM.Translate(scaleD(textX), scaleD(textY)-h/2)
 		op.ColorScale.ScaleWithColor(inputTextColor)
-		text.Draw(screen, ti.Value, face, op)
+		text.Draw(screen, shown, face, op)
 
 		// Cursor
-		if ti.focused && ti.cursorBlink < 30 {
-			w, _ := MeasureText(ti.Value, face)
+		if ti.focused && ti.cursorOn() {
+			w, _ := MeasureText(shown, face)
 			cursorX := scaleF(textX) + float32(w) + 2
 			vector.DrawFilledRect(screen, cursorX, scaleF(ti.Y+8), scaleF(2), scaleF(ti.H-16), inputTextColor, false)
 		}
@@ -149,12 +154,17 @@ func (ti *TextInput) Draw(screen *ebiten.Image) {
 		text.Draw(screen, ti.Placeholder, face, op)
 
 		// Cursor when focused and empty
-		if ti.focused && ti.cursorBlink < 30 {
+		if ti.focused && ti.cursorOn() {
 			vector.DrawFilledRect(screen, scaleF(textX), scaleF(ti.Y+8), scaleF(2), scaleF(ti.H-16), inputTextColor, false)
 		}
 	}
 }
 
+// cursorOn returns true in the half second of each second the cursor shows.
+func (ti *TextInput) cursorOn() bool {
+	return time.Since(ti.focusedAt)%time.Second < time.Second/2
+}
+
 // IsFocused returns true if the input is focused.
 func (ti *TextInput) IsFocused() bool {
 	return ti.focused
@@ -162,6 +172,9 @@ func (ti *TextInput) IsFocused() bool {
 
 // SetFocused sets the focus state.
 func (ti *TextInput) SetFocused(focused bool) {
+	if focused && !ti.focused {
+		ti.focusedAt = time.Now()
+	}
 	ti.focused = focused
 }
 
@@ -444,6 +457,270 @@ func (bg *ButtonGroup) Draw(screen *ebiten.Image) {
 	}
 }
 
+// Slider picks a value in a range by dragging a knob along a track.
+type Slider struct {
+	X, Y, W  int // Track position; the knob is centered on Y
+	Min, Max int
+	Step     int
+	Value    int
+	hovered  bool
+	dragging bool
+}
+
+// sliderKnobR is the radius of a slider's knob.
+const sliderKnobR = 8
+
+// NewSlider creates a new slider.
+func NewSlider(x, y, w, minValue, maxValue, step, value int) *Slider {
+	return &Slider{X: x, Y: y, W: w, Min: minValue, Max: maxValue, Step: max(step, 1), Value: value}
+}
+
+// Update handles slider input. Returns true if the value changed.
+func (sl *Slider) Update(input *InputHandler) bool {
+	mx, my := input.MousePosition()
+	sl.hovered = mx >= sl.X-sliderKnobR && mx <= sl.X+sl.W+sliderKnobR &&
+		my >= sl.Y-sliderKnobR && my <= sl.Y+sliderKnobR
+
+	if sl.hovered && input.IsLeftJustPressed() {
+		sl.dragging = true
+	}
+	if !input.IsLeftPressed() {
+		sl.dragging = false
+	}
+	if !sl.dragging || sl.W <= 0 {
+		return false
+	}
+
+	// Snap the mouse position to the nearest step
+	frac := float64(min(max(mx-sl.X, 0), sl.W)) / float64(sl.W)
+	steps := int(frac*float64(sl.Max-sl.Min)/float64(sl.Step) + 0.5)
+	value := min(sl.Min+steps*sl.Step, sl.Max)
+	if value == sl.Value {
+		return false
+	}
+	sl.Value = value
+	return true
+}
+
+// Draw renders the slider.
+func (sl *Slider) Draw(screen *ebiten.Image) {
+	frac := float32(0)
+	if sl.Max > sl.Min {
+		frac = float32(sl.Value-sl.Min) / float32(sl.Max-sl.Min)
+	}
+	trackH := 6
+	vector.DrawFilledRect(screen, scaleF(sl.X), scaleF(sl.Y-trackH/2), scaleF(sl.W), scaleF(trackH), widgetBg, false)
+	vector.DrawFilledRect(screen, scaleF(sl.X), scaleF(sl.Y-trackH/2), scaleF(sl.W)*frac, scaleF(trackH), accentColor, false)
+
+	knobC := textSecondary
+	if sl.hovered || sl.dragging {
+		knobC = textPrimary
+	}
+	vector.DrawFilledCircle(screen, scaleF(sl.X)+scaleF(sl.W)*frac, scaleF(sl.Y), scaleF(sliderKnobR), knobC, true)
+}
+
+// IsHovered returns true if the mouse is over the slider.
+func (sl *Slider) IsHovered() bool {
+	return sl.hovered || sl.dragging
+}
+
+// DropdownOption is one choice of a Dropdown.
+type DropdownOption struct {
+	Label string
+	Value string
+}
+
+// Dropdown list limits
+const (
+	dropdownItemH      = 28
+	dropdownMaxVisible = 6 // More options scroll with the mouse wheel
+)
+
+// Dropdown is a selection box that opens a list of options when clicked.
+// While open it takes all input, so it should be updated first and drawn last.
+type Dropdown struct {
+	X, Y, W, H int
+	Options    []DropdownOption
+	Selected   int
+	open       bool
+	hovered    bool // Header hovered
+	hoveredOpt int  // Option hovered in the open list (-1 = none)
+	scroll     int  // First visible option
+}
+
+// NewDropdown creates a new dropdown.
+func NewDropdown(x, y, w, h int, options []DropdownOption, selected int) *Dropdown {
+	return &Dropdown{
+		X: x, Y: y, W: w, H: h,
+		Options:    options,
+		Selected:   selected,
+		hoveredOpt: -1,
+	}
+}
+
+// SetOptions replaces the options and selects the one with the given value
+// (the first option if none matches).
+func (dd *Dropdown) SetOptions(options []DropdownOption, value string) {
+	dd.Options = options
+	dd.Selected = 0
+	for i, opt := range options {
+		if opt.Value == value {
+			dd.Selected = i
+			break
+		}
+	}
+	dd.open = false
+	dd.scroll = 0
+}
+
+// Value returns the value of the selected option.
+func (dd *Dropdown) Value() string {
+	if dd.Selected < 0 || dd.Selected >= len(dd.Options) {
+		return ""
+	}
+	return dd.Options[dd.Selected].Value
+}
+
+// IsOpen returns true if the option list is shown.
+func (dd *Dropdown) IsOpen() bool {
+	return dd.open
+}
+
+// Close hides the option list.
+func (dd *Dropdown) Close() {
+	dd.open = false
+}
+
+// listRect returns the position and visible option count of the open list.
+// The list opens below the header, or above it if it would leave the screen.
+func (dd *Dropdown) listRect() (y, count int) {
+	count = min(len(dd.Options), dropdownMaxVisible)
+	y = dd.Y + dd.H
+	if y+count*dropdownItemH > ScreenHeight {
+		y = dd.Y - count*dropdownItemH
+	}
+	return y, count
+}
+
+// Update handles dropdown input. Returns true if the input was consumed.
+func (dd *Dropdown) Update(input *InputHandler) bool {
+	mx, my := input.MousePosition()
+	dd.hovered = mx >= dd.X && mx < dd.X+dd.W && my >= dd.Y && my < dd.Y+dd.H
+	dd.hoveredOpt = -1
+
+	if !dd.open {
+		if input.IsLeftJustPressed() && dd.hovered && len(dd.Options) > 0 {
+			dd.open = true
+			dd.scroll = max(0, min(dd.Selected, len(dd.Options)-dropdownMaxVisible))
+			return true
+		}
+		return false
+	}
+
+	listY, count := dd.listRect()
+	if _, wy := ebiten.Wheel(); wy != 0 {
+		dd.scroll -= int(wy)
+		dd.scroll = max(0, min(dd.scroll, len(dd.Options)-count))
+	}
+	if mx >= dd.X && mx < dd.X+dd.W && my >= listY && my < listY+count*dropdownItemH {
+		dd.hoveredOpt = dd.scroll + (my-listY)/dropdownItemH
+	}
+
+	if input.IsLeftJustPressed() {
+		if dd.hoveredOpt >= 0 {
+			dd.Selected = dd.hoveredOpt
+		}
+		// Any click closes the list, including clicks outside it
+		dd.open = false
+	}
+	return true
+}
+
+// Draw renders the dropdown and, when open, its option list.
+func (dd *Dropdown) Draw(screen *ebiten.Image) {
+	face := GetRegularFace()
+	if face == nil {
+		return
+	}
+
+	// Header box
+	bgColor := widgetBg
+	if dd.hovered || dd.open {
+		bgColor = widgetHoverBg
+	}
+	vector.DrawFilledRect(screen, scaleF(dd.X), scaleF(dd.Y), scaleF(dd.W), scaleF(dd.H), bgColor, false)
+	borderColor := widgetBorder
+	if dd.open {
+		borderColor = widgetFocusBorder
+	} else if dd.hovered {
+		borderColor = accentColor
+	}
+	vector.StrokeRect(screen, scaleF(dd.X), scaleF(dd.Y), scaleF(dd.W), scaleF(dd.H), float32(UIScale*2), borderColor, false)
+
+	label := ""
+	if dd.Selected >= 0 && dd.Selected < len(dd.Options) {
+		label = dd.Options[dd.Selected].Label
+	}
+	dd.drawLabel(screen, label, dd.Y+dd.H/2, inputTextColor)
+
+	// Arrow
+	ax := scaleF(dd.X + dd.W - 20)
+	ay := scaleF(dd.Y + dd.H/2)
+	vector.StrokeLine(screen, ax-scaleF(5), ay-scaleF(3), ax, ay+scaleF(2), float32(UIScale*2), textSecondary, false)
+	vector.StrokeLine(screen, ax, ay+scaleF(2), ax+scaleF(5), ay-scaleF(3), float32(UIScale*2), textSecondary, false)
+
+	if !dd.open {
+		return
+	}
+
+	// Option list
+	listY, count := dd.listRect()
+	vector.DrawFilledRect(screen, scaleF(dd.X), scaleF(listY), scaleF(dd.W), scaleF(count*dropdownItemH), widgetBg, false)
+	for i := 0; i < count; i++ {
+		idx := dd.scroll + i
+		itemY := listY + i*dropdownItemH
+		if idx == dd.hoveredOpt {
+			vector.DrawFilledRect(screen, scaleF(dd.X), scaleF(itemY), scaleF(dd.W), scaleF(dropdownItemH), widgetHoverBg, false)
+		}
+		textColor := textSecondary
+		if idx == dd.Selected {
+			textColor = radioActive
+		} else if idx == dd.hoveredOpt {
+			textColor = textPrimary
+		}
+		dd.drawLabel(screen, dd.Options[idx].Label, itemY+dropdownItemH/2, textColor)
+	}
+	vector.StrokeRect(screen, scaleF(dd.X), scaleF(listY), scaleF(dd.W), scaleF(count*dropdownItemH), float32(UIScale), widgetBorder, false)
+}
+
+// ellipsize cuts s short with an ellipsis if it is wider than maxW.
+func ellipsize(s string, face *text.GoTextFace, maxW float64) string {
+	if w, _ := MeasureText(s, face); w <= maxW {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if w, _ := MeasureText(string(runes)+"…", face); w <= maxW {
+			break
+		}
+	}
+	return string(runes) + "…"
+}
+
+// drawLabel draws an option label vertically centered on centerY,
+// cut short with an ellipsis if it does not fit beside the arrow.
+func (dd *Dropdown) drawLabel(screen *ebiten.Image, label string, centerY int, c color.Color) {
+	face := GetRegularFace()
+	label = ellipsize(label, face, scaleD(dd.W-40))
+
+	op := &text.DrawOptions{}
+	_, h := MeasureText(label, face)
+	op.GeoM.Translate(scaleD(dd.X+10), scaleD(centerY)-h/2)
+	op.ColorScale.ScaleWithColor(c)
+	text.Draw(screen, label, face, op)
+}
+
 // ModalButton is a button for modal dialogs.
 type ModalButton struct {
 	X, Y, W, H int
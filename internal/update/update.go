@@ -0,0 +1,311 @@
+// Package update checks the project's releases for a newer version of the
+// GUI and, where the executable can be replaced, downloads and stages it.
+//
+// Release builds set the version and the release signing key at link time:
+//
+//	go build -ldflags "-X github.com/hailam/chessplay/internal/update.Version=v1.2.0 \
+//	    -X github.com/hailam/chessplay/internal/update.PublicKey=<base64 key>"
+//
+// Each release carries an executable per platform, named by AssetName, and
+// next to it a .sig file: the base64 Ed25519ph signature (Ed25519 over the
+// SHA-512 digest) of the executable. Without a key, updates are announced but
+// never downloaded.
+package update
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the version of this build, e.g. "v1.2.0". Development builds
+// are "dev" and never update.
+var Version = "dev"
+
+// PublicKey is the base64 Ed25519 key release executables are signed with.
+var PublicKey = ""
+
+// ReleasesURL is the releases feed queried for the latest release.
+const ReleasesURL = "https://api.github.com/repos/hailam/chessplay/releases/latest"
+
+// Limits on what is downloaded
+const (
+	maxFeedSize = 1 << 20 // Release description
+	maxSigSize  = 1 << 10 // Signature file
+	maxExeSize  = 1 << 30 // Executable
+)
+
+var (
+	// ErrNoPublicKey is returned by Stage when the build has no signing key.
+	ErrNoPublicKey = errors.New("no release signing key in this build")
+
+	// ErrNoAsset is returned by Stage when the release has no executable
+	// or signature for this platform.
+	ErrNoAsset = errors.New("no download for this platform")
+
+	// ErrSignature is returned by Stage when the download does not match
+	// its signature.
+	ErrSignature = errors.New("update signature mismatch")
+)
+
+// Release is a published release.
+type Release struct {
+	Version string  `json:"tag_name"`
+	URL     string  `json:"html_url"` // Release page
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a file of a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// asset returns the asset with the given name, or nil.
+func (r *Release) asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// Checker queries a releases feed and stages updates.
+type Checker struct {
+	Client    *http.Client
+	URL       string            // Releases feed
+	Current   string            // Version running
+	PublicKey ed25519.PublicKey // Key releases are signed with; nil = none
+}
+
+// NewChecker creates a checker of the project's releases for this build.
+func NewChecker() *Checker {
+	c := &Checker{
+		Client:  &http.Client{Timeout: 10 * time.Minute},
+		URL:     ReleasesURL,
+		Current: Version,
+	}
+	if key, err := base64.StdEncoding.DecodeString(PublicKey); err == nil && len(key) == ed25519.PublicKeySize {
+		c.PublicKey = key
+	}
+	return c
+}
+
+// Supported returns true if this platform can replace its executable with
+// a staged update.
+func Supported() bool {
+	switch runtime.GOOS {
+	case "windows", "linux", "darwin":
+		return true
+	}
+	return false
+}
+
+// AssetName returns the name of the release executable for a platform,
+// e.g. "chessplay-linux-amd64" or "chessplay-windows-amd64.exe".
+func AssetName(goos, goarch string) string {
+	name := "chessplay-" + goos + "-" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Check returns the latest release if it is newer than the running
+// version, or nil. Development builds never have a newer release.
+func (c *Checker) Check(ctx context.Context) (*Release, error) {
+	if _, ok := parseVersion(c.Current); !ok {
+		return nil, nil
+	}
+	resp, err := c.get(ctx, c.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	r := &Release{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxFeedSize)).Decode(r); err != nil {
+		return nil, fmt.Errorf("reading releases: %w", err)
+	}
+	if !Newer(r.Version, c.Current) {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// Stage downloads the executable of the release for this platform into dir
+// and checks its signature. It returns the path of the verified file, which
+// Apply installs.
+func (c *Checker) Stage(ctx context.Context, r *Release, dir string) (string, error) {
+	if c.PublicKey == nil {
+		return "", ErrNoPublicKey
+	}
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	exe, sig := r.asset(name), r.asset(name+".sig")
+	if exe == nil || sig == nil {
+		return "", ErrNoAsset
+	}
+
+	signature, err := c.download(ctx, sig.URL, maxSigSize)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", sig.Name, err)
+	}
+	signature, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", sig.Name, err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, r.Version+"-"+name)
+	tmp := path + ".tmp"
+	digest, err := c.downloadFile(ctx, exe.URL, tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("%s: %w", exe.Name, err)
+	}
+	if ed25519.VerifyWithOptions(c.PublicKey, digest, signature, &ed25519.Options{Hash: crypto.SHA512}) != nil {
+		os.Remove(tmp)
+		return "", ErrSignature
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return path, nil
+}
+
+// get starts a GET request, failing on any status but 200.
+func (c *Checker) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+	return resp, nil
+}
+
+// download returns the body at url, of at most limit bytes.
+func (c *Checker) download(ctx context.Context, url string, limit int64) ([]byte, error) {
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+// downloadFile writes the body at url to an executable file at path and
+// returns its SHA-512 digest.
+func (c *Checker) downloadFile(ctx context.Context, url, path string) ([]byte, error) {
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return nil, err
+	}
+	h := sha512.New()
+	_, err = io.Copy(io.MultiWriter(out, h), io.LimitReader(resp.Body, maxExeSize))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// Apply replaces the running executable with a staged update, which takes
+// effect the next time the program starts. The old executable is kept as
+// a .old file until Cleanup, as Windows cannot delete a running program.
+func Apply(staged string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(staged, exe); err != nil {
+		os.Rename(old, exe) // Put the running version back
+		return err
+	}
+	return nil
+}
+
+// Cleanup removes the executable replaced by the last Apply.
+func Cleanup() {
+	if exe, err := os.Executable(); err == nil {
+		if exe, err = filepath.EvalSymlinks(exe); err == nil {
+			os.Remove(exe + ".old")
+		}
+	}
+}
+
+// Newer returns true if version v is newer than current. Versions are
+// "vMAJOR.MINOR.PATCH", with the v and trailing parts optional; anything
+// else, such as "dev", is never newer nor older.
+func Newer(v, current string) bool {
+	a, ok := parseVersion(v)
+	b, okCurrent := parseVersion(current)
+	if !ok || !okCurrent {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+	return false
+}
+
+// parseVersion parses a version into its major, minor and patch numbers.
+// Pre-release versions such as "v1.2.0-rc1" are not accepted.
+func parseVersion(v string) ([3]int, bool) {
+	var n [3]int
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return n, false
+	}
+	for i, p := range parts {
+		x, err := strconv.Atoi(p)
+		if err != nil || x < 0 {
+			return n, false
+		}
+		n[i] = x
+	}
+	return n, true
+}
@@ -0,0 +1,114 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		v, current string
+		want       bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"v2", "v1.9.9", true},
+		{"1.2.1", "v1.2.0", true},
+		{"v1.2.0", "v1.2.0", false},
+		{"v1.1.0", "v1.2.0", false},
+		{"v1.3.0", "dev", false},
+		{"v1.3.0-rc1", "v1.2.0", false},
+		{"", "v1.2.0", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.v, tt.current); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.v, tt.current, got, tt.want)
+		}
+	}
+}
+
+// releaseServer serves a release feed with an executable for this platform,
+// signed with key.
+func releaseServer(t *testing.T, version string, exe []byte, key ed25519.PrivateKey) *httptest.Server {
+	digest := sha512.Sum512(exe)
+	sig, err := key.Sign(nil, digest[:], &ed25519.Options{Hash: crypto.SHA512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Release{Version: version, Assets: []Asset{
+			{Name: name, URL: srv.URL + "/exe", Size: int64(len(exe))},
+			{Name: name + ".sig", URL: srv.URL + "/sig"},
+		}})
+	})
+	mux.HandleFunc("/exe", func(w http.ResponseWriter, r *http.Request) { w.Write(exe) })
+	mux.HandleFunc("/sig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(base64.StdEncoding.EncodeToString(sig) + "\n"))
+	})
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCheckAndStage(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := bytes.Repeat([]byte("new version "), 1000)
+	srv := releaseServer(t, "v1.3.0", exe, priv)
+	c := &Checker{Client: srv.Client(), URL: srv.URL + "/latest", Current: "v1.2.0", PublicKey: pub}
+	ctx := context.Background()
+
+	r, err := c.Check(ctx)
+	if err != nil || r == nil || r.Version != "v1.3.0" {
+		t.Fatalf("Check = %+v, %v; want v1.3.0", r, err)
+	}
+	path, err := c.Stage(ctx, r, t.TempDir())
+	if err != nil {
+		t.Fatalf("Stage failed: %v", err)
+	}
+	if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, exe) {
+		t.Errorf("Staged file differs from the release, err %v", err)
+	}
+
+	// Up to date, and development builds
+	for _, current := range []string{"v1.3.0", "dev"} {
+		c.Current = current
+		if r, err := c.Check(ctx); r != nil || err != nil {
+			t.Errorf("Check from %s = %+v, %v; want nothing", current, r, err)
+		}
+	}
+
+	// A download signed with another key is rejected
+	other, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.PublicKey = other
+	if _, err := c.Stage(ctx, r, t.TempDir()); !errors.Is(err, ErrSignature) {
+		t.Errorf("Stage with the wrong key: err %v, want %v", err, ErrSignature)
+	}
+	c.PublicKey = nil
+	if _, err := c.Stage(ctx, r, t.TempDir()); !errors.Is(err, ErrNoPublicKey) {
+		t.Errorf("Stage without a key: err %v, want %v", err, ErrNoPublicKey)
+	}
+	c.PublicKey = pub
+	if _, err := c.Stage(ctx, &Release{Version: "v1.3.0"}, t.TempDir()); !errors.Is(err, ErrNoAsset) {
+		t.Errorf("Stage without assets: err %v, want %v", err, ErrNoAsset)
+	}
+}
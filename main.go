@@ -1,15 +1,68 @@
 // ChessPlay - A chess game built with Ebitengine
+//
+// A chessplay:// link or Lichess analysis URL on the command line opens the
+// game at the shared position:
+//
+//	chessplay "chessplay://position?moves=e2e4,e7e5"
+//
+// A PGN file saved by chessplay reopens the game with its annotations.
+//
+// Set CHESSPLAY_DEBUG=1 to log every piece selection and move. The log is
+// also written to chessplay.log in the logs folder of the data directory,
+// which -data-dir moves (as for the UCI engine).
+//
+// "make web" builds the game for the browser into bin/web.
 package main
 
 import (
+	"flag"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/hailam/chessplay/internal/share"
+	"github.com/hailam/chessplay/internal/storage"
 	"github.com/hailam/chessplay/internal/ui"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
+var dataDir = flag.String("data-dir", "", "data directory for preferences, games, NNUE networks, books and logs (default: the platform's)")
+
 func main() {
+	flag.Parse()
+	storage.SetDataDir(*dataDir)
+	if dir, err := storage.GetLogsDir(); err == nil {
+		if f, err := os.Create(filepath.Join(dir, "chessplay.log")); err == nil {
+			log.SetOutput(io.MultiWriter(os.Stderr, f))
+			defer f.Close()
+		}
+	}
+
+	var link *share.Link
+	var gamePath string
+	arg := flag.Arg(0)
+	if strings.HasSuffix(strings.ToLower(arg), ".pgn") {
+		gamePath = arg
+	} else if arg != "" {
+		var err error
+		if link, err = share.Parse(arg); err != nil {
+			log.Fatalf("Cannot open %s: %v", arg, err)
+		}
+	}
+
 	game := ui.NewGame()
+	if link != nil {
+		if err := game.OpenLink(link); err != nil {
+			log.Fatalf("Cannot open %s: %v", arg, err)
+		}
+	}
+	if gamePath != "" {
+		if err := game.OpenGame(gamePath); err != nil {
+			log.Fatalf("Cannot open %s: %v", gamePath, err)
+		}
+	}
 
 	ebiten.SetWindowSize(ui.ScreenWidth, ui.ScreenHeight)
 	ebiten.SetWindowTitle("ChessPlay")
@@ -18,7 +71,12 @@ func main() {
 	// Enable smooth scaling when window is resized or fullscreen
 	ebiten.SetScreenFilterEnabled(true)
 
-	if err := ebiten.RunGame(game); err != nil {
+	err := ebiten.RunGame(game)
+
+	// Flush storage (and push changes to the sync backend) on exit
+	game.Close()
+
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,128 @@
+// Network architecture identification.
+// Network files only carry hashes of their architecture, so the feature set
+// and layer sizes are recovered by matching the hashes of candidate shapes.
+
+package sfnnue
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hailam/chessplay/sfnnue/features"
+	"github.com/hailam/chessplay/sfnnue/layers"
+)
+
+// ErrUnsupportedNetwork is returned for network files whose version or
+// architecture cannot be evaluated.
+var ErrUnsupportedNetwork = errors.New("unsupported network")
+
+// Network file versions of older Stockfish releases (nnue_common.h:58)
+const versionSF12 uint32 = 0x7AF32F16 // Stockfish 12 and 13
+
+// Architecture describes the shape of a network: its input features and
+// layer sizes.
+type Architecture struct {
+	FeatureSet string // Input feature set name
+	Inputs     int    // Input feature dimensions
+	L1         int    // Feature transformer outputs per perspective
+	L2, L3     int    // Hidden layer sizes (0 if the layer stack is not recognised)
+
+	featureHash uint32
+}
+
+// String formats the architecture like Stockfish's network banner,
+// e.g. "HalfKAv2_hm(Friend) (22528, 128, 15, 32, 1)".
+func (a Architecture) String() string {
+	if a.L2 == 0 {
+		return fmt.Sprintf("%s (%d, %d, ?)", a.FeatureSet, a.Inputs, a.L1)
+	}
+	return fmt.Sprintf("%s (%d, %d, %d, %d, 1)", a.FeatureSet, a.Inputs, a.L1, a.L2, a.L3)
+}
+
+// featureSets lists the input feature sets that network files are checked
+// against. Only the first two can be evaluated.
+var featureSets = []Architecture{
+	{FeatureSet: features.Name, Inputs: PSQInputDimensions, featureHash: features.HashValue},
+	{FeatureSet: features.Name + "+" + features.ThreatName, Inputs: PSQInputDimensions + ThreatInputDimensions,
+		featureHash: features.ThreatHashValue},
+	{FeatureSet: "HalfKAv2(Friend)", Inputs: 45056, featureHash: 0x5F234CB8}, // Stockfish 14
+	{FeatureSet: "HalfKP(Friend)", Inputs: 41024, featureHash: 0x5D69D5B8},   // Stockfish 12 and 13
+}
+
+// Architectures of the big and small networks
+var (
+	BigArchitecture   = withLayers(featureSets[1], TransformedFeatureDimensionsBig, L2Big, L3Big)
+	SmallArchitecture = withLayers(featureSets[0], TransformedFeatureDimensionsSmall, L2Small, L3Small)
+)
+
+// withLayers returns a feature set with the given layer sizes.
+func withLayers(a Architecture, l1, l2, l3 int) Architecture {
+	a.L1, a.L2, a.L3 = l1, l2, l3
+	return a
+}
+
+// hashes returns the feature transformer hash and the file hash of the
+// architecture, as computed by FeatureTransformer and NetworkArchitecture.
+func (a Architecture) hashes() (transformer, file uint32) {
+	transformer = a.featureHash ^ uint32(a.L1*2)
+
+	stack := uint32(0xEC42E90D) ^ uint32(a.L1*2)
+	stack = layers.AffineTransformHashValue(stack, a.L2+1)
+	stack = layers.ClippedReLUHashValue(stack)
+	stack = layers.AffineTransformHashValue(stack, a.L3)
+	stack = layers.ClippedReLUHashValue(stack)
+	stack = layers.AffineTransformHashValue(stack, 1)
+	return transformer, transformer ^ stack
+}
+
+// Hidden layer sizes tried by decodeArchitecture. The layer hashes collide
+// over wider ranges, so only sizes Stockfish networks have used are tried.
+var (
+	candidateL2 = []int{15, 31, 7, 63}
+	candidateL3 = []int{32, 64, 16}
+)
+
+// maxL1 bounds the feature transformer size decodeArchitecture accepts.
+const maxL1 = 16384
+
+// decodeArchitecture recovers the architecture of a network file from its
+// file and feature transformer hashes. The hidden layer sizes are left at
+// zero when only the feature set is recognised.
+func decodeArchitecture(fileHash, transformerHash uint32) (Architecture, bool) {
+	for _, fs := range featureSets {
+		l1 := transformerHash ^ fs.featureHash
+		if l1 == 0 || l1%2 != 0 || l1/2 > maxL1 {
+			continue
+		}
+		a := withLayers(fs, int(l1/2), 0, 0)
+		for _, l2 := range candidateL2 {
+			for _, l3 := range candidateL3 {
+				if _, file := withLayers(fs, a.L1, l2, l3).hashes(); file == fileHash {
+					return withLayers(fs, a.L1, l2, l3), true
+				}
+			}
+		}
+		return a, true
+	}
+	return Architecture{}, false
+}
+
+// unsupportedVersion describes a network file of another format version.
+func unsupportedVersion(version uint32) error {
+	if version == versionSF12 {
+		return fmt.Errorf("%w: file version %08x is from Stockfish 12 or 13, need %08x",
+			ErrUnsupportedNetwork, version, Version)
+	}
+	return fmt.Errorf("%w: file version %08x, need %08x", ErrUnsupportedNetwork, version, Version)
+}
+
+// unsupportedArchitecture describes a network file whose hash matches
+// neither architecture. transformerHash is 0 if the file ends after the
+// header.
+func unsupportedArchitecture(fileHash, transformerHash uint32) error {
+	supported := fmt.Sprintf("supported: %v and %v", BigArchitecture, SmallArchitecture)
+	if a, ok := decodeArchitecture(fileHash, transformerHash); ok {
+		return fmt.Errorf("%w: architecture %v (%s)", ErrUnsupportedNetwork, a, supported)
+	}
+	return fmt.Errorf("%w: unknown architecture (hash %08x; %s)", ErrUnsupportedNetwork, fileHash, supported)
+}
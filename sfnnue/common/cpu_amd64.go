@@ -0,0 +1,35 @@
+package common
+
+// HasAVX2 reports whether the CPU and operating system support AVX2.
+// The AVX2 kernels fall back to scalar code when it is false.
+var HasAVX2 = detectAVX2()
+
+// cpuid executes the CPUID instruction (implemented in cpu_amd64.s).
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+// xgetbv reads extended control register 0 (implemented in cpu_amd64.s).
+func xgetbv() (eax, edx uint32)
+
+// detectAVX2 checks the AVX2 feature flag and that the OS saves the YMM
+// registers on context switches.
+func detectAVX2() bool {
+	maxID, _, _, _ := cpuid(0, 0)
+	if maxID < 7 {
+		return false
+	}
+
+	const osxsave, avx = 1 << 27, 1 << 28
+	_, _, ecx1, _ := cpuid(1, 0)
+	if ecx1&osxsave == 0 || ecx1&avx == 0 {
+		return false
+	}
+
+	// XMM and YMM state enabled
+	if xcr0, _ := xgetbv(); xcr0&6 != 6 {
+		return false
+	}
+
+	const avx2 = 1 << 5
+	_, ebx7, _, _ := cpuid(7, 0)
+	return ebx7&avx2 != 0
+}
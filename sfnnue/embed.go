@@ -0,0 +1,11 @@
+//go:build embednet
+
+package sfnnue
+
+import _ "embed"
+
+// embeddedSmallNet is the small network compiled into the binary.
+// Fetch it with "make embed-net" before building with -tags embednet.
+//
+//go:embed nets/nn-37f18f62d772.nnue
+var embeddedSmallNet []byte
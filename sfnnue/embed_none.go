@@ -0,0 +1,6 @@
+//go:build !embednet
+
+package sfnnue
+
+// embeddedSmallNet is empty in builds without the embednet tag.
+var embeddedSmallNet []byte
@@ -1,8 +1,8 @@
-//go:build !arm64
+//go:build !arm64 && !amd64
 
 // Scalar fallback for layer SIMD operations.
-// Used on non-ARM64 platforms.
-// ARM64 uses simd_arm64.go with NEON assembly.
+// Used on non-ARM64, non-AMD64 platforms.
+// ARM64 uses simd_arm64.go with NEON assembly, AMD64 simd_amd64.go with AVX2.
 
 package layers
 
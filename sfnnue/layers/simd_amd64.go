@@ -0,0 +1,76 @@
+//go:build amd64
+
+// AMD64 AVX2 SIMD operations for affine transform layers.
+// Uses pure Go assembly with AVX2 instructions, selected at runtime by CPU
+// detection, with scalar loops for the remainders and for CPUs without AVX2.
+// No CGO required.
+
+package layers
+
+import (
+	"unsafe"
+
+	"github.com/hailam/chessplay/sfnnue/common"
+)
+
+// useAVX2 selects the AVX2 kernels. Tests clear it to compare them with the
+// scalar path.
+var useAVX2 = common.HasAVX2
+
+// Assembly function declarations (implemented in simd_amd64.s)
+
+//go:noescape
+func avx2DotProductInt8Uint8(weights, inputs unsafe.Pointer, n int) int32
+
+//go:noescape
+func avx2SparseChunkMulAcc(output, weights unsafe.Pointer, outLen int, inputChunk uint32)
+
+// SIMDDotProductInt8Uint8 computes dot product of int8 weights and uint8 inputs.
+// Uses AVX2 for vectorized computation.
+// Returns: sum(weights[i] * inputs[i]) for i in [0, count)
+func SIMDDotProductInt8Uint8(weights []int8, inputs []uint8, count int) int32 {
+	if count > len(weights) {
+		count = len(weights)
+	}
+	if count > len(inputs) {
+		count = len(inputs)
+	}
+
+	var sum int32
+	processed := 0
+	if useAVX2 && count >= 16 {
+		processed = count &^ 15
+		sum = avx2DotProductInt8Uint8(unsafe.Pointer(&weights[0]), unsafe.Pointer(&inputs[0]), processed)
+	}
+	for i := processed; i < count; i++ {
+		sum += int32(weights[i]) * int32(inputs[i])
+	}
+	return sum
+}
+
+// SIMDSparseChunkMulAcc processes one non-zero input chunk across all outputs.
+// Uses AVX2 for 8 outputs at a time.
+// output: int32 output array
+// weights: int8 weights at colOffset (contiguous for all outputs)
+// outLen: number of outputs (typically 16)
+// inputChunk: packed input bytes as uint32
+func SIMDSparseChunkMulAcc(output []int32, weights []int8, outLen int, inputChunk uint32) {
+	if outLen == 0 || inputChunk == 0 {
+		return
+	}
+
+	processed := 0
+	if useAVX2 && outLen >= 8 {
+		processed = outLen &^ 7
+		avx2SparseChunkMulAcc(unsafe.Pointer(&output[0]), unsafe.Pointer(&weights[0]), processed, inputChunk)
+	}
+
+	b0 := int32(uint8(inputChunk))
+	b1 := int32(uint8(inputChunk >> 8))
+	b2 := int32(uint8(inputChunk >> 16))
+	b3 := int32(uint8(inputChunk >> 24))
+	for k := processed; k < outLen; k++ {
+		w := weights[k*4 : k*4+4]
+		output[k] += int32(w[0])*b0 + int32(w[1])*b1 + int32(w[2])*b2 + int32(w[3])*b3
+	}
+}
@@ -0,0 +1,74 @@
+//go:build amd64
+
+#include "textflag.h"
+
+// AMD64 AVX2 SIMD operations for affine transform layers.
+
+// func avx2DotProductInt8Uint8(weights, inputs unsafe.Pointer, n int) int32
+// Returns sum(weights[i] * inputs[i]) (n must be a multiple of 16).
+// Bytes are widened to int16 before VPMADDWD, so the result is exact for
+// any input.
+TEXT ·avx2DotProductInt8Uint8(SB), NOSPLIT, $0-28
+	MOVQ weights+0(FP), SI
+	MOVQ inputs+8(FP), DI
+	MOVQ n+16(FP), CX
+	VPXOR Y0, Y0, Y0
+	SHRQ $4, CX
+	JZ   dot_sum
+
+dot_loop:
+	VPMOVSXBW (SI), Y1
+	VPMOVZXBW (DI), Y2
+	VPMADDWD  Y2, Y1, Y1
+	VPADDD    Y1, Y0, Y0
+	ADDQ      $16, SI
+	ADDQ      $16, DI
+	DECQ      CX
+	JNZ       dot_loop
+
+dot_sum:
+	// Horizontal sum of the eight int32 lanes
+	VEXTRACTI128 $1, Y0, X1
+	VPADDD       X1, X0, X0
+	VPSHUFD      $0x4E, X0, X1
+	VPADDD       X1, X0, X0
+	VPSHUFD      $0xB1, X0, X1
+	VPADDD       X1, X0, X0
+	MOVL         X0, AX
+	VZEROUPPER
+	MOVL AX, ret+24(FP)
+	RET
+
+// func avx2SparseChunkMulAcc(output, weights unsafe.Pointer, outLen int, inputChunk uint32)
+// output[k] += sum(weights[4k+j] * byte j of inputChunk), 8 outputs per
+// iteration (outLen must be a multiple of 8)
+TEXT ·avx2SparseChunkMulAcc(SB), NOSPLIT, $0-28
+	MOVQ output+0(FP), DI
+	MOVQ weights+8(FP), SI
+	MOVQ outLen+16(FP), CX
+	MOVL inputChunk+24(FP), AX
+	SHRQ $3, CX
+	JZ   sparse_done
+
+	// Input bytes widened to int16 and repeated: [b0 b1 b2 b3] x 4
+	MOVQ         AX, X0
+	VPMOVZXBW    X0, X0
+	VPBROADCASTQ X0, Y15
+
+sparse_loop:
+	VPMOVSXBW (SI), Y1   // Weights of outputs 0-3
+	VPMOVSXBW 16(SI), Y2 // Weights of outputs 4-7
+	VPMADDWD  Y15, Y1, Y1
+	VPMADDWD  Y15, Y2, Y2
+	VPHADDD   Y2, Y1, Y1    // Lanes hold outputs 0 1 4 5 | 2 3 6 7
+	VPERMQ    $0xD8, Y1, Y1 // Restore output order
+	VPADDD    (DI), Y1, Y1
+	VMOVDQU   Y1, (DI)
+	ADDQ      $32, SI
+	ADDQ      $32, DI
+	DECQ      CX
+	JNZ       sparse_loop
+
+sparse_done:
+	VZEROUPPER
+	RET
@@ -81,6 +81,36 @@ func TestSIMDDotProductInt8Uint8_SmallCount(t *testing.T) {
 	}
 }
 
+func TestSIMDSparseChunkMulAcc(t *testing.T) {
+	// Output counts with and without a remainder after 8-wide vectors
+	for _, outLen := range []int{1, 4, 8, 15, 16, 32} {
+		weights := make([]int8, outLen*4)
+		for i := range weights {
+			weights[i] = int8(i*37 - 128)
+		}
+		for _, chunk := range []uint32{0x01020304, 0xffffffff, 0x80007f00} {
+			output := make([]int32, outLen)
+			expected := make([]int32, outLen)
+			for k := range output {
+				output[k] = int32(k * 1000)
+				expected[k] = output[k]
+				for j := 0; j < 4; j++ {
+					expected[k] += int32(weights[k*4+j]) * int32(uint8(chunk>>(8*j)))
+				}
+			}
+
+			SIMDSparseChunkMulAcc(output, weights, outLen, chunk)
+
+			for k := range output {
+				if output[k] != expected[k] {
+					t.Errorf("outLen %d chunk %#x: output[%d] = %d, expected %d",
+						outLen, chunk, k, output[k], expected[k])
+				}
+			}
+		}
+	}
+}
+
 // BenchmarkSIMDDotProductInt8Uint8 benchmarks the dot product operation
 func BenchmarkSIMDDotProductInt8Uint8_256(b *testing.B) {
 	weights := make([]int8, 256)
@@ -4,9 +4,12 @@
 package sfnnue
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 )
 
 // Network represents a complete NNUE network (big or small).
@@ -23,6 +26,7 @@ type Network struct {
 
 	// File info
 	CurrentFile    string
+	FileSize       int64
 	NetDescription string
 
 	// Initialization status
@@ -83,7 +87,14 @@ func (n *Network) Load(filename string) error {
 	}
 	defer f.Close()
 
-	return n.LoadFromReader(f)
+	if err := n.LoadFromReader(f); err != nil {
+		return err
+	}
+	n.CurrentFile = filename
+	if st, err := f.Stat(); err == nil {
+		n.FileSize = st.Size()
+	}
+	return nil
 }
 
 // LoadFromReader loads network parameters from a reader.
@@ -97,7 +108,10 @@ func (n *Network) LoadFromReader(r io.Reader) error {
 	}
 
 	if hashValue != n.Hash {
-		return fmt.Errorf("hash mismatch: expected %08x, got %08x", n.Hash, hashValue)
+		// The transformer hash that follows identifies the feature set
+		ftHash, _ := ReadLittleEndian[uint32](r)
+		return fmt.Errorf("hash mismatch: expected %08x, got %08x: %w",
+			n.Hash, hashValue, unsupportedArchitecture(hashValue, ftHash))
 	}
 
 	n.NetDescription = description
@@ -119,7 +133,7 @@ func (n *Network) readHeader(r io.Reader) (uint32, string, error) {
 		return 0, "", fmt.Errorf("failed to read version: %w", err)
 	}
 	if version != Version {
-		return 0, "", fmt.Errorf("version mismatch: expected %08x, got %08x", Version, version)
+		return 0, "", unsupportedVersion(version)
 	}
 
 	// Read hash
@@ -231,10 +245,45 @@ func (n *Network) Evaluate(
 // Networks holds both big and small networks.
 // Ported from network.h:132-139
 type Networks struct {
-	Big   *Network
+	Big   *Network // nil when evaluating with the small network only
 	Small *Network
 }
 
+// SmallOnly returns whether only the small network is loaded.
+func (n *Networks) SmallOnly() bool {
+	return n.Big == nil
+}
+
+// EmbeddedSmallName is the file name of the small network compiled into
+// builds with the embednet tag.
+const EmbeddedSmallName = "nn-37f18f62d772.nnue"
+
+// ErrNoEmbeddedNetwork is returned when the embedded network is requested
+// from a build without one.
+var ErrNoEmbeddedNetwork = errors.New("no embedded network (build with -tags embednet)")
+
+// HasEmbeddedNetwork returns whether a small network is compiled into the binary.
+func HasEmbeddedNetwork() bool {
+	return len(embeddedSmallNet) > 0
+}
+
+// loadSmall loads the small network from a file, or from the embedded
+// network if file is empty.
+func (n *Network) loadSmall(file string) error {
+	if file != "" {
+		return n.Load(file)
+	}
+	if !HasEmbeddedNetwork() {
+		return ErrNoEmbeddedNetwork
+	}
+	if err := n.LoadFromReader(bytes.NewReader(embeddedSmallNet)); err != nil {
+		return err
+	}
+	n.CurrentFile = EmbeddedSmallName
+	n.FileSize = int64(len(embeddedSmallNet))
+	return nil
+}
+
 // NewNetworks creates both networks
 func NewNetworks() *Networks {
 	return &Networks{
@@ -243,7 +292,8 @@ func NewNetworks() *Networks {
 	}
 }
 
-// LoadNetworks loads both networks from files
+// LoadNetworks loads both networks from files. An empty smallFile loads
+// the embedded small network.
 func LoadNetworks(bigFile, smallFile string) (*Networks, error) {
 	nets := NewNetworks()
 
@@ -251,13 +301,103 @@ func LoadNetworks(bigFile, smallFile string) (*Networks, error) {
 		return nil, fmt.Errorf("failed to load big network: %w", err)
 	}
 
-	if err := nets.Small.Load(smallFile); err != nil {
+	if err := nets.Small.loadSmall(smallFile); err != nil {
 		return nil, fmt.Errorf("failed to load small network: %w", err)
 	}
 
 	return nets, nil
 }
 
+// LoadSmallNetwork loads only the small network, from smallFile or, if it
+// is empty, from the embedded network. Big is left nil.
+func LoadSmallNetwork(smallFile string) (*Networks, error) {
+	nets := &Networks{Small: NewSmallNetwork()}
+	if err := nets.Small.loadSmall(smallFile); err != nil {
+		return nil, fmt.Errorf("failed to load small network: %w", err)
+	}
+	return nets, nil
+}
+
+// LoadSmallNetworkData loads only the small network from the contents of a
+// network file, named name, for platforms without files. Big is left nil.
+func LoadSmallNetworkData(data []byte, name string) (*Networks, error) {
+	nets := &Networks{Small: NewSmallNetwork()}
+	if err := nets.Small.LoadFromReader(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to load small network: %w", err)
+	}
+	nets.Small.CurrentFile = name
+	nets.Small.FileSize = int64(len(data))
+	return nets, nil
+}
+
+// NetworkFileInfo describes a network file from its header.
+type NetworkFileInfo struct {
+	Name        string // File name without directory
+	Size        int64  // File size in bytes
+	IsBig       bool   // Big network architecture (false = small)
+	Hash        uint32
+	Arch        Architecture
+	Description string
+}
+
+// NetworkInfo describes the loaded network file.
+func (n *Network) NetworkInfo() NetworkFileInfo {
+	arch := SmallArchitecture
+	if n.IsBig {
+		arch = BigArchitecture
+	}
+	return NetworkFileInfo{
+		Name:        filepath.Base(n.CurrentFile),
+		Size:        n.FileSize,
+		IsBig:       n.IsBig,
+		Hash:        n.Hash,
+		Arch:        arch,
+		Description: n.NetDescription,
+	}
+}
+
+// expectedHash returns the file hash of the big or small architecture
+// without allocating the network parameters.
+func expectedHash(big bool) uint32 {
+	if big {
+		ft := &FeatureTransformer{HalfDimensions: TransformedFeatureDimensionsBig, UseThreats: true}
+		return ft.GetHashValue() ^ NewBigNetworkArchitecture().GetHashValue()
+	}
+	ft := &FeatureTransformer{HalfDimensions: TransformedFeatureDimensionsSmall}
+	return ft.GetHashValue() ^ NewSmallNetworkArchitecture().GetHashValue()
+}
+
+// InspectNetwork reads only the header of a network file and reports which
+// architecture it is for. Files of other versions or architectures are rejected
+// with an ErrUnsupportedNetwork error describing the file.
+func InspectNetwork(filename string) (*NetworkFileInfo, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	hashValue, description, err := (&Network{}).readHeader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	info := &NetworkFileInfo{Name: filepath.Base(filename), Hash: hashValue, Description: description}
+	if st, err := f.Stat(); err == nil {
+		info.Size = st.Size()
+	}
+	switch hashValue {
+	case expectedHash(true):
+		info.IsBig, info.Arch = true, BigArchitecture
+	case expectedHash(false):
+		info.IsBig, info.Arch = false, SmallArchitecture
+	default:
+		ftHash, _ := ReadLittleEndian[uint32](f)
+		return nil, unsupportedArchitecture(hashValue, ftHash)
+	}
+	return info, nil
+}
+
 // Evaluator provides a high-level interface for NNUE evaluation.
 type Evaluator struct {
 	Networks *Networks
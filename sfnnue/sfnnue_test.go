@@ -1,8 +1,13 @@
 package sfnnue
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -299,6 +304,102 @@ func TestAccumulatorStack(t *testing.T) {
 	t.Log("Accumulator stack operations work correctly")
 }
 
+// TestSIMDKernels checks the SIMD operations against scalar reference loops
+// for lengths that exercise both the vector body and the remainder, with
+// values at the saturation limits.
+func TestSIMDKernels(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	lengths := []int{0, 1, 7, 8, 15, 16, 17, 31, 32, 33, 63, 64, 100, 512, 1024}
+
+	for _, n := range lengths {
+		a := make([]int16, n)
+		b := make([]int16, n)
+		for i := range a {
+			a[i] = int16(rng.Intn(1 << 16))
+			b[i] = int16(rng.Intn(1 << 16))
+		}
+
+		got := append([]int16(nil), a...)
+		SIMDAddInt16(got, b)
+		for i := range got {
+			if got[i] != a[i]+b[i] {
+				t.Fatalf("SIMDAddInt16 n=%d: [%d] = %d, want %d", n, i, got[i], a[i]+b[i])
+			}
+		}
+		got = append([]int16(nil), a...)
+		SIMDSubInt16(got, b)
+		for i := range got {
+			if got[i] != a[i]-b[i] {
+				t.Fatalf("SIMDSubInt16 n=%d: [%d] = %d, want %d", n, i, got[i], a[i]-b[i])
+			}
+		}
+
+		// Offset variants read src[offset:offset+n]
+		src := append(make([]int16, 3), b...)
+		got = append([]int16(nil), a...)
+		SIMDAddInt16Offset(got, src, 3, n)
+		SIMDSubInt16Offset(got, src, 3, n)
+		for i := range got {
+			if got[i] != a[i] {
+				t.Fatalf("SIMDAddInt16Offset/SubInt16Offset n=%d: [%d] = %d, want %d", n, i, got[i], a[i])
+			}
+		}
+
+		a32 := make([]int32, n)
+		b32 := make([]int32, n)
+		for i := range a32 {
+			a32[i] = int32(rng.Uint32())
+			b32[i] = int32(rng.Uint32())
+		}
+		got32 := append([]int32(nil), a32...)
+		SIMDAddInt32(got32, b32)
+		for i := range got32 {
+			if got32[i] != a32[i]+b32[i] {
+				t.Fatalf("SIMDAddInt32 n=%d: [%d] = %d, want %d", n, i, got32[i], a32[i]+b32[i])
+			}
+		}
+		SIMDSubInt32(got32, b32)
+		for i := range got32 {
+			if got32[i] != a32[i] {
+				t.Fatalf("SIMDSubInt32 n=%d: [%d] = %d, want %d", n, i, got32[i], a32[i])
+			}
+		}
+
+		weights := make([]int8, n)
+		inputs := make([]uint8, n)
+		var wantDot int32
+		for i := range weights {
+			weights[i] = int8(rng.Intn(256))
+			inputs[i] = uint8(rng.Intn(256))
+			wantDot += int32(weights[i]) * int32(inputs[i])
+		}
+		if dot := SIMDDotProductInt8Uint8(weights, inputs, n); dot != wantDot {
+			t.Fatalf("SIMDDotProductInt8Uint8 n=%d: %d, want %d", n, dot, wantDot)
+		}
+
+		relu := make([]uint8, n)
+		SIMDClippedReLU(a32, relu, 6)
+		for i := range relu {
+			want := min(max(int(a32[i]>>6), 0), 127)
+			if int(relu[i]) != want {
+				t.Fatalf("SIMDClippedReLU n=%d: [%d] = %d, want %d", n, i, relu[i], want)
+			}
+		}
+
+		for _, maxVal := range []int{254, 255} {
+			out := make([]uint8, n)
+			SIMDTransformClampMul(a, b, out, maxVal)
+			for i := range out {
+				x := min(max(int(a[i]), 0), maxVal)
+				y := min(max(int(b[i]), 0), maxVal)
+				if want := uint8((x * y) >> 9); out[i] != want {
+					t.Fatalf("SIMDTransformClampMul n=%d max=%d: [%d] = %d, want %d", n, maxVal, i, out[i], want)
+				}
+			}
+		}
+	}
+}
+
 // Benchmarks for SIMD operations
 
 // BenchmarkSIMDAddInt16 benchmarks int16 vector addition
@@ -358,6 +459,89 @@ func BenchmarkAccumulatorUpdate(b *testing.B) {
 	}
 }
 
+func TestInspectNetwork(t *testing.T) {
+	// The header-only hashes must match the ones Load checks
+	if expectedHash(true) != NewBigNetwork().Hash || expectedHash(false) != NewSmallNetwork().Hash {
+		t.Fatalf("expectedHash does not match the network constructors")
+	}
+
+	dir := t.TempDir()
+	write := func(name string, version, hash uint32, desc string) string {
+		var buf bytes.Buffer
+		WriteLittleEndian(&buf, version)
+		WriteLittleEndian(&buf, hash)
+		WriteLittleEndian(&buf, uint32(len(desc)))
+		buf.WriteString(desc)
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	info, err := InspectNetwork(write("big.nnue", Version, expectedHash(true), "big net"))
+	if err != nil || !info.IsBig || info.Description != "big net" {
+		t.Errorf("big network: got %+v, %v", info, err)
+	}
+	info, err = InspectNetwork(write("small.nnue", Version, expectedHash(false), "small net"))
+	if err != nil || info.IsBig {
+		t.Errorf("small network: got %+v, %v", info, err)
+	}
+	if _, err := InspectNetwork(write("old.nnue", Version+1, expectedHash(true), "")); err == nil {
+		t.Errorf("expected an error for a version mismatch")
+	}
+	if _, err := InspectNetwork(write("other.nnue", Version, 0x12345678, "")); !errors.Is(err, ErrUnsupportedNetwork) {
+		t.Errorf("expected ErrUnsupportedNetwork for an unknown architecture, got %v", err)
+	}
+
+	// Networks of other sizes are identified from the transformer hash
+	// that follows the header
+	wide := withLayers(featureSets[0], 256, 31, 32)
+	ftHash, fileHash := wide.hashes()
+	path := write("wide.nnue", Version, fileHash, "")
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	WriteLittleEndian(f, ftHash)
+	f.Close()
+	_, err = InspectNetwork(path)
+	if !errors.Is(err, ErrUnsupportedNetwork) || !strings.Contains(err.Error(), wide.String()) {
+		t.Errorf("expected the architecture %v in the error, got %v", wide, err)
+	}
+}
+
+func TestArchitectureHashes(t *testing.T) {
+	if _, h := BigArchitecture.hashes(); h != expectedHash(true) {
+		t.Errorf("BigArchitecture hash %08x, want %08x", h, expectedHash(true))
+	}
+	if _, h := SmallArchitecture.hashes(); h != expectedHash(false) {
+		t.Errorf("SmallArchitecture hash %08x, want %08x", h, expectedHash(false))
+	}
+	for _, a := range []Architecture{BigArchitecture, SmallArchitecture} {
+		ft, file := a.hashes()
+		if got, ok := decodeArchitecture(file, ft); !ok || got != a {
+			t.Errorf("decodeArchitecture(%v) = %v, %v", a, got, ok)
+		}
+	}
+	if got := SmallArchitecture.String(); got != "HalfKAv2_hm(Friend) (22528, 128, 15, 32, 1)" {
+		t.Errorf("SmallArchitecture.String() = %q", got)
+	}
+}
+
+func TestEmbeddedSmallNetwork(t *testing.T) {
+	nets, err := LoadSmallNetwork("")
+	if !HasEmbeddedNetwork() {
+		if !errors.Is(err, ErrNoEmbeddedNetwork) {
+			t.Errorf("expected ErrNoEmbeddedNetwork, got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nets.SmallOnly() || nets.Small.NetworkInfo().Name != EmbeddedSmallName {
+		t.Errorf("embedded network: got %+v", nets.Small.NetworkInfo())
+	}
+}
+
 // BenchmarkAccumulatorCompute benchmarks full accumulator computation
 func BenchmarkAccumulatorCompute(b *testing.B) {
 	halfDims := TransformedFeatureDimensionsBig // 1024
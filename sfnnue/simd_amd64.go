@@ -0,0 +1,205 @@
+//go:build amd64 && !goexperiment.simd
+
+// AMD64 AVX2 SIMD operations for NNUE evaluation.
+// Uses pure Go assembly with AVX2 instructions, selected at runtime by CPU
+// detection. CPUs without AVX2 run the scalar loops that also handle the
+// remainders. No CGO required.
+
+package sfnnue
+
+import (
+	"unsafe"
+
+	"github.com/hailam/chessplay/sfnnue/common"
+)
+
+// useAVX2 selects the AVX2 kernels. Tests clear it to compare them with the
+// scalar path.
+var useAVX2 = common.HasAVX2
+
+// Assembly function declarations (implemented in simd_amd64.s)
+
+//go:noescape
+func avx2AddInt16(dst, src unsafe.Pointer, n int)
+
+//go:noescape
+func avx2SubInt16(dst, src unsafe.Pointer, n int)
+
+//go:noescape
+func avx2AddInt32(dst, src unsafe.Pointer, n int)
+
+//go:noescape
+func avx2SubInt32(dst, src unsafe.Pointer, n int)
+
+//go:noescape
+func avx2DotProductInt8Uint8(weights, inputs unsafe.Pointer, n int) int32
+
+//go:noescape
+func avx2ClippedReLU(input, output unsafe.Pointer, n, shift int)
+
+//go:noescape
+func avx2TransformClampMul(acc0, acc1, output unsafe.Pointer, n, maxVal int)
+
+//go:noescape
+func prefetchLines(addr unsafe.Pointer, count int)
+
+// PrefetchLines prefetches count cache lines (64 bytes each) starting at addr.
+func PrefetchLines(addr unsafe.Pointer, count int) {
+	prefetchLines(addr, count)
+}
+
+// SIMDAddInt16 adds src to dst using AVX2.
+// dst[i] += src[i] for all i in range
+func SIMDAddInt16(dst, src []int16) {
+	n := min(len(dst), len(src))
+	processed := 0
+	if useAVX2 && n >= 16 {
+		processed = n &^ 15 // Round down to multiple of 16
+		avx2AddInt16(unsafe.Pointer(&dst[0]), unsafe.Pointer(&src[0]), processed)
+	}
+
+	// Handle remainder (or everything without AVX2)
+	for i := processed; i < n; i++ {
+		dst[i] += src[i]
+	}
+}
+
+// SIMDSubInt16 subtracts src from dst using AVX2.
+// dst[i] -= src[i] for all i in range
+func SIMDSubInt16(dst, src []int16) {
+	n := min(len(dst), len(src))
+	processed := 0
+	if useAVX2 && n >= 16 {
+		processed = n &^ 15
+		avx2SubInt16(unsafe.Pointer(&dst[0]), unsafe.Pointer(&src[0]), processed)
+	}
+
+	for i := processed; i < n; i++ {
+		dst[i] -= src[i]
+	}
+}
+
+// SIMDAddInt32 adds src to dst using AVX2.
+// dst[i] += src[i] for all i in range
+func SIMDAddInt32(dst, src []int32) {
+	n := min(len(dst), len(src))
+	processed := 0
+	if useAVX2 && n >= 8 {
+		processed = n &^ 7 // Round down to multiple of 8
+		avx2AddInt32(unsafe.Pointer(&dst[0]), unsafe.Pointer(&src[0]), processed)
+	}
+
+	for i := processed; i < n; i++ {
+		dst[i] += src[i]
+	}
+}
+
+// SIMDSubInt32 subtracts src from dst using AVX2.
+// dst[i] -= src[i] for all i in range
+func SIMDSubInt32(dst, src []int32) {
+	n := min(len(dst), len(src))
+	processed := 0
+	if useAVX2 && n >= 8 {
+		processed = n &^ 7
+		avx2SubInt32(unsafe.Pointer(&dst[0]), unsafe.Pointer(&src[0]), processed)
+	}
+
+	for i := processed; i < n; i++ {
+		dst[i] -= src[i]
+	}
+}
+
+// SIMDCopyInt16 copies src to dst.
+// The runtime's memmove already uses AVX on amd64.
+func SIMDCopyInt16(dst, src []int16) {
+	copy(dst, src)
+}
+
+// SIMDCopyInt32 copies src to dst.
+func SIMDCopyInt32(dst, src []int32) {
+	copy(dst, src)
+}
+
+// SIMDAddInt16Offset adds src[offset:offset+count] to dst[0:count] using AVX2.
+func SIMDAddInt16Offset(dst []int16, src []int16, offset, count int) {
+	if count == 0 || offset+count > len(src) || count > len(dst) {
+		return
+	}
+	SIMDAddInt16(dst[:count], src[offset:offset+count])
+}
+
+// SIMDSubInt16Offset subtracts src[offset:offset+count] from dst[0:count] using AVX2.
+func SIMDSubInt16Offset(dst []int16, src []int16, offset, count int) {
+	if count == 0 || offset+count > len(src) || count > len(dst) {
+		return
+	}
+	SIMDSubInt16(dst[:count], src[offset:offset+count])
+}
+
+// SIMDDotProductInt8Uint8 computes dot product using AVX2.
+// Returns: sum(weights[i] * inputs[i]) for i in [0, count)
+func SIMDDotProductInt8Uint8(weights []int8, inputs []uint8, count int) int32 {
+	count = min(count, min(len(weights), len(inputs)))
+	var sum int32
+	processed := 0
+	if useAVX2 && count >= 16 {
+		processed = count &^ 15
+		sum = avx2DotProductInt8Uint8(unsafe.Pointer(&weights[0]), unsafe.Pointer(&inputs[0]), processed)
+	}
+
+	for i := processed; i < count; i++ {
+		sum += int32(weights[i]) * int32(inputs[i])
+	}
+	return sum
+}
+
+// SIMDClippedReLU applies ClippedReLU activation using AVX2.
+// Applies: output[i] = clamp(input[i] >> shift, 0, 127)
+func SIMDClippedReLU(input []int32, output []uint8, shift int) {
+	count := min(len(input), len(output))
+	processed := 0
+	if useAVX2 && count >= 32 {
+		processed = count &^ 31
+		avx2ClippedReLU(unsafe.Pointer(&input[0]), unsafe.Pointer(&output[0]), processed, shift)
+	}
+
+	for i := processed; i < count; i++ {
+		val := input[i] >> shift
+		if val < 0 {
+			val = 0
+		} else if val > 127 {
+			val = 127
+		}
+		output[i] = uint8(val)
+	}
+}
+
+// SIMDTransformClampMul performs the fused Transform inner loop using AVX2.
+// Computes: output[i] = uint8((clamp(acc0[i], 0, maxVal) * clamp(acc1[i], 0, maxVal)) >> 9)
+// acc0 and acc1 are the two halves of the accumulation array.
+// The AVX2 kernel needs the product to fit in 16 unsigned bits (maxVal <= 255).
+func SIMDTransformClampMul(acc0, acc1 []int16, output []uint8, maxVal int) {
+	count := min(len(acc0), min(len(acc1), len(output)))
+	processed := 0
+	if useAVX2 && count >= 32 && maxVal >= 0 && maxVal <= 255 {
+		processed = count &^ 31
+		avx2TransformClampMul(unsafe.Pointer(&acc0[0]), unsafe.Pointer(&acc1[0]), unsafe.Pointer(&output[0]), processed, maxVal)
+	}
+
+	maxVal16 := int16(maxVal)
+	for i := processed; i < count; i++ {
+		sum0 := acc0[i]
+		sum1 := acc1[i]
+		if sum0 < 0 {
+			sum0 = 0
+		} else if sum0 > maxVal16 {
+			sum0 = maxVal16
+		}
+		if sum1 < 0 {
+			sum1 = 0
+		} else if sum1 > maxVal16 {
+			sum1 = maxVal16
+		}
+		output[i] = uint8((int(sum0) * int(sum1)) >> 9)
+	}
+}
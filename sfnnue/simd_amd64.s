@@ -0,0 +1,278 @@
+//go:build amd64 && !goexperiment.simd
+
+#include "textflag.h"
+
+// AMD64 AVX2 SIMD operations for NNUE evaluation.
+// Callers pass element counts that are multiples of the vector width and
+// handle the remainder in Go; every kernel ends with VZEROUPPER.
+
+// Dword order that undoes the lane interleaving of VPACKSSDW + VPACKUSWB
+DATA reluPerm<>+0(SB)/4, $0
+DATA reluPerm<>+4(SB)/4, $4
+DATA reluPerm<>+8(SB)/4, $1
+DATA reluPerm<>+12(SB)/4, $5
+DATA reluPerm<>+16(SB)/4, $2
+DATA reluPerm<>+20(SB)/4, $6
+DATA reluPerm<>+24(SB)/4, $3
+DATA reluPerm<>+28(SB)/4, $7
+GLOBL reluPerm<>(SB), RODATA|NOPTR, $32
+
+// func avx2AddInt16(dst, src unsafe.Pointer, n int)
+// dst[i] += src[i], 32 elements per iteration (n must be a multiple of 16)
+TEXT ·avx2AddInt16(SB), NOSPLIT, $0-24
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ n+16(FP), CX
+	SHRQ $4, CX
+	JZ   add16_done
+
+	TESTQ $1, CX
+	JZ    add16_pairs
+	VMOVDQU (DI), Y0
+	VPADDW  (SI), Y0, Y0
+	VMOVDQU Y0, (DI)
+	ADDQ    $32, DI
+	ADDQ    $32, SI
+
+add16_pairs:
+	SHRQ $1, CX
+	JZ   add16_done
+
+add16_loop:
+	VMOVDQU (DI), Y0
+	VMOVDQU 32(DI), Y1
+	VPADDW  (SI), Y0, Y0
+	VPADDW  32(SI), Y1, Y1
+	VMOVDQU Y0, (DI)
+	VMOVDQU Y1, 32(DI)
+	ADDQ    $64, DI
+	ADDQ    $64, SI
+	DECQ    CX
+	JNZ     add16_loop
+
+add16_done:
+	VZEROUPPER
+	RET
+
+// func avx2SubInt16(dst, src unsafe.Pointer, n int)
+// dst[i] -= src[i], 32 elements per iteration (n must be a multiple of 16)
+TEXT ·avx2SubInt16(SB), NOSPLIT, $0-24
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ n+16(FP), CX
+	SHRQ $4, CX
+	JZ   sub16_done
+
+	TESTQ $1, CX
+	JZ    sub16_pairs
+	VMOVDQU (DI), Y0
+	VPSUBW  (SI), Y0, Y0
+	VMOVDQU Y0, (DI)
+	ADDQ    $32, DI
+	ADDQ    $32, SI
+
+sub16_pairs:
+	SHRQ $1, CX
+	JZ   sub16_done
+
+sub16_loop:
+	VMOVDQU (DI), Y0
+	VMOVDQU 32(DI), Y1
+	VPSUBW  (SI), Y0, Y0
+	VPSUBW  32(SI), Y1, Y1
+	VMOVDQU Y0, (DI)
+	VMOVDQU Y1, 32(DI)
+	ADDQ    $64, DI
+	ADDQ    $64, SI
+	DECQ    CX
+	JNZ     sub16_loop
+
+sub16_done:
+	VZEROUPPER
+	RET
+
+// func avx2AddInt32(dst, src unsafe.Pointer, n int)
+// dst[i] += src[i], 8 elements per iteration (n must be a multiple of 8)
+TEXT ·avx2AddInt32(SB), NOSPLIT, $0-24
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ n+16(FP), CX
+	SHRQ $3, CX
+	JZ   add32_done
+
+add32_loop:
+	VMOVDQU (DI), Y0
+	VPADDD  (SI), Y0, Y0
+	VMOVDQU Y0, (DI)
+	ADDQ    $32, DI
+	ADDQ    $32, SI
+	DECQ    CX
+	JNZ     add32_loop
+
+add32_done:
+	VZEROUPPER
+	RET
+
+// func avx2SubInt32(dst, src unsafe.Pointer, n int)
+// dst[i] -= src[i], 8 elements per iteration (n must be a multiple of 8)
+TEXT ·avx2SubInt32(SB), NOSPLIT, $0-24
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ n+16(FP), CX
+	SHRQ $3, CX
+	JZ   sub32_done
+
+sub32_loop:
+	VMOVDQU (DI), Y0
+	VPSUBD  (SI), Y0, Y0
+	VMOVDQU Y0, (DI)
+	ADDQ    $32, DI
+	ADDQ    $32, SI
+	DECQ    CX
+	JNZ     sub32_loop
+
+sub32_done:
+	VZEROUPPER
+	RET
+
+// func avx2DotProductInt8Uint8(weights, inputs unsafe.Pointer, n int) int32
+// Returns sum(weights[i] * inputs[i]) (n must be a multiple of 16).
+// Bytes are widened to int16 before VPMADDWD, so the result is exact for
+// any input (VPMADDUBSW would saturate for inputs above 127).
+TEXT ·avx2DotProductInt8Uint8(SB), NOSPLIT, $0-28
+	MOVQ weights+0(FP), SI
+	MOVQ inputs+8(FP), DI
+	MOVQ n+16(FP), CX
+	VPXOR Y0, Y0, Y0
+	SHRQ $4, CX
+	JZ   dot_sum
+
+dot_loop:
+	VPMOVSXBW (SI), Y1
+	VPMOVZXBW (DI), Y2
+	VPMADDWD  Y2, Y1, Y1
+	VPADDD    Y1, Y0, Y0
+	ADDQ      $16, SI
+	ADDQ      $16, DI
+	DECQ      CX
+	JNZ       dot_loop
+
+dot_sum:
+	// Horizontal sum of the eight int32 lanes
+	VEXTRACTI128 $1, Y0, X1
+	VPADDD       X1, X0, X0
+	VPSHUFD      $0x4E, X0, X1
+	VPADDD       X1, X0, X0
+	VPSHUFD      $0xB1, X0, X1
+	VPADDD       X1, X0, X0
+	MOVL         X0, AX
+	VZEROUPPER
+	MOVL AX, ret+24(FP)
+	RET
+
+// func avx2ClippedReLU(input, output unsafe.Pointer, n, shift int)
+// output[i] = clamp(input[i] >> shift, 0, 127), 32 elements per iteration
+// (n must be a multiple of 32)
+TEXT ·avx2ClippedReLU(SB), NOSPLIT, $0-32
+	MOVQ input+0(FP), SI
+	MOVQ output+8(FP), DI
+	MOVQ n+16(FP), CX
+	MOVQ shift+24(FP), AX
+	SHRQ $5, CX
+	JZ   relu_done
+
+	MOVQ         AX, X15
+	MOVL         $0x7f7f7f7f, AX
+	MOVQ         AX, X14
+	VPBROADCASTD X14, Y14
+	VMOVDQU      reluPerm<>(SB), Y13
+
+relu_loop:
+	VMOVDQU   (SI), Y0
+	VMOVDQU   32(SI), Y1
+	VMOVDQU   64(SI), Y2
+	VMOVDQU   96(SI), Y3
+	VPSRAD    X15, Y0, Y0
+	VPSRAD    X15, Y1, Y1
+	VPSRAD    X15, Y2, Y2
+	VPSRAD    X15, Y3, Y3
+	VPACKSSDW Y1, Y0, Y0  // Saturate to int16
+	VPACKSSDW Y3, Y2, Y2
+	VPACKUSWB Y2, Y0, Y0  // Saturate to [0, 255]
+	VPERMD    Y0, Y13, Y0 // Restore element order
+	VPMINUB   Y14, Y0, Y0 // Clamp to 127
+	VMOVDQU   Y0, (DI)
+	ADDQ      $128, SI
+	ADDQ      $32, DI
+	DECQ      CX
+	JNZ       relu_loop
+
+relu_done:
+	VZEROUPPER
+	RET
+
+// func avx2TransformClampMul(acc0, acc1, output unsafe.Pointer, n, maxVal int)
+// output[i] = (clamp(acc0[i], 0, maxVal) * clamp(acc1[i], 0, maxVal)) >> 9,
+// 32 elements per iteration (n must be a multiple of 32, maxVal at most 255)
+TEXT ·avx2TransformClampMul(SB), NOSPLIT, $0-40
+	MOVQ acc0+0(FP), SI
+	MOVQ acc1+8(FP), DX
+	MOVQ output+16(FP), DI
+	MOVQ n+24(FP), CX
+	MOVQ maxVal+32(FP), AX
+	SHRQ $5, CX
+	JZ   tcm_done
+
+	VPXOR        Y15, Y15, Y15
+	MOVQ         AX, X14
+	VPBROADCASTW X14, Y14
+
+tcm_loop:
+	VMOVDQU (SI), Y0
+	VMOVDQU 32(SI), Y1
+	VMOVDQU (DX), Y2
+	VMOVDQU 32(DX), Y3
+	VPMAXSW Y15, Y0, Y0
+	VPMAXSW Y15, Y1, Y1
+	VPMAXSW Y15, Y2, Y2
+	VPMAXSW Y15, Y3, Y3
+	VPMINSW Y14, Y0, Y0
+	VPMINSW Y14, Y1, Y1
+	VPMINSW Y14, Y2, Y2
+	VPMINSW Y14, Y3, Y3
+
+	// The product fits in 16 unsigned bits; the logical shift leaves 0..127
+	VPMULLW   Y2, Y0, Y0
+	VPMULLW   Y3, Y1, Y1
+	VPSRLW    $9, Y0, Y0
+	VPSRLW    $9, Y1, Y1
+	VPACKUSWB Y1, Y0, Y0
+	VPERMQ    $0xD8, Y0, Y0 // Restore element order
+	VMOVDQU   Y0, (DI)
+
+	ADDQ $64, SI
+	ADDQ $64, DX
+	ADDQ $32, DI
+	DECQ CX
+	JNZ  tcm_loop
+
+tcm_done:
+	VZEROUPPER
+	RET
+
+// func prefetchLines(addr unsafe.Pointer, count int)
+// Prefetches count 64-byte cache lines into all cache levels.
+TEXT ·prefetchLines(SB), NOSPLIT, $0-16
+	MOVQ  addr+0(FP), AX
+	MOVQ  count+8(FP), CX
+	TESTQ CX, CX
+	JLE   prefetch_done
+
+prefetch_loop:
+	PREFETCHT0 (AX)
+	ADDQ       $64, AX
+	DECQ       CX
+	JNZ        prefetch_loop
+
+prefetch_done:
+	RET
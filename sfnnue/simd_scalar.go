@@ -1,11 +1,9 @@
-//go:build !arm64 && (!goexperiment.simd || !amd64)
+//go:build !arm64 && !amd64
 
 // Scalar fallback for NNUE operations when SIMD is not available.
-// Used when:
-// - Building on non-ARM64, non-AMD64 platforms (e.g., 386, riscv64)
-// - Building on AMD64 without GOEXPERIMENT=simd
+// Used on non-ARM64, non-AMD64 platforms (e.g., 386, riscv64).
 // ARM64 uses simd_neon.go with NEON assembly.
-// AMD64 with GOEXPERIMENT=simd uses simd.go.
+// AMD64 uses simd_amd64.go with AVX2 assembly, or simd.go with GOEXPERIMENT=simd.
 
 package sfnnue
 